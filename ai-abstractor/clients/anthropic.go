@@ -1,11 +1,15 @@
 package clients
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"ai-abstractor/models"
 
 	"github.com/sirupsen/logrus"
 )
@@ -19,10 +23,12 @@ type AnthropicClient struct {
 }
 
 type anthropicRequest struct {
-	Model     string                   `json:"model"`
-	MaxTokens int                      `json:"max_tokens"`
-	Messages  []anthropicMessage       `json:"messages"`
-	System    string                   `json:"system,omitempty"`
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+	System    string             `json:"system,omitempty"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -30,6 +36,12 @@ type anthropicMessage struct {
 	Content string `json:"content"`
 }
 
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
 type anthropicResponse struct {
 	Content []anthropicContent `json:"content"`
 	Usage   anthropicUsage     `json:"usage"`
@@ -37,8 +49,11 @@ type anthropicResponse struct {
 }
 
 type anthropicContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string                 `json:"type"`
+	Text  string                 `json:"text"`
+	ID    string                 `json:"id,omitempty"`    // tool_use blocks
+	Name  string                 `json:"name,omitempty"`  // tool_use blocks
+	Input map[string]interface{} `json:"input,omitempty"` // tool_use blocks
 }
 
 type anthropicUsage struct {
@@ -51,6 +66,21 @@ type anthropicError struct {
 	Message string `json:"message"`
 }
 
+// anthropicStreamEvent is the data of one server-sent event of a streamed response.
+// Only the fields needed to rebuild text content and usage are decoded.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"` // message_start
+	Delta *struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta,omitempty"` // content_block_delta
+	Usage *anthropicUsage `json:"usage,omitempty"` // message_delta
+	Error *anthropicError `json:"error,omitempty"` // error
+}
+
 func NewAnthropicClient(apiKey, baseURL, model string, maxTokens int) (*AnthropicClient, error) {
 	logrus.WithFields(logrus.Fields{
 		"model":      model,
@@ -68,63 +98,250 @@ func NewAnthropicClient(apiKey, baseURL, model string, maxTokens int) (*Anthropi
 }
 
 func (c *AnthropicClient) GenerateResponse(ctx context.Context, systemMessage, userPrompt string) (string, int, error) {
+	return c.GenerateConversation(ctx, systemMessage, []models.Message{
+		{Role: models.RoleUser, Content: userPrompt},
+	})
+}
+
+// GenerateConversation sends a multi-turn conversation; the last message should be a user turn
+func (c *AnthropicClient) GenerateConversation(ctx context.Context, systemMessage string, messages []models.Message) (string, int, error) {
+	completion, err := c.GenerateWithTools(ctx, systemMessage, messages, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	return completion.Content, completion.TokensUsed, nil
+}
+
+// GenerateWithTools sends a conversation along with tools the model may request calls to
+func (c *AnthropicClient) GenerateWithTools(ctx context.Context, systemMessage string, messages []models.Message, tools []models.Tool) (*Completion, error) {
+	return withRateLimitRetry(ctx, func() (*Completion, error) {
+		return c.sendConversation(ctx, systemMessage, messages, tools)
+	})
+}
+
+// GenerateStream sends a conversation and passes text deltas to onDelta as they arrive.
+// The returned completion holds the aggregated content.
+func (c *AnthropicClient) GenerateStream(ctx context.Context, systemMessage string, messages []models.Message, onDelta DeltaFunc) (*Completion, error) {
+	return withRateLimitRetry(ctx, func() (*Completion, error) {
+		return c.streamConversation(ctx, systemMessage, messages, onDelta)
+	})
+}
+
+// sendConversation performs a single Messages API call
+func (c *AnthropicClient) sendConversation(ctx context.Context, systemMessage string, messages []models.Message, tools []models.Tool) (*Completion, error) {
 	reqBody := anthropicRequest{
 		Model:     c.model,
 		MaxTokens: c.maxTokens,
-		Messages: []anthropicMessage{
-			{
-				Role:    "user",
-				Content: userPrompt,
-			},
-		},
+		Messages:  make([]anthropicMessage, 0, len(messages)),
+	}
+
+	for _, msg := range messages {
+		reqBody.Messages = append(reqBody.Messages, anthropicMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
 	}
 
 	if systemMessage != "" {
 		reqBody.System = systemMessage
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", 0, err
+	for _, tool := range tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"} // Anthropic requires an input schema
+		}
+		reqBody.Tools = append(reqBody.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
 	}
 
-	url := fmt.Sprintf("%s/v1/messages", c.baseURL)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	resp, err := c.post(ctx, reqBody)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	var anthropicResp anthropicResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&anthropicResp)
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", 0, err
+	if resp.StatusCode == http.StatusTooManyRequests {
+		rateErr := &RateLimitError{
+			Provider:   "anthropic",
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		if decodeErr == nil && anthropicResp.Error != nil {
+			rateErr.Message = anthropicResp.Error.Message
+		}
+		return nil, rateErr
 	}
-	defer resp.Body.Close()
 
-	var anthropicResp anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
-		return "", 0, err
+	if decodeErr != nil {
+		return nil, decodeErr
 	}
 
 	if anthropicResp.Error != nil {
-		return "", 0, fmt.Errorf("anthropic error: %s", anthropicResp.Error.Message)
+		return nil, fmt.Errorf("anthropic error: %s", anthropicResp.Error.Message)
 	}
 
-	if len(anthropicResp.Content) == 0 {
-		return "", anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens, nil
+	completion := &Completion{
+		TokensUsed:       anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
+		PromptTokens:     anthropicResp.Usage.InputTokens,
+		CompletionTokens: anthropicResp.Usage.OutputTokens,
 	}
 
-	content := anthropicResp.Content[0].Text
-	totalTokens := anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens
+	// Text blocks are concatenated; tool_use blocks become structured tool calls
+	for _, block := range anthropicResp.Content {
+		switch block.Type {
+		case "tool_use":
+			arguments := block.Input
+			if arguments == nil {
+				arguments = map[string]interface{}{}
+			}
+			completion.ToolCalls = append(completion.ToolCalls, models.ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: arguments,
+			})
+		default:
+			completion.Content += block.Text
+		}
+	}
 
 	logrus.WithFields(logrus.Fields{
 		"model":          c.model,
-		"tokens_used":    totalTokens,
-		"content_length": len(content),
+		"turns":          len(messages),
+		"tokens_used":    completion.TokensUsed,
+		"content_length": len(completion.Content),
+		"tool_calls":     len(completion.ToolCalls),
 	}).Debug("Anthropic response generated")
 
-	return content, totalTokens, nil
-}
\ No newline at end of file
+	return completion, nil
+}
+
+// streamConversation performs a single streamed Messages API call, reading the
+// server-sent events until the message stops
+func (c *AnthropicClient) streamConversation(ctx context.Context, systemMessage string, messages []models.Message, onDelta DeltaFunc) (*Completion, error) {
+	reqBody := anthropicRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		Messages:  make([]anthropicMessage, 0, len(messages)),
+		System:    systemMessage,
+		Stream:    true,
+	}
+	for _, msg := range messages {
+		reqBody.Messages = append(reqBody.Messages, anthropicMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	resp, err := c.post(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Failures before the stream starts are plain JSON error bodies
+	if resp.StatusCode != http.StatusOK {
+		var anthropicResp anthropicResponse
+		decodeErr := json.NewDecoder(resp.Body).Decode(&anthropicResp)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			rateErr := &RateLimitError{
+				Provider:   "anthropic",
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			}
+			if decodeErr == nil && anthropicResp.Error != nil {
+				rateErr.Message = anthropicResp.Error.Message
+			}
+			return nil, rateErr
+		}
+		if decodeErr == nil && anthropicResp.Error != nil {
+			return nil, fmt.Errorf("anthropic error: %s", anthropicResp.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic error: unexpected status %d", resp.StatusCode)
+	}
+
+	// Output tokens in message_delta are cumulative, so the last report wins
+	var usage anthropicUsage
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue // event names and keep-alives; the type is repeated in the data
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return nil, fmt.Errorf("invalid stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				usage = event.Message.Usage
+			}
+		case "content_block_delta":
+			if event.Delta == nil || event.Delta.Text == "" {
+				continue
+			}
+			content.WriteString(event.Delta.Text)
+			if err := onDelta(event.Delta.Text); err != nil {
+				return nil, err
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return nil, fmt.Errorf("anthropic error: %s", event.Error.Message)
+			}
+			return nil, fmt.Errorf("anthropic error: stream failed")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	completion := &Completion{
+		Content:          content.String(),
+		TokensUsed:       usage.InputTokens + usage.OutputTokens,
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"model":          c.model,
+		"turns":          len(messages),
+		"tokens_used":    completion.TokensUsed,
+		"content_length": len(completion.Content),
+	}).Debug("Anthropic streamed response generated")
+
+	return completion, nil
+}
+
+// post sends a request body to the Messages API
+func (c *AnthropicClient) post(ctx context.Context, reqBody anthropicRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/v1/messages", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	return c.client.Do(req)
+}
@@ -0,0 +1,137 @@
+package clients
+
+import (
+	"ai-abstractor/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newAnthropicServer returns a client talking to a server that records each request body
+// and lets handler write the response
+func newAnthropicServer(t *testing.T, handler func(w http.ResponseWriter, body anthropicRequest)) *AnthropicClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewAnthropicClient("key", server.URL, "claude-test", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func writeAnthropicText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anthropicResponse{
+		Content: []anthropicContent{{Type: "text", Text: text}},
+		Usage:   anthropicUsage{InputTokens: 3, OutputTokens: 2},
+	})
+}
+
+func TestAnthropicGenerateConversationSendsAllTurns(t *testing.T) {
+	var sent anthropicRequest
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		sent = body
+		writeAnthropicText(w, "Paris")
+	})
+
+	conversation := []models.Message{
+		{Role: models.RoleUser, Content: "Pick a country"},
+		{Role: models.RoleAssistant, Content: "France"},
+		{Role: models.RoleUser, Content: "What is its capital?"},
+	}
+	content, tokens, err := client.GenerateConversation(context.Background(), "Be brief", conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Paris" || tokens != 5 {
+		t.Fatalf("got %q with %d tokens", content, tokens)
+	}
+
+	if sent.System != "Be brief" {
+		t.Errorf("system %q, want %q", sent.System, "Be brief")
+	}
+	if len(sent.Messages) != len(conversation) {
+		t.Fatalf("sent %d messages, want %d", len(sent.Messages), len(conversation))
+	}
+	for i, msg := range conversation {
+		if sent.Messages[i].Role != msg.Role || sent.Messages[i].Content != msg.Content {
+			t.Errorf("message %d: got %+v, want %+v", i, sent.Messages[i], msg)
+		}
+	}
+}
+
+func TestAnthropicGenerateResponseSendsSinglePrompt(t *testing.T) {
+	var sent anthropicRequest
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		sent = body
+		writeAnthropicText(w, "ok")
+	})
+
+	if _, _, err := client.GenerateResponse(context.Background(), "", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if sent.System != "" {
+		t.Errorf("unexpected system %q", sent.System)
+	}
+	if len(sent.Messages) != 1 || sent.Messages[0].Role != models.RoleUser || sent.Messages[0].Content != "hello" {
+		t.Fatalf("unexpected messages %+v", sent.Messages)
+	}
+}
+
+func TestAnthropicGenerateWithToolsReturnsToolCalls(t *testing.T) {
+	var sent anthropicRequest
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		sent = body
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(anthropicResponse{
+			Content: []anthropicContent{
+				{Type: "text", Text: "Looking it up."},
+				{Type: "tool_use", ID: "toolu_1", Name: "get_weather", Input: map[string]interface{}{"city": "Paris"}},
+			},
+			Usage: anthropicUsage{InputTokens: 3, OutputTokens: 2},
+		})
+	})
+
+	tools := []models.Tool{
+		{Name: "get_weather", Description: "Current weather", Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		}},
+		{Name: "now"},
+	}
+	completion, err := client.GenerateWithTools(context.Background(), "", []models.Message{{Role: models.RoleUser, Content: "Weather in Paris?"}}, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent.Tools) != 2 || sent.Tools[0].Name != "get_weather" || sent.Tools[0].InputSchema["type"] != "object" {
+		t.Fatalf("tools not passed through: %+v", sent.Tools)
+	}
+	if sent.Tools[1].InputSchema["type"] != "object" {
+		t.Errorf("a tool without parameters needs an empty object schema, got %v", sent.Tools[1].InputSchema)
+	}
+
+	if completion.Content != "Looking it up." {
+		t.Errorf("got content %q", completion.Content)
+	}
+	if len(completion.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(completion.ToolCalls))
+	}
+	call := completion.ToolCalls[0]
+	if call.ID != "toolu_1" || call.Name != "get_weather" || call.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool call %+v", call)
+	}
+}
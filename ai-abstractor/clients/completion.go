@@ -0,0 +1,33 @@
+package clients
+
+import "ai-abstractor/models"
+
+// Completion is a provider's answer to a conversation: text content, any tool calls the
+// model requested, and the tokens consumed
+type Completion struct {
+	Content          string
+	ToolCalls        []models.ToolCall
+	TokensUsed       int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// DeltaFunc receives each piece of text as a streamed completion arrives. Returning an
+// error stops the stream and the error is returned from the generate call.
+type DeltaFunc func(delta string) error
+
+// charsPerToken approximates the length of an English token, for providers that don't
+// report usage
+const charsPerToken = 4
+
+// estimateUsage sets a completion's usage from the length of the prompt and content,
+// rounding each up to whole tokens
+func estimateUsage(completion *Completion, systemMessage string, conversation []models.Message) {
+	promptChars := len(systemMessage)
+	for _, message := range conversation {
+		promptChars += len(message.Content)
+	}
+	completion.PromptTokens = (promptChars + charsPerToken - 1) / charsPerToken
+	completion.CompletionTokens = (len(completion.Content) + charsPerToken - 1) / charsPerToken
+	completion.TokensUsed = completion.PromptTokens + completion.CompletionTokens
+}
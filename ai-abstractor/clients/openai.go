@@ -2,6 +2,14 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-abstractor/models"
 
 	"github.com/sashabaranov/go-openai"
 	"github.com/sirupsen/logrus"
@@ -21,6 +29,11 @@ func NewOpenAIClient(apiKey, baseURL, model string, maxTokens int, temperature f
 		config.BaseURL = baseURL
 	}
 
+	// Capture Retry-After on 429s, which the library does not expose on its errors
+	config.HTTPClient = &http.Client{
+		Transport: &retryAfterTransport{base: http.DefaultTransport},
+	}
+
 	client := openai.NewClientWithConfig(config)
 
 	logrus.WithFields(logrus.Fields{
@@ -39,50 +52,193 @@ func NewOpenAIClient(apiKey, baseURL, model string, maxTokens int, temperature f
 }
 
 func (c *OpenAIClient) GenerateResponse(ctx context.Context, systemMessage, userPrompt string) (string, int, error) {
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: userPrompt,
-		},
+	return c.GenerateConversation(ctx, systemMessage, []models.Message{
+		{Role: models.RoleUser, Content: userPrompt},
+	})
+}
+
+// GenerateConversation sends a multi-turn conversation; the last message should be a user turn
+func (c *OpenAIClient) GenerateConversation(ctx context.Context, systemMessage string, conversation []models.Message) (string, int, error) {
+	completion, err := c.GenerateWithTools(ctx, systemMessage, conversation, nil)
+	if err != nil {
+		return "", 0, err
 	}
+	return completion.Content, completion.TokensUsed, nil
+}
 
-	if systemMessage != "" {
-		messages = []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: systemMessage,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: userPrompt,
-			},
+// GenerateWithTools sends a conversation along with tools the model may request calls to
+func (c *OpenAIClient) GenerateWithTools(ctx context.Context, systemMessage string, conversation []models.Message, tools []models.Tool) (*Completion, error) {
+	return withRateLimitRetry(ctx, func() (*Completion, error) {
+		return c.sendConversation(ctx, systemMessage, conversation, tools)
+	})
+}
+
+// GenerateStream sends a conversation and passes content deltas to onDelta as they arrive.
+// The returned completion holds the aggregated content.
+func (c *OpenAIClient) GenerateStream(ctx context.Context, systemMessage string, conversation []models.Message, onDelta DeltaFunc) (*Completion, error) {
+	return withRateLimitRetry(ctx, func() (*Completion, error) {
+		return c.streamConversation(ctx, systemMessage, conversation, onDelta)
+	})
+}
+
+// sendConversation performs a single chat completion call
+func (c *OpenAIClient) sendConversation(ctx context.Context, systemMessage string, conversation []models.Message, tools []models.Tool) (*Completion, error) {
+	req := c.buildRequest(systemMessage, conversation)
+
+	for _, tool := range tools {
+		definition := openai.FunctionDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		if tool.Parameters != nil {
+			definition.Parameters = tool.Parameters
+		}
+		req.Tools = append(req.Tools, openai.Tool{
+			Type:     openai.ToolTypeFunction,
+			Function: definition,
+		})
+	}
+
+	holder := &retryAfterHolder{}
+	resp, err := c.client.CreateChatCompletion(context.WithValue(ctx, retryAfterKey{}, holder), req)
+	if err != nil {
+		return nil, c.wrapError(err, holder)
+	}
+
+	completion := &Completion{
+		TokensUsed:       resp.Usage.TotalTokens,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+	if len(resp.Choices) == 0 {
+		return completion, nil
+	}
+
+	message := resp.Choices[0].Message
+	completion.Content = message.Content
+
+	// Function arguments arrive as a JSON string and are decoded into an object
+	for _, call := range message.ToolCalls {
+		arguments := map[string]interface{}{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %w", call.Function.Name, err)
+			}
+		}
+		completion.ToolCalls = append(completion.ToolCalls, models.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: arguments,
+		})
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"model":          c.model,
+		"turns":          len(conversation),
+		"tokens_used":    completion.TokensUsed,
+		"content_length": len(completion.Content),
+		"tool_calls":     len(completion.ToolCalls),
+	}).Debug("OpenAI response generated")
+
+	return completion, nil
+}
+
+// streamConversation performs a single streamed chat completion call. Streamed
+// responses do not report usage, so it is estimated from the prompt and content, which
+// keeps streamed requests within token budgets.
+func (c *OpenAIClient) streamConversation(ctx context.Context, systemMessage string, conversation []models.Message, onDelta DeltaFunc) (*Completion, error) {
+	req := c.buildRequest(systemMessage, conversation)
+	req.Stream = true
+
+	holder := &retryAfterHolder{}
+	stream, err := c.client.CreateChatCompletionStream(context.WithValue(ctx, retryAfterKey{}, holder), req)
+	if err != nil {
+		return nil, c.wrapError(err, holder)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
 		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+
+	completion := &Completion{Content: content.String()}
+	estimateUsage(completion, systemMessage, conversation)
+
+	logrus.WithFields(logrus.Fields{
+		"model":          c.model,
+		"turns":          len(conversation),
+		"tokens_used":    completion.TokensUsed,
+		"content_length": content.Len(),
+	}).Debug("OpenAI streamed response generated")
+
+	return completion, nil
+}
+
+// buildRequest converts a conversation into a chat completion request
+func (c *OpenAIClient) buildRequest(systemMessage string, conversation []models.Message) openai.ChatCompletionRequest {
+	messages := make([]openai.ChatCompletionMessage, 0, len(conversation)+1)
+
+	if systemMessage != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: systemMessage,
+		})
+	}
+
+	for _, msg := range conversation {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
 	}
 
-	req := openai.ChatCompletionRequest{
+	return openai.ChatCompletionRequest{
 		Model:       c.model,
 		Messages:    messages,
 		MaxTokens:   c.maxTokens,
 		Temperature: c.temperature,
 	}
+}
 
-	resp, err := c.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		return "", 0, err
+// wrapError converts rate limit failures into a RateLimitError
+func (c *OpenAIClient) wrapError(err error, holder *retryAfterHolder) error {
+	if isOpenAIRateLimit(err) {
+		return &RateLimitError{
+			Provider:   "openai",
+			RetryAfter: parseRetryAfter(holder.value),
+			Message:    err.Error(),
+		}
 	}
+	return err
+}
 
-	if len(resp.Choices) == 0 {
-		return "", resp.Usage.TotalTokens, nil
+// isOpenAIRateLimit reports whether an OpenAI error is an HTTP 429
+func isOpenAIRateLimit(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
 	}
 
-	content := resp.Choices[0].Message.Content
-	tokens := resp.Usage.TotalTokens
-
-	logrus.WithFields(logrus.Fields{
-		"model":         c.model,
-		"tokens_used":   tokens,
-		"content_length": len(content),
-	}).Debug("OpenAI response generated")
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
 
-	return content, tokens, nil
-}
\ No newline at end of file
+	return false
+}
@@ -0,0 +1,136 @@
+package clients
+
+import (
+	"ai-abstractor/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newOpenAIServer returns a client talking to a server that records each request body
+func newOpenAIServer(t *testing.T, handler func(w http.ResponseWriter, body openai.ChatCompletionRequest)) *OpenAIClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewOpenAIClient("key", server.URL, "gpt-test", 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func writeOpenAIMessage(w http.ResponseWriter, message openai.ChatCompletionMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: message}},
+		Usage:   openai.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5},
+	})
+}
+
+func TestOpenAIGenerateConversationSendsAllTurns(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		sent = body
+		writeOpenAIMessage(w, openai.ChatCompletionMessage{Role: models.RoleAssistant, Content: "Paris"})
+	})
+
+	conversation := []models.Message{
+		{Role: models.RoleUser, Content: "Pick a country"},
+		{Role: models.RoleAssistant, Content: "France"},
+		{Role: models.RoleUser, Content: "What is its capital?"},
+	}
+	content, tokens, err := client.GenerateConversation(context.Background(), "Be brief", conversation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Paris" || tokens != 5 {
+		t.Fatalf("got %q with %d tokens", content, tokens)
+	}
+
+	// The system message leads, followed by the turns in order
+	want := append([]models.Message{{Role: models.RoleSystem, Content: "Be brief"}}, conversation...)
+	if len(sent.Messages) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(sent.Messages), len(want))
+	}
+	for i, msg := range want {
+		if sent.Messages[i].Role != msg.Role || sent.Messages[i].Content != msg.Content {
+			t.Errorf("message %d: got %s %q, want %+v", i, sent.Messages[i].Role, sent.Messages[i].Content, msg)
+		}
+	}
+}
+
+func TestOpenAIGenerateResponseSendsSinglePrompt(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		sent = body
+		writeOpenAIMessage(w, openai.ChatCompletionMessage{Role: models.RoleAssistant, Content: "ok"})
+	})
+
+	if _, _, err := client.GenerateResponse(context.Background(), "", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.Messages) != 1 || sent.Messages[0].Role != models.RoleUser || sent.Messages[0].Content != "hello" {
+		t.Fatalf("unexpected messages %+v", sent.Messages)
+	}
+}
+
+func TestOpenAIGenerateWithToolsReturnsToolCalls(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		sent = body
+		writeOpenAIMessage(w, openai.ChatCompletionMessage{Role: models.RoleAssistant, ToolCalls: []openai.ToolCall{{
+			ID:       "call_1",
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+		}}})
+	})
+
+	tools := []models.Tool{{Name: "get_weather", Description: "Current weather", Parameters: map[string]interface{}{"type": "object"}}}
+	completion, err := client.GenerateWithTools(context.Background(), "", []models.Message{{Role: models.RoleUser, Content: "Weather in Paris?"}}, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent.Tools) != 1 || sent.Tools[0].Type != openai.ToolTypeFunction || sent.Tools[0].Function.Name != "get_weather" {
+		t.Fatalf("tools not passed through: %+v", sent.Tools)
+	}
+	if len(completion.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(completion.ToolCalls))
+	}
+	call := completion.ToolCalls[0]
+	if call.ID != "call_1" || call.Name != "get_weather" || call.Arguments["city"] != "Paris" {
+		t.Errorf("unexpected tool call %+v", call)
+	}
+	if completion.TokensUsed != 5 {
+		t.Errorf("got %d tokens, want 5", completion.TokensUsed)
+	}
+}
+
+func TestOpenAIGenerateWithToolsRejectsInvalidArguments(t *testing.T) {
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		writeOpenAIMessage(w, openai.ChatCompletionMessage{Role: models.RoleAssistant, ToolCalls: []openai.ToolCall{{
+			ID:       "call_1",
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: "get_weather", Arguments: `{"city":`},
+		}}})
+	})
+
+	tools := []models.Tool{{Name: "get_weather"}}
+	if _, err := client.GenerateWithTools(context.Background(), "", []models.Message{{Role: models.RoleUser, Content: "Weather?"}}, tools); err == nil {
+		t.Fatal("expected malformed tool arguments to be reported")
+	}
+}
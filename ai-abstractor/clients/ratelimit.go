@@ -0,0 +1,114 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxInlineRateLimitWait is the longest Retry-After the clients will sleep through
+	// themselves; longer delays are returned to the caller as a RateLimitError
+	maxInlineRateLimitWait = 10 * time.Second
+	// maxRateLimitRetries bounds how many times a single request waits and retries
+	maxRateLimitRetries = 2
+	// defaultRateLimitDelay is used when a 429 carries no usable Retry-After header
+	defaultRateLimitDelay = 5 * time.Second
+)
+
+// RateLimitError is returned when a provider rejects a request with HTTP 429
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s rate limited, retry after %s: %s", e.Provider, e.RetryAfter, e.Message)
+	}
+	return fmt.Sprintf("%s rate limited, retry after %s", e.Provider, e.RetryAfter)
+}
+
+// parseRetryAfter reads a Retry-After header given either as seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultRateLimitDelay
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(when); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+
+	return defaultRateLimitDelay
+}
+
+// withRateLimitRetry runs attempt, sleeping and retrying on short rate limits as long as
+// the wait fits within the context deadline. Other errors are returned unchanged.
+func withRateLimitRetry(ctx context.Context, attempt func() (*Completion, error)) (*Completion, error) {
+	for retry := 0; ; retry++ {
+		completion, err := attempt()
+
+		rateErr, ok := err.(*RateLimitError)
+		if !ok || retry >= maxRateLimitRetries || rateErr.RetryAfter > maxInlineRateLimitWait {
+			return completion, err
+		}
+
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Until(deadline) <= rateErr.RetryAfter {
+			return completion, err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"provider":    rateErr.Provider,
+			"retry_after": rateErr.RetryAfter,
+			"retry":       retry + 1,
+		}).Warn("Provider rate limited request, waiting before retry")
+
+		timer := time.NewTimer(rateErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+type retryAfterKey struct{}
+
+// retryAfterHolder receives the Retry-After header of a rate limited response
+type retryAfterHolder struct {
+	value string
+}
+
+// retryAfterTransport captures Retry-After headers from 429 responses for libraries
+// that do not expose response headers on their errors
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	if holder, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHolder); ok {
+		holder.value = resp.Header.Get("Retry-After")
+	}
+
+	return resp, err
+}
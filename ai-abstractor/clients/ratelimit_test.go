@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"ai-abstractor/models"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":                              defaultRateLimitDelay,
+		"3":                             3 * time.Second,
+		"0.5":                           500 * time.Millisecond,
+		"-1":                            defaultRateLimitDelay,
+		"soon":                          defaultRateLimitDelay,
+		"Mon, 01 Jan 2001 00:00:00 GMT": 0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("%q: got %s, want %s", value, got, want)
+		}
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("date %s: got %s, want about an hour", future, got)
+	}
+}
+
+func writeRateLimited(w http.ResponseWriter, retryAfter string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", retryAfter)
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte(`{"error": {"type": "rate_limit_error", "message": "slow down"}}`))
+}
+
+func TestAnthropicRetriesShortRateLimit(t *testing.T) {
+	calls := 0
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		calls++
+		if calls == 1 {
+			writeRateLimited(w, "0")
+			return
+		}
+		writeAnthropicText(w, "ok")
+	})
+
+	content, _, err := client.GenerateResponse(context.Background(), "", "hello")
+	if err != nil || content != "ok" {
+		t.Fatalf("got %q, %v", content, err)
+	}
+	if calls != 2 {
+		t.Fatalf("made %d calls, want 2", calls)
+	}
+}
+
+func TestAnthropicReturnsLongRateLimit(t *testing.T) {
+	calls := 0
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		calls++
+		writeRateLimited(w, "120")
+	})
+
+	_, _, err := client.GenerateResponse(context.Background(), "", "hello")
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected a RateLimitError, got %v", err)
+	}
+	if rateErr.Provider != "anthropic" || rateErr.RetryAfter != 120*time.Second || rateErr.Message != "slow down" {
+		t.Errorf("unexpected error %+v", rateErr)
+	}
+	if calls != 1 {
+		t.Errorf("made %d calls, want no retry past the inline limit", calls)
+	}
+}
+
+func TestOpenAIRateLimitRetryAfter(t *testing.T) {
+	calls := 0
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		calls++
+		if calls == 1 {
+			writeRateLimited(w, "0")
+			return
+		}
+		if calls == 2 {
+			writeOpenAIMessage(w, openai.ChatCompletionMessage{Role: models.RoleAssistant, Content: "ok"})
+			return
+		}
+		writeRateLimited(w, "120")
+	})
+
+	content, _, err := client.GenerateResponse(context.Background(), "", "hello")
+	if err != nil || content != "ok" || calls != 2 {
+		t.Fatalf("got %q, %v after %d calls; want a retried success", content, err, calls)
+	}
+
+	_, _, err = client.GenerateResponse(context.Background(), "", "hello")
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected a RateLimitError, got %v", err)
+	}
+	if rateErr.Provider != "openai" || rateErr.RetryAfter != 120*time.Second {
+		t.Errorf("unexpected error %+v", rateErr)
+	}
+}
+
+func TestRateLimitRetryRespectsDeadline(t *testing.T) {
+	calls := 0
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := withRateLimitRetry(ctx, func() (*Completion, error) {
+		calls++
+		return nil, &RateLimitError{Provider: "test", RetryAfter: 5 * time.Second}
+	})
+	if _, ok := err.(*RateLimitError); !ok {
+		t.Fatalf("expected the rate limit error back, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("made %d attempts, want no wait past the deadline", calls)
+	}
+}
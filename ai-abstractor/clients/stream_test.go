@@ -0,0 +1,132 @@
+package clients
+
+import (
+	"ai-abstractor/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// writeEvents writes each line of a server-sent event stream
+func writeEvents(w http.ResponseWriter, lines ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s\n", line)
+	}
+}
+
+// collectDeltas returns a DeltaFunc appending to deltas
+func collectDeltas(deltas *[]string) DeltaFunc {
+	return func(delta string) error {
+		*deltas = append(*deltas, delta)
+		return nil
+	}
+}
+
+var capitalQuestion = []models.Message{{Role: models.RoleUser, Content: "Capital of France?"}}
+
+func TestAnthropicGenerateStreamAggregatesDeltas(t *testing.T) {
+	var sent anthropicRequest
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		sent = body
+		writeEvents(w,
+			"event: message_start",
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":7,"output_tokens":1}}}`,
+			"",
+			"event: ping",
+			`data: {"type":"ping"}`,
+			"",
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Par"}}`,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"is"}}`,
+			`data: {"type":"message_delta","usage":{"output_tokens":2}}`,
+			`data: {"type":"message_stop"}`,
+		)
+	})
+
+	var deltas []string
+	completion, err := client.GenerateStream(context.Background(), "Be brief", capitalQuestion, collectDeltas(&deltas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sent.Stream || sent.System != "Be brief" {
+		t.Errorf("sent %+v, want a streamed request with the system message", sent)
+	}
+	if !reflect.DeepEqual(deltas, []string{"Par", "is"}) {
+		t.Errorf("got deltas %q", deltas)
+	}
+	// The last output token count replaces the one from message_start
+	if completion.Content != "Paris" || completion.TokensUsed != 9 {
+		t.Errorf("got %q with %d tokens, want Paris with 9", completion.Content, completion.TokensUsed)
+	}
+}
+
+func TestAnthropicGenerateStreamReportsStreamErrors(t *testing.T) {
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		writeEvents(w,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Par"}}`,
+			`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+		)
+	})
+
+	var deltas []string
+	_, err := client.GenerateStream(context.Background(), "", capitalQuestion, collectDeltas(&deltas))
+	if err == nil || err.Error() != "anthropic error: Overloaded" {
+		t.Errorf("got %v, want the stream's error", err)
+	}
+}
+
+func TestAnthropicGenerateStreamStopsWhenDeltaFails(t *testing.T) {
+	client := newAnthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		writeEvents(w,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Par"}}`,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"is"}}`,
+		)
+	})
+
+	stop := errors.New("client went away")
+	calls := 0
+	_, err := client.GenerateStream(context.Background(), "", capitalQuestion, func(delta string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("got %v after %d deltas, want the delta error after the first", err, calls)
+	}
+}
+
+func TestOpenAIGenerateStreamAggregatesDeltas(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	client := newOpenAIServer(t, func(w http.ResponseWriter, body openai.ChatCompletionRequest) {
+		sent = body
+		writeEvents(w,
+			`data: {"choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+			"",
+			`data: {"choices":[{"index":0,"delta":{"content":"Par"}}]}`,
+			"",
+			`data: {"choices":[{"index":0,"delta":{"content":"is"}}]}`,
+			"",
+			"data: [DONE]",
+		)
+	})
+
+	var deltas []string
+	completion, err := client.GenerateStream(context.Background(), "Be brief", capitalQuestion, collectDeltas(&deltas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sent.Stream {
+		t.Error("expected a streamed request")
+	}
+	if !reflect.DeepEqual(deltas, []string{"Par", "is"}) || completion.Content != "Paris" {
+		t.Errorf("got deltas %q and content %q", deltas, completion.Content)
+	}
+	// Usage isn't reported, so it is estimated at four characters a token
+	if completion.PromptTokens != 7 || completion.CompletionTokens != 2 || completion.TokensUsed != 9 {
+		t.Errorf("got %+v, want 7 prompt and 2 completion tokens", completion)
+	}
+}
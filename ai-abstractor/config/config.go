@@ -15,12 +15,16 @@ type Config struct {
 	Anthropic    AnthropicConfig
 	App          AppConfig
 	Capabilities CapabilityConfig
+	Tokens       TokenConfig
+	Tracing      TracingConfig
 }
 
 type RedisConfig struct {
-	URL           string
-	RequestCh     string
-	ResponseCh    string
+	URL        string
+	Namespace  string
+	RequestCh  string
+	ResponseCh string
+	StreamCh   string // prefix of the per-request channels streamed tokens are published on
 }
 
 type OpenAIConfig struct {
@@ -44,8 +48,28 @@ type AppConfig struct {
 }
 
 type CapabilityConfig struct {
-	RefreshInterval time.Duration
-	Enabled         bool
+	RefreshInterval  time.Duration
+	Enabled          bool
+	SigningSecret    string        // shared HMAC secret for signing announcements; empty disables
+	AnnounceDebounce time.Duration // coalesces bursts of announcements; zero disables
+	InstanceID       string        // identifies this replica; set with a replica-specific request channel
+	InstanceWeight   int           // relative share of requests routed to this replica
+	ServiceVersion   string        // release announced for workflows that pin service versions
+}
+
+// TokenConfig controls token accounting and budgets. Budgets apply to usage within the
+// current window; zero leaves a budget unlimited.
+type TokenConfig struct {
+	AccountingEnabled bool
+	Window            time.Duration // usage resets at each window boundary
+	ProviderBudget    int           // tokens per provider per window
+	PrefixBudget      int           // tokens per correlation ID prefix (e.g. workflow) per window
+	PrefixSeparator   string        // ends the prefix of a correlation ID
+}
+
+// TracingConfig configures OpenTelemetry span export
+type TracingConfig struct {
+	OTLPEndpoint string // OTLP/HTTP collector URL; empty disables export
 }
 
 func Load() (*Config, error) {
@@ -86,12 +110,51 @@ func Load() (*Config, error) {
 		}
 	}
 
+	var announceDebounce time.Duration
+	if debounceStr := os.Getenv("CAPABILITY_ANNOUNCE_DEBOUNCE"); debounceStr != "" {
+		if debounce, err := time.ParseDuration(debounceStr); err == nil {
+			announceDebounce = debounce
+		}
+	}
+
+	instanceWeight := 1
+	if weightStr := os.Getenv("INSTANCE_WEIGHT"); weightStr != "" {
+		if w, err := strconv.Atoi(weightStr); err == nil && w > 0 {
+			instanceWeight = w
+		}
+	}
+
+	tokenWindow := 24 * time.Hour
+	if windowStr := os.Getenv("TOKEN_BUDGET_WINDOW"); windowStr != "" {
+		if window, err := time.ParseDuration(windowStr); err == nil && window > 0 {
+			tokenWindow = window
+		}
+	}
+
+	providerBudget := 0
+	if budgetStr := os.Getenv("TOKEN_BUDGET_PER_PROVIDER"); budgetStr != "" {
+		if b, err := strconv.Atoi(budgetStr); err == nil && b >= 0 {
+			providerBudget = b
+		}
+	}
+
+	prefixBudget := 0
+	if budgetStr := os.Getenv("TOKEN_BUDGET_PER_PREFIX"); budgetStr != "" {
+		if b, err := strconv.Atoi(budgetStr); err == nil && b >= 0 {
+			prefixBudget = b
+		}
+	}
+
+	redisConfig := RedisConfig{
+		URL:       getEnv("REDIS_URL", "redis://localhost:6379"),
+		Namespace: getEnv("REDIS_NAMESPACE", ""),
+	}
+	redisConfig.RequestCh = Namespaced(redisConfig.Namespace, getEnv("AI_REQUEST_CHANNEL", "ai-requests"))
+	redisConfig.ResponseCh = Namespaced(redisConfig.Namespace, getEnv("AI_RESPONSE_CHANNEL", "ai-responses"))
+	redisConfig.StreamCh = Namespaced(redisConfig.Namespace, getEnv("AI_STREAM_CHANNEL", "ai-responses-stream"))
+
 	config := &Config{
-		Redis: RedisConfig{
-			URL:        getEnv("REDIS_URL", "redis://localhost:6379"),
-			RequestCh:  getEnv("AI_REQUEST_CHANNEL", "ai-requests"),
-			ResponseCh: getEnv("AI_RESPONSE_CHANNEL", "ai-responses"),
-		},
+		Redis: redisConfig,
 		OpenAI: OpenAIConfig{
 			APIKey:      getEnv("OPENAI_API_KEY", ""),
 			BaseURL:     getEnv("OPENAI_BASE_URL", ""),
@@ -110,8 +173,23 @@ func Load() (*Config, error) {
 			Port:     port,
 		},
 		Capabilities: CapabilityConfig{
-			RefreshInterval: refreshInterval,
-			Enabled:         getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			RefreshInterval:  refreshInterval,
+			Enabled:          getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			SigningSecret:    getEnv("CAPABILITY_SIGNING_SECRET", ""),
+			AnnounceDebounce: announceDebounce,
+			InstanceID:       getEnv("INSTANCE_ID", ""),
+			InstanceWeight:   instanceWeight,
+			ServiceVersion:   getEnv("SERVICE_VERSION", ""),
+		},
+		Tokens: TokenConfig{
+			AccountingEnabled: getBoolEnv("TOKEN_ACCOUNTING_ENABLED", true),
+			Window:            tokenWindow,
+			ProviderBudget:    providerBudget,
+			PrefixBudget:      prefixBudget,
+			PrefixSeparator:   getEnv("TOKEN_PREFIX_SEPARATOR", ":"),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 		},
 	}
 
@@ -126,6 +204,14 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// Namespaced prefixes a Redis key or channel name with the environment namespace, if any
+func Namespaced(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
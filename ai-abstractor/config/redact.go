@@ -0,0 +1,86 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in exposed configuration
+const redactedValue = "REDACTED"
+
+// maskSecret hides a secret while still showing whether it is set
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL masks any password embedded in a connection URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if strings.Contains(raw, "@") {
+			return redactedValue
+		}
+		return raw
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	return u.String()
+}
+
+// Redacted returns the effective configuration with secrets masked, for operators
+// verifying what the service actually parsed
+func (c *Config) Redacted() map[string]interface{} {
+	return map[string]interface{}{
+		"redis": map[string]interface{}{
+			"url":              redactURL(c.Redis.URL),
+			"namespace":        c.Redis.Namespace,
+			"request_channel":  c.Redis.RequestCh,
+			"response_channel": c.Redis.ResponseCh,
+			"stream_channel":   c.Redis.StreamCh,
+		},
+		"openai": map[string]interface{}{
+			"api_key":     maskSecret(c.OpenAI.APIKey),
+			"base_url":    redactURL(c.OpenAI.BaseURL),
+			"model":       c.OpenAI.Model,
+			"max_tokens":  c.OpenAI.MaxTokens,
+			"temperature": c.OpenAI.Temperature,
+		},
+		"anthropic": map[string]interface{}{
+			"api_key":    maskSecret(c.Anthropic.APIKey),
+			"base_url":   redactURL(c.Anthropic.BaseURL),
+			"model":      c.Anthropic.Model,
+			"max_tokens": c.Anthropic.MaxTokens,
+		},
+		"app": map[string]interface{}{
+			"log_level": c.App.LogLevel,
+			"port":      c.App.Port,
+		},
+		"capabilities": map[string]interface{}{
+			"enabled":           c.Capabilities.Enabled,
+			"refresh_interval":  c.Capabilities.RefreshInterval.String(),
+			"signing_secret":    maskSecret(c.Capabilities.SigningSecret),
+			"announce_debounce": c.Capabilities.AnnounceDebounce.String(),
+			"instance_id":       c.Capabilities.InstanceID,
+			"instance_weight":   c.Capabilities.InstanceWeight,
+			"service_version":   c.Capabilities.ServiceVersion,
+		},
+		"tokens": map[string]interface{}{
+			"accounting_enabled": c.Tokens.AccountingEnabled,
+			"window":             c.Tokens.Window.String(),
+			"provider_budget":    c.Tokens.ProviderBudget,
+			"prefix_budget":      c.Tokens.PrefixBudget,
+			"prefix_separator":   c.Tokens.PrefixSeparator,
+		},
+		"tracing": map[string]interface{}{
+			"otlp_endpoint": redactURL(c.Tracing.OTLPEndpoint),
+		},
+	}
+}
@@ -3,18 +3,27 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"ai-abstractor/clients"
 	"ai-abstractor/models"
+	"ai-abstractor/tracing"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type AIHandler struct {
 	openAI    *clients.OpenAIClient
 	anthropic *clients.AnthropicClient
+
+	streamPublisher     StreamPublisher
+	streamChannelPrefix string
+
+	tokens *TokenAccountant
 }
 
 func NewAIHandler(openAI *clients.OpenAIClient, anthropic *clients.AnthropicClient) *AIHandler {
@@ -24,6 +33,11 @@ func NewAIHandler(openAI *clients.OpenAIClient, anthropic *clients.AnthropicClie
 	}
 }
 
+// SetTokenAccountant enables token accounting and budget enforcement
+func (h *AIHandler) SetTokenAccountant(tokens *TokenAccountant) {
+	h.tokens = tokens
+}
+
 func (h *AIHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 	var req models.AIRequest
 	if err := json.Unmarshal(data, &req); err != nil {
@@ -33,34 +47,65 @@ func (h *AIHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 		return responseData
 	}
 
+	// Continue the caller's trace, if it sent one
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, req.TraceContext), "ai.generate",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("ai.provider", req.Provider),
+			attribute.String("correlation_id", req.CorrelationID),
+			attribute.Bool("ai.stream", req.Stream),
+		))
+
 	logrus.WithFields(logrus.Fields{
 		"correlation_id":  req.CorrelationID,
 		"provider":        req.Provider,
 		"response_format": req.ResponseFormat,
+		"turns":           len(req.Messages),
+		"stream":          req.Stream,
 	}).Info("Processing AI request")
 
-	// Build the complete prompt with context and format instructions
-	fullPrompt := h.buildPrompt(req)
+	// Build the conversation, with context and format instructions on the final user turn
+	systemMessage, conversation, convErr := h.buildConversation(req)
+	if convErr == nil {
+		convErr = validateTools(req.Tools)
+	}
+
+	var budgetErr error
+	if convErr == nil && h.tokens != nil {
+		budgetErr = h.tokens.Check(ctx, req.Provider, req.CorrelationID, req.TokenBudget)
+	}
 	
 	var response *models.AIResponse
 
-	switch req.Provider {
-	case models.ProviderOpenAI:
+	switch {
+	case convErr != nil:
+		response = models.NewErrorResponse(req.CorrelationID, req.Provider, fmt.Sprintf("Invalid conversation: %v", convErr))
+	case budgetErr != nil:
+		logrus.WithError(budgetErr).WithField("correlation_id", req.CorrelationID).Warn("AI request rejected by token budget")
+		response = models.NewBudgetExceededResponse(req.CorrelationID, req.Provider, budgetErr.Error())
+	case req.Provider == models.ProviderOpenAI:
 		if h.openAI == nil {
 			response = models.NewErrorResponse(req.CorrelationID, req.Provider, "OpenAI client not configured")
 		} else {
-			response = h.handleOpenAIRequest(ctx, &req, fullPrompt)
+			response = h.handleOpenAIRequest(ctx, &req, systemMessage, conversation)
 		}
-	case models.ProviderAnthropic:
+	case req.Provider == models.ProviderAnthropic:
 		if h.anthropic == nil {
 			response = models.NewErrorResponse(req.CorrelationID, req.Provider, "Anthropic client not configured")
 		} else {
-			response = h.handleAnthropicRequest(ctx, &req, fullPrompt)
+			response = h.handleAnthropicRequest(ctx, &req, systemMessage, conversation)
 		}
 	default:
 		response = models.NewErrorResponse(req.CorrelationID, req.Provider, fmt.Sprintf("Unknown provider: %s", req.Provider))
 	}
 
+	span.SetAttributes(attribute.String("ai.model", response.Model), attribute.Int("ai.tokens_used", response.TokensUsed))
+	var spanErr error
+	if !response.Success {
+		spanErr = errors.New(response.Error)
+	}
+	tracing.End(span, spanErr)
+
 	responseData, err := json.Marshal(response)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to marshal AI response")
@@ -71,6 +116,72 @@ func (h *AIHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 	return responseData
 }
 
+// buildConversation assembles the turns sent to the provider. Prior messages are passed
+// through in order; system turns are folded into the system message. The prompt, when
+// set, becomes the final user turn, otherwise the last message must be a user turn.
+func (h *AIHandler) buildConversation(req models.AIRequest) (string, []models.Message, error) {
+	systemParts := []string{}
+	if req.SystemMessage != "" {
+		systemParts = append(systemParts, req.SystemMessage)
+	}
+
+	conversation := make([]models.Message, 0, len(req.Messages)+1)
+	for i, msg := range req.Messages {
+		switch msg.Role {
+		case models.RoleSystem:
+			systemParts = append(systemParts, msg.Content)
+		case models.RoleUser, models.RoleAssistant:
+			if msg.Content == "" {
+				return "", nil, fmt.Errorf("message %d has empty content", i)
+			}
+			conversation = append(conversation, msg)
+		default:
+			return "", nil, fmt.Errorf("message %d has unsupported role: %s", i, msg.Role)
+		}
+	}
+
+	systemMessage := strings.Join(systemParts, "\n\n")
+
+	if req.Prompt != "" {
+		conversation = append(conversation, models.Message{
+			Role:    models.RoleUser,
+			Content: h.buildPrompt(req),
+		})
+		return systemMessage, conversation, nil
+	}
+
+	if len(conversation) == 0 {
+		return "", nil, fmt.Errorf("either prompt or messages must be provided")
+	}
+
+	last := len(conversation) - 1
+	if conversation[last].Role != models.RoleUser {
+		return "", nil, fmt.Errorf("last message must be a user turn when no prompt is given")
+	}
+
+	// Apply context and format instructions to the final user turn
+	finalReq := req
+	finalReq.Prompt = conversation[last].Content
+	conversation[last].Content = h.buildPrompt(finalReq)
+
+	return systemMessage, conversation, nil
+}
+
+// validateTools checks tool declarations before they are sent to a provider
+func validateTools(tools []models.Tool) error {
+	seen := make(map[string]bool, len(tools))
+	for i, tool := range tools {
+		if tool.Name == "" {
+			return fmt.Errorf("tool %d has no name", i)
+		}
+		if seen[tool.Name] {
+			return fmt.Errorf("duplicate tool name: %s", tool.Name)
+		}
+		seen[tool.Name] = true
+	}
+	return nil
+}
+
 func (h *AIHandler) buildPrompt(req models.AIRequest) string {
 	var promptParts []string
 
@@ -110,40 +221,64 @@ func (h *AIHandler) getFormatInstruction(format string) string {
 	}
 }
 
-func (h *AIHandler) handleOpenAIRequest(ctx context.Context, req *models.AIRequest, fullPrompt string) *models.AIResponse {
-	content, tokens, err := h.openAI.GenerateResponse(ctx, req.SystemMessage, fullPrompt)
+func (h *AIHandler) handleOpenAIRequest(ctx context.Context, req *models.AIRequest, systemMessage string, conversation []models.Message) *models.AIResponse {
+	completion, err := h.generate(ctx, h.openAI, req, systemMessage, conversation)
+	if errors.Is(err, errStreamCancelled) {
+		logrus.WithField("correlation_id", req.CorrelationID).Info("OpenAI stream cancelled by client")
+		return models.NewCancelledResponse(req.CorrelationID, req.Provider, err.Error())
+	}
+	if rateErr, ok := err.(*clients.RateLimitError); ok {
+		logrus.WithError(err).Warn("OpenAI request rate limited")
+		return models.NewRateLimitedResponse(req.CorrelationID, req.Provider, fmt.Sprintf("OpenAI error: %v", err), rateErr.RetryAfter)
+	}
 	if err != nil {
 		logrus.WithError(err).Error("OpenAI request failed")
 		return models.NewErrorResponse(req.CorrelationID, req.Provider, fmt.Sprintf("OpenAI error: %v", err))
 	}
 
-	// Validate response format if needed
-	if !h.validateResponseFormat(content, req.ResponseFormat) {
-		logrus.WithFields(logrus.Fields{
-			"format": req.ResponseFormat,
-			"content_preview": content[:min(100, len(content))],
-		}).Warn("Response format validation failed")
+	if h.tokens != nil {
+		h.tokens.Record(ctx, req.Provider, req.CorrelationID, completion)
 	}
 
-	return models.NewSuccessResponse(req.CorrelationID, req.Provider, "openai", content, req.ResponseFormat, tokens)
+	return h.buildSuccessResponse(req, "openai", completion)
 }
 
-func (h *AIHandler) handleAnthropicRequest(ctx context.Context, req *models.AIRequest, fullPrompt string) *models.AIResponse {
-	content, tokens, err := h.anthropic.GenerateResponse(ctx, req.SystemMessage, fullPrompt)
+func (h *AIHandler) handleAnthropicRequest(ctx context.Context, req *models.AIRequest, systemMessage string, conversation []models.Message) *models.AIResponse {
+	completion, err := h.generate(ctx, h.anthropic, req, systemMessage, conversation)
+	if errors.Is(err, errStreamCancelled) {
+		logrus.WithField("correlation_id", req.CorrelationID).Info("Anthropic stream cancelled by client")
+		return models.NewCancelledResponse(req.CorrelationID, req.Provider, err.Error())
+	}
+	if rateErr, ok := err.(*clients.RateLimitError); ok {
+		logrus.WithError(err).Warn("Anthropic request rate limited")
+		return models.NewRateLimitedResponse(req.CorrelationID, req.Provider, fmt.Sprintf("Anthropic error: %v", err), rateErr.RetryAfter)
+	}
 	if err != nil {
 		logrus.WithError(err).Error("Anthropic request failed")
 		return models.NewErrorResponse(req.CorrelationID, req.Provider, fmt.Sprintf("Anthropic error: %v", err))
 	}
 
-	// Validate response format if needed
-	if !h.validateResponseFormat(content, req.ResponseFormat) {
+	if h.tokens != nil {
+		h.tokens.Record(ctx, req.Provider, req.CorrelationID, completion)
+	}
+
+	return h.buildSuccessResponse(req, "anthropic", completion)
+}
+
+// buildSuccessResponse validates the content format and attaches any requested tool calls.
+// A response made only of tool calls carries no content to validate.
+func (h *AIHandler) buildSuccessResponse(req *models.AIRequest, model string, completion *clients.Completion) *models.AIResponse {
+	content := completion.Content
+	if (content != "" || len(completion.ToolCalls) == 0) && !h.validateResponseFormat(content, req.ResponseFormat) {
 		logrus.WithFields(logrus.Fields{
-			"format": req.ResponseFormat,
+			"format":          req.ResponseFormat,
 			"content_preview": content[:min(100, len(content))],
 		}).Warn("Response format validation failed")
 	}
 
-	return models.NewSuccessResponse(req.CorrelationID, req.Provider, "anthropic", content, req.ResponseFormat, tokens)
+	response := models.NewSuccessResponse(req.CorrelationID, req.Provider, model, content, req.ResponseFormat, completion.TokensUsed)
+	response.ToolCalls = completion.ToolCalls
+	return response
 }
 
 func (h *AIHandler) validateResponseFormat(content, format string) bool {
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatusServer exposes service health and the effective, redacted configuration
+type StatusServer struct {
+	service   string
+	config    map[string]interface{}
+	startTime time.Time
+	server    *http.Server
+	tokens    *TokenAccountant
+}
+
+// NewStatusServer creates a status server; config must already have secrets masked
+func NewStatusServer(service string, config map[string]interface{}) *StatusServer {
+	return &StatusServer{
+		service:   service,
+		config:    config,
+		startTime: time.Now(),
+	}
+}
+
+// SetTokenAccountant exposes token usage totals on /usage
+func (s *StatusServer) SetTokenAccountant(tokens *TokenAccountant) {
+	s.tokens = tokens
+}
+
+// Start serves /health, /config and /usage until the context is cancelled
+func (s *StatusServer) Start(ctx context.Context, port int) error {
+	router := http.NewServeMux()
+	router.HandleFunc("/health", s.healthHandler)
+	router.HandleFunc("/config", s.configHandler)
+	router.HandleFunc("/usage", s.usageHandler)
+
+	s.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", port),
+		Handler:      router,
+		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+	}
+
+	logrus.WithField("port", port).Info("Starting status server")
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.server.Shutdown(shutdownCtx)
+	}()
+
+	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("status server error: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StatusServer) healthHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"status":    "healthy",
+		"service":   s.service,
+		"timestamp": time.Now(),
+		"uptime":    time.Since(s.startTime).String(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *StatusServer) configHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.config)
+}
+
+// usageHandler reports token usage and budgets for the current accounting window
+func (s *StatusServer) usageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.tokens == nil {
+		http.Error(w, "Token accounting disabled", http.StatusNotFound)
+		return
+	}
+
+	totals, err := s.tokens.Totals(r.Context())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read token usage")
+		http.Error(w, "Failed to read token usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(totals)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"ai-abstractor/clients"
+	"ai-abstractor/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errStreamCancelled is returned when every subscriber of a stream has gone away
+var errStreamCancelled = errors.New("stream cancelled by client")
+
+// StreamPublisher publishes stream chunks and reports how many subscribers received each
+type StreamPublisher interface {
+	PublishWithReceivers(ctx context.Context, channel string, data []byte) (int64, error)
+}
+
+// generator is implemented by the provider clients
+type generator interface {
+	GenerateWithTools(ctx context.Context, systemMessage string, conversation []models.Message, tools []models.Tool) (*clients.Completion, error)
+	GenerateStream(ctx context.Context, systemMessage string, conversation []models.Message, onDelta clients.DeltaFunc) (*clients.Completion, error)
+}
+
+// SetStreamPublisher enables streaming; chunks for a request are published on
+// channelPrefix:<correlation_id>
+func (h *AIHandler) SetStreamPublisher(publisher StreamPublisher, channelPrefix string) {
+	h.streamPublisher = publisher
+	h.streamChannelPrefix = channelPrefix
+}
+
+// generate runs a request against a provider, streaming it when asked to and possible.
+// Tool calls are not streamed; such requests are published as a single chunk.
+func (h *AIHandler) generate(ctx context.Context, provider generator, req *models.AIRequest, systemMessage string, conversation []models.Message) (*clients.Completion, error) {
+	if !req.Stream || h.streamPublisher == nil {
+		return provider.GenerateWithTools(ctx, systemMessage, conversation, req.Tools)
+	}
+
+	stream := &streamWriter{
+		publisher:     h.streamPublisher,
+		channel:       h.streamChannelPrefix + ":" + req.CorrelationID,
+		correlationID: req.CorrelationID,
+	}
+
+	var completion *clients.Completion
+	var err error
+	if len(req.Tools) > 0 {
+		completion, err = provider.GenerateWithTools(ctx, systemMessage, conversation, req.Tools)
+		if err == nil && completion.Content != "" {
+			err = stream.write(ctx, completion.Content)
+		}
+	} else {
+		completion, err = provider.GenerateStream(ctx, systemMessage, conversation, func(delta string) error {
+			return stream.write(ctx, delta)
+		})
+	}
+
+	stream.finish(ctx, err)
+	return completion, err
+}
+
+// streamWriter publishes the chunks of one streamed response
+type streamWriter struct {
+	publisher     StreamPublisher
+	channel       string
+	correlationID string
+	index         int
+	subscribed    bool // a subscriber has received at least one chunk
+}
+
+// write publishes a delta. Once a subscriber has been seen, finding none left means the
+// client went away and the generation is stopped.
+func (w *streamWriter) write(ctx context.Context, delta string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	receivers, err := w.publish(ctx, models.StreamChunk{Delta: delta})
+	if err != nil {
+		// The aggregated response is still delivered, so keep generating
+		logrus.WithError(err).WithField("channel", w.channel).Warn("Failed to publish stream chunk")
+		return nil
+	}
+
+	if receivers > 0 {
+		w.subscribed = true
+	} else if w.subscribed {
+		return errStreamCancelled
+	}
+	return nil
+}
+
+// finish publishes the final chunk, carrying the error if the stream failed
+func (w *streamWriter) finish(ctx context.Context, streamErr error) {
+	if errors.Is(streamErr, errStreamCancelled) {
+		return // nobody is listening
+	}
+
+	chunk := models.StreamChunk{Done: true}
+	if streamErr != nil {
+		chunk.Error = streamErr.Error()
+	}
+
+	// The request context may already be cancelled; the final chunk is still worth sending
+	if _, err := w.publish(context.WithoutCancel(ctx), chunk); err != nil {
+		logrus.WithError(err).WithField("channel", w.channel).Warn("Failed to publish final stream chunk")
+	}
+}
+
+func (w *streamWriter) publish(ctx context.Context, chunk models.StreamChunk) (int64, error) {
+	chunk.CorrelationID = w.correlationID
+	chunk.Index = w.index
+	w.index++
+
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return 0, err
+	}
+	return w.publisher.PublishWithReceivers(ctx, w.channel, data)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"ai-abstractor/clients"
+	"ai-abstractor/models"
+)
+
+// recordingPublisher records every chunk published; receivers reports how many
+// subscribers got the chunk with the given index
+type recordingPublisher struct {
+	channels  []string
+	chunks    []models.StreamChunk
+	receivers func(index int) int64
+}
+
+func (p *recordingPublisher) PublishWithReceivers(ctx context.Context, channel string, data []byte) (int64, error) {
+	var chunk models.StreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return 0, err
+	}
+	p.channels = append(p.channels, channel)
+	p.chunks = append(p.chunks, chunk)
+	if p.receivers == nil {
+		return 1, nil
+	}
+	return p.receivers(chunk.Index), nil
+}
+
+// scriptedGenerator streams deltas, then returns err
+type scriptedGenerator struct {
+	deltas   []string
+	err      error
+	streamed bool
+}
+
+func (g *scriptedGenerator) GenerateWithTools(ctx context.Context, systemMessage string, conversation []models.Message, tools []models.Tool) (*clients.Completion, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	content := ""
+	for _, delta := range g.deltas {
+		content += delta
+	}
+	return &clients.Completion{Content: content}, nil
+}
+
+func (g *scriptedGenerator) GenerateStream(ctx context.Context, systemMessage string, conversation []models.Message, onDelta clients.DeltaFunc) (*clients.Completion, error) {
+	g.streamed = true
+	content := ""
+	for _, delta := range g.deltas {
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+		content += delta
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &clients.Completion{Content: content}, nil
+}
+
+func streamingHandler(publisher StreamPublisher) *AIHandler {
+	handler := NewAIHandler(nil, nil)
+	handler.SetStreamPublisher(publisher, "ai:stream")
+	return handler
+}
+
+func TestGenerateStreamsChunksInOrder(t *testing.T) {
+	publisher := &recordingPublisher{}
+	provider := &scriptedGenerator{deltas: []string{"Par", "is"}}
+	request := &models.AIRequest{CorrelationID: "req-1", Stream: true}
+
+	completion, err := streamingHandler(publisher).generate(context.Background(), provider, request, "", nil)
+	if err != nil || completion.Content != "Paris" {
+		t.Fatalf("got %v, %v, want the aggregated completion", completion, err)
+	}
+
+	want := []models.StreamChunk{
+		{CorrelationID: "req-1", Index: 0, Delta: "Par"},
+		{CorrelationID: "req-1", Index: 1, Delta: "is"},
+		{CorrelationID: "req-1", Index: 2, Done: true},
+	}
+	if !reflect.DeepEqual(publisher.chunks, want) {
+		t.Errorf("got chunks %+v, want %+v", publisher.chunks, want)
+	}
+	for _, channel := range publisher.channels {
+		if channel != "ai:stream:req-1" {
+			t.Errorf("published on %s, want the request's channel", channel)
+		}
+	}
+}
+
+func TestGenerateStopsWhenSubscribersLeave(t *testing.T) {
+	// The subscriber receives the first chunk, then goes away
+	publisher := &recordingPublisher{receivers: func(index int) int64 {
+		if index == 0 {
+			return 1
+		}
+		return 0
+	}}
+	provider := &scriptedGenerator{deltas: []string{"Par", "is", " is the capital"}}
+	request := &models.AIRequest{CorrelationID: "req-1", Stream: true}
+
+	_, err := streamingHandler(publisher).generate(context.Background(), provider, request, "", nil)
+	if !errors.Is(err, errStreamCancelled) {
+		t.Fatalf("got %v, want the stream cancelled", err)
+	}
+	if len(publisher.chunks) != 2 {
+		t.Errorf("published %d chunks, want the generation stopped without a final chunk", len(publisher.chunks))
+	}
+}
+
+func TestGenerateWaitsForFirstSubscriber(t *testing.T) {
+	// Nobody has subscribed yet; the stream keeps going until someone has been seen
+	publisher := &recordingPublisher{receivers: func(index int) int64 { return 0 }}
+	provider := &scriptedGenerator{deltas: []string{"Par", "is"}}
+	request := &models.AIRequest{CorrelationID: "req-1", Stream: true}
+
+	if _, err := streamingHandler(publisher).generate(context.Background(), provider, request, "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publisher.chunks) != 3 {
+		t.Errorf("published %d chunks, want every delta and the final chunk", len(publisher.chunks))
+	}
+}
+
+func TestGenerateSendsFailureInFinalChunk(t *testing.T) {
+	publisher := &recordingPublisher{}
+	provider := &scriptedGenerator{deltas: []string{"Par"}, err: errors.New("anthropic error: Overloaded")}
+	request := &models.AIRequest{CorrelationID: "req-1", Stream: true}
+
+	if _, err := streamingHandler(publisher).generate(context.Background(), provider, request, "", nil); err == nil {
+		t.Fatal("expected the provider's error")
+	}
+	last := publisher.chunks[len(publisher.chunks)-1]
+	if !last.Done || last.Error != "anthropic error: Overloaded" {
+		t.Errorf("got final chunk %+v, want it to carry the error", last)
+	}
+}
+
+func TestGeneratePublishesToolRequestsAsOneChunk(t *testing.T) {
+	publisher := &recordingPublisher{}
+	provider := &scriptedGenerator{deltas: []string{"Par", "is"}}
+	request := &models.AIRequest{CorrelationID: "req-1", Stream: true, Tools: []models.Tool{{Name: "lookup"}}}
+
+	if _, err := streamingHandler(publisher).generate(context.Background(), provider, request, "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.streamed {
+		t.Error("expected a request with tools not to be streamed from the provider")
+	}
+	if len(publisher.chunks) != 2 || publisher.chunks[0].Delta != "Paris" || !publisher.chunks[1].Done {
+		t.Errorf("got chunks %+v, want the whole content then the final chunk", publisher.chunks)
+	}
+}
+
+func TestGenerateWithoutStreamingPublishesNothing(t *testing.T) {
+	publisher := &recordingPublisher{}
+	provider := &scriptedGenerator{deltas: []string{"Par", "is"}}
+
+	if _, err := streamingHandler(publisher).generate(context.Background(), provider, &models.AIRequest{CorrelationID: "req-1"}, "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.streamed || len(publisher.chunks) != 0 {
+		t.Errorf("expected an unstreamed request, got %d chunks", len(publisher.chunks))
+	}
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-abstractor/clients"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTokenBudgetExceeded is returned when a request would run over a token budget
+var ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+
+const (
+	usageKindProvider = "provider"
+	usageKindPrefix   = "prefix"
+
+	usageFieldPrompt     = "prompt"
+	usageFieldCompletion = "completion"
+	usageFieldTotal      = "total"
+)
+
+// TokenUsage is the tokens consumed within one window
+type TokenUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// TokenTotals reports usage in the current window per provider and per correlation prefix
+type TokenTotals struct {
+	WindowStart time.Time             `json:"window_start"`
+	WindowEnd   time.Time             `json:"window_end"`
+	Providers   map[string]TokenUsage `json:"providers"`
+	Prefixes    map[string]TokenUsage `json:"prefixes"`
+	Budgets     map[string]int        `json:"budgets"`
+}
+
+// TokenAccountant accumulates token usage in Redis and enforces budgets. Usage is
+// bucketed into fixed windows so budgets roll over at each window boundary.
+//
+// Correlation IDs are grouped by the part before the separator, so callers that
+// prefix IDs with a workflow ID share one budget. IDs without the separator are
+// accounted on their own.
+type TokenAccountant struct {
+	client    *redis.Client
+	keyPrefix string
+	window    time.Duration
+	separator string
+	now       func() time.Time
+
+	providerBudget int
+	prefixBudget   int
+}
+
+// NewTokenAccountant creates an accountant storing usage under keyPrefix
+func NewTokenAccountant(client *redis.Client, keyPrefix string, window time.Duration, separator string) *TokenAccountant {
+	return &TokenAccountant{
+		client:    client,
+		keyPrefix: keyPrefix,
+		window:    window,
+		separator: separator,
+		now:       time.Now,
+	}
+}
+
+// SetBudgets sets the per-provider and per-prefix budgets for each window; zero disables
+func (a *TokenAccountant) SetBudgets(providerBudget, prefixBudget int) {
+	a.providerBudget = providerBudget
+	a.prefixBudget = prefixBudget
+}
+
+// Prefix returns the accounting group of a correlation ID
+func (a *TokenAccountant) Prefix(correlationID string) string {
+	if a.separator != "" {
+		if idx := strings.Index(correlationID, a.separator); idx > 0 {
+			return correlationID[:idx]
+		}
+	}
+	return correlationID
+}
+
+// Check rejects a request whose provider or prefix has used up its budget in the
+// current window. requestBudget, when positive, can only tighten the configured prefix
+// budget, never raise it. Accounting failures are logged and the request is allowed.
+func (a *TokenAccountant) Check(ctx context.Context, provider, correlationID string, requestBudget int) error {
+	prefixBudget := a.prefixBudget
+	if requestBudget > 0 && (prefixBudget <= 0 || requestBudget < prefixBudget) {
+		prefixBudget = requestBudget
+	}
+	if a.providerBudget <= 0 && prefixBudget <= 0 {
+		return nil
+	}
+
+	start := a.windowStart(a.now())
+	prefix := a.Prefix(correlationID)
+
+	pipe := a.client.Pipeline()
+	providerUsed := pipe.HGet(ctx, a.usageKey(start, usageKindProvider, provider), usageFieldTotal)
+	prefixUsed := pipe.HGet(ctx, a.usageKey(start, usageKindPrefix, prefix), usageFieldTotal)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		logrus.WithError(err).Warn("Failed to read token usage, skipping budget check")
+		return nil
+	}
+
+	resetAt := start.Add(a.window)
+
+	if used, _ := providerUsed.Int64(); a.providerBudget > 0 && used >= int64(a.providerBudget) {
+		return fmt.Errorf("%w: provider %s used %d of %d tokens, resets at %s",
+			ErrTokenBudgetExceeded, provider, used, a.providerBudget, resetAt.Format(time.RFC3339))
+	}
+
+	if used, _ := prefixUsed.Int64(); prefixBudget > 0 && used >= int64(prefixBudget) {
+		return fmt.Errorf("%w: %s used %d of %d tokens, resets at %s",
+			ErrTokenBudgetExceeded, prefix, used, prefixBudget, resetAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// Record adds a completion's usage to its provider and prefix in the current window
+func (a *TokenAccountant) Record(ctx context.Context, provider, correlationID string, completion *clients.Completion) {
+	prompt, output := int64(completion.PromptTokens), int64(completion.CompletionTokens)
+	total := int64(completion.TokensUsed)
+	if total == 0 {
+		total = prompt + output
+	}
+	if total == 0 {
+		return
+	}
+
+	start := a.windowStart(a.now())
+	keys := []string{
+		a.usageKey(start, usageKindProvider, provider),
+		a.usageKey(start, usageKindPrefix, a.Prefix(correlationID)),
+	}
+
+	// Keys outlive their window briefly so the previous window can still be inspected
+	pipe := a.client.TxPipeline()
+	for _, key := range keys {
+		pipe.HIncrBy(ctx, key, usageFieldPrompt, prompt)
+		pipe.HIncrBy(ctx, key, usageFieldCompletion, output)
+		pipe.HIncrBy(ctx, key, usageFieldTotal, total)
+		pipe.Expire(ctx, key, 2*a.window)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"provider":       provider,
+			"correlation_id": correlationID,
+			"tokens":         total,
+		}).Warn("Failed to record token usage")
+	}
+}
+
+// Totals reads usage for the current window
+func (a *TokenAccountant) Totals(ctx context.Context) (*TokenTotals, error) {
+	start := a.windowStart(a.now())
+	totals := &TokenTotals{
+		WindowStart: start,
+		WindowEnd:   start.Add(a.window),
+		Providers:   make(map[string]TokenUsage),
+		Prefixes:    make(map[string]TokenUsage),
+		Budgets: map[string]int{
+			"per_provider": a.providerBudget,
+			"per_prefix":   a.prefixBudget,
+		},
+	}
+
+	windowKey := a.windowKey(start)
+	iter := a.client.Scan(ctx, 0, windowKey+":*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		fields, err := a.client.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token usage %s: %w", key, err)
+		}
+
+		usage := parseTokenUsage(fields)
+		rest := strings.TrimPrefix(key, windowKey+":")
+		switch {
+		case strings.HasPrefix(rest, usageKindProvider+":"):
+			totals.Providers[strings.TrimPrefix(rest, usageKindProvider+":")] = usage
+		case strings.HasPrefix(rest, usageKindPrefix+":"):
+			totals.Prefixes[strings.TrimPrefix(rest, usageKindPrefix+":")] = usage
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan token usage: %w", err)
+	}
+
+	return totals, nil
+}
+
+func (a *TokenAccountant) windowStart(now time.Time) time.Time {
+	return now.UTC().Truncate(a.window)
+}
+
+func (a *TokenAccountant) windowKey(start time.Time) string {
+	return fmt.Sprintf("%s:%d", a.keyPrefix, start.Unix())
+}
+
+func (a *TokenAccountant) usageKey(start time.Time, kind, name string) string {
+	return a.windowKey(start) + ":" + kind + ":" + name
+}
+
+func parseTokenUsage(fields map[string]string) TokenUsage {
+	parse := func(field string) int64 {
+		value, _ := strconv.ParseInt(fields[field], 10, 64)
+		return value
+	}
+	return TokenUsage{
+		PromptTokens:     parse(usageFieldPrompt),
+		CompletionTokens: parse(usageFieldCompletion),
+		TotalTokens:      parse(usageFieldTotal),
+	}
+}
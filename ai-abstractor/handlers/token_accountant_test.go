@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai-abstractor/clients"
+	"ai-abstractor/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestAccountant returns an accountant on an in-process Redis with an hourly window
+// and a clock the test controls
+func newTestAccountant(t *testing.T, now *time.Time) *TokenAccountant {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	accountant := NewTokenAccountant(client, "ai-tokens", time.Hour, ":")
+	accountant.now = func() time.Time { return *now }
+	return accountant
+}
+
+func record(accountant *TokenAccountant, provider, correlationID string, tokens int) {
+	accountant.Record(context.Background(), provider, correlationID, &clients.Completion{TokensUsed: tokens})
+}
+
+func TestTokenAccountantEnforcesBudgets(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)
+	accountant := newTestAccountant(t, &now)
+	accountant.SetBudgets(1000, 100)
+	ctx := context.Background()
+
+	// Requests of one workflow add up under its prefix
+	record(accountant, "openai", "exec-1:a", 60)
+	if err := accountant.Check(ctx, "openai", "exec-1:c", 0); err != nil {
+		t.Fatalf("under budget: got %v, want allowed", err)
+	}
+	record(accountant, "openai", "exec-1:b", 40)
+
+	for _, tc := range []struct {
+		name          string
+		provider      string
+		correlationID string
+		requestBudget int
+		wantExceeded  bool
+	}{
+		{"prefix budget used up", "openai", "exec-1:d", 0, true},
+		{"request cannot raise the prefix budget", "openai", "exec-1:d", 5000, true},
+		{"other workflow unaffected", "openai", "exec-2:a", 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := accountant.Check(ctx, tc.provider, tc.correlationID, tc.requestBudget)
+			if got := errors.Is(err, ErrTokenBudgetExceeded); got != tc.wantExceeded {
+				t.Errorf("got %v, want exceeded %v", err, tc.wantExceeded)
+			}
+		})
+	}
+
+	record(accountant, "openai", "exec-2:a", 30)
+	if err := accountant.Check(ctx, "openai", "exec-2:b", 20); !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Errorf("request budget below usage: got %v, want exceeded", err)
+	}
+	if err := accountant.Check(ctx, "openai", "exec-2:b", 50); err != nil {
+		t.Errorf("request budget above usage: got %v, want allowed", err)
+	}
+
+	record(accountant, "anthropic", "exec-3", 1000)
+	if err := accountant.Check(ctx, "anthropic", "exec-4", 0); !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Errorf("provider budget used up: got %v, want exceeded", err)
+	}
+}
+
+func TestTokenAccountantRollsOverEachWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)
+	accountant := newTestAccountant(t, &now)
+	accountant.SetBudgets(0, 100)
+	ctx := context.Background()
+
+	record(accountant, "openai", "exec-1:a", 100)
+	if err := accountant.Check(ctx, "openai", "exec-1:b", 0); !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Fatalf("got %v, want exceeded within the window", err)
+	}
+
+	// Still the same window until the hour boundary
+	now = time.Date(2026, 1, 1, 10, 59, 59, 0, time.UTC)
+	if err := accountant.Check(ctx, "openai", "exec-1:b", 0); !errors.Is(err, ErrTokenBudgetExceeded) {
+		t.Fatalf("got %v, want exceeded until the window ends", err)
+	}
+
+	now = time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)
+	if err := accountant.Check(ctx, "openai", "exec-1:b", 0); err != nil {
+		t.Fatalf("got %v, want the budget reset in the next window", err)
+	}
+
+	totals, err := accountant.Totals(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !totals.WindowStart.Equal(now) || len(totals.Prefixes) != 0 {
+		t.Errorf("got totals %+v, want an empty window starting at %s", totals, now)
+	}
+}
+
+func TestHandleRequestRejectsOverBudget(t *testing.T) {
+	now := time.Now()
+	accountant := newTestAccountant(t, &now)
+	accountant.SetBudgets(0, 10)
+	record(accountant, models.ProviderOpenAI, "exec-1:a", 10)
+
+	handler := NewAIHandler(nil, nil)
+	handler.SetTokenAccountant(accountant)
+	request, _ := json.Marshal(models.AIRequest{
+		CorrelationID: "exec-1:b",
+		Provider:      models.ProviderOpenAI,
+		Prompt:        "summarize",
+	})
+
+	var response models.AIResponse
+	if err := json.Unmarshal(handler.HandleRequest(context.Background(), request), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || response.ErrorCode != models.ErrorCodeBudgetExceeded {
+		t.Errorf("got %+v, want a budget exceeded error", response)
+	}
+}
+
+func TestStreamedOpenAIRequestCountsAgainstBudget(t *testing.T) {
+	// OpenAI streams don't report usage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"A forty character long streamed answer."}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	openAI, err := clients.NewOpenAIClient("key", server.URL, "gpt-test", 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	accountant := newTestAccountant(t, &now)
+	accountant.SetBudgets(0, 10)
+	handler := NewAIHandler(openAI, nil)
+	handler.SetTokenAccountant(accountant)
+	handler.SetStreamPublisher(&recordingPublisher{}, "ai:stream")
+
+	send := func(correlationID string) models.AIResponse {
+		request, _ := json.Marshal(models.AIRequest{
+			CorrelationID: correlationID,
+			Provider:      models.ProviderOpenAI,
+			Prompt:        "summarize",
+			Stream:        true,
+		})
+		var response models.AIResponse
+		if err := json.Unmarshal(handler.HandleRequest(context.Background(), request), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	if response := send("exec-1:a"); !response.Success || response.TokensUsed == 0 {
+		t.Fatalf("got %+v, want a streamed answer with estimated usage", response)
+	}
+	totals, err := accountant.Totals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used := totals.Prefixes["exec-1"].TotalTokens; used < 10 {
+		t.Errorf("got %d tokens recorded for the stream, want its estimated usage", used)
+	}
+	if response := send("exec-1:b"); response.Success || response.ErrorCode != models.ErrorCodeBudgetExceeded {
+		t.Errorf("got %+v, want the next request rejected by the budget", response)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"ai-abstractor/capabilities"
 	"ai-abstractor/clients"
 	"ai-abstractor/config"
 	"ai-abstractor/handlers"
+	"ai-abstractor/tracing"
 
 	"github.com/sirupsen/logrus"
 )
@@ -33,6 +35,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Setup tracing; spans are only exported when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(ctx, "ai-abstractor", cfg.Tracing.OTLPEndpoint)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to setup tracing")
+	}
+
 	var wg sync.WaitGroup
 
 	// Initialize OpenAI client if API key is provided
@@ -86,6 +94,20 @@ func main() {
 
 	// Initialize AI handler
 	aiHandler := handlers.NewAIHandler(openAIClient, anthropicClient)
+	aiHandler.SetStreamPublisher(redisClient, cfg.Redis.StreamCh)
+
+	// Account token usage and enforce budgets if enabled
+	var tokenAccountant *handlers.TokenAccountant
+	if cfg.Tokens.AccountingEnabled {
+		tokenAccountant = handlers.NewTokenAccountant(
+			redisClient.GetClient(),
+			config.Namespaced(cfg.Redis.Namespace, "ai-tokens"),
+			cfg.Tokens.Window,
+			cfg.Tokens.PrefixSeparator,
+		)
+		tokenAccountant.SetBudgets(cfg.Tokens.ProviderBudget, cfg.Tokens.PrefixBudget)
+		aiHandler.SetTokenAccountant(tokenAccountant)
+	}
 
 	// Initialize capability manager if enabled
 	var capabilityManager *capabilities.CapabilityManager
@@ -95,7 +117,14 @@ func main() {
 			capabilities.GetAIAbstractorCapabilities(),
 			redisClient.GetClient(),
 			cfg.Capabilities.RefreshInterval,
+			cfg.Redis.Namespace,
 		)
+		capabilityManager.SetSigningSecret(cfg.Capabilities.SigningSecret)
+		capabilityManager.SetDebounceWindow(cfg.Capabilities.AnnounceDebounce)
+		capabilityManager.SetServiceVersion(cfg.Capabilities.ServiceVersion)
+		if cfg.Capabilities.InstanceID != "" {
+			capabilityManager.SetInstance(cfg.Capabilities.InstanceID, cfg.Redis.RequestCh, cfg.Capabilities.InstanceWeight)
+		}
 
 		// Start capability manager
 		if err := capabilityManager.Start(ctx); err != nil {
@@ -105,6 +134,19 @@ func main() {
 		}
 	}
 
+	// Start status server exposing health and redacted configuration
+	statusServer := handlers.NewStatusServer("ai-abstractor", cfg.Redacted())
+	if tokenAccountant != nil {
+		statusServer.SetTokenAccountant(tokenAccountant)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := statusServer.Start(ctx, cfg.App.Port); err != nil {
+			logrus.WithError(err).Error("Status server failed")
+		}
+	}()
+
 	// Start Redis message listener
 	wg.Add(1)
 	go func() {
@@ -142,5 +184,12 @@ func main() {
 	cancel()
 	wg.Wait()
 
+	// Flush spans still buffered for export
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logrus.WithError(err).Warn("Failed to flush traces")
+	}
+
 	logrus.Info("AI Abstractor service stopped")
 }
\ No newline at end of file
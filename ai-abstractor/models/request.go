@@ -1,17 +1,48 @@
 package models
 
 type AIRequest struct {
-	Provider      string   `json:"provider"`      // "openai" or "anthropic"
-	CorrelationID string   `json:"correlation_id"`
-	Prompt        string   `json:"prompt"`
-	SystemMessage string   `json:"system_message,omitempty"`
-	Context       []string `json:"context,omitempty"`
-	ResponseFormat string  `json:"response_format"` // "json", "yaml", "text", "markdown"
-	Model         string   `json:"model,omitempty"`
-	MaxTokens     int      `json:"max_tokens,omitempty"`
-	Temperature   float32  `json:"temperature,omitempty"`
+	Provider       string            `json:"provider"` // "openai" or "anthropic"
+	CorrelationID  string            `json:"correlation_id"`
+	Prompt         string            `json:"prompt"`
+	Messages       []Message         `json:"messages,omitempty"` // prior conversation turns, oldest first
+	SystemMessage  string            `json:"system_message,omitempty"`
+	Context        []string          `json:"context,omitempty"`
+	ResponseFormat string            `json:"response_format"` // "json", "yaml", "text", "markdown"
+	Model          string            `json:"model,omitempty"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Temperature    float32           `json:"temperature,omitempty"`
+	Tools          []Tool            `json:"tools,omitempty"`         // functions the model may ask to call
+	Stream         bool              `json:"stream,omitempty"`        // publish tokens as they arrive on the stream channel
+	TokenBudget    int               `json:"token_budget,omitempty"`  // tightens the budget of this request's correlation prefix
+	TraceContext   map[string]string `json:"trace_context,omitempty"` // W3C trace context of the caller's span
 }
 
+// Tool declares a function the model may request a call to
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // JSON Schema of the arguments
+}
+
+// ToolCall is a model's request to invoke one of the declared tools
+type ToolCall struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// Message is a single turn in a multi-step conversation
+type Message struct {
+	Role    string `json:"role"` // "user", "assistant" or "system"
+	Content string `json:"content"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleSystem    = "system"
+)
+
 const (
 	ProviderOpenAI    = "openai"
 	ProviderAnthropic = "anthropic"
@@ -3,15 +3,34 @@ package models
 import "time"
 
 type AIResponse struct {
-	CorrelationID  string    `json:"correlation_id"`
-	Success        bool      `json:"success"`
-	Content        string    `json:"content,omitempty"`
-	Error          string    `json:"error,omitempty"`
-	Timestamp      time.Time `json:"timestamp"`
-	Provider       string    `json:"provider"`
-	Model          string    `json:"model"`
-	TokensUsed     int       `json:"tokens_used,omitempty"`
-	ResponseFormat string    `json:"response_format"`
+	CorrelationID  string     `json:"correlation_id"`
+	Success        bool       `json:"success"`
+	Content        string     `json:"content,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Timestamp      time.Time  `json:"timestamp"`
+	Provider       string     `json:"provider"`
+	Model          string     `json:"model"`
+	TokensUsed     int        `json:"tokens_used,omitempty"`
+	ResponseFormat string     `json:"response_format"`
+	ErrorCode      string     `json:"error_code,omitempty"`
+	RetryAfter     float64    `json:"retry_after_seconds,omitempty"` // set with ErrorCodeRateLimited
+	ToolCalls      []ToolCall `json:"tool_calls,omitempty"`          // tool invocations requested by the model
+}
+
+const (
+	ErrorCodeRateLimited    = "RATE_LIMITED"
+	ErrorCodeCancelled      = "CANCELLED"
+	ErrorCodeBudgetExceeded = "TOKEN_BUDGET_EXCEEDED"
+)
+
+// StreamChunk carries one increment of a streamed response. The last chunk of a
+// stream has Done set; the aggregated response still arrives on the response channel.
+type StreamChunk struct {
+	CorrelationID string `json:"correlation_id"`
+	Index         int    `json:"index"`
+	Delta         string `json:"delta,omitempty"`
+	Done          bool   `json:"done,omitempty"`
+	Error         string `json:"error,omitempty"`
 }
 
 func NewSuccessResponse(correlationID, provider, model, content, format string, tokensUsed int) *AIResponse {
@@ -35,4 +54,26 @@ func NewErrorResponse(correlationID, provider, error string) *AIResponse {
 		Timestamp:     time.Now(),
 		Provider:      provider,
 	}
-}
\ No newline at end of file
+}
+
+// NewRateLimitedResponse reports a provider rate limit along with how long to wait
+func NewRateLimitedResponse(correlationID, provider, error string, retryAfter time.Duration) *AIResponse {
+	response := NewErrorResponse(correlationID, provider, error)
+	response.ErrorCode = ErrorCodeRateLimited
+	response.RetryAfter = retryAfter.Seconds()
+	return response
+}
+
+// NewCancelledResponse reports a request abandoned because its client went away
+func NewCancelledResponse(correlationID, provider, error string) *AIResponse {
+	response := NewErrorResponse(correlationID, provider, error)
+	response.ErrorCode = ErrorCodeCancelled
+	return response
+}
+
+// NewBudgetExceededResponse reports a request rejected because a token budget is used up
+func NewBudgetExceededResponse(correlationID, provider, error string) *AIResponse {
+	response := NewErrorResponse(correlationID, provider, error)
+	response.ErrorCode = ErrorCodeBudgetExceeded
+	return response
+}
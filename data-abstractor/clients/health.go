@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultHealthCheckInterval = 30 * time.Second
+	healthProbeTimeout         = 3 * time.Second
+)
+
+// BackendHealth tracks whether a backend is reachable. A failed probe marks the backend
+// unavailable and reconnects in the background until a probe succeeds again.
+type BackendHealth struct {
+	name         string
+	probe        func(ctx context.Context) error
+	reconnect    func(ctx context.Context) error
+	mutex        sync.RWMutex
+	available    bool
+	lastError    error
+	reconnecting bool
+}
+
+func newBackendHealth(name string, probe, reconnect func(ctx context.Context) error) *BackendHealth {
+	return &BackendHealth{
+		name:      name,
+		probe:     probe,
+		reconnect: reconnect,
+		available: true,
+	}
+}
+
+// Name returns the backend name used in logs and errors
+func (h *BackendHealth) Name() string {
+	return h.name
+}
+
+// Available reports whether the last probe succeeded
+func (h *BackendHealth) Available() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.available
+}
+
+// LastError returns the error from the last failed probe, if any
+func (h *BackendHealth) LastError() error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.lastError
+}
+
+// Confirm probes the backend after a request failed and reports whether it is down. A
+// healthy probe means the error came from the request itself, not the connection.
+func (h *BackendHealth) Confirm(ctx context.Context) bool {
+	probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	if err := h.probe(probeCtx); err != nil {
+		if ctx.Err() != nil {
+			return false // the request was cancelled, not the backend
+		}
+		h.markUnavailable(err)
+		return true
+	}
+	return false
+}
+
+// Monitor probes the backend every interval until the context is cancelled
+func (h *BackendHealth) Monitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+			err := h.probe(probeCtx)
+			cancel()
+
+			if err != nil {
+				h.markUnavailable(err)
+			} else {
+				h.markAvailable()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *BackendHealth) markAvailable() {
+	h.mutex.Lock()
+	recovered := !h.available
+	h.available = true
+	h.lastError = nil
+	h.mutex.Unlock()
+
+	if recovered {
+		logrus.WithField("backend", h.name).Info("Backend available again")
+	}
+}
+
+// markUnavailable records the failure and starts a single background reconnect loop
+func (h *BackendHealth) markUnavailable(err error) {
+	h.mutex.Lock()
+	wasAvailable := h.available
+	h.available = false
+	h.lastError = err
+	startReconnect := !h.reconnecting
+	h.reconnecting = true
+	h.mutex.Unlock()
+
+	if wasAvailable {
+		logrus.WithError(err).WithField("backend", h.name).Warn("Backend unavailable")
+	}
+	if startReconnect {
+		go h.reconnectLoop()
+	}
+}
+
+// reconnectLoop retries with exponential backoff, capped at 30 seconds, until the backend
+// answers a probe
+func (h *BackendHealth) reconnectLoop() {
+	defer func() {
+		h.mutex.Lock()
+		h.reconnecting = false
+		h.mutex.Unlock()
+	}()
+
+	backoff := time.Second
+	for {
+		time.Sleep(backoff)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := h.reconnect(ctx)
+		if err == nil {
+			err = h.probe(ctx)
+		}
+		cancel()
+
+		if err == nil {
+			h.markAvailable()
+			return
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"backend": h.name,
+			"retry":   backoff,
+		}).Debug("Backend reconnect failed")
+
+		h.mutex.Lock()
+		h.lastError = err
+		h.mutex.Unlock()
+
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
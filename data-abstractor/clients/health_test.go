@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBackend answers probes according to up and counts reconnect attempts
+type fakeBackend struct {
+	up         atomic.Bool
+	reconnects atomic.Int32
+}
+
+func newFakeBackend(t *testing.T) (*fakeBackend, *BackendHealth) {
+	t.Helper()
+	backend := &fakeBackend{}
+	backend.up.Store(true)
+	health := newBackendHealth("neo4j", func(ctx context.Context) error {
+		if !backend.up.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		backend.reconnects.Add(1)
+		return nil
+	})
+	return backend, health
+}
+
+// waitFor polls condition until it holds or the timeout passes
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestConfirmIgnoresRequestErrorsOnHealthyBackend(t *testing.T) {
+	backend, health := newFakeBackend(t)
+
+	if health.Confirm(context.Background()) {
+		t.Error("expected a healthy probe not to report an outage")
+	}
+	if !health.Available() || backend.reconnects.Load() != 0 {
+		t.Error("expected the backend to stay available without reconnecting")
+	}
+}
+
+func TestConfirmIgnoresCancelledRequests(t *testing.T) {
+	backend, health := newFakeBackend(t)
+	backend.up.Store(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if health.Confirm(ctx) {
+		t.Error("expected a cancelled request not to report an outage")
+	}
+	if !health.Available() {
+		t.Error("expected the backend to stay available")
+	}
+}
+
+func TestOutageReconnectsUntilBackendRecovers(t *testing.T) {
+	backend, health := newFakeBackend(t)
+	backend.up.Store(false)
+
+	// Concurrent failures share one reconnect loop
+	for i := 0; i < 3; i++ {
+		if !health.Confirm(context.Background()) {
+			t.Fatal("expected a failed probe to report an outage")
+		}
+	}
+	if health.Available() || health.LastError() == nil || health.LastError().Error() != "connection refused" {
+		t.Fatalf("got available %v and error %v, want unavailable with the probe error", health.Available(), health.LastError())
+	}
+
+	// The first reconnect attempt comes after a second; the probe still fails then
+	if !waitFor(t, 3*time.Second, func() bool { return backend.reconnects.Load() >= 1 }) {
+		t.Fatal("expected a reconnect attempt")
+	}
+	if health.Available() {
+		t.Error("expected the backend to stay unavailable while probes fail")
+	}
+
+	backend.up.Store(true)
+	if !waitFor(t, 5*time.Second, health.Available) {
+		t.Fatal("expected the backend to become available once it answers")
+	}
+	if health.LastError() != nil {
+		t.Errorf("got error %v after recovering, want none", health.LastError())
+	}
+	if reconnects := backend.reconnects.Load(); reconnects != 2 {
+		t.Errorf("got %d reconnect attempts, want one loop retrying twice", reconnects)
+	}
+}
+
+func TestMonitorTracksProbes(t *testing.T) {
+	backend, health := newFakeBackend(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go health.Monitor(ctx, 10*time.Millisecond)
+
+	backend.up.Store(false)
+	if !waitFor(t, time.Second, func() bool { return !health.Available() }) {
+		t.Fatal("expected a failed probe to mark the backend unavailable")
+	}
+
+	// The monitor notices recovery before the reconnect loop's first attempt
+	backend.up.Store(true)
+	if !waitFor(t, 500*time.Millisecond, health.Available) {
+		t.Error("expected a successful probe to mark the backend available")
+	}
+}
@@ -2,6 +2,8 @@ package clients
 
 import (
 	"context"
+	"sort"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,33 +12,85 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// EnrichmentCollection holds the documents enrich merges into node metadata
+const EnrichmentCollection = "enrichment"
+
 type MongoClient struct {
 	client   *mongo.Client
+	url      string
 	database string
+	mutex    sync.RWMutex
+	health   *BackendHealth
 }
 
 func NewMongoClient(url, database string) (*MongoClient, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	client, err := connectMongo(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
-	}
-
 	logrus.WithFields(logrus.Fields{
 		"url":      url,
 		"database": database,
 	}).Info("MongoDB client connected")
 
-	return &MongoClient{
+	m := &MongoClient{
 		client:   client,
+		url:      url,
 		database: database,
-	}, nil
+	}
+	m.health = newBackendHealth("mongodb", m.ping, m.reconnect)
+
+	return m, nil
+}
+
+func connectMongo(ctx context.Context, url string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Health returns the connection health tracker for this backend
+func (m *MongoClient) Health() *BackendHealth {
+	return m.health
+}
+
+func (m *MongoClient) currentClient() *mongo.Client {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.client
+}
+
+func (m *MongoClient) ping(ctx context.Context) error {
+	return m.currentClient().Ping(ctx, nil)
+}
+
+// reconnect replaces the client with a freshly connected one
+func (m *MongoClient) reconnect(ctx context.Context) error {
+	client, err := connectMongo(ctx, m.url)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	previous := m.client
+	m.client = client
+	m.mutex.Unlock()
+
+	previous.Disconnect(context.Background())
+	logrus.WithField("database", m.database).Info("MongoDB client reconnected")
+	return nil
 }
 
 func (m *MongoClient) GetEnrichmentData(ctx context.Context, nodeIDs []string) (map[string]interface{}, error) {
@@ -44,7 +98,7 @@ func (m *MongoClient) GetEnrichmentData(ctx context.Context, nodeIDs []string) (
 		return make(map[string]interface{}), nil
 	}
 
-	collection := m.client.Database(m.database).Collection("enrichment")
+	collection := m.currentClient().Database(m.database).Collection(EnrichmentCollection)
 	
 	filter := bson.M{"node_id": bson.M{"$in": nodeIDs}}
 	
@@ -82,8 +136,69 @@ func (m *MongoClient) GetEnrichmentData(ctx context.Context, nodeIDs []string) (
 	return enrichmentData, nil
 }
 
+// UpsertResult counts the documents an upsert touched
+type UpsertResult struct {
+	Matched  int64
+	Modified int64
+	Upserted int64
+}
+
+// UpsertEnrichmentData writes documents to a collection, keyed by node ID. Missing
+// documents are inserted. Existing ones have the given fields merged into them, or are
+// replaced outright when replace is set.
+func (m *MongoClient) UpsertEnrichmentData(ctx context.Context, collectionName string, documents map[string]map[string]interface{}, replace bool) (*UpsertResult, error) {
+	if len(documents) == 0 {
+		return &UpsertResult{}, nil
+	}
+
+	// Sorted so a batch that fails part way has written a predictable prefix
+	nodeIDs := make([]string, 0, len(documents))
+	for nodeID := range documents {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	writes := make([]mongo.WriteModel, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		filter := bson.M{"node_id": nodeID}
+		fields := bson.M{}
+		for k, v := range documents[nodeID] {
+			if k == "_id" || k == "node_id" {
+				continue
+			}
+			fields[k] = v
+		}
+
+		if replace {
+			fields["node_id"] = nodeID
+			writes = append(writes, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(fields).SetUpsert(true))
+		} else {
+			writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": fields}).SetUpsert(true))
+		}
+	}
+
+	collection := m.currentClient().Database(m.database).Collection(collectionName)
+	result, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"collection": collectionName,
+		"documents":  len(writes),
+		"replace":    replace,
+		"upserted":   result.UpsertedCount,
+	}).Debug("MongoDB enrichment data stored")
+
+	return &UpsertResult{
+		Matched:  result.MatchedCount,
+		Modified: result.ModifiedCount,
+		Upserted: result.UpsertedCount,
+	}, nil
+}
+
 func (m *MongoClient) GetMetadataByNodeID(ctx context.Context, nodeID string) (map[string]interface{}, error) {
-	collection := m.client.Database(m.database).Collection("metadata")
+	collection := m.currentClient().Database(m.database).Collection("metadata")
 	
 	filter := bson.M{"node_id": nodeID}
 	
@@ -105,6 +220,6 @@ func (m *MongoClient) GetMetadataByNodeID(ctx context.Context, nodeID string) (m
 func (m *MongoClient) Close() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	
-	return m.client.Disconnect(ctx)
-}
\ No newline at end of file
+
+	return m.currentClient().Disconnect(ctx)
+}
@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// newTestMongoClient connects to the MongoDB at MONGODB_TEST_URL, skipping the test
+// without one. It uses a database unique to the test, dropped afterwards.
+func newTestMongoClient(t *testing.T) *MongoClient {
+	t.Helper()
+	url := os.Getenv("MONGODB_TEST_URL")
+	if url == "" {
+		t.Skip("MONGODB_TEST_URL not set")
+	}
+
+	database := "test_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	client, err := NewMongoClient(url, database)
+	if err != nil {
+		t.Skipf("mongodb unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		client.currentClient().Database(database).Drop(context.Background())
+		client.Close()
+	})
+	return client
+}
+
+func TestMongoUpsertEnrichmentDataMergesFields(t *testing.T) {
+	client := newTestMongoClient(t)
+	ctx := context.Background()
+
+	result, err := client.UpsertEnrichmentData(ctx, EnrichmentCollection, map[string]map[string]interface{}{
+		"1": {"owner": "analytics", "rows": 10},
+		"2": {"owner": "finance"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Upserted != 2 || result.Matched != 0 {
+		t.Errorf("got %+v, want both documents inserted", result)
+	}
+
+	// A second write merges into the stored document; node_id and _id cannot be overwritten
+	result, err = client.UpsertEnrichmentData(ctx, EnrichmentCollection, map[string]map[string]interface{}{
+		"1": {"rows": 12, "node_id": "9", "_id": "forged"},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 1 || result.Modified != 1 || result.Upserted != 0 {
+		t.Errorf("got %+v, want the existing document updated", result)
+	}
+
+	data, err := client.GetEnrichmentData(ctx, []string{"1", "2", "9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"1": bson.M{"owner": "analytics", "rows": int32(12)},
+		"2": bson.M{"owner": "finance"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %v, want %v", data, want)
+	}
+}
+
+func TestMongoUpsertEnrichmentDataReplacesDocuments(t *testing.T) {
+	client := newTestMongoClient(t)
+	ctx := context.Background()
+
+	if _, err := client.UpsertEnrichmentData(ctx, "reports", map[string]map[string]interface{}{"1": {"owner": "analytics", "rows": 10}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpsertEnrichmentData(ctx, "reports", map[string]map[string]interface{}{"1": {"rows": 12}}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored bson.M
+	if err := client.currentClient().Database(client.database).Collection("reports").FindOne(ctx, bson.M{"node_id": "1"}).Decode(&stored); err != nil {
+		t.Fatal(err)
+	}
+	delete(stored, "_id")
+	if want := (bson.M{"node_id": "1", "rows": int32(12)}); !reflect.DeepEqual(stored, want) {
+		t.Errorf("got %v, want the document replaced", stored)
+	}
+}
@@ -2,6 +2,9 @@ package clients
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
@@ -9,7 +12,12 @@ import (
 )
 
 type Neo4jClient struct {
-	driver neo4j.DriverWithContext
+	driver   neo4j.DriverWithContext
+	url      string
+	username string
+	password string
+	mutex    sync.RWMutex
+	health   *BackendHealth
 }
 
 type GraphResult struct {
@@ -33,6 +41,28 @@ type Relationship struct {
 }
 
 func NewNeo4jClient(url, username, password string) (*Neo4jClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	driver, err := connectNeo4j(ctx, url, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithField("url", url).Info("Neo4j client connected")
+
+	client := &Neo4jClient{
+		driver:   driver,
+		url:      url,
+		username: username,
+		password: password,
+	}
+	client.health = newBackendHealth("neo4j", client.ping, client.reconnect)
+
+	return client, nil
+}
+
+func connectNeo4j(ctx context.Context, url, username, password string) (neo4j.DriverWithContext, error) {
 	driver, err := neo4j.NewDriverWithContext(
 		url,
 		neo4j.BasicAuth(username, password, ""),
@@ -45,20 +75,48 @@ func NewNeo4jClient(url, username, password string) (*Neo4jClient, error) {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	if err := driver.VerifyConnectivity(ctx); err != nil {
+		driver.Close(context.Background())
 		return nil, err
 	}
 
-	logrus.WithField("url", url).Info("Neo4j client connected")
+	return driver, nil
+}
 
-	return &Neo4jClient{driver: driver}, nil
+// Health returns the connection health tracker for this backend
+func (n *Neo4jClient) Health() *BackendHealth {
+	return n.health
+}
+
+func (n *Neo4jClient) currentDriver() neo4j.DriverWithContext {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.driver
+}
+
+func (n *Neo4jClient) ping(ctx context.Context) error {
+	return n.currentDriver().VerifyConnectivity(ctx)
+}
+
+// reconnect replaces the driver with a freshly verified one
+func (n *Neo4jClient) reconnect(ctx context.Context) error {
+	driver, err := connectNeo4j(ctx, n.url, n.username, n.password)
+	if err != nil {
+		return err
+	}
+
+	n.mutex.Lock()
+	previous := n.driver
+	n.driver = driver
+	n.mutex.Unlock()
+
+	previous.Close(context.Background())
+	logrus.WithField("url", n.url).Info("Neo4j client reconnected")
+	return nil
 }
 
 func (n *Neo4jClient) ExecuteCypher(ctx context.Context, cypher string, params map[string]interface{}) (*GraphResult, error) {
-	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	session := n.currentDriver().NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
 	defer session.Close(ctx)
 
 	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
@@ -77,13 +135,13 @@ func (n *Neo4jClient) ExecuteCypher(ctx context.Context, cypher string, params m
 
 		for cypherResult.Next(ctx) {
 			record := cypherResult.Record()
-			
+
 			for _, value := range record.Values {
 				switch v := value.(type) {
 				case neo4j.Node:
 					if !nodeMap[v.GetId()] {
 						node := Node{
-							ID:         string(rune(v.GetId())),
+							ID:         formatID(v.GetId()),
 							Labels:     v.Labels,
 							Properties: v.Props,
 						}
@@ -93,10 +151,10 @@ func (n *Neo4jClient) ExecuteCypher(ctx context.Context, cypher string, params m
 				case neo4j.Relationship:
 					if !relMap[v.GetId()] {
 						rel := Relationship{
-							ID:         string(rune(v.GetId())),
+							ID:         formatID(v.GetId()),
 							Type:       v.Type,
-							StartNode:  string(rune(v.StartId)),
-							EndNode:    string(rune(v.EndId)),
+							StartNode:  formatID(v.StartId),
+							EndNode:    formatID(v.EndId),
 							Properties: v.Props,
 						}
 						graphResult.Relationships = append(graphResult.Relationships, rel)
@@ -106,7 +164,7 @@ func (n *Neo4jClient) ExecuteCypher(ctx context.Context, cypher string, params m
 					for _, node := range v.Nodes {
 						if !nodeMap[node.GetId()] {
 							n := Node{
-								ID:         string(rune(node.GetId())),
+								ID:         formatID(node.GetId()),
 								Labels:     node.Labels,
 								Properties: node.Props,
 							}
@@ -117,10 +175,10 @@ func (n *Neo4jClient) ExecuteCypher(ctx context.Context, cypher string, params m
 					for _, rel := range v.Relationships {
 						if !relMap[rel.GetId()] {
 							r := Relationship{
-								ID:         string(rune(rel.GetId())),
+								ID:         formatID(rel.GetId()),
 								Type:       rel.Type,
-								StartNode:  string(rune(rel.StartId)),
-								EndNode:    string(rune(rel.EndId)),
+								StartNode:  formatID(rel.StartId),
+								EndNode:    formatID(rel.EndId),
 								Properties: rel.Props,
 							}
 							graphResult.Relationships = append(graphResult.Relationships, r)
@@ -146,14 +204,38 @@ func (n *Neo4jClient) GetNodesByIds(ctx context.Context, nodeIds []string) (*Gra
 		return &GraphResult{Nodes: []Node{}, Relationships: []Relationship{}}, nil
 	}
 
-	cypher := "MATCH (n) WHERE n.id IN $node_ids RETURN n"
+	internalIds, err := parseIDs(nodeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	cypher := "MATCH (n) WHERE id(n) IN $node_ids RETURN n"
 	params := map[string]interface{}{
-		"node_ids": nodeIds,
+		"node_ids": internalIds,
 	}
 
 	return n.ExecuteCypher(ctx, cypher, params)
 }
 
+// formatID renders a Neo4j internal ID as the decimal string used in responses
+func formatID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// parseIDs reverses formatID. IDs are only ever internal IDs, never an id property, so
+// a lookup can't match an unrelated node whose property happens to collide.
+func parseIDs(ids []string) ([]int64, error) {
+	parsed := make([]int64, len(ids))
+	for i, id := range ids {
+		internal, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node ID %q: expected a Neo4j internal ID", id)
+		}
+		parsed[i] = internal
+	}
+	return parsed, nil
+}
+
 func (n *Neo4jClient) Close() error {
-	return n.driver.Close(context.Background())
-}
\ No newline at end of file
+	return n.currentDriver().Close(context.Background())
+}
@@ -0,0 +1,143 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func TestFormatIDRoundTrip(t *testing.T) {
+	// 1114111 is the last Unicode code point; string(rune(id)) mangled everything above it
+	ids := []int64{0, 65, 127, 1114111, 1114112, 1 << 40}
+	formatted := make([]string, len(ids))
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		formatted[i] = formatID(id)
+		if seen[formatted[i]] {
+			t.Errorf("ID %d collides with another ID as %q", id, formatted[i])
+		}
+		seen[formatted[i]] = true
+	}
+
+	parsed, err := parseIDs(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		if parsed[i] != id {
+			t.Errorf("got %d back for %q, want %d", parsed[i], formatted[i], id)
+		}
+	}
+}
+
+func TestParseIDsRejectsPropertyIDs(t *testing.T) {
+	for _, id := range []string{"node1", "", "12abc", "1.5"} {
+		if _, err := parseIDs([]string{"7", id}); err == nil {
+			t.Errorf("ID %q: expected an error", id)
+		}
+	}
+}
+
+// newTestNeo4jClient connects to the Neo4j at NEO4J_TEST_URL, skipping the test without
+// one. It returns a label unique to the test; nodes with it are deleted afterwards.
+func newTestNeo4jClient(t *testing.T) (*Neo4jClient, string) {
+	t.Helper()
+	url := os.Getenv("NEO4J_TEST_URL")
+	if url == "" {
+		t.Skip("NEO4J_TEST_URL not set")
+	}
+
+	client, err := NewNeo4jClient(url, os.Getenv("NEO4J_TEST_USERNAME"), os.Getenv("NEO4J_TEST_PASSWORD"))
+	if err != nil {
+		t.Skipf("neo4j unavailable: %v", err)
+	}
+
+	label := "Test_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	t.Cleanup(func() {
+		runWrite(t, client, "MATCH (n:`"+label+"`) DETACH DELETE n", nil)
+		client.Close()
+	})
+	return client, label
+}
+
+// runWrite runs a write query and returns the first column of each record
+func runWrite(t *testing.T, client *Neo4jClient, cypher string, params map[string]interface{}) []interface{} {
+	t.Helper()
+	ctx := context.Background()
+	session := client.currentDriver().NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	values, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (interface{}, error) {
+		result, err := tx.Run(ctx, cypher, params)
+		if err != nil {
+			return nil, err
+		}
+		var values []interface{}
+		for result.Next(ctx) {
+			values = append(values, result.Record().Values[0])
+		}
+		return values, result.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values.([]interface{})
+}
+
+func TestNeo4jCreatedNodeRoundTripsThroughGetNodesByIds(t *testing.T) {
+	client, label := newTestNeo4jClient(t)
+
+	created := runWrite(t, client, "CREATE (n:`"+label+"` {name: 'target'}) RETURN id(n)", nil)
+	targetID := formatID(created[0].(int64))
+
+	// A node whose id property equals the target's ID must not be returned for it
+	runWrite(t, client, "CREATE (n:`"+label+"` {name: 'decoy', id: $id})", map[string]interface{}{"id": targetID})
+
+	result, err := client.GetNodesByIds(context.Background(), []string{targetID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Nodes) != 1 {
+		t.Fatalf("got %d nodes, want only the created one: %+v", len(result.Nodes), result.Nodes)
+	}
+	if result.Nodes[0].ID != targetID || result.Nodes[0].Properties["name"] != "target" {
+		t.Errorf("got node %+v, want ID %s named target", result.Nodes[0], targetID)
+	}
+
+	// The returned ID fetches the same node again
+	again, err := client.GetNodesByIds(context.Background(), []string{result.Nodes[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Nodes) != 1 || again.Nodes[0].ID != targetID {
+		t.Errorf("got %+v on the second lookup, want the same node", again.Nodes)
+	}
+}
+
+func TestNeo4jExecuteCypherBindsParameters(t *testing.T) {
+	client, label := newTestNeo4jClient(t)
+	runWrite(t, client, "CREATE (:`"+label+"` {name: 'sales'}), (:`"+label+"` {name: 'x'' OR 1=1 //'})", nil)
+
+	cypher := "MATCH (n:`" + label + "`) WHERE n.name = $name RETURN n LIMIT $limit"
+
+	// A value that would change the query if spliced into it only matches itself
+	result, err := client.ExecuteCypher(context.Background(), cypher, map[string]interface{}{"name": "x' OR 1=1 //", "limit": int64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Nodes) != 1 || result.Nodes[0].Properties["name"] != "x' OR 1=1 //" {
+		t.Errorf("got %+v, want only the node with that literal name", result.Nodes)
+	}
+
+	result, err = client.ExecuteCypher(context.Background(), cypher, map[string]interface{}{"name": "sales", "limit": int64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Nodes) != 1 || result.Nodes[0].Properties["name"] != "sales" {
+		t.Errorf("got %+v, want the sales node", result.Nodes)
+	}
+}
@@ -5,15 +5,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
+// qdrantUpsertBatchSize caps the points sent to Qdrant in one upsert call
+const qdrantUpsertBatchSize = 256
+
 type QdrantClient struct {
 	baseURL    string
 	collection string
 	httpClient *http.Client
+	health     *BackendHealth
+}
+
+// Point is a vector stored in Qdrant; ID is an unsigned integer or a UUID string
+type Point struct {
+	ID      interface{}            `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+type qdrantUpsertRequest struct {
+	Points []Point `json:"points"`
+}
+
+type qdrantCollectionResponse struct {
+	Result struct {
+		Config struct {
+			Params struct {
+				Vectors json.RawMessage `json:"vectors"`
+			} `json:"params"`
+		} `json:"config"`
+	} `json:"result"`
 }
 
 type SearchResult struct {
@@ -41,6 +68,8 @@ func NewQdrantClient(url, collection string) (*QdrantClient, error) {
 		collection: collection,
 		httpClient: &http.Client{},
 	}
+	// HTTP connections are re-established per request, so reconnecting is just a probe
+	client.health = newBackendHealth("qdrant", client.ping, client.ping)
 
 	logrus.WithFields(logrus.Fields{
 		"url":        url,
@@ -50,6 +79,31 @@ func NewQdrantClient(url, collection string) (*QdrantClient, error) {
 	return client, nil
 }
 
+// Health returns the connection health tracker for this backend
+func (q *QdrantClient) Health() *BackendHealth {
+	return q.health
+}
+
+// ping checks that Qdrant answers; a missing collection is a request error, not an outage
+func (q *QdrantClient) ping(ctx context.Context) error {
+	url := fmt.Sprintf("%s/collections/%s", q.baseURL, q.collection)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("qdrant health check failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func (q *QdrantClient) SearchSimilar(ctx context.Context, vector []float32, limit uint64) ([]SearchResult, error) {
 	searchReq := qdrantSearchRequest{
 		Vector:      vector,
@@ -111,6 +165,98 @@ func (q *QdrantClient) SearchSimilar(ctx context.Context, vector []float32, limi
 	return results, nil
 }
 
+// Collection returns the name of the collection searched and indexed
+func (q *QdrantClient) Collection() string {
+	return q.collection
+}
+
+// VectorSize returns the dimensionality the collection's vectors are configured with.
+// It is read on every call so a recreated collection is picked up.
+func (q *QdrantClient) VectorSize(ctx context.Context) (int, error) {
+	url := fmt.Sprintf("%s/collections/%s", q.baseURL, q.collection)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("qdrant collection lookup failed with status %d", resp.StatusCode)
+	}
+
+	var collectionResp qdrantCollectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&collectionResp); err != nil {
+		return 0, err
+	}
+
+	// A single unnamed vector is configured as {"size": n}; named vectors aren't supported
+	var vectors struct {
+		Size int `json:"size"`
+	}
+	if err := json.Unmarshal(collectionResp.Result.Config.Params.Vectors, &vectors); err != nil || vectors.Size == 0 {
+		return 0, fmt.Errorf("collection %s does not use a single unnamed vector", q.collection)
+	}
+
+	return vectors.Size, nil
+}
+
+// Upsert writes points to the collection, inserting new IDs and overwriting existing
+// ones. Points are sent in batches, each waited on until applied, and the number
+// written is returned; on failure, earlier batches remain written.
+func (q *QdrantClient) Upsert(ctx context.Context, points []Point) (int, error) {
+	written := 0
+	for start := 0; start < len(points); start += qdrantUpsertBatchSize {
+		end := start + qdrantUpsertBatchSize
+		if end > len(points) {
+			end = len(points)
+		}
+		if err := q.upsertBatch(ctx, points[start:end]); err != nil {
+			return written, err
+		}
+		written = end
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"collection": q.collection,
+		"points":     written,
+	}).Debug("Qdrant points upserted")
+
+	return written, nil
+}
+
+func (q *QdrantClient) upsertBatch(ctx context.Context, points []Point) error {
+	reqBody, err := json.Marshal(qdrantUpsertRequest{Points: points})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/collections/%s/points?wait=true", q.baseURL, q.collection)
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Qdrant explains rejected points in the body
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("qdrant upsert failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
 func (q *QdrantClient) SearchText(ctx context.Context, text string, limit uint64) ([]SearchResult, error) {
 	logrus.WithFields(logrus.Fields{
 		"text":       text,
@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newQdrantServer returns a client for collection "docs" on a server handled by handler
+func newQdrantServer(t *testing.T, handler http.HandlerFunc) *QdrantClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewQdrantClient(server.URL, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestQdrantVectorSize(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   int
+		err    string
+	}{
+		{"unnamed vector", http.StatusOK, `{"result":{"config":{"params":{"vectors":{"size":384,"distance":"Cosine"}}}}}`, 384, ""},
+		{"named vectors", http.StatusOK, `{"result":{"config":{"params":{"vectors":{"text":{"size":384}}}}}}`, 0, "collection docs does not use a single unnamed vector"},
+		{"missing collection", http.StatusNotFound, `{"status":{"error":"Not found"}}`, 0, "qdrant collection lookup failed with status 404"},
+	}
+	for _, c := range cases {
+		client := newQdrantServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.URL.Path != "/collections/docs" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		})
+
+		size, err := client.VectorSize(context.Background())
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: got %v, want %q", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil || size != c.want {
+			t.Errorf("%s: got %d, %v, want %d", c.name, size, err, c.want)
+		}
+	}
+}
+
+func TestQdrantUpsertSendsBatches(t *testing.T) {
+	var batches []int
+	client := newQdrantServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/collections/docs/points" || r.URL.Query().Get("wait") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var body qdrantUpsertRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		batches = append(batches, len(body.Points))
+		w.Write([]byte(`{"result":{"status":"completed"},"status":"ok"}`))
+	})
+
+	points := make([]Point, 2*qdrantUpsertBatchSize+10)
+	for i := range points {
+		points[i] = Point{ID: uint64(i), Vector: []float32{0.1, 0.2}}
+	}
+	written, err := client.Upsert(context.Background(), points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != len(points) {
+		t.Errorf("wrote %d points, want %d", written, len(points))
+	}
+	if len(batches) != 3 || batches[0] != qdrantUpsertBatchSize || batches[2] != 10 {
+		t.Errorf("got batches %v, want two full batches and the remainder", batches)
+	}
+}
+
+func TestQdrantUpsertReportsPointsWrittenBeforeFailure(t *testing.T) {
+	calls := 0
+	client := newQdrantServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":{"error":"Wrong input: Vector dimension error"}}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	points := make([]Point, qdrantUpsertBatchSize+1)
+	for i := range points {
+		points[i] = Point{ID: uint64(i), Vector: []float32{0.1}}
+	}
+	written, err := client.Upsert(context.Background(), points)
+	if written != qdrantUpsertBatchSize {
+		t.Errorf("got %d written, want the first batch", written)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "qdrant upsert failed with status 400: ") || !strings.HasSuffix(err.Error(), "Vector dimension error\"}}") {
+		t.Errorf("got %v, want the status and Qdrant's explanation", err)
+	}
+}
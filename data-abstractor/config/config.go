@@ -16,11 +16,14 @@ type Config struct {
 	Qdrant       QdrantConfig
 	App          AppConfig
 	Capabilities CapabilityConfig
+	Tracing      TracingConfig
 }
 
 type RedisConfig struct {
-	URL     string
-	Channel string
+	URL        string
+	Namespace  string
+	Channel    string
+	ResponseCh string
 }
 
 type Neo4jConfig struct {
@@ -30,8 +33,9 @@ type Neo4jConfig struct {
 }
 
 type MongoConfig struct {
-	URL      string
-	Database string
+	URL             string
+	Database        string
+	StoreCollection string // where store writes documents unless a request names another
 }
 
 type QdrantConfig struct {
@@ -40,13 +44,24 @@ type QdrantConfig struct {
 }
 
 type AppConfig struct {
-	LogLevel string
-	Port     int
+	LogLevel            string
+	Port                int
+	HealthCheckInterval time.Duration // how often backends are probed for reconnection
 }
 
 type CapabilityConfig struct {
-	RefreshInterval time.Duration
-	Enabled         bool
+	RefreshInterval  time.Duration
+	Enabled          bool
+	SigningSecret    string        // shared HMAC secret for signing announcements; empty disables
+	AnnounceDebounce time.Duration // coalesces bursts of announcements; zero disables
+	InstanceID       string        // identifies this replica; set with a replica-specific request channel
+	InstanceWeight   int           // relative share of requests routed to this replica
+	ServiceVersion   string        // release announced for workflows that pin service versions
+}
+
+// TracingConfig configures OpenTelemetry span export
+type TracingConfig struct {
+	OTLPEndpoint string // OTLP/HTTP collector URL; empty disables export
 }
 
 func Load() (*Config, error) {
@@ -66,31 +81,66 @@ func Load() (*Config, error) {
 		}
 	}
 
+	var announceDebounce time.Duration
+	if debounceStr := os.Getenv("CAPABILITY_ANNOUNCE_DEBOUNCE"); debounceStr != "" {
+		if debounce, err := time.ParseDuration(debounceStr); err == nil {
+			announceDebounce = debounce
+		}
+	}
+
+	instanceWeight := 1
+	if weightStr := os.Getenv("INSTANCE_WEIGHT"); weightStr != "" {
+		if w, err := strconv.Atoi(weightStr); err == nil && w > 0 {
+			instanceWeight = w
+		}
+	}
+
+	healthCheckInterval := 30 * time.Second
+	if intervalStr := os.Getenv("BACKEND_HEALTH_INTERVAL"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil && interval > 0 {
+			healthCheckInterval = interval
+		}
+	}
+
+	redisConfig := RedisConfig{
+		URL:       getEnv("REDIS_URL", "redis://localhost:6379"),
+		Namespace: getEnv("REDIS_NAMESPACE", ""),
+	}
+	redisConfig.Channel = Namespaced(redisConfig.Namespace, getEnv("REDIS_CHANNEL", "data-requests"))
+	redisConfig.ResponseCh = Namespaced(redisConfig.Namespace, getEnv("REDIS_RESPONSE_CHANNEL", "data-responses"))
+
 	config := &Config{
-		Redis: RedisConfig{
-			URL:     getEnv("REDIS_URL", "redis://localhost:6379"),
-			Channel: getEnv("REDIS_CHANNEL", "data-requests"),
-		},
+		Redis: redisConfig,
 		Neo4j: Neo4jConfig{
 			URL:      getEnv("NEO4J_URL", "bolt://localhost:7687"),
 			Username: getEnv("NEO4J_USER", "neo4j"),
 			Password: getEnv("NEO4J_PASSWORD", "password"),
 		},
 		MongoDB: MongoConfig{
-			URL:      getEnv("MONGODB_URL", "mongodb://localhost:27017"),
-			Database: getEnv("MONGODB_DATABASE", "enrichment"),
+			URL:             getEnv("MONGODB_URL", "mongodb://localhost:27017"),
+			Database:        getEnv("MONGODB_DATABASE", "enrichment"),
+			StoreCollection: getEnv("MONGODB_STORE_COLLECTION", "enrichment"),
 		},
 		Qdrant: QdrantConfig{
 			URL:        getEnv("QDRANT_URL", "http://localhost:6333"),
 			Collection: getEnv("QDRANT_COLLECTION", "embeddings"),
 		},
 		App: AppConfig{
-			LogLevel: getEnv("LOG_LEVEL", "info"),
-			Port:     port,
+			LogLevel:            getEnv("LOG_LEVEL", "info"),
+			Port:                port,
+			HealthCheckInterval: healthCheckInterval,
 		},
 		Capabilities: CapabilityConfig{
-			RefreshInterval: refreshInterval,
-			Enabled:         getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			RefreshInterval:  refreshInterval,
+			Enabled:          getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			SigningSecret:    getEnv("CAPABILITY_SIGNING_SECRET", ""),
+			AnnounceDebounce: announceDebounce,
+			InstanceID:       getEnv("INSTANCE_ID", ""),
+			InstanceWeight:   instanceWeight,
+			ServiceVersion:   getEnv("SERVICE_VERSION", ""),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 		},
 	}
 
@@ -104,6 +154,14 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// Namespaced prefixes a Redis key or channel name with the environment namespace, if any
+func Namespaced(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in exposed configuration
+const redactedValue = "REDACTED"
+
+// maskSecret hides a secret while still showing whether it is set
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL masks any password embedded in a connection URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if strings.Contains(raw, "@") {
+			return redactedValue
+		}
+		return raw
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	return u.String()
+}
+
+// Redacted returns the effective configuration with secrets masked, for operators
+// verifying what the service actually parsed
+func (c *Config) Redacted() map[string]interface{} {
+	return map[string]interface{}{
+		"redis": map[string]interface{}{
+			"url":              redactURL(c.Redis.URL),
+			"namespace":        c.Redis.Namespace,
+			"request_channel":  c.Redis.Channel,
+			"response_channel": c.Redis.ResponseCh,
+		},
+		"neo4j": map[string]interface{}{
+			"url":      redactURL(c.Neo4j.URL),
+			"username": c.Neo4j.Username,
+			"password": maskSecret(c.Neo4j.Password),
+		},
+		"mongodb": map[string]interface{}{
+			"url":              redactURL(c.MongoDB.URL),
+			"database":         c.MongoDB.Database,
+			"store_collection": c.MongoDB.StoreCollection,
+		},
+		"qdrant": map[string]interface{}{
+			"url":        redactURL(c.Qdrant.URL),
+			"collection": c.Qdrant.Collection,
+		},
+		"app": map[string]interface{}{
+			"log_level":             c.App.LogLevel,
+			"port":                  c.App.Port,
+			"health_check_interval": c.App.HealthCheckInterval.String(),
+		},
+		"capabilities": map[string]interface{}{
+			"enabled":           c.Capabilities.Enabled,
+			"refresh_interval":  c.Capabilities.RefreshInterval.String(),
+			"signing_secret":    maskSecret(c.Capabilities.SigningSecret),
+			"announce_debounce": c.Capabilities.AnnounceDebounce.String(),
+			"instance_id":       c.Capabilities.InstanceID,
+			"instance_weight":   c.Capabilities.InstanceWeight,
+			"service_version":   c.Capabilities.ServiceVersion,
+		},
+		"tracing": map[string]interface{}{
+			"otlp_endpoint": redactURL(c.Tracing.OTLPEndpoint),
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"data-abstractor/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handleBatch runs the steps of a batch in order within one round trip. An enrich step
+// without node IDs enriches the nodes returned by the previous successful step. The
+// batch stops at the first failed step unless continue_on_error is set.
+func (h *DataHandler) handleBatch(ctx context.Context, req *models.Request) *models.Response {
+	if len(req.Steps) == 0 {
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, "Steps are required for batch operation")
+	}
+	for i, step := range req.Steps {
+		if step.Operation == models.OperationBatch {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Step %d: batches cannot be nested", i))
+		}
+	}
+
+	results := make([]*models.Response, 0, len(req.Steps))
+	var previous *models.GraphData
+	var firstFailure *models.Response
+	var failures []string
+
+	for i := range req.Steps {
+		step := req.Steps[i]
+		step.CorrelationID = req.CorrelationID
+
+		if step.Operation == models.OperationEnrich && len(step.Query.NodeIDs) == 0 && previous != nil {
+			step.Query.NodeIDs = nodeIDs(previous.Nodes)
+		}
+
+		result := h.dispatch(ctx, &step)
+		results = append(results, result)
+
+		if result.Success {
+			previous = result.Data
+			continue
+		}
+
+		if firstFailure == nil {
+			firstFailure = result
+		}
+		failures = append(failures, fmt.Sprintf("step %d (%s): %s", i, step.Operation, result.Error))
+
+		logrus.WithFields(logrus.Fields{
+			"correlation_id": req.CorrelationID,
+			"step":           i,
+			"operation":      step.Operation,
+		}).Warn("Batch step failed")
+
+		if !req.ContinueOnError {
+			break
+		}
+	}
+
+	// The combined response carries the last successful step's data
+	var response *models.Response
+	if firstFailure == nil {
+		response = models.NewSuccessResponse(req.CorrelationID, req.Operation, previous)
+	} else {
+		response = models.NewErrorResponse(req.CorrelationID, req.Operation, "Batch failed: "+strings.Join(failures, "; "))
+		response.Data = previous
+		response.ErrorCode = firstFailure.ErrorCode
+	}
+	response.Steps = results
+
+	return response
+}
+
+func nodeIDs(nodes []models.GraphNode) []string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	return ids
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"data-abstractor/clients"
+	"data-abstractor/models"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// runBatch sends a batch request to a handler without backends; the steps used here all
+// fail validation before reaching one
+func runBatch(t *testing.T, request models.Request) *models.Response {
+	t.Helper()
+	request.Operation = models.OperationBatch
+	request.CorrelationID = "batch-1"
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response models.Response
+	if err := json.Unmarshal(NewDataHandler(nil, nil, nil).HandleRequest(context.Background(), data), &response); err != nil {
+		t.Fatal(err)
+	}
+	return &response
+}
+
+func TestBatchStopsAtFirstFailedStep(t *testing.T) {
+	response := runBatch(t, models.Request{Steps: []models.Request{
+		{Operation: models.OperationTraverse},
+		{Operation: "unknown"},
+	}})
+
+	if response.Success || response.Error != "Batch failed: step 0 (traverse): Cypher query is required for traverse operation" {
+		t.Errorf("got %+v, want the first step's failure", response)
+	}
+	if len(response.Steps) != 1 {
+		t.Fatalf("got %d step results, want the batch stopped after the first", len(response.Steps))
+	}
+	if step := response.Steps[0]; step.CorrelationID != "batch-1" || step.Operation != models.OperationTraverse {
+		t.Errorf("got step result %+v, want it under the batch's correlation ID", step)
+	}
+}
+
+func TestBatchContinuesOnErrorWhenAsked(t *testing.T) {
+	response := runBatch(t, models.Request{ContinueOnError: true, Steps: []models.Request{
+		{Operation: models.OperationTraverse},
+		{Operation: "unknown"},
+		// Nothing succeeded before, so there are no node IDs to carry over
+		{Operation: models.OperationEnrich},
+	}})
+
+	want := "Batch failed: step 0 (traverse): Cypher query is required for traverse operation; " +
+		"step 1 (unknown): Unknown operation: unknown; " +
+		"step 2 (enrich): Node IDs are required for enrich operation"
+	if response.Success || response.Error != want {
+		t.Errorf("got error %q, want every step's failure", response.Error)
+	}
+	if len(response.Steps) != 3 {
+		t.Errorf("got %d step results, want all 3", len(response.Steps))
+	}
+}
+
+func TestBatchRejectsInvalidBatches(t *testing.T) {
+	cases := []struct {
+		name    string
+		request models.Request
+		want    string
+	}{
+		{"no steps", models.Request{}, "Steps are required for batch operation"},
+		{"nested batch", models.Request{Steps: []models.Request{
+			{Operation: models.OperationTraverse, Query: models.QueryData{Cypher: "MATCH (n) RETURN n"}},
+			{Operation: models.OperationBatch},
+		}}, "Step 1: batches cannot be nested"},
+	}
+	for _, c := range cases {
+		response := runBatch(t, c.request)
+		if response.Success || response.Error != c.want || len(response.Steps) != 0 {
+			t.Errorf("%s: got %+v, want %q before running any step", c.name, response, c.want)
+		}
+	}
+}
+
+// newBackendHandler connects a handler to the Neo4j at NEO4J_TEST_URL and the MongoDB at
+// MONGODB_TEST_URL, skipping the test without them. It returns a label unique to the
+// test and a function creating nodes with it; they are deleted afterwards.
+func newBackendHandler(t *testing.T) (*DataHandler, string, func(names ...string)) {
+	t.Helper()
+	neo4jURL, mongoURL := os.Getenv("NEO4J_TEST_URL"), os.Getenv("MONGODB_TEST_URL")
+	if neo4jURL == "" || mongoURL == "" {
+		t.Skip("NEO4J_TEST_URL and MONGODB_TEST_URL not set")
+	}
+	username, password := os.Getenv("NEO4J_TEST_USERNAME"), os.Getenv("NEO4J_TEST_PASSWORD")
+
+	graph, err := clients.NewNeo4jClient(neo4jURL, username, password)
+	if err != nil {
+		t.Skipf("neo4j unavailable: %v", err)
+	}
+	t.Cleanup(func() { graph.Close() })
+	documents, err := clients.NewMongoClient(mongoURL, "data_abstractor_test")
+	if err != nil {
+		t.Skipf("mongodb unavailable: %v", err)
+	}
+	t.Cleanup(func() { documents.Close() })
+
+	driver, err := neo4j.NewDriverWithContext(neo4jURL, neo4j.BasicAuth(username, password, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := "Test_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	write := func(cypher string, params map[string]interface{}) {
+		ctx := context.Background()
+		if _, err := neo4j.ExecuteQuery(ctx, driver, cypher, params, neo4j.EagerResultTransformer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		write("MATCH (n:`"+label+"`) DETACH DELETE n", nil)
+		driver.Close(context.Background())
+	})
+
+	create := func(names ...string) {
+		for _, name := range names {
+			write("CREATE (:`"+label+"` {name: $name})", map[string]interface{}{"name": name})
+		}
+	}
+	return NewDataHandler(graph, documents, nil), label, create
+}
+
+func TestBatchEnrichesPreviousStepsNodes(t *testing.T) {
+	handler, label, create := newBackendHandler(t)
+	create("sales", "returns")
+
+	request, _ := json.Marshal(models.Request{
+		Operation:     models.OperationBatch,
+		CorrelationID: "batch-1",
+		Steps: []models.Request{
+			{Operation: models.OperationTraverse, Query: models.QueryData{Cypher: "MATCH (n:`" + label + "`) RETURN n"}},
+			{Operation: models.OperationEnrich},
+		},
+	})
+	var response models.Response
+	if err := json.Unmarshal(handler.HandleRequest(context.Background(), request), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Success || len(response.Steps) != 2 {
+		t.Fatalf("got %+v, want both steps to succeed", response)
+	}
+	traversed, enriched := response.Steps[0].Data.Nodes, response.Data.Nodes
+	if len(traversed) != 2 || len(enriched) != 2 {
+		t.Fatalf("got %d traversed and %d enriched nodes, want 2 of each", len(traversed), len(enriched))
+	}
+	ids := make(map[string]bool)
+	for _, node := range traversed {
+		ids[node.ID] = true
+	}
+	for _, node := range enriched {
+		if !ids[node.ID] {
+			t.Errorf("enriched node %s, which was not traversed", node.ID)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// cypherParameterName matches the parameter names usable as $name in Cypher
+var cypherParameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// normalizeCypherParameters checks that query parameters can be sent to Neo4j and converts
+// JSON numbers with no fractional part to integers, so values such as LIMIT $limit are
+// accepted. Only null, booleans, numbers, strings, lists and maps are allowed.
+func normalizeCypherParameters(params map[string]interface{}) (map[string]interface{}, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if !cypherParameterName.MatchString(name) {
+			return nil, fmt.Errorf("invalid parameter name %q", name)
+		}
+		converted, err := normalizeCypherValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		normalized[name] = converted
+	}
+	return normalized, nil
+}
+
+func normalizeCypherValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, string, int64:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), nil
+		}
+		return v, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			converted, err := normalizeCypherValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			list[i] = converted
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted, err := normalizeCypherValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			m[key] = converted
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
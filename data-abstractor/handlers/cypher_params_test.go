@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"data-abstractor/models"
+)
+
+func TestNormalizeCypherParametersConvertsJSONValues(t *testing.T) {
+	var params map[string]interface{}
+	decoded := `{"limit": 10, "score": 0.5, "name": "x' OR 1=1 //", "ids": [1, 2], "filter": {"min": 3, "tags": ["a"]}, "missing": null}`
+	if err := json.Unmarshal([]byte(decoded), &params); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := normalizeCypherParameters(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Values are passed to the driver as they are; strings are never spliced into the query
+	want := map[string]interface{}{
+		"limit":   int64(10),
+		"score":   0.5,
+		"name":    "x' OR 1=1 //",
+		"ids":     []interface{}{int64(1), int64(2)},
+		"filter":  map[string]interface{}{"min": int64(3), "tags": []interface{}{"a"}},
+		"missing": nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestNormalizeCypherParametersRejectsInvalidParameters(t *testing.T) {
+	cases := []struct {
+		name   string
+		params map[string]interface{}
+		want   string
+	}{
+		{"name with spaces", map[string]interface{}{"node id": 1}, `invalid parameter name "node id"`},
+		{"name with a query", map[string]interface{}{"x}) DETACH DELETE n //": 1}, "invalid parameter name"},
+		{"leading digit", map[string]interface{}{"1st": 1}, `invalid parameter name "1st"`},
+		{"unsupported value", map[string]interface{}{"ids": []interface{}{1.0, struct{}{}}}, "parameter ids: item 1: unsupported type struct {}"},
+	}
+	for _, c := range cases {
+		if _, err := normalizeCypherParameters(c.params); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want an error containing %q", c.name, err, c.want)
+		}
+	}
+
+	if params, err := normalizeCypherParameters(nil); params != nil || err != nil {
+		t.Errorf("got %v, %v for no parameters, want nil", params, err)
+	}
+}
+
+func TestTraverseRejectsInvalidParametersBeforeQuerying(t *testing.T) {
+	// Without a Neo4j client, reaching the backend would panic
+	handler := NewDataHandler(nil, nil, nil)
+	request, _ := json.Marshal(map[string]interface{}{
+		"operation":      models.OperationTraverse,
+		"correlation_id": "req-1",
+		"query": map[string]interface{}{
+			"cypher":     "MATCH (n) WHERE n.name = $name RETURN n",
+			"parameters": map[string]interface{}{"na me": "sales"},
+		},
+	})
+
+	var response models.Response
+	if err := json.Unmarshal(handler.HandleRequest(context.Background(), request), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || !strings.Contains(response.Error, `Invalid query parameters: invalid parameter name "na me"`) {
+		t.Errorf("got %+v, want the parameters rejected", response)
+	}
+}
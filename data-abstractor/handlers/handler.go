@@ -3,27 +3,40 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"data-abstractor/clients"
 	"data-abstractor/models"
+	"data-abstractor/tracing"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DataHandler struct {
-	neo4j   *clients.Neo4jClient
-	mongo   *clients.MongoClient
-	qdrant  *clients.QdrantClient
+	neo4j           *clients.Neo4jClient
+	mongo           *clients.MongoClient
+	qdrant          *clients.QdrantClient
+	storeCollection string
 }
 
 func NewDataHandler(neo4j *clients.Neo4jClient, mongo *clients.MongoClient, qdrant *clients.QdrantClient) *DataHandler {
 	return &DataHandler{
-		neo4j:  neo4j,
-		mongo:  mongo,
-		qdrant: qdrant,
+		neo4j:           neo4j,
+		mongo:           mongo,
+		qdrant:          qdrant,
+		storeCollection: clients.EnrichmentCollection,
+	}
+}
+
+// SetStoreCollection sets the collection store writes to when a request names none
+func (h *DataHandler) SetStoreCollection(collection string) {
+	if collection != "" {
+		h.storeCollection = collection
 	}
 }
 
@@ -41,18 +54,18 @@ func (h *DataHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 		"operation":      req.Operation,
 	}).Info("Processing request")
 
-	var response *models.Response
+	// Continue the caller's trace, if it sent one
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, req.TraceContext), "data."+req.Operation,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("correlation_id", req.CorrelationID)))
 
-	switch req.Operation {
-	case models.OperationTraverse:
-		response = h.handleTraverse(ctx, &req)
-	case models.OperationSearch:
-		response = h.handleSearch(ctx, &req)
-	case models.OperationEnrich:
-		response = h.handleEnrich(ctx, &req)
-	default:
-		response = models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Unknown operation: %s", req.Operation))
+	response := h.dispatch(ctx, &req)
+
+	var spanErr error
+	if !response.Success {
+		spanErr = errors.New(response.Error)
 	}
+	tracing.End(span, spanErr)
 
 	responseData, err := json.Marshal(response)
 	if err != nil {
@@ -64,14 +77,46 @@ func (h *DataHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 	return responseData
 }
 
+// dispatch runs a single operation
+func (h *DataHandler) dispatch(ctx context.Context, req *models.Request) *models.Response {
+	switch req.Operation {
+	case models.OperationTraverse:
+		return h.handleTraverse(ctx, req)
+	case models.OperationSearch:
+		return h.handleSearch(ctx, req)
+	case models.OperationEnrich:
+		return h.handleEnrich(ctx, req)
+	case models.OperationBatch:
+		return h.handleBatch(ctx, req)
+	case models.OperationStore:
+		return h.handleStore(ctx, req)
+	case models.OperationIndex:
+		return h.handleIndex(ctx, req)
+	default:
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Unknown operation: %s", req.Operation))
+	}
+}
+
 func (h *DataHandler) handleTraverse(ctx context.Context, req *models.Request) *models.Response {
 	if req.Query.Cypher == "" {
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, "Cypher query is required for traverse operation")
 	}
 
-	graphResult, err := h.neo4j.ExecuteCypher(ctx, req.Query.Cypher, nil)
+	params, err := normalizeCypherParameters(req.Query.Parameters)
+	if err != nil {
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Invalid query parameters: %v", err))
+	}
+
+	if response := unavailable(req, h.neo4j.Health()); response != nil {
+		return response
+	}
+
+	graphResult, err := h.neo4j.ExecuteCypher(ctx, req.Query.Cypher, params)
 	if err != nil {
 		logrus.WithError(err).Error("Neo4j query failed")
+		if response := failedUpstream(ctx, req, h.neo4j.Health()); response != nil {
+			return response
+		}
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Query failed: %v", err))
 	}
 
@@ -90,6 +135,13 @@ func (h *DataHandler) handleSearch(ctx context.Context, req *models.Request) *mo
 	var searchResults []clients.SearchResult
 	var err error
 
+	if response := unavailable(req, h.qdrant.Health()); response != nil {
+		return response
+	}
+	if response := unavailable(req, h.neo4j.Health()); response != nil {
+		return response
+	}
+
 	if len(req.Query.Embedding) > 0 {
 		limit := uint64(req.Limit)
 		if limit == 0 {
@@ -108,6 +160,9 @@ func (h *DataHandler) handleSearch(ctx context.Context, req *models.Request) *mo
 
 	if err != nil {
 		logrus.WithError(err).Error("Qdrant search failed")
+		if response := failedUpstream(ctx, req, h.qdrant.Health()); response != nil {
+			return response
+		}
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Search failed: %v", err))
 	}
 
@@ -121,6 +176,9 @@ func (h *DataHandler) handleSearch(ctx context.Context, req *models.Request) *mo
 	graphResult, err := h.neo4j.GetNodesByIds(ctx, nodeIDs)
 	if err != nil {
 		logrus.WithError(err).Error("Neo4j node retrieval failed")
+		if response := failedUpstream(ctx, req, h.neo4j.Health()); response != nil {
+			return response
+		}
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Node retrieval failed: %v", err))
 	}
 
@@ -146,9 +204,19 @@ func (h *DataHandler) handleEnrich(ctx context.Context, req *models.Request) *mo
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, "Node IDs are required for enrich operation")
 	}
 
+	if response := unavailable(req, h.neo4j.Health()); response != nil {
+		return response
+	}
+	if response := unavailable(req, h.mongo.Health()); response != nil {
+		return response
+	}
+
 	graphResult, err := h.neo4j.GetNodesByIds(ctx, req.Query.NodeIDs)
 	if err != nil {
 		logrus.WithError(err).Error("Neo4j node retrieval failed")
+		if response := failedUpstream(ctx, req, h.neo4j.Health()); response != nil {
+			return response
+		}
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Node retrieval failed: %v", err))
 	}
 
@@ -156,6 +224,9 @@ func (h *DataHandler) handleEnrich(ctx context.Context, req *models.Request) *mo
 
 	if err := h.enrichNodes(ctx, graphData.Nodes); err != nil {
 		logrus.WithError(err).Error("Enrichment failed")
+		if response := failedUpstream(ctx, req, h.mongo.Health()); response != nil {
+			return response
+		}
 		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Enrichment failed: %v", err))
 	}
 
@@ -195,12 +266,7 @@ func (h *DataHandler) shouldEnrich(enrichFields []string) bool {
 }
 
 func (h *DataHandler) enrichNodes(ctx context.Context, nodes []models.GraphNode) error {
-	nodeIDs := make([]string, len(nodes))
-	for i, node := range nodes {
-		nodeIDs[i] = node.ID
-	}
-
-	enrichmentData, err := h.mongo.GetEnrichmentData(ctx, nodeIDs)
+	enrichmentData, err := h.mongo.GetEnrichmentData(ctx, nodeIDs(nodes))
 	if err != nil {
 		return err
 	}
@@ -219,6 +285,24 @@ func (h *DataHandler) enrichNodes(ctx context.Context, nodes []models.GraphNode)
 	return nil
 }
 
+// unavailable returns an UPSTREAM_UNAVAILABLE response when the backend is known to be down,
+// so requests fail fast while it reconnects in the background
+func unavailable(req *models.Request, health *clients.BackendHealth) *models.Response {
+	if health.Available() {
+		return nil
+	}
+	return models.NewUnavailableResponse(req.CorrelationID, req.Operation, health.Name())
+}
+
+// failedUpstream returns an UPSTREAM_UNAVAILABLE response when a failed call turns out to
+// be a backend outage rather than a problem with the request
+func failedUpstream(ctx context.Context, req *models.Request, health *clients.BackendHealth) *models.Response {
+	if !health.Confirm(ctx) {
+		return nil
+	}
+	return models.NewUnavailableResponse(req.CorrelationID, req.Operation, health.Name())
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if strings.EqualFold(s, item) {
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+
+	"data-abstractor/clients"
+	"data-abstractor/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pointUUID matches the string IDs Qdrant accepts for points
+var pointUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+
+// handleIndex upserts the request's points into the Qdrant collection so vectors produced
+// by a workflow become searchable. Every vector must match the collection's dimensionality;
+// nothing is written if one doesn't.
+func (h *DataHandler) handleIndex(ctx context.Context, req *models.Request) *models.Response {
+	if len(req.Points) == 0 {
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, "Points are required for index operation")
+	}
+
+	points := make([]clients.Point, len(req.Points))
+	for i, point := range req.Points {
+		id, err := normalizePointID(point.ID)
+		if err != nil {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Point %d: %v", i, err))
+		}
+		if len(point.Vector) == 0 {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Point %d: vector is required", i))
+		}
+		points[i] = clients.Point{ID: id, Vector: point.Vector, Payload: point.Payload}
+	}
+
+	if response := unavailable(req, h.qdrant.Health()); response != nil {
+		return response
+	}
+
+	size, err := h.qdrant.VectorSize(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Qdrant collection lookup failed")
+		if response := failedUpstream(ctx, req, h.qdrant.Health()); response != nil {
+			return response
+		}
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Collection lookup failed: %v", err))
+	}
+	for i, point := range points {
+		if len(point.Vector) != size {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Point %d: vector has %d dimensions, collection %s expects %d", i, len(point.Vector), h.qdrant.Collection(), size))
+		}
+	}
+
+	indexed, err := h.qdrant.Upsert(ctx, points)
+	if err != nil {
+		logrus.WithError(err).WithField("indexed", indexed).Error("Qdrant upsert failed")
+		if response := failedUpstream(ctx, req, h.qdrant.Health()); response != nil {
+			return response
+		}
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Index failed after %d of %d points: %v", indexed, len(points), err))
+	}
+
+	return models.NewSuccessResponse(req.CorrelationID, req.Operation, &models.GraphData{
+		Nodes:         []models.GraphNode{},
+		Relationships: []models.GraphRelationship{},
+		Metadata: map[string]interface{}{
+			"collection": h.qdrant.Collection(),
+			"indexed":    indexed,
+		},
+	})
+}
+
+// normalizePointID converts a point ID decoded from JSON to the form Qdrant accepts: an
+// unsigned integer or a UUID string
+func normalizePointID(id interface{}) (interface{}, error) {
+	switch v := id.(type) {
+	case float64:
+		if v >= 0 && v == math.Trunc(v) && v < math.MaxUint64 {
+			return uint64(v), nil
+		}
+		return nil, fmt.Errorf("id %v is not an unsigned integer", v)
+	case string:
+		if pointUUID.MatchString(v) {
+			return v, nil
+		}
+		return nil, fmt.Errorf("id %q is not a UUID", v)
+	case nil:
+		return nil, fmt.Errorf("id is required")
+	default:
+		return nil, fmt.Errorf("id must be an unsigned integer or a UUID string")
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"data-abstractor/clients"
+	"data-abstractor/models"
+)
+
+// newIndexHandler returns a handler whose Qdrant collection "docs" has 3-dimensional
+// vectors, and the points upserted into it
+func newIndexHandler(t *testing.T) (*DataHandler, *[]clients.Point) {
+	t.Helper()
+	var upserted []clients.Point
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"result":{"config":{"params":{"vectors":{"size":3,"distance":"Cosine"}}}}}`))
+			return
+		}
+		var body struct {
+			Points []clients.Point `json:"points"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		upserted = append(upserted, body.Points...)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	qdrant, err := clients.NewQdrantClient(server.URL, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDataHandler(nil, nil, qdrant), &upserted
+}
+
+func runIndex(t *testing.T, handler *DataHandler, points string) *models.Response {
+	t.Helper()
+	request := `{"operation":"index","correlation_id":"req-1","points":` + points + `}`
+	var response models.Response
+	if err := json.Unmarshal(handler.HandleRequest(context.Background(), []byte(request)), &response); err != nil {
+		t.Fatal(err)
+	}
+	return &response
+}
+
+func TestIndexUpsertsPoints(t *testing.T) {
+	handler, upserted := newIndexHandler(t)
+
+	response := runIndex(t, handler, `[
+		{"id": 7, "vector": [0.1, 0.2, 0.3], "payload": {"node_id": "42"}},
+		{"id": "5c56c793-69f3-4fbf-87e6-c4bf54c28c26", "vector": [0.4, 0.5, 0.6]}
+	]`)
+	if !response.Success {
+		t.Fatalf("got %+v, want the points indexed", response)
+	}
+	if response.Data.Metadata["indexed"] != float64(2) || response.Data.Metadata["collection"] != "docs" {
+		t.Errorf("got metadata %v", response.Data.Metadata)
+	}
+
+	// Both kinds of point ID are passed through
+	if len(*upserted) != 2 || (*upserted)[0].ID != float64(7) || (*upserted)[1].ID != "5c56c793-69f3-4fbf-87e6-c4bf54c28c26" {
+		t.Fatalf("upserted %+v", *upserted)
+	}
+	if (*upserted)[0].Payload["node_id"] != "42" {
+		t.Errorf("got payload %v, want the node ID kept", (*upserted)[0].Payload)
+	}
+}
+
+func TestIndexRejectsInvalidPoints(t *testing.T) {
+	cases := []struct {
+		name   string
+		points string
+		want   string
+	}{
+		{"no points", `[]`, "Points are required for index operation"},
+		{"missing ID", `[{"vector": [0.1, 0.2, 0.3]}]`, "Point 0: id is required"},
+		{"negative ID", `[{"id": -1, "vector": [0.1, 0.2, 0.3]}]`, "Point 0: id -1 is not an unsigned integer"},
+		{"fractional ID", `[{"id": 1.5, "vector": [0.1, 0.2, 0.3]}]`, "Point 0: id 1.5 is not an unsigned integer"},
+		{"non-UUID string", `[{"id": "doc-1", "vector": [0.1, 0.2, 0.3]}]`, `Point 0: id "doc-1" is not a UUID`},
+		{"missing vector", `[{"id": 1, "vector": [0.1, 0.2, 0.3]}, {"id": 2}]`, "Point 1: vector is required"},
+		{"wrong dimensions", `[{"id": 1, "vector": [0.1, 0.2]}]`, "Point 0: vector has 2 dimensions, collection docs expects 3"},
+	}
+	for _, c := range cases {
+		handler, upserted := newIndexHandler(t)
+		response := runIndex(t, handler, c.points)
+		if response.Success || !strings.Contains(response.Error, c.want) {
+			t.Errorf("%s: got %+v, want %q", c.name, response, c.want)
+		}
+		if len(*upserted) != 0 {
+			t.Errorf("%s: expected nothing upserted, got %d points", c.name, len(*upserted))
+		}
+	}
+}
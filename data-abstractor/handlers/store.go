@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"data-abstractor/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handleStore upserts the request's documents into MongoDB, keyed by node ID, so results
+// computed by a workflow are available to later enrichment. Fields are merged into
+// existing documents unless replace is set.
+func (h *DataHandler) handleStore(ctx context.Context, req *models.Request) *models.Response {
+	if len(req.Documents) == 0 {
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, "Documents are required for store operation")
+	}
+	for nodeID, fields := range req.Documents {
+		if nodeID == "" {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, "Documents must be keyed by a non-empty node ID")
+		}
+		if !req.Replace && !hasStorableFields(fields) {
+			return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Document for node %s has no fields to merge", nodeID))
+		}
+	}
+
+	collection := req.Collection
+	if collection == "" {
+		collection = h.storeCollection
+	}
+
+	if response := unavailable(req, h.mongo.Health()); response != nil {
+		return response
+	}
+
+	result, err := h.mongo.UpsertEnrichmentData(ctx, collection, req.Documents, req.Replace)
+	if err != nil {
+		logrus.WithError(err).Error("MongoDB store failed")
+		if response := failedUpstream(ctx, req, h.mongo.Health()); response != nil {
+			return response
+		}
+		return models.NewErrorResponse(req.CorrelationID, req.Operation, fmt.Sprintf("Store failed: %v", err))
+	}
+
+	return models.NewSuccessResponse(req.CorrelationID, req.Operation, &models.GraphData{
+		Nodes:         []models.GraphNode{},
+		Relationships: []models.GraphRelationship{},
+		Metadata: map[string]interface{}{
+			"collection": collection,
+			"matched":    result.Matched,
+			"modified":   result.Modified,
+			"upserted":   result.Upserted,
+		},
+	})
+}
+
+// hasStorableFields reports whether a document has fields besides the ones store manages
+func hasStorableFields(fields map[string]interface{}) bool {
+	for k := range fields {
+		if k != "_id" && k != "node_id" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"data-abstractor/models"
+)
+
+func TestStoreRejectsInvalidDocuments(t *testing.T) {
+	cases := []struct {
+		name    string
+		request models.Request
+		want    string
+	}{
+		{"no documents", models.Request{}, "Documents are required for store operation"},
+		{"empty node ID", models.Request{Documents: map[string]map[string]interface{}{"": {"owner": "analytics"}}}, "Documents must be keyed by a non-empty node ID"},
+		{"only managed fields", models.Request{Documents: map[string]map[string]interface{}{"1": {"node_id": "1", "_id": "x"}}}, "Document for node 1 has no fields to merge"},
+	}
+
+	// Without a MongoDB client, reaching the backend would panic
+	handler := NewDataHandler(nil, nil, nil)
+	for _, c := range cases {
+		c.request.Operation = models.OperationStore
+		c.request.CorrelationID = "req-1"
+		data, _ := json.Marshal(c.request)
+
+		var response models.Response
+		if err := json.Unmarshal(handler.HandleRequest(context.Background(), data), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Success || response.Error != c.want {
+			t.Errorf("%s: got %+v, want %q", c.name, response, c.want)
+		}
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"data-abstractor/capabilities"
 	"data-abstractor/clients"
 	"data-abstractor/config"
 	"data-abstractor/handlers"
+	"data-abstractor/tracing"
 
 	"github.com/sirupsen/logrus"
 )
@@ -33,6 +35,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Setup tracing; spans are only exported when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(ctx, "data-abstractor", cfg.Tracing.OTLPEndpoint)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to setup tracing")
+	}
+
 	var wg sync.WaitGroup
 
 	neo4jClient, err := clients.NewNeo4jClient(cfg.Neo4j.URL, cfg.Neo4j.Username, cfg.Neo4j.Password)
@@ -65,7 +73,7 @@ func main() {
 		}
 	}()
 
-	redisClient, err := clients.NewRedisClient(cfg.Redis.URL, "data-requests", "data-responses")
+	redisClient, err := clients.NewRedisClient(cfg.Redis.URL, cfg.Redis.Channel, cfg.Redis.ResponseCh)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to connect to Redis")
 	}
@@ -76,6 +84,16 @@ func main() {
 	}()
 
 	dataHandler := handlers.NewDataHandler(neo4jClient, mongoClient, qdrantClient)
+	dataHandler.SetStoreCollection(cfg.MongoDB.StoreCollection)
+
+	// Probe backends so outages are detected and reconnected without a restart
+	for _, health := range []*clients.BackendHealth{neo4jClient.Health(), mongoClient.Health(), qdrantClient.Health()} {
+		wg.Add(1)
+		go func(health *clients.BackendHealth) {
+			defer wg.Done()
+			health.Monitor(ctx, cfg.App.HealthCheckInterval)
+		}(health)
+	}
 
 	// Initialize capability manager if enabled
 	var capabilityManager *capabilities.CapabilityManager
@@ -85,7 +103,14 @@ func main() {
 			capabilities.GetDataAbstractorCapabilities(),
 			redisClient.GetClient(),
 			cfg.Capabilities.RefreshInterval,
+			cfg.Redis.Namespace,
 		)
+		capabilityManager.SetSigningSecret(cfg.Capabilities.SigningSecret)
+		capabilityManager.SetDebounceWindow(cfg.Capabilities.AnnounceDebounce)
+		capabilityManager.SetServiceVersion(cfg.Capabilities.ServiceVersion)
+		if cfg.Capabilities.InstanceID != "" {
+			capabilityManager.SetInstance(cfg.Capabilities.InstanceID, cfg.Redis.Channel, cfg.Capabilities.InstanceWeight)
+		}
 
 		// Start capability manager
 		if err := capabilityManager.Start(ctx); err != nil {
@@ -95,6 +120,16 @@ func main() {
 		}
 	}
 
+	// Start status server exposing health and redacted configuration
+	statusServer := handlers.NewStatusServer("data-abstractor", cfg.Redacted())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := statusServer.Start(ctx, cfg.App.Port); err != nil {
+			logrus.WithError(err).Error("Status server failed")
+		}
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -125,5 +160,12 @@ func main() {
 	cancel()
 	wg.Wait()
 
+	// Flush spans still buffered for export
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logrus.WithError(err).Warn("Failed to flush traces")
+	}
+
 	logrus.Info("Data Abstractor service stopped")
 }
\ No newline at end of file
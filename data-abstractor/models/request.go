@@ -1,22 +1,46 @@
 package models
 
 type Request struct {
-	Operation     string      `json:"operation"`
-	CorrelationID string      `json:"correlation_id"`
-	Query         QueryData   `json:"query"`
-	Enrich        []string    `json:"enrich,omitempty"`
-	Limit         int         `json:"limit,omitempty"`
+	Operation     string            `json:"operation"`
+	CorrelationID string            `json:"correlation_id"`
+	Query         QueryData         `json:"query"`
+	Enrich        []string          `json:"enrich,omitempty"`
+	Limit         int               `json:"limit,omitempty"`
+	TraceContext  map[string]string `json:"trace_context,omitempty"` // W3C trace context of the caller's span
+
+	// Batch requests only
+	Steps           []Request `json:"steps,omitempty"`             // run in order
+	ContinueOnError bool      `json:"continue_on_error,omitempty"` // run later steps after a failure
+
+	// Store requests only
+	Documents  map[string]map[string]interface{} `json:"documents,omitempty"`  // fields to store, keyed by node ID
+	Collection string                            `json:"collection,omitempty"` // defaults to the configured store collection
+	Replace    bool                              `json:"replace,omitempty"`    // replace stored documents instead of merging fields into them
+
+	// Index requests only
+	Points []Point `json:"points,omitempty"`
+}
+
+// Point is a vector to index in Qdrant. IDs are unsigned integers or UUID strings.
+type Point struct {
+	ID      interface{}            `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"` // node_id links the point to a graph node in search results
 }
 
 type QueryData struct {
-	Cypher    string    `json:"cypher,omitempty"`
-	Text      string    `json:"text,omitempty"`
-	Embedding []float32 `json:"embedding,omitempty"`
-	NodeIDs   []string  `json:"node_ids,omitempty"`
+	Cypher     string                 `json:"cypher,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"` // bound to $name placeholders in cypher
+	Text       string                 `json:"text,omitempty"`
+	Embedding  []float32              `json:"embedding,omitempty"`
+	NodeIDs    []string               `json:"node_ids,omitempty"`
 }
 
 const (
 	OperationTraverse = "traverse"
 	OperationSearch   = "search"
 	OperationEnrich   = "enrich"
-)
\ No newline at end of file
+	OperationBatch    = "batch"
+	OperationStore    = "store"
+	OperationIndex    = "index"
+)
@@ -1,16 +1,25 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Response struct {
 	CorrelationID string      `json:"correlation_id"`
 	Success       bool        `json:"success"`
 	Data          *GraphData  `json:"data,omitempty"`
 	Error         string      `json:"error,omitempty"`
+	ErrorCode     string      `json:"error_code,omitempty"`
 	Timestamp     time.Time   `json:"timestamp"`
 	Operation     string      `json:"operation"`
+	Steps         []*Response `json:"steps,omitempty"` // per-step results of a batch
 }
 
+// ErrorCodeUpstreamUnavailable marks a failure caused by an unreachable backend; the
+// request can be retried once the backend is back
+const ErrorCodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+
 type GraphData struct {
 	Nodes         []GraphNode         `json:"nodes"`
 	Relationships []GraphRelationship `json:"relationships"`
@@ -43,6 +52,13 @@ func NewSuccessResponse(correlationID, operation string, data *GraphData) *Respo
 	}
 }
 
+// NewUnavailableResponse reports that a backend required by the operation is unreachable
+func NewUnavailableResponse(correlationID, operation, backend string) *Response {
+	response := NewErrorResponse(correlationID, operation, fmt.Sprintf("%s is unavailable, reconnecting", backend))
+	response.ErrorCode = ErrorCodeUpstreamUnavailable
+	return response
+}
+
 func NewErrorResponse(correlationID, operation, error string) *Response {
 	return &Response{
 		CorrelationID: correlationID,
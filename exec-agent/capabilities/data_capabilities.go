@@ -66,7 +66,7 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 						"nodes": []map[string]interface{}{
 							{
 								"id":         "node1",
-								"labels":     []string{"Document"],
+								"labels":     []string{"Document"},
 								"properties": map[string]interface{}{"title": "Document Title", "content": "..."},
 								"metadata":   map[string]interface{}{"source": "system1"},
 								"score":      0.95,
@@ -119,4 +119,4 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 			CorrelationField: "correlation_id",
 		},
 	}
-}
\ No newline at end of file
+}
@@ -12,7 +12,7 @@ import (
 type DynamicCapabilityManager struct {
 	*CapabilityManager
 	enhancedCapabilities *EnhancedExecCapabilities
-	logger               *logrus.Logger
+	logger               *logrus.Entry
 	imageRefreshInterval time.Duration
 	stopImageRefresh     chan struct{}
 }
@@ -24,16 +24,18 @@ func NewDynamicCapabilityManager(
 	redisClient *redis.Client,
 	refreshInterval time.Duration,
 	imageRefreshInterval time.Duration,
+	namespace string,
 ) *DynamicCapabilityManager {
 	// Get initial capabilities
 	initialCapabilities := enhancedCapabilities.GetExecAgentCapabilitiesWithImages()
-	
+
 	// Create the base capability manager
 	baseManager := NewCapabilityManager(
 		component,
 		initialCapabilities,
 		redisClient,
 		refreshInterval,
+		namespace,
 	)
 	
 	return &DynamicCapabilityManager{
@@ -114,18 +116,16 @@ func (dcm *DynamicCapabilityManager) refreshImageCapabilities(ctx context.Contex
 	
 	// Get new capabilities
 	newCapabilities := dcm.enhancedCapabilities.GetExecAgentCapabilitiesWithImages()
-	
-	// Update the capability manager's capabilities
-	oldCapabilities := dcm.CapabilityManager.capabilities
-	dcm.CapabilityManager.capabilities = newCapabilities
-	
+
 	// Calculate new hash
-	newHash, err := dcm.CapabilityManager.calculateCapabilityHash()
+	newHash, err := capabilityHash(newCapabilities)
 	if err != nil {
-		// Restore old capabilities on error
-		dcm.CapabilityManager.capabilities = oldCapabilities
 		return err
 	}
+
+	// Update the capability manager's capabilities
+	oldCapabilities := dcm.CapabilityManager.GetCapabilities()
+	dcm.CapabilityManager.setCapabilities(newCapabilities)
 	
 	// If capabilities changed, announce them
 	if newHash != oldHash {
@@ -135,8 +135,8 @@ func (dcm *DynamicCapabilityManager) refreshImageCapabilities(ctx context.Contex
 			"old_operations": len(oldCapabilities.Operations),
 			"new_operations": len(newCapabilities.Operations),
 		}).Info("Image capabilities changed, announcing update")
-		
-		if err := dcm.CapabilityManager.announceCapabilities(ctx, TriggerConfigChange); err != nil {
+
+		if err := dcm.CapabilityManager.scheduleAnnouncement(ctx, TriggerConfigChange); err != nil {
 			dcm.logger.WithError(err).Error("Failed to announce updated capabilities")
 			return err
 		}
@@ -10,10 +10,13 @@ func GetExecAgentCapabilities() *ServiceCapabilities {
 				InputExample: map[string]interface{}{
 					"correlation_id": "unique-request-id",
 					"container": map[string]interface{}{
-						"image":       "python:3.9-slim",
-						"command":     []string{"python", "/workspace/input/script.py"},
-						"working_dir": "/workspace",
-						"ports":       map[string]string{"8080": "8080"},
+						"image":        "python:3.9-slim",
+						"command":      []string{"python", "/workspace/input/script.py"},
+						"working_dir":  "/workspace",
+						"ports":        map[string]string{"8080": "8080"},
+						"cpu_limit":    1,
+						"memory_limit": "512m",
+						"pids_limit":   256,
 					},
 					"input": map[string]interface{}{
 						"graph_data": map[string]interface{}{
@@ -228,6 +231,31 @@ func GetExecAgentCapabilities() *ServiceCapabilities {
 				RetrySafe:         false,
 				EstimatedDuration: "30s-15m",
 			},
+			{
+				Name:        "execution_status",
+				Description: "Look up the recorded lifecycle of an execution by its ID, or by workflow execution and task",
+				InputExample: map[string]interface{}{
+					"correlation_id": "status-001",
+					"operation":      "status",
+					"exec_id":        "exec_analysis_001",
+				},
+				OutputExample: map[string]interface{}{
+					"correlation_id": "status-001",
+					"success":        true,
+					"execution_id":   "exec_analysis_001",
+					"status": map[string]interface{}{
+						"exec_id":    "exec_analysis_001",
+						"status":     "completed",
+						"queued_at":  "2025-01-01T00:00:00Z",
+						"start_time": "2025-01-01T00:00:01Z",
+						"end_time":   "2025-01-01T00:00:06Z",
+						"exit_code":  0,
+						"updated_at": "2025-01-01T00:00:06Z",
+					},
+				},
+				RetrySafe:         true,
+				EstimatedDuration: "10ms-100ms",
+			},
 		},
 		MessagePatterns: MessagePatterns{
 			RequestChannel:   "exec-requests",
@@ -235,4 +263,4 @@ func GetExecAgentCapabilities() *ServiceCapabilities {
 			CorrelationField: "correlation_id",
 		},
 	}
-}
\ No newline at end of file
+}
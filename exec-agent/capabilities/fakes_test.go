@@ -0,0 +1,110 @@
+package capabilities
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeDockerAPI is a Docker daemon with images held in memory. Pulling an image from
+// registry makes it local.
+type fakeDockerAPI struct {
+	mutex          sync.Mutex
+	local          map[string]types.ImageInspect
+	registry       map[string]types.ImageInspect
+	pullErrors     map[string]error  // returned by the pull request itself
+	progressErrors map[string]string // reported midway through the pull progress stream
+	files          map[string]string // capabilities file content by image
+	pulls          map[string]types.ImagePullOptions
+	containers     map[string]*container.Config
+	removed        []string
+}
+
+func newFakeDockerAPI() *fakeDockerAPI {
+	return &fakeDockerAPI{
+		local:          make(map[string]types.ImageInspect),
+		registry:       make(map[string]types.ImageInspect),
+		pullErrors:     make(map[string]error),
+		progressErrors: make(map[string]string),
+		files:          make(map[string]string),
+		pulls:          make(map[string]types.ImagePullOptions),
+		containers:     make(map[string]*container.Config),
+	}
+}
+
+func (f *fakeDockerAPI) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	inspect, exists := f.local[imageID]
+	if !exists {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("No such image: %s", imageID))
+	}
+	return inspect, nil, nil
+}
+
+func (f *fakeDockerAPI) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.pulls[ref] = options
+	if err := f.pullErrors[ref]; err != nil {
+		return nil, err
+	}
+
+	progress := `{"status":"Pulling from ` + ref + `"}` + "\n"
+	if message, failed := f.progressErrors[ref]; failed {
+		return io.NopCloser(strings.NewReader(progress + `{"error":"` + message + `"}` + "\n")), nil
+	}
+	inspect, exists := f.registry[ref]
+	if !exists {
+		return nil, errdefs.NotFound(fmt.Errorf("manifest for %s not found", ref))
+	}
+	f.local[ref] = inspect
+	return io.NopCloser(strings.NewReader(progress + `{"status":"Downloaded newer image"}` + "\n")), nil
+}
+
+func (f *fakeDockerAPI) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	id := fmt.Sprintf("container-%d", len(f.containers)+1)
+	f.containers[id] = config
+	return container.CreateResponse{ID: id}, nil
+}
+
+func (f *fakeDockerAPI) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	config, exists := f.containers[containerID]
+	if !exists {
+		return nil, types.ContainerPathStat{}, errdefs.NotFound(errors.New("No such container"))
+	}
+	content, exists := f.files[config.Image]
+	if !exists || srcPath != capabilitiesFilePath {
+		return nil, types.ContainerPathStat{}, errdefs.NotFound(fmt.Errorf("Could not find the file %s in container %s", srcPath, containerID))
+	}
+
+	// The daemon sends the file as the single entry of a tar stream
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	writer.WriteHeader(&tar.Header{Name: "capabilities.json", Mode: 0644, Size: int64(len(content))})
+	writer.Write([]byte(content))
+	writer.Close()
+	return io.NopCloser(&archive), types.ContainerPathStat{Name: "capabilities.json", Size: int64(len(content))}, nil
+}
+
+func (f *fakeDockerAPI) ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.removed = append(f.removed, containerID)
+	return nil
+}
@@ -1,17 +1,38 @@
 package capabilities
 
 import (
+	"archive/tar"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
+	"io"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 )
 
+// capabilitiesFilePath is where an image may declare its operations
+const capabilitiesFilePath = "/app/capabilities.json"
+
+// maxCapabilitiesFileSize bounds how much of a capabilities file is read
+const maxCapabilitiesFileSize = 1 << 20
+
+// DockerAPI is the part of the Docker Engine API the scanner uses
+type DockerAPI interface {
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, types.ContainerPathStat, error)
+	ContainerRemove(ctx context.Context, containerID string, options types.ContainerRemoveOptions) error
+}
+
 // ImageCapability represents the capabilities defined in a container image
 type ImageCapability struct {
 	ImageName    string      `json:"image_name"`
@@ -31,16 +52,19 @@ type ImageMeta struct {
 
 // ImageScanner scans Docker images for capability information
 type ImageScanner struct {
+	docker            DockerAPI
 	imageCapabilities map[string]*ImageCapability
 	mutex             sync.RWMutex
-	logger            *logrus.Logger
+	logger            *logrus.Entry
 	scanInterval      time.Duration
 	knownImages       []string
+	registryAuth      map[string]RegistryCredentials
 }
 
-// NewImageScanner creates a new image scanner
-func NewImageScanner(knownImages []string, scanInterval time.Duration) *ImageScanner {
+// NewImageScanner creates a new image scanner talking to the Docker daemon through docker
+func NewImageScanner(docker DockerAPI, knownImages []string, scanInterval time.Duration) *ImageScanner {
 	return &ImageScanner{
+		docker:            docker,
 		imageCapabilities: make(map[string]*ImageCapability),
 		logger:            logrus.WithField("component", "image_scanner"),
 		scanInterval:      scanInterval,
@@ -105,64 +129,68 @@ func (is *ImageScanner) scanImage(ctx context.Context, imageName string) error {
 // pullImageIfNeeded pulls the image if it's not available locally
 func (is *ImageScanner) pullImageIfNeeded(ctx context.Context, imageName string) error {
 	// Check if image exists locally
-	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", imageName)
-	if err := cmd.Run(); err == nil {
-		// Image already exists
+	_, _, err := is.docker.ImageInspectWithRaw(ctx, imageName)
+	if err == nil {
 		return nil
 	}
-	
-	is.logger.WithField("image", imageName).Info("Pulling image")
-	
-	// Pull the image
-	cmd = exec.CommandContext(ctx, "docker", "pull", imageName)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("docker pull failed: %w", err)
+	if !client.IsErrNotFound(err) {
+		return fmt.Errorf("image inspect failed: %w", err)
+	}
+
+	host, credentials, authenticated := is.credentialsFor(imageName)
+	is.logger.WithFields(logrus.Fields{
+		"image":         imageName,
+		"registry":      host,
+		"authenticated": authenticated,
+	}).Info("Pulling image")
+
+	options := types.ImagePullOptions{}
+	if authenticated {
+		options.RegistryAuth, err = encodeRegistryAuth(host, credentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pull the image; the daemon reports failures midway through the progress stream
+	progress, err := is.docker.ImagePull(ctx, imageName, options)
+	if err != nil {
+		return pullError(imageName, host, err)
+	}
+	defer progress.Close()
+
+	decoder := json.NewDecoder(progress)
+	for {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if message.Error != "" {
+			return pullError(imageName, host, fmt.Errorf("%s", message.Error))
+		}
 	}
-	
-	return nil
 }
 
 // getImageMetadata extracts metadata from the image
 func (is *ImageScanner) getImageMetadata(ctx context.Context, imageName string) (*ImageMeta, error) {
-	// Get image inspection data
-	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", imageName)
-	output, err := cmd.Output()
+	inspect, _, err := is.docker.ImageInspectWithRaw(ctx, imageName)
 	if err != nil {
-		return nil, fmt.Errorf("docker inspect failed: %w", err)
-	}
-	
-	// Parse JSON output
-	var inspectData []map[string]interface{}
-	if err := json.Unmarshal(output, &inspectData); err != nil {
-		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
-	}
-	
-	if len(inspectData) == 0 {
-		return nil, fmt.Errorf("no inspect data found")
+		return nil, fmt.Errorf("image inspect failed: %w", err)
 	}
-	
-	imageData := inspectData[0]
-	
-	// Extract metadata
+
 	metadata := &ImageMeta{
 		Labels: make(map[string]string),
+		Size:   inspect.Size,
 	}
-	
+
 	// Extract labels
-	if labelsInterface, exists := imageData["Config"].(map[string]interface{})["Labels"]; exists && labelsInterface != nil {
-		if labels, ok := labelsInterface.(map[string]interface{}); ok {
-			for key, value := range labels {
-				if valueStr, ok := value.(string); ok {
-					metadata.Labels[key] = valueStr
-				}
-			}
-		}
-	}
-	
-	// Extract size
-	if sizeInterface, exists := imageData["Size"]; exists {
-		if size, ok := sizeInterface.(float64); ok {
-			metadata.Size = int64(size)
+	if inspect.Config != nil {
+		for key, value := range inspect.Config.Labels {
+			metadata.Labels[key] = value
 		}
 	}
 	
@@ -203,14 +231,41 @@ func (is *ImageScanner) extractCapabilities(ctx context.Context, imageName strin
 	return operations, nil
 }
 
-// extractCapabilitiesFromFile extracts capabilities from a file in the image
+// extractCapabilitiesFromFile extracts capabilities from a file in the image. The file is
+// copied out of a container that is created but never started.
 func (is *ImageScanner) extractCapabilitiesFromFile(ctx context.Context, imageName string) ([]Operation, error) {
-	// Try to extract capabilities.json from the image
-	cmd := exec.CommandContext(ctx, "docker", "run", "--rm", imageName, "cat", "/app/capabilities.json")
-	output, err := cmd.Output()
+	created, err := is.docker.ContainerCreate(ctx, &container.Config{
+		Image:           imageName,
+		Cmd:             []string{"true"}, // never run; images without a command need one to be created
+		NetworkDisabled: true,
+	}, nil, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	defer func() {
+		// The request context may be cancelled; the container should still go
+		removeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := is.docker.ContainerRemove(removeCtx, created.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			is.logger.WithError(err).WithField("container_id", created.ID).Warn("Failed to remove capability scan container")
+		}
+	}()
+
+	archive, _, err := is.docker.CopyFromContainer(ctx, created.ID, capabilitiesFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("no capabilities file found")
 	}
+	defer archive.Close()
+
+	// The file arrives as the single entry of a tar stream
+	reader := tar.NewReader(archive)
+	if _, err := reader.Next(); err != nil {
+		return nil, fmt.Errorf("failed to read capabilities file: %w", err)
+	}
+	output, err := io.ReadAll(io.LimitReader(reader, maxCapabilitiesFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capabilities file: %w", err)
+	}
 	
 	var operations []Operation
 	if err := json.Unmarshal(output, &operations); err != nil {
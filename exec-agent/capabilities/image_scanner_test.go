@@ -0,0 +1,127 @@
+package capabilities
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// operationNames returns the names of operations in order
+func operationNames(operations []Operation) []string {
+	names := make([]string, len(operations))
+	for i, operation := range operations {
+		names[i] = operation.Name
+	}
+	return names
+}
+
+func TestScanImageReadsLabelsAndCapabilitiesFile(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.local["acme/reports:2"] = types.ImageInspect{
+		Size: 4096,
+		Config: &container.Config{Labels: map[string]string{
+			"version":                 "2.1.0",
+			"description":             "Report builder",
+			"author":                  "data team",
+			"exec-agent.capabilities": `[{"name": "render_report", "retry_safe": true}]`,
+			"framework":               "python",
+		}},
+	}
+	docker.files["acme/reports:2"] = `[{"name": "export_pdf", "description": "Export a report as PDF"}]`
+	scanner := NewImageScanner(docker, []string{"acme/reports:2"}, 0)
+
+	if err := scanner.ScanAllImages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	capability, exists := scanner.GetImageCapability("acme/reports:2")
+	if !exists {
+		t.Fatal("expected the image to be scanned")
+	}
+	want := []string{"render_report", "export_pdf", "python_custom"}
+	if got := operationNames(capability.Operations); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("got operations %v, want label, file, then inferred operations %v", got, want)
+	}
+	if !capability.Operations[0].RetrySafe || capability.Operations[1].Description != "Export a report as PDF" {
+		t.Errorf("got operations %+v, want their declared fields", capability.Operations)
+	}
+	meta := capability.Metadata
+	if meta.Version != "2.1.0" || meta.Description != "Report builder" || meta.Author != "data team" || meta.Size != 4096 {
+		t.Errorf("got metadata %+v", meta)
+	}
+
+	// The file is copied from a container that is never started and is removed afterwards
+	if len(docker.pulls) != 0 {
+		t.Errorf("got pulls %v, want none for a local image", docker.pulls)
+	}
+	if len(docker.containers) != 1 || len(docker.removed) != 1 {
+		t.Fatalf("got %d containers created and %d removed, want one of each", len(docker.containers), len(docker.removed))
+	}
+	if config := docker.containers[docker.removed[0]]; config.Image != "acme/reports:2" || !config.NetworkDisabled {
+		t.Errorf("got container config %+v, want the scanned image without a network", config)
+	}
+}
+
+func TestScanImagePullsMissingImage(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.registry["acme/etl-python:1"] = types.ImageInspect{Config: &container.Config{}}
+	scanner := NewImageScanner(docker, []string{"acme/etl-python:1"}, 0)
+
+	if err := scanner.ScanAllImages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, pulled := docker.pulls["acme/etl-python:1"]; !pulled {
+		t.Error("expected the missing image to be pulled")
+	}
+	capability, exists := scanner.GetImageCapability("acme/etl-python:1")
+	if !exists {
+		t.Fatal("expected the pulled image to be scanned")
+	}
+
+	// Without labels or a capabilities file, operations are inferred from the name
+	operations := make(map[string]bool)
+	for _, name := range operationNames(capability.Operations) {
+		operations[name] = true
+	}
+	if len(operations) != 2 || !operations["etl_processing"] || !operations["python_execution"] {
+		t.Errorf("got operations %v, want them inferred from the image name", operations)
+	}
+	if len(docker.removed) != 1 {
+		t.Errorf("got %d containers removed, want the scan container removed without a file", len(docker.removed))
+	}
+}
+
+func TestScanImageSkipsImagesThatFailToPull(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.progressErrors["acme/broken:1"] = "failed to register layer"
+	docker.local["acme/tool:1"] = types.ImageInspect{}
+	scanner := NewImageScanner(docker, []string{"acme/broken:1", "acme/tool:1"}, 0)
+
+	if err := scanner.ScanAllImages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := scanner.GetImageCapability("acme/broken:1"); exists {
+		t.Error("expected an image that failed to pull not to be recorded")
+	}
+	if _, exists := scanner.GetImageCapability("acme/tool:1"); !exists {
+		t.Error("expected the remaining images to still be scanned")
+	}
+}
+
+func TestCapabilitiesFileMustBeValid(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.local["acme/tool:1"] = types.ImageInspect{}
+	docker.files["acme/tool:1"] = `{"name": "not a list"}`
+	scanner := NewImageScanner(docker, nil, 0)
+
+	if _, err := scanner.extractCapabilitiesFromFile(context.Background(), "acme/tool:1"); err == nil {
+		t.Error("expected an invalid capabilities file to be rejected")
+	}
+	if len(docker.removed) != 1 {
+		t.Errorf("got %d containers removed, want the scan container removed", len(docker.removed))
+	}
+}
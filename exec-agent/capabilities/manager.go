@@ -2,26 +2,42 @@ package capabilities
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"exec-agent/config"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 )
 
 // CapabilityManager handles service capability announcements
 type CapabilityManager struct {
-	component              string
-	capabilities           *ServiceCapabilities
-	redisClient           *redis.Client
-	logger                *logrus.Logger
-	refreshInterval       time.Duration
-	lastCapabilityHash    string
-	stopChan              chan struct{}
-	refreshRequestSub     *redis.PubSub
+	component          string
+	namespace          string
+	capabilities       *ServiceCapabilities
+	redisClient        *redis.Client
+	logger             *logrus.Entry
+	refreshInterval    time.Duration
+	lastCapabilityHash string
+	stateMu            sync.Mutex // guards capabilities and lastCapabilityHash
+	stopChan           chan struct{}
+	refreshRequestSub  *redis.PubSub
+	signingSecret      string
+	debounceWindow     time.Duration
+	pendingMu          sync.Mutex
+	pendingTimer       *time.Timer
+	pendingTrigger     AnnouncementTrigger
+	pendingSeq         uint64
+	instanceID         string
+	instanceChannel    string
+	instanceWeight     int
+	serviceVersion     string
 }
 
 // ServiceCapabilities represents the complete capability information for a service
@@ -49,10 +65,16 @@ type MessagePatterns struct {
 
 // CapabilityAnnouncement represents a capability announcement message
 type CapabilityAnnouncement struct {
-	Component    string               `json:"component"`
-	Timestamp    string               `json:"timestamp"`
-	Trigger      string               `json:"trigger"`
-	Capabilities *ServiceCapabilities `json:"capabilities"`
+	Component       string               `json:"component"`
+	Version         string               `json:"version"`
+	Timestamp       string               `json:"timestamp"`
+	Trigger         string               `json:"trigger"`
+	Capabilities    *ServiceCapabilities `json:"capabilities"`
+	Signature       string               `json:"signature,omitempty"`        // HMAC-SHA256 when a signing secret is set
+	Instance        string               `json:"instance,omitempty"`         // replica identifier when several replicas run
+	InstanceChannel string               `json:"instance_channel,omitempty"` // request channel consumed only by this replica
+	InstanceWeight  int                  `json:"instance_weight,omitempty"`  // relative share of requests for this replica
+	ServiceVersion  string               `json:"service_version,omitempty"`  // release this replica runs, for pinned workflows
 }
 
 // AnnouncementTrigger defines the possible triggers for capability announcements
@@ -65,20 +87,28 @@ const (
 	TriggerRefreshRequest AnnouncementTrigger = "refresh_request"
 )
 
+const (
+	// debouncedAnnouncementTimeout bounds a debounced announcement, which runs after the
+	// caller that triggered it has returned
+	debouncedAnnouncementTimeout = 10 * time.Second
+)
+
 const (
 	DefaultRefreshInterval = 5 * time.Minute
 	AnnouncementChannel    = "service_capability_announcements"
 	RefreshRequestChannel  = "capability_refresh_request"
 )
 
-// NewCapabilityManager creates a new capability manager
-func NewCapabilityManager(component string, capabilities *ServiceCapabilities, redisClient *redis.Client, refreshInterval time.Duration) *CapabilityManager {
+// NewCapabilityManager creates a new capability manager. The namespace, when set,
+// is prefixed onto the announcement and refresh channels to isolate environments.
+func NewCapabilityManager(component string, capabilities *ServiceCapabilities, redisClient *redis.Client, refreshInterval time.Duration, namespace string) *CapabilityManager {
 	if refreshInterval == 0 {
 		refreshInterval = DefaultRefreshInterval
 	}
 
 	return &CapabilityManager{
 		component:       component,
+		namespace:       namespace,
 		capabilities:    capabilities,
 		redisClient:     redisClient,
 		logger:          logrus.WithField("component", "capability_manager"),
@@ -87,6 +117,52 @@ func NewCapabilityManager(component string, capabilities *ServiceCapabilities, r
 	}
 }
 
+// SetSigningSecret signs every announcement with an HMAC of the shared secret so the
+// orchestrator can reject forged announcements
+func (cm *CapabilityManager) SetSigningSecret(secret string) {
+	cm.signingSecret = secret
+}
+
+// SetDebounceWindow coalesces config-change and refresh-request announcements made
+// within the window into a single announcement of the latest state. Zero announces
+// immediately.
+func (cm *CapabilityManager) SetDebounceWindow(window time.Duration) {
+	cm.debounceWindow = window
+}
+
+// SetInstance identifies this replica in announcements. A replica that consumes its own
+// request channel advertises it so the orchestrator can balance requests across replicas.
+func (cm *CapabilityManager) SetInstance(id, requestChannel string, weight int) {
+	cm.instanceID = id
+	cm.instanceChannel = requestChannel
+	cm.instanceWeight = weight
+}
+
+// SetServiceVersion announces the release this replica runs. Unlike the capability hash
+// it only changes on deployment, so workflows pin it to stay on one release.
+func (cm *CapabilityManager) SetServiceVersion(version string) {
+	cm.serviceVersion = version
+}
+
+// SignAnnouncement computes the hex HMAC-SHA256 of an announcement payload. The payload
+// is canonicalized (sorted keys, signature removed) so the receiver can recompute it.
+func SignAnnouncement(secret string, payload []byte) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return "", err
+	}
+	delete(fields, "signature")
+
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(canonical)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
 // Start begins the capability management processes
 func (cm *CapabilityManager) Start(ctx context.Context) error {
 	cm.logger.WithField("component", cm.component).Info("Starting capability manager")
@@ -111,6 +187,13 @@ func (cm *CapabilityManager) Stop() {
 	cm.logger.Info("Stopping capability manager")
 	close(cm.stopChan)
 
+	cm.pendingMu.Lock()
+	if cm.pendingTimer != nil {
+		cm.pendingTimer.Stop()
+		cm.pendingTimer = nil
+	}
+	cm.pendingMu.Unlock()
+
 	if cm.refreshRequestSub != nil {
 		if err := cm.refreshRequestSub.Close(); err != nil {
 			cm.logger.WithError(err).Warn("Error closing refresh request subscription")
@@ -118,10 +201,62 @@ func (cm *CapabilityManager) Stop() {
 	}
 }
 
-// UpdateCapabilities updates the service capabilities and announces if changed
+// UpdateCapabilities updates the service capabilities and announces if changed. With a
+// debounce window set the announcement is deferred and publish errors are only logged.
 func (cm *CapabilityManager) UpdateCapabilities(ctx context.Context, newCapabilities *ServiceCapabilities) error {
+	cm.setCapabilities(newCapabilities)
+	return cm.scheduleAnnouncement(ctx, TriggerConfigChange)
+}
+
+// setCapabilities replaces the capabilities announced from now on
+func (cm *CapabilityManager) setCapabilities(newCapabilities *ServiceCapabilities) {
+	cm.stateMu.Lock()
+	defer cm.stateMu.Unlock()
 	cm.capabilities = newCapabilities
-	return cm.announceCapabilities(ctx, TriggerConfigChange)
+}
+
+// scheduleAnnouncement announces immediately, or restarts the debounce timer so that a
+// burst of triggers produces one announcement once the window has been quiet. The
+// deferred announcement does not use ctx, which may be gone by the time it runs.
+func (cm *CapabilityManager) scheduleAnnouncement(ctx context.Context, trigger AnnouncementTrigger) error {
+	if cm.debounceWindow <= 0 {
+		return cm.announceCapabilities(ctx, trigger)
+	}
+
+	cm.pendingMu.Lock()
+	defer cm.pendingMu.Unlock()
+
+	if cm.pendingTimer != nil {
+		cm.pendingTimer.Stop()
+	}
+	cm.pendingSeq++
+	seq := cm.pendingSeq
+	cm.pendingTrigger = trigger
+	cm.pendingTimer = time.AfterFunc(cm.debounceWindow, func() {
+		cm.flushAnnouncement(seq)
+	})
+
+	return nil
+}
+
+// flushAnnouncement publishes a debounced announcement unless a newer trigger has
+// superseded it. The current capabilities are read at publish time, so the latest wins.
+func (cm *CapabilityManager) flushAnnouncement(seq uint64) {
+	cm.pendingMu.Lock()
+	if seq != cm.pendingSeq || cm.pendingTimer == nil {
+		cm.pendingMu.Unlock()
+		return
+	}
+	trigger := cm.pendingTrigger
+	cm.pendingTimer = nil
+	cm.pendingMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), debouncedAnnouncementTimeout)
+	defer cancel()
+
+	if err := cm.announceCapabilities(ctx, trigger); err != nil {
+		cm.logger.WithError(err).Warn("Failed to publish debounced capability announcement")
+	}
 }
 
 // periodicRefresh periodically checks and announces capabilities if changed
@@ -147,7 +282,7 @@ func (cm *CapabilityManager) periodicRefresh(ctx context.Context) {
 
 // listenForRefreshRequests listens for refresh requests and responds
 func (cm *CapabilityManager) listenForRefreshRequests(ctx context.Context) {
-	cm.refreshRequestSub = cm.redisClient.Subscribe(ctx, RefreshRequestChannel)
+	cm.refreshRequestSub = cm.redisClient.Subscribe(ctx, config.Namespaced(cm.namespace, RefreshRequestChannel))
 	defer func() {
 		if err := cm.refreshRequestSub.Close(); err != nil {
 			cm.logger.WithError(err).Warn("Error closing refresh request subscription")
@@ -173,7 +308,7 @@ func (cm *CapabilityManager) listenForRefreshRequests(ctx context.Context) {
 				}
 			}
 
-			if err := cm.announceCapabilities(ctx, TriggerRefreshRequest); err != nil {
+			if err := cm.scheduleAnnouncement(ctx, TriggerRefreshRequest); err != nil {
 				cm.logger.WithError(err).Warn("Failed to announce capabilities on refresh request")
 			}
 
@@ -189,24 +324,38 @@ func (cm *CapabilityManager) listenForRefreshRequests(ctx context.Context) {
 
 // announceCapabilities publishes capability information to Redis
 func (cm *CapabilityManager) announceCapabilities(ctx context.Context, trigger AnnouncementTrigger) error {
+	cm.stateMu.Lock()
+	defer cm.stateMu.Unlock()
+	return cm.announceLocked(ctx, trigger, false)
+}
+
+// announceLocked publishes the current capabilities; force announces a periodic refresh
+// even when nothing changed. Callers must hold stateMu, which also keeps concurrent
+// announcements from being published out of order.
+func (cm *CapabilityManager) announceLocked(ctx context.Context, trigger AnnouncementTrigger, force bool) error {
 	// Calculate hash of current capabilities
-	currentHash, err := cm.calculateCapabilityHash()
+	currentHash, err := capabilityHash(cm.capabilities)
 	if err != nil {
 		return fmt.Errorf("failed to calculate capability hash: %w", err)
 	}
 
 	// Only announce if capabilities have changed or if it's a forced trigger
-	if trigger == TriggerPeriodicRefresh && currentHash == cm.lastCapabilityHash {
+	if !force && trigger == TriggerPeriodicRefresh && currentHash == cm.lastCapabilityHash {
 		cm.logger.Debug("Capabilities unchanged, skipping periodic announcement")
 		return nil
 	}
 
 	// Create announcement
 	announcement := &CapabilityAnnouncement{
-		Component:    cm.component,
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
-		Trigger:      string(trigger),
-		Capabilities: cm.capabilities,
+		Component:       cm.component,
+		Version:         currentHash[:12], // capability hash identifies the announced version
+		Timestamp:       time.Now().UTC().Format(time.RFC3339),
+		Trigger:         string(trigger),
+		Capabilities:    cm.capabilities,
+		Instance:        cm.instanceID,
+		InstanceChannel: cm.instanceChannel,
+		InstanceWeight:  cm.instanceWeight,
+		ServiceVersion:  cm.serviceVersion,
 	}
 
 	// Marshal to JSON
@@ -215,8 +364,19 @@ func (cm *CapabilityManager) announceCapabilities(ctx context.Context, trigger A
 		return fmt.Errorf("failed to marshal capability announcement: %w", err)
 	}
 
+	// Sign the announcement if a shared secret is configured
+	if cm.signingSecret != "" {
+		announcement.Signature, err = SignAnnouncement(cm.signingSecret, data)
+		if err != nil {
+			return fmt.Errorf("failed to sign capability announcement: %w", err)
+		}
+		if data, err = json.Marshal(announcement); err != nil {
+			return fmt.Errorf("failed to marshal capability announcement: %w", err)
+		}
+	}
+
 	// Publish to Redis
-	if err := cm.redisClient.Publish(ctx, AnnouncementChannel, data).Err(); err != nil {
+	if err := cm.redisClient.Publish(ctx, config.Namespaced(cm.namespace, AnnouncementChannel), data).Err(); err != nil {
 		return fmt.Errorf("failed to publish capability announcement: %w", err)
 	}
 
@@ -233,9 +393,9 @@ func (cm *CapabilityManager) announceCapabilities(ctx context.Context, trigger A
 	return nil
 }
 
-// calculateCapabilityHash calculates a hash of the current capabilities
-func (cm *CapabilityManager) calculateCapabilityHash() (string, error) {
-	data, err := json.Marshal(cm.capabilities)
+// capabilityHash calculates a hash of a set of capabilities
+func capabilityHash(capabilities *ServiceCapabilities) (string, error) {
+	data, err := json.Marshal(capabilities)
 	if err != nil {
 		return "", err
 	}
@@ -246,21 +406,21 @@ func (cm *CapabilityManager) calculateCapabilityHash() (string, error) {
 
 // GetCapabilities returns the current capabilities
 func (cm *CapabilityManager) GetCapabilities() *ServiceCapabilities {
+	cm.stateMu.Lock()
+	defer cm.stateMu.Unlock()
 	return cm.capabilities
 }
 
 // GetLastHash returns the hash of the last announced capabilities
 func (cm *CapabilityManager) GetLastHash() string {
+	cm.stateMu.Lock()
+	defer cm.stateMu.Unlock()
 	return cm.lastCapabilityHash
 }
 
 // ForceAnnouncement forces a capability announcement regardless of changes
 func (cm *CapabilityManager) ForceAnnouncement(ctx context.Context, trigger AnnouncementTrigger) error {
-	originalHash := cm.lastCapabilityHash
-	cm.lastCapabilityHash = "" // Force announcement by clearing hash
-	err := cm.announceCapabilities(ctx, trigger)
-	if err != nil {
-		cm.lastCapabilityHash = originalHash // Restore original hash on error
-	}
-	return err
-}
\ No newline at end of file
+	cm.stateMu.Lock()
+	defer cm.stateMu.Unlock()
+	return cm.announceLocked(ctx, trigger, true)
+}
@@ -0,0 +1,91 @@
+package capabilities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+)
+
+// ErrRegistryAuth is returned when a registry rejects the scanner's credentials or
+// requires credentials that are not configured
+var ErrRegistryAuth = errors.New("registry authentication failed")
+
+// dockerHubRegistry is the registry of images whose name has no registry host
+const dockerHubRegistry = "docker.io"
+
+// RegistryCredentials authenticate pulls from a private registry
+type RegistryCredentials struct {
+	Username string
+	Password string // password or access token
+}
+
+// SetRegistryAuth sets the credentials used to pull images, keyed by registry host
+// (e.g. "registry.example.com:5000", or "docker.io" for Docker Hub)
+func (is *ImageScanner) SetRegistryAuth(auth map[string]RegistryCredentials) {
+	is.registryAuth = auth
+}
+
+// credentialsFor selects the credentials for an image's registry
+func (is *ImageScanner) credentialsFor(imageName string) (string, RegistryCredentials, bool) {
+	host := registryHost(imageName)
+	credentials, exists := is.registryAuth[host]
+	return host, credentials, exists
+}
+
+// registryHost returns the registry an image reference is pulled from. As in Docker, the
+// first path component is a registry only when it looks like a host name.
+func registryHost(imageName string) string {
+	first, _, found := strings.Cut(imageName, "/")
+	if !found {
+		return dockerHubRegistry
+	}
+	if first == "localhost" || strings.ContainsAny(first, ".:") {
+		return first
+	}
+	return dockerHubRegistry
+}
+
+// dockerHubAuthServer is the server address Docker Hub credentials are issued for
+const dockerHubAuthServer = "https://index.docker.io/v1/"
+
+// encodeRegistryAuth builds the X-Registry-Auth value for a pull. Credentials travel to
+// the daemon in the request header, never on a command line or in a config file.
+func encodeRegistryAuth(host string, credentials RegistryCredentials) (string, error) {
+	serverAddress := host
+	if host == dockerHubRegistry {
+		serverAddress = dockerHubAuthServer
+	}
+
+	encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      credentials.Username,
+		Password:      credentials.Password,
+		ServerAddress: serverAddress,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode credentials for %s: %w", host, err)
+	}
+	return encoded, nil
+}
+
+// pullError reports authorization failures as ErrRegistryAuth so they are not mistaken
+// for a missing image or an unreachable registry
+func pullError(imageName, host string, err error) error {
+	if errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) || isAuthFailure(err.Error()) {
+		return fmt.Errorf("%w: pulling %s from %s: %v", ErrRegistryAuth, imageName, host, err)
+	}
+	return fmt.Errorf("image pull failed: %w", err)
+}
+
+// isAuthFailure recognises the registry messages for missing or rejected credentials
+func isAuthFailure(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range []string{"unauthorized", "authentication required", "access denied", "denied: ", "incorrect username or password"} {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
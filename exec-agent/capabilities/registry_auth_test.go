@@ -0,0 +1,108 @@
+package capabilities
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+)
+
+func TestRegistryHost(t *testing.T) {
+	cases := map[string]string{
+		"python:3.11":                      "docker.io",
+		"library/python:3.11":              "docker.io",
+		"acme/etl:1":                       "docker.io",
+		"registry.example.com/team/tool:1": "registry.example.com",
+		"registry.example.com:5000/tool:1": "registry.example.com:5000",
+		"localhost/tool:dev":               "localhost",
+		"localhost:5000/tool:dev":          "localhost:5000",
+	}
+	for image, want := range cases {
+		if got := registryHost(image); got != want {
+			t.Errorf("%s: got %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestPullUsesCredentialsForImageRegistry(t *testing.T) {
+	docker := newFakeDockerAPI()
+	images := []string{"registry.example.com:5000/team/tool:1", "python:3.11", "ghcr.io/org/tool:2"}
+	for _, image := range images {
+		docker.registry[image] = types.ImageInspect{}
+	}
+	scanner := NewImageScanner(docker, images, 0)
+	scanner.SetRegistryAuth(map[string]RegistryCredentials{
+		"registry.example.com:5000": {Username: "ci", Password: "private-secret"},
+		"docker.io":                 {Username: "hub-user", Password: "hub-token"},
+	})
+
+	if err := scanner.ScanAllImages(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]registry.AuthConfig{
+		"registry.example.com:5000/team/tool:1": {Username: "ci", Password: "private-secret", ServerAddress: "registry.example.com:5000"},
+		"python:3.11":                           {Username: "hub-user", Password: "hub-token", ServerAddress: "https://index.docker.io/v1/"},
+	}
+	for _, image := range images {
+		options, pulled := docker.pulls[image]
+		if !pulled {
+			t.Errorf("%s: expected a pull", image)
+			continue
+		}
+		expected, authenticated := want[image]
+		if !authenticated {
+			if options.RegistryAuth != "" {
+				t.Errorf("%s: got credentials for a registry without any configured", image)
+			}
+			continue
+		}
+		auth, err := registry.DecodeAuthConfig(options.RegistryAuth)
+		if err != nil {
+			t.Fatalf("%s: %v", image, err)
+		}
+		if *auth != expected {
+			t.Errorf("%s: got credentials %+v, want %+v", image, *auth, expected)
+		}
+	}
+}
+
+func TestPullAuthFailuresAreReported(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.pullErrors["registry.example.com/tool:1"] = errdefs.Unauthorized(errors.New("unauthorized: authentication required"))
+	docker.progressErrors["acme/private:1"] = "pull access denied for acme/private, repository does not exist or may require 'docker login': denied: requested access to the resource is denied"
+	docker.progressErrors["acme/tool:1"] = "manifest for acme/tool:1 not found: manifest unknown"
+	scanner := NewImageScanner(docker, nil, 0)
+
+	for image, authFailure := range map[string]bool{
+		"registry.example.com/tool:1": true,
+		"acme/private:1":              true,
+		"acme/tool:1":                 false,
+	} {
+		err := scanner.pullImageIfNeeded(context.Background(), image)
+		if err == nil {
+			t.Errorf("%s: expected the pull to fail", image)
+			continue
+		}
+		if errors.Is(err, ErrRegistryAuth) != authFailure {
+			t.Errorf("%s: got %v, want an auth failure %v", image, err, authFailure)
+		}
+	}
+}
+
+func TestLocalImagesAreNotPulled(t *testing.T) {
+	docker := newFakeDockerAPI()
+	docker.local["registry.example.com/tool:1"] = types.ImageInspect{}
+	scanner := NewImageScanner(docker, nil, 0)
+	scanner.SetRegistryAuth(map[string]RegistryCredentials{"registry.example.com": {Username: "ci", Password: "secret"}})
+
+	if err := scanner.pullImageIfNeeded(context.Background(), "registry.example.com/tool:1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(docker.pulls) != 0 {
+		t.Errorf("got pulls %v, want none for a local image", docker.pulls)
+	}
+}
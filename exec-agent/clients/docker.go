@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"io"
 	"time"
 )
 
@@ -17,11 +18,17 @@ type ContainerConfig struct {
 	Mounts      []Mount
 	Ports       map[string]string
 	WorkingDir  string
+	Stdin       io.Reader // piped to the container when set
+	CPUs        float64   // CPU cores; 0 is unlimited
+	Memory      int64     // bytes; 0 is unlimited
+	PidsLimit   int64     // 0 is unlimited
 }
 
 type ExecutionResult struct {
 	ExitCode int
-	Output   string
+	Output   string // stdout and stderr interleaved
+	Stdout   string
+	Stderr   string
 	Error    string
 	Duration time.Duration
 }
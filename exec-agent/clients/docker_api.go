@@ -0,0 +1,24 @@
+package clients
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/client"
+)
+
+// NewDockerAPIClient connects to the Docker Engine API for callers that talk to the daemon
+// directly rather than through the docker CLI. An empty apiVersion negotiates one.
+func NewDockerAPIClient(host, apiVersion string) (*client.Client, error) {
+	opts := []client.Opt{client.WithHost(host)}
+	if apiVersion != "" {
+		opts = append(opts, client.WithVersion(apiVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker API client: %v", err)
+	}
+	return cli, nil
+}
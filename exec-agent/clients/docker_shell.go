@@ -1,12 +1,16 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -55,6 +59,11 @@ func (d *DockerShellClient) ExecuteContainer(ctx context.Context, config Contain
 	// Build docker run command
 	args := []string{"run", "--rm"}
 
+	// Keep stdin open so input can be piped to the container
+	if config.Stdin != nil {
+		args = append(args, "-i")
+	}
+
 	// Add network
 	if d.networkName != "" {
 		args = append(args, "--network", d.networkName)
@@ -70,6 +79,18 @@ func (d *DockerShellClient) ExecuteContainer(ctx context.Context, config Contain
 		args = append(args, "-e", env)
 	}
 
+	// Add resource limits; swap is capped at the memory limit so it can't be bypassed
+	if config.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(config.CPUs, 'f', -1, 64))
+	}
+	if config.Memory > 0 {
+		memory := strconv.FormatInt(config.Memory, 10)
+		args = append(args, "--memory", memory, "--memory-swap", memory)
+	}
+	if config.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.FormatInt(config.PidsLimit, 10))
+	}
+
 	// Add working directory
 	if config.WorkingDir != "" {
 		args = append(args, "-w", config.WorkingDir)
@@ -95,12 +116,21 @@ func (d *DockerShellClient) ExecuteContainer(ctx context.Context, config Contain
 	defer cancel()
 
 	cmd := exec.CommandContext(execCtx, "docker", args...)
-	output, err := cmd.CombinedOutput()
+	cmd.Stdin = config.Stdin
+
+	// Capture the streams separately while keeping their interleaved order in Output
+	var stdout, stderr bytes.Buffer
+	combined := &lockedBuffer{}
+	cmd.Stdout = io.MultiWriter(&stdout, combined)
+	cmd.Stderr = io.MultiWriter(&stderr, combined)
+	err := cmd.Run()
 
 	duration := time.Since(startTime)
 
 	result := &ExecutionResult{
-		Output:   string(output),
+		Output:   combined.String(),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
 		Duration: duration,
 	}
 
@@ -129,6 +159,24 @@ func (d *DockerShellClient) ExecuteContainer(ctx context.Context, config Contain
 	return result, nil
 }
 
+// lockedBuffer is a buffer safe for the concurrent writes of a command's stdout and stderr
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
 func (d *DockerShellClient) CreateWorkspace(executionID string) (string, error) {
 	workspacePath := filepath.Join(d.workDir, executionID)
 	if err := os.MkdirAll(workspacePath, 0755); err != nil {
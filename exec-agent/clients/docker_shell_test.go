@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDockerScript stands in for the docker CLI: it records its arguments, reports on
+// stderr and echoes stdin to stdout the way a filter image would
+const fakeDockerScript = `#!/bin/sh
+printf '%s\n' "$@" > "$DOCKER_ARGS_FILE"
+echo "reading input" >&2
+cat
+`
+
+// newFakeDockerShellClient puts the fake docker CLI first on PATH and returns a client
+// using it and the file its arguments are recorded in
+func newFakeDockerShellClient(t *testing.T) (*DockerShellClient, string) {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(fakeDockerScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	argsFile := filepath.Join(binDir, "args")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("DOCKER_ARGS_FILE", argsFile)
+
+	client, err := NewDockerShellClient(t.TempDir(), "", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, argsFile
+}
+
+func readArgs(t *testing.T, argsFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestExecuteContainerPipesStdin(t *testing.T) {
+	client, argsFile := newFakeDockerShellClient(t)
+
+	config := ContainerConfig{Image: "filters:1", Command: []string{"cat"}, Stdin: strings.NewReader("line 1\nline 2\n")}
+	result, err := client.ExecuteContainer(context.Background(), config, "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ExitCode != 0 || result.Error != "" {
+		t.Fatalf("got exit code %d and error %q", result.ExitCode, result.Error)
+	}
+	if result.Stdout != "line 1\nline 2\n" {
+		t.Errorf("got stdout %q, want stdin echoed", result.Stdout)
+	}
+	if result.Stderr != "reading input\n" {
+		t.Errorf("got stderr %q, want it captured separately", result.Stderr)
+	}
+	if !strings.Contains(result.Output, "reading input\n") || !strings.Contains(result.Output, "line 2\n") {
+		t.Errorf("got output %q, want both streams", result.Output)
+	}
+
+	args := readArgs(t, argsFile)
+	if args[0] != "run" || args[2] != "-i" {
+		t.Errorf("got docker args %v, want stdin kept open with -i", args)
+	}
+}
+
+func TestExecuteContainerWithoutStdin(t *testing.T) {
+	client, argsFile := newFakeDockerShellClient(t)
+
+	result, err := client.ExecuteContainer(context.Background(), ContainerConfig{Image: "tools:1"}, "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stdout != "" || result.ExitCode != 0 {
+		t.Errorf("got stdout %q and exit code %d, want no input echoed", result.Stdout, result.ExitCode)
+	}
+	for _, arg := range readArgs(t, argsFile) {
+		if arg == "-i" {
+			t.Error("expected stdin not to be kept open without input")
+		}
+	}
+}
+
+func TestExecuteContainerAppliesResourceLimits(t *testing.T) {
+	client, argsFile := newFakeDockerShellClient(t)
+
+	config := ContainerConfig{Image: "tools:1", CPUs: 1.5, Memory: 256 << 20, PidsLimit: 64}
+	if _, err := client.ExecuteContainer(context.Background(), config, "exec-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Docker applies these flags as the container's HostConfig resource constraints
+	args := strings.Join(readArgs(t, argsFile), " ")
+	for _, want := range []string{"--cpus 1.5", "--memory 268435456 --memory-swap 268435456", "--pids-limit 64"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("got docker args %q, want %q", args, want)
+		}
+	}
+
+	if _, err := client.ExecuteContainer(context.Background(), ContainerConfig{Image: "tools:1"}, "exec-2"); err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(readArgs(t, argsFile), " "); strings.Contains(args, "--cpus") || strings.Contains(args, "--memory") || strings.Contains(args, "--pids-limit") {
+		t.Errorf("got docker args %q, want no limits when none are set", args)
+	}
+}
@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"exec-agent/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrExecutionStatusNotFound is returned for executions with no status record, either
+// never seen or expired
+var ErrExecutionStatusNotFound = errors.New("execution status not found")
+
+// DefaultExecutionStatusTTL is how long status records are kept after their last update
+const DefaultExecutionStatusTTL = 24 * time.Hour
+
+// ExecutionStatusStore keeps execution status records in Redis, keyed by the agent's
+// execution ID, with an index from workflow execution and task to the latest one
+type ExecutionStatusStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewExecutionStatusStore stores records under prefix; a non-positive ttl uses the default
+func NewExecutionStatusStore(client *redis.Client, prefix string, ttl time.Duration) *ExecutionStatusStore {
+	if ttl <= 0 {
+		ttl = DefaultExecutionStatusTTL
+	}
+	return &ExecutionStatusStore{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (s *ExecutionStatusStore) key(execID string) string {
+	return s.prefix + ":" + execID
+}
+
+func (s *ExecutionStatusStore) taskKey(executionID, taskID string) string {
+	return s.prefix + ":task:" + executionID + ":" + taskID
+}
+
+// Save writes a status record, and points its task's index entry at it
+func (s *ExecutionStatusStore) Save(ctx context.Context, status *models.ExecutionStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution status: %v", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, s.key(status.ExecID), data, s.ttl)
+	if status.ExecutionID != "" && status.TaskID != "" {
+		pipe.Set(ctx, s.taskKey(status.ExecutionID, status.TaskID), status.ExecID, s.ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Get returns the status record of an agent execution
+func (s *ExecutionStatusStore) Get(ctx context.Context, execID string) (*models.ExecutionStatus, error) {
+	data, err := s.client.Get(ctx, s.key(execID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrExecutionStatusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var status models.ExecutionStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal execution status: %v", err)
+	}
+	return &status, nil
+}
+
+// GetForTask returns the status record of the latest attempt of a workflow task
+func (s *ExecutionStatusStore) GetForTask(ctx context.Context, executionID, taskID string) (*models.ExecutionStatus, error) {
+	execID, err := s.client.Get(ctx, s.taskKey(executionID, taskID)).Result()
+	if err == redis.Nil {
+		return nil, ErrExecutionStatusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, execID)
+}
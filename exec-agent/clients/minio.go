@@ -2,11 +2,15 @@ package clients
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -14,13 +18,32 @@ import (
 )
 
 type MinioClient struct {
-	client     *minio.Client
-	bucketName string
+	client        *minio.Client
+	presignClient *minio.Client // signs URLs for the public endpoint; nil uses client
+	creds         *credentials.Credentials
+	bucketName    string
+	pathScheme    string
+	now           func() time.Time // dates uploads in the path scheme
 }
 
+// UploadedObject is an object written by an upload and its size in bytes
+type UploadedObject struct {
+	Name string
+	Size int64
+}
+
+// DefaultPathScheme lays out uploads as executions/{execution_id}/{file}
+const DefaultPathScheme = "executions/{execution_id}/{file}"
+
+// missingPathValue replaces placeholders that have no value for an execution
+const missingPathValue = "unknown"
+
+var pathPlaceholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
 func NewMinioClient(endpoint, accessKeyID, secretAccessKey, bucketName string, useSSL bool) (*MinioClient, error) {
+	creds := credentials.NewStaticV4(accessKeyID, secretAccessKey, "")
 	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretAccessKey, ""),
+		Creds:  creds,
 		Secure: useSSL,
 	})
 	if err != nil {
@@ -50,11 +73,65 @@ func NewMinioClient(endpoint, accessKeyID, secretAccessKey, bucketName string, u
 
 	return &MinioClient{
 		client:     client,
+		creds:      creds,
 		bucketName: bucketName,
+		pathScheme: DefaultPathScheme,
+		now:        time.Now,
 	}, nil
 }
 
+// SetPublicEndpoint signs presigned URLs for a host other than the one the agent connects
+// to, e.g. "https://files.example.com" when MINIO_ENDPOINT is only reachable internally.
+// The region is fixed because the public host may not be reachable from the agent to look
+// it up.
+func (m *MinioClient) SetPublicEndpoint(publicURL, region string) error {
+	parsed, err := url.Parse(publicURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid Minio public endpoint: %s", publicURL)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("Minio public endpoint must be an http or https URL: %s", publicURL)
+	}
+
+	client, err := minio.New(parsed.Host, &minio.Options{
+		Creds:  m.creds,
+		Secure: parsed.Scheme == "https",
+		Region: region,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Minio client for public endpoint: %v", err)
+	}
+
+	m.presignClient = client
+	return nil
+}
+
+// PresignedGetURL returns a URL anyone can download an object from until it expires
+func (m *MinioClient) PresignedGetURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	client := m.client
+	if m.presignClient != nil {
+		client = m.presignClient
+	}
+
+	presigned, err := client.PresignedGetObject(ctx, m.bucketName, objectName, expiry, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %v", objectName, err)
+	}
+	return presigned.String(), nil
+}
+
+// ErrObjectTooLarge is returned when an object is larger than a download's limit
+var ErrObjectTooLarge = errors.New("object exceeds the download size limit")
+
 func (m *MinioClient) DownloadFile(ctx context.Context, objectName, destPath string) error {
+	_, err := m.DownloadFileWithLimit(ctx, objectName, destPath, -1)
+	return err
+}
+
+// DownloadFileWithLimit downloads an object to destPath and returns its size. Objects
+// larger than limit fail with ErrObjectTooLarge before more than limit bytes reach the
+// disk, and the partial file is removed; a negative limit downloads any size.
+func (m *MinioClient) DownloadFileWithLimit(ctx context.Context, objectName, destPath string, limit int64) (int64, error) {
 	logrus.WithFields(logrus.Fields{
 		"object": objectName,
 		"dest":   destPath,
@@ -62,38 +139,58 @@ func (m *MinioClient) DownloadFile(ctx context.Context, objectName, destPath str
 
 	// Ensure destination directory exists
 	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %v", err)
+		return 0, fmt.Errorf("failed to create destination directory: %v", err)
 	}
 
 	// Get object from Minio
 	object, err := m.client.GetObject(ctx, m.bucketName, objectName, minio.GetObjectOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to get object from Minio: %v", err)
+		return 0, fmt.Errorf("failed to get object from Minio: %v", err)
 	}
 	defer object.Close()
 
+	// Objects known to be too large fail before anything is written
+	var source io.Reader = object
+	if limit >= 0 {
+		if info, err := object.Stat(); err == nil && info.Size > limit {
+			return 0, ErrObjectTooLarge
+		}
+		// The size may change under us, so the copy is bounded too
+		source = io.LimitReader(object, limit+1)
+	}
+
 	// Create destination file
 	destFile, err := os.Create(destPath)
 	if err != nil {
-		return fmt.Errorf("failed to create destination file: %v", err)
+		return 0, fmt.Errorf("failed to create destination file: %v", err)
 	}
 	defer destFile.Close()
 
 	// Copy object content to file
-	_, err = io.Copy(destFile, object)
+	written, err := io.Copy(destFile, source)
+	if err == nil && limit >= 0 && written > limit {
+		err = ErrObjectTooLarge
+	}
 	if err != nil {
-		return fmt.Errorf("failed to copy object content: %v", err)
+		destFile.Close()
+		os.Remove(destPath)
+		if err == ErrObjectTooLarge {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to copy object content: %v", err)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"object": objectName,
 		"dest":   destPath,
+		"size":   written,
 	}).Info("File downloaded successfully")
 
-	return nil
+	return written, nil
 }
 
-func (m *MinioClient) UploadFile(ctx context.Context, filePath, objectName string) error {
+// UploadFile uploads a file and returns the size stored
+func (m *MinioClient) UploadFile(ctx context.Context, filePath, objectName string) (int64, error) {
 	logrus.WithFields(logrus.Fields{
 		"file":   filePath,
 		"object": objectName,
@@ -102,35 +199,35 @@ func (m *MinioClient) UploadFile(ctx context.Context, filePath, objectName strin
 	// Get file info
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %v", err)
+		return 0, fmt.Errorf("failed to stat file: %v", err)
 	}
 
 	// Open file
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return 0, fmt.Errorf("failed to open file: %v", err)
 	}
 	defer file.Close()
 
 	// Upload file
-	_, err = m.client.PutObject(ctx, m.bucketName, objectName, file, fileInfo.Size(), minio.PutObjectOptions{
+	info, err := m.client.PutObject(ctx, m.bucketName, objectName, file, fileInfo.Size(), minio.PutObjectOptions{
 		ContentType: "application/octet-stream",
 	})
 	if err != nil {
-		return fmt.Errorf("failed to upload object to Minio: %v", err)
+		return 0, fmt.Errorf("failed to upload object to Minio: %v", err)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"file":   filePath,
 		"object": objectName,
-		"size":   fileInfo.Size(),
+		"size":   info.Size,
 	}).Info("File uploaded successfully")
 
-	return nil
+	return info.Size, nil
 }
 
-func (m *MinioClient) UploadDirectory(ctx context.Context, dirPath, prefix string) ([]string, error) {
-	var uploadedObjects []string
+func (m *MinioClient) UploadDirectory(ctx context.Context, dirPath, prefix string) ([]UploadedObject, error) {
+	var uploadedObjects []UploadedObject
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -150,11 +247,12 @@ func (m *MinioClient) UploadDirectory(ctx context.Context, dirPath, prefix strin
 		// Convert to forward slashes for object name
 		objectName := filepath.ToSlash(filepath.Join(prefix, relPath))
 
-		if err := m.UploadFile(ctx, path, objectName); err != nil {
+		size, err := m.UploadFile(ctx, path, objectName)
+		if err != nil {
 			return fmt.Errorf("failed to upload %s: %v", path, err)
 		}
 
-		uploadedObjects = append(uploadedObjects, objectName)
+		uploadedObjects = append(uploadedObjects, UploadedObject{Name: objectName, Size: size})
 		return nil
 	})
 
@@ -200,12 +298,72 @@ func (m *MinioClient) DownloadDirectory(ctx context.Context, prefix, destDir str
 	return nil
 }
 
-func (m *MinioClient) GenerateObjectPath(executionID, filename string) string {
-	return fmt.Sprintf("executions/%s/%s", executionID, filename)
+// SetPathScheme configures the object layout for uploads, e.g. "{tenant}/{date}/{execution_id}/{file}".
+// Built-in placeholders are execution_id, file, date, year, month and day; any other
+// placeholder is filled from the workflow execution's labels or the request's path labels.
+func (m *MinioClient) SetPathScheme(scheme string) error {
+	if !strings.Contains(scheme, "{file}") {
+		return fmt.Errorf("minio path scheme must contain {file}: %s", scheme)
+	}
+	if !strings.Contains(scheme, "{execution_id}") {
+		logrus.WithField("scheme", scheme).Warn("Minio path scheme has no {execution_id}; uploads from different executions may overwrite each other")
+	}
+	m.pathScheme = scheme
+	return nil
+}
+
+func (m *MinioClient) GenerateObjectPath(executionID, filename string, labels map[string]string) string {
+	return m.renderPath(executionID, filename, labels)
 }
 
-func (m *MinioClient) GenerateDirectoryPath(executionID, dirname string) string {
-	return fmt.Sprintf("executions/%s/%s", executionID, dirname)
+func (m *MinioClient) GenerateDirectoryPath(executionID, dirname string, labels map[string]string) string {
+	return m.renderPath(executionID, dirname, labels)
+}
+
+// renderPath resolves the path scheme. Label values are sanitized so they cannot add
+// path segments; placeholders without a value render as "unknown". Built-in placeholders
+// are filled last, so a label named like one cannot redirect the upload.
+func (m *MinioClient) renderPath(executionID, file string, labels map[string]string) string {
+	values := make(map[string]string, len(labels)+6)
+	for key, value := range labels {
+		values[key] = sanitizePathSegment(value)
+	}
+
+	now := m.now().UTC()
+	values["date"] = now.Format("2006-01-02")
+	values["year"] = now.Format("2006")
+	values["month"] = now.Format("01")
+	values["day"] = now.Format("02")
+	values["execution_id"] = executionID
+	values["file"] = file
+
+	var missing []string
+	path := pathPlaceholderPattern.ReplaceAllStringFunc(m.pathScheme, func(match string) string {
+		name := match[1 : len(match)-1]
+		if value, exists := values[name]; exists && value != "" {
+			return value
+		}
+		missing = append(missing, name)
+		return missingPathValue
+	})
+
+	if len(missing) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"execution_id": executionID,
+			"missing":      missing,
+		}).Warn("Minio path scheme placeholders have no value")
+	}
+
+	return path
+}
+
+func sanitizePathSegment(value string) string {
+	value = strings.ReplaceAll(value, "/", "_")
+	value = strings.ReplaceAll(value, "\\", "_")
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
 }
 
 func (m *MinioClient) ObjectExists(ctx context.Context, objectName string) (bool, error) {
@@ -0,0 +1,123 @@
+package clients
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func newPathTestClient(scheme string) *MinioClient {
+	return &MinioClient{
+		pathScheme: scheme,
+		now:        func() time.Time { return time.Date(2025, 3, 7, 23, 30, 0, 0, time.UTC) },
+	}
+}
+
+func TestRenderPathSchemes(t *testing.T) {
+	labels := map[string]string{"tenant": "acme", "template": "nightly-report"}
+	cases := map[string]string{
+		DefaultPathScheme:                        "executions/exec-1/result.json",
+		"{tenant}/{date}/{execution_id}/{file}":  "acme/2025-03-07/exec-1/result.json",
+		"{template}/{year}/{month}/{day}/{file}": "nightly-report/2025/03/07/result.json",
+	}
+	for scheme, want := range cases {
+		if got := newPathTestClient(scheme).GenerateObjectPath("exec-1", "result.json", labels); got != want {
+			t.Errorf("%s: got %q, want %q", scheme, got, want)
+		}
+	}
+}
+
+func TestRenderPathMarksMissingValues(t *testing.T) {
+	client := newPathTestClient("{tenant}/{region}/{execution_id}/{file}")
+	got := client.GenerateObjectPath("exec-1", "result.json", map[string]string{"tenant": "acme", "region": ""})
+	if want := "acme/unknown/exec-1/result.json"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderPathLabelsCannotOverrideBuiltIns(t *testing.T) {
+	client := newPathTestClient("{tenant}/{date}/{execution_id}/{file}")
+	labels := map[string]string{
+		"tenant":       "acme",
+		"date":         "1999-01-01",
+		"execution_id": "someone-else",
+		"file":         "overwritten.json",
+	}
+	if got, want := client.GenerateObjectPath("exec-1", "result.json", labels), "acme/2025-03-07/exec-1/result.json"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderPathLabelsCannotAddSegments(t *testing.T) {
+	client := newPathTestClient("{tenant}/{execution_id}/{file}")
+	for tenant, want := range map[string]string{
+		"../other":  ".._other/exec-1/output",
+		"a/b":       "a_b/exec-1/output",
+		"..":        "_/exec-1/output",
+		`acme\logs`: "acme_logs/exec-1/output",
+	} {
+		if got := client.GenerateDirectoryPath("exec-1", "output", map[string]string{"tenant": tenant}); got != want {
+			t.Errorf("tenant %q: got %q, want %q", tenant, got, want)
+		}
+	}
+}
+
+func TestSetPathSchemeRequiresFile(t *testing.T) {
+	client := newPathTestClient(DefaultPathScheme)
+	if err := client.SetPathScheme("{tenant}/{execution_id}"); err == nil {
+		t.Error("expected a scheme without {file} to be rejected")
+	}
+	if err := client.SetPathScheme("{tenant}/{execution_id}/{file}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// newPresignTestClient returns a client that can sign URLs for the public endpoint
+// without contacting a server
+func newPresignTestClient(t *testing.T, publicURL string) *MinioClient {
+	t.Helper()
+	client := &MinioClient{creds: credentials.NewStaticV4("access", "secret", ""), bucketName: "outputs"}
+	if err := client.SetPublicEndpoint(publicURL, "us-east-1"); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPresignedGetURLUsesPublicEndpoint(t *testing.T) {
+	client := newPresignTestClient(t, "https://files.example.com")
+
+	presigned, err := client.PresignedGetURL(context.Background(), "executions/exec-1/output/report.csv", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(presigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "https" || parsed.Host != "files.example.com" {
+		t.Errorf("got %s://%s, want the public endpoint", parsed.Scheme, parsed.Host)
+	}
+	if parsed.Path != "/outputs/executions/exec-1/output/report.csv" {
+		t.Errorf("got path %q, want the bucket and object name", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("X-Amz-Expires") != "900" || query.Get("X-Amz-Signature") == "" {
+		t.Errorf("got query %v, want a signature valid for 900 seconds", query)
+	}
+	if !strings.Contains(query.Get("X-Amz-Credential"), "/us-east-1/s3/") {
+		t.Errorf("got credential %q, want it scoped to the configured region", query.Get("X-Amz-Credential"))
+	}
+}
+
+func TestSetPublicEndpointRejectsInvalidURLs(t *testing.T) {
+	client := &MinioClient{creds: credentials.NewStaticV4("access", "secret", "")}
+	for _, publicURL := range []string{"files.example.com", "ftp://files.example.com", "https://"} {
+		if err := client.SetPublicEndpoint(publicURL, "us-east-1"); err == nil {
+			t.Errorf("%s: expected the endpoint to be rejected", publicURL)
+		}
+	}
+}
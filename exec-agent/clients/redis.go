@@ -9,6 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// queueDepthTTL bounds how long a reported queue depth stays visible if the service stops
+const queueDepthTTL = 30 * time.Second
+
 type RedisClient struct {
 	client     *redis.Client
 	requestCh  string
@@ -61,6 +64,8 @@ func (r *RedisClient) Listen(ctx context.Context, handler func([]byte) []byte) e
 				continue
 			}
 
+			r.reportQueueDepth(ctx, len(ch))
+
 			logrus.WithFields(logrus.Fields{
 				"channel": msg.Channel,
 				"payload_size": len(msg.Payload),
@@ -75,6 +80,15 @@ func (r *RedisClient) Listen(ctx context.Context, handler func([]byte) []byte) e
 	}
 }
 
+// reportQueueDepth publishes how many requests are waiting behind the current one so
+// callers can extend their response wait while the service is backed up
+func (r *RedisClient) reportQueueDepth(ctx context.Context, depth int) {
+	key := r.requestCh + ":queue_depth"
+	if err := r.client.Set(ctx, key, depth, queueDepthTTL).Err(); err != nil {
+		logrus.WithError(err).Debug("Failed to report queue depth")
+	}
+}
+
 func (r *RedisClient) Publish(ctx context.Context, channel string, data []byte) error {
 	return r.client.Publish(ctx, channel, data).Err()
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -18,20 +19,33 @@ type Config struct {
 	App          AppConfig
 	Capabilities CapabilityConfig
 	ImageScan    ImageScanConfig
+	Output       OutputConfig
+	Input        InputConfig
+	Tracing      TracingConfig
 }
 
 type RedisConfig struct {
-	URL           string
-	RequestCh     string
-	ResponseCh    string
+	URL        string
+	Namespace  string
+	RequestCh  string
+	ResponseCh string
+	StatusTTL  time.Duration // how long execution status records are kept after their last update
 }
 
 type DockerConfig struct {
-	Host           string
-	APIVersion     string
-	WorkDir        string
-	NetworkName    string
-	CleanupTimeout time.Duration
+	Host                 string
+	APIVersion           string
+	WorkDir              string
+	NetworkName          string
+	CleanupTimeout       time.Duration
+	AllowPrivilegedPorts bool     // allow host port mappings below 1024
+	AllowedWorkingDirs   []string // roots a container working_dir must fall within
+	DefaultCPUs          float64  // container limits for requests that set none
+	DefaultMemory        string
+	DefaultPids          int
+	MaxCPUs              float64 // highest container limits a request may set; 0 is no maximum
+	MaxMemory            string
+	MaxPids              int
 }
 
 type MinioConfig struct {
@@ -40,12 +54,37 @@ type MinioConfig struct {
 	SecretAccessKey string
 	UseSSL          bool
 	BucketName      string
+	PathScheme      string        // object layout for uploads, see MinioClient.SetPathScheme
+	PublicEndpoint  string        // URL presigned links point at when Endpoint is internal-only; empty uses Endpoint
+	Region          string        // region presigned links are signed for when PublicEndpoint is set
+	PresignExpiry   time.Duration // lifetime of presigned URLs for uploaded outputs
 }
 
 type ServiceProxyConfig struct {
-	Port               int
-	DataAbstractorURL  string
-	AIAbstractorURL    string
+	Port              int
+	DataAbstractorURL string
+	AIAbstractorURL   string
+	ContainerURL      string // proxy address as seen from inside containers
+	ContainerDataURL  string
+	ContainerAIURL    string
+	DataHeaders       map[string]string // injected into requests proxied to the data abstractor
+	AIHeaders         map[string]string // injected into requests proxied to the AI abstractor
+	StripHeaders      []string          // removed from proxied requests on both routes
+	DataTimeout       time.Duration
+	AITimeout         time.Duration
+}
+
+// OutputConfig bounds the output file content inlined in execution responses
+type OutputConfig struct {
+	MaxInlineFiles    int   // 0 inlines any number of files
+	MaxInlineBytes    int64 // total inlined bytes; 0 disables the budget
+	MaxInlineFileSize int64 // files this size or larger are never inlined
+}
+
+// InputConfig bounds the input a request may place in its workspace
+type InputConfig struct {
+	MaxWorkspaceBytes int64 // input bytes per workspace; 0 is unlimited
+	MaxFiles          int   // input files and Minio objects per request; 0 is unlimited
 }
 
 type AppConfig struct {
@@ -54,14 +93,31 @@ type AppConfig struct {
 }
 
 type CapabilityConfig struct {
-	RefreshInterval time.Duration
-	Enabled         bool
+	RefreshInterval  time.Duration
+	Enabled          bool
+	SigningSecret    string        // shared HMAC secret for signing announcements; empty disables
+	AnnounceDebounce time.Duration // coalesces bursts of announcements; zero disables
+	InstanceID       string        // identifies this replica; set with a replica-specific request channel
+	InstanceWeight   int           // relative share of requests routed to this replica
+	ServiceVersion   string        // release announced for workflows that pin service versions
 }
 
 type ImageScanConfig struct {
-	Enabled         bool
-	ScanInterval    time.Duration
-	KnownImages     []string
+	Enabled      bool
+	ScanInterval time.Duration
+	KnownImages  []string
+	RegistryAuth map[string]RegistryCredentials // registry host -> credentials for pulling its images
+}
+
+// RegistryCredentials authenticate pulls from a private registry; a token goes in Password
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// TracingConfig configures OpenTelemetry span export
+type TracingConfig struct {
+	OTLPEndpoint string // OTLP/HTTP collector URL; empty disables export
 }
 
 func Load() (*Config, error) {
@@ -100,6 +156,20 @@ func Load() (*Config, error) {
 		}
 	}
 
+	var announceDebounce time.Duration
+	if debounceStr := os.Getenv("CAPABILITY_ANNOUNCE_DEBOUNCE"); debounceStr != "" {
+		if debounce, err := time.ParseDuration(debounceStr); err == nil {
+			announceDebounce = debounce
+		}
+	}
+
+	instanceWeight := 1
+	if weightStr := os.Getenv("INSTANCE_WEIGHT"); weightStr != "" {
+		if w, err := strconv.Atoi(weightStr); err == nil && w > 0 {
+			instanceWeight = w
+		}
+	}
+
 	imageScanInterval := 30 * time.Minute
 	if intervalStr := os.Getenv("IMAGE_SCAN_INTERVAL"); intervalStr != "" {
 		if interval, err := time.ParseDuration(intervalStr); err == nil {
@@ -124,18 +194,51 @@ func Load() (*Config, error) {
 		}
 	}
 
+	allowedWorkingDirs := []string{"/workspace"}
+	if dirsStr := os.Getenv("ALLOWED_WORKING_DIRS"); dirsStr != "" {
+		allowedWorkingDirs = nil
+		for _, dir := range strings.Split(dirsStr, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				allowedWorkingDirs = append(allowedWorkingDirs, dir)
+			}
+		}
+	}
+
+	var stripHeaders []string
+	if headersStr := os.Getenv("SERVICE_PROXY_STRIP_HEADERS"); headersStr != "" {
+		for _, name := range strings.Split(headersStr, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				stripHeaders = append(stripHeaders, name)
+			}
+		}
+	}
+
+	containerProxyURL := strings.TrimSuffix(getEnv("SERVICE_PROXY_CONTAINER_URL", fmt.Sprintf("http://host.docker.internal:%d", proxyPort)), "/")
+
+	redisConfig := RedisConfig{
+		URL:       getEnv("REDIS_URL", "redis://localhost:6379"),
+		Namespace: getEnv("REDIS_NAMESPACE", ""),
+		StatusTTL: getDurationEnv("EXEC_STATUS_TTL", 24*time.Hour),
+	}
+	redisConfig.RequestCh = Namespaced(redisConfig.Namespace, getEnv("EXEC_REQUEST_CHANNEL", "exec-requests"))
+	redisConfig.ResponseCh = Namespaced(redisConfig.Namespace, getEnv("EXEC_RESPONSE_CHANNEL", "exec-responses"))
+
 	config := &Config{
-		Redis: RedisConfig{
-			URL:        getEnv("REDIS_URL", "redis://localhost:6379"),
-			RequestCh:  getEnv("EXEC_REQUEST_CHANNEL", "exec-requests"),
-			ResponseCh: getEnv("EXEC_RESPONSE_CHANNEL", "exec-responses"),
-		},
+		Redis: redisConfig,
 		Docker: DockerConfig{
-			Host:           getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"),
-			APIVersion:     getEnv("DOCKER_API_VERSION", "1.41"),
-			WorkDir:        getEnv("EXEC_WORK_DIR", "/tmp/exec-agent"),
-			NetworkName:    getEnv("DOCKER_NETWORK", "smart_data_abstractor_default"),
-			CleanupTimeout: time.Duration(cleanupTimeoutSec) * time.Second,
+			Host:                 getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"),
+			APIVersion:           getEnv("DOCKER_API_VERSION", "1.41"),
+			WorkDir:              getEnv("EXEC_WORK_DIR", "/tmp/exec-agent"),
+			NetworkName:          getEnv("DOCKER_NETWORK", "smart_data_abstractor_default"),
+			CleanupTimeout:       time.Duration(cleanupTimeoutSec) * time.Second,
+			AllowPrivilegedPorts: getBoolEnv("ALLOW_PRIVILEGED_PORTS", false),
+			AllowedWorkingDirs:   allowedWorkingDirs,
+			DefaultCPUs:          getFloatEnv("CONTAINER_DEFAULT_CPUS", 1),
+			DefaultMemory:        getEnv("CONTAINER_DEFAULT_MEMORY", "512m"),
+			DefaultPids:          getIntEnv("CONTAINER_DEFAULT_PIDS", 256),
+			MaxCPUs:              getFloatEnv("CONTAINER_MAX_CPUS", 4),
+			MaxMemory:            getEnv("CONTAINER_MAX_MEMORY", "4g"),
+			MaxPids:              getIntEnv("CONTAINER_MAX_PIDS", 1024),
 		},
 		Minio: MinioConfig{
 			Endpoint:        getEnv("MINIO_ENDPOINT", "localhost:9000"),
@@ -143,24 +246,54 @@ func Load() (*Config, error) {
 			SecretAccessKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
 			UseSSL:          useSSL,
 			BucketName:      getEnv("MINIO_BUCKET", "exec-data"),
+			PathScheme:      getEnv("MINIO_PATH_SCHEME", "executions/{execution_id}/{file}"),
+			PublicEndpoint:  getEnv("MINIO_PUBLIC_ENDPOINT", ""),
+			Region:          getEnv("MINIO_REGION", "us-east-1"),
+			PresignExpiry:   getDurationEnv("MINIO_PRESIGN_EXPIRY", time.Hour),
 		},
 		ServiceProxy: ServiceProxyConfig{
 			Port:              proxyPort,
 			DataAbstractorURL: getEnv("DATA_ABSTRACTOR_URL", "http://data-abstractor:8080"),
 			AIAbstractorURL:   getEnv("AI_ABSTRACTOR_URL", "http://ai-abstractor:8081"),
+			ContainerURL:      containerProxyURL,
+			ContainerDataURL:  getEnv("CONTAINER_DATA_SERVICE_URL", containerProxyURL+"/data"),
+			ContainerAIURL:    getEnv("CONTAINER_AI_SERVICE_URL", containerProxyURL+"/ai"),
+			DataHeaders:       parseHeaderList(os.Getenv("SERVICE_PROXY_DATA_HEADERS")),
+			AIHeaders:         parseHeaderList(os.Getenv("SERVICE_PROXY_AI_HEADERS")),
+			StripHeaders:      stripHeaders,
+			DataTimeout:       getDurationEnv("SERVICE_PROXY_DATA_TIMEOUT", 30*time.Second),
+			AITimeout:         getDurationEnv("SERVICE_PROXY_AI_TIMEOUT", 30*time.Second),
 		},
 		App: AppConfig{
 			LogLevel: getEnv("LOG_LEVEL", "info"),
 			Port:     port,
 		},
 		Capabilities: CapabilityConfig{
-			RefreshInterval: refreshInterval,
-			Enabled:         getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			RefreshInterval:  refreshInterval,
+			Enabled:          getBoolEnv("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			SigningSecret:    getEnv("CAPABILITY_SIGNING_SECRET", ""),
+			AnnounceDebounce: announceDebounce,
+			InstanceID:       getEnv("INSTANCE_ID", ""),
+			InstanceWeight:   instanceWeight,
+			ServiceVersion:   getEnv("SERVICE_VERSION", ""),
 		},
 		ImageScan: ImageScanConfig{
 			Enabled:      getBoolEnv("IMAGE_SCAN_ENABLED", true),
 			ScanInterval: imageScanInterval,
 			KnownImages:  knownImages,
+			RegistryAuth: parseRegistryAuth(os.Getenv("IMAGE_REGISTRY_AUTH")),
+		},
+		Output: OutputConfig{
+			MaxInlineFiles:    getIntEnv("OUTPUT_MAX_INLINE_FILES", 50),
+			MaxInlineBytes:    int64(getIntEnv("OUTPUT_MAX_INLINE_BYTES", 10*1024*1024)),
+			MaxInlineFileSize: int64(getIntEnv("OUTPUT_MAX_INLINE_FILE_SIZE", 1024*1024)),
+		},
+		Input: InputConfig{
+			MaxWorkspaceBytes: int64(getIntEnv("WORKSPACE_MAX_BYTES", 1024*1024*1024)),
+			MaxFiles:          getIntEnv("WORKSPACE_MAX_INPUT_FILES", 1000),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 		},
 	}
 
@@ -177,6 +310,14 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// Namespaced prefixes a Redis key or channel name with the environment namespace, if any
+func Namespaced(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -184,6 +325,66 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f >= 0 {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// parseHeaderList parses "Name: value" pairs separated by semicolons
+func parseHeaderList(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		name, headerValue, found := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(headerValue)
+	}
+	return headers
+}
+
+// parseRegistryAuth parses "host=username:password" entries separated by semicolons; the
+// password may itself contain colons
+func parseRegistryAuth(value string) map[string]RegistryCredentials {
+	auth := make(map[string]RegistryCredentials)
+	for _, entry := range strings.Split(value, ";") {
+		host, credentials, found := strings.Cut(entry, "=")
+		host = strings.TrimSpace(host)
+		if !found || host == "" {
+			continue
+		}
+		username, password, found := strings.Cut(strings.TrimSpace(credentials), ":")
+		if !found || username == "" {
+			continue
+		}
+		auth[host] = RegistryCredentials{Username: username, Password: password}
+	}
+	return auth
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if b, err := strconv.ParseBool(value); err == nil {
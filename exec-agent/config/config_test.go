@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadContainerServiceURLs(t *testing.T) {
+	t.Setenv("SERVICE_PROXY_CONTAINER_URL", "http://proxy.internal:9100/")
+	t.Setenv("CONTAINER_AI_SERVICE_URL", "http://ai.internal")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServiceProxy.ContainerURL != "http://proxy.internal:9100" {
+		t.Errorf("container URL %q", cfg.ServiceProxy.ContainerURL)
+	}
+	if cfg.ServiceProxy.ContainerDataURL != "http://proxy.internal:9100/data" {
+		t.Errorf("data URL %q, want it derived from the container URL", cfg.ServiceProxy.ContainerDataURL)
+	}
+	if cfg.ServiceProxy.ContainerAIURL != "http://ai.internal" {
+		t.Errorf("AI URL %q, want the explicit override", cfg.ServiceProxy.ContainerAIURL)
+	}
+}
+
+func TestLoadContainerPolicy(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Docker.AllowPrivilegedPorts || len(cfg.Docker.AllowedWorkingDirs) != 1 || cfg.Docker.AllowedWorkingDirs[0] != "/workspace" {
+		t.Errorf("unexpected default policy: privileged %v, dirs %v", cfg.Docker.AllowPrivilegedPorts, cfg.Docker.AllowedWorkingDirs)
+	}
+
+	t.Setenv("ALLOW_PRIVILEGED_PORTS", "true")
+	t.Setenv("ALLOWED_WORKING_DIRS", "/workspace, /srv ,")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Docker.AllowPrivilegedPorts {
+		t.Error("expected privileged ports to be allowed")
+	}
+	if len(cfg.Docker.AllowedWorkingDirs) != 2 || cfg.Docker.AllowedWorkingDirs[1] != "/srv" {
+		t.Errorf("got allowed dirs %v", cfg.Docker.AllowedWorkingDirs)
+	}
+}
+
+func TestLoadServiceProxyRouteOptions(t *testing.T) {
+	t.Setenv("SERVICE_PROXY_DATA_HEADERS", "Authorization: Bearer a:b ; X-Tenant:acme;invalid")
+	t.Setenv("SERVICE_PROXY_STRIP_HEADERS", "Cookie, X-Forwarded-For,")
+	t.Setenv("SERVICE_PROXY_AI_TIMEOUT", "2m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := cfg.ServiceProxy
+	if len(proxy.DataHeaders) != 2 || proxy.DataHeaders["Authorization"] != "Bearer a:b" || proxy.DataHeaders["X-Tenant"] != "acme" {
+		t.Errorf("got data headers %v", proxy.DataHeaders)
+	}
+	if len(proxy.AIHeaders) != 0 {
+		t.Errorf("got AI headers %v, want none", proxy.AIHeaders)
+	}
+	if len(proxy.StripHeaders) != 2 || proxy.StripHeaders[1] != "X-Forwarded-For" {
+		t.Errorf("got strip headers %v", proxy.StripHeaders)
+	}
+	if proxy.DataTimeout != 30*time.Second || proxy.AITimeout != 2*time.Minute {
+		t.Errorf("got timeouts %v and %v, want the default and the override", proxy.DataTimeout, proxy.AITimeout)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces secrets in exposed configuration
+const redactedValue = "REDACTED"
+
+// maskSecret hides a secret while still showing whether it is set
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// maskHeaderValues keeps injected header names visible but hides their values, which
+// usually carry credentials
+func maskHeaderValues(headers map[string]string) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for name, value := range headers {
+		masked[name] = maskSecret(value)
+	}
+	return masked
+}
+
+// maskRegistryAuth keeps registry hosts and usernames visible but hides passwords
+func maskRegistryAuth(auth map[string]RegistryCredentials) map[string]interface{} {
+	masked := make(map[string]interface{}, len(auth))
+	for host, credentials := range auth {
+		masked[host] = map[string]string{
+			"username": credentials.Username,
+			"password": maskSecret(credentials.Password),
+		}
+	}
+	return masked
+}
+
+// redactURL masks any password embedded in a connection URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if strings.Contains(raw, "@") {
+			return redactedValue
+		}
+		return raw
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+	}
+
+	return u.String()
+}
+
+// Redacted returns the effective configuration with secrets masked, for operators
+// verifying what the service actually parsed
+func (c *Config) Redacted() map[string]interface{} {
+	return map[string]interface{}{
+		"redis": map[string]interface{}{
+			"url":              redactURL(c.Redis.URL),
+			"namespace":        c.Redis.Namespace,
+			"request_channel":  c.Redis.RequestCh,
+			"response_channel": c.Redis.ResponseCh,
+			"status_ttl":       c.Redis.StatusTTL.String(),
+		},
+		"docker": map[string]interface{}{
+			"host":                   c.Docker.Host,
+			"api_version":            c.Docker.APIVersion,
+			"work_dir":               c.Docker.WorkDir,
+			"network_name":           c.Docker.NetworkName,
+			"cleanup_timeout":        c.Docker.CleanupTimeout.String(),
+			"allow_privileged_ports": c.Docker.AllowPrivilegedPorts,
+			"allowed_working_dirs":   c.Docker.AllowedWorkingDirs,
+			"default_cpus":           c.Docker.DefaultCPUs,
+			"default_memory":         c.Docker.DefaultMemory,
+			"default_pids":           c.Docker.DefaultPids,
+			"max_cpus":               c.Docker.MaxCPUs,
+			"max_memory":             c.Docker.MaxMemory,
+			"max_pids":               c.Docker.MaxPids,
+		},
+		"minio": map[string]interface{}{
+			"endpoint":          c.Minio.Endpoint,
+			"access_key_id":     c.Minio.AccessKeyID,
+			"secret_access_key": maskSecret(c.Minio.SecretAccessKey),
+			"use_ssl":           c.Minio.UseSSL,
+			"bucket_name":       c.Minio.BucketName,
+			"path_scheme":       c.Minio.PathScheme,
+			"public_endpoint":   redactURL(c.Minio.PublicEndpoint),
+			"region":            c.Minio.Region,
+			"presign_expiry":    c.Minio.PresignExpiry.String(),
+		},
+		"service_proxy": map[string]interface{}{
+			"port":                c.ServiceProxy.Port,
+			"data_abstractor_url": redactURL(c.ServiceProxy.DataAbstractorURL),
+			"ai_abstractor_url":   redactURL(c.ServiceProxy.AIAbstractorURL),
+			"container_url":       redactURL(c.ServiceProxy.ContainerURL),
+			"container_data_url":  redactURL(c.ServiceProxy.ContainerDataURL),
+			"container_ai_url":    redactURL(c.ServiceProxy.ContainerAIURL),
+			"data_headers":        maskHeaderValues(c.ServiceProxy.DataHeaders),
+			"ai_headers":          maskHeaderValues(c.ServiceProxy.AIHeaders),
+			"strip_headers":       c.ServiceProxy.StripHeaders,
+			"data_timeout":        c.ServiceProxy.DataTimeout.String(),
+			"ai_timeout":          c.ServiceProxy.AITimeout.String(),
+		},
+		"app": map[string]interface{}{
+			"log_level": c.App.LogLevel,
+			"port":      c.App.Port,
+		},
+		"capabilities": map[string]interface{}{
+			"enabled":           c.Capabilities.Enabled,
+			"refresh_interval":  c.Capabilities.RefreshInterval.String(),
+			"signing_secret":    maskSecret(c.Capabilities.SigningSecret),
+			"announce_debounce": c.Capabilities.AnnounceDebounce.String(),
+			"instance_id":       c.Capabilities.InstanceID,
+			"instance_weight":   c.Capabilities.InstanceWeight,
+			"service_version":   c.Capabilities.ServiceVersion,
+		},
+		"image_scan": map[string]interface{}{
+			"enabled":       c.ImageScan.Enabled,
+			"scan_interval": c.ImageScan.ScanInterval.String(),
+			"known_images":  c.ImageScan.KnownImages,
+			"registry_auth": maskRegistryAuth(c.ImageScan.RegistryAuth),
+		},
+		"output": map[string]interface{}{
+			"max_inline_files":     c.Output.MaxInlineFiles,
+			"max_inline_bytes":     c.Output.MaxInlineBytes,
+			"max_inline_file_size": c.Output.MaxInlineFileSize,
+		},
+		"input": map[string]interface{}{
+			"max_workspace_bytes": c.Input.MaxWorkspaceBytes,
+			"max_files":           c.Input.MaxFiles,
+		},
+		"tracing": map[string]interface{}{
+			"otlp_endpoint": redactURL(c.Tracing.OTLPEndpoint),
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"exec-agent/models"
+)
+
+// minContainerMemory is the smallest memory limit Docker accepts
+const minContainerMemory = 6 << 20
+
+// ContainerResources caps what a container may use; a zero field means no limit
+type ContainerResources struct {
+	CPUs   float64 // CPU cores, fractions allowed
+	Memory int64   // bytes
+	Pids   int64   // processes
+}
+
+// DefaultContainerResources apply to requests that don't set limits
+var DefaultContainerResources = ContainerResources{
+	CPUs:   1,
+	Memory: 512 << 20,
+	Pids:   256,
+}
+
+// MaxContainerResources bound the limits a request may ask for
+var MaxContainerResources = ContainerResources{
+	CPUs:   4,
+	Memory: 4 << 30,
+	Pids:   1024,
+}
+
+// resolveResources returns the limits a container runs with: the request's own where
+// it sets them, the policy's defaults otherwise. Requests above the policy's maximum
+// are rejected rather than clamped, so callers learn they didn't get what they asked for.
+func resolveResources(spec *models.ContainerSpec, policy ContainerPolicy) (ContainerResources, error) {
+	resources := policy.DefaultResources
+	max := policy.MaxResources
+
+	if spec.CPULimit < 0 {
+		return resources, fmt.Errorf("cpu_limit must not be negative")
+	}
+	if spec.CPULimit > 0 {
+		if max.CPUs > 0 && spec.CPULimit > max.CPUs {
+			return resources, fmt.Errorf("cpu_limit %g exceeds the maximum of %g", spec.CPULimit, max.CPUs)
+		}
+		resources.CPUs = spec.CPULimit
+	}
+
+	if spec.MemoryLimit != "" {
+		memory, err := ParseMemoryLimit(spec.MemoryLimit)
+		if err != nil {
+			return resources, fmt.Errorf("invalid memory_limit: %v", err)
+		}
+		if memory < minContainerMemory {
+			return resources, fmt.Errorf("memory_limit %s is below the minimum of 6m", spec.MemoryLimit)
+		}
+		if max.Memory > 0 && memory > max.Memory {
+			return resources, fmt.Errorf("memory_limit %s exceeds the maximum of %d bytes", spec.MemoryLimit, max.Memory)
+		}
+		resources.Memory = memory
+	}
+
+	if spec.PidsLimit < 0 {
+		return resources, fmt.Errorf("pids_limit must not be negative")
+	}
+	if spec.PidsLimit > 0 {
+		if max.Pids > 0 && spec.PidsLimit > max.Pids {
+			return resources, fmt.Errorf("pids_limit %d exceeds the maximum of %d", spec.PidsLimit, max.Pids)
+		}
+		resources.Pids = spec.PidsLimit
+	}
+
+	return resources, nil
+}
+
+// ParseMemoryLimit parses a byte count with an optional b, k, m or g suffix, as Docker's
+// --memory flag does, e.g. "512m" or "2g"
+func ParseMemoryLimit(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "g"):
+		multiplier = 1 << 30
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "b"):
+	default:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || number <= 0 {
+			return 0, fmt.Errorf("%q is not a positive size", value)
+		}
+		return number, nil
+	}
+
+	number, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("%q is not a positive size", value)
+	}
+	if number > (1<<62)/multiplier {
+		return 0, fmt.Errorf("%q is too large", value)
+	}
+	return number * multiplier, nil
+}
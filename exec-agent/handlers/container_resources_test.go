@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"exec-agent/models"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	valid := map[string]int64{"1048576": 1 << 20, "512m": 512 << 20, "2G": 2 << 30, "64k": 64 << 10, "100b": 100}
+	for value, want := range valid {
+		if got, err := ParseMemoryLimit(value); err != nil || got != want {
+			t.Errorf("%s: got %d, %v, want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "-1m", "lots", "1.5g", "99999999999g"} {
+		if _, err := ParseMemoryLimit(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestBuildContainerConfigAppliesResourceLimits(t *testing.T) {
+	handler := newTestExecutionHandler()
+
+	config, err := handler.buildContainerConfig(&models.ExecutionRequest{Container: models.ContainerSpec{Image: "tools:1"}}, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPUs != 1 || config.Memory != 512<<20 || config.PidsLimit != 256 {
+		t.Errorf("got cpus %g, memory %d, pids %d, want the defaults", config.CPUs, config.Memory, config.PidsLimit)
+	}
+
+	config, err = handler.buildContainerConfig(&models.ExecutionRequest{Container: models.ContainerSpec{
+		Image: "tools:1", CPULimit: 2.5, MemoryLimit: "1g", PidsLimit: 64,
+	}}, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPUs != 2.5 || config.Memory != 1<<30 || config.PidsLimit != 64 {
+		t.Errorf("got cpus %g, memory %d, pids %d, want the requested limits", config.CPUs, config.Memory, config.PidsLimit)
+	}
+}
+
+func TestBuildContainerConfigRejectsOverLimitResources(t *testing.T) {
+	handler := newTestExecutionHandler()
+
+	cases := map[string]models.ContainerSpec{
+		"cpu_limit 8 exceeds the maximum of 4": {CPULimit: 8},
+		"cpu_limit must not be negative":       {CPULimit: -1},
+		"memory_limit 8g exceeds the maximum":  {MemoryLimit: "8g"},
+		"memory_limit 1m is below the minimum": {MemoryLimit: "1m"},
+		"invalid memory_limit":                 {MemoryLimit: "plenty"},
+		"pids_limit 4096 exceeds the maximum":  {PidsLimit: 4096},
+		"pids_limit must not be negative":      {PidsLimit: -5},
+	}
+	for want, container := range cases {
+		container.Image = "tools:1"
+		_, err := handler.buildContainerConfig(&models.ExecutionRequest{Container: container}, "/tmp/ws", "exec-1")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: expected error containing %q, got %v", container, want, err)
+		}
+	}
+
+	// A policy without a maximum accepts any positive limit
+	handler.SetContainerPolicy(ContainerPolicy{AllowedWorkingDirs: []string{"/workspace"}})
+	config, err := handler.buildContainerConfig(&models.ExecutionRequest{Container: models.ContainerSpec{Image: "tools:1", CPULimit: 16}}, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CPUs != 16 || config.Memory != 0 {
+		t.Errorf("got cpus %g and memory %d, want the request's CPUs and no memory limit", config.CPUs, config.Memory)
+	}
+}
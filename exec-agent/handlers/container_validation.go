@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// privilegedPortLimit is the first port unprivileged processes may bind on the host
+const privilegedPortLimit = 1024
+
+// ContainerPolicy restricts the container settings a request may ask for
+type ContainerPolicy struct {
+	AllowPrivilegedPorts bool               // permit host ports below 1024
+	AllowedWorkingDirs   []string           // absolute roots a working_dir must fall within
+	DefaultResources     ContainerResources // limits for requests that set none
+	MaxResources         ContainerResources // highest limits a request may set
+}
+
+// DefaultContainerPolicy keeps working directories inside the mounted workspace
+var DefaultContainerPolicy = ContainerPolicy{
+	AllowedWorkingDirs: []string{"/workspace"},
+	DefaultResources:   DefaultContainerResources,
+	MaxResources:       MaxContainerResources,
+}
+
+// validatePorts checks container_port[/protocol] -> [host_ip:]host_port mappings
+func validatePorts(ports map[string]string, allowPrivileged bool) error {
+	for containerPort, hostPort := range ports {
+		port, protocol := containerPort, "tcp"
+		if idx := strings.Index(containerPort, "/"); idx >= 0 {
+			port, protocol = containerPort[:idx], containerPort[idx+1:]
+		}
+		if protocol != "tcp" && protocol != "udp" && protocol != "sctp" {
+			return fmt.Errorf("invalid protocol %q in port mapping %s", protocol, containerPort)
+		}
+		if _, err := parsePortNumber(port); err != nil {
+			return fmt.Errorf("invalid container port %q: %v", containerPort, err)
+		}
+
+		hostPortNumber := hostPort
+		if idx := strings.LastIndex(hostPort, ":"); idx >= 0 {
+			hostPortNumber = hostPort[idx+1:]
+		}
+		number, err := parsePortNumber(hostPortNumber)
+		if err != nil {
+			return fmt.Errorf("invalid host port %q for container port %s: %v", hostPort, containerPort, err)
+		}
+		if number < privilegedPortLimit && !allowPrivileged {
+			return fmt.Errorf("host port %d for container port %s is privileged and not allowed", number, containerPort)
+		}
+	}
+
+	return nil
+}
+
+func parsePortNumber(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("not a number")
+	}
+	if number < 1 || number > 65535 {
+		return 0, fmt.Errorf("must be between 1 and 65535")
+	}
+	return number, nil
+}
+
+// validateWorkingDir requires an absolute container path inside one of the allowed roots
+func validateWorkingDir(workingDir string, allowedRoots []string) error {
+	if !path.IsAbs(workingDir) {
+		return fmt.Errorf("working_dir %q must be an absolute path", workingDir)
+	}
+
+	cleaned := path.Clean(workingDir)
+	for _, root := range allowedRoots {
+		root = path.Clean(root)
+		if cleaned == root || root == "/" || strings.HasPrefix(cleaned, root+"/") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("working_dir %q is outside the allowed directories %s", workingDir, strings.Join(allowedRoots, ", "))
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"exec-agent/models"
+	"strings"
+	"testing"
+)
+
+func TestValidatePorts(t *testing.T) {
+	valid := []map[string]string{
+		{"8080": "8080"},
+		{"8080/tcp": "127.0.0.1:18080"},
+		{"53/udp": "5353"},
+	}
+	for _, ports := range valid {
+		if err := validatePorts(ports, false); err != nil {
+			t.Errorf("%v: unexpected error %v", ports, err)
+		}
+	}
+
+	invalid := map[string]map[string]string{
+		"invalid protocol":       {"8080/http": "8080"},
+		"invalid container port": {"http": "8080"},
+		"must be between":        {"70000": "8080"},
+		"invalid host port":      {"8080": "127.0.0.1:"},
+		"privileged":             {"8080": "80"},
+	}
+	for want, ports := range invalid {
+		err := validatePorts(ports, false)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: expected error containing %q, got %v", ports, want, err)
+		}
+	}
+
+	if err := validatePorts(map[string]string{"8080": "80"}, true); err != nil {
+		t.Errorf("privileged host ports must be accepted when allowed, got %v", err)
+	}
+}
+
+func TestValidateWorkingDir(t *testing.T) {
+	roots := []string{"/workspace", "/opt/app/"}
+	for _, dir := range []string{"/workspace", "/workspace/src", "/opt/app/bin", "/workspace/src/../out"} {
+		if err := validateWorkingDir(dir, roots); err != nil {
+			t.Errorf("%s: unexpected error %v", dir, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"workspace/src":     "must be an absolute path",
+		"/etc":              "outside the allowed directories",
+		"/workspace-other":  "outside the allowed directories",
+		"/workspace/../etc": "outside the allowed directories",
+	}
+	for dir, want := range invalid {
+		err := validateWorkingDir(dir, roots)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected error containing %q, got %v", dir, want, err)
+		}
+	}
+}
+
+func TestBuildContainerConfigRejectsUnsafeSettings(t *testing.T) {
+	handler := newTestExecutionHandler()
+
+	cases := map[string]models.ContainerSpec{
+		"privileged":               {Image: "tools:1", Ports: map[string]string{"8080": "22"}},
+		"outside the allowed":      {Image: "tools:1", WorkingDir: "/etc"},
+		"must be an absolute path": {Image: "tools:1", WorkingDir: "src"},
+	}
+	for want, container := range cases {
+		_, err := handler.buildContainerConfig(&models.ExecutionRequest{Container: container}, "/tmp/ws", "exec-1")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: expected error containing %q, got %v", container, want, err)
+		}
+	}
+
+	handler.SetContainerPolicy(ContainerPolicy{AllowPrivilegedPorts: true, AllowedWorkingDirs: []string{"/srv"}})
+	config, err := handler.buildContainerConfig(&models.ExecutionRequest{Container: models.ContainerSpec{
+		Image: "tools:1", Ports: map[string]string{"8080": "80"}, WorkingDir: "/srv/app",
+	}}, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatalf("expected the policy to allow the settings, got %v", err)
+	}
+	if config.WorkingDir != "/srv/app" {
+		t.Errorf("got working dir %q", config.WorkingDir)
+	}
+}
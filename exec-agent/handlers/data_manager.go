@@ -3,9 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"exec-agent/clients"
 	"exec-agent/models"
@@ -14,24 +18,96 @@ import (
 )
 
 type DataManager struct {
-	minioClient  *clients.MinioClient
-	dockerClient *clients.DockerClient
+	minioClient    *clients.MinioClient
+	dockerClient   WorkspaceProvider
+	inlineLimits   InlineLimits
+	quota          WorkspaceQuota
+	urlExpiry      time.Duration                 // lifetime of presigned URLs for uploaded outputs
+	workspaces     map[string]*reusableWorkspace // execution/task -> workspace kept for a retry
+	workspaceMutex sync.Mutex
+}
+
+// WorkspaceProvider creates and removes the directories executions run in
+type WorkspaceProvider interface {
+	CreateWorkspace(executionID string) (string, error)
+	CleanupWorkspace(executionID string) error
+}
+
+// InlineLimits bound how much output file content is returned inline in a response.
+// Files beyond a limit are still listed with their path and size. Zero MaxFiles or
+// MaxBytes means no limit.
+type InlineLimits struct {
+	MaxFiles    int   // most files whose content is inlined
+	MaxBytes    int64 // total inlined bytes across all files
+	MaxFileSize int64 // largest single file inlined
+}
+
+// DefaultInlineLimits inlines files under 1MB, up to 50 files and 10MB in total
+var DefaultInlineLimits = InlineLimits{
+	MaxFiles:    50,
+	MaxBytes:    10 * 1024 * 1024,
+	MaxFileSize: 1024 * 1024,
 }
 
 func NewDataManager(minioClient *clients.MinioClient, dockerClient *clients.DockerClient) *DataManager {
 	return &DataManager{
 		minioClient:  minioClient,
 		dockerClient: dockerClient,
+		inlineLimits: DefaultInlineLimits,
+		quota:        DefaultWorkspaceQuota,
+		urlExpiry:    DefaultPresignExpiry,
+		workspaces:   make(map[string]*reusableWorkspace),
+	}
+}
+
+// DefaultPresignExpiry is how long presigned URLs for uploaded outputs stay valid
+const DefaultPresignExpiry = time.Hour
+
+// SetPresignExpiry sets how long presigned URLs for uploaded outputs stay valid
+func (dm *DataManager) SetPresignExpiry(expiry time.Duration) {
+	if expiry > 0 {
+		dm.urlExpiry = expiry
 	}
 }
 
-func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string, input *models.InputSpec) (string, error) {
+// SetInlineLimits overrides how much output file content is inlined in responses
+func (dm *DataManager) SetInlineLimits(limits InlineLimits) {
+	if limits.MaxFileSize <= 0 {
+		limits.MaxFileSize = DefaultInlineLimits.MaxFileSize
+	}
+	dm.inlineLimits = limits
+}
+
+// SetWorkspaceQuota overrides how much input a request may place in its workspace
+func (dm *DataManager) SetWorkspaceQuota(quota WorkspaceQuota) {
+	dm.quota = quota
+}
+
+// PrepareInputData creates a workspace and places the request's inputs in it, within the
+// workspace quota. The workspace is removed again if any input can't be placed.
+func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string, input *models.InputSpec) (workspace string, err error) {
+	var inlineBytes int64
+	for _, file := range input.Files {
+		inlineBytes += int64(len(file.Content))
+	}
+	if err := checkInputQuota(len(input.Files)+len(input.MinioObjects), inlineBytes, dm.quota); err != nil {
+		return "", err
+	}
+
 	workspacePath, err := dm.dockerClient.CreateWorkspace(executionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create workspace: %v", err)
 	}
+	defer func() {
+		if err != nil {
+			if cleanupErr := dm.CleanupWorkspace(executionID); cleanupErr != nil {
+				logrus.WithError(cleanupErr).WithField("execution_id", executionID).Warn("Failed to remove workspace after failed input preparation")
+			}
+		}
+	}()
 
 	inputDir := filepath.Join(workspacePath, "input")
+	usage := &workspaceUsage{quota: dm.quota}
 
 	logrus.WithFields(logrus.Fields{
 		"execution_id": executionID,
@@ -40,17 +116,24 @@ func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string,
 
 	// Handle graph data
 	if input.GraphData != nil {
-		if err := dm.writeGraphData(inputDir, input.GraphData); err != nil {
-			return "", fmt.Errorf("failed to write graph data: %v", err)
+		if err := dm.writeGraphData(inputDir, input.GraphData, usage); err != nil {
+			return "", fmt.Errorf("failed to write graph data: %w", err)
 		}
 	}
 
-	// Handle Minio objects
+	// Handle Minio objects, stopping each download once it would exceed the quota
 	for _, obj := range input.MinioObjects {
 		localPath := filepath.Join(inputDir, obj.LocalPath)
-		if err := dm.minioClient.DownloadFile(ctx, obj.ObjectName, localPath); err != nil {
+		size, err := dm.minioClient.DownloadFileWithLimit(ctx, obj.ObjectName, localPath, usage.remaining())
+		if errors.Is(err, clients.ErrObjectTooLarge) {
+			return "", fmt.Errorf("%w: Minio object %s does not fit in the remaining %d of %d bytes", ErrWorkspaceQuotaExceeded, obj.ObjectName, usage.remaining(), dm.quota.MaxBytes)
+		}
+		if err != nil {
 			return "", fmt.Errorf("failed to download Minio object %s: %v", obj.ObjectName, err)
 		}
+		if err := usage.reserve("Minio object "+obj.ObjectName, size); err != nil {
+			return "", err
+		}
 	}
 
 	// Handle direct file data
@@ -62,8 +145,8 @@ func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string,
 			return "", fmt.Errorf("failed to create directory for file %s: %v", file.Path, err)
 		}
 
-		if err := os.WriteFile(filePath, []byte(file.Content), 0644); err != nil {
-			return "", fmt.Errorf("failed to write file %s: %v", file.Path, err)
+		if err := usage.writeFile("file "+file.Path, filePath, []byte(file.Content)); err != nil {
+			return "", fmt.Errorf("failed to write file %s: %w", file.Path, err)
 		}
 	}
 
@@ -75,8 +158,8 @@ func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string,
 			return "", fmt.Errorf("failed to marshal config data: %v", err)
 		}
 
-		if err := os.WriteFile(configPath, configData, 0644); err != nil {
-			return "", fmt.Errorf("failed to write config file: %v", err)
+		if err := usage.writeFile("config data", configPath, configData); err != nil {
+			return "", fmt.Errorf("failed to write config file: %w", err)
 		}
 	}
 
@@ -86,12 +169,13 @@ func (dm *DataManager) PrepareInputData(ctx context.Context, executionID string,
 		"minio_objects":  len(input.MinioObjects),
 		"has_graph_data": input.GraphData != nil,
 		"has_config":     input.ConfigData != nil,
+		"input_bytes":    usage.bytes,
 	}).Info("Input data prepared successfully")
 
 	return workspacePath, nil
 }
 
-func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, workspacePath string, output *models.OutputSpec) (*models.ExecutionResult, error) {
+func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, workspacePath string, output *models.OutputSpec, labels map[string]string) (*models.ExecutionResult, error) {
 	result := &models.ExecutionResult{
 		OutputFiles:  make([]models.OutputFile, 0),
 		MinioObjects: make([]models.MinioOutputObject, 0),
@@ -116,7 +200,9 @@ func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, works
 		}
 	}
 
-	// Process expected files
+	// Process expected files, inlining content within the configured limits
+	inlinedFiles := 0
+	var inlinedBytes int64
 	for _, expectedFile := range output.ExpectedFiles {
 		filePath := filepath.Join(outputDir, expectedFile)
 		if info, err := os.Stat(filePath); err == nil {
@@ -126,10 +212,18 @@ func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, works
 				Size: info.Size(),
 			}
 
-			// Read content for small files (< 1MB)
-			if info.Size() < 1024*1024 {
+			switch {
+			case info.Size() >= dm.inlineLimits.MaxFileSize:
+				outputFile.InlineSkipped = "file_size"
+			case dm.inlineLimits.MaxFiles > 0 && inlinedFiles >= dm.inlineLimits.MaxFiles:
+				outputFile.InlineSkipped = "file_count"
+			case dm.inlineLimits.MaxBytes > 0 && inlinedBytes+info.Size() > dm.inlineLimits.MaxBytes:
+				outputFile.InlineSkipped = "byte_budget"
+			default:
 				if content, err := os.ReadFile(filePath); err == nil {
 					outputFile.Content = string(content)
+					inlinedFiles++
+					inlinedBytes += int64(len(content))
 				}
 			}
 
@@ -142,15 +236,25 @@ func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, works
 		}
 	}
 
+	var prefix string
+	if dm.minioClient != nil && (output.MinioUpload || hasSkippedFiles(result.OutputFiles)) {
+		prefix = dm.minioClient.GenerateDirectoryPath(executionID, "output", pathLabels(labels, output.PathLabels))
+	}
+
 	// Upload to Minio if requested
 	if output.MinioUpload {
-		minioObjects, err := dm.uploadOutputToMinio(ctx, executionID, outputDir)
+		minioObjects, err := dm.uploadOutputToMinio(ctx, prefix, outputDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to upload output to Minio: %v", err)
 		}
 		result.MinioObjects = minioObjects
 	}
 
+	// Files that were not inlined are returned by reference to a Minio object instead
+	if prefix != "" {
+		dm.referenceSkippedFiles(ctx, prefix, result)
+	}
+
 	// Include logs if requested
 	if output.ReturnLogs {
 		logPath := filepath.Join(workspacePath, "execution.log")
@@ -159,6 +263,17 @@ func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, works
 		}
 	}
 
+	result.Metadata["inlined_files"] = inlinedFiles
+	result.Metadata["inlined_bytes"] = inlinedBytes
+	if inlinedFiles < len(result.OutputFiles) {
+		logrus.WithFields(logrus.Fields{
+			"execution_id":  executionID,
+			"output_files":  len(result.OutputFiles),
+			"inlined_files": inlinedFiles,
+			"inlined_bytes": inlinedBytes,
+		}).Info("Output file content not inlined beyond limits")
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"execution_id":   executionID,
 		"output_files":   len(result.OutputFiles),
@@ -169,7 +284,7 @@ func (dm *DataManager) ExtractOutputData(ctx context.Context, executionID, works
 	return result, nil
 }
 
-func (dm *DataManager) writeGraphData(inputDir string, graphData *models.GraphData) error {
+func (dm *DataManager) writeGraphData(inputDir string, graphData *models.GraphData, usage *workspaceUsage) error {
 	graphPath := filepath.Join(inputDir, "graph_data.json")
 	
 	data, err := json.MarshalIndent(graphData, "", "  ")
@@ -177,7 +292,7 @@ func (dm *DataManager) writeGraphData(inputDir string, graphData *models.GraphDa
 		return fmt.Errorf("failed to marshal graph data: %v", err)
 	}
 
-	return os.WriteFile(graphPath, data, 0644)
+	return usage.writeFile("graph data", graphPath, data)
 }
 
 func (dm *DataManager) readGraphUpdate(graphPath string) (*models.GraphData, error) {
@@ -198,7 +313,20 @@ func (dm *DataManager) readGraphUpdate(graphPath string) (*models.GraphData, err
 	return &graphData, nil
 }
 
-func (dm *DataManager) uploadOutputToMinio(ctx context.Context, executionID, outputDir string) ([]models.MinioOutputObject, error) {
+// pathLabels returns the values for custom path scheme placeholders: the workflow
+// execution's labels, overridden by the request's own path labels
+func pathLabels(executionLabels, requestLabels map[string]string) map[string]string {
+	labels := make(map[string]string, len(executionLabels)+len(requestLabels))
+	for key, value := range executionLabels {
+		labels[key] = value
+	}
+	for key, value := range requestLabels {
+		labels[key] = value
+	}
+	return labels
+}
+
+func (dm *DataManager) uploadOutputToMinio(ctx context.Context, prefix, outputDir string) ([]models.MinioOutputObject, error) {
 	var minioObjects []models.MinioOutputObject
 
 	// Check if output directory exists and has files
@@ -206,24 +334,78 @@ func (dm *DataManager) uploadOutputToMinio(ctx context.Context, executionID, out
 		return minioObjects, nil // No output directory, return empty list
 	}
 
-	// Upload entire output directory
-	prefix := dm.minioClient.GenerateDirectoryPath(executionID, "output")
+	// Upload entire output directory; files are streamed from disk
 	uploadedObjects, err := dm.minioClient.UploadDirectory(ctx, outputDir, prefix)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert to MinioOutputObject format
-	for _, objectName := range uploadedObjects {
+	for _, object := range uploadedObjects {
 		minioObjects = append(minioObjects, models.MinioOutputObject{
-			ObjectName: objectName,
-			Size:       0, // Size will be filled by Minio client if needed
+			ObjectName: object.Name,
+			Size:       object.Size,
+			URL:        dm.presignedURL(ctx, object.Name),
 		})
 	}
 
 	return minioObjects, nil
 }
 
+// referenceSkippedFiles points each output file that was not inlined at its Minio object,
+// uploading it under the output prefix unless the whole directory was already uploaded.
+// A file that cannot be uploaded is still listed with its path and size.
+func (dm *DataManager) referenceSkippedFiles(ctx context.Context, prefix string, result *models.ExecutionResult) {
+	uploaded := make(map[string]models.MinioOutputObject, len(result.MinioObjects))
+	for _, object := range result.MinioObjects {
+		uploaded[object.ObjectName] = object
+	}
+
+	for i := range result.OutputFiles {
+		file := &result.OutputFiles[i]
+		if file.InlineSkipped == "" {
+			continue
+		}
+
+		objectName := path.Join(prefix, filepath.ToSlash(file.Name))
+		object, exists := uploaded[objectName]
+		if !exists {
+			size, err := dm.minioClient.UploadFile(ctx, file.Path, objectName)
+			if err != nil {
+				logrus.WithError(err).WithField("file", file.Name).Warn("Failed to upload output file for reference")
+				continue
+			}
+			object = models.MinioOutputObject{
+				ObjectName: objectName,
+				Size:       size,
+				URL:        dm.presignedURL(ctx, objectName),
+			}
+		}
+
+		file.ObjectName = object.ObjectName
+		file.URL = object.URL
+	}
+}
+
+// presignedURL signs a download URL for an uploaded object. An object whose URL cannot
+// be signed is still returned, so callers can fetch it with their own credentials.
+func (dm *DataManager) presignedURL(ctx context.Context, objectName string) string {
+	url, err := dm.minioClient.PresignedGetURL(ctx, objectName, dm.urlExpiry)
+	if err != nil {
+		logrus.WithError(err).WithField("object", objectName).Warn("Failed to presign output object URL")
+	}
+	return url
+}
+
+func hasSkippedFiles(files []models.OutputFile) bool {
+	for _, file := range files {
+		if file.InlineSkipped != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (dm *DataManager) CleanupWorkspace(executionID string) error {
 	return dm.dockerClient.CleanupWorkspace(executionID)
 }
\ No newline at end of file
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathLabelsLetRequestOverrideExecutionLabels(t *testing.T) {
+	got := pathLabels(
+		map[string]string{"tenant": "acme", "team": "data"},
+		map[string]string{"team": "ml", "region": "eu"},
+	)
+	want := map[string]string{"tenant": "acme", "team": "ml", "region": "eu"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -3,15 +3,23 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"exec-agent/clients"
 	"exec-agent/models"
+	"exec-agent/tracing"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ExecutionHandler struct {
@@ -19,19 +27,66 @@ type ExecutionHandler struct {
 	minioClient  *clients.MinioClient
 	dataManager  *DataManager
 	serviceProxy *ServiceProxy
+	serviceURLs  ContainerServiceURLs
+	policy       ContainerPolicy
+	statusStore  ExecutionStatusStore
 }
 
+// ContainerServiceURLs are the addresses containers use to reach the service proxy
+type ContainerServiceURLs struct {
+	ProxyURL string
+	DataURL  string
+	AIURL    string
+}
+
+// DefaultContainerServiceURLs reaches the proxy on the Docker host's default port
+var DefaultContainerServiceURLs = ContainerServiceURLs{
+	ProxyURL: "http://host.docker.internal:9000",
+	DataURL:  "http://host.docker.internal:9000/data",
+	AIURL:    "http://host.docker.internal:9000/ai",
+}
+
+// envReferencePattern matches ${NAME} references in environment values
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.]*)\}`)
+
 func NewExecutionHandler(dockerClient *clients.DockerClient, minioClient *clients.MinioClient, serviceProxy *ServiceProxy) *ExecutionHandler {
 	dataManager := NewDataManager(minioClient, dockerClient)
-	
+
 	return &ExecutionHandler{
 		dockerClient: dockerClient,
 		minioClient:  minioClient,
 		dataManager:  dataManager,
 		serviceProxy: serviceProxy,
+		serviceURLs:  DefaultContainerServiceURLs,
+		policy:       DefaultContainerPolicy,
 	}
 }
 
+// SetContainerPolicy overrides the port and working directory restrictions for requests
+func (eh *ExecutionHandler) SetContainerPolicy(policy ContainerPolicy) {
+	eh.policy = policy
+}
+
+// SetContainerServiceURLs overrides the service proxy addresses injected into containers
+func (eh *ExecutionHandler) SetContainerServiceURLs(urls ContainerServiceURLs) {
+	eh.serviceURLs = urls
+}
+
+// SetOutputURLExpiry sets how long presigned URLs for uploaded outputs stay valid
+func (eh *ExecutionHandler) SetOutputURLExpiry(expiry time.Duration) {
+	eh.dataManager.SetPresignExpiry(expiry)
+}
+
+// SetWorkspaceQuota bounds the input bytes and files a request may place in its workspace
+func (eh *ExecutionHandler) SetWorkspaceQuota(quota WorkspaceQuota) {
+	eh.dataManager.SetWorkspaceQuota(quota)
+}
+
+// SetOutputInlineLimits bounds how much output file content responses inline
+func (eh *ExecutionHandler) SetOutputInlineLimits(limits InlineLimits) {
+	eh.dataManager.SetInlineLimits(limits)
+}
+
 func (eh *ExecutionHandler) HandleRequest(ctx context.Context, data []byte) []byte {
 	startTime := time.Now()
 	
@@ -43,7 +98,12 @@ func (eh *ExecutionHandler) HandleRequest(ctx context.Context, data []byte) []by
 		return responseData
 	}
 
+	if req.Operation == models.OperationStatus {
+		return eh.handleStatusRequest(ctx, &req, startTime)
+	}
+
 	executionID := uuid.New().String()
+	tracker := eh.trackExecution(&req, executionID, startTime)
 	
 	logrus.WithFields(logrus.Fields{
 		"correlation_id": req.CorrelationID,
@@ -51,7 +111,26 @@ func (eh *ExecutionHandler) HandleRequest(ctx context.Context, data []byte) []by
 		"container_image": req.Container.Image,
 	}).Info("Processing execution request")
 
-	response := eh.executeContainer(ctx, &req, executionID, startTime)
+	// Continue the caller's trace, if it sent one
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, req.TraceContext), "exec.run",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("correlation_id", req.CorrelationID),
+			attribute.String("execution_id", executionID),
+			attribute.String("container.image", req.Container.Image),
+		))
+
+	response := eh.executeContainer(ctx, &req, executionID, startTime, tracker)
+	tracker.finish(response)
+
+	var spanErr error
+	if response.Result != nil {
+		span.SetAttributes(attribute.Int("container.exit_code", response.Result.ExitCode))
+	}
+	if !response.Success {
+		spanErr = errors.New(response.Error)
+	}
+	tracing.End(span, spanErr)
 	
 	responseData, err := json.Marshal(response)
 	if err != nil {
@@ -63,7 +142,7 @@ func (eh *ExecutionHandler) HandleRequest(ctx context.Context, data []byte) []by
 	return responseData
 }
 
-func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.ExecutionRequest, executionID string, startTime time.Time) *models.ExecutionResponse {
+func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.ExecutionRequest, executionID string, startTime time.Time, tracker *executionTracker) *models.ExecutionResponse {
 	// Set default timeout if not specified
 	timeout := time.Duration(300) * time.Second // 5 minutes default
 	if req.Timeout > 0 {
@@ -73,15 +152,17 @@ func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.Ex
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Prepare input data and workspace
-	workspacePath, err := eh.dataManager.PrepareInputData(execCtx, executionID, &req.Input)
+	// Prepare input data and workspace, reusing one kept by a failed earlier attempt
+	workspacePath, workspaceID, reused, err := eh.dataManager.PrepareWorkspace(execCtx, executionID, req)
 	if err != nil {
 		return models.NewErrorResponse(req.CorrelationID, executionID, fmt.Sprintf("Failed to prepare input data: %v", err), time.Since(startTime))
 	}
 
-	// Cleanup workspace on completion
+	// Cleanup workspace on completion; a reusable workspace is kept while a retry may follow
+	succeeded := false
 	defer func() {
-		if cleanupErr := eh.dataManager.CleanupWorkspace(executionID); cleanupErr != nil {
+		keep := !succeeded && !req.Workspace.FinalAttempt
+		if cleanupErr := eh.dataManager.ReleaseWorkspace(req, workspaceID, keep); cleanupErr != nil {
 			logrus.WithError(cleanupErr).Warn("Failed to cleanup workspace")
 		}
 	}()
@@ -92,14 +173,25 @@ func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.Ex
 		return models.NewErrorResponse(req.CorrelationID, executionID, fmt.Sprintf("Failed to build container config: %v", err), time.Since(startTime))
 	}
 
+	// Pipe the requested input to the container's stdin
+	stdin, err := openStdin(&req.Input, workspacePath)
+	if err != nil {
+		return models.NewErrorResponse(req.CorrelationID, executionID, fmt.Sprintf("Failed to prepare stdin: %v", err), time.Since(startTime))
+	}
+	if stdin != nil {
+		defer stdin.Close()
+		containerConfig.Stdin = stdin
+	}
+
 	// Execute container
+	tracker.running()
 	execResult, err := eh.dockerClient.ExecuteContainer(execCtx, *containerConfig, executionID)
 	if err != nil {
 		return models.NewErrorResponse(req.CorrelationID, executionID, fmt.Sprintf("Container execution failed: %v", err), time.Since(startTime))
 	}
 
 	// Extract output data
-	outputResult, err := eh.dataManager.ExtractOutputData(execCtx, executionID, workspacePath, &req.Output)
+	outputResult, err := eh.dataManager.ExtractOutputData(execCtx, executionID, workspacePath, &req.Output, req.Labels)
 	if err != nil {
 		return models.NewErrorResponse(req.CorrelationID, executionID, fmt.Sprintf("Failed to extract output data: %v", err), time.Since(startTime))
 	}
@@ -107,6 +199,8 @@ func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.Ex
 	// Merge execution results
 	outputResult.ExitCode = execResult.ExitCode
 	outputResult.Output = execResult.Output
+	outputResult.Stdout = execResult.Stdout
+	outputResult.Stderr = execResult.Stderr
 	if execResult.Error != "" {
 		outputResult.Metadata["execution_error"] = execResult.Error
 	}
@@ -115,11 +209,23 @@ func (eh *ExecutionHandler) executeContainer(ctx context.Context, req *models.Ex
 	if !success && outputResult.Metadata == nil {
 		outputResult.Metadata = make(map[string]interface{})
 	}
+	if workspaceReuseKey(req) != "" {
+		outputResult.Metadata["workspace_reused"] = reused
+	}
+	succeeded = success && execResult.Error == ""
 
 	return models.NewSuccessResponse(req.CorrelationID, executionID, outputResult, time.Since(startTime))
 }
 
 func (eh *ExecutionHandler) buildContainerConfig(req *models.ExecutionRequest, workspacePath, executionID string) (*clients.ContainerConfig, error) {
+	if err := validatePorts(req.Container.Ports, eh.policy.AllowPrivilegedPorts); err != nil {
+		return nil, err
+	}
+	resources, err := resolveResources(&req.Container, eh.policy)
+	if err != nil {
+		return nil, err
+	}
+
 	// Prepare mounts
 	mounts := []clients.Mount{
 		{
@@ -146,27 +252,29 @@ func (eh *ExecutionHandler) buildContainerConfig(req *models.ExecutionRequest, w
 
 	// Add service proxy access if requested
 	if len(req.ServiceAccess) > 0 {
-		// Service proxy will be accessible at host IP
-		environment = append(environment, "SERVICE_PROXY_URL=http://host.docker.internal:9000")
-		
+		environment = append(environment, fmt.Sprintf("SERVICE_PROXY_URL=%s", eh.serviceURLs.ProxyURL))
+
 		for _, service := range req.ServiceAccess {
 			switch service {
 			case models.ServiceData:
-				environment = append(environment, "DATA_SERVICE_URL=http://host.docker.internal:9000/data")
+				environment = append(environment, fmt.Sprintf("DATA_SERVICE_URL=%s", eh.serviceURLs.DataURL))
 			case models.ServiceAI:
-				environment = append(environment, "AI_SERVICE_URL=http://host.docker.internal:9000/ai")
+				environment = append(environment, fmt.Sprintf("AI_SERVICE_URL=%s", eh.serviceURLs.AIURL))
 			}
 		}
 	}
 
-	// Add custom environment variables
+	// Add custom environment variables, resolving ${NAME} references
 	for key, value := range req.Environment {
-		environment = append(environment, fmt.Sprintf("%s=%s", key, value))
+		environment = append(environment, fmt.Sprintf("%s=%s", key, interpolateEnvValue(value, environment, req.Input.ConfigData)))
 	}
 
 	// Set working directory
 	workingDir := "/workspace"
 	if req.Container.WorkingDir != "" {
+		if err := validateWorkingDir(req.Container.WorkingDir, eh.policy.AllowedWorkingDirs); err != nil {
+			return nil, err
+		}
 		workingDir = req.Container.WorkingDir
 	}
 
@@ -177,12 +285,59 @@ func (eh *ExecutionHandler) buildContainerConfig(req *models.ExecutionRequest, w
 		Mounts:      mounts,
 		Ports:       req.Container.Ports,
 		WorkingDir:  workingDir,
+		CPUs:        resources.CPUs,
+		Memory:      resources.Memory,
+		PidsLimit:   resources.Pids,
 	}
 
 	return config, nil
 }
 
-func (eh *ExecutionHandler) GetExecutionStatus(executionID string) (string, error) {
-	// This could be extended to track execution status in Redis or database
-	return "unknown", nil
-}
\ No newline at end of file
+// openStdin returns the request's stdin content, or the named input file, for piping to
+// the container; nil when the request provides neither
+func openStdin(input *models.InputSpec, workspacePath string) (io.ReadCloser, error) {
+	if input.Stdin != "" && input.StdinFile != "" {
+		return nil, fmt.Errorf("stdin and stdin_file are mutually exclusive")
+	}
+
+	if input.Stdin != "" {
+		return io.NopCloser(strings.NewReader(input.Stdin)), nil
+	}
+
+	if input.StdinFile == "" {
+		return nil, nil
+	}
+
+	inputDir := filepath.Join(workspacePath, "input")
+	path := filepath.Join(inputDir, input.StdinFile)
+	if rel, err := filepath.Rel(inputDir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("stdin_file %s is outside the input directory", input.StdinFile)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin_file %s: %v", input.StdinFile, err)
+	}
+	return file, nil
+}
+
+// interpolateEnvValue resolves ${NAME} references in a custom environment value against
+// the built-in environment and the request's config data. Workflow variables are resolved
+// by the orchestrator before dispatch; unknown references are left untouched.
+func interpolateEnvValue(value string, environment []string, configData map[string]interface{}) string {
+	return envReferencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+
+		for _, entry := range environment {
+			if strings.HasPrefix(entry, name+"=") {
+				return strings.TrimPrefix(entry, name+"=")
+			}
+		}
+
+		if configValue, exists := configData[name]; exists {
+			return fmt.Sprintf("%v", configValue)
+		}
+
+		return match
+	})
+}
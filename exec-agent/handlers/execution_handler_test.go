@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"exec-agent/models"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestExecutionHandler returns a handler that can build container configs without
+// Docker or Minio
+func newTestExecutionHandler() *ExecutionHandler {
+	return &ExecutionHandler{
+		serviceURLs: DefaultContainerServiceURLs,
+		policy:      DefaultContainerPolicy,
+	}
+}
+
+// envValue returns the value of name in a container environment
+func envValue(environment []string, name string) (string, bool) {
+	for _, entry := range environment {
+		if strings.HasPrefix(entry, name+"=") {
+			return strings.TrimPrefix(entry, name+"="), true
+		}
+	}
+	return "", false
+}
+
+func TestBuildContainerConfigInjectsConfiguredServiceURLs(t *testing.T) {
+	handler := newTestExecutionHandler()
+	handler.SetContainerServiceURLs(ContainerServiceURLs{
+		ProxyURL: "http://10.0.0.5:9100",
+		DataURL:  "http://10.0.0.5:9100/data",
+		AIURL:    "http://10.0.0.5:9100/ai",
+	})
+
+	req := &models.ExecutionRequest{
+		Container:     models.ContainerSpec{Image: "tools:1"},
+		ServiceAccess: []string{models.ServiceData},
+	}
+	config, err := handler.buildContainerConfig(req, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"SERVICE_PROXY_URL": "http://10.0.0.5:9100",
+		"DATA_SERVICE_URL":  "http://10.0.0.5:9100/data",
+	} {
+		if got, _ := envValue(config.Environment, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, exists := envValue(config.Environment, "AI_SERVICE_URL"); exists {
+		t.Error("expected no AI service URL without AI access")
+	}
+
+	// Without service access no proxy address is injected at all
+	req.ServiceAccess = nil
+	config, err = handler.buildContainerConfig(req, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := envValue(config.Environment, "SERVICE_PROXY_URL"); exists {
+		t.Error("expected no proxy URL without service access")
+	}
+}
+
+func TestBuildContainerConfigInterpolatesEnvironment(t *testing.T) {
+	handler := newTestExecutionHandler()
+
+	req := &models.ExecutionRequest{
+		Container:     models.ContainerSpec{Image: "tools:1"},
+		ServiceAccess: []string{models.ServiceAI},
+		Environment: map[string]string{
+			"MODEL_ENDPOINT": "${AI_SERVICE_URL}/generate",
+			"REPORT_PATH":    "${WORKSPACE_OUTPUT}/${report_name}.csv",
+			"UNKNOWN":        "${missing}",
+		},
+		Input: models.InputSpec{ConfigData: map[string]interface{}{"report_name": "daily"}},
+	}
+	config, err := handler.buildContainerConfig(req, "/tmp/ws", "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"MODEL_ENDPOINT": DefaultContainerServiceURLs.AIURL + "/generate",
+		"REPORT_PATH":    "/workspace/output/daily.csv",
+		"UNKNOWN":        "${missing}",
+	} {
+		if got, _ := envValue(config.Environment, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestOpenStdin(t *testing.T) {
+	workspacePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspacePath, "input"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspacePath, "input", "rows.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []models.InputSpec{{Stdin: "a,b\n"}, {StdinFile: "rows.csv"}} {
+		stdin, err := openStdin(&input, workspacePath)
+		if err != nil {
+			t.Fatalf("%+v: %v", input, err)
+		}
+		content, _ := io.ReadAll(stdin)
+		stdin.Close()
+		if string(content) != "a,b\n" {
+			t.Errorf("%+v: got %q", input, content)
+		}
+	}
+
+	if stdin, err := openStdin(&models.InputSpec{}, workspacePath); stdin != nil || err != nil {
+		t.Errorf("got %v, %v without stdin, want nothing to pipe", stdin, err)
+	}
+
+	rejected := map[string]models.InputSpec{
+		"mutually exclusive": {Stdin: "x", StdinFile: "rows.csv"},
+		"outside the input":  {StdinFile: "../config/config.json"},
+		"failed to open":     {StdinFile: "missing.csv"},
+	}
+	for want, input := range rejected {
+		if _, err := openStdin(&input, workspacePath); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%+v: got %v, want an error containing %q", input, err, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"exec-agent/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// statusHeartbeatInterval is how often a running execution's status record is refreshed
+const statusHeartbeatInterval = 15 * time.Second
+
+// statusWriteTimeout bounds a status write, which must not hold up the execution
+const statusWriteTimeout = 2 * time.Second
+
+// errStatusTrackingDisabled is returned for lookups when no status store is configured
+var errStatusTrackingDisabled = errors.New("execution status tracking is not enabled")
+
+// ExecutionStatusStore records and looks up execution lifecycles
+type ExecutionStatusStore interface {
+	Save(ctx context.Context, status *models.ExecutionStatus) error
+	Get(ctx context.Context, execID string) (*models.ExecutionStatus, error)
+	GetForTask(ctx context.Context, executionID, taskID string) (*models.ExecutionStatus, error)
+}
+
+// SetStatusStore enables recording each execution's lifecycle and looking it up
+func (eh *ExecutionHandler) SetStatusStore(store ExecutionStatusStore) {
+	eh.statusStore = store
+}
+
+// executionTracker records one execution's lifecycle. Recording is best effort: a failed
+// write is logged and never fails the execution. A nil tracker records nothing.
+type executionTracker struct {
+	store     ExecutionStatusStore
+	mutex     sync.Mutex
+	status    models.ExecutionStatus
+	heartbeat chan struct{}
+	done      sync.WaitGroup
+}
+
+// trackExecution records a request as queued and returns its tracker
+func (eh *ExecutionHandler) trackExecution(req *models.ExecutionRequest, execID string, queuedAt time.Time) *executionTracker {
+	if eh.statusStore == nil {
+		return nil
+	}
+
+	t := &executionTracker{
+		store: eh.statusStore,
+		status: models.ExecutionStatus{
+			ExecID:        execID,
+			CorrelationID: req.CorrelationID,
+			ExecutionID:   req.ExecutionID,
+			TaskID:        req.TaskID,
+			Image:         req.Container.Image,
+			Status:        models.ExecutionQueued,
+			QueuedAt:      queuedAt,
+		},
+	}
+	t.save()
+	return t
+}
+
+// running records that the container is starting and keeps the record fresh until finish
+func (t *executionTracker) running() {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	now := time.Now()
+	t.status.Status = models.ExecutionRunning
+	t.status.StartTime = &now
+	t.mutex.Unlock()
+	t.save()
+
+	t.heartbeat = make(chan struct{})
+	t.done.Add(1)
+	go func() {
+		defer t.done.Done()
+		ticker := time.NewTicker(statusHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.heartbeat:
+				return
+			case <-ticker.C:
+				t.save()
+			}
+		}
+	}()
+}
+
+// finish records how the execution ended, from the response sent back
+func (t *executionTracker) finish(response *models.ExecutionResponse) {
+	if t == nil {
+		return
+	}
+	if t.heartbeat != nil {
+		close(t.heartbeat)
+		t.done.Wait()
+	}
+
+	t.mutex.Lock()
+	now := time.Now()
+	t.status.EndTime = &now
+	t.status.Status = models.ExecutionCompleted
+	if !response.Success {
+		t.status.Status = models.ExecutionFailed
+		t.status.Error = response.Error
+	}
+	if result := response.Result; result != nil {
+		exitCode := result.ExitCode
+		t.status.ExitCode = &exitCode
+		if exitCode != 0 {
+			t.status.Status = models.ExecutionFailed
+		}
+		if executionError, _ := result.Metadata["execution_error"].(string); executionError != "" {
+			t.status.Status = models.ExecutionFailed
+			t.status.Error = executionError
+		}
+	}
+	t.mutex.Unlock()
+	t.save()
+}
+
+func (t *executionTracker) save() {
+	t.mutex.Lock()
+	t.status.UpdatedAt = time.Now()
+	status := t.status
+	t.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusWriteTimeout)
+	defer cancel()
+	if err := t.store.Save(ctx, &status); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"exec_id": status.ExecID,
+			"status":  status.Status,
+		}).Warn("Failed to record execution status")
+	}
+}
+
+// GetExecutionStatus returns the recorded status of an agent execution
+func (eh *ExecutionHandler) GetExecutionStatus(ctx context.Context, execID string) (*models.ExecutionStatus, error) {
+	if eh.statusStore == nil {
+		return nil, errStatusTrackingDisabled
+	}
+	return eh.statusStore.Get(ctx, execID)
+}
+
+// GetTaskExecutionStatus returns the recorded status of the latest attempt of a workflow task
+func (eh *ExecutionHandler) GetTaskExecutionStatus(ctx context.Context, executionID, taskID string) (*models.ExecutionStatus, error) {
+	if eh.statusStore == nil {
+		return nil, errStatusTrackingDisabled
+	}
+	return eh.statusStore.GetForTask(ctx, executionID, taskID)
+}
+
+// handleStatusRequest answers a status operation, by exec_id or by execution_id and task_id
+func (eh *ExecutionHandler) handleStatusRequest(ctx context.Context, req *models.ExecutionRequest, startTime time.Time) []byte {
+	var status *models.ExecutionStatus
+	var err error
+	switch {
+	case req.ExecID != "":
+		status, err = eh.GetExecutionStatus(ctx, req.ExecID)
+	case req.ExecutionID != "" && req.TaskID != "":
+		status, err = eh.GetTaskExecutionStatus(ctx, req.ExecutionID, req.TaskID)
+	default:
+		err = errors.New("status operation requires exec_id, or execution_id and task_id")
+	}
+
+	var response *models.ExecutionResponse
+	if err != nil {
+		response = models.NewErrorResponse(req.CorrelationID, req.ExecID, fmt.Sprintf("Failed to get execution status: %v", err), time.Since(startTime))
+	} else {
+		response = models.NewSuccessResponse(req.CorrelationID, status.ExecID, nil, time.Since(startTime))
+		response.Status = status
+	}
+
+	responseData, _ := json.Marshal(response)
+	return responseData
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"exec-agent/clients"
+	"exec-agent/models"
+
+	"github.com/gorilla/mux"
+)
+
+// memoryStatusStore keeps status records in memory and remembers every write
+type memoryStatusStore struct {
+	mutex   sync.Mutex
+	records map[string]models.ExecutionStatus
+	tasks   map[string]string
+	saved   []models.ExecutionStatus
+}
+
+func newMemoryStatusStore() *memoryStatusStore {
+	return &memoryStatusStore{records: make(map[string]models.ExecutionStatus), tasks: make(map[string]string)}
+}
+
+func (s *memoryStatusStore) Save(ctx context.Context, status *models.ExecutionStatus) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[status.ExecID] = *status
+	if status.ExecutionID != "" && status.TaskID != "" {
+		s.tasks[status.ExecutionID+":"+status.TaskID] = status.ExecID
+	}
+	s.saved = append(s.saved, *status)
+	return nil
+}
+
+func (s *memoryStatusStore) Get(ctx context.Context, execID string) (*models.ExecutionStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status, exists := s.records[execID]
+	if !exists {
+		return nil, clients.ErrExecutionStatusNotFound
+	}
+	return &status, nil
+}
+
+func (s *memoryStatusStore) GetForTask(ctx context.Context, executionID, taskID string) (*models.ExecutionStatus, error) {
+	s.mutex.Lock()
+	execID, exists := s.tasks[executionID+":"+taskID]
+	s.mutex.Unlock()
+	if !exists {
+		return nil, clients.ErrExecutionStatusNotFound
+	}
+	return s.Get(ctx, execID)
+}
+
+// transitions returns the statuses written, in order
+func (s *memoryStatusStore) transitions() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	statuses := make([]string, len(s.saved))
+	for i, status := range s.saved {
+		statuses[i] = status.Status
+	}
+	return statuses
+}
+
+// newStatusTestHandler returns a handler that runs containers with a stand-in docker CLI
+// exiting with exitCode, and records statuses in memory
+func newStatusTestHandler(t *testing.T, exitCode string) (*ExecutionHandler, *memoryStatusStore) {
+	t.Helper()
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n[ \"$1\" = run ] || exit 0\necho done\nexit " + exitCode + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	docker, err := clients.NewDockerClient("", "", t.TempDir(), "", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newMemoryStatusStore()
+	handler := NewExecutionHandler(docker, nil, nil)
+	handler.SetStatusStore(store)
+	return handler, store
+}
+
+// handle sends a request to the handler and decodes its response
+func handle(t *testing.T, handler *ExecutionHandler, request *models.ExecutionRequest) *models.ExecutionResponse {
+	t.Helper()
+	data, _ := json.Marshal(request)
+	var response models.ExecutionResponse
+	if err := json.Unmarshal(handler.HandleRequest(context.Background(), data), &response); err != nil {
+		t.Fatal(err)
+	}
+	return &response
+}
+
+func assertTransitions(t *testing.T, store *memoryStatusStore, want ...string) {
+	t.Helper()
+	if got := store.transitions(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got transitions %v, want %v", got, want)
+	}
+}
+
+func TestExecutionStatusRecordsCompletedRun(t *testing.T) {
+	handler, store := newStatusTestHandler(t, "0")
+
+	response := handle(t, handler, &models.ExecutionRequest{
+		CorrelationID: "req-1",
+		ExecutionID:   "workflow-1",
+		TaskID:        "build",
+		Container:     models.ContainerSpec{Image: "tools:1"},
+	})
+	if !response.Success {
+		t.Fatalf("got %+v, want the execution to succeed", response)
+	}
+
+	assertTransitions(t, store, models.ExecutionQueued, models.ExecutionRunning, models.ExecutionCompleted)
+	status, err := store.Get(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ExitCode == nil || *status.ExitCode != 0 || status.Error != "" {
+		t.Errorf("got %+v, want exit code 0 without an error", status)
+	}
+	if status.StartTime == nil || status.EndTime == nil || status.EndTime.Before(*status.StartTime) || status.StartTime.Before(status.QueuedAt) {
+		t.Errorf("got queued %v, started %v, ended %v, want them in order", status.QueuedAt, status.StartTime, status.EndTime)
+	}
+	if status.CorrelationID != "req-1" || status.Image != "tools:1" {
+		t.Errorf("got %+v, want the request's details", status)
+	}
+
+	// The record can be looked up by agent execution and by workflow task
+	for _, lookup := range []*models.ExecutionRequest{
+		{Operation: models.OperationStatus, ExecID: response.ExecutionID},
+		{Operation: models.OperationStatus, ExecutionID: "workflow-1", TaskID: "build"},
+	} {
+		answer := handle(t, handler, lookup)
+		if !answer.Success || answer.Status == nil || answer.Status.Status != models.ExecutionCompleted || answer.ExecutionID != response.ExecutionID {
+			t.Errorf("%+v: got %+v, want the completed record", lookup, answer)
+		}
+	}
+	if len(store.transitions()) != 3 {
+		t.Errorf("got %d writes, want status lookups not to record anything", len(store.saved))
+	}
+}
+
+func TestExecutionStatusRecordsNonZeroExit(t *testing.T) {
+	handler, store := newStatusTestHandler(t, "3")
+
+	response := handle(t, handler, &models.ExecutionRequest{Container: models.ContainerSpec{Image: "tools:1"}})
+
+	assertTransitions(t, store, models.ExecutionQueued, models.ExecutionRunning, models.ExecutionFailed)
+	status, _ := store.Get(context.Background(), response.ExecutionID)
+	// The shell client reports a container that exits non-zero as a failed run
+	if status.ExitCode == nil || *status.ExitCode != -1 || !strings.Contains(status.Error, "exit status 3") {
+		t.Errorf("got %+v, want the failed run's exit code and error", status)
+	}
+}
+
+func TestExecutionStatusRecordsFailureBeforeRunning(t *testing.T) {
+	handler, store := newStatusTestHandler(t, "0")
+
+	response := handle(t, handler, &models.ExecutionRequest{Container: models.ContainerSpec{Image: "tools:1", WorkingDir: "/etc"}})
+	if response.Success {
+		t.Fatal("expected the request to be rejected")
+	}
+
+	assertTransitions(t, store, models.ExecutionQueued, models.ExecutionFailed)
+	status, _ := store.Get(context.Background(), response.ExecutionID)
+	if status.StartTime != nil || status.ExitCode != nil || !strings.Contains(status.Error, "Failed to build container config") {
+		t.Errorf("got %+v, want a failure without a run", status)
+	}
+}
+
+func TestStatusOperationErrors(t *testing.T) {
+	handler, _ := newStatusTestHandler(t, "0")
+
+	cases := []struct {
+		request *models.ExecutionRequest
+		want    string
+	}{
+		{&models.ExecutionRequest{Operation: models.OperationStatus, TaskID: "build"}, "requires exec_id, or execution_id and task_id"},
+		{&models.ExecutionRequest{Operation: models.OperationStatus, ExecID: "missing"}, "execution status not found"},
+	}
+	for _, c := range cases {
+		response := handle(t, handler, c.request)
+		if response.Success || !strings.Contains(response.Error, c.want) {
+			t.Errorf("%+v: got %+v, want an error containing %q", c.request, response, c.want)
+		}
+	}
+
+	handler.SetStatusStore(nil)
+	response := handle(t, handler, &models.ExecutionRequest{Operation: models.OperationStatus, ExecID: "exec-1"})
+	if response.Success || !strings.Contains(response.Error, "tracking is not enabled") {
+		t.Errorf("got %+v, want lookups refused without a store", response)
+	}
+}
+
+func TestExecutionStatusEndpoint(t *testing.T) {
+	store := newMemoryStatusStore()
+	store.Save(context.Background(), &models.ExecutionStatus{ExecID: "agent-1", ExecutionID: "workflow-1", TaskID: "build", Status: models.ExecutionRunning})
+	sp := NewServiceProxy(0, "", "", nil)
+	sp.SetStatusStore(store)
+
+	cases := []struct {
+		vars       map[string]string
+		wantStatus int
+	}{
+		{map[string]string{"exec_id": "agent-1"}, http.StatusOK},
+		{map[string]string{"execution_id": "workflow-1", "task_id": "build"}, http.StatusOK},
+		{map[string]string{"exec_id": "missing"}, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		sp.executionStatusHandler(recorder, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/executions", nil), c.vars))
+		if recorder.Code != c.wantStatus {
+			t.Errorf("%v: got status %d, want %d", c.vars, recorder.Code, c.wantStatus)
+			continue
+		}
+		if c.wantStatus != http.StatusOK {
+			continue
+		}
+		var status models.ExecutionStatus
+		if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil || status.ExecID != "agent-1" || status.Status != models.ExecutionRunning {
+			t.Errorf("%v: got %+v, %v, want the running record", c.vars, status, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"exec-agent/clients"
+)
+
+// dirWorkspaces lays out workspaces under a directory the way the Docker client does and
+// counts how many it created
+type dirWorkspaces struct {
+	mutex   sync.Mutex
+	root    string
+	created int
+}
+
+func (d *dirWorkspaces) CreateWorkspace(executionID string) (string, error) {
+	d.mutex.Lock()
+	d.created++
+	d.mutex.Unlock()
+
+	workspacePath := filepath.Join(d.root, executionID)
+	for _, dir := range []string{"input", "output", "config"} {
+		if err := os.MkdirAll(filepath.Join(workspacePath, dir), 0755); err != nil {
+			return "", err
+		}
+	}
+	return workspacePath, nil
+}
+
+func (d *dirWorkspaces) CleanupWorkspace(executionID string) error {
+	return os.RemoveAll(filepath.Join(d.root, executionID))
+}
+
+// newTestDataManager returns a data manager whose workspaces live in a temporary directory
+// and that has no Minio client
+func newTestDataManager(t *testing.T) (*DataManager, *dirWorkspaces) {
+	t.Helper()
+	workspaces := &dirWorkspaces{root: t.TempDir()}
+	dm := NewDataManager(nil, nil)
+	dm.dockerClient = workspaces
+	return dm, workspaces
+}
+
+// fakeS3 is just enough of the S3 API for the Minio client: one bucket whose objects are
+// kept in memory, counting the uploads it received
+type fakeS3 struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	puts    int
+	host    string
+}
+
+// withFakeS3 connects dm to a fake S3 server with the bucket "outputs"
+func withFakeS3(t *testing.T, dm *DataManager) *fakeS3 {
+	t.Helper()
+	s3 := &fakeS3{bucket: "outputs", objects: make(map[string][]byte)}
+	server := httptest.NewServer(s3)
+	t.Cleanup(server.Close)
+
+	serverURL, _ := url.Parse(server.URL)
+	s3.host = serverURL.Host
+	client, err := clients.NewMinioClient(s3.host, "access", "secret", s3.bucket, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm.minioClient = client
+	return s3
+}
+
+func (s *fakeS3) put(name string, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.objects[name] = content
+}
+
+func (s *fakeS3) get(name string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	content, exists := s.objects[name]
+	return content, exists
+}
+
+func (s *fakeS3) uploads() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.puts
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.URL.Query().Has("location"):
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`)
+	case key == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut:
+		content, err := readS3Payload(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.mutex.Lock()
+		s.objects[key] = content
+		s.puts++
+		s.mutex.Unlock()
+		w.Header().Set("ETag", `"fake"`)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		content, exists := s.get(key)
+		if !exists {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("ETag", `"fake"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+// readS3Payload returns an uploaded object's content. Over plain HTTP the client signs
+// uploads chunk by chunk, framing each chunk as "<hex size>;chunk-signature=...\r\n".
+func readS3Payload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var content bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(&content, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exec-agent/models"
+)
+
+// writeOutputs creates a workspace whose output directory holds files of the given sizes
+func writeOutputs(t *testing.T, sizes map[string]int) string {
+	t.Helper()
+	workspacePath := t.TempDir()
+	outputDir := filepath.Join(workspacePath, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return workspacePath
+}
+
+func TestOutputFilesAreInlinedWithinLimits(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	dm.SetInlineLimits(InlineLimits{MaxFiles: 3, MaxBytes: 25, MaxFileSize: 100})
+	workspacePath := writeOutputs(t, map[string]int{
+		"a.txt": 10, "big.bin": 200, "b.txt": 10, "c.txt": 10, "d.txt": 4, "e.txt": 1,
+	})
+
+	output := &models.OutputSpec{ExpectedFiles: []string{"a.txt", "big.bin", "b.txt", "missing.txt", "c.txt", "d.txt", "e.txt"}}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are considered in the order expected; a skipped file leaves room for later ones
+	want := map[string]string{
+		"a.txt":   "",
+		"big.bin": "file_size",
+		"b.txt":   "",
+		"c.txt":   "byte_budget",
+		"d.txt":   "",
+		"e.txt":   "file_count",
+	}
+	if len(result.OutputFiles) != len(want) {
+		t.Fatalf("got %d output files, want %d", len(result.OutputFiles), len(want))
+	}
+	for _, file := range result.OutputFiles {
+		if file.InlineSkipped != want[file.Name] {
+			t.Errorf("%s: got skipped %q, want %q", file.Name, file.InlineSkipped, want[file.Name])
+		}
+		if inlined := file.Content != ""; inlined != (file.InlineSkipped == "") {
+			t.Errorf("%s: got inlined %v with skipped %q", file.Name, inlined, file.InlineSkipped)
+		}
+		if file.Path == "" || file.Size == 0 {
+			t.Errorf("%s: expected skipped files to still be listed with path and size, got %+v", file.Name, file)
+		}
+	}
+
+	if result.Metadata["inlined_files"] != 3 || result.Metadata["inlined_bytes"] != int64(24) {
+		t.Errorf("got metadata %v, want 3 files and 24 bytes inlined", result.Metadata)
+	}
+}
+
+func TestZeroInlineLimitsOnlyCapFileSize(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	dm.SetInlineLimits(InlineLimits{})
+	workspacePath := writeOutputs(t, map[string]int{"a.txt": 600 * 1024, "b.txt": 600 * 1024, "big.bin": 1024 * 1024})
+
+	output := &models.OutputSpec{ExpectedFiles: []string{"a.txt", "b.txt", "big.bin"}}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No count or byte limit applies, but the default per-file cap still does
+	for _, file := range result.OutputFiles {
+		wantSkipped := ""
+		if file.Name == "big.bin" {
+			wantSkipped = "file_size"
+		}
+		if file.InlineSkipped != wantSkipped {
+			t.Errorf("%s: got skipped %q, want %q", file.Name, file.InlineSkipped, wantSkipped)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"exec-agent/models"
+)
+
+func TestLargeOutputFilesAreReturnedByReference(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	s3 := withFakeS3(t, dm)
+	dm.SetInlineLimits(InlineLimits{MaxFileSize: 1024})
+	workspacePath := writeOutputs(t, map[string]int{"small.txt": 10, "large.bin": 8 * 1024 * 1024})
+
+	output := &models.OutputSpec{ExpectedFiles: []string{"small.txt", "large.bin"}}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]models.OutputFile)
+	for _, file := range result.OutputFiles {
+		files[file.Name] = file
+	}
+	if small := files["small.txt"]; small.Content == "" || small.ObjectName != "" {
+		t.Errorf("got %+v, want the small file inlined without a reference", small)
+	}
+
+	large := files["large.bin"]
+	if large.Content != "" || large.InlineSkipped != "file_size" {
+		t.Errorf("got content of %d bytes skipped as %q, want the large file not inlined", len(large.Content), large.InlineSkipped)
+	}
+	if large.ObjectName != "executions/exec-1/output/large.bin" {
+		t.Errorf("got object name %q", large.ObjectName)
+	}
+	if !strings.Contains(large.URL, s3.host+"/outputs/executions/exec-1/output/large.bin?") {
+		t.Errorf("got URL %q, want a presigned URL for the object", large.URL)
+	}
+	if content, _ := s3.get(large.ObjectName); len(content) != 8*1024*1024 {
+		t.Errorf("got an uploaded object of %d bytes, want the whole file", len(content))
+	}
+	if s3.uploads() != 1 {
+		t.Errorf("got %d uploads, want only the large file", s3.uploads())
+	}
+}
+
+func TestReferencedFilesReuseTheOutputUpload(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	s3 := withFakeS3(t, dm)
+	dm.SetInlineLimits(InlineLimits{MaxFileSize: 1024})
+	workspacePath := writeOutputs(t, map[string]int{"small.txt": 10, "large.bin": 4096})
+
+	output := &models.OutputSpec{ExpectedFiles: []string{"small.txt", "large.bin"}, MinioUpload: true}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The whole directory is uploaded once; the large file points at its object
+	if s3.uploads() != 2 || len(result.MinioObjects) != 2 {
+		t.Fatalf("got %d uploads and %d objects, want each file uploaded once", s3.uploads(), len(result.MinioObjects))
+	}
+	for _, file := range result.OutputFiles {
+		if file.Name != "large.bin" {
+			continue
+		}
+		if file.ObjectName != "executions/exec-1/output/large.bin" || file.URL == "" {
+			t.Errorf("got %+v, want a reference to the uploaded object", file)
+		}
+	}
+}
+
+func TestLargeOutputFileStaysListedWithoutMinio(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	dm.SetInlineLimits(InlineLimits{MaxFileSize: 1024})
+	workspacePath := writeOutputs(t, map[string]int{"large.bin": 4096})
+
+	output := &models.OutputSpec{ExpectedFiles: []string{"large.bin"}}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.OutputFiles) != 1 {
+		t.Fatalf("got %d output files, want 1", len(result.OutputFiles))
+	}
+	if file := result.OutputFiles[0]; file.Content != "" || file.ObjectName != "" || file.Size != 4096 {
+		t.Errorf("got %+v, want the file listed by size only", file)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"exec-agent/models"
+)
+
+func TestUploadedOutputsReportSizeAndPresignedURL(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	s3 := withFakeS3(t, dm)
+	dm.SetPresignExpiry(10 * time.Minute)
+	if err := dm.minioClient.SetPathScheme("{tenant}/{execution_id}/{file}"); err != nil {
+		t.Fatal(err)
+	}
+	workspacePath := writeOutputs(t, map[string]int{"result.json": 120, "chart.png": 2048})
+
+	output := &models.OutputSpec{MinioUpload: true, PathLabels: map[string]string{"tenant": "acme"}}
+	result, err := dm.ExtractOutputData(context.Background(), "exec-1", workspacePath, output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int64{"acme/exec-1/output/result.json": 120, "acme/exec-1/output/chart.png": 2048}
+	if len(result.MinioObjects) != len(want) {
+		t.Fatalf("got objects %+v, want %d", result.MinioObjects, len(want))
+	}
+	for _, object := range result.MinioObjects {
+		size, expected := want[object.ObjectName]
+		if !expected || object.Size != size {
+			t.Errorf("got object %s of %d bytes, want one of %v", object.ObjectName, object.Size, want)
+			continue
+		}
+		if content, _ := s3.get(object.ObjectName); int64(len(content)) != size {
+			t.Errorf("%s: stored %d bytes, want %d", object.ObjectName, len(content), size)
+		}
+
+		presigned, err := url.Parse(object.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", object.ObjectName, err)
+		}
+		if presigned.Host != s3.host || presigned.Path != "/outputs/"+object.ObjectName {
+			t.Errorf("got URL %s, want it to point at the object", object.URL)
+		}
+		if expires := presigned.Query().Get("X-Amz-Expires"); expires != "600" {
+			t.Errorf("%s: got expiry %q, want the configured 600 seconds", object.ObjectName, expires)
+		}
+	}
+}
+
+func TestPresignExpiryDefaultsWhenUnset(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	dm.SetPresignExpiry(0)
+	if dm.urlExpiry != DefaultPresignExpiry {
+		t.Errorf("got expiry %v, want the default %v", dm.urlExpiry, DefaultPresignExpiry)
+	}
+}
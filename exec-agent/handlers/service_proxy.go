@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"exec-agent/clients"
+	"exec-agent/models"
+
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -17,8 +21,33 @@ import (
 type ServiceProxy struct {
 	dataAbstractorURL string
 	aiAbstractorURL   string
+	dataRoute         ProxyRouteOptions
+	aiRoute           ProxyRouteOptions
 	server            *http.Server
 	redisPublisher    func(channel string, data interface{}) error
+	statusStore       ExecutionStatusStore
+}
+
+// ProxyRouteOptions controls how requests on one proxied route reach the backend
+type ProxyRouteOptions struct {
+	Headers      map[string]string // set on every forwarded request, e.g. service-to-service auth
+	StripHeaders []string          // dropped from forwarded requests in addition to hop-by-hop headers
+	Timeout      time.Duration     // backend request timeout; zero uses the default
+}
+
+const defaultProxyTimeout = 30 * time.Second
+
+// hopByHopHeaders apply to a single connection and must not be forwarded (RFC 7230 section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
 }
 
 func NewServiceProxy(port int, dataAbstractorURL, aiAbstractorURL string, redisPublisher func(string, interface{}) error) *ServiceProxy {
@@ -29,6 +58,18 @@ func NewServiceProxy(port int, dataAbstractorURL, aiAbstractorURL string, redisP
 	}
 }
 
+// SetRouteOptions configures header injection, header stripping and timeouts for the
+// data and AI routes. Must be called before Start.
+func (sp *ServiceProxy) SetRouteOptions(data, ai ProxyRouteOptions) {
+	sp.dataRoute = data
+	sp.aiRoute = ai
+}
+
+// SetStatusStore enables the execution status endpoints. Must be called before Start.
+func (sp *ServiceProxy) SetStatusStore(store ExecutionStatusStore) {
+	sp.statusStore = store
+}
+
 func (sp *ServiceProxy) Start(ctx context.Context, port int) error {
 	router := mux.NewRouter()
 
@@ -45,10 +86,24 @@ func (sp *ServiceProxy) Start(ctx context.Context, port int) error {
 	router.HandleFunc("/data/query", sp.dataQueryHandler).Methods("POST")
 	router.HandleFunc("/ai/query", sp.aiQueryHandler).Methods("POST")
 
+	// Execution status lookups
+	if sp.statusStore != nil {
+		router.HandleFunc("/executions/{exec_id}", sp.executionStatusHandler).Methods("GET")
+		router.HandleFunc("/executions/{execution_id}/tasks/{task_id}", sp.executionStatusHandler).Methods("GET")
+	}
+
+	// Leave room for the slowest route so the server does not cut off a proxied response
+	writeTimeout := 30 * time.Second
+	for _, route := range []ProxyRouteOptions{sp.dataRoute, sp.aiRoute} {
+		if route.Timeout+5*time.Second > writeTimeout {
+			writeTimeout = route.Timeout + 5*time.Second
+		}
+	}
+
 	sp.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: router,
-		WriteTimeout: 30 * time.Second,
+		Addr:         fmt.Sprintf(":%d", port),
+		Handler:      router,
+		WriteTimeout: writeTimeout,
 		ReadTimeout:  30 * time.Second,
 	}
 
@@ -87,6 +142,31 @@ func (sp *ServiceProxy) healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// executionStatusHandler returns a recorded execution status, by agent execution ID or
+// by workflow execution and task
+func (sp *ServiceProxy) executionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var status *models.ExecutionStatus
+	var err error
+	if execID, ok := vars["exec_id"]; ok {
+		status, err = sp.statusStore.Get(r.Context(), execID)
+	} else {
+		status, err = sp.statusStore.GetForTask(r.Context(), vars["execution_id"], vars["task_id"])
+	}
+	if errors.Is(err, clients.ErrExecutionStatusNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get execution status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
 func (sp *ServiceProxy) dataProxyHandler(w http.ResponseWriter, r *http.Request) {
 	// Remove /data prefix from path
 	targetPath := strings.TrimPrefix(r.URL.Path, "/data")
@@ -96,7 +176,7 @@ func (sp *ServiceProxy) dataProxyHandler(w http.ResponseWriter, r *http.Request)
 		targetURL += "?" + r.URL.RawQuery
 	}
 
-	sp.proxyRequest(w, r, targetURL)
+	sp.proxyRequest(w, r, targetURL, sp.dataRoute)
 }
 
 func (sp *ServiceProxy) aiProxyHandler(w http.ResponseWriter, r *http.Request) {
@@ -108,10 +188,10 @@ func (sp *ServiceProxy) aiProxyHandler(w http.ResponseWriter, r *http.Request) {
 		targetURL += "?" + r.URL.RawQuery
 	}
 
-	sp.proxyRequest(w, r, targetURL)
+	sp.proxyRequest(w, r, targetURL, sp.aiRoute)
 }
 
-func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL string) {
+func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, targetURL string, route ProxyRouteOptions) {
 	// Create new request
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -119,21 +199,25 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 		return
 	}
 
-	req, err := http.NewRequest(r.Method, targetURL, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, bytes.NewReader(body))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to create request: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Copy headers
-	for key, values := range r.Header {
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
+	// Copy headers, minus hop-by-hop and configured ones, then inject the route's headers
+	req.Header = sanitizeHeaders(r.Header, route.StripHeaders)
+	for key, value := range route.Headers {
+		req.Header.Set(key, value)
+	}
+
+	timeout := route.Timeout
+	if timeout <= 0 {
+		timeout = defaultProxyTimeout
 	}
 
 	// Execute request
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to execute request: %v", err), http.StatusBadGateway)
@@ -142,7 +226,7 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 	defer resp.Body.Close()
 
 	// Copy response headers
-	for key, values := range resp.Header {
+	for key, values := range sanitizeHeaders(resp.Header, nil) {
 		for _, value := range values {
 			w.Header().Add(key, value)
 		}
@@ -156,6 +240,31 @@ func (sp *ServiceProxy) proxyRequest(w http.ResponseWriter, r *http.Request, tar
 	}
 }
 
+// sanitizeHeaders copies headers without hop-by-hop headers, headers listed in Connection,
+// and the extra names given
+func sanitizeHeaders(header http.Header, strip []string) http.Header {
+	result := header.Clone()
+	if result == nil {
+		result = make(http.Header)
+	}
+
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				result.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		result.Del(name)
+	}
+	for _, name := range strip {
+		result.Del(name)
+	}
+
+	return result
+}
+
 func (sp *ServiceProxy) dataQueryHandler(w http.ResponseWriter, r *http.Request) {
 	var request map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
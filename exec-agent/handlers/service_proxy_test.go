@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newProxyBackend starts a backend that records the first request it receives
+func newProxyBackend(t *testing.T, delay time.Duration) (*httptest.Server, chan *http.Request) {
+	t.Helper()
+	received := make(chan *http.Request, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r:
+		default:
+		}
+		time.Sleep(delay)
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-Backend", "data")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(backend.Close)
+	return backend, received
+}
+
+func TestProxyInjectsAuthAndStripsHopByHopHeaders(t *testing.T) {
+	backend, received := newProxyBackend(t, 0)
+	sp := NewServiceProxy(0, backend.URL, "http://ai.invalid", nil)
+	sp.SetRouteOptions(ProxyRouteOptions{
+		Headers:      map[string]string{"Authorization": "Bearer service-token"},
+		StripHeaders: []string{"Cookie"},
+	}, ProxyRouteOptions{})
+
+	request := httptest.NewRequest(http.MethodPost, "/data/query?limit=5", strings.NewReader(`{}`))
+	request.Header.Set("Authorization", "Bearer from-container")
+	request.Header.Set("Connection", "keep-alive, X-Hop")
+	request.Header.Set("X-Hop", "1")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Proxy-Authorization", "Basic secret")
+	request.Header.Set("Cookie", "session=1")
+	request.Header.Set("X-Request-ID", "req-1")
+	recorder := httptest.NewRecorder()
+	sp.dataProxyHandler(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", recorder.Code, recorder.Body.String())
+	}
+	forwarded := <-received
+	if forwarded.URL.Path != "/query" || forwarded.URL.RawQuery != "limit=5" {
+		t.Errorf("got %s?%s, want the path without the route prefix", forwarded.URL.Path, forwarded.URL.RawQuery)
+	}
+	if got := forwarded.Header.Get("Authorization"); got != "Bearer service-token" {
+		t.Errorf("got Authorization %q, want the injected header to replace the caller's", got)
+	}
+	for _, name := range []string{"X-Hop", "Upgrade", "Proxy-Authorization", "Cookie"} {
+		if value := forwarded.Header.Get(name); value != "" {
+			t.Errorf("got %s %q forwarded, want it stripped", name, value)
+		}
+	}
+	if forwarded.Header.Get("X-Request-ID") != "req-1" {
+		t.Error("expected end-to-end headers to be forwarded")
+	}
+
+	if recorder.Header().Get("Keep-Alive") != "" || recorder.Header().Get("X-Backend") != "data" {
+		t.Errorf("got response headers %v, want only end-to-end headers copied", recorder.Header())
+	}
+}
+
+func TestProxyRoutesUseTheirOwnOptions(t *testing.T) {
+	backend, received := newProxyBackend(t, 0)
+	sp := NewServiceProxy(0, "http://data.invalid", backend.URL, nil)
+	sp.SetRouteOptions(ProxyRouteOptions{
+		Headers: map[string]string{"Authorization": "Bearer data-token"},
+	}, ProxyRouteOptions{
+		Headers: map[string]string{"X-Api-Key": "ai-key"},
+	})
+
+	recorder := httptest.NewRecorder()
+	sp.aiProxyHandler(recorder, httptest.NewRequest(http.MethodGet, "/ai/models", nil))
+
+	forwarded := <-received
+	if forwarded.Header.Get("X-Api-Key") != "ai-key" || forwarded.Header.Get("Authorization") != "" {
+		t.Errorf("got headers %v, want only the AI route's headers", forwarded.Header)
+	}
+}
+
+func TestProxyAppliesRouteTimeout(t *testing.T) {
+	backend, _ := newProxyBackend(t, 500*time.Millisecond)
+	sp := NewServiceProxy(0, backend.URL, backend.URL, nil)
+	sp.SetRouteOptions(ProxyRouteOptions{Timeout: 50 * time.Millisecond}, ProxyRouteOptions{})
+
+	recorder := httptest.NewRecorder()
+	sp.dataProxyHandler(recorder, httptest.NewRequest(http.MethodGet, "/data/health", nil))
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("got status %d from a slow data backend, want %d", recorder.Code, http.StatusBadGateway)
+	}
+
+	// The AI route keeps the default timeout
+	recorder = httptest.NewRecorder()
+	sp.aiProxyHandler(recorder, httptest.NewRequest(http.MethodGet, "/ai/health", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got status %d from the AI route, want %d", recorder.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrWorkspaceQuotaExceeded is returned when a request's inputs don't fit its workspace quota
+var ErrWorkspaceQuotaExceeded = errors.New("workspace quota exceeded")
+
+// WorkspaceQuota bounds what a request may place in its workspace. Zero MaxBytes or
+// MaxFiles means no limit.
+type WorkspaceQuota struct {
+	MaxBytes int64 // input bytes written or downloaded, including graph and config data
+	MaxFiles int   // input files and Minio objects
+}
+
+// DefaultWorkspaceQuota allows 1GB of input across up to 1000 files
+var DefaultWorkspaceQuota = WorkspaceQuota{
+	MaxBytes: 1 << 30,
+	MaxFiles: 1000,
+}
+
+// workspaceUsage counts the bytes placed in a workspace against its quota
+type workspaceUsage struct {
+	quota WorkspaceQuota
+	bytes int64
+}
+
+// remaining returns how many more bytes fit, or -1 when there is no limit
+func (u *workspaceUsage) remaining() int64 {
+	if u.quota.MaxBytes <= 0 {
+		return -1
+	}
+	return u.quota.MaxBytes - u.bytes
+}
+
+// reserve accounts for size more bytes, failing if they would exceed the quota
+func (u *workspaceUsage) reserve(what string, size int64) error {
+	if u.quota.MaxBytes > 0 && u.bytes+size > u.quota.MaxBytes {
+		return fmt.Errorf("%w: %s needs %d bytes but only %d of %d remain", ErrWorkspaceQuotaExceeded, what, size, u.quota.MaxBytes-u.bytes, u.quota.MaxBytes)
+	}
+	u.bytes += size
+	return nil
+}
+
+// writeFile writes data to a workspace file once the quota allows it
+func (u *workspaceUsage) writeFile(what, path string, data []byte) error {
+	if err := u.reserve(what, int64(len(data))); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// checkInputQuota rejects requests whose file count, or inline content alone, is over the
+// quota before anything is written or downloaded
+func checkInputQuota(fileCount int, inlineBytes int64, quota WorkspaceQuota) error {
+	if quota.MaxFiles > 0 && fileCount > quota.MaxFiles {
+		return fmt.Errorf("%w: %d input files exceed the limit of %d", ErrWorkspaceQuotaExceeded, fileCount, quota.MaxFiles)
+	}
+	if quota.MaxBytes > 0 && inlineBytes > quota.MaxBytes {
+		return fmt.Errorf("%w: %d bytes of inline files exceed the limit of %d", ErrWorkspaceQuotaExceeded, inlineBytes, quota.MaxBytes)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"exec-agent/models"
+)
+
+// assertQuotaExceeded checks that preparing input failed on the quota and left no workspace
+func assertQuotaExceeded(t *testing.T, workspaces *dirWorkspaces, err error, want string) {
+	t.Helper()
+	if !errors.Is(err, ErrWorkspaceQuotaExceeded) || !strings.Contains(err.Error(), want) {
+		t.Fatalf("got %v, want the quota exceeded by %s", err, want)
+	}
+	if _, statErr := os.Stat(filepath.Join(workspaces.root, "exec-1")); !os.IsNotExist(statErr) {
+		t.Errorf("expected the workspace to be removed, got %v", statErr)
+	}
+}
+
+func TestInputFileCountIsRejectedBeforeCreatingWorkspace(t *testing.T) {
+	dm, workspaces := newTestDataManager(t)
+	dm.SetWorkspaceQuota(WorkspaceQuota{MaxFiles: 2})
+
+	input := &models.InputSpec{
+		Files:        []models.FileData{{Path: "a.txt", Content: "a"}, {Path: "b.txt", Content: "b"}},
+		MinioObjects: []models.MinioObject{{ObjectName: "data.csv", LocalPath: "data.csv"}},
+	}
+	_, err := dm.PrepareInputData(context.Background(), "exec-1", input)
+	assertQuotaExceeded(t, workspaces, err, "3 input files exceed the limit of 2")
+	if workspaces.created != 0 {
+		t.Error("expected no workspace to be created")
+	}
+}
+
+func TestInlineBytesAreRejectedBeforeCreatingWorkspace(t *testing.T) {
+	dm, workspaces := newTestDataManager(t)
+	dm.SetWorkspaceQuota(WorkspaceQuota{MaxBytes: 10})
+
+	input := &models.InputSpec{Files: []models.FileData{{Path: "a.txt", Content: "123456"}, {Path: "b.txt", Content: "123456"}}}
+	_, err := dm.PrepareInputData(context.Background(), "exec-1", input)
+	assertQuotaExceeded(t, workspaces, err, "12 bytes of inline files exceed the limit of 10")
+	if workspaces.created != 0 {
+		t.Error("expected no workspace to be created")
+	}
+}
+
+func TestConfigDataCountsTowardsQuota(t *testing.T) {
+	dm, workspaces := newTestDataManager(t)
+	dm.SetWorkspaceQuota(WorkspaceQuota{MaxBytes: 20})
+
+	input := &models.InputSpec{
+		Files:      []models.FileData{{Path: "a.txt", Content: "0123456789"}},
+		ConfigData: map[string]interface{}{"mode": "a long enough value"},
+	}
+	_, err := dm.PrepareInputData(context.Background(), "exec-1", input)
+	assertQuotaExceeded(t, workspaces, err, "config data needs")
+	if workspaces.created != 1 {
+		t.Errorf("got %d workspaces created, want the one removed again", workspaces.created)
+	}
+}
+
+func TestMinioDownloadStopsAtQuota(t *testing.T) {
+	dm, workspaces := newTestDataManager(t)
+	s3 := withFakeS3(t, dm)
+	s3.put("inputs/small.csv", []byte(strings.Repeat("x", 40)))
+	s3.put("inputs/large.csv", []byte(strings.Repeat("x", 4096)))
+	dm.SetWorkspaceQuota(WorkspaceQuota{MaxBytes: 1024})
+
+	input := &models.InputSpec{MinioObjects: []models.MinioObject{
+		{ObjectName: "inputs/small.csv", LocalPath: "small.csv"},
+		{ObjectName: "inputs/large.csv", LocalPath: "large.csv"},
+	}}
+	_, err := dm.PrepareInputData(context.Background(), "exec-1", input)
+	assertQuotaExceeded(t, workspaces, err, "Minio object inputs/large.csv does not fit in the remaining 984 of 1024 bytes")
+}
+
+func TestInputWithinQuotaIsPrepared(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	s3 := withFakeS3(t, dm)
+	s3.put("inputs/data.csv", []byte("a,b\n1,2\n"))
+	dm.SetWorkspaceQuota(WorkspaceQuota{MaxBytes: 1024, MaxFiles: 2})
+
+	input := &models.InputSpec{
+		Files:        []models.FileData{{Path: "nested/a.txt", Content: "hello"}},
+		MinioObjects: []models.MinioObject{{ObjectName: "inputs/data.csv", LocalPath: "data.csv"}},
+	}
+	workspacePath, err := dm.PrepareInputData(context.Background(), "exec-1", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"nested/a.txt": "hello", "data.csv": "a,b\n1,2\n"} {
+		content, err := os.ReadFile(filepath.Join(workspacePath, "input", name))
+		if err != nil || string(content) != want {
+			t.Errorf("%s: got %q, %v, want %q", name, content, err, want)
+		}
+	}
+}
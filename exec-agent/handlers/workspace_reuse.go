@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"exec-agent/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reusableWorkspaceTTL is how long a kept workspace waits for the next attempt of its task
+const reusableWorkspaceTTL = 30 * time.Minute
+
+// reusableWorkspace is a prepared workspace kept between attempts of one task
+type reusableWorkspace struct {
+	id        string
+	path      string
+	inputHash string
+	expiresAt time.Time
+	inUse     bool // an attempt is running in it, so it is never reaped
+}
+
+// workspaceReuseKey identifies the task a request's workspace may be reused for, or ""
+// when reuse is not requested
+func workspaceReuseKey(req *models.ExecutionRequest) string {
+	if !req.Workspace.Reuse || req.ExecutionID == "" || req.TaskID == "" {
+		return ""
+	}
+	return req.ExecutionID + "/" + req.TaskID
+}
+
+// PrepareWorkspace returns a workspace with the request's inputs in place. When the request
+// asks for reuse and an earlier attempt of the same task left a workspace with identical
+// inputs, that workspace is returned with its output directory emptied instead of
+// downloading everything again. The returned ID is passed to ReleaseWorkspace.
+func (dm *DataManager) PrepareWorkspace(ctx context.Context, executionID string, req *models.ExecutionRequest) (string, string, bool, error) {
+	key := workspaceReuseKey(req)
+	if key == "" {
+		workspacePath, err := dm.PrepareInputData(ctx, executionID, &req.Input)
+		return workspacePath, executionID, false, err
+	}
+
+	inputHash, err := hashInput(&req.Input)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	dm.reapWorkspaces(time.Now())
+
+	dm.workspaceMutex.Lock()
+	kept := dm.workspaces[key]
+	if kept != nil && (req.Workspace.Clean || kept.inputHash != inputHash) {
+		delete(dm.workspaces, key)
+	} else if kept != nil {
+		kept.inUse = true
+	}
+	dm.workspaceMutex.Unlock()
+
+	if kept != nil && !req.Workspace.Clean && kept.inputHash == inputHash {
+		err := resetOutputDir(kept.path)
+		if err == nil {
+			logrus.WithFields(logrus.Fields{
+				"execution_id": executionID,
+				"task_id":      req.TaskID,
+				"workspace":    kept.path,
+			}).Info("Reusing workspace from previous attempt")
+			return kept.path, kept.id, true, nil
+		}
+		logrus.WithError(err).WithField("workspace", kept.path).Warn("Failed to reset kept workspace, preparing a new one")
+		dm.workspaceMutex.Lock()
+		delete(dm.workspaces, key)
+		dm.workspaceMutex.Unlock()
+	}
+	if kept != nil {
+		if err := dm.CleanupWorkspace(kept.id); err != nil {
+			logrus.WithError(err).WithField("workspace", kept.path).Warn("Failed to remove discarded workspace")
+		}
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	workspaceID := "task-" + hex.EncodeToString(sum[:8])
+	workspacePath, err := dm.PrepareInputData(ctx, workspaceID, &req.Input)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	dm.workspaceMutex.Lock()
+	dm.workspaces[key] = &reusableWorkspace{
+		id:        workspaceID,
+		path:      workspacePath,
+		inputHash: inputHash,
+		expiresAt: time.Now().Add(reusableWorkspaceTTL),
+		inUse:     true,
+	}
+	dm.workspaceMutex.Unlock()
+
+	return workspacePath, workspaceID, false, nil
+}
+
+// ReleaseWorkspace removes a workspace after an execution unless keep is set and the
+// request asked for reuse, in which case it waits for the next attempt of the task
+func (dm *DataManager) ReleaseWorkspace(req *models.ExecutionRequest, workspaceID string, keep bool) error {
+	key := workspaceReuseKey(req)
+	if key != "" {
+		dm.workspaceMutex.Lock()
+		kept := dm.workspaces[key]
+		if kept != nil && kept.id == workspaceID {
+			kept.inUse = false
+			if keep {
+				kept.expiresAt = time.Now().Add(reusableWorkspaceTTL)
+				dm.workspaceMutex.Unlock()
+				return nil
+			}
+			delete(dm.workspaces, key)
+		}
+		dm.workspaceMutex.Unlock()
+	}
+
+	return dm.CleanupWorkspace(workspaceID)
+}
+
+// reapWorkspaces removes kept workspaces whose task was never retried
+func (dm *DataManager) reapWorkspaces(now time.Time) {
+	var expired []*reusableWorkspace
+
+	dm.workspaceMutex.Lock()
+	for key, kept := range dm.workspaces {
+		if !kept.inUse && now.After(kept.expiresAt) {
+			delete(dm.workspaces, key)
+			expired = append(expired, kept)
+		}
+	}
+	dm.workspaceMutex.Unlock()
+
+	for _, kept := range expired {
+		if err := dm.CleanupWorkspace(kept.id); err != nil {
+			logrus.WithError(err).WithField("workspace", kept.path).Warn("Failed to remove expired workspace")
+		}
+	}
+}
+
+// hashInput fingerprints a request's inputs so a kept workspace is only reused for the
+// same inputs
+func hashInput(input *models.InputSpec) (string, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash input: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// resetOutputDir empties a workspace's output directory so a retry starts without the
+// previous attempt's files
+func resetOutputDir(workspacePath string) error {
+	outputDir := filepath.Join(workspacePath, "output")
+	if err := os.RemoveAll(outputDir); err != nil {
+		return err
+	}
+	return os.MkdirAll(outputDir, 0755)
+}
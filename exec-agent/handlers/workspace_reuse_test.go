@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"exec-agent/models"
+)
+
+// retryRequest is an attempt of task "process" in execution "exec-1" that asks for reuse
+func retryRequest(content string) *models.ExecutionRequest {
+	return &models.ExecutionRequest{
+		ExecutionID: "exec-1",
+		TaskID:      "process",
+		Input:       models.InputSpec{Files: []models.FileData{{Path: "data.csv", Content: content}}},
+		Workspace:   models.WorkspaceSpec{Reuse: true},
+	}
+}
+
+func TestFailedAttemptWorkspaceIsReusedByRetry(t *testing.T) {
+	dm, workspaces := newTestDataManager(t)
+	ctx := context.Background()
+	req := retryRequest("a,b\n1,2\n")
+
+	path, id, reused, err := dm.PrepareWorkspace(ctx, "agent-1", req)
+	if err != nil || reused {
+		t.Fatalf("expected a fresh workspace, got reused=%v, %v", reused, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "output", "partial.txt"), []byte("half done"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.ReleaseWorkspace(req, id, true); err != nil {
+		t.Fatal(err)
+	}
+
+	retryPath, retryID, reused, err := dm.PrepareWorkspace(ctx, "agent-2", retryRequest("a,b\n1,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reused || retryPath != path || retryID != id {
+		t.Fatalf("got %s (reused=%v), want the kept workspace %s", retryPath, reused, path)
+	}
+	if workspaces.created != 1 {
+		t.Errorf("created %d workspaces, want the inputs prepared once", workspaces.created)
+	}
+	if _, err := os.Stat(filepath.Join(path, "output", "partial.txt")); !os.IsNotExist(err) {
+		t.Error("expected the previous attempt's output to be removed")
+	}
+	if content, err := os.ReadFile(filepath.Join(path, "input", "data.csv")); err != nil || string(content) != "a,b\n1,2\n" {
+		t.Errorf("expected the inputs to stay in place, got %q, %v", content, err)
+	}
+}
+
+func TestWorkspaceIsRecreatedWhenReuseDoesNotApply(t *testing.T) {
+	cases := map[string]func(req *models.ExecutionRequest){
+		"inputs changed": func(req *models.ExecutionRequest) { req.Input.Files[0].Content = "a,b\n3,4\n" },
+		"clean":          func(req *models.ExecutionRequest) { req.Workspace.Clean = true },
+		"another task":   func(req *models.ExecutionRequest) { req.TaskID = "report" },
+	}
+	for name, change := range cases {
+		t.Run(name, func(t *testing.T) {
+			dm, workspaces := newTestDataManager(t)
+			ctx := context.Background()
+
+			first := retryRequest("a,b\n1,2\n")
+			_, id, _, err := dm.PrepareWorkspace(ctx, "agent-1", first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dm.ReleaseWorkspace(first, id, true); err != nil {
+				t.Fatal(err)
+			}
+
+			retry := retryRequest("a,b\n1,2\n")
+			change(retry)
+			path, _, reused, err := dm.PrepareWorkspace(ctx, "agent-2", retry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reused || workspaces.created != 2 {
+				t.Errorf("got reused=%v after %d workspaces, want a new workspace", reused, workspaces.created)
+			}
+			content, _ := os.ReadFile(filepath.Join(path, "input", "data.csv"))
+			if string(content) != retry.Input.Files[0].Content {
+				t.Errorf("got input %q, want the retry's own", content)
+			}
+		})
+	}
+}
+
+func TestWorkspaceIsRemovedWhenNotKept(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	ctx := context.Background()
+	req := retryRequest("a,b\n1,2\n")
+
+	// A successful or final attempt releases without keeping
+	path, id, _, err := dm.PrepareWorkspace(ctx, "agent-1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.ReleaseWorkspace(req, id, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the workspace to be removed")
+	}
+	if _, _, reused, err := dm.PrepareWorkspace(ctx, "agent-2", req); err != nil || reused {
+		t.Errorf("expected no workspace left to reuse, got reused=%v, %v", reused, err)
+	}
+}
+
+func TestKeptWorkspaceIsReapedAfterTTL(t *testing.T) {
+	dm, _ := newTestDataManager(t)
+	req := retryRequest("a,b\n1,2\n")
+
+	path, id, _, err := dm.PrepareWorkspace(context.Background(), "agent-1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.ReleaseWorkspace(req, id, true); err != nil {
+		t.Fatal(err)
+	}
+
+	dm.reapWorkspaces(time.Now().Add(reusableWorkspaceTTL / 2))
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the workspace to be kept within its TTL: %v", err)
+	}
+	dm.reapWorkspaces(time.Now().Add(reusableWorkspaceTTL + time.Second))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected a workspace never retried to be removed after its TTL")
+	}
+}
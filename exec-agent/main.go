@@ -6,11 +6,13 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"exec-agent/capabilities"
 	"exec-agent/clients"
 	"exec-agent/config"
 	"exec-agent/handlers"
+	"exec-agent/tracing"
 
 	"github.com/sirupsen/logrus"
 )
@@ -33,6 +35,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Setup tracing; spans are only exported when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(ctx, "exec-agent", cfg.Tracing.OTLPEndpoint)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to setup tracing")
+	}
+
 	var wg sync.WaitGroup
 
 	// Initialize Docker client
@@ -63,6 +71,12 @@ func main() {
 	if err != nil {
 		logrus.WithError(err).Warn("Failed to initialize Minio client - blob storage features will be disabled")
 		minioClient = nil
+	} else if err := minioClient.SetPathScheme(cfg.Minio.PathScheme); err != nil {
+		logrus.WithError(err).Fatal("Invalid Minio path scheme")
+	} else if cfg.Minio.PublicEndpoint != "" {
+		if err := minioClient.SetPublicEndpoint(cfg.Minio.PublicEndpoint, cfg.Minio.Region); err != nil {
+			logrus.WithError(err).Fatal("Invalid Minio public endpoint")
+		}
 	}
 
 	// Initialize Redis client
@@ -81,7 +95,26 @@ func main() {
 		cfg.ServiceProxy.Port,
 		cfg.ServiceProxy.DataAbstractorURL,
 		cfg.ServiceProxy.AIAbstractorURL,
-		redisClient.PublishToChannel,
+		func(channel string, data interface{}) error {
+			return redisClient.PublishToChannel(config.Namespaced(cfg.Redis.Namespace, channel), data)
+		},
+	)
+
+	// Execution lifecycles are recorded in Redis for lookup by the proxy and status requests
+	statusStore := clients.NewExecutionStatusStore(redisClient.GetClient(), config.Namespaced(cfg.Redis.Namespace, "exec-status"), cfg.Redis.StatusTTL)
+	serviceProxy.SetStatusStore(statusStore)
+
+	serviceProxy.SetRouteOptions(
+		handlers.ProxyRouteOptions{
+			Headers:      cfg.ServiceProxy.DataHeaders,
+			StripHeaders: cfg.ServiceProxy.StripHeaders,
+			Timeout:      cfg.ServiceProxy.DataTimeout,
+		},
+		handlers.ProxyRouteOptions{
+			Headers:      cfg.ServiceProxy.AIHeaders,
+			StripHeaders: cfg.ServiceProxy.StripHeaders,
+			Timeout:      cfg.ServiceProxy.AITimeout,
+		},
 	)
 
 	// Start service proxy server
@@ -93,17 +126,84 @@ func main() {
 		}
 	}()
 
+	// Start status server exposing health and redacted configuration
+	statusServer := handlers.NewStatusServer("exec-agent", cfg.Redacted())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := statusServer.Start(ctx, cfg.App.Port); err != nil {
+			logrus.WithError(err).Error("Status server failed")
+		}
+	}()
+
 	// Initialize execution handler
 	executionHandler := handlers.NewExecutionHandler(dockerClient, minioClient, serviceProxy)
+	memoryLimit := func(name, value string) int64 {
+		if value == "0" {
+			return 0
+		}
+		memory, err := handlers.ParseMemoryLimit(value)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Invalid %s", name)
+		}
+		return memory
+	}
+	defaultMemory := memoryLimit("CONTAINER_DEFAULT_MEMORY", cfg.Docker.DefaultMemory)
+	maxMemory := memoryLimit("CONTAINER_MAX_MEMORY", cfg.Docker.MaxMemory)
+	executionHandler.SetContainerPolicy(handlers.ContainerPolicy{
+		AllowPrivilegedPorts: cfg.Docker.AllowPrivilegedPorts,
+		AllowedWorkingDirs:   cfg.Docker.AllowedWorkingDirs,
+		DefaultResources: handlers.ContainerResources{
+			CPUs:   cfg.Docker.DefaultCPUs,
+			Memory: defaultMemory,
+			Pids:   int64(cfg.Docker.DefaultPids),
+		},
+		MaxResources: handlers.ContainerResources{
+			CPUs:   cfg.Docker.MaxCPUs,
+			Memory: maxMemory,
+			Pids:   int64(cfg.Docker.MaxPids),
+		},
+	})
+	executionHandler.SetContainerServiceURLs(handlers.ContainerServiceURLs{
+		ProxyURL: cfg.ServiceProxy.ContainerURL,
+		DataURL:  cfg.ServiceProxy.ContainerDataURL,
+		AIURL:    cfg.ServiceProxy.ContainerAIURL,
+	})
+	executionHandler.SetOutputInlineLimits(handlers.InlineLimits{
+		MaxFiles:    cfg.Output.MaxInlineFiles,
+		MaxBytes:    cfg.Output.MaxInlineBytes,
+		MaxFileSize: cfg.Output.MaxInlineFileSize,
+	})
+	executionHandler.SetOutputURLExpiry(cfg.Minio.PresignExpiry)
+	executionHandler.SetWorkspaceQuota(handlers.WorkspaceQuota{
+		MaxBytes: cfg.Input.MaxWorkspaceBytes,
+		MaxFiles: cfg.Input.MaxFiles,
+	})
+	executionHandler.SetStatusStore(statusStore)
 
 	// Initialize image scanner if enabled
 	var imageScanner *capabilities.ImageScanner
 	var enhancedCapabilities *capabilities.EnhancedExecCapabilities
 	if cfg.ImageScan.Enabled {
+		dockerAPI, err := clients.NewDockerAPIClient(cfg.Docker.Host, cfg.Docker.APIVersion)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to initialize Docker API client")
+		}
+		defer dockerAPI.Close()
+
 		imageScanner = capabilities.NewImageScanner(
+			dockerAPI,
 			cfg.ImageScan.KnownImages,
 			cfg.ImageScan.ScanInterval,
 		)
+		registryAuth := make(map[string]capabilities.RegistryCredentials, len(cfg.ImageScan.RegistryAuth))
+		for host, credentials := range cfg.ImageScan.RegistryAuth {
+			registryAuth[host] = capabilities.RegistryCredentials{
+				Username: credentials.Username,
+				Password: credentials.Password,
+			}
+		}
+		imageScanner.SetRegistryAuth(registryAuth)
 		
 		// Start periodic scanning
 		imageScanner.StartPeriodicScan(ctx)
@@ -131,12 +231,19 @@ func main() {
 				redisClient.GetClient(),
 				cfg.Capabilities.RefreshInterval,
 				cfg.ImageScan.ScanInterval,
+				cfg.Redis.Namespace,
 			)
+			dynamicManager.SetSigningSecret(cfg.Capabilities.SigningSecret)
+			dynamicManager.SetDebounceWindow(cfg.Capabilities.AnnounceDebounce)
+			dynamicManager.SetServiceVersion(cfg.Capabilities.ServiceVersion)
+			if cfg.Capabilities.InstanceID != "" {
+				dynamicManager.SetInstance(cfg.Capabilities.InstanceID, cfg.Redis.RequestCh, cfg.Capabilities.InstanceWeight)
+			}
 			capabilityManager = dynamicManager
-			
+
 			logrus.WithFields(logrus.Fields{
 				"image_refresh_interval": cfg.ImageScan.ScanInterval,
-				"known_images":          len(cfg.ImageScan.KnownImages),
+				"known_images":           len(cfg.ImageScan.KnownImages),
 			}).Info("Dynamic capability manager configured with image scanning")
 		} else {
 			// Use basic capability manager
@@ -145,7 +252,14 @@ func main() {
 				capabilities.GetExecAgentCapabilities(),
 				redisClient.GetClient(),
 				cfg.Capabilities.RefreshInterval,
+				cfg.Redis.Namespace,
 			)
+			basicManager.SetSigningSecret(cfg.Capabilities.SigningSecret)
+			basicManager.SetDebounceWindow(cfg.Capabilities.AnnounceDebounce)
+			basicManager.SetServiceVersion(cfg.Capabilities.ServiceVersion)
+			if cfg.Capabilities.InstanceID != "" {
+				basicManager.SetInstance(cfg.Capabilities.InstanceID, cfg.Redis.RequestCh, cfg.Capabilities.InstanceWeight)
+			}
 			capabilityManager = basicManager
 		}
 
@@ -198,5 +312,12 @@ func main() {
 	cancel()
 	wg.Wait()
 
+	// Flush spans still buffered for export
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logrus.WithError(err).Warn("Failed to flush traces")
+	}
+
 	logrus.Info("Exec Agent service stopped")
 }
\ No newline at end of file
@@ -1,34 +1,58 @@
 package models
 
+// OperationStatus looks up a recorded execution status instead of running a container
+const OperationStatus = "status"
+
 type ExecutionRequest struct {
-	CorrelationID   string            `json:"correlation_id"`
-	Container       ContainerSpec     `json:"container"`
-	Input           InputSpec         `json:"input"`
-	Output          OutputSpec        `json:"output"`
-	Environment     map[string]string `json:"environment,omitempty"`
-	Timeout         int               `json:"timeout,omitempty"` // seconds
-	ServiceAccess   []string          `json:"service_access,omitempty"` // ["data", "ai"]
+	CorrelationID string            `json:"correlation_id"`
+	Operation     string            `json:"operation,omitempty"` // empty or "execute" runs the container; "status" looks up ExecID or ExecutionID and TaskID
+	ExecID        string            `json:"exec_id,omitempty"`   // agent execution to look up with the status operation
+	Container     ContainerSpec     `json:"container"`
+	Input         InputSpec         `json:"input"`
+	Output        OutputSpec        `json:"output"`
+	Environment   map[string]string `json:"environment,omitempty"`
+	Timeout       int               `json:"timeout,omitempty"`        // seconds
+	ServiceAccess []string          `json:"service_access,omitempty"` // ["data", "ai"]
+	ExecutionID   string            `json:"execution_id,omitempty"`   // workflow execution the task belongs to
+	TaskID        string            `json:"task_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"` // labels of the workflow execution, filling Minio path placeholders
+	Workspace     WorkspaceSpec     `json:"workspace,omitempty"`
+	TraceContext  map[string]string `json:"trace_context,omitempty"` // W3C trace context of the caller's span
+}
+
+// WorkspaceSpec controls reuse of a prepared workspace across retries of the same task,
+// identified by ExecutionID and TaskID
+type WorkspaceSpec struct {
+	Reuse        bool `json:"reuse,omitempty"`         // keep the workspace for the next attempt instead of preparing inputs again
+	Clean        bool `json:"clean,omitempty"`         // discard a kept workspace and prepare inputs from scratch
+	FinalAttempt bool `json:"final_attempt,omitempty"` // no retry follows, so the workspace is removed afterwards
 }
 
 type ContainerSpec struct {
-	Image      string   `json:"image"`
-	Command    []string `json:"command,omitempty"`
-	WorkingDir string   `json:"working_dir,omitempty"`
-	Ports      map[string]string `json:"ports,omitempty"` // container_port:host_port
+	Image       string            `json:"image"`
+	Command     []string          `json:"command,omitempty"`
+	WorkingDir  string            `json:"working_dir,omitempty"`
+	Ports       map[string]string `json:"ports,omitempty"`        // container_port:host_port
+	CPULimit    float64           `json:"cpu_limit,omitempty"`    // CPU cores, e.g. 0.5
+	MemoryLimit string            `json:"memory_limit,omitempty"` // bytes with an optional k, m or g suffix, e.g. "512m"
+	PidsLimit   int64             `json:"pids_limit,omitempty"`   // most processes the container may run
 }
 
 type InputSpec struct {
-	GraphData    *GraphData     `json:"graph_data,omitempty"`
-	MinioObjects []MinioObject  `json:"minio_objects,omitempty"`
-	Files        []FileData     `json:"files,omitempty"`
+	GraphData    *GraphData             `json:"graph_data,omitempty"`
+	MinioObjects []MinioObject          `json:"minio_objects,omitempty"`
+	Files        []FileData             `json:"files,omitempty"`
 	ConfigData   map[string]interface{} `json:"config_data,omitempty"`
+	Stdin        string                 `json:"stdin,omitempty"`      // piped to the container's standard input
+	StdinFile    string                 `json:"stdin_file,omitempty"` // input file, relative to the input directory, piped to standard input
 }
 
 type OutputSpec struct {
-	ExpectedFiles   []string `json:"expected_files,omitempty"`
-	MinioUpload     bool     `json:"minio_upload,omitempty"`
-	GraphUpdate     bool     `json:"graph_update,omitempty"`
-	ReturnLogs      bool     `json:"return_logs,omitempty"`
+	ExpectedFiles []string          `json:"expected_files,omitempty"`
+	MinioUpload   bool              `json:"minio_upload,omitempty"`
+	GraphUpdate   bool              `json:"graph_update,omitempty"`
+	ReturnLogs    bool              `json:"return_logs,omitempty"`
+	PathLabels    map[string]string `json:"path_labels,omitempty"` // values for custom placeholders in the Minio path scheme
 }
 
 type GraphData struct {
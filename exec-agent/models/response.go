@@ -3,36 +3,42 @@ package models
 import "time"
 
 type ExecutionResponse struct {
-	CorrelationID string            `json:"correlation_id"`
-	Success       bool              `json:"success"`
-	Result        *ExecutionResult  `json:"result,omitempty"`
-	Error         string            `json:"error,omitempty"`
-	Timestamp     time.Time         `json:"timestamp"`
-	Duration      time.Duration     `json:"duration"`
-	ExecutionID   string            `json:"execution_id"`
+	CorrelationID string           `json:"correlation_id"`
+	Success       bool             `json:"success"`
+	Result        *ExecutionResult `json:"result,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	Timestamp     time.Time        `json:"timestamp"`
+	Duration      time.Duration    `json:"duration"`
+	ExecutionID   string           `json:"execution_id"`
+	Status        *ExecutionStatus `json:"status,omitempty"` // answer to a status operation
 }
 
 type ExecutionResult struct {
-	ExitCode      int                `json:"exit_code"`
-	Output        string             `json:"output,omitempty"`
-	Logs          string             `json:"logs,omitempty"`
-	GraphUpdate   *GraphData         `json:"graph_update,omitempty"`
-	OutputFiles   []OutputFile       `json:"output_files,omitempty"`
-	MinioObjects  []MinioOutputObject `json:"minio_objects,omitempty"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	ExitCode     int                    `json:"exit_code"`
+	Output       string                 `json:"output,omitempty"`
+	Stdout       string                 `json:"stdout,omitempty"`
+	Stderr       string                 `json:"stderr,omitempty"`
+	Logs         string                 `json:"logs,omitempty"`
+	GraphUpdate  *GraphData             `json:"graph_update,omitempty"`
+	OutputFiles  []OutputFile           `json:"output_files,omitempty"`
+	MinioObjects []MinioOutputObject    `json:"minio_objects,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type OutputFile struct {
-	Name    string `json:"name"`
-	Path    string `json:"path"`
-	Content string `json:"content,omitempty"`
-	Size    int64  `json:"size"`
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	Content       string `json:"content,omitempty"`
+	Size          int64  `json:"size"`
+	InlineSkipped string `json:"inline_skipped,omitempty"` // why content was not inlined: file_size, file_count or byte_budget
+	ObjectName    string `json:"object_name,omitempty"`    // Minio object holding a file that was not inlined
+	URL           string `json:"url,omitempty"`            // presigned download URL of ObjectName
 }
 
 type MinioOutputObject struct {
 	ObjectName string `json:"object_name"`
 	Size       int64  `json:"size"`
-	URL        string `json:"url,omitempty"` // presigned URL if needed
+	URL        string `json:"url,omitempty"` // presigned download URL
 }
 
 func NewSuccessResponse(correlationID, executionID string, result *ExecutionResult, duration time.Duration) *ExecutionResponse {
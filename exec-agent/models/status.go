@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Execution lifecycle states recorded by the status store
+const (
+	ExecutionQueued    = "queued"
+	ExecutionRunning   = "running"
+	ExecutionCompleted = "completed"
+	ExecutionFailed    = "failed"
+)
+
+// ExecutionStatus is the recorded lifecycle of one container execution. UpdatedAt is
+// refreshed while the container runs, so a running record that stops being updated
+// belongs to an agent that died.
+type ExecutionStatus struct {
+	ExecID        string     `json:"exec_id"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	ExecutionID   string     `json:"execution_id,omitempty"` // workflow execution the task belongs to
+	TaskID        string     `json:"task_id,omitempty"`
+	Image         string     `json:"image,omitempty"`
+	Status        string     `json:"status"`
+	QueuedAt      time.Time  `json:"queued_at"`
+	StartTime     *time.Time `json:"start_time,omitempty"`
+	EndTime       *time.Time `json:"end_time,omitempty"`
+	ExitCode      *int       `json:"exit_code,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
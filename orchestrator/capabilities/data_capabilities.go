@@ -11,7 +11,11 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 					"operation":      "traverse",
 					"correlation_id": "unique-request-id",
 					"query": map[string]interface{}{
-						"cypher": "MATCH (n:Person)-[r:KNOWS]->(m:Person) RETURN n, r, m LIMIT 10",
+						"cypher": "MATCH (n:Person {name: $name})-[r:KNOWS]->(m:Person) RETURN n, r, m LIMIT $limit",
+						"parameters": map[string]interface{}{
+							"name":  "John",
+							"limit": 10,
+						},
 					},
 					"enrich": []string{"metadata"},
 					"limit":  100,
@@ -66,7 +70,7 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 						"nodes": []map[string]interface{}{
 							{
 								"id":         "node1",
-								"labels":     []string{"Document"],
+								"labels":     []string{"Document"},
 								"properties": map[string]interface{}{"title": "Document Title", "content": "..."},
 								"metadata":   map[string]interface{}{"source": "system1"},
 								"score":      0.95,
@@ -112,6 +116,104 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 				RetrySafe:         true,
 				EstimatedDuration: "1-3s",
 			},
+			{
+				Name:        "batch",
+				Description: "Run several operations in order in one round trip; an enrich step without node_ids enriches the previous step's nodes",
+				InputExample: map[string]interface{}{
+					"operation":      "batch",
+					"correlation_id": "unique-request-id",
+					"steps": []map[string]interface{}{
+						{
+							"operation": "traverse",
+							"query": map[string]interface{}{
+								"cypher": "MATCH (n:Person)-[r:KNOWS]->(m:Person) RETURN n, r, m LIMIT 10",
+							},
+						},
+						{
+							"operation": "enrich",
+						},
+					},
+					"continue_on_error": false,
+				},
+				OutputExample: map[string]interface{}{
+					"correlation_id": "unique-request-id",
+					"success":        true,
+					"operation":      "batch",
+					"timestamp":      "2025-01-20T10:30:00Z",
+					"data": map[string]interface{}{
+						"nodes":         []map[string]interface{}{},
+						"relationships": []map[string]interface{}{},
+					},
+					"steps": []map[string]interface{}{
+						{"success": true, "operation": "traverse"},
+						{"success": true, "operation": "enrich"},
+					},
+				},
+				RetrySafe:         true,
+				EstimatedDuration: "2-8s",
+			},
+			{
+				Name:        "store",
+				Description: "Upsert documents into MongoDB keyed by node ID, merging fields into existing documents or replacing them, so results are available to later enrichment",
+				InputExample: map[string]interface{}{
+					"operation":      "store",
+					"correlation_id": "unique-request-id",
+					"documents": map[string]interface{}{
+						"node1": map[string]interface{}{"summary": "Key contributor", "confidence": 0.8},
+					},
+					"collection": "enrichment",
+					"replace":    false,
+				},
+				OutputExample: map[string]interface{}{
+					"correlation_id": "unique-request-id",
+					"success":        true,
+					"operation":      "store",
+					"timestamp":      "2025-01-20T10:30:00Z",
+					"data": map[string]interface{}{
+						"nodes":         []map[string]interface{}{},
+						"relationships": []map[string]interface{}{},
+						"metadata": map[string]interface{}{
+							"collection": "enrichment",
+							"matched":    0,
+							"modified":   0,
+							"upserted":   1,
+						},
+					},
+				},
+				RetrySafe:         true,
+				EstimatedDuration: "1-3s",
+			},
+			{
+				Name:        "index",
+				Description: "Upsert vectors with payloads into the Qdrant collection so they can be found by similarity search; vectors must match the collection's dimensionality",
+				InputExample: map[string]interface{}{
+					"operation":      "index",
+					"correlation_id": "unique-request-id",
+					"points": []map[string]interface{}{
+						{
+							"id":      42,
+							"vector":  []float32{0.1, 0.2, 0.3},
+							"payload": map[string]interface{}{"node_id": "node1"},
+						},
+					},
+				},
+				OutputExample: map[string]interface{}{
+					"correlation_id": "unique-request-id",
+					"success":        true,
+					"operation":      "index",
+					"timestamp":      "2025-01-20T10:30:00Z",
+					"data": map[string]interface{}{
+						"nodes":         []map[string]interface{}{},
+						"relationships": []map[string]interface{}{},
+						"metadata": map[string]interface{}{
+							"collection": "embeddings",
+							"indexed":    1,
+						},
+					},
+				},
+				RetrySafe:         true,
+				EstimatedDuration: "1-5s",
+			},
 		},
 		MessagePatterns: MessagePatterns{
 			RequestChannel:   "data-requests",
@@ -119,4 +221,4 @@ func GetDataAbstractorCapabilities() *ServiceCapabilities {
 			CorrelationField: "correlation_id",
 		},
 	}
-}
\ No newline at end of file
+}
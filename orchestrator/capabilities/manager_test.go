@@ -0,0 +1,63 @@
+package capabilities
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestDebouncedUpdatesCoalesceIntoOneAnnouncement(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	subscription := client.Subscribe(context.Background(), AnnouncementChannel)
+	defer subscription.Close()
+	if _, err := subscription.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	announcements := subscription.Channel()
+
+	manager := NewCapabilityManager("data-abstractor", &ServiceCapabilities{}, client, time.Hour, "")
+	manager.SetDebounceWindow(50 * time.Millisecond)
+	defer manager.Stop()
+
+	// Each update's context is gone before the window closes; the latest state still goes out
+	for _, name := range []string{"query", "traverse", "search"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := manager.UpdateCapabilities(ctx, &ServiceCapabilities{Operations: []Operation{{Name: name}}}); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+	}
+
+	select {
+	case msg := <-announcements:
+		var announcement CapabilityAnnouncement
+		if err := json.Unmarshal([]byte(msg.Payload), &announcement); err != nil {
+			t.Fatal(err)
+		}
+		if ops := announcement.Capabilities.Operations; len(ops) != 1 || ops[0].Name != "search" {
+			t.Errorf("got operations %+v, want only the latest update", ops)
+		}
+		if announcement.Trigger != string(TriggerConfigChange) {
+			t.Errorf("got trigger %q, want %q", announcement.Trigger, TriggerConfigChange)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a debounced announcement")
+	}
+
+	select {
+	case msg := <-announcements:
+		t.Errorf("got a second announcement %s, want the burst coalesced into one", msg.Payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if manager.GetLastHash() == "" {
+		t.Error("expected the announced hash to be recorded")
+	}
+}
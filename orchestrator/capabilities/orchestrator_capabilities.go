@@ -8,8 +8,8 @@ func GetOrchestratorCapabilities() *ServiceCapabilities {
 				Name:        "execute_workflow",
 				Description: "Execute complex workflows with DAG-based task dependencies, AI integration, and state persistence",
 				InputExample: map[string]interface{}{
-					"correlation_id":     "workflow-001",
-					"workflow_template":  "data-analysis-basic",
+					"correlation_id":    "workflow-001",
+					"workflow_template": "data-analysis-basic",
 					"variables": map[string]interface{}{
 						"query_limit":      100,
 						"analysis_prompt":  "Identify key trends and patterns in the data",
@@ -100,7 +100,7 @@ func GetOrchestratorCapabilities() *ServiceCapabilities {
 					"workflow_id":  "data-analysis-basic",
 					"status":       "running",
 					"start_time":   "2025-01-20T10:25:00Z",
-					"end_time":     null,
+					"end_time":     nil,
 					"progress": map[string]interface{}{
 						"total_tasks":     5,
 						"completed_tasks": 3,
@@ -193,4 +193,4 @@ func GetOrchestratorCapabilities() *ServiceCapabilities {
 			CorrelationField: "correlation_id",
 		},
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,96 @@
+package clients
+
+import (
+	"context"
+	"orchestrator/capabilities"
+	"testing"
+	"time"
+)
+
+func TestVerifyAnnouncement(t *testing.T) {
+	payload := []byte(`{"component":"data-abstractor","version":"abc","capabilities":{}}`)
+	signature, err := capabilities.SignAnnouncement("shared", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := NewServiceRegistry(nil, 0, "")
+	if err := open.verifyAnnouncement(payload, ""); err != nil {
+		t.Errorf("without a secret: got %v, want unsigned announcements accepted", err)
+	}
+
+	registry := NewServiceRegistry(nil, 0, "")
+	registry.SetVerificationSecret("shared")
+
+	forged, err := capabilities.SignAnnouncement("other", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(`{"component":"data-abstractor","version":"def","capabilities":{}}`)
+
+	for _, tc := range []struct {
+		name      string
+		payload   []byte
+		signature string
+		wantErr   bool
+	}{
+		{"signed", payload, signature, false},
+		{"unsigned", payload, "", true},
+		{"wrong secret", payload, forged, true},
+		{"tampered payload", tampered, signature, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := registry.verifyAnnouncement(tc.payload, tc.signature)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestServiceRegistryDropsUnverifiedAnnouncements(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := NewServiceRegistry(client, time.Minute, "")
+	registry.SetVerificationSecret("shared")
+	if err := registry.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Stop()
+	// Give the registry's subscription time to be established
+	time.Sleep(100 * time.Millisecond)
+
+	announce := func(component, secret string) {
+		t.Helper()
+		manager := capabilities.NewCapabilityManager(component, &capabilities.ServiceCapabilities{
+			Operations: []capabilities.Operation{{Name: "query"}},
+		}, client, time.Hour, "")
+		if secret != "" {
+			manager.SetSigningSecret(secret)
+		}
+		if err := manager.ForceAnnouncement(ctx, capabilities.TriggerStartup); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	announce("unsigned-service", "")
+	announce("forged-service", "other")
+	announce("data-abstractor", "shared")
+
+	// Announcements are processed in order, so once the signed one lands the others have
+	// been seen too
+	deadline := time.Now().Add(2 * time.Second)
+	for !registry.IsServiceAvailable("data-abstractor") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the signed announcement to be accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, component := range []string{"unsigned-service", "forged-service"} {
+		if registry.IsServiceAvailable(component) {
+			t.Errorf("expected the announcement from %s to be dropped", component)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"orchestrator/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FileArtifactStore persists task outputs to a local directory as they complete
+type FileArtifactStore struct {
+	rootDir string
+	mutex   sync.Mutex
+	logger  *logrus.Logger
+}
+
+// ArtifactManifest lists the task outputs written for an execution so far
+type ArtifactManifest struct {
+	ExecutionID string          `json:"execution_id"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Tasks       []ArtifactEntry `json:"tasks"`
+}
+
+// ArtifactEntry describes a single persisted task output
+type ArtifactEntry struct {
+	TaskID      string    `json:"task_id"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// NewFileArtifactStore creates an artifact store rooted at the given directory
+func NewFileArtifactStore(rootDir string) *FileArtifactStore {
+	return &FileArtifactStore{
+		rootDir: rootDir,
+		logger:  logrus.New(),
+	}
+}
+
+// SaveTaskOutput writes a task's output under executions/{id}/tasks/{taskId}/ and
+// records it in the execution manifest
+func (s *FileArtifactStore) SaveTaskOutput(ctx context.Context, executionID, taskID string, output map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := validateArtifactID(executionID); err != nil {
+		return err
+	}
+	if err := validateArtifactID(taskID); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal task output: %w", err)
+	}
+
+	relPath := filepath.Join("executions", executionID, "tasks", taskID, "output.json")
+	if err := s.writeAtomic(filepath.Join(s.rootDir, relPath), data); err != nil {
+		return fmt.Errorf("failed to write task output: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	manifest, err := s.loadManifest(executionID)
+	if err != nil {
+		return err
+	}
+
+	entry := ArtifactEntry{
+		TaskID:      taskID,
+		Path:        relPath,
+		Size:        int64(len(data)),
+		CompletedAt: time.Now(),
+	}
+
+	replaced := false
+	for i, existing := range manifest.Tasks {
+		if existing.TaskID == taskID {
+			manifest.Tasks[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		manifest.Tasks = append(manifest.Tasks, entry)
+	}
+	manifest.UpdatedAt = entry.CompletedAt
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := s.writeAtomic(s.manifestPath(executionID), manifestData); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"execution_id": executionID,
+		"task_id":      taskID,
+		"path":         relPath,
+	}).Debug("Persisted task output artifact")
+
+	return nil
+}
+
+// AppendServiceExchange adds a service request/response to an execution's recording.
+// Exchanges are appended as JSON lines, so recording never rewrites what is already there.
+func (s *FileArtifactStore) AppendServiceExchange(executionID string, exchange *models.ServiceExchange) error {
+	if err := validateArtifactID(executionID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(exchange)
+	if err != nil {
+		return fmt.Errorf("failed to marshal service exchange: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := s.recordingPath(executionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	return nil
+}
+
+// LoadServiceExchanges returns the recorded service exchanges of an execution in order
+func (s *FileArtifactStore) LoadServiceExchanges(executionID string) ([]*models.ServiceExchange, error) {
+	if err := validateArtifactID(executionID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	exchanges, err := s.readRecording(executionID)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recording found for execution %s", executionID)
+	}
+	return exchanges, err
+}
+
+// readRecording reads an execution's recording; a missing file is returned as is. A
+// last line without a newline is an append cut short by a crash and is skipped.
+func (s *FileArtifactStore) readRecording(executionID string) ([]*models.ServiceExchange, error) {
+	data, err := os.ReadFile(s.recordingPath(executionID))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	// The element after the last newline is empty, or the interrupted append
+	lines = lines[:len(lines)-1]
+
+	exchanges := make([]*models.ServiceExchange, 0, len(lines))
+	for i, line := range lines {
+		var exchange models.ServiceExchange
+		if err := json.Unmarshal([]byte(line), &exchange); err != nil {
+			return nil, fmt.Errorf("failed to parse recording line %d: %w", i+1, err)
+		}
+		exchanges = append(exchanges, &exchange)
+	}
+
+	return exchanges, nil
+}
+
+// recordingPath returns the service exchange recording location for an execution
+func (s *FileArtifactStore) recordingPath(executionID string) string {
+	return filepath.Join(s.rootDir, "executions", executionID, "recording.jsonl")
+}
+
+// GetManifest returns the artifact manifest for an execution
+func (s *FileArtifactStore) GetManifest(executionID string) (*ArtifactManifest, error) {
+	if err := validateArtifactID(executionID); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := os.Stat(s.manifestPath(executionID)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no artifacts found for execution %s", executionID)
+	}
+
+	return s.loadManifest(executionID)
+}
+
+// loadManifest reads the manifest from disk, returning an empty one if it does not exist yet
+func (s *FileArtifactStore) loadManifest(executionID string) (*ArtifactManifest, error) {
+	manifest := &ArtifactManifest{
+		ExecutionID: executionID,
+		Tasks:       []ArtifactEntry{},
+	}
+
+	data, err := os.ReadFile(s.manifestPath(executionID))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// manifestPath returns the manifest location for an execution
+func (s *FileArtifactStore) manifestPath(executionID string) string {
+	return filepath.Join(s.rootDir, "executions", executionID, "manifest.json")
+}
+
+// writeAtomic writes data to a temp file and renames it so watchers never see partial files
+func (s *FileArtifactStore) writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// validateArtifactID rejects identifiers that would escape the artifact directory
+func validateArtifactID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("invalid artifact identifier: %q", id)
+	}
+	return nil
+}
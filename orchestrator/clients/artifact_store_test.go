@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"orchestrator/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileArtifactStoreWritesOutputAndManifest(t *testing.T) {
+	root := t.TempDir()
+	store := NewFileArtifactStore(root)
+	ctx := context.Background()
+
+	if err := store.SaveTaskOutput(ctx, "exec-1", "fetch", map[string]interface{}{"rows": float64(3)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveTaskOutput(ctx, "exec-1", "summarize", map[string]interface{}{"text": "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	// Saving a task again replaces its entry
+	if err := store.SaveTaskOutput(ctx, "exec-1", "fetch", map[string]interface{}{"rows": float64(4)}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := store.GetManifest("exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Tasks) != 2 {
+		t.Fatalf("got %d manifest entries, want 2", len(manifest.Tasks))
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, manifest.Tasks[0].Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output map[string]interface{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatal(err)
+	}
+	if output["rows"] != float64(4) {
+		t.Errorf("got output %v, want the latest save", output)
+	}
+}
+
+func TestFileArtifactStoreRejectsUnsafeIDs(t *testing.T) {
+	store := NewFileArtifactStore(t.TempDir())
+
+	for _, id := range []string{"", ".", "..", "../escape", `a\b`} {
+		if err := store.SaveTaskOutput(context.Background(), id, "task", nil); err == nil {
+			t.Errorf("execution ID %q: expected an error", id)
+		}
+		if err := store.SaveTaskOutput(context.Background(), "exec", id, nil); err == nil {
+			t.Errorf("task ID %q: expected an error", id)
+		}
+	}
+
+	if _, err := store.GetManifest("unknown"); err == nil {
+		t.Error("expected an error for an execution without artifacts")
+	}
+}
+
+func TestFileArtifactStoreAppendsServiceExchanges(t *testing.T) {
+	store := NewFileArtifactStore(t.TempDir())
+	path := filepath.Join(store.rootDir, "executions", "exec-1", "recording.jsonl")
+
+	if err := store.AppendServiceExchange("exec-1", &models.ServiceExchange{TaskID: "fetch", Service: "data"}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AppendServiceExchange("exec-1", &models.ServiceExchange{TaskID: "summarize", Service: "ai"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Appending leaves what was recorded before untouched
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, first) || bytes.Count(data, []byte("\n")) != 2 {
+		t.Errorf("got recording %q, want two lines starting with the first exchange", data)
+	}
+
+	// A crash mid-append leaves a partial last line, which is skipped
+	if err := os.WriteFile(path, append(data, `{"task_id": "rep`...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exchanges, err := store.LoadServiceExchanges("exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 2 || exchanges[0].TaskID != "fetch" || exchanges[1].TaskID != "summarize" {
+		t.Errorf("got %+v, want the two complete exchanges in order", exchanges)
+	}
+}
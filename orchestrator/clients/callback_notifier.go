@@ -0,0 +1,228 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"orchestrator/models"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CallbackSignatureHeader carries the HMAC-SHA256 of the callback body, as sha256=<hex>
+const CallbackSignatureHeader = "X-Orchestrator-Signature"
+
+// CallbackOptions controls where callbacks may go and how they are retried
+type CallbackOptions struct {
+	MaxAttempts    int           // deliveries tried before giving up
+	InitialDelay   time.Duration // wait before the first retry, doubled for each one after
+	MaxDelay       time.Duration // longest wait between retries
+	AttemptTimeout time.Duration // bound on a single delivery
+	AllowedHosts   []string      // hosts callbacks may go to, "*.example.com" for subdomains; empty allows any
+	AllowPrivate   bool          // allow loopback, private and link-local addresses
+}
+
+// errCallbackAddressRefused is returned when a callback would connect to an address
+// outside the public internet, which is never retried
+var errCallbackAddressRefused = errors.New("callback address refused")
+
+// DefaultCallbackOptions tries five times over about fifteen seconds
+var DefaultCallbackOptions = CallbackOptions{
+	MaxAttempts:    5,
+	InitialDelay:   time.Second,
+	MaxDelay:       30 * time.Second,
+	AttemptTimeout: 10 * time.Second,
+}
+
+// HTTPCallbackNotifier POSTs workflow responses to request callback URLs, signed with a
+// shared secret so receivers can check they came from this orchestrator
+type HTTPCallbackNotifier struct {
+	secret  string
+	options CallbackOptions
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// NewHTTPCallbackNotifier creates a notifier signing with secret; an empty secret sends
+// callbacks unsigned
+func NewHTTPCallbackNotifier(secret string, options CallbackOptions) *HTTPCallbackNotifier {
+	if options.MaxAttempts < 1 {
+		options.MaxAttempts = 1
+	}
+	// Addresses are checked as they are dialled, after DNS resolution and on redirects,
+	// so a public name can't be pointed at an internal service
+	dialer := &net.Dialer{Timeout: 30 * time.Second, Control: func(network, address string, _ syscall.RawConn) error {
+		return checkCallbackAddress(address, options.AllowPrivate)
+	}}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &HTTPCallbackNotifier{
+		secret:  secret,
+		options: options,
+		client:  &http.Client{Timeout: options.AttemptTimeout, Transport: transport},
+		logger:  logrus.New(),
+	}
+}
+
+// CheckCallbackURL rejects a callback URL whose host is not on the allow-list, or is a
+// private address given literally. Names resolving to private addresses are refused
+// when dialled.
+func (n *HTTPCallbackNotifier) CheckCallbackURL(callbackURL *url.URL) error {
+	host := strings.ToLower(callbackURL.Hostname())
+	if len(n.options.AllowedHosts) > 0 && !callbackHostAllowed(host, n.options.AllowedHosts) {
+		return fmt.Errorf("host %s is not an allowed callback host", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !n.options.AllowPrivate && isPrivateAddress(ip) {
+		return fmt.Errorf("host %s is not a public address", host)
+	}
+	return nil
+}
+
+// callbackHostAllowed matches a host against the allow-list
+func callbackHostAllowed(host string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix := strings.TrimPrefix(pattern, "*"); suffix != pattern {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCallbackAddress refuses to dial an address outside the public internet
+func checkCallbackAddress(address string, allowPrivate bool) error {
+	if allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errCallbackAddressRefused, address)
+	}
+	if ip := net.ParseIP(host); ip == nil || isPrivateAddress(ip) {
+		return fmt.Errorf("%w: %s is not a public address", errCallbackAddressRefused, host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, private in practice
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateAddress reports whether an IP is loopback, private, link-local, unspecified
+// or multicast
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// NotifyCallback delivers the response, retrying network errors, 429 and 5xx responses
+// with exponential backoff. Other non-2xx responses are not retried.
+func (n *HTTPCallbackNotifier) NotifyCallback(ctx context.Context, callbackURL string, response *models.WorkflowResponse) error {
+	// Requests saved before the allow-list changed are checked again
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	if err := n.CheckCallbackURL(parsed); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow response: %w", err)
+	}
+	signature := SignCallback(n.secret, body)
+
+	delay := n.options.InitialDelay
+	var lastErr error
+	for attempt := 1; attempt <= n.options.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("callback abandoned after %d attempt(s): %w", attempt-1, lastErr)
+			case <-timer.C:
+			}
+			delay *= 2
+			if n.options.MaxDelay > 0 && delay > n.options.MaxDelay {
+				delay = n.options.MaxDelay
+			}
+		}
+
+		retry, err := n.deliver(ctx, callbackURL, body, signature)
+		if err == nil {
+			n.logger.WithFields(logrus.Fields{
+				"execution_id": response.ExecutionID,
+				"attempt":      attempt,
+			}).Debug("Delivered workflow callback")
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+
+		n.logger.WithError(err).WithFields(logrus.Fields{
+			"execution_id": response.ExecutionID,
+			"attempt":      attempt,
+		}).Debug("Workflow callback attempt failed")
+	}
+
+	return lastErr
+}
+
+// deliver sends one callback and reports whether a failure is worth retrying
+func (n *HTTPCallbackNotifier) deliver(ctx context.Context, callbackURL string, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signature != "" {
+		req.Header.Set(CallbackSignatureHeader, "sha256="+signature)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errCallbackAddressRefused), fmt.Errorf("failed to send callback: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("callback receiver returned status %d", resp.StatusCode)
+}
+
+// SignCallback computes the hex HMAC-SHA256 of a callback body, or "" without a secret
+func SignCallback(secret string, body []byte) string {
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
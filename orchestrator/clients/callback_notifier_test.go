@@ -0,0 +1,131 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"orchestrator/models"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCallbackOptions retries quickly and allows the loopback address httptest uses
+var testCallbackOptions = CallbackOptions{
+	MaxAttempts:    3,
+	InitialDelay:   time.Millisecond,
+	MaxDelay:       time.Millisecond,
+	AttemptTimeout: time.Second,
+	AllowPrivate:   true,
+}
+
+func TestNotifyCallbackSignsBody(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(CallbackSignatureHeader)
+	}))
+	defer server.Close()
+
+	notifier := NewHTTPCallbackNotifier("shared-secret", testCallbackOptions)
+	response := &models.WorkflowResponse{ExecutionID: "exec-1", Status: models.StatusCompleted, Success: true}
+	if err := notifier.NotifyCallback(context.Background(), server.URL, response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(string(body), `"execution_id":"exec-1"`) {
+		t.Errorf("callback body does not carry the response: %s", body)
+	}
+	if want := "sha256=" + SignCallback("shared-secret", body); signature != want {
+		t.Errorf("got signature %q, want %q", signature, want)
+	}
+}
+
+func TestNotifyCallbackRetriesServerErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	notifier := NewHTTPCallbackNotifier("", testCallbackOptions)
+	if err := notifier.NotifyCallback(context.Background(), server.URL, &models.WorkflowResponse{}); err != nil {
+		t.Fatalf("expected delivery on the third attempt, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestNotifyCallbackDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := NewHTTPCallbackNotifier("", testCallbackOptions)
+	if err := notifier.NotifyCallback(context.Background(), server.URL, &models.WorkflowResponse{}); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestNotifyCallbackRefusesPrivateAddresses(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+	}))
+	defer server.Close()
+
+	options := testCallbackOptions
+	options.AllowPrivate = false
+	notifier := NewHTTPCallbackNotifier("", options)
+
+	err := notifier.NotifyCallback(context.Background(), server.URL, &models.WorkflowResponse{})
+	if err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Fatalf("expected a loopback callback to be refused, got %v", err)
+	}
+	if attempts != 0 {
+		t.Errorf("refused callback reached the server %d time(s)", attempts)
+	}
+}
+
+func TestCheckCallbackURL(t *testing.T) {
+	notifier := NewHTTPCallbackNotifier("", CallbackOptions{AllowedHosts: []string{"hooks.example.com", "*.partner.io"}})
+
+	cases := map[string]bool{
+		"https://hooks.example.com/done": true,
+		"https://api.partner.io/cb":      true,
+		"https://partner.io/cb":          false,
+		"https://evil.example.com/done":  false,
+		"http://169.254.169.254/latest":  false,
+	}
+	for raw, allowed := range cases {
+		parsed, _ := url.Parse(raw)
+		err := notifier.CheckCallbackURL(parsed)
+		if allowed && err != nil {
+			t.Errorf("%s: unexpected error: %v", raw, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("%s: expected the URL to be rejected", raw)
+		}
+	}
+
+	open := NewHTTPCallbackNotifier("", CallbackOptions{})
+	for _, raw := range []string{"http://127.0.0.1:8080/", "http://10.0.0.5/", "http://[::1]/", "http://100.64.1.1/"} {
+		parsed, _ := url.Parse(raw)
+		if err := open.CheckCallbackURL(parsed); err == nil {
+			t.Errorf("%s: expected a private address to be rejected", raw)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// capabilityKeyPrefix prefixes the Redis hash holding a component's announcements
+	capabilityKeyPrefix = "orchestrator:capabilities:"
+	// defaultInstanceField stores announcements from services that do not name an instance
+	defaultInstanceField = "default"
+)
+
+// capabilityKey returns the Redis hash holding a component's announcements
+func (sr *ServiceRegistry) capabilityKey(component string) string {
+	return sr.capabilityPrefix + component
+}
+
+// persistedCapability is the stored form of an announcement. The payload is kept exactly
+// as announced so its signature can be checked again when the registry hydrates.
+type persistedCapability struct {
+	Payload     json.RawMessage `json:"payload"`
+	LastUpdated time.Time       `json:"last_updated"`
+}
+
+// persistCapability writes an announcement to Redis so a restarted orchestrator knows about
+// the service before it announces again. Each announcing instance has its own field and
+// the hash expires once the component has been silent for the stale threshold. Writes run
+// in the background one at a time; one that is older than the last write of the same
+// instance is dropped so a slow write cannot replace a newer announcement.
+func (sr *ServiceRegistry) persistCapability(capability *ServiceCapability, payload []byte) {
+	if payload == nil {
+		var err error
+		if payload, err = json.Marshal(capability); err != nil {
+			sr.logger.WithError(err).WithField("component", capability.Component).Warn("Failed to marshal capability for persistence")
+			return
+		}
+	}
+	data, err := json.Marshal(persistedCapability{Payload: payload, LastUpdated: capability.LastUpdated})
+	if err != nil {
+		sr.logger.WithError(err).WithField("component", capability.Component).Warn("Failed to marshal capability for persistence")
+		return
+	}
+
+	field := capability.Instance
+	if field == "" {
+		field = defaultInstanceField
+	}
+	key := sr.capabilityKey(capability.Component)
+	updated := capability.LastUpdated
+
+	sr.persisting.Add(1)
+	go func() {
+		defer sr.persisting.Done()
+		sr.persistMutex.Lock()
+		defer sr.persistMutex.Unlock()
+
+		written := key + "/" + field
+		if last, exists := sr.persistedAt[written]; exists && updated.Before(last) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		pipe := sr.redisClient.TxPipeline()
+		pipe.HSet(ctx, key, field, data)
+		pipe.Expire(ctx, key, sr.staleThreshold)
+		if _, err := pipe.Exec(ctx); err != nil {
+			sr.logger.WithError(err).WithField("component", capability.Component).Warn("Failed to persist capability announcement")
+			return
+		}
+		sr.persistedAt[written] = updated
+	}()
+}
+
+// hydrate loads persisted announcements into the registry. Services keep the time they
+// were last seen, so ones that have since gone quiet are still treated as stale. Entries
+// that cannot be decoded, or whose signature fails verification, are skipped.
+func (sr *ServiceRegistry) hydrate(ctx context.Context) error {
+	var keys []string
+	iter := sr.redisClient.Scan(ctx, 0, sr.capabilityPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, key := range keys {
+		fields, err := sr.redisClient.HGetAll(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+
+		component := strings.TrimPrefix(key, sr.capabilityPrefix)
+		for field, data := range fields {
+			logger := sr.logger.WithFields(logrus.Fields{
+				"component": component,
+				"instance":  field,
+			})
+
+			var stored persistedCapability
+			var capability ServiceCapability
+			if err := json.Unmarshal([]byte(data), &stored); err != nil || len(stored.Payload) == 0 {
+				logger.WithError(err).Warn("Skipping unreadable persisted capability")
+				continue
+			}
+			if err := json.Unmarshal(stored.Payload, &capability); err != nil || capability.Component != component || capability.Capabilities == nil {
+				logger.WithError(err).Warn("Skipping unreadable persisted capability")
+				continue
+			}
+			if err := sr.verifyAnnouncement(stored.Payload, capability.Signature); err != nil {
+				logger.WithError(err).Warn("Skipping unverified persisted capability")
+				continue
+			}
+
+			capability.LastUpdated = stored.LastUpdated
+			sr.restoreCapability(&capability)
+			loaded++
+		}
+	}
+
+	sr.logger.WithField("announcements", loaded).Info("Loaded persisted service capabilities")
+	return nil
+}
+
+// restoreCapability records a persisted announcement without emitting lifecycle events;
+// a newer live announcement always wins
+func (sr *ServiceRegistry) restoreCapability(capability *ServiceCapability) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if current, exists := sr.capabilities[capability.Component]; !exists || capability.LastUpdated.After(current.LastUpdated) {
+		sr.capabilities[capability.Component] = capability
+		sr.lastSeen[capability.Component] = capability.LastUpdated
+	}
+	if instance := sr.instances[capability.Component][capability.Instance]; instance == nil || capability.LastUpdated.After(instance.LastSeen) {
+		sr.updateInstance(capability)
+	}
+}
@@ -0,0 +1,144 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"orchestrator/capabilities"
+	"testing"
+	"time"
+)
+
+// signedPayload returns an announcement for component as a signing capability manager
+// publishes it
+func signedPayload(t *testing.T, secret, component, version string) []byte {
+	t.Helper()
+	announcement := map[string]interface{}{
+		"component":    component,
+		"version":      version,
+		"trigger":      "startup",
+		"capabilities": map[string]interface{}{"operations": []interface{}{map[string]interface{}{"name": "query"}}},
+	}
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if announcement["signature"], err = capabilities.SignAnnouncement(secret, data); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = json.Marshal(announcement); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// announce records payload on the registry as if it had arrived on the announcement channel
+func announce(t *testing.T, registry *ServiceRegistry, payload []byte) {
+	t.Helper()
+	var capability ServiceCapability
+	if err := json.Unmarshal(payload, &capability); err != nil {
+		t.Fatal(err)
+	}
+	registry.updateCapability(&capability, payload)
+}
+
+func TestServiceRegistryHydratesAfterRestart(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	before := NewServiceRegistry(client, time.Minute, "")
+	announce(t, before, signedPayload(t, "shared", "data-abstractor", "1.0"))
+	before.persisting.Wait()
+
+	ttl, err := client.TTL(ctx, capabilityKeyPrefix+"data-abstractor").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("got TTL %v, want the stale threshold", ttl)
+	}
+
+	after := NewServiceRegistry(client, time.Minute, "")
+	after.SetVerificationSecret("shared")
+	if err := after.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer after.Stop()
+
+	if !after.IsServiceAvailable("data-abstractor") {
+		t.Fatal("expected a restarted registry to know the persisted service")
+	}
+	if version, _ := after.GetServiceVersion("data-abstractor"); version != "1.0" {
+		t.Errorf("got version %q, want 1.0", version)
+	}
+}
+
+func TestServiceRegistryHydrateSkipsUnusableEntries(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	forged, err := json.Marshal(persistedCapability{
+		Payload:     signedPayload(t, "other", "ai-abstractor", "1.0"),
+		LastUpdated: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := json.Marshal(persistedCapability{
+		Payload:     signedPayload(t, "shared", "data-abstractor", "1.0"),
+		LastUpdated: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.HSet(ctx, capabilityKeyPrefix+"exec-agent", defaultInstanceField, "not json")
+	client.HSet(ctx, capabilityKeyPrefix+"ai-abstractor", defaultInstanceField, forged)
+	client.HSet(ctx, capabilityKeyPrefix+"data-abstractor", defaultInstanceField, valid)
+
+	registry := NewServiceRegistry(client, time.Minute, "")
+	registry.SetVerificationSecret("shared")
+	if err := registry.hydrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if registry.IsServiceAvailable("exec-agent") {
+		t.Error("expected an unreadable entry to be skipped")
+	}
+	if registry.IsServiceAvailable("ai-abstractor") {
+		t.Error("expected an entry signed with another secret to be skipped")
+	}
+	if !registry.IsServiceAvailable("data-abstractor") {
+		t.Error("expected the verified entry to be loaded")
+	}
+}
+
+func TestServiceRegistryPersistKeepsNewestAnnouncement(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	registry := NewServiceRegistry(client, time.Minute, "")
+
+	newer := &ServiceCapability{Component: "data-abstractor", Version: "2.0", Capabilities: &ServiceCapabilities{}, LastUpdated: time.Now()}
+	older := &ServiceCapability{Component: "data-abstractor", Version: "1.0", Capabilities: &ServiceCapabilities{}, LastUpdated: newer.LastUpdated.Add(-time.Second)}
+
+	// Hold the writer so both writes are pending, then let them race
+	registry.persistMutex.Lock()
+	registry.persistCapability(newer, nil)
+	registry.persistCapability(older, nil)
+	registry.persistMutex.Unlock()
+	registry.persisting.Wait()
+
+	data, err := client.HGet(context.Background(), capabilityKeyPrefix+"data-abstractor", defaultInstanceField).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored persistedCapability
+	var capability ServiceCapability
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(stored.Payload, &capability); err != nil {
+		t.Fatal(err)
+	}
+	if capability.Version != "2.0" {
+		t.Errorf("got persisted version %q, want the newer announcement", capability.Version)
+	}
+}
@@ -0,0 +1,144 @@
+package clients
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting a service whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"    // requests flow normally
+	CircuitOpen     = "open"      // requests fail fast until the cooldown ends
+	CircuitHalfOpen = "half_open" // one probe request decides whether to close again
+)
+
+// Outcomes of a request as seen by the circuit breaker
+const (
+	breakerSuccess = iota // the service answered, whether or not the operation succeeded
+	breakerFailure        // timed out or could not be published
+	breakerAborted        // cancelled by the caller; says nothing about the service
+)
+
+// circuitBreaker stops sending requests to a service after consecutive failures. After
+// the cooldown a single probe is let through; its outcome closes or reopens the circuit.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex               sync.Mutex
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	probing             bool
+	trips               uint64
+	rejected            uint64
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// allow reports whether a request may be sent now
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) < cb.cooldown {
+			cb.rejected++
+			return false
+		}
+		cb.state = CircuitHalfOpen
+		cb.probing = true
+		return true
+	case CircuitHalfOpen:
+		if cb.probing {
+			cb.rejected++
+			return false
+		}
+		cb.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an allowed request
+func (cb *circuitBreaker) record(outcome int, now time.Time) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	halfOpen := cb.state == CircuitHalfOpen
+	if halfOpen {
+		cb.probing = false
+	}
+
+	switch outcome {
+	case breakerSuccess:
+		cb.consecutiveFailures = 0
+		cb.state = CircuitClosed
+	case breakerFailure:
+		cb.consecutiveFailures++
+		// Failures of requests let through before the circuit opened don't extend the cooldown
+		if cb.state != CircuitOpen && (halfOpen || cb.consecutiveFailures >= cb.threshold) {
+			cb.trips++
+			cb.state = CircuitOpen
+			cb.openedAt = now
+		}
+	}
+}
+
+// stats reports the breaker's state and counters
+func (cb *circuitBreaker) stats() map[string]interface{} {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	stats := map[string]interface{}{
+		"state":                cb.state,
+		"consecutive_failures": cb.consecutiveFailures,
+		"trips":                cb.trips,
+		"rejected":             cb.rejected,
+	}
+	if cb.state != CircuitClosed {
+		stats["opened_at"] = cb.openedAt
+	}
+	return stats
+}
+
+// CircuitBreakerConfig makes requests to a service fail fast with ErrCircuitOpen after
+// Threshold consecutive timeouts or publish failures, until Cooldown has passed and a
+// probe request succeeds. Each service has its own breaker; a zero Threshold disables them.
+type CircuitBreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// newServiceBreakers creates a breaker for each service, or none when disabled
+func newServiceBreakers(config CircuitBreakerConfig) map[string]*circuitBreaker {
+	breakers := make(map[string]*circuitBreaker)
+	if config.Threshold <= 0 {
+		return breakers
+	}
+	for _, service := range []string{"data", "ai", "exec"} {
+		breakers[service] = newCircuitBreaker(config.Threshold, config.Cooldown)
+	}
+	return breakers
+}
+
+// breakerStats reports the state of each service's circuit breaker
+func (mc *RedisMessageCoordinator) breakerStats() map[string]interface{} {
+	stats := make(map[string]interface{}, len(mc.breakers))
+	for service, breaker := range mc.breakers {
+		stats[service] = breaker.stats()
+	}
+	return stats
+}
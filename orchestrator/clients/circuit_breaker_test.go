@@ -0,0 +1,117 @@
+package clients
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAfterThreshold(t *testing.T) {
+	breaker := newCircuitBreaker(3, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !breaker.allow(now) {
+			t.Fatalf("request %d rejected before the threshold", i)
+		}
+		breaker.record(breakerFailure, now)
+	}
+
+	// A cancelled request neither counts as a failure nor resets the count
+	breaker.allow(now)
+	breaker.record(breakerAborted, now)
+	if breaker.state != CircuitClosed {
+		t.Fatalf("state %s after an aborted request, want closed", breaker.state)
+	}
+
+	breaker.allow(now)
+	breaker.record(breakerFailure, now)
+	if breaker.state != CircuitOpen || breaker.trips != 1 {
+		t.Fatalf("state %s with %d trips, want open with 1", breaker.state, breaker.trips)
+	}
+	if breaker.allow(now.Add(time.Second)) {
+		t.Fatal("expected an open circuit to reject requests")
+	}
+	if breaker.rejected != 1 {
+		t.Fatalf("rejected %d, want 1", breaker.rejected)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	breaker := newCircuitBreaker(2, time.Minute)
+	now := time.Now()
+
+	breaker.record(breakerFailure, now)
+	breaker.record(breakerSuccess, now)
+	breaker.record(breakerFailure, now)
+	if breaker.state != CircuitClosed {
+		t.Fatalf("state %s, want closed after non-consecutive failures", breaker.state)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute)
+	opened := time.Now()
+	breaker.record(breakerFailure, opened)
+
+	afterCooldown := opened.Add(time.Minute)
+	if !breaker.allow(afterCooldown) {
+		t.Fatal("expected a probe after the cooldown")
+	}
+	if breaker.state != CircuitHalfOpen {
+		t.Fatalf("state %s, want half_open", breaker.state)
+	}
+	if breaker.allow(afterCooldown) {
+		t.Fatal("expected only one probe while half open")
+	}
+
+	// A failed probe reopens the circuit for another cooldown
+	breaker.record(breakerFailure, afterCooldown)
+	if breaker.state != CircuitOpen || breaker.trips != 2 {
+		t.Fatalf("state %s with %d trips, want open with 2", breaker.state, breaker.trips)
+	}
+	if breaker.allow(afterCooldown.Add(time.Second)) {
+		t.Fatal("expected the reopened circuit to reject requests")
+	}
+
+	// A successful probe closes it
+	later := afterCooldown.Add(time.Minute)
+	if !breaker.allow(later) {
+		t.Fatal("expected a second probe after the cooldown")
+	}
+	breaker.record(breakerSuccess, later)
+	if breaker.state != CircuitClosed || breaker.consecutiveFailures != 0 {
+		t.Fatalf("state %s with %d failures, want closed with 0", breaker.state, breaker.consecutiveFailures)
+	}
+}
+
+func TestCircuitBreakerLateFailuresKeepCooldown(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute)
+	opened := time.Now()
+	breaker.record(breakerFailure, opened)
+
+	// A request allowed before the circuit opened fails later
+	breaker.record(breakerFailure, opened.Add(30*time.Second))
+	if !breaker.openedAt.Equal(opened) {
+		t.Fatalf("openedAt moved to %v, want %v", breaker.openedAt, opened)
+	}
+	if breaker.trips != 1 {
+		t.Fatalf("trips %d, want 1", breaker.trips)
+	}
+	if !breaker.allow(opened.Add(time.Minute)) {
+		t.Fatal("expected a probe once the original cooldown ended")
+	}
+}
+
+func TestNewServiceBreakers(t *testing.T) {
+	if breakers := newServiceBreakers(CircuitBreakerConfig{}); len(breakers) != 0 {
+		t.Fatalf("expected no breakers when disabled, got %d", len(breakers))
+	}
+
+	breakers := newServiceBreakers(CircuitBreakerConfig{Threshold: 2, Cooldown: time.Second})
+	for _, service := range []string{"data", "ai", "exec"} {
+		breaker := breakers[service]
+		if breaker == nil || breaker.threshold != 2 || breaker.cooldown != time.Second {
+			t.Errorf("%s: unexpected breaker %+v", service, breaker)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons a service response is dead-lettered
+const (
+	DeadLetterNoWaiter    = "no_waiter"    // no caller is waiting, usually a reply after the request timed out
+	DeadLetterChannelFull = "channel_full" // the caller already received a response, usually a duplicate
+	DeadLetterUnparseable = "unparseable"  // the payload is not a service response
+)
+
+// deadLetterQueueSize bounds dead letters waiting to be published; beyond it they are dropped
+const deadLetterQueueSize = 256
+
+// DeadLetter is an undeliverable service response republished for inspection
+type DeadLetter struct {
+	Reason     string    `json:"reason"`
+	Channel    string    `json:"channel"` // response channel it arrived on
+	ReceivedAt time.Time `json:"received_at"`
+	Payload    string    `json:"payload"` // the response exactly as received
+}
+
+// deadLetterMessage is a dead letter queued for publishing
+type deadLetterMessage struct {
+	channel string
+	letter  DeadLetter
+}
+
+// deadLetterMetrics counts dead letters by outcome
+type deadLetterMetrics struct {
+	noWaiter    uint64
+	channelFull uint64
+	unparseable uint64
+	published   uint64
+	dropped     uint64 // queue full or publish failed
+}
+
+// deadLetter queues an undeliverable response for its service's dead-letter channel
+// without blocking the response listener
+func (mc *RedisMessageCoordinator) deadLetter(responseChannel, reason, payload string) {
+	switch reason {
+	case DeadLetterNoWaiter:
+		atomic.AddUint64(&mc.deadLetterMetrics.noWaiter, 1)
+	case DeadLetterChannelFull:
+		atomic.AddUint64(&mc.deadLetterMetrics.channelFull, 1)
+	case DeadLetterUnparseable:
+		atomic.AddUint64(&mc.deadLetterMetrics.unparseable, 1)
+	}
+
+	channel := mc.deadLetterChannels[responseChannel]
+	if channel == "" {
+		return
+	}
+
+	message := deadLetterMessage{
+		channel: channel,
+		letter: DeadLetter{
+			Reason:     reason,
+			Channel:    responseChannel,
+			ReceivedAt: time.Now(),
+			Payload:    payload,
+		},
+	}
+
+	select {
+	case mc.deadLetters <- message:
+	default:
+		atomic.AddUint64(&mc.deadLetterMetrics.dropped, 1)
+	}
+}
+
+// deadLetterPublisher publishes queued dead letters until the coordinator closes
+func (mc *RedisMessageCoordinator) deadLetterPublisher() {
+	for {
+		select {
+		case message := <-mc.deadLetters:
+			mc.publishDeadLetter(message)
+		case <-mc.stopChan:
+			return
+		}
+	}
+}
+
+func (mc *RedisMessageCoordinator) publishDeadLetter(message deadLetterMessage) {
+	data, err := json.Marshal(message.letter)
+	if err != nil {
+		atomic.AddUint64(&mc.deadLetterMetrics.dropped, 1)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := mc.client.Publish(ctx, message.channel, data).Err(); err != nil {
+		atomic.AddUint64(&mc.deadLetterMetrics.dropped, 1)
+		mc.logger.WithError(err).WithFields(logrus.Fields{
+			"channel": message.channel,
+			"reason":  message.letter.Reason,
+		}).Warn("Failed to publish dead letter")
+		return
+	}
+	atomic.AddUint64(&mc.deadLetterMetrics.published, 1)
+}
+
+// GetDeadLetterStats reports how many responses were dead-lettered and why
+func (mc *RedisMessageCoordinator) GetDeadLetterStats() map[string]interface{} {
+	return map[string]interface{}{
+		"no_waiter":    atomic.LoadUint64(&mc.deadLetterMetrics.noWaiter),
+		"channel_full": atomic.LoadUint64(&mc.deadLetterMetrics.channelFull),
+		"unparseable":  atomic.LoadUint64(&mc.deadLetterMetrics.unparseable),
+		"published":    atomic.LoadUint64(&mc.deadLetterMetrics.published),
+		"dropped":      atomic.LoadUint64(&mc.deadLetterMetrics.dropped),
+		"channels":     mc.deadLetterChannels,
+	}
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"orchestrator/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultMaxExecutionEvents caps an execution's event log; the oldest events are dropped
+// beyond it
+const DefaultMaxExecutionEvents = 10000
+
+// RedisEventLog keeps an append-only list of lifecycle events per execution
+type RedisEventLog struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	maxEvents int64
+}
+
+// NewRedisEventLog creates an event log whose lists expire ttl after their last event
+func NewRedisEventLog(client *redis.Client, keyPrefix string, ttl time.Duration) *RedisEventLog {
+	return &RedisEventLog{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+		maxEvents: DefaultMaxExecutionEvents,
+	}
+}
+
+// AppendEvent adds an event to the end of its execution's log
+func (l *RedisEventLog) AppendEvent(ctx context.Context, event *models.ExecutionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution event: %w", err)
+	}
+
+	key := l.eventsKey(event.ExecutionID)
+	pipe := l.client.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -l.maxEvents, -1)
+	if l.ttl > 0 {
+		pipe.Expire(ctx, key, l.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append execution event: %w", err)
+	}
+	return nil
+}
+
+// Events returns an execution's events, oldest first
+func (l *RedisEventLog) Events(ctx context.Context, executionID string) ([]models.ExecutionEvent, error) {
+	entries, err := l.client.LRange(ctx, l.eventsKey(executionID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load execution events: %w", err)
+	}
+
+	events := make([]models.ExecutionEvent, 0, len(entries))
+	for _, entry := range entries {
+		var event models.ExecutionEvent
+		if err := json.Unmarshal([]byte(entry), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal execution event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (l *RedisEventLog) eventsKey(executionID string) string {
+	return fmt.Sprintf("%s:events:%s", l.keyPrefix, executionID)
+}
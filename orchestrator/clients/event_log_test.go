@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"context"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedisEventLogKeepsOrderAndCap(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+	log := NewRedisEventLog(client, "test", time.Hour)
+	log.maxEvents = 3
+
+	types := []string{
+		models.EventWorkflowStarted,
+		models.EventTaskStarted,
+		models.EventTaskRetried,
+		models.EventTaskCompleted,
+		models.EventWorkflowCompleted,
+	}
+	for _, eventType := range types {
+		if err := log.AppendEvent(ctx, &models.ExecutionEvent{Type: eventType, ExecutionID: "exec-1", Timestamp: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := log.Events(ctx, "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Type)
+	}
+	if want := types[2:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want the newest %v in order", got, want)
+	}
+
+	if ttl := server.TTL("test:events:exec-1"); ttl != time.Hour {
+		t.Errorf("got TTL %v, want an hour", ttl)
+	}
+	if events, err := log.Events(ctx, "exec-2"); err != nil || len(events) != 0 {
+		t.Errorf("expected no events for another execution, got %v, %v", events, err)
+	}
+}
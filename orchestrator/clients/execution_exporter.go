@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"orchestrator/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPExecutionExporter posts finished execution records as JSON to an HTTP endpoint,
+// such as an ingestion API or a Kafka REST proxy
+type HTTPExecutionExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// NewHTTPExecutionExporter creates an exporter posting to url with the given extra headers
+func NewHTTPExecutionExporter(url string, headers map[string]string) *HTTPExecutionExporter {
+	return &HTTPExecutionExporter{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{},
+		logger:  logrus.New(),
+	}
+}
+
+// ExportExecution sends one execution record; any non-2xx response is an error
+func (e *HTTPExecutionExporter) ExportExecution(ctx context.Context, record *models.ExecutionRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution record: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export execution: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("export sink returned status %d", resp.StatusCode)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"execution_id": record.ExecutionID,
+		"status":       record.Status,
+	}).Debug("Exported execution record")
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/models"
+	"testing"
+	"time"
+)
+
+func TestHTTPExecutionExporterPostsRecord(t *testing.T) {
+	received := make(chan *models.ExecutionRecord, 1)
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var record models.ExecutionRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Errorf("decode record: %v", err)
+		}
+		received <- &record
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	exporter := NewHTTPExecutionExporter(server.URL, map[string]string{"Authorization": "Bearer token"})
+	start := time.Now().Add(-time.Second)
+	record := &models.ExecutionRecord{
+		ExecutionID: "exec-1",
+		WorkflowID:  "wf",
+		Status:      models.StatusCompleted,
+		StartTime:   start,
+		EndTime:     start.Add(time.Second),
+		DurationMs:  1000,
+		Tasks:       []models.TaskRecord{{ID: "fetch", Type: "data", Status: models.StatusCompleted}},
+	}
+	if err := exporter.ExportExecution(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	if got.ExecutionID != "exec-1" || got.Status != models.StatusCompleted || got.DurationMs != 1000 {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if len(got.Tasks) != 1 || got.Tasks[0].ID != "fetch" {
+		t.Errorf("unexpected task records %+v", got.Tasks)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("expected the configured headers to be sent, got Authorization %q", auth)
+	}
+}
+
+func TestHTTPExecutionExporterReportsSinkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter := NewHTTPExecutionExporter(server.URL, nil)
+	if err := exporter.ExportExecution(context.Background(), &models.ExecutionRecord{ExecutionID: "exec-1"}); err == nil {
+		t.Fatal("expected a non-2xx response to be an error")
+	}
+}
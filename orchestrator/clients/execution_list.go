@@ -0,0 +1,160 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orchestrator/models"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// DefaultExecutionPageSize is used when a listing does not ask for a page size
+	DefaultExecutionPageSize = 50
+	// MaxExecutionPageSize bounds a single page
+	MaxExecutionPageSize = 500
+	// executionScanBatch is how many index entries are read at a time while filtering
+	executionScanBatch = 200
+)
+
+// ErrInvalidCursor is returned for a cursor not produced by ListExecutions
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ExecutionListOptions selects a page of executions
+type ExecutionListOptions struct {
+	Status models.ExecutionStatus // empty lists every status
+	Limit  int
+	Cursor string // next_cursor of the previous page; empty starts from the newest
+}
+
+// indexPosition is a point in the execution index, newest first. Executions started in
+// the same second share a score and are ordered by ID, so both are needed to resume.
+type indexPosition struct {
+	score int64
+	id    string
+}
+
+func (p indexPosition) String() string {
+	return fmt.Sprintf("%d:%s", p.score, p.id)
+}
+
+func parseCursor(cursor string) (*indexPosition, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	scoreStr, id, found := strings.Cut(cursor, ":")
+	if !found || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	score, err := strconv.ParseInt(scoreStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &indexPosition{score: score, id: id}, nil
+}
+
+// ListExecutions pages through the execution index, newest first, optionally keeping only
+// executions with a given status. Index entries whose execution has expired are skipped.
+func (r *RedisStateManager) ListExecutions(ctx context.Context, opts ExecutionListOptions) (*models.ExecutionPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultExecutionPageSize
+	}
+	if limit > MaxExecutionPageSize {
+		limit = MaxExecutionPageSize
+	}
+
+	after, err := parseCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ExecutionPage{Executions: make([]models.ExecutionSummary, 0, limit)}
+	var positions []indexPosition
+
+	maxScore := "+inf"
+	if after != nil {
+		maxScore = strconv.FormatInt(after.score, 10)
+	}
+
+	for offset := int64(0); ; offset += executionScanBatch {
+		entries, err := r.client.ZRevRangeByScoreWithScores(ctx, r.executionIndexKey(), &redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    maxScore,
+			Offset: offset,
+			Count:  executionScanBatch,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list executions: %w", err)
+		}
+
+		candidates := make([]indexPosition, 0, len(entries))
+		for _, entry := range entries {
+			position := indexPosition{score: int64(entry.Score), id: fmt.Sprint(entry.Member)}
+			// Entries sharing the cursor's score come in descending ID order
+			if after != nil && position.score == after.score && position.id >= after.id {
+				continue
+			}
+			candidates = append(candidates, position)
+		}
+
+		summaries, err := r.loadSummaries(ctx, candidates)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, summary := range summaries {
+			if summary == nil || (opts.Status != "" && summary.Status != opts.Status) {
+				continue
+			}
+			// One match beyond the page shows there is another page
+			if len(page.Executions) == limit {
+				page.NextCursor = positions[len(positions)-1].String()
+				return page, nil
+			}
+			page.Executions = append(page.Executions, *summary)
+			positions = append(positions, candidates[i])
+		}
+
+		if len(entries) < executionScanBatch {
+			return page, nil
+		}
+	}
+}
+
+// loadSummaries reads the listing fields of executions; expired executions are nil
+func (r *RedisStateManager) loadSummaries(ctx context.Context, positions []indexPosition) ([]*models.ExecutionSummary, error) {
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(positions))
+	for i, position := range positions {
+		keys[i] = r.executionKey(position.id)
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load executions: %w", err)
+	}
+
+	summaries := make([]*models.ExecutionSummary, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var summary models.ExecutionSummary
+		if err := json.Unmarshal([]byte(data), &summary); err != nil {
+			r.logger.WithError(err).WithField("execution_id", positions[i].id).Warn("Skipping unreadable execution in listing")
+			continue
+		}
+		summaries[i] = &summary
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,103 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// saveListedExecutions saves executions exec-0 to exec-<n-1> with the given statuses. Pairs
+// share a start second, so pages have to break between executions with the same score.
+func saveListedExecutions(t *testing.T, states *RedisStateManager, statuses ...models.ExecutionStatus) {
+	t.Helper()
+	start := time.Unix(1700000000, 0)
+	for i, status := range statuses {
+		execution := &models.WorkflowExecution{
+			ID:         fmt.Sprintf("exec-%d", i),
+			WorkflowID: "wf",
+			Status:     status,
+			StartTime:  start.Add(time.Duration(i/2) * time.Second),
+			TaskStates: map[string]*models.TaskState{},
+			Variables:  map[string]interface{}{},
+			Metadata:   map[string]interface{}{},
+		}
+		if err := states.SaveExecution(context.Background(), execution); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listAll follows next_cursor from the first page to the last, returning the IDs listed
+// and the size of each page
+func listAll(t *testing.T, states *RedisStateManager, opts ExecutionListOptions) ([]string, []int) {
+	t.Helper()
+	var ids []string
+	var sizes []int
+	for {
+		page, err := states.ListExecutions(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(page.Executions))
+		for _, summary := range page.Executions {
+			ids = append(ids, summary.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, sizes
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func TestListExecutionsPagesNewestFirst(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+	saveListedExecutions(t, states,
+		models.StatusCompleted, models.StatusRunning, models.StatusFailed, models.StatusRunning,
+		models.StatusCompleted, models.StatusRunning, models.StatusCompleted)
+
+	ids, sizes := listAll(t, states, ExecutionListOptions{Limit: 3})
+
+	want := []string{"exec-6", "exec-5", "exec-4", "exec-3", "exec-2", "exec-1", "exec-0"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want every execution once, newest first", ids)
+	}
+	if !reflect.DeepEqual(sizes, []int{3, 3, 1}) {
+		t.Errorf("got page sizes %v, want 3, 3, 1", sizes)
+	}
+}
+
+func TestListExecutionsFiltersByStatusAcrossPages(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+	saveListedExecutions(t, states,
+		models.StatusRunning, models.StatusCompleted, models.StatusRunning, models.StatusCompleted,
+		models.StatusRunning, models.StatusRunning, models.StatusCompleted)
+
+	// An expired execution leaves its index entry behind
+	server.Del("test:execution:exec-4")
+
+	ids, sizes := listAll(t, states, ExecutionListOptions{Status: models.StatusRunning, Limit: 2})
+
+	if want := []string{"exec-5", "exec-2", "exec-0"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %v, want the running executions still stored, newest first", ids)
+	}
+	if !reflect.DeepEqual(sizes, []int{2, 1}) {
+		t.Errorf("got page sizes %v, want 2, 1", sizes)
+	}
+}
+
+func TestListExecutionsRejectsInvalidCursor(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+
+	for _, cursor := range []string{"exec-1", "abc:exec-1", "1700000000:"} {
+		if _, err := states.ListExecutions(context.Background(), ExecutionListOptions{Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
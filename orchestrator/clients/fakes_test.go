@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"orchestrator/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newMiniRedisClient returns a client for an in-process miniredis, for tests that need
+// real Redis semantics without a server. The server controls time via FastForward.
+func newMiniRedisClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// newStubRedisClient returns a client for a server that answers every command with the
+// raw RESP reply, for tests that only need one canned answer
+func newStubRedisClient(t *testing.T, reply string) *redis.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveStubRedis(conn, reply)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// serveStubRedis reads RESP arrays of bulk strings and answers each with reply
+func serveStubRedis(conn net.Conn, reply string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "*")))
+		if err != nil {
+			return
+		}
+		// Each argument is a length line followed by its data line
+		for i := 0; i < count*2; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// taskExecutorFunc adapts a function to the engine's TaskExecutor interface
+type taskExecutorFunc func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error
+
+func (f taskExecutorFunc) ExecuteTask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+	return f(ctx, task, execution)
+}
+
+// memoryStateManager keeps executions in memory, for tests that drive the engine
+type memoryStateManager struct {
+	mutex      sync.Mutex
+	executions map[string]*models.WorkflowExecution
+}
+
+func newMemoryStateManager() *memoryStateManager {
+	return &memoryStateManager{executions: make(map[string]*models.WorkflowExecution)}
+}
+
+func (m *memoryStateManager) SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.executions[execution.ID] = execution
+	return nil
+}
+
+func (m *memoryStateManager) LoadExecution(ctx context.Context, executionID string) (*models.WorkflowExecution, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	execution, exists := m.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("execution %s not found", executionID)
+	}
+	return execution, nil
+}
+
+func (m *memoryStateManager) DeleteExecution(ctx context.Context, executionID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.executions, executionID)
+	return nil
+}
+
+func (m *memoryStateManager) ListActiveExecutions(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
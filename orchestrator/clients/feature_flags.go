@@ -0,0 +1,87 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// RedisFlagStore keeps operator-controlled feature flags in a Redis hash
+type RedisFlagStore struct {
+	client    *redis.Client
+	keyPrefix string
+	logger    *logrus.Logger
+}
+
+// NewRedisFlagStore creates a feature flag store under the given key prefix
+func NewRedisFlagStore(client *redis.Client, keyPrefix string) *RedisFlagStore {
+	return &RedisFlagStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+		logger:    logrus.New(),
+	}
+}
+
+// GetFlags returns all flags with their JSON-decoded values
+func (f *RedisFlagStore) GetFlags(ctx context.Context) (map[string]interface{}, error) {
+	raw, err := f.client.HGetAll(ctx, f.flagsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	flags := make(map[string]interface{}, len(raw))
+	for name, data := range raw {
+		var value interface{}
+		if err := json.Unmarshal([]byte(data), &value); err != nil {
+			f.logger.WithError(err).WithField("flag", name).Warn("Ignoring feature flag with invalid value")
+			continue
+		}
+		flags[name] = value
+	}
+
+	return flags, nil
+}
+
+// SetFlag stores a flag value; any JSON-encodable value is accepted
+func (f *RedisFlagStore) SetFlag(ctx context.Context, name string, value interface{}) error {
+	if name == "" {
+		return fmt.Errorf("flag name is required")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal flag value: %w", err)
+	}
+
+	if err := f.client.HSet(ctx, f.flagsKey(), name, data).Err(); err != nil {
+		return fmt.Errorf("failed to set feature flag: %w", err)
+	}
+
+	f.logger.WithFields(logrus.Fields{
+		"flag":  name,
+		"value": value,
+	}).Info("Feature flag updated")
+
+	return nil
+}
+
+// DeleteFlag removes a flag, reporting whether it existed
+func (f *RedisFlagStore) DeleteFlag(ctx context.Context, name string) (bool, error) {
+	removed, err := f.client.HDel(ctx, f.flagsKey(), name).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+
+	if removed > 0 {
+		f.logger.WithField("flag", name).Info("Feature flag deleted")
+	}
+
+	return removed > 0, nil
+}
+
+func (f *RedisFlagStore) flagsKey() string {
+	return fmt.Sprintf("%s:feature_flags", f.keyPrefix)
+}
@@ -0,0 +1,42 @@
+package clients
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRedisFlagStoreRoundTrip(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	store := NewRedisFlagStore(client, "test")
+	ctx := context.Background()
+
+	if err := store.SetFlag(ctx, "use_cheap_model", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFlag(ctx, "max_rows", 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetFlag(ctx, "", true); err == nil {
+		t.Error("expected an unnamed flag to be rejected")
+	}
+
+	// A value written outside the API that isn't JSON is skipped
+	client.HSet(ctx, store.flagsKey(), "broken", "{")
+
+	flags, err := store.GetFlags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"use_cheap_model": true, "max_rows": float64(50)}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("got %v, want %v", flags, want)
+	}
+
+	if removed, err := store.DeleteFlag(ctx, "max_rows"); err != nil || !removed {
+		t.Fatalf("delete: %v, %v", removed, err)
+	}
+	if removed, err := store.DeleteFlag(ctx, "max_rows"); err != nil || removed {
+		t.Fatalf("second delete: %v, %v", removed, err)
+	}
+}
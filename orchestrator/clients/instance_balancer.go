@@ -0,0 +1,182 @@
+package clients
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Load balancing strategies for replicas that announce their own request channel
+const (
+	BalanceRoundRobin        = "round_robin"
+	BalanceRandom            = "random"
+	BalanceLeastRecentlyUsed = "least_recently_used"
+)
+
+// serviceComponents maps coordinator service names to announcing components
+var serviceComponents = map[string]string{
+	"data": "data-abstractor",
+	"ai":   "ai-abstractor",
+	"exec": "exec-agent",
+}
+
+// InstanceSource lists the active replicas of a component
+type InstanceSource interface {
+	GetInstances(component string) []ServiceInstance
+}
+
+// InstanceBalancer picks a replica's request channel for each request. Instance weights
+// apply to the round-robin and random strategies; least-recently-used ignores them.
+type InstanceBalancer struct {
+	source   InstanceSource
+	strategy string
+	mutex    sync.Mutex
+	current  map[string]map[string]int // smooth weighted round-robin state per component
+	lastUsed map[string]map[string]int // selection sequence per component, for least-recently-used
+	sequence int
+	random   *rand.Rand
+}
+
+// NewInstanceBalancer creates a balancer over the registry's replicas. An empty strategy
+// defaults to round-robin.
+func NewInstanceBalancer(source InstanceSource, strategy string) (*InstanceBalancer, error) {
+	switch strategy {
+	case "":
+		strategy = BalanceRoundRobin
+	case BalanceRoundRobin, BalanceRandom, BalanceLeastRecentlyUsed:
+	default:
+		return nil, fmt.Errorf("unknown load balancing strategy %q", strategy)
+	}
+
+	return &InstanceBalancer{
+		source:   source,
+		strategy: strategy,
+		current:  make(map[string]map[string]int),
+		lastUsed: make(map[string]map[string]int),
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// SelectChannel returns the request channel of the chosen replica, or fallback when the
+// component has no replicas with their own channel
+func (b *InstanceBalancer) SelectChannel(component, fallback string) string {
+	instances := b.source.GetInstances(component)
+	if len(instances) == 0 {
+		return fallback
+	}
+	return b.choose(component, instances).RequestChannel
+}
+
+// SelectPinnedChannel returns the request channel of a replica running version, or
+// fallback when the component has no replicas with their own channel. It fails when
+// replicas exist but none runs the version.
+func (b *InstanceBalancer) SelectPinnedChannel(component, version, fallback string) (string, error) {
+	instances := b.source.GetInstances(component)
+	if len(instances) == 0 {
+		return fallback, nil
+	}
+
+	var matching []ServiceInstance
+	for _, instance := range instances {
+		if instance.Version == version {
+			matching = append(matching, instance)
+		}
+	}
+	if len(matching) == 0 {
+		return "", fmt.Errorf("no replica of %s runs pinned version %s", component, version)
+	}
+
+	// Replicas of one version are balanced apart from the rest of the component
+	return b.choose(component+"@"+version, matching).RequestChannel, nil
+}
+
+// choose picks one of instances with the configured strategy, keeping selection state
+// under key
+func (b *InstanceBalancer) choose(key string, instances []ServiceInstance) ServiceInstance {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.strategy {
+	case BalanceRandom:
+		return b.pickRandom(instances)
+	case BalanceLeastRecentlyUsed:
+		return b.pickLeastRecentlyUsed(key, instances)
+	default:
+		return b.pickRoundRobin(key, instances)
+	}
+}
+
+// pickRoundRobin uses smooth weighted round-robin: every replica gains its weight, the
+// highest is chosen and pays back the total, so a 2:1 weighting yields a, b, a, a, b, a
+func (b *InstanceBalancer) pickRoundRobin(component string, instances []ServiceInstance) ServiceInstance {
+	current := instanceState(b.current, component, instances)
+
+	total := 0
+	best := 0
+	for i, instance := range instances {
+		current[instance.ID] += instance.Weight
+		total += instance.Weight
+		if current[instance.ID] > current[instances[best].ID] {
+			best = i
+		}
+	}
+
+	current[instances[best].ID] -= total
+	return instances[best]
+}
+
+// pickRandom chooses a replica with probability proportional to its weight
+func (b *InstanceBalancer) pickRandom(instances []ServiceInstance) ServiceInstance {
+	total := 0
+	for _, instance := range instances {
+		total += instance.Weight
+	}
+
+	n := b.random.Intn(total)
+	for _, instance := range instances {
+		if n < instance.Weight {
+			return instance
+		}
+		n -= instance.Weight
+	}
+	return instances[len(instances)-1]
+}
+
+// pickLeastRecentlyUsed chooses the replica that was selected longest ago; replicas never
+// selected come first
+func (b *InstanceBalancer) pickLeastRecentlyUsed(component string, instances []ServiceInstance) ServiceInstance {
+	lastUsed := instanceState(b.lastUsed, component, instances)
+
+	best := 0
+	for i, instance := range instances {
+		if lastUsed[instance.ID] < lastUsed[instances[best].ID] {
+			best = i
+		}
+	}
+
+	b.sequence++
+	lastUsed[instances[best].ID] = b.sequence
+	return instances[best]
+}
+
+// instanceState returns a component's selection state, dropping replicas that have left
+func instanceState(states map[string]map[string]int, component string, instances []ServiceInstance) map[string]int {
+	state, exists := states[component]
+	if !exists {
+		state = make(map[string]int)
+		states[component] = state
+	}
+
+	active := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		active[instance.ID] = true
+	}
+	for id := range state {
+		if !active[id] {
+			delete(state, id)
+		}
+	}
+
+	return state
+}
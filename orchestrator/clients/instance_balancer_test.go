@@ -0,0 +1,133 @@
+package clients
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeInstanceSource serves a fixed replica list per component that tests can change
+type fakeInstanceSource struct {
+	mutex     sync.Mutex
+	instances map[string][]ServiceInstance
+}
+
+func (f *fakeInstanceSource) GetInstances(component string) []ServiceInstance {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.instances[component]
+}
+
+func (f *fakeInstanceSource) set(component string, instances ...ServiceInstance) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.instances[component] = instances
+}
+
+func newFakeInstanceSource() *fakeInstanceSource {
+	return &fakeInstanceSource{instances: make(map[string][]ServiceInstance)}
+}
+
+func replica(id string, weight int) ServiceInstance {
+	return ServiceInstance{ID: id, Component: "data-abstractor", RequestChannel: "data-requests:" + id, Weight: weight}
+}
+
+func TestInstanceBalancerSpreadsRequestsAcrossInstances(t *testing.T) {
+	for _, strategy := range []string{BalanceRoundRobin, BalanceLeastRecentlyUsed} {
+		source := newFakeInstanceSource()
+		source.set("data-abstractor", replica("a", 1), replica("b", 1), replica("c", 1))
+		balancer, err := NewInstanceBalancer(source, strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		counts := make(map[string]int)
+		for i := 0; i < 30; i++ {
+			counts[balancer.SelectChannel("data-abstractor", "data-requests")]++
+		}
+		for _, id := range []string{"a", "b", "c"} {
+			if counts["data-requests:"+id] != 10 {
+				t.Errorf("%s: got %v, want 10 requests on each replica", strategy, counts)
+				break
+			}
+		}
+	}
+}
+
+func TestInstanceBalancerHonoursWeights(t *testing.T) {
+	source := newFakeInstanceSource()
+	source.set("data-abstractor", replica("a", 2), replica("b", 1))
+	balancer, err := NewInstanceBalancer(source, BalanceRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, balancer.SelectChannel("data-abstractor", "data-requests"))
+	}
+	want := []string{"data-requests:a", "data-requests:b", "data-requests:a", "data-requests:a", "data-requests:b", "data-requests:a"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestInstanceBalancerFallsBackWhenInstancesAreGone(t *testing.T) {
+	source := newFakeInstanceSource()
+	source.set("data-abstractor", replica("a", 1), replica("b", 1))
+	balancer, err := NewInstanceBalancer(source, BalanceRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balancer.SelectChannel("data-abstractor", "data-requests")
+
+	// A replica that left is never chosen again
+	source.set("data-abstractor", replica("b", 1))
+	for i := 0; i < 3; i++ {
+		if got := balancer.SelectChannel("data-abstractor", "data-requests"); got != "data-requests:b" {
+			t.Fatalf("got %q after replica a left, want data-requests:b", got)
+		}
+	}
+
+	// With no replicas left requests go to the shared channel
+	source.set("data-abstractor")
+	if got := balancer.SelectChannel("data-abstractor", "data-requests"); got != "data-requests" {
+		t.Errorf("got %q with no replicas, want the shared data-requests channel", got)
+	}
+}
+
+func TestInstanceBalancerRoutesPinnedRequestsToTheirVersion(t *testing.T) {
+	source := newFakeInstanceSource()
+	old, upgraded := replica("a", 1), replica("b", 1)
+	old.Version, upgraded.Version = "1.4.0", "1.5.0"
+	source.set("data-abstractor", old, upgraded)
+	balancer, err := NewInstanceBalancer(source, BalanceRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := balancer.SelectPinnedChannel("data-abstractor", "1.4.0", "data-requests")
+		if err != nil || got != "data-requests:a" {
+			t.Fatalf("got %q, %v, want the replica running 1.4.0", got, err)
+		}
+	}
+
+	// Once the pinned version is gone the request fails instead of reaching another one
+	source.set("data-abstractor", upgraded)
+	if _, err := balancer.SelectPinnedChannel("data-abstractor", "1.4.0", "data-requests"); err == nil {
+		t.Error("expected an error with no replica on the pinned version")
+	}
+
+	source.set("data-abstractor")
+	if got, err := balancer.SelectPinnedChannel("data-abstractor", "1.4.0", "data-requests"); err != nil || got != "data-requests" {
+		t.Errorf("got %q, %v with no replicas, want the shared data-requests channel", got, err)
+	}
+}
+
+func TestNewInstanceBalancerRejectsUnknownStrategy(t *testing.T) {
+	if _, err := NewInstanceBalancer(newFakeInstanceSource(), "fastest"); err == nil {
+		t.Error("expected an unknown strategy to be rejected")
+	}
+}
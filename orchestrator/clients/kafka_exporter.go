@@ -0,0 +1,137 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"orchestrator/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KafkaProducer writes keyed messages to Kafka topics
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaExecutionExporter publishes finished execution records as JSON to a Kafka topic,
+// keyed by execution ID so the records of one execution stay in one partition
+type KafkaExecutionExporter struct {
+	producer KafkaProducer
+	topic    string
+	logger   *logrus.Logger
+}
+
+// NewKafkaExecutionExporter creates an exporter publishing to topic through producer
+func NewKafkaExecutionExporter(producer KafkaProducer, topic string) *KafkaExecutionExporter {
+	return &KafkaExecutionExporter{
+		producer: producer,
+		topic:    topic,
+		logger:   logrus.New(),
+	}
+}
+
+// ExportExecution publishes one execution record
+func (e *KafkaExecutionExporter) ExportExecution(ctx context.Context, record *models.ExecutionRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution record: %w", err)
+	}
+
+	if err := e.producer.Produce(ctx, e.topic, []byte(record.ExecutionID), value); err != nil {
+		return fmt.Errorf("failed to export execution to topic %s: %w", e.topic, err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"execution_id": record.ExecutionID,
+		"status":       record.Status,
+		"topic":        e.topic,
+	}).Debug("Exported execution record")
+
+	return nil
+}
+
+// KafkaRESTProducer produces messages through a Kafka REST proxy speaking the v2 API,
+// so the orchestrator needs no native Kafka client
+type KafkaRESTProducer struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewKafkaRESTProducer creates a producer for the REST proxy at baseURL, sending the
+// given extra headers with every request
+func NewKafkaRESTProducer(baseURL string, headers map[string]string) *KafkaRESTProducer {
+	return &KafkaRESTProducer{
+		url:     strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  &http.Client{},
+	}
+}
+
+// kafkaRESTRecords is the v2 produce request body for binary records
+type kafkaRESTRecords struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+type kafkaRESTRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// kafkaRESTOffsets is the v2 produce response; records the proxy could not write carry
+// an error instead of an offset
+type kafkaRESTOffsets struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Produce writes one message; a non-2xx response or a per-record error is an error
+func (p *KafkaRESTProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+	body, err := json.Marshal(kafkaRESTRecords{Records: []kafkaRESTRecord{{
+		Key:   base64.StdEncoding.EncodeToString(key),
+		Value: base64.StdEncoding.EncodeToString(value),
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal produce request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create produce request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.binary.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka REST proxy returned status %d", resp.StatusCode)
+	}
+
+	var offsets kafkaRESTOffsets
+	if err := json.NewDecoder(resp.Body).Decode(&offsets); err != nil {
+		return fmt.Errorf("failed to decode produce response: %w", err)
+	}
+	for _, offset := range offsets.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka REST proxy rejected the message: %s", offset.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package clients
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/models"
+	"sync"
+	"testing"
+)
+
+// memoryKafkaProducer keeps produced messages in memory, by topic
+type memoryKafkaProducer struct {
+	mutex    sync.Mutex
+	messages map[string][]kafkaMessage
+	err      error
+}
+
+type kafkaMessage struct {
+	key   string
+	value []byte
+}
+
+func newMemoryKafkaProducer() *memoryKafkaProducer {
+	return &memoryKafkaProducer{messages: make(map[string][]kafkaMessage)}
+}
+
+func (p *memoryKafkaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.messages[topic] = append(p.messages[topic], kafkaMessage{key: string(key), value: value})
+	return nil
+}
+
+func TestKafkaExecutionExporterPublishesRecord(t *testing.T) {
+	producer := newMemoryKafkaProducer()
+	exporter := NewKafkaExecutionExporter(producer, "executions")
+
+	record := &models.ExecutionRecord{
+		ExecutionID: "exec-1",
+		WorkflowID:  "wf",
+		Status:      models.StatusCompleted,
+		Labels:      map[string]string{"team": "analytics"},
+		TokensUsed:  1200,
+		Tasks:       []models.TaskRecord{{ID: "summarize", Type: "ai", Status: models.StatusCompleted, TokensUsed: 1200}},
+	}
+	if err := exporter.ExportExecution(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := producer.messages["executions"]
+	if len(messages) != 1 || messages[0].key != "exec-1" {
+		t.Fatalf("got messages %+v, want one keyed by execution ID", messages)
+	}
+	var got models.ExecutionRecord
+	if err := json.Unmarshal(messages[0].value, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.StatusCompleted || got.Labels["team"] != "analytics" || got.TokensUsed != 1200 || len(got.Tasks) != 1 {
+		t.Errorf("unexpected record %+v", got)
+	}
+}
+
+func TestKafkaExecutionExporterReportsProducerErrors(t *testing.T) {
+	producer := newMemoryKafkaProducer()
+	producer.err = errors.New("broker unavailable")
+	exporter := NewKafkaExecutionExporter(producer, "executions")
+
+	if err := exporter.ExportExecution(context.Background(), &models.ExecutionRecord{ExecutionID: "exec-1"}); err == nil {
+		t.Fatal("expected a producer failure to be an error")
+	}
+}
+
+func TestKafkaRESTProducerPostsBinaryRecords(t *testing.T) {
+	var path, contentType, auth string
+	var body kafkaRESTRecords
+	rejected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType, auth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode produce request: %v", err)
+		}
+		if rejected {
+			w.Write([]byte(`{"offsets": [{"partition": null, "offset": null, "error_code": 40403, "error": "topic not found"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets": [{"partition": 0, "offset": 12}]}`))
+	}))
+	defer server.Close()
+
+	producer := NewKafkaRESTProducer(server.URL+"/", map[string]string{"Authorization": "Bearer token"})
+	if err := producer.Produce(context.Background(), "executions", []byte("exec-1"), []byte(`{"status":"completed"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/topics/executions" || contentType != "application/vnd.kafka.binary.v2+json" || auth != "Bearer token" {
+		t.Errorf("got %s with content type %q and Authorization %q", path, contentType, auth)
+	}
+	if len(body.Records) != 1 {
+		t.Fatalf("got records %+v, want one", body.Records)
+	}
+	key, _ := base64.StdEncoding.DecodeString(body.Records[0].Key)
+	value, _ := base64.StdEncoding.DecodeString(body.Records[0].Value)
+	if string(key) != "exec-1" || string(value) != `{"status":"completed"}` {
+		t.Errorf("got key %q and value %q", key, value)
+	}
+
+	rejected = true
+	if err := producer.Produce(context.Background(), "missing", []byte("exec-1"), []byte("{}")); err == nil {
+		t.Error("expected a record the proxy rejected to be an error")
+	}
+}
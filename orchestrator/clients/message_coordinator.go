@@ -4,46 +4,74 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"orchestrator/engine"
+	"orchestrator/logging"
+	"orchestrator/metrics"
 	"orchestrator/models"
+	"orchestrator/tracing"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RedisMessageCoordinator handles communication with other services via Redis pub/sub
 type RedisMessageCoordinator struct {
-	client         *redis.Client
-	dataConfig     ServiceChannelConfig
-	aiConfig       ServiceChannelConfig  
-	execConfig     ServiceChannelConfig
-	defaultTimeout time.Duration
-	subscribers    map[string]*redis.PubSub
-	responseWaiters map[string]chan *models.ServiceResponse
-	mutex          sync.RWMutex
-	logger         *logrus.Logger
+	client              *redis.Client
+	dataConfig          ServiceChannelConfig
+	aiConfig            ServiceChannelConfig
+	execConfig          ServiceChannelConfig
+	defaultTimeout      time.Duration
+	queueWaitPerRequest time.Duration
+	maxResponseWait     time.Duration
+	balancer            *InstanceBalancer
+	sampler             *logging.Sampler
+	subscribers         map[string]*redis.PubSub
+	responseWaiters     map[string]*responseWaiter
+	waiterMetrics       waiterMetrics
+	deadLetters         chan deadLetterMessage
+	deadLetterChannels  map[string]string // response channel -> dead-letter channel
+	deadLetterMetrics   deadLetterMetrics
+	breakers            map[string]*circuitBreaker // service -> breaker; empty when disabled
+	mutex               sync.RWMutex
+	stopChan            chan struct{}
+	logger              *logrus.Logger
 }
 
 // ServiceChannelConfig defines channel configuration for a service
 type ServiceChannelConfig struct {
-	RequestChannel  string
-	ResponseChannel string
-	Timeout         time.Duration
+	RequestChannel    string
+	ResponseChannel   string
+	DeadLetterChannel string // receives responses no caller was waiting for; empty disables
+	Timeout           time.Duration
 }
 
 // NewRedisMessageCoordinator creates a new Redis-based message coordinator
-func NewRedisMessageCoordinator(client *redis.Client, dataConfig, aiConfig, execConfig ServiceChannelConfig, defaultTimeout time.Duration) *RedisMessageCoordinator {
+func NewRedisMessageCoordinator(client *redis.Client, dataConfig, aiConfig, execConfig ServiceChannelConfig, defaultTimeout time.Duration, breakerConfig CircuitBreakerConfig) *RedisMessageCoordinator {
 	mc := &RedisMessageCoordinator{
-		client:          client,
-		dataConfig:      dataConfig,
-		aiConfig:        aiConfig,
-		execConfig:      execConfig,
-		defaultTimeout:  defaultTimeout,
-		subscribers:     make(map[string]*redis.PubSub),
-		responseWaiters: make(map[string]chan *models.ServiceResponse),
-		logger:          logrus.New(),
+		client:             client,
+		dataConfig:         dataConfig,
+		aiConfig:           aiConfig,
+		execConfig:         execConfig,
+		defaultTimeout:     defaultTimeout,
+		subscribers:        make(map[string]*redis.PubSub),
+		responseWaiters:    make(map[string]*responseWaiter),
+		deadLetters:        make(chan deadLetterMessage, deadLetterQueueSize),
+		deadLetterChannels: make(map[string]string),
+		breakers:           newServiceBreakers(breakerConfig),
+		stopChan:           make(chan struct{}),
+		logger:             logrus.New(),
+	}
+
+	for _, config := range []ServiceChannelConfig{dataConfig, aiConfig, execConfig} {
+		if config.DeadLetterChannel != "" {
+			mc.deadLetterChannels[config.ResponseChannel] = config.DeadLetterChannel
+		}
 	}
 
 	// Start response listeners
@@ -51,9 +79,30 @@ func NewRedisMessageCoordinator(client *redis.Client, dataConfig, aiConfig, exec
 	mc.startResponseListener(aiConfig.ResponseChannel)
 	mc.startResponseListener(execConfig.ResponseChannel)
 
+	go mc.waiterReaper()
+	go mc.deadLetterPublisher()
+
 	return mc
 }
 
+// SetAdaptiveTimeout extends the response wait by perQueuedRequest for every request the
+// target service reports as queued, never exceeding maxWait. A zero perQueuedRequest disables it.
+func (mc *RedisMessageCoordinator) SetAdaptiveTimeout(perQueuedRequest, maxWait time.Duration) {
+	mc.queueWaitPerRequest = perQueuedRequest
+	mc.maxResponseWait = maxWait
+}
+
+// SetLogSampler thins out per-request logs under load. Failed responses are always logged.
+func (mc *RedisMessageCoordinator) SetLogSampler(sampler *logging.Sampler) {
+	mc.sampler = sampler
+}
+
+// SetInstanceBalancer spreads requests across service replicas that announce their own
+// request channel; services without such replicas keep using the configured channel
+func (mc *RedisMessageCoordinator) SetInstanceBalancer(balancer *InstanceBalancer) {
+	mc.balancer = balancer
+}
+
 // SendDataRequest sends a request to the data service
 func (mc *RedisMessageCoordinator) SendDataRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
 	return mc.sendServiceRequest(ctx, request, mc.dataConfig)
@@ -70,7 +119,7 @@ func (mc *RedisMessageCoordinator) SendExecRequest(ctx context.Context, request
 }
 
 // sendServiceRequest is the generic implementation for sending requests
-func (mc *RedisMessageCoordinator) sendServiceRequest(ctx context.Context, request *models.ServiceRequest, config ServiceChannelConfig) (*models.ServiceResponse, error) {
+func (mc *RedisMessageCoordinator) sendServiceRequest(ctx context.Context, request *models.ServiceRequest, config ServiceChannelConfig) (response *models.ServiceResponse, err error) {
 	// Generate unique correlation ID if not provided
 	if request.CorrelationID == "" {
 		request.CorrelationID = uuid.New().String()
@@ -79,62 +128,146 @@ func (mc *RedisMessageCoordinator) sendServiceRequest(ctx context.Context, reque
 	// Set service in request
 	request.Service = mc.getServiceNameFromConfig(config)
 
-	mc.logger.WithFields(logrus.Fields{
+	// The service continues this span from the trace context sent with the request
+	ctx, span := tracing.Tracer().Start(ctx, "service.request", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("service.name", request.Service),
+		attribute.String("service.operation", request.Operation),
+		attribute.String("correlation_id", request.CorrelationID),
+	))
+	defer func() {
+		if err == nil && response != nil && !response.Success {
+			tracing.End(span, fmt.Errorf("%s service error: %s", request.Service, response.Error))
+			return
+		}
+		tracing.End(span, err)
+	}()
+	request.TraceContext = tracing.Inject(ctx)
+
+	startTime := time.Now()
+	metricOutcome := "failed"
+	defer func() {
+		metrics.ServiceRequestFinished(request.Service, metricOutcome, time.Since(startTime))
+	}()
+
+	// Fail fast while the service's circuit is open
+	breaker := mc.breakers[request.Service]
+	if breaker != nil && !breaker.allow(time.Now()) {
+		metricOutcome = "circuit_open"
+		return nil, fmt.Errorf("%s service unavailable: %w", request.Service, ErrCircuitOpen)
+	}
+	outcome := breakerFailure
+	if breaker != nil {
+		defer func() { breaker.record(outcome, time.Now()) }()
+	}
+
+	requestChannel := config.RequestChannel
+	if mc.balancer != nil {
+		// Executions that pinned service versions only go to replicas running them
+		if call, ok := engine.TaskCallFromContext(ctx); ok && call.PinnedVersion != "" {
+			requestChannel, err = mc.balancer.SelectPinnedChannel(serviceComponents[request.Service], call.PinnedVersion, requestChannel)
+			if err != nil {
+				outcome = breakerAborted
+				return nil, err
+			}
+		} else {
+			requestChannel = mc.balancer.SelectChannel(serviceComponents[request.Service], requestChannel)
+		}
+	}
+
+	mc.sampler.Info(mc.logger, logrus.Fields{
 		"correlation_id": request.CorrelationID,
 		"service":        request.Service,
 		"operation":      request.Operation,
-		"channel":        config.RequestChannel,
-	}).Info("Sending service request")
+		"channel":        requestChannel,
+	}, "Sending service request")
 
-	// Create response waiter
-	responseChan := make(chan *models.ServiceResponse, 1)
-	mc.mutex.Lock()
-	mc.responseWaiters[request.CorrelationID] = responseChan
-	mc.mutex.Unlock()
+	// Create response waiter, expiring with the response wait of the replica sent to
+	timeout := mc.effectiveTimeout(ctx, config, requestChannel)
+	waiter := mc.addWaiter(request.CorrelationID, 1, timeout)
 
 	// Cleanup waiter on return
-	defer func() {
-		mc.mutex.Lock()
-		delete(mc.responseWaiters, request.CorrelationID)
-		mc.mutex.Unlock()
-		close(responseChan)
-	}()
+	defer mc.removeWaiter(request.CorrelationID, waiter)
 
 	// Marshal request
 	requestData, err := json.Marshal(request)
 	if err != nil {
+		outcome = breakerAborted
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Publish request
-	err = mc.client.Publish(ctx, config.RequestChannel, requestData).Err()
+	err = mc.client.Publish(ctx, requestChannel, requestData).Err()
 	if err != nil {
 		return nil, fmt.Errorf("failed to publish request: %w", err)
 	}
 
 	// Wait for response with timeout
-	timeout := config.Timeout
-	if timeout == 0 {
-		timeout = mc.defaultTimeout
-	}
-
 	select {
-	case response := <-responseChan:
-		mc.logger.WithFields(logrus.Fields{
+	case response, ok := <-waiter.ch:
+		if !ok {
+			return nil, fmt.Errorf("response waiter closed before a response arrived")
+		}
+		outcome = breakerSuccess
+		metricOutcome = "success"
+		if !response.Success {
+			metricOutcome = "error"
+		}
+		mc.sampler.Info(mc.logger, logrus.Fields{
 			"correlation_id": request.CorrelationID,
 			"service":        request.Service,
+			"operation":      request.Operation,
 			"success":        response.Success,
-		}).Info("Received service response")
+		}, "Received service response")
 		return response, nil
 
 	case <-time.After(timeout):
+		metricOutcome = "timeout"
+		atomic.AddUint64(&mc.waiterMetrics.timedOut, 1)
 		return nil, fmt.Errorf("request timeout after %v", timeout)
 
 	case <-ctx.Done():
+		outcome = breakerAborted
+		metricOutcome = "cancelled"
+		atomic.AddUint64(&mc.waiterMetrics.cancelled, 1)
 		return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
 	}
 }
 
+// effectiveTimeout returns the service timeout, extended by the queue depth last reported
+// on requestChannel, the channel the request is published to. Falls back to the static
+// timeout when no depth signal is available.
+func (mc *RedisMessageCoordinator) effectiveTimeout(ctx context.Context, config ServiceChannelConfig, requestChannel string) time.Duration {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = mc.defaultTimeout
+	}
+
+	if mc.queueWaitPerRequest <= 0 {
+		return timeout
+	}
+
+	depth, err := mc.client.Get(ctx, requestChannel+":queue_depth").Int()
+	if err != nil || depth <= 0 {
+		return timeout
+	}
+
+	extended := timeout + time.Duration(depth)*mc.queueWaitPerRequest
+	if mc.maxResponseWait > 0 && extended > mc.maxResponseWait {
+		extended = mc.maxResponseWait
+	}
+	if extended < timeout {
+		return timeout
+	}
+
+	mc.logger.WithFields(logrus.Fields{
+		"channel":     requestChannel,
+		"queue_depth": depth,
+		"timeout":     extended,
+	}).Debug("Extended response wait for queued service")
+
+	return extended
+}
+
 // startResponseListener starts listening for responses on a channel
 func (mc *RedisMessageCoordinator) startResponseListener(channel string) {
 	pubsub := mc.client.Subscribe(context.Background(), channel)
@@ -150,7 +283,7 @@ func (mc *RedisMessageCoordinator) responseListener(channel string, pubsub *redi
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
-	
+
 	for msg := range ch {
 		var response models.ServiceResponse
 		if err := json.Unmarshal([]byte(msg.Payload), &response); err != nil {
@@ -158,25 +291,13 @@ func (mc *RedisMessageCoordinator) responseListener(channel string, pubsub *redi
 				"channel": channel,
 				"payload": msg.Payload,
 			}).Error("Failed to unmarshal service response")
+			mc.deadLetter(channel, DeadLetterUnparseable, msg.Payload)
 			continue
 		}
 
-		// Route response to waiting caller
-		mc.mutex.RLock()
-		responseChan, exists := mc.responseWaiters[response.CorrelationID]
-		mc.mutex.RUnlock()
-
-		if exists {
-			select {
-			case responseChan <- &response:
-				// Response delivered
-			default:
-				// Channel full or closed, log warning
-				mc.logger.WithField("correlation_id", response.CorrelationID).Warn("Failed to deliver response - channel full or closed")
-			}
-		} else {
-			// No waiter for this response - might be expired
-			mc.logger.WithField("correlation_id", response.CorrelationID).Debug("Received response with no waiting caller")
+		// Route response to waiting caller, dead-lettering it when nobody is waiting
+		if reason := mc.deliverResponse(&response); reason != "" {
+			mc.deadLetter(channel, reason, msg.Payload)
 		}
 	}
 
@@ -212,19 +333,10 @@ func (mc *RedisMessageCoordinator) SendBroadcastRequest(ctx context.Context, req
 
 	// Create response collector
 	responses := make([]*models.ServiceResponse, 0, expectedResponses)
-	responseChan := make(chan *models.ServiceResponse, expectedResponses)
-	
-	mc.mutex.Lock()
-	mc.responseWaiters[request.CorrelationID] = responseChan
-	mc.mutex.Unlock()
+	waiter := mc.addWaiter(request.CorrelationID, expectedResponses, timeout)
 
 	// Cleanup waiter on return
-	defer func() {
-		mc.mutex.Lock()
-		delete(mc.responseWaiters, request.CorrelationID)
-		mc.mutex.Unlock()
-		close(responseChan)
-	}()
+	defer mc.removeWaiter(request.CorrelationID, waiter)
 
 	// Marshal and send request to all services
 	requestData, err := json.Marshal(request)
@@ -247,21 +359,26 @@ func (mc *RedisMessageCoordinator) SendBroadcastRequest(ctx context.Context, req
 
 	// Collect responses
 	timeoutChan := time.After(timeout)
-	
+
 	for len(responses) < expectedResponses {
 		select {
-		case response := <-responseChan:
+		case response, ok := <-waiter.ch:
+			if !ok {
+				return responses, fmt.Errorf("broadcast waiter closed: received %d/%d responses", len(responses), expectedResponses)
+			}
 			responses = append(responses, response)
-			
+
 		case <-timeoutChan:
+			atomic.AddUint64(&mc.waiterMetrics.timedOut, 1)
 			mc.logger.WithFields(logrus.Fields{
 				"correlation_id": request.CorrelationID,
 				"received":       len(responses),
 				"expected":       expectedResponses,
 			}).Warn("Broadcast request timeout - returning partial results")
 			return responses, fmt.Errorf("broadcast timeout: received %d/%d responses", len(responses), expectedResponses)
-			
+
 		case <-ctx.Done():
+			atomic.AddUint64(&mc.waiterMetrics.cancelled, 1)
 			return responses, fmt.Errorf("broadcast cancelled: %w", ctx.Err())
 		}
 	}
@@ -299,6 +416,13 @@ func (mc *RedisMessageCoordinator) SendAsyncRequest(ctx context.Context, request
 	return mc.client.Publish(ctx, config.RequestChannel, requestData).Err()
 }
 
+// PendingRequests returns how many requests are waiting for a response
+func (mc *RedisMessageCoordinator) PendingRequests() int {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+	return len(mc.responseWaiters)
+}
+
 // GetStats returns statistics about the message coordinator
 func (mc *RedisMessageCoordinator) GetStats() map[string]interface{} {
 	mc.mutex.RLock()
@@ -306,11 +430,14 @@ func (mc *RedisMessageCoordinator) GetStats() map[string]interface{} {
 	mc.mutex.RUnlock()
 
 	return map[string]interface{}{
-		"pending_requests":  pendingRequests,
+		"pending_requests":   pendingRequests,
 		"active_subscribers": len(mc.subscribers),
-		"data_channel":      mc.dataConfig.RequestChannel,
-		"ai_channel":        mc.aiConfig.RequestChannel,
-		"exec_channel":      mc.execConfig.RequestChannel,
+		"data_channel":       mc.dataConfig.RequestChannel,
+		"ai_channel":         mc.aiConfig.RequestChannel,
+		"exec_channel":       mc.execConfig.RequestChannel,
+		"response_waiters":   mc.waiterStats(time.Now()),
+		"dead_letters":       mc.GetDeadLetterStats(),
+		"circuit_breakers":   mc.breakerStats(),
 	}
 }
 
@@ -325,10 +452,12 @@ func (mc *RedisMessageCoordinator) Close() error {
 		}
 	}
 
+	close(mc.stopChan)
+
 	// Clear response waiters
 	mc.mutex.Lock()
-	for corrID, ch := range mc.responseWaiters {
-		close(ch)
+	for corrID, waiter := range mc.responseWaiters {
+		waiter.close()
 		delete(mc.responseWaiters, corrID)
 	}
 	mc.mutex.Unlock()
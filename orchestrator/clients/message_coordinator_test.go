@@ -0,0 +1,188 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"orchestrator/models"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestCoordinator returns a coordinator on client without starting its listeners
+func newTestCoordinator(client *redis.Client) *RedisMessageCoordinator {
+	return &RedisMessageCoordinator{
+		client:          client,
+		defaultTimeout:  30 * time.Second,
+		responseWaiters: make(map[string]*responseWaiter),
+		breakers:        make(map[string]*circuitBreaker),
+		logger:          logrus.New(),
+	}
+}
+
+func TestEffectiveTimeoutExtendsForQueuedRequests(t *testing.T) {
+	// The service reports 5 requests queued ahead
+	mc := newTestCoordinator(newStubRedisClient(t, "$1\r\n5\r\n"))
+	mc.SetAdaptiveTimeout(2*time.Second, time.Minute)
+
+	got := mc.effectiveTimeout(context.Background(), ServiceChannelConfig{RequestChannel: "data-requests", Timeout: 10 * time.Second}, "data-requests")
+	if got != 20*time.Second {
+		t.Errorf("got %v, want 20s", got)
+	}
+}
+
+func TestEffectiveTimeoutIsCapped(t *testing.T) {
+	mc := newTestCoordinator(newStubRedisClient(t, "$3\r\n100\r\n"))
+	mc.SetAdaptiveTimeout(2*time.Second, time.Minute)
+
+	got := mc.effectiveTimeout(context.Background(), ServiceChannelConfig{RequestChannel: "data-requests", Timeout: 10 * time.Second}, "data-requests")
+	if got != time.Minute {
+		t.Errorf("got %v, want the 1m cap", got)
+	}
+}
+
+func TestEffectiveTimeoutFallsBackWithoutSignal(t *testing.T) {
+	// No depth reported
+	mc := newTestCoordinator(newStubRedisClient(t, "$-1\r\n"))
+	mc.SetAdaptiveTimeout(2*time.Second, time.Minute)
+
+	if got := mc.effectiveTimeout(context.Background(), ServiceChannelConfig{RequestChannel: "data-requests"}, "data-requests"); got != 30*time.Second {
+		t.Errorf("got %v, want the 30s default timeout", got)
+	}
+
+	// Disabled, so the depth is never read
+	mc = newTestCoordinator(newStubRedisClient(t, "$1\r\n5\r\n"))
+	if got := mc.effectiveTimeout(context.Background(), ServiceChannelConfig{RequestChannel: "data-requests", Timeout: 10 * time.Second}, "data-requests"); got != 10*time.Second {
+		t.Errorf("got %v, want the static 10s timeout", got)
+	}
+}
+
+// waitForSubscribers waits until every channel has a subscriber on the server
+func waitForSubscribers(t *testing.T, server *miniredis.Miniredis, channels ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		counts := server.PubSubNumSub(channels...)
+		subscribed := true
+		for _, channel := range channels {
+			if counts[channel] == 0 {
+				subscribed = false
+			}
+		}
+		if subscribed {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for subscribers on %v", channels)
+}
+
+func TestUndeliverableResponsesAreRepublishedToDeadLetterChannel(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	dataConfig := ServiceChannelConfig{RequestChannel: "data:requests", ResponseChannel: "data:responses", DeadLetterChannel: "data:dead-letters"}
+	aiConfig := ServiceChannelConfig{RequestChannel: "ai:requests", ResponseChannel: "ai:responses"}
+	execConfig := ServiceChannelConfig{RequestChannel: "exec:requests", ResponseChannel: "exec:responses"}
+	mc := NewRedisMessageCoordinator(client, dataConfig, aiConfig, execConfig, time.Second, CircuitBreakerConfig{})
+	defer mc.Close()
+
+	deadLetters := client.Subscribe(ctx, "data:dead-letters")
+	defer deadLetters.Close()
+	waitForSubscribers(t, server, "data:responses", "ai:responses", "data:dead-letters")
+
+	// Nobody is waiting on this correlation ID, e.g. the request already timed out
+	late := `{"correlation_id":"timed-out","success":true,"service":"data"}`
+	if err := client.Publish(ctx, "data:responses", late).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-deadLetters.Channel():
+		var letter DeadLetter
+		if err := json.Unmarshal([]byte(msg.Payload), &letter); err != nil {
+			t.Fatal(err)
+		}
+		if letter.Reason != DeadLetterNoWaiter || letter.Channel != "data:responses" || letter.Payload != late {
+			t.Errorf("got dead letter %+v, want the late response as received", letter)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+
+	// A service without a dead-letter channel only counts its undeliverable responses
+	if err := client.Publish(ctx, "ai:responses", "not json").Err(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		stats := mc.GetDeadLetterStats()
+		if stats["unparseable"].(uint64) > 0 && stats["published"].(uint64) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := mc.GetDeadLetterStats()
+	if stats["no_waiter"].(uint64) != 1 || stats["unparseable"].(uint64) != 1 || stats["published"].(uint64) != 1 {
+		t.Errorf("got stats %v, want one of each reason and one published", stats)
+	}
+}
+
+func TestEffectiveTimeoutReadsTheChosenChannelsDepth(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	server.Set("data-requests:b:queue_depth", "5")
+	mc := newTestCoordinator(client)
+	mc.SetAdaptiveTimeout(2*time.Second, time.Minute)
+
+	config := ServiceChannelConfig{RequestChannel: "data-requests", Timeout: 10 * time.Second}
+	if got := mc.effectiveTimeout(context.Background(), config, "data-requests:b"); got != 20*time.Second {
+		t.Errorf("got %v for the backed-up replica, want 20s", got)
+	}
+	if got := mc.effectiveTimeout(context.Background(), config, "data-requests:a"); got != 10*time.Second {
+		t.Errorf("got %v for an idle replica, want the static 10s", got)
+	}
+}
+
+func TestRequestWaitFollowsTheQueueOfTheReplicaSentTo(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	dataConfig := ServiceChannelConfig{RequestChannel: "data:requests", ResponseChannel: "data:responses", Timeout: 20 * time.Millisecond}
+	aiConfig := ServiceChannelConfig{RequestChannel: "ai:requests", ResponseChannel: "ai:responses"}
+	execConfig := ServiceChannelConfig{RequestChannel: "exec:requests", ResponseChannel: "exec:responses"}
+	mc := NewRedisMessageCoordinator(client, dataConfig, aiConfig, execConfig, time.Second, CircuitBreakerConfig{})
+	defer mc.Close()
+	mc.SetAdaptiveTimeout(10*time.Millisecond, time.Second)
+
+	source := newFakeInstanceSource()
+	source.set("data-abstractor", ServiceInstance{ID: "b", Component: "data-abstractor", RequestChannel: "data:requests:b", Weight: 1})
+	balancer, err := NewInstanceBalancer(source, BalanceRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.SetInstanceBalancer(balancer)
+
+	// Only the replica's own queue counts; the shared channel's would hit the cap
+	server.Set("data:requests:b:queue_depth", "3")
+	server.Set("data:requests:queue_depth", "1000")
+
+	requests := client.Subscribe(ctx, "data:requests:b")
+	defer requests.Close()
+	waitForSubscribers(t, server, "data:requests:b")
+
+	// Nobody answers, so the request waits out the extended timeout
+	_, err = mc.SendDataRequest(ctx, &models.ServiceRequest{Operation: "query"})
+	if err == nil || err.Error() != "request timeout after 50ms" {
+		t.Errorf("got %v, want a timeout after 20ms plus 3 queued requests at 10ms", err)
+	}
+
+	select {
+	case <-requests.Channel():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request on the replica's channel")
+	}
+}
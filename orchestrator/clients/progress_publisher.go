@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"orchestrator/models"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisProgressPublisher fans execution progress out over one Redis channel per execution
+type RedisProgressPublisher struct {
+	client        *redis.Client
+	channelPrefix string
+}
+
+// NewRedisProgressPublisher creates a progress publisher whose channels share the given prefix
+func NewRedisProgressPublisher(client *redis.Client, channelPrefix string) *RedisProgressPublisher {
+	return &RedisProgressPublisher{
+		client:        client,
+		channelPrefix: channelPrefix,
+	}
+}
+
+// PublishProgress sends an event to the channel of its execution
+func (p *RedisProgressPublisher) PublishProgress(ctx context.Context, event *models.ProgressEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+
+	if err := p.client.Publish(ctx, p.Channel(event.ExecutionID), data).Err(); err != nil {
+		return fmt.Errorf("failed to publish progress event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe listens for the progress events of one execution. The caller must close the
+// returned subscription.
+func (p *RedisProgressPublisher) Subscribe(ctx context.Context, executionID string) (*redis.PubSub, error) {
+	pubsub := p.client.Subscribe(ctx, p.Channel(executionID))
+
+	// Wait for the confirmation so no event published after this returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to progress of %s: %w", executionID, err)
+	}
+	return pubsub, nil
+}
+
+// Channel returns the channel carrying an execution's progress events
+func (p *RedisProgressPublisher) Channel(executionID string) string {
+	return p.channelPrefix + ":" + executionID
+}
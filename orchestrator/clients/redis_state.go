@@ -3,20 +3,36 @@ package clients
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"orchestrator/models"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrExecutionNotFound is returned when loading an execution that is not stored
+var ErrExecutionNotFound = errors.New("execution not found")
+
 // RedisStateManager implements state persistence using Redis
 type RedisStateManager struct {
 	client     *redis.Client
 	keyPrefix  string
 	executionTTL time.Duration
 	logger     *logrus.Logger
+
+	// storedDefinitions maps execution IDs to the definition last written for them, so
+	// the definition is written once rather than on every save
+	storedDefinitions sync.Map
+}
+
+// storedDefinition is what an execution runs, kept apart from the frequently rewritten
+// execution record
+type storedDefinition struct {
+	Workflow *models.WorkflowDefinition `json:"workflow"`
+	Request  *models.WorkflowRequest    `json:"request,omitempty"`
 }
 
 // NewRedisStateManager creates a new Redis-based state manager
@@ -32,17 +48,37 @@ func NewRedisStateManager(client *redis.Client, keyPrefix string, executionTTL t
 // SaveExecution persists workflow execution state to Redis
 func (r *RedisStateManager) SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error {
 	key := r.executionKey(execution.ID)
-	
-	data, err := json.Marshal(execution)
+
+	// The definition is stored under its own key; the record only carries task progress
+	record := *execution
+	record.Workflow = nil
+	record.Request = nil
+	data, err := json.Marshal(&record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal execution: %w", err)
 	}
 
+	var definition []byte
+	stored, _ := r.storedDefinitions.Load(execution.ID)
+	if execution.Workflow != nil && stored != execution.Workflow {
+		definition, err = json.Marshal(storedDefinition{Workflow: execution.Workflow, Request: execution.Request})
+		if err != nil {
+			return fmt.Errorf("failed to marshal workflow definition: %w", err)
+		}
+	}
+
 	// Use pipeline for atomic updates
 	pipe := r.client.TxPipeline()
 	
 	// Save execution data
 	pipe.Set(ctx, key, data, r.executionTTL)
+
+	// Save the definition, or keep it alive as long as the record
+	if definition != nil {
+		pipe.Set(ctx, r.definitionKey(execution.ID), definition, r.executionTTL)
+	} else if execution.Workflow != nil {
+		pipe.Expire(ctx, r.definitionKey(execution.ID), r.executionTTL)
+	}
 	
 	// Add to active executions set if still running
 	if execution.Status == models.StatusRunning || execution.Status == models.StatusRetrying {
@@ -67,6 +103,15 @@ func (r *RedisStateManager) SaveExecution(ctx context.Context, execution *models
 		return fmt.Errorf("failed to save execution state: %w", err)
 	}
 
+	// Forget finished executions; a later save writes their definition again
+	if execution.Status == models.StatusRunning || execution.Status == models.StatusRetrying {
+		if definition != nil {
+			r.storedDefinitions.Store(execution.ID, execution.Workflow)
+		}
+	} else {
+		r.storedDefinitions.Delete(execution.ID)
+	}
+
 	r.logger.WithFields(logrus.Fields{
 		"execution_id": execution.ID,
 		"status":       execution.Status,
@@ -76,14 +121,20 @@ func (r *RedisStateManager) SaveExecution(ctx context.Context, execution *models
 	return nil
 }
 
-// LoadExecution retrieves workflow execution state from Redis
+// LoadExecution retrieves workflow execution state from Redis, along with the
+// definition it runs
 func (r *RedisStateManager) LoadExecution(ctx context.Context, executionID string) (*models.WorkflowExecution, error) {
 	key := r.executionKey(executionID)
-	
-	data, err := r.client.Get(ctx, key).Result()
+
+	pipe := r.client.Pipeline()
+	dataCmd := pipe.Get(ctx, key)
+	definitionCmd := pipe.Get(ctx, r.definitionKey(executionID))
+	pipe.Exec(ctx)
+
+	data, err := dataCmd.Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("execution %s not found", executionID)
+			return nil, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
 		}
 		return nil, fmt.Errorf("failed to load execution: %w", err)
 	}
@@ -93,6 +144,18 @@ func (r *RedisStateManager) LoadExecution(ctx context.Context, executionID strin
 		return nil, fmt.Errorf("failed to unmarshal execution: %w", err)
 	}
 
+	// Executions saved before definitions were stored have none
+	if definitionData, err := definitionCmd.Bytes(); err == nil {
+		var definition storedDefinition
+		if err := json.Unmarshal(definitionData, &definition); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal workflow definition: %w", err)
+		}
+		execution.Workflow = definition.Workflow
+		execution.Request = definition.Request
+	} else if err != redis.Nil {
+		return nil, fmt.Errorf("failed to load workflow definition: %w", err)
+	}
+
 	r.logger.WithFields(logrus.Fields{
 		"execution_id": executionID,
 		"status":       execution.Status,
@@ -101,12 +164,45 @@ func (r *RedisStateManager) LoadExecution(ctx context.Context, executionID strin
 	return &execution, nil
 }
 
+// LoadTaskState retrieves a single task's state from a stored execution. Only the
+// requested entry of task_states is decoded, so sibling task outputs are never
+// unmarshalled. A nil state with no error means the task is not part of the execution.
+func (r *RedisStateManager) LoadTaskState(ctx context.Context, executionID, taskID string) (*models.TaskState, error) {
+	data, err := r.client.Get(ctx, r.executionKey(executionID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w: %s", ErrExecutionNotFound, executionID)
+		}
+		return nil, fmt.Errorf("failed to load execution: %w", err)
+	}
+
+	var partial struct {
+		TaskStates map[string]json.RawMessage `json:"task_states"`
+	}
+	if err := json.Unmarshal(data, &partial); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal execution: %w", err)
+	}
+
+	raw, ok := partial.TaskStates[taskID]
+	if !ok || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var state models.TaskState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task state: %w", err)
+	}
+
+	return &state, nil
+}
+
 // DeleteExecution removes workflow execution state from Redis
 func (r *RedisStateManager) DeleteExecution(ctx context.Context, executionID string) error {
 	pipe := r.client.TxPipeline()
-	
+
 	// Remove execution data
 	pipe.Del(ctx, r.executionKey(executionID))
+	pipe.Del(ctx, r.definitionKey(executionID))
 	
 	// Remove from active executions
 	pipe.SRem(ctx, r.activeExecutionsKey(), executionID)
@@ -118,6 +214,7 @@ func (r *RedisStateManager) DeleteExecution(ctx context.Context, executionID str
 	if err != nil {
 		return fmt.Errorf("failed to delete execution: %w", err)
 	}
+	r.storedDefinitions.Delete(executionID)
 
 	r.logger.WithField("execution_id", executionID).Debug("Deleted execution state from Redis")
 	return nil
@@ -201,10 +298,11 @@ func (r *RedisStateManager) CleanupExpiredExecutions(ctx context.Context, before
 
 	// Delete in batches
 	pipe := r.client.TxPipeline()
-	
+
 	for _, executionID := range toDelete {
 		// Remove execution data
 		pipe.Del(ctx, r.executionKey(executionID))
+		pipe.Del(ctx, r.definitionKey(executionID))
 		
 		// Remove from active set (if still there)
 		pipe.SRem(ctx, r.activeExecutionsKey(), executionID)
@@ -263,12 +361,167 @@ func (r *RedisStateManager) LoadTaskCheckpoint(ctx context.Context, executionID,
 	return &state, nil
 }
 
+// SaveMatrixGroup records the executions launched by a matrix request
+func (r *RedisStateManager) SaveMatrixGroup(ctx context.Context, matrixID string, executionIDs []string) error {
+	if len(executionIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(executionIDs))
+	for i, id := range executionIDs {
+		members[i] = id
+	}
+
+	key := r.matrixKey(matrixID)
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, members...)
+	pipe.Expire(ctx, key, r.executionTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save matrix group: %w", err)
+	}
+
+	return nil
+}
+
+// LoadMatrixGroup returns the execution IDs of a matrix group in launch order
+func (r *RedisStateManager) LoadMatrixGroup(ctx context.Context, matrixID string) ([]string, error) {
+	executionIDs, err := r.client.LRange(ctx, r.matrixKey(matrixID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load matrix group: %w", err)
+	}
+
+	if len(executionIDs) == 0 {
+		return nil, fmt.Errorf("matrix group %s not found", matrixID)
+	}
+
+	return executionIDs, nil
+}
+
+// LoadTaskResult returns a cached task output by its content hash
+func (r *RedisStateManager) LoadTaskResult(ctx context.Context, key string) (map[string]interface{}, bool, error) {
+	data, err := r.client.Get(ctx, r.resultCacheKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to load cached task result: %w", err)
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal cached task result: %w", err)
+	}
+
+	return output, true, nil
+}
+
+// SaveTaskResult caches a task output by its content hash for ttl
+func (r *RedisStateManager) SaveTaskResult(ctx context.Context, key string, output map[string]interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task result: %w", err)
+	}
+
+	if err := r.client.Set(ctx, r.resultCacheKey(key), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache task result: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimIdempotencyKey records executionID under key for ttl unless the key is already
+// taken. It returns true when the claim succeeded, or false and the execution ID that
+// holds the key. SETNX makes concurrent duplicates agree on a single winner.
+func (r *RedisStateManager) ClaimIdempotencyKey(ctx context.Context, key, executionID string, ttl time.Duration) (bool, string, error) {
+	redisKey := r.idempotencyKey(key)
+
+	// A claim expiring between SETNX and GET is retried once
+	for attempt := 0; attempt < 2; attempt++ {
+		claimed, err := r.client.SetNX(ctx, redisKey, executionID, ttl).Result()
+		if err != nil {
+			return false, "", fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if claimed {
+			return true, executionID, nil
+		}
+
+		existing, err := r.client.Get(ctx, redisKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return false, "", fmt.Errorf("failed to read idempotency key: %w", err)
+		}
+		return false, existing, nil
+	}
+
+	return false, "", fmt.Errorf("idempotency key %s is contended", key)
+}
+
+// releaseIdempotencyScript deletes KEYS[1] only while it still holds ARGV[1], so a
+// release can't remove a claim another execution made after this one expired
+var releaseIdempotencyScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseIdempotencyKey drops a claim whose execution never started, so the request
+// can be submitted again. A key held by another execution is left alone.
+func (r *RedisStateManager) ReleaseIdempotencyKey(ctx context.Context, key, executionID string) error {
+	if err := releaseIdempotencyScript.Run(ctx, r.client, []string{r.idempotencyKey(key)}, executionID).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ClaimRecovery takes the right to recover an execution for ttl. Only one instance's
+// recovery loop gets it, so an orphaned execution is not started again twice.
+func (r *RedisStateManager) ClaimRecovery(ctx context.Context, executionID string, ttl time.Duration) (bool, error) {
+	claimed, err := r.client.SetNX(ctx, r.recoveryKey(executionID), time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim recovery: %w", err)
+	}
+	return claimed, nil
+}
+
+// TouchHeartbeat marks an execution as actively worked on for the next ttl
+func (r *RedisStateManager) TouchHeartbeat(ctx context.Context, executionID string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, r.heartbeatKey(executionID), time.Now().Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to touch heartbeat: %w", err)
+	}
+	return nil
+}
+
+// ClearHeartbeat removes an execution's heartbeat once it stops running
+func (r *RedisStateManager) ClearHeartbeat(ctx context.Context, executionID string) error {
+	if err := r.client.Del(ctx, r.heartbeatKey(executionID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear heartbeat: %w", err)
+	}
+	return nil
+}
+
+// HeartbeatAlive reports whether an execution's heartbeat has not yet expired
+func (r *RedisStateManager) HeartbeatAlive(ctx context.Context, executionID string) (bool, error) {
+	exists, err := r.client.Exists(ctx, r.heartbeatKey(executionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check heartbeat: %w", err)
+	}
+	return exists > 0, nil
+}
+
 // Key generation methods
 
 func (r *RedisStateManager) executionKey(executionID string) string {
 	return fmt.Sprintf("%s:execution:%s", r.keyPrefix, executionID)
 }
 
+func (r *RedisStateManager) definitionKey(executionID string) string {
+	return fmt.Sprintf("%s:definition:%s", r.keyPrefix, executionID)
+}
+
 func (r *RedisStateManager) activeExecutionsKey() string {
 	return fmt.Sprintf("%s:active", r.keyPrefix)
 }
@@ -277,6 +530,26 @@ func (r *RedisStateManager) executionIndexKey() string {
 	return fmt.Sprintf("%s:index", r.keyPrefix)
 }
 
+func (r *RedisStateManager) matrixKey(matrixID string) string {
+	return fmt.Sprintf("%s:matrix:%s", r.keyPrefix, matrixID)
+}
+
+func (r *RedisStateManager) resultCacheKey(key string) string {
+	return fmt.Sprintf("%s:result_cache:%s", r.keyPrefix, key)
+}
+
+func (r *RedisStateManager) idempotencyKey(key string) string {
+	return fmt.Sprintf("%s:idempotency:%s", r.keyPrefix, key)
+}
+
+func (r *RedisStateManager) heartbeatKey(executionID string) string {
+	return fmt.Sprintf("%s:heartbeat:%s", r.keyPrefix, executionID)
+}
+
+func (r *RedisStateManager) recoveryKey(executionID string) string {
+	return fmt.Sprintf("%s:recovery:%s", r.keyPrefix, executionID)
+}
+
 func (r *RedisStateManager) taskCheckpointKey(executionID, taskID string) string {
 	return fmt.Sprintf("%s:checkpoint:%s:%s", r.keyPrefix, executionID, taskID)
 }
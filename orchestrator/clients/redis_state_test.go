@@ -0,0 +1,307 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orchestrator/config"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisStateManagerNamespacesAreIsolated(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	staging := NewRedisStateManager(client, config.Namespaced("staging", "orchestrator"), time.Hour)
+	production := NewRedisStateManager(client, config.Namespaced("production", "orchestrator"), time.Hour)
+
+	execution := &models.WorkflowExecution{
+		ID:         "exec-1",
+		WorkflowID: "wf",
+		Status:     models.StatusRunning,
+		TaskStates: map[string]*models.TaskState{},
+		Variables:  map[string]interface{}{},
+		Metadata:   map[string]interface{}{},
+	}
+	if err := staging.SaveExecution(ctx, execution); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := staging.LoadExecution(ctx, "exec-1"); err != nil {
+		t.Fatalf("expected the execution in its own namespace: %v", err)
+	}
+	if _, err := production.LoadExecution(ctx, "exec-1"); err == nil {
+		t.Fatal("expected another namespace not to see the execution")
+	}
+
+	active, err := production.ListActiveExecutions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 0 {
+		t.Fatalf("expected no active executions in another namespace, got %v", active)
+	}
+}
+
+func TestRedisStateManagerMatrixGroup(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx := context.Background()
+	states := NewRedisStateManager(client, "test", time.Hour)
+
+	ids := []string{"exec_m_0", "exec_m_1", "exec_m_2"}
+	if err := states.SaveMatrixGroup(ctx, "m", ids); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := states.LoadMatrixGroup(ctx, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(ids) {
+		t.Fatalf("got %v, want %v", loaded, ids)
+	}
+	for i := range ids {
+		if loaded[i] != ids[i] {
+			t.Fatalf("got %v, want %v in launch order", loaded, ids)
+		}
+	}
+
+	if _, err := states.LoadMatrixGroup(ctx, "missing"); err == nil {
+		t.Error("expected an unknown matrix group to fail")
+	}
+}
+
+func TestRedisStateManagerHeartbeatExpires(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+	states := NewRedisStateManager(client, "test", time.Hour)
+
+	if err := states.TouchHeartbeat(ctx, "exec-1", 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(60 * time.Second)
+	if alive, err := states.HeartbeatAlive(ctx, "exec-1"); err != nil || !alive {
+		t.Fatalf("expected a heartbeat within its TTL to be alive, got %v, %v", alive, err)
+	}
+
+	server.FastForward(31 * time.Second)
+	if alive, err := states.HeartbeatAlive(ctx, "exec-1"); err != nil || alive {
+		t.Fatalf("expected the heartbeat to expire after its TTL, got %v, %v", alive, err)
+	}
+
+	if err := states.TouchHeartbeat(ctx, "exec-1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := states.ClearHeartbeat(ctx, "exec-1"); err != nil {
+		t.Fatal(err)
+	}
+	if alive, _ := states.HeartbeatAlive(ctx, "exec-1"); alive {
+		t.Error("expected a cleared heartbeat to be gone")
+	}
+}
+
+func TestClaimIdempotencyKeyConcurrentDuplicatesShareOneExecution(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+
+	const submissions = 20
+	var wg sync.WaitGroup
+	claimed := make([]bool, submissions)
+	holders := make([]string, submissions)
+	errs := make([]error, submissions)
+	for i := 0; i < submissions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			claimed[i], holders[i], errs[i] = states.ClaimIdempotencyKey(context.Background(), "order-42", fmt.Sprintf("exec-%d", i), time.Minute)
+		}(i)
+	}
+	wg.Wait()
+
+	winners := 0
+	for i := 0; i < submissions; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if claimed[i] {
+			winners++
+		}
+		if holders[i] != holders[0] {
+			t.Errorf("submission %d maps to %s, submission 0 to %s", i, holders[i], holders[0])
+		}
+	}
+	if winners != 1 {
+		t.Errorf("got %d submissions starting an execution, want 1", winners)
+	}
+}
+
+func TestReleaseIdempotencyKeyLeavesAnotherClaimAlone(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+	ctx := context.Background()
+
+	if claimed, _, err := states.ClaimIdempotencyKey(ctx, "order-42", "exec-a", time.Minute); err != nil || !claimed {
+		t.Fatalf("got claimed=%v err=%v, want the first claim to win", claimed, err)
+	}
+
+	// exec-a's claim expires and exec-b claims the key before exec-a releases it
+	server.FastForward(2 * time.Minute)
+	if claimed, _, err := states.ClaimIdempotencyKey(ctx, "order-42", "exec-b", time.Minute); err != nil || !claimed {
+		t.Fatalf("got claimed=%v err=%v, want the key free after expiry", claimed, err)
+	}
+
+	if err := states.ReleaseIdempotencyKey(ctx, "order-42", "exec-a"); err != nil {
+		t.Fatal(err)
+	}
+	_, holder, err := states.ClaimIdempotencyKey(ctx, "order-42", "exec-c", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder != "exec-b" {
+		t.Errorf("got key held by %q, want exec-b's claim kept", holder)
+	}
+
+	if err := states.ReleaseIdempotencyKey(ctx, "order-42", "exec-b"); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, _, err := states.ClaimIdempotencyKey(ctx, "order-42", "exec-c", time.Minute); err != nil || !claimed {
+		t.Errorf("got claimed=%v err=%v, want the key free after its holder released it", claimed, err)
+	}
+}
+
+func TestTaskResultCacheExpires(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	states := NewRedisStateManager(client, "test", time.Hour)
+	ctx := context.Background()
+
+	if _, found, err := states.LoadTaskResult(ctx, "abc123"); err != nil || found {
+		t.Fatalf("got found=%v err=%v, want a miss on an empty cache", found, err)
+	}
+	if err := states.SaveTaskResult(ctx, "abc123", map[string]interface{}{"rows": 3.0}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	output, found, err := states.LoadTaskResult(ctx, "abc123")
+	if err != nil || !found || output["rows"] != 3.0 {
+		t.Fatalf("got %v, found=%v, err=%v, want the saved output", output, found, err)
+	}
+
+	server.FastForward(2 * time.Minute)
+	if _, found, err := states.LoadTaskResult(ctx, "abc123"); err != nil || found {
+		t.Errorf("got found=%v err=%v, want the entry expired", found, err)
+	}
+}
+
+func TestRedisStateManagerKeepsDefinitionAcrossRestart(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	workflow := &models.WorkflowDefinition{ID: "report", Tasks: []models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}},
+		{ID: "summarize", Type: "ai", DependsOn: []string{"fetch"}},
+	}}
+	execution := &models.WorkflowExecution{
+		ID:         "exec-1",
+		WorkflowID: "report",
+		Status:     models.StatusRunning,
+		TaskStates: map[string]*models.TaskState{},
+		Variables:  map[string]interface{}{},
+		Metadata:   map[string]interface{}{},
+		Workflow:   workflow,
+		Request:    &models.WorkflowRequest{WorkflowTemplate: "report", CorrelationID: "corr-1"},
+	}
+	before := NewRedisStateManager(client, "test", time.Hour)
+	if err := before.SaveExecution(ctx, execution); err != nil {
+		t.Fatal(err)
+	}
+	// Progress saves don't rewrite the definition
+	execution.TaskStates["fetch"] = &models.TaskState{ID: "fetch", Status: models.StatusCompleted}
+	if err := before.SaveExecution(ctx, execution); err != nil {
+		t.Fatal(err)
+	}
+
+	// The hot record holds task progress only
+	if !server.Exists(before.definitionKey("exec-1")) {
+		t.Fatal("expected the definition under its own key")
+	}
+	data, err := client.Get(ctx, before.executionKey("exec-1")).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(data, "summarize") {
+		t.Error("expected the definition to be kept out of the execution record")
+	}
+
+	after := NewRedisStateManager(client, "test", time.Hour)
+	loaded, err := after.LoadExecution(ctx, "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Workflow == nil || !reflect.DeepEqual(loaded.Workflow.Tasks, workflow.Tasks) {
+		t.Fatalf("got definition %+v, want the full task list", loaded.Workflow)
+	}
+	if loaded.Request == nil || loaded.Request.CorrelationID != "corr-1" {
+		t.Errorf("got request %+v, want the original", loaded.Request)
+	}
+	if loaded.TaskStates["fetch"].Status != models.StatusCompleted {
+		t.Errorf("got fetch %s, want the saved progress", loaded.TaskStates["fetch"].Status)
+	}
+
+	// The definition lives as long as the record
+	server.FastForward(30 * time.Minute)
+	if err := after.SaveExecution(ctx, loaded); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(45 * time.Minute)
+	if reloaded, err := after.LoadExecution(ctx, "exec-1"); err != nil || reloaded.Workflow == nil {
+		t.Errorf("got %v, %v, want the definition kept alive with the record", reloaded, err)
+	}
+}
+
+func TestRedisStateManagerLoadTaskState(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx := context.Background()
+	states := NewRedisStateManager(client, "test", time.Hour)
+
+	execution := &models.WorkflowExecution{
+		ID:     "exec-1",
+		Status: models.StatusRunning,
+		TaskStates: map[string]*models.TaskState{
+			"fetch":     {ID: "fetch", Status: models.StatusCompleted, RetryCount: 1, Output: map[string]interface{}{"rows": float64(3)}},
+			"summarize": {ID: "summarize", Status: models.StatusPending, Output: map[string]interface{}{"text": "large output"}},
+		},
+	}
+	if err := states.SaveExecution(ctx, execution); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := states.LoadTaskState(ctx, "exec-1", "fetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "fetch" || state.Status != models.StatusCompleted || state.RetryCount != 1 || state.Output["rows"] != float64(3) {
+		t.Errorf("got %+v, want the stored fetch state", state)
+	}
+
+	if state, err := states.LoadTaskState(ctx, "exec-1", "missing"); state != nil || err != nil {
+		t.Errorf("got %+v, %v, want no state for a task not in the execution", state, err)
+	}
+	if _, err := states.LoadTaskState(ctx, "unknown", "fetch"); !errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("got %v, want ErrExecutionNotFound", err)
+	}
+
+	// A stored entry that can't be decoded is an error, but not a missing execution
+	server.Set(states.executionKey("exec-2"), `{"id": "exec-2", "task_states": {"fetch": {"status": 7}}}`)
+	if _, err := states.LoadTaskState(ctx, "exec-2", "fetch"); err == nil || errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("got %v, want a decode error", err)
+	}
+
+	server.Close()
+	if _, err := states.LoadTaskState(ctx, "exec-1", "fetch"); err == nil || errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("got %v with Redis down, want an error other than not found", err)
+	}
+}
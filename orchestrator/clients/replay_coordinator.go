@@ -0,0 +1,109 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReplayCoordinator wraps a message coordinator to record service exchanges of
+// executions started with record mode, and to answer executions started in replay mode
+// from a recording instead of calling the services. Requests made outside a task, or
+// for executions in neither mode, pass straight through.
+type ReplayCoordinator struct {
+	inner  engine.MessageCoordinator
+	store  *FileArtifactStore
+	logger *logrus.Logger
+}
+
+// NewReplayCoordinator creates a record/replay wrapper storing recordings in the artifact store
+func NewReplayCoordinator(inner engine.MessageCoordinator, store *FileArtifactStore) *ReplayCoordinator {
+	return &ReplayCoordinator{
+		inner:  inner,
+		store:  store,
+		logger: logrus.New(),
+	}
+}
+
+// SendDataRequest sends, records or replays a data service request
+func (rc *ReplayCoordinator) SendDataRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return rc.send(ctx, request, rc.inner.SendDataRequest)
+}
+
+// SendAIRequest sends, records or replays an AI service request
+func (rc *ReplayCoordinator) SendAIRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return rc.send(ctx, request, rc.inner.SendAIRequest)
+}
+
+// SendExecRequest sends, records or replays an exec service request
+func (rc *ReplayCoordinator) SendExecRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return rc.send(ctx, request, rc.inner.SendExecRequest)
+}
+
+func (rc *ReplayCoordinator) send(ctx context.Context, request *models.ServiceRequest, sendFn func(context.Context, *models.ServiceRequest) (*models.ServiceResponse, error)) (*models.ServiceResponse, error) {
+	call, ok := engine.TaskCallFromContext(ctx)
+	if !ok {
+		return sendFn(ctx, request)
+	}
+
+	if call.ReplayFrom != "" {
+		return rc.replay(call, request)
+	}
+
+	response, err := sendFn(ctx, request)
+	if call.Record {
+		exchange := &models.ServiceExchange{
+			TaskID:     call.TaskID,
+			Attempt:    call.Attempt,
+			Service:    request.Service,
+			Operation:  request.Operation,
+			Request:    call.RedactRequest(request),
+			Response:   response,
+			RecordedAt: time.Now(),
+		}
+		if err != nil {
+			exchange.Error = err.Error()
+		}
+		if recordErr := rc.store.AppendServiceExchange(call.ExecutionID, exchange); recordErr != nil {
+			rc.logger.WithError(recordErr).WithFields(logrus.Fields{
+				"execution_id": call.ExecutionID,
+				"task_id":      call.TaskID,
+			}).Warn("Failed to record service exchange")
+		}
+	}
+
+	return response, err
+}
+
+// replay returns the response recorded for the same task, attempt and service
+func (rc *ReplayCoordinator) replay(call engine.TaskCall, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	exchanges, err := rc.store.LoadServiceExchanges(call.ReplayFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exchange := range exchanges {
+		if exchange.TaskID != call.TaskID || exchange.Attempt != call.Attempt || exchange.Service != request.Service {
+			continue
+		}
+
+		rc.logger.WithFields(logrus.Fields{
+			"execution_id": call.ExecutionID,
+			"replay_of":    call.ReplayFrom,
+			"task_id":      call.TaskID,
+			"attempt":      call.Attempt,
+		}).Debug("Replaying recorded service response")
+
+		if exchange.Error != "" {
+			return nil, errors.New(exchange.Error)
+		}
+		return exchange.Response, nil
+	}
+
+	return nil, fmt.Errorf("no recorded %s response for task %s attempt %d in execution %s", request.Service, call.TaskID, call.Attempt, call.ReplayFrom)
+}
@@ -0,0 +1,171 @@
+package clients
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"orchestrator/engine"
+	"orchestrator/models"
+)
+
+// coordinatorFunc answers every service request with the same function
+type coordinatorFunc func(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error)
+
+func (f coordinatorFunc) SendDataRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f(ctx, request)
+}
+
+func (f coordinatorFunc) SendAIRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f(ctx, request)
+}
+
+func (f coordinatorFunc) SendExecRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f(ctx, request)
+}
+
+func TestReplayCoordinatorDoesNotRecordResolvedSecrets(t *testing.T) {
+	const secret = "sk-live-3f9a7c"
+	secretsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(secretsDir, "openai-key"), []byte(secret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var dispatched string
+	store := NewFileArtifactStore(t.TempDir())
+	coordinator := NewReplayCoordinator(coordinatorFunc(func(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		dispatched, _ = request.Parameters["api_key"].(string)
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"text": "ok"}}, nil
+	}), store)
+
+	taskExecutor := taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		_, err := coordinator.SendAIRequest(ctx, &models.ServiceRequest{
+			Service:    "ai",
+			Operation:  "generate",
+			Parameters: task.Parameters,
+		})
+		return err
+	})
+	executor := engine.NewWorkflowExecutor(taskExecutor, newMemoryStateManager(), coordinator, 1)
+	executor.SetSecretProvider(NewFileSecretProvider(secretsDir))
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{
+		ID:   "summarize",
+		Type: "ai",
+		Parameters: map[string]interface{}{
+			"prompt":  "summarize",
+			"api_key": map[string]interface{}{engine.SecretRefKey: "openai-key"},
+		},
+	}}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{Record: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dispatched != secret {
+		t.Fatalf("got dispatched api_key %q, want the resolved secret", dispatched)
+	}
+
+	exchanges, err := store.LoadServiceExchanges(response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 1 {
+		t.Fatalf("got %d recorded exchanges, want 1", len(exchanges))
+	}
+	if exchanges[0].Request.Parameters["prompt"] != "summarize" {
+		t.Errorf("got recorded parameters %v, want the non-secret ones kept", exchanges[0].Request.Parameters)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.rootDir, "executions", response.ExecutionID, "recording.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Errorf("recording contains the secret value: %s", data)
+	}
+}
+
+func TestReplayCoordinatorReplaysRecordedResponses(t *testing.T) {
+	store := NewFileArtifactStore(t.TempDir())
+	calls := 0
+	live := coordinatorFunc(func(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		calls++
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"text": "live answer"}}, nil
+	})
+	coordinator := NewReplayCoordinator(live, store)
+
+	var answers []interface{}
+	taskExecutor := taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		response, err := coordinator.SendAIRequest(ctx, &models.ServiceRequest{Service: "ai", Operation: "generate"})
+		if err != nil {
+			return err
+		}
+		answers = append(answers, response.Data["text"])
+		return nil
+	})
+	executor := engine.NewWorkflowExecutor(taskExecutor, newMemoryStateManager(), coordinator, 1)
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{
+		ID:         "summarize",
+		Type:       "ai",
+		Parameters: map[string]interface{}{"prompt": "summarize"},
+	}}}
+
+	recorded, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{Record: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ReplayFrom: recorded.ExecutionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Status != models.StatusCompleted {
+		t.Fatalf("got replay status %s, want completed", replayed.Status)
+	}
+	if calls != 1 {
+		t.Errorf("got %d live service calls, want only the recorded run to reach the service", calls)
+	}
+	if len(answers) != 2 || answers[0] != answers[1] {
+		t.Errorf("got answers %v, want the replay to return the recorded response", answers)
+	}
+}
+
+func TestReplayCoordinatorFailsWithoutRecordedResponse(t *testing.T) {
+	store := NewFileArtifactStore(t.TempDir())
+	coordinator := NewReplayCoordinator(coordinatorFunc(func(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		t.Error("expected a replayed request not to reach the service")
+		return nil, nil
+	}), store)
+
+	if err := store.AppendServiceExchange("exec-recorded", &models.ServiceExchange{
+		TaskID:   "summarize",
+		Attempt:  0,
+		Service:  "ai",
+		Response: &models.ServiceResponse{Success: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		call engine.TaskCall
+	}{
+		{"other task", engine.TaskCall{TaskID: "fetch", Attempt: 0, ReplayFrom: "exec-recorded"}},
+		{"other attempt", engine.TaskCall{TaskID: "summarize", Attempt: 1, ReplayFrom: "exec-recorded"}},
+		{"missing recording", engine.TaskCall{TaskID: "summarize", Attempt: 0, ReplayFrom: "exec-missing"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := engine.WithTaskCall(context.Background(), tc.call)
+			if _, err := coordinator.SendAIRequest(ctx, &models.ServiceRequest{Service: "ai"}); err == nil {
+				t.Error("expected an error without a matching recorded response")
+			}
+		})
+	}
+
+	ctx := engine.WithTaskCall(context.Background(), engine.TaskCall{TaskID: "summarize", Attempt: 0, ReplayFrom: "exec-recorded"})
+	if response, err := coordinator.SendAIRequest(ctx, &models.ServiceRequest{Service: "ai"}); err != nil || !response.Success {
+		t.Errorf("got %+v, %v, want the recorded response", response, err)
+	}
+}
@@ -0,0 +1,173 @@
+package clients
+
+import (
+	"orchestrator/models"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// waiterReapInterval is how often lingering response waiters are looked for
+	waiterReapInterval = 30 * time.Second
+	// waiterReapGrace leaves the waiting caller time to clean up after its own timeout
+	waiterReapGrace = 5 * time.Second
+)
+
+// responseWaiter is a caller waiting for responses to one correlation ID
+type responseWaiter struct {
+	ch        chan *models.ServiceResponse
+	createdAt time.Time
+	deadline  time.Time
+	closeOnce sync.Once
+}
+
+// close closes the channel once, whoever removes the waiter first
+func (w *responseWaiter) close() {
+	w.closeOnce.Do(func() { close(w.ch) })
+}
+
+// waiterMetrics counts how waits ended
+type waiterMetrics struct {
+	delivered   uint64 // responses handed to a waiting caller
+	timedOut    uint64 // waits that hit their timeout
+	cancelled   uint64 // waits whose context was cancelled
+	undelivered uint64 // responses dropped because the waiter's buffer was full
+	unmatched   uint64 // responses with no waiter, usually late replies
+	reclaimed   uint64 // waiters removed by the reaper after outliving their deadline
+}
+
+// addWaiter registers a waiter for a correlation ID that expires after timeout
+func (mc *RedisMessageCoordinator) addWaiter(correlationID string, buffer int, timeout time.Duration) *responseWaiter {
+	now := time.Now()
+	waiter := &responseWaiter{
+		ch:        make(chan *models.ServiceResponse, buffer),
+		createdAt: now,
+		deadline:  now.Add(timeout),
+	}
+
+	mc.mutex.Lock()
+	mc.responseWaiters[correlationID] = waiter
+	mc.mutex.Unlock()
+
+	return waiter
+}
+
+// removeWaiter unregisters a waiter unless it was already replaced or reclaimed
+func (mc *RedisMessageCoordinator) removeWaiter(correlationID string, waiter *responseWaiter) {
+	mc.mutex.Lock()
+	if mc.responseWaiters[correlationID] == waiter {
+		delete(mc.responseWaiters, correlationID)
+	}
+	mc.mutex.Unlock()
+	waiter.close()
+}
+
+// deliverResponse hands a response to its waiter. The read lock is held across the
+// non-blocking send so the waiter cannot be closed mid-delivery. It returns the dead-letter
+// reason when the response could not be delivered, or "" on success.
+func (mc *RedisMessageCoordinator) deliverResponse(response *models.ServiceResponse) string {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	waiter, exists := mc.responseWaiters[response.CorrelationID]
+	if !exists {
+		// No waiter for this response - might be expired
+		atomic.AddUint64(&mc.waiterMetrics.unmatched, 1)
+		mc.logger.WithField("correlation_id", response.CorrelationID).Debug("Received response with no waiting caller")
+		return DeadLetterNoWaiter
+	}
+
+	select {
+	case waiter.ch <- response:
+		atomic.AddUint64(&mc.waiterMetrics.delivered, 1)
+		return ""
+	default:
+		atomic.AddUint64(&mc.waiterMetrics.undelivered, 1)
+		mc.logger.WithField("correlation_id", response.CorrelationID).Warn("Failed to deliver response - channel full")
+		return DeadLetterChannelFull
+	}
+}
+
+// waiterReaper periodically reclaims waiters whose caller never removed them
+func (mc *RedisMessageCoordinator) waiterReaper() {
+	ticker := time.NewTicker(waiterReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mc.reapWaiters(time.Now())
+		case <-mc.stopChan:
+			return
+		}
+	}
+}
+
+// reapWaiters removes waiters more than the grace period past their deadline and returns
+// how many were reclaimed
+func (mc *RedisMessageCoordinator) reapWaiters(now time.Time) int {
+	mc.mutex.Lock()
+	var stale []string
+	for correlationID, waiter := range mc.responseWaiters {
+		if now.After(waiter.deadline.Add(waiterReapGrace)) {
+			delete(mc.responseWaiters, correlationID)
+			waiter.close()
+			stale = append(stale, correlationID)
+		}
+	}
+	mc.mutex.Unlock()
+
+	if len(stale) > 0 {
+		atomic.AddUint64(&mc.waiterMetrics.reclaimed, uint64(len(stale)))
+		mc.logger.WithFields(logrus.Fields{
+			"count":           len(stale),
+			"correlation_ids": stale,
+		}).Warn("Reclaimed orphaned response waiters")
+	}
+
+	return len(stale)
+}
+
+// waiterStats reports outcome counters and the age distribution of current waiters
+func (mc *RedisMessageCoordinator) waiterStats(now time.Time) map[string]interface{} {
+	ages := map[string]int{
+		"under_10s": 0,
+		"10s_to_1m": 0,
+		"1m_to_5m":  0,
+		"over_5m":   0,
+	}
+	var oldest time.Duration
+
+	mc.mutex.RLock()
+	for _, waiter := range mc.responseWaiters {
+		age := now.Sub(waiter.createdAt)
+		if age > oldest {
+			oldest = age
+		}
+		switch {
+		case age < 10*time.Second:
+			ages["under_10s"]++
+		case age < time.Minute:
+			ages["10s_to_1m"]++
+		case age < 5*time.Minute:
+			ages["1m_to_5m"]++
+		default:
+			ages["over_5m"]++
+		}
+	}
+	mc.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"delivered":          atomic.LoadUint64(&mc.waiterMetrics.delivered),
+		"timed_out":          atomic.LoadUint64(&mc.waiterMetrics.timedOut),
+		"cancelled":          atomic.LoadUint64(&mc.waiterMetrics.cancelled),
+		"undelivered":        atomic.LoadUint64(&mc.waiterMetrics.undelivered),
+		"unmatched":          atomic.LoadUint64(&mc.waiterMetrics.unmatched),
+		"reclaimed":          atomic.LoadUint64(&mc.waiterMetrics.reclaimed),
+		"oldest_age_seconds": oldest.Seconds(),
+		"age_distribution":   ages,
+	}
+}
@@ -0,0 +1,90 @@
+package clients
+
+import (
+	"orchestrator/models"
+	"testing"
+	"time"
+)
+
+func TestReapWaitersReclaimsOnlyOrphanedWaiters(t *testing.T) {
+	mc := newTestCoordinator(nil)
+	orphaned := mc.addWaiter("orphaned", 1, time.Second)
+	waiting := mc.addWaiter("waiting", 1, time.Minute)
+
+	// Within the grace period the caller may still be cleaning up after its own timeout
+	if reclaimed := mc.reapWaiters(orphaned.deadline.Add(waiterReapGrace)); reclaimed != 0 {
+		t.Fatalf("reclaimed %d waiters within the grace period", reclaimed)
+	}
+
+	if reclaimed := mc.reapWaiters(orphaned.deadline.Add(waiterReapGrace + time.Millisecond)); reclaimed != 1 {
+		t.Fatalf("reclaimed %d waiters, want the orphaned one", reclaimed)
+	}
+	if _, open := <-orphaned.ch; open {
+		t.Error("expected the reclaimed waiter's channel to be closed")
+	}
+	if _, exists := mc.responseWaiters["waiting"]; !exists {
+		t.Error("expected the waiter still within its deadline to be kept")
+	}
+
+	// The original caller returning later must neither panic nor remove a newer waiter
+	replacement := mc.addWaiter("orphaned", 1, time.Minute)
+	mc.removeWaiter("orphaned", orphaned)
+	if mc.responseWaiters["orphaned"] != replacement {
+		t.Error("expected the newer waiter for the correlation ID to be kept")
+	}
+
+	mc.removeWaiter("waiting", waiting)
+	mc.removeWaiter("orphaned", replacement)
+	if stats := mc.waiterStats(time.Now()); stats["reclaimed"] != uint64(1) {
+		t.Errorf("got %v reclaimed, want 1", stats["reclaimed"])
+	}
+}
+
+func TestWaiterStatsCountDeliveryOutcomes(t *testing.T) {
+	mc := newTestCoordinator(nil)
+	waiter := mc.addWaiter("request-1", 1, time.Minute)
+	defer mc.removeWaiter("request-1", waiter)
+
+	response := &models.ServiceResponse{CorrelationID: "request-1"}
+	if reason := mc.deliverResponse(response); reason != "" {
+		t.Fatalf("expected the response to be delivered, got %s", reason)
+	}
+	if reason := mc.deliverResponse(response); reason != DeadLetterChannelFull {
+		t.Errorf("got %q for a second response to a full waiter, want %s", reason, DeadLetterChannelFull)
+	}
+	if reason := mc.deliverResponse(&models.ServiceResponse{CorrelationID: "late"}); reason != DeadLetterNoWaiter {
+		t.Errorf("got %q for a response nobody waits for, want %s", reason, DeadLetterNoWaiter)
+	}
+
+	stats := mc.waiterStats(time.Now())
+	for name, want := range map[string]uint64{"delivered": 1, "undelivered": 1, "unmatched": 1, "reclaimed": 0} {
+		if stats[name] != want {
+			t.Errorf("got %v %s, want %d", stats[name], name, want)
+		}
+	}
+}
+
+func TestWaiterStatsReportAgeDistribution(t *testing.T) {
+	mc := newTestCoordinator(nil)
+	now := time.Now()
+	for id, age := range map[string]time.Duration{
+		"new":    time.Second,
+		"recent": 30 * time.Second,
+		"slow":   2 * time.Minute,
+		"stuck":  10 * time.Minute,
+	} {
+		waiter := mc.addWaiter(id, 1, time.Hour)
+		waiter.createdAt = now.Add(-age)
+	}
+
+	stats := mc.waiterStats(now)
+	ages := stats["age_distribution"].(map[string]int)
+	for bucket, want := range map[string]int{"under_10s": 1, "10s_to_1m": 1, "1m_to_5m": 1, "over_5m": 1} {
+		if ages[bucket] != want {
+			t.Errorf("got %d waiters %s, want %d", ages[bucket], bucket, want)
+		}
+	}
+	if oldest := stats["oldest_age_seconds"]; oldest != (10 * time.Minute).Seconds() {
+		t.Errorf("got oldest age %v, want 600s", oldest)
+	}
+}
@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// secretNamePattern keeps secret names from escaping the secrets directory
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// FileSecretProvider resolves secrets from files in a directory, as mounted by Docker
+// and Kubernetes secrets, falling back to SECRET_<NAME> environment variables
+type FileSecretProvider struct {
+	dir string
+}
+
+// NewFileSecretProvider creates a provider reading from dir; an empty dir only uses the
+// environment
+func NewFileSecretProvider(dir string) *FileSecretProvider {
+	return &FileSecretProvider{dir: dir}
+}
+
+// GetSecret returns the named secret. File contents are trimmed of surrounding whitespace.
+func (p *FileSecretProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	if !secretNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	if p.dir != "" {
+		data, err := os.ReadFile(filepath.Join(p.dir, name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+		}
+	}
+
+	envName := "SECRET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	if value, exists := os.LookupEnv(envName); exists {
+		return value, nil
+	}
+
+	return "", fmt.Errorf("secret %s not found", name)
+}
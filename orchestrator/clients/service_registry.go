@@ -2,8 +2,12 @@ package clients
 
 import (
 	"context"
+	"crypto/hmac"
 	"encoding/json"
 	"fmt"
+	"orchestrator/capabilities"
+	"orchestrator/config"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,23 +17,61 @@ import (
 
 // ServiceRegistry collects and manages capability announcements from services
 type ServiceRegistry struct {
-	redisClient      *redis.Client
-	capabilities     map[string]*ServiceCapability
-	lastSeen         map[string]time.Time
-	mutex            sync.RWMutex
-	logger           *logrus.Logger
-	subscriber       *redis.PubSub
-	stopChan         chan struct{}
-	staleThreshold   time.Duration
+	redisClient         *redis.Client
+	capabilities        map[string]*ServiceCapability
+	lastSeen            map[string]time.Time
+	mutex               sync.RWMutex
+	logger              *logrus.Entry
+	subscriber          *redis.PubSub
+	stopChan            chan struct{}
+	staleThreshold      time.Duration
+	announcementChannel string
+	refreshChannel      string
+	eventChannel        string
+	staleNotified       map[string]bool
+	eventSubscribers    map[chan ServiceLifecycleEvent]struct{}
+	subscribersMutex    sync.Mutex
+	verificationSecret  string
+	instances           map[string]map[string]*ServiceInstance // component -> instance ID
+	capabilityPrefix    string
+	persistMutex        sync.Mutex
+	persisting          sync.WaitGroup
+	persistedAt         map[string]time.Time // capability key/instance -> last written announcement
+}
+
+// ServiceLifecycleEvent describes a change in the set of registered services
+type ServiceLifecycleEvent struct {
+	Type            string    `json:"type"`
+	Component       string    `json:"component"`
+	Version         string    `json:"version,omitempty"`
+	PreviousVersion string    `json:"previous_version,omitempty"`
+	LastSeen        time.Time `json:"last_seen"`
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 // ServiceCapability represents a service's announced capabilities
 type ServiceCapability struct {
-	Component    string                 `json:"component"`
-	Timestamp    string                 `json:"timestamp"`
-	Trigger      string                 `json:"trigger"`
-	Capabilities *ServiceCapabilities   `json:"capabilities"`
-	LastUpdated  time.Time              `json:"last_updated"`
+	Component       string               `json:"component"`
+	Version         string               `json:"version,omitempty"`
+	Timestamp       string               `json:"timestamp"`
+	Trigger         string               `json:"trigger"`
+	Capabilities    *ServiceCapabilities `json:"capabilities"`
+	Signature       string               `json:"signature,omitempty"`
+	Instance        string               `json:"instance,omitempty"`
+	InstanceChannel string               `json:"instance_channel,omitempty"`
+	InstanceWeight  int                  `json:"instance_weight,omitempty"`
+	ServiceVersion  string               `json:"service_version,omitempty"` // release the replica runs; Version is the capability hash
+	LastUpdated     time.Time            `json:"last_updated"`
+}
+
+// ServiceInstance is a replica of a component that consumes its own request channel
+type ServiceInstance struct {
+	ID             string    `json:"id"`
+	Component      string    `json:"component"`
+	RequestChannel string    `json:"request_channel"`
+	Weight         int       `json:"weight"`
+	Version        string    `json:"version,omitempty"` // announced service version
+	LastSeen       time.Time `json:"last_seen"`
 }
 
 // ServiceCapabilities represents the complete capability information for a service
@@ -78,30 +120,79 @@ const (
 	DefaultStaleThreshold = 15 * time.Minute
 	AnnouncementChannel   = "service_capability_announcements"
 	RefreshRequestChannel = "capability_refresh_request"
+	LifecycleEventChannel = "service_lifecycle_events"
 )
 
-// NewServiceRegistry creates a new service registry
-func NewServiceRegistry(redisClient *redis.Client, staleThreshold time.Duration) *ServiceRegistry {
+// Service lifecycle event types
+const (
+	EventServiceAdded        = "service_added"
+	EventServiceRemoved      = "service_removed"
+	EventCapabilitiesChanged = "capabilities_changed"
+	EventServiceStale        = "service_stale"
+)
+
+// NewServiceRegistry creates a new service registry. The namespace, when set, is
+// prefixed onto the announcement and refresh channels to isolate environments.
+func NewServiceRegistry(redisClient *redis.Client, staleThreshold time.Duration, namespace string) *ServiceRegistry {
 	if staleThreshold == 0 {
 		staleThreshold = DefaultStaleThreshold
 	}
 
 	return &ServiceRegistry{
-		redisClient:    redisClient,
-		capabilities:   make(map[string]*ServiceCapability),
-		lastSeen:       make(map[string]time.Time),
-		logger:         logrus.WithField("component", "service_registry"),
-		staleThreshold: staleThreshold,
-		stopChan:       make(chan struct{}),
+		redisClient:         redisClient,
+		capabilities:        make(map[string]*ServiceCapability),
+		lastSeen:            make(map[string]time.Time),
+		logger:              logrus.WithField("component", "service_registry"),
+		staleThreshold:      staleThreshold,
+		stopChan:            make(chan struct{}),
+		announcementChannel: config.Namespaced(namespace, AnnouncementChannel),
+		refreshChannel:      config.Namespaced(namespace, RefreshRequestChannel),
+		eventChannel:        config.Namespaced(namespace, LifecycleEventChannel),
+		staleNotified:       make(map[string]bool),
+		eventSubscribers:    make(map[chan ServiceLifecycleEvent]struct{}),
+		instances:           make(map[string]map[string]*ServiceInstance),
+		capabilityPrefix:    config.Namespaced(namespace, capabilityKeyPrefix),
+		persistedAt:         make(map[string]time.Time),
+	}
+}
+
+// SetVerificationSecret requires announcements to carry a valid HMAC signature made with
+// the shared secret; unsigned or forged announcements are dropped
+func (sr *ServiceRegistry) SetVerificationSecret(secret string) {
+	sr.verificationSecret = secret
+}
+
+// verifyAnnouncement checks an announcement's signature when verification is enabled
+func (sr *ServiceRegistry) verifyAnnouncement(payload []byte, signature string) error {
+	if sr.verificationSecret == "" {
+		return nil
+	}
+	if signature == "" {
+		return fmt.Errorf("announcement is not signed")
 	}
+
+	expected, err := capabilities.SignAnnouncement(sr.verificationSecret, payload)
+	if err != nil {
+		return fmt.Errorf("failed to compute announcement signature: %w", err)
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("announcement signature is invalid")
+	}
+
+	return nil
 }
 
 // Start begins listening for capability announcements
 func (sr *ServiceRegistry) Start(ctx context.Context) error {
 	sr.logger.Info("Starting service registry")
 
+	// Know about running services immediately instead of waiting for them to announce
+	if err := sr.hydrate(ctx); err != nil {
+		sr.logger.WithError(err).Warn("Failed to load persisted service capabilities")
+	}
+
 	// Subscribe to capability announcements
-	sr.subscriber = sr.redisClient.Subscribe(ctx, AnnouncementChannel)
+	sr.subscriber = sr.redisClient.Subscribe(ctx, sr.announcementChannel)
 
 	// Start announcement listener
 	go sr.listenForAnnouncements(ctx)
@@ -125,6 +216,9 @@ func (sr *ServiceRegistry) Stop() {
 			sr.logger.WithError(err).Warn("Error closing capability subscriber")
 		}
 	}
+
+	// Let announcements already received reach Redis before the process exits
+	sr.persisting.Wait()
 }
 
 // listenForAnnouncements processes incoming capability announcements
@@ -153,7 +247,12 @@ func (sr *ServiceRegistry) listenForAnnouncements(ctx context.Context) {
 				continue
 			}
 
-			sr.updateCapability(&capability)
+			if err := sr.verifyAnnouncement([]byte(msg.Payload), capability.Signature); err != nil {
+				sr.logger.WithError(err).WithField("component", capability.Component).Warn("Dropping unverified capability announcement")
+				continue
+			}
+
+			sr.updateCapability(&capability, []byte(msg.Payload))
 
 		case <-sr.stopChan:
 			sr.logger.Info("Capability announcement listener stopped")
@@ -165,23 +264,73 @@ func (sr *ServiceRegistry) listenForAnnouncements(ctx context.Context) {
 	}
 }
 
-// updateCapability updates the capability information for a service
-func (sr *ServiceRegistry) updateCapability(capability *ServiceCapability) {
+// updateCapability updates the capability information for a service. The payload is the
+// announcement as received, persisted so its signature can be verified after a restart.
+func (sr *ServiceRegistry) updateCapability(capability *ServiceCapability, payload []byte) {
 	sr.mutex.Lock()
 	defer sr.mutex.Unlock()
 
+	previous, existed := sr.capabilities[capability.Component]
+	wasStale := sr.staleNotified[capability.Component]
+
 	capability.LastUpdated = time.Now()
 	sr.capabilities[capability.Component] = capability
 	sr.lastSeen[capability.Component] = capability.LastUpdated
+	delete(sr.staleNotified, capability.Component)
+	sr.updateInstance(capability)
+	sr.persistCapability(capability, payload)
+
+	event := ServiceLifecycleEvent{
+		Component: capability.Component,
+		Version:   capability.Version,
+		LastSeen:  capability.LastUpdated,
+	}
+	switch {
+	case !existed || wasStale:
+		event.Type = EventServiceAdded
+		sr.emitEvent(event)
+	case capabilitiesDiffer(previous, capability):
+		event.Type = EventCapabilitiesChanged
+		event.PreviousVersion = previous.Version
+		sr.emitEvent(event)
+	}
 
 	sr.logger.WithFields(logrus.Fields{
-		"component":     capability.Component,
-		"trigger":       capability.Trigger,
-		"operations":    len(capability.Capabilities.Operations),
-		"last_updated":  capability.LastUpdated,
+		"component":    capability.Component,
+		"version":      capability.Version,
+		"trigger":      capability.Trigger,
+		"operations":   len(capability.Capabilities.Operations),
+		"last_updated": capability.LastUpdated,
 	}).Info("Updated service capability")
 }
 
+// updateInstance records the announcing replica when it advertises its own request channel.
+// Callers must hold the registry mutex.
+func (sr *ServiceRegistry) updateInstance(capability *ServiceCapability) {
+	if capability.Instance == "" || capability.InstanceChannel == "" {
+		return
+	}
+
+	weight := capability.InstanceWeight
+	if weight <= 0 {
+		weight = 1
+	}
+
+	instances, exists := sr.instances[capability.Component]
+	if !exists {
+		instances = make(map[string]*ServiceInstance)
+		sr.instances[capability.Component] = instances
+	}
+	instances[capability.Instance] = &ServiceInstance{
+		ID:             capability.Instance,
+		Component:      capability.Component,
+		RequestChannel: capability.InstanceChannel,
+		Weight:         weight,
+		Version:        capability.ServiceVersion,
+		LastSeen:       capability.LastUpdated,
+	}
+}
+
 // cleanupStaleServices removes services that haven't announced capabilities recently
 func (sr *ServiceRegistry) cleanupStaleServices(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -201,7 +350,8 @@ func (sr *ServiceRegistry) cleanupStaleServices(ctx context.Context) {
 	}
 }
 
-// removeStaleServices removes services that haven't been seen recently
+// removeStaleServices flags services that haven't been seen within the stale threshold
+// and removes them once they have been silent for twice that long
 func (sr *ServiceRegistry) removeStaleServices() {
 	sr.mutex.Lock()
 	defer sr.mutex.Unlock()
@@ -209,12 +359,51 @@ func (sr *ServiceRegistry) removeStaleServices() {
 	now := time.Now()
 	removedCount := 0
 
+	for component, instances := range sr.instances {
+		for id, instance := range instances {
+			if now.Sub(instance.LastSeen) > 2*sr.staleThreshold {
+				delete(instances, id)
+			}
+		}
+		if len(instances) == 0 {
+			delete(sr.instances, component)
+		}
+	}
+
 	for component, lastSeen := range sr.lastSeen {
-		if now.Sub(lastSeen) > sr.staleThreshold {
+		age := now.Sub(lastSeen)
+		if age <= sr.staleThreshold {
+			continue
+		}
+
+		version := ""
+		if capability, exists := sr.capabilities[component]; exists {
+			version = capability.Version
+		}
+
+		if !sr.staleNotified[component] {
+			sr.staleNotified[component] = true
+			sr.emitEvent(ServiceLifecycleEvent{
+				Type:      EventServiceStale,
+				Component: component,
+				Version:   version,
+				LastSeen:  lastSeen,
+			})
+		}
+
+		if age > 2*sr.staleThreshold {
 			delete(sr.capabilities, component)
 			delete(sr.lastSeen, component)
+			delete(sr.staleNotified, component)
 			removedCount++
 
+			sr.emitEvent(ServiceLifecycleEvent{
+				Type:      EventServiceRemoved,
+				Component: component,
+				Version:   version,
+				LastSeen:  lastSeen,
+			})
+
 			sr.logger.WithFields(logrus.Fields{
 				"component": component,
 				"last_seen": lastSeen,
@@ -228,6 +417,72 @@ func (sr *ServiceRegistry) removeStaleServices() {
 	}
 }
 
+// capabilitiesDiffer reports whether an announcement changed a service's capabilities,
+// using the announced version when both sides carry one
+func capabilitiesDiffer(previous, current *ServiceCapability) bool {
+	if previous.Version != "" && current.Version != "" {
+		return previous.Version != current.Version
+	}
+
+	previousData, _ := json.Marshal(previous.Capabilities)
+	currentData, _ := json.Marshal(current.Capabilities)
+	return string(previousData) != string(currentData)
+}
+
+// emitEvent publishes a lifecycle event to Redis and to local subscribers. Delivery to
+// local subscribers never blocks; a subscriber that falls behind misses events.
+func (sr *ServiceRegistry) emitEvent(event ServiceLifecycleEvent) {
+	event.Timestamp = time.Now()
+
+	sr.logger.WithFields(logrus.Fields{
+		"event":     event.Type,
+		"component": event.Component,
+		"version":   event.Version,
+	}).Info("Service lifecycle event")
+
+	if data, err := json.Marshal(event); err != nil {
+		sr.logger.WithError(err).Error("Failed to marshal lifecycle event")
+	} else {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := sr.redisClient.Publish(ctx, sr.eventChannel, data).Err(); err != nil {
+				sr.logger.WithError(err).Warn("Failed to publish lifecycle event")
+			}
+		}()
+	}
+
+	sr.subscribersMutex.Lock()
+	defer sr.subscribersMutex.Unlock()
+
+	for subscriber := range sr.eventSubscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// SubscribeEvents returns a channel of lifecycle events and a function to unsubscribe
+func (sr *ServiceRegistry) SubscribeEvents() (<-chan ServiceLifecycleEvent, func()) {
+	subscriber := make(chan ServiceLifecycleEvent, 32)
+
+	sr.subscribersMutex.Lock()
+	sr.eventSubscribers[subscriber] = struct{}{}
+	sr.subscribersMutex.Unlock()
+
+	unsubscribe := func() {
+		sr.subscribersMutex.Lock()
+		defer sr.subscribersMutex.Unlock()
+		if _, exists := sr.eventSubscribers[subscriber]; exists {
+			delete(sr.eventSubscribers, subscriber)
+			close(subscriber)
+		}
+	}
+
+	return subscriber, unsubscribe
+}
+
 // GetServiceCapability returns the capability information for a specific service
 func (sr *ServiceRegistry) GetServiceCapability(component string) (*ServiceCapability, bool) {
 	sr.mutex.RLock()
@@ -246,6 +501,58 @@ func (sr *ServiceRegistry) GetServiceCapability(component string) (*ServiceCapab
 	return capability, true
 }
 
+// GetInstances returns the active replicas of a component that announced their own
+// request channel, ordered by instance ID
+func (sr *ServiceRegistry) GetInstances(component string) []ServiceInstance {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	var result []ServiceInstance
+	for _, instance := range sr.instances[component] {
+		if time.Since(instance.LastSeen) <= sr.staleThreshold {
+			result = append(result, *instance)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// GetServiceVersion returns the capability version currently announced by an active service
+func (sr *ServiceRegistry) GetServiceVersion(component string) (string, bool) {
+	capability, exists := sr.GetServiceCapability(component)
+	if !exists {
+		return "", false
+	}
+	return capability.Version, true
+}
+
+// GetServiceRelease returns the service version, as opposed to the capability version,
+// last announced by an active service. It is empty when the service announces none.
+func (sr *ServiceRegistry) GetServiceRelease(component string) (string, bool) {
+	capability, exists := sr.GetServiceCapability(component)
+	if !exists {
+		return "", false
+	}
+	return capability.ServiceVersion, true
+}
+
+// HasServiceRelease reports whether an active replica of a service runs the service
+// version
+func (sr *ServiceRegistry) HasServiceRelease(component, version string) bool {
+	if current, exists := sr.GetServiceRelease(component); exists && current == version {
+		return true
+	}
+	for _, instance := range sr.GetInstances(component) {
+		if instance.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAllActiveServices returns all currently active services
 func (sr *ServiceRegistry) GetAllActiveServices() map[string]*ServiceCapability {
 	sr.mutex.RLock()
@@ -334,6 +641,22 @@ func (sr *ServiceRegistry) GetOperationByName(operationName string) (*Operation,
 	return nil, "", false
 }
 
+// IsOperationRetrySafe reports whether an active service announced an operation as safe
+// to retry. known is false when the service or operation hasn't been announced.
+func (sr *ServiceRegistry) IsOperationRetrySafe(component, operation string) (safe bool, known bool) {
+	capability, exists := sr.GetServiceCapability(component)
+	if !exists || capability.Capabilities == nil {
+		return false, false
+	}
+
+	for _, op := range capability.Capabilities.Operations {
+		if op.Name == operation {
+			return op.RetrySafe, true
+		}
+	}
+	return false, false
+}
+
 // IsServiceAvailable checks if a specific service is currently available
 func (sr *ServiceRegistry) IsServiceAvailable(component string) bool {
 	sr.mutex.RLock()
@@ -364,7 +687,7 @@ func (sr *ServiceRegistry) requestCapabilityRefresh(ctx context.Context, targetC
 		return
 	}
 
-	if err := sr.redisClient.Publish(ctx, RefreshRequestChannel, data).Err(); err != nil {
+	if err := sr.redisClient.Publish(ctx, sr.refreshChannel, data).Err(); err != nil {
 		sr.logger.WithError(err).Error("Failed to publish refresh request")
 		return
 	}
@@ -0,0 +1,161 @@
+package clients
+
+import (
+	"context"
+	"orchestrator/capabilities"
+	"testing"
+	"time"
+)
+
+func TestNewServiceRegistryNamespacesChannels(t *testing.T) {
+	registry := NewServiceRegistry(nil, 0, "staging")
+
+	for _, tc := range []struct{ got, want string }{
+		{registry.announcementChannel, "staging:" + AnnouncementChannel},
+		{registry.refreshChannel, "staging:" + RefreshRequestChannel},
+		{registry.eventChannel, "staging:" + LifecycleEventChannel},
+		{registry.capabilityPrefix, "staging:" + capabilityKeyPrefix},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+
+	plain := NewServiceRegistry(nil, 0, "")
+	if plain.announcementChannel != AnnouncementChannel {
+		t.Errorf("got %q, want %q without a namespace", plain.announcementChannel, AnnouncementChannel)
+	}
+}
+
+func TestServiceRegistryNamespacesAreIsolated(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	staging := NewServiceRegistry(client, 0, "staging")
+	production := NewServiceRegistry(client, 0, "production")
+	for _, registry := range []*ServiceRegistry{staging, production} {
+		if err := registry.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+		defer registry.Stop()
+	}
+
+	manager := capabilities.NewCapabilityManager("data-abstractor", &capabilities.ServiceCapabilities{
+		Operations: []capabilities.Operation{{Name: "query"}},
+	}, client, time.Hour, "staging")
+	waitForSubscribers(t, server, staging.announcementChannel, production.announcementChannel)
+	if err := manager.ForceAnnouncement(ctx, capabilities.TriggerStartup); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !staging.IsServiceAvailable("data-abstractor") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the registry in the announcing namespace to see the service")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if production.IsServiceAvailable("data-abstractor") {
+		t.Fatal("expected a registry in another namespace not to see the service")
+	}
+}
+
+// nextEvent waits briefly for a lifecycle event, returning nil if none arrives
+func nextEvent(events <-chan ServiceLifecycleEvent) *ServiceLifecycleEvent {
+	select {
+	case event := <-events:
+		return &event
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func TestServiceRegistryEmitsLifecycleEvents(t *testing.T) {
+	registry := NewServiceRegistry(newStubRedisClient(t, "+OK\r\n"), time.Minute, "")
+	events, unsubscribe := registry.SubscribeEvents()
+	defer unsubscribe()
+
+	announce := func(version string) {
+		registry.updateCapability(&ServiceCapability{
+			Component:    "data-abstractor",
+			Version:      version,
+			Capabilities: &ServiceCapabilities{},
+		}, nil)
+	}
+
+	announce("1.0")
+	if event := nextEvent(events); event == nil || event.Type != EventServiceAdded || event.Component != "data-abstractor" {
+		t.Fatalf("first announcement: got %+v, want service_added", event)
+	}
+
+	// A periodic re-announcement of the same version is not an event
+	announce("1.0")
+	if event := nextEvent(events); event != nil {
+		t.Fatalf("unchanged announcement: got %+v, want no event", event)
+	}
+
+	announce("1.1")
+	if event := nextEvent(events); event == nil || event.Type != EventCapabilitiesChanged || event.PreviousVersion != "1.0" || event.Version != "1.1" {
+		t.Fatalf("new version: got %+v, want capabilities_changed from 1.0", event)
+	}
+
+	// Silent past the threshold: stale once, however often cleanup runs
+	registry.mutex.Lock()
+	registry.lastSeen["data-abstractor"] = time.Now().Add(-90 * time.Second)
+	registry.mutex.Unlock()
+	registry.removeStaleServices()
+	registry.removeStaleServices()
+	if event := nextEvent(events); event == nil || event.Type != EventServiceStale {
+		t.Fatalf("stale service: got %+v, want service_stale", event)
+	}
+	if event := nextEvent(events); event != nil {
+		t.Fatalf("repeated cleanup: got %+v, want no event", event)
+	}
+
+	// A stale service that announces again is added back
+	announce("1.1")
+	if event := nextEvent(events); event == nil || event.Type != EventServiceAdded {
+		t.Fatalf("returning service: got %+v, want service_added", event)
+	}
+
+	// Silent past twice the threshold: removed
+	registry.mutex.Lock()
+	registry.lastSeen["data-abstractor"] = time.Now().Add(-3 * time.Minute)
+	registry.mutex.Unlock()
+	registry.removeStaleServices()
+	for _, want := range []string{EventServiceStale, EventServiceRemoved} {
+		if event := nextEvent(events); event == nil || event.Type != want {
+			t.Fatalf("removed service: got %+v, want %s", event, want)
+		}
+	}
+	if _, exists := registry.GetServiceCapability("data-abstractor"); exists {
+		t.Error("expected the removed service to be gone")
+	}
+}
+
+func TestServiceRegistryTracksServiceVersionsPerReplica(t *testing.T) {
+	registry := NewServiceRegistry(newStubRedisClient(t, "+OK\r\n"), time.Minute, "")
+	for _, replica := range []struct{ id, version string }{{"a", "1.4.0"}, {"b", "1.5.0"}} {
+		registry.updateCapability(&ServiceCapability{
+			Component:       "data-abstractor",
+			Version:         "3f9a1c",
+			Capabilities:    &ServiceCapabilities{},
+			Instance:        replica.id,
+			InstanceChannel: "data-requests:" + replica.id,
+			ServiceVersion:  replica.version,
+		}, nil)
+	}
+
+	if version, _ := registry.GetServiceRelease("data-abstractor"); version != "1.5.0" {
+		t.Errorf("got service version %q, want the last announced 1.5.0", version)
+	}
+	for _, version := range []string{"1.4.0", "1.5.0"} {
+		if !registry.HasServiceRelease("data-abstractor", version) {
+			t.Errorf("expected a replica running %s", version)
+		}
+	}
+	if registry.HasServiceRelease("data-abstractor", "3f9a1c") {
+		t.Error("the capability hash must not count as a service version")
+	}
+}
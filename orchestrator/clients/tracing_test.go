@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"orchestrator/models"
+	"orchestrator/tracing"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording every span for the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := tracing.Setup(context.Background(), "orchestrator", ""); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestServiceRequestSpanContinuesCallerTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	client, server := newMiniRedisClient(t)
+
+	dataConfig := ServiceChannelConfig{RequestChannel: "data:requests", ResponseChannel: "data:responses"}
+	aiConfig := ServiceChannelConfig{RequestChannel: "ai:requests", ResponseChannel: "ai:responses"}
+	execConfig := ServiceChannelConfig{RequestChannel: "exec:requests", ResponseChannel: "exec:responses"}
+	mc := NewRedisMessageCoordinator(client, dataConfig, aiConfig, execConfig, 5*time.Second, CircuitBreakerConfig{})
+	defer mc.Close()
+
+	requests := client.Subscribe(context.Background(), "data:requests")
+	defer requests.Close()
+	waitForSubscribers(t, server, "data:requests", "data:responses")
+
+	// Play the data abstractor, failing the request with the trace context it was sent
+	received := make(chan *models.ServiceRequest, 1)
+	go func() {
+		msg := <-requests.Channel()
+		var request models.ServiceRequest
+		if err := json.Unmarshal([]byte(msg.Payload), &request); err != nil {
+			t.Error(err)
+			return
+		}
+		received <- &request
+		response, _ := json.Marshal(models.ServiceResponse{CorrelationID: request.CorrelationID, Error: "collection not found", Service: "data"})
+		client.Publish(context.Background(), "data:responses", response)
+	}()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "task.execute")
+	if _, err := mc.SendDataRequest(ctx, &models.ServiceRequest{Operation: "query"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "service.request" {
+		t.Fatalf("got %d spans, want the service request then its caller", len(spans))
+	}
+	span := spans[0]
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() || span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Error("expected the service request span to be a child of the caller's span")
+	}
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("got span kind %v, want client", span.SpanKind())
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "data service error: collection not found" {
+		t.Errorf("got status %+v, want the service's error", span.Status())
+	}
+
+	// The service continues the request's span, not the caller's
+	request := <-received
+	remote := trace.SpanContextFromContext(tracing.Extract(context.Background(), request.TraceContext))
+	if remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("got trace context %v, want the service request span's", request.TraceContext)
+	}
+}
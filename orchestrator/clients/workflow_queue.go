@@ -0,0 +1,162 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"orchestrator/models"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Priorities beyond this magnitude are clamped so scores stay exact as float64
+const maxQueuePriority = 1000
+
+// queuePriorityScale separates priorities in the score; the arrival sequence below it
+// keeps requests of equal priority in FIFO order
+const queuePriorityScale = 1e12
+
+// RedisWorkflowQueue holds workflow requests waiting to run in a Redis sorted set,
+// highest priority first and FIFO within a priority, so waiting requests survive restarts.
+// A dequeued request moves to this instance's processing set until it is acknowledged,
+// so one taken by an instance that crashes is queued again when the instance restarts.
+type RedisWorkflowQueue struct {
+	client        *redis.Client
+	key           string
+	seqKey        string
+	processingKey string
+}
+
+// QueuedRequest is a request taken from the queue, held in the processing set until Ack
+type QueuedRequest struct {
+	Request *models.WorkflowRequest
+	entry   string
+}
+
+// dequeueScript moves the first entry of the queue (KEYS[1]) to the processing set
+// (KEYS[2]) with its score, returning the entry
+var dequeueScript = redis.NewScript(`
+local entry = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if #entry == 0 then
+	return false
+end
+redis.call('ZREM', KEYS[1], entry[1])
+redis.call('ZADD', KEYS[2], entry[2], entry[1])
+return entry[1]
+`)
+
+// requeueScript moves every entry of the processing set (KEYS[2]) back to the queue
+// (KEYS[1]) with its original score, returning how many there were
+var requeueScript = redis.NewScript(`
+local entries = redis.call('ZRANGE', KEYS[2], 0, -1, 'WITHSCORES')
+for i = 1, #entries, 2 do
+	redis.call('ZADD', KEYS[1], entries[i + 1], entries[i])
+end
+redis.call('DEL', KEYS[2])
+return #entries / 2
+`)
+
+// NewRedisWorkflowQueue creates a workflow request queue under the given key prefix.
+// instanceID names this instance's processing set and must stay the same across restarts.
+func NewRedisWorkflowQueue(client *redis.Client, keyPrefix, instanceID string) *RedisWorkflowQueue {
+	return &RedisWorkflowQueue{
+		client:        client,
+		key:           keyPrefix + ":workflow-queue",
+		seqKey:        keyPrefix + ":workflow-queue-seq",
+		processingKey: keyPrefix + ":workflow-queue-processing:" + instanceID,
+	}
+}
+
+// Enqueue adds a request to the queue
+func (q *RedisWorkflowQueue) Enqueue(ctx context.Context, request *models.WorkflowRequest) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow request: %w", err)
+	}
+
+	seq, err := q.client.Incr(ctx, q.seqKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to sequence workflow request: %w", err)
+	}
+
+	// The sequence prefix keeps identical requests distinct members
+	member := strconv.FormatInt(seq, 10) + ":" + string(payload)
+	if err := q.client.ZAdd(ctx, q.key, &redis.Z{
+		Score:  queueScore(request.Priority, seq),
+		Member: member,
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue workflow request: %w", err)
+	}
+	return nil
+}
+
+// Dequeue takes the next request, or nil when the queue is empty. The request stays in
+// this instance's processing set until Ack.
+func (q *RedisWorkflowQueue) Dequeue(ctx context.Context) (*QueuedRequest, error) {
+	entry, err := dequeueScript.Run(ctx, q.client, []string{q.key, q.processingKey}).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue workflow request: %w", err)
+	}
+
+	queued := &QueuedRequest{entry: entry}
+	_, payload, found := strings.Cut(entry, ":")
+	if !found {
+		return nil, q.discard(ctx, queued, fmt.Errorf("malformed workflow queue entry"))
+	}
+
+	var request models.WorkflowRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		return nil, q.discard(ctx, queued, fmt.Errorf("failed to unmarshal queued workflow request: %w", err))
+	}
+	queued.Request = &request
+	return queued, nil
+}
+
+// discard drops an entry that can never be processed from the processing set, so it
+// isn't requeued on every restart. A failure to drop it is returned alongside cause.
+func (q *RedisWorkflowQueue) discard(ctx context.Context, queued *QueuedRequest, cause error) error {
+	if err := q.Ack(ctx, queued); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// Ack removes a dequeued request from the processing set, once its execution is saved or
+// it finished without one
+func (q *RedisWorkflowQueue) Ack(ctx context.Context, queued *QueuedRequest) error {
+	if err := q.client.ZRem(ctx, q.processingKey, queued.entry).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge workflow request: %w", err)
+	}
+	return nil
+}
+
+// RequeueProcessing returns requests this instance took but never acknowledged, e.g.
+// because it crashed, to the queue in their original order
+func (q *RedisWorkflowQueue) RequeueProcessing(ctx context.Context) (int64, error) {
+	count, err := requeueScript.Run(ctx, q.client, []string{q.key, q.processingKey}).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue workflow requests: %w", err)
+	}
+	return count, nil
+}
+
+// Len returns how many requests are waiting
+func (q *RedisWorkflowQueue) Len(ctx context.Context) (int64, error) {
+	return q.client.ZCard(ctx, q.key).Result()
+}
+
+// queueScore orders requests by descending priority, then by arrival
+func queueScore(priority int, seq int64) float64 {
+	if priority > maxQueuePriority {
+		priority = maxQueuePriority
+	} else if priority < -maxQueuePriority {
+		priority = -maxQueuePriority
+	}
+	return -float64(priority)*queuePriorityScale + float64(seq)
+}
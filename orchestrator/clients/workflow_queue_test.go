@@ -0,0 +1,207 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestQueueScoreOrdersByPriorityThenArrival(t *testing.T) {
+	type entry struct {
+		name     string
+		priority int
+		seq      int64
+	}
+	entries := []entry{
+		{"low", -5, 1},
+		{"default-first", 0, 2},
+		{"high", 10, 3},
+		{"default-second", 0, 4},
+		{"clamped", 5000, 5},
+		{"max", 1000, 6},
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return queueScore(entries[i].priority, entries[i].seq) < queueScore(entries[j].priority, entries[j].seq)
+	})
+
+	want := []string{"clamped", "max", "high", "default-first", "default-second", "low"}
+	for i, name := range want {
+		if entries[i].name != name {
+			t.Fatalf("position %d: got %s, want %s", i, entries[i].name, name)
+		}
+	}
+}
+
+// newTestWorkflowQueue returns a queue on an in-process Redis
+func newTestWorkflowQueue(t *testing.T, instanceID string) (*RedisWorkflowQueue, *redis.Client, string) {
+	t.Helper()
+	client, _ := newMiniRedisClient(t)
+	return NewRedisWorkflowQueue(client, "test", instanceID), client, "test"
+}
+
+// dequeueAll drains the queue, returning the correlation IDs in the order they came out
+func dequeueAll(t *testing.T, queue *RedisWorkflowQueue) []string {
+	t.Helper()
+	var order []string
+	for {
+		queued, err := queue.Dequeue(context.Background())
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if queued == nil {
+			return order
+		}
+		order = append(order, queued.Request.CorrelationID)
+	}
+}
+
+func TestRedisWorkflowQueueDequeuesByPriority(t *testing.T) {
+	queue, _, _ := newTestWorkflowQueue(t, "a")
+	ctx := context.Background()
+
+	for _, request := range []*models.WorkflowRequest{
+		{CorrelationID: "low", Priority: -1},
+		{CorrelationID: "first"},
+		{CorrelationID: "high", Priority: 5},
+		{CorrelationID: "second"},
+	} {
+		if err := queue.Enqueue(ctx, request); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	for _, want := range []string{"high", "first", "second", "low"} {
+		queued, err := queue.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if queued == nil || queued.Request.CorrelationID != want {
+			t.Fatalf("got %+v, want %s", queued, want)
+		}
+	}
+
+	queued, err := queue.Dequeue(ctx)
+	if err != nil || queued != nil {
+		t.Fatalf("expected an empty queue, got %+v, %v", queued, err)
+	}
+}
+
+func TestRedisWorkflowQueueRequeuesUnacknowledged(t *testing.T) {
+	queue, client, prefix := newTestWorkflowQueue(t, "a")
+	ctx := context.Background()
+
+	for _, id := range []string{"acked", "lost-first", "lost-second"} {
+		if err := queue.Enqueue(ctx, &models.WorkflowRequest{CorrelationID: id}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+
+	var taken []*QueuedRequest
+	for i := 0; i < 3; i++ {
+		queued, err := queue.Dequeue(ctx)
+		if err != nil || queued == nil {
+			t.Fatalf("dequeue: %+v, %v", queued, err)
+		}
+		taken = append(taken, queued)
+	}
+	if err := queue.Ack(ctx, taken[0]); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	// Another instance's processing set is not touched
+	other := NewRedisWorkflowQueue(client, prefix, "b")
+	if count, err := other.RequeueProcessing(ctx); err != nil || count != 0 {
+		t.Fatalf("other instance requeued %d, %v", count, err)
+	}
+
+	// The restarted instance gets back the requests it never acknowledged, in order
+	restarted := NewRedisWorkflowQueue(client, prefix, "a")
+	count, err := restarted.RequeueProcessing(ctx)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("requeued %d, want 2", count)
+	}
+
+	for _, want := range []string{"lost-first", "lost-second"} {
+		queued, err := restarted.Dequeue(ctx)
+		if err != nil || queued == nil || queued.Request.CorrelationID != want {
+			t.Fatalf("got %+v, %v, want %s", queued, err, want)
+		}
+	}
+}
+
+func TestRedisWorkflowQueueIsFIFOWithinAPriority(t *testing.T) {
+	queue, _, _ := newTestWorkflowQueue(t, "a")
+	ctx := context.Background()
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("urgent-%d", i)
+		want = append(want, id)
+		if err := queue.Enqueue(ctx, &models.WorkflowRequest{CorrelationID: id, Priority: 3}); err != nil {
+			t.Fatal(err)
+		}
+		// Interleaved lower priority requests don't disturb the order
+		if err := queue.Enqueue(ctx, &models.WorkflowRequest{CorrelationID: fmt.Sprintf("routine-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		want = append(want, fmt.Sprintf("routine-%d", i))
+	}
+
+	if got := dequeueAll(t, queue); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got order %v, want %v", got, want)
+	}
+}
+
+func TestRedisWorkflowQueueLateHighPriorityRunsNext(t *testing.T) {
+	queue, _, _ := newTestWorkflowQueue(t, "a")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := queue.Enqueue(ctx, &models.WorkflowRequest{CorrelationID: fmt.Sprintf("low-%d", i), Priority: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The only worker slot takes the first low priority request
+	running, err := queue.Dequeue(ctx)
+	if err != nil || running == nil || running.Request.CorrelationID != "low-0" {
+		t.Fatalf("got %+v, %v, want low-0", running, err)
+	}
+
+	if err := queue.Enqueue(ctx, &models.WorkflowRequest{CorrelationID: "high", Priority: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if got := dequeueAll(t, queue); strings.Join(got, ",") != "high,low-1,low-2" {
+		t.Errorf("got order %v, want high ahead of the waiting low priority requests", got)
+	}
+}
+
+func TestRedisWorkflowQueueDiscardsMalformedEntries(t *testing.T) {
+	queue, client, _ := newTestWorkflowQueue(t, "a")
+	ctx := context.Background()
+
+	for _, member := range []string{"no-separator", "1:{not json"} {
+		if err := client.ZAdd(ctx, queue.key, &redis.Z{Score: 1, Member: member}).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if queued, err := queue.Dequeue(ctx); err == nil || queued != nil {
+			t.Fatalf("entry %q: got %+v, %v, want an error", member, queued, err)
+		}
+	}
+
+	// Nothing is left behind to be requeued after a restart
+	if count, err := NewRedisWorkflowQueue(client, "test", "a").RequeueProcessing(ctx); err != nil || count != 0 {
+		t.Errorf("requeued %d, %v, want the malformed entries gone", count, err)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,10 @@ type Config struct {
 	Services     ServicesConfig
 	Orchestrator OrchestratorConfig
 	Capabilities CapabilityConfig
+	Export       ExportConfig
+	Callback     CallbackConfig
+	Logging      LoggingConfig
+	Tracing      TracingConfig
 }
 
 type ServerConfig struct {
@@ -20,9 +25,11 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	ShutdownTimeout time.Duration
+	DrainTimeout    time.Duration // how long shutdown waits for running workflows before cancelling them
 }
 
 type RedisConfig struct {
+	Namespace    string
 	Host         string
 	Port         string
 	Password     string
@@ -33,36 +40,113 @@ type RedisConfig struct {
 }
 
 type ServicesConfig struct {
-	DataService     ServiceConfig
-	AIService       ServiceConfig
-	ExecService     ServiceConfig
-	MessageTimeout  time.Duration
-	DefaultTimeout  time.Duration
+	DataService             ServiceConfig
+	AIService               ServiceConfig
+	ExecService             ServiceConfig
+	MessageTimeout          time.Duration
+	DefaultTimeout          time.Duration
+	QueueWaitPerRequest     time.Duration
+	MaxResponseWait         time.Duration
+	LoadBalanceStrategy     string        // round_robin, random or least_recently_used across announced replicas
+	CircuitBreakerThreshold int           // consecutive timeouts that open a service's circuit; 0 disables
+	CircuitBreakerCooldown  time.Duration // how long an open circuit fails fast before probing
+	CriticalServices        []string      // services /ready requires to be announced
+	AvailabilityGrace       time.Duration // how long require_services executions wait for missing services
 }
 
 type ServiceConfig struct {
-	RequestChannel  string
-	ResponseChannel string
-	Timeout         time.Duration
+	RequestChannel    string
+	ResponseChannel   string
+	DeadLetterChannel string // unmatched responses are republished here; empty disables
+	Timeout           time.Duration
 }
 
 type OrchestratorConfig struct {
-	WorkspaceDir       string
-	TemplatesDir       string
-	MaxConcurrent      int
-	DefaultTimeout     time.Duration
-	ExecutionTTL       time.Duration
-	CleanupInterval    time.Duration
-	RecoveryEnabled    bool
-	RecoveryInterval   time.Duration
+	WorkspaceDir          string
+	TemplatesDir          string
+	ArtifactsDir          string
+	StrictTemplates       bool
+	MaxConcurrent         int
+	MaxConcurrentTasks    int           // tasks running at once across all executions; 0 is unlimited
+	MaxSubWorkflowDepth   int           // how deeply workflow tasks may nest executions
+	TaskQueueTimeout      time.Duration // how long a task may wait for a slot; 0 is unbounded
+	BatchAbandonGrace     time.Duration // how long a cancelled batch waits for its tasks to stop
+	DefaultTimeout        time.Duration
+	ExecutionTTL          time.Duration
+	CleanupInterval       time.Duration
+	RecoveryEnabled       bool
+	RecoveryInterval      time.Duration
+	MaxRecoveryAttempts   int           // restarts or resumes of one execution before recovery fails it
+	InstanceID            string        // names this instance's queue processing set; must survive restarts
+	HeartbeatTTL          time.Duration // 0 disables execution heartbeats and the watchdog
+	MaxInterpolationDepth int           // deepest nesting allowed in task parameters
+	MaxInterpolationNodes int           // most values allowed across a task's parameters
+	ResultCacheTTL        time.Duration // how long deterministic task results are reused; 0 disables the cache
+	SecretsDir            string        // directory of secret files for secretRef parameters
+	PromptsDir            string        // directory of workflow generation prompt templates; empty uses built-ins
+	GenerationProviders   []string      // ordered provider[:model] entries tried for workflow generation
+	GenerationRetries     int           // times an invalid generated workflow is sent back for correction
+	ServiceAccessPolicy   string        // YAML file restricting exec task service_access; empty allows everything
+	IdempotencyTTL        time.Duration // how long workflow idempotency keys are remembered
+}
+
+// ExportConfig configures the sink finished executions are exported to
+type ExportConfig struct {
+	HTTPURL       string  // empty disables export
+	Authorization string  // optional Authorization header value, also sent to the Kafka REST proxy
+	KafkaRESTURL  string  // Kafka REST proxy to publish to instead of HTTPURL; empty disables
+	KafkaTopic    string  // topic records are published to
+	TokenPrice    float64 // cost of 1000 tokens in exported records; 0 leaves cost out
+}
+
+// CallbackConfig controls delivery of workflow responses to request callback URLs
+type CallbackConfig struct {
+	SigningSecret  string        // HMAC key for the signature header; empty sends callbacks unsigned
+	MaxAttempts    int           // deliveries tried before giving up
+	InitialDelay   time.Duration // wait before the first retry, doubled for each one after
+	AttemptTimeout time.Duration // bound on a single delivery
+	AllowedHosts   []string      // hosts callbacks may go to; empty allows any public host
+	AllowPrivate   bool          // allow callbacks to loopback, private and link-local addresses
+}
+
+// LoggingConfig controls sampling of high-volume per-request logs
+type LoggingConfig struct {
+	SampleRate    int      // log one in N requests; 1 logs everything
+	SampleFilters []string // key=value fields that are always logged, e.g. service=ai
+}
+
+// TracingConfig configures OpenTelemetry span export
+type TracingConfig struct {
+	OTLPEndpoint string // OTLP/HTTP collector URL; empty disables export
 }
 
 type CapabilityConfig struct {
-	RefreshInterval time.Duration
-	Enabled         bool
+	RefreshInterval  time.Duration
+	Enabled          bool
+	SigningSecret    string        // shared HMAC secret for signing announcements; empty disables
+	AnnounceDebounce time.Duration // coalesces bursts of announcements; zero disables
 }
 
 func LoadConfig() *Config {
+	redisConfig := RedisConfig{
+		Namespace:    getEnvOrDefault("REDIS_NAMESPACE", ""),
+		Host:         getEnvOrDefault("REDIS_HOST", "localhost"),
+		Port:         getEnvOrDefault("REDIS_PORT", "6379"),
+		Password:     getEnvOrDefault("REDIS_PASSWORD", ""),
+		Database:     getIntOrDefault("REDIS_DATABASE", 0),
+		PoolSize:     getIntOrDefault("REDIS_POOL_SIZE", 10),
+		MinIdleConns: getIntOrDefault("REDIS_MIN_IDLE_CONNS", 5),
+		MaxRetries:   getIntOrDefault("REDIS_MAX_RETRIES", 3),
+	}
+
+	// Responses nobody waits for go to <service>-responses-deadletter unless disabled
+	deadLetterChannel := func(service string) string {
+		if !getBoolOrDefault("DEAD_LETTER_RESPONSES", true) {
+			return ""
+		}
+		return Namespaced(redisConfig.Namespace, service+"-responses-deadletter")
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port:            getEnvOrDefault("ORCHESTRATOR_PORT", "8080"),
@@ -70,52 +154,114 @@ func LoadConfig() *Config {
 			ReadTimeout:     getDurationOrDefault("SERVER_READ_TIMEOUT", 30*time.Second),
 			WriteTimeout:    getDurationOrDefault("SERVER_WRITE_TIMEOUT", 30*time.Second),
 			ShutdownTimeout: getDurationOrDefault("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			DrainTimeout:    getDurationOrDefault("WORKFLOW_DRAIN_TIMEOUT", 60*time.Second),
 		},
-		Redis: RedisConfig{
-			Host:         getEnvOrDefault("REDIS_HOST", "localhost"),
-			Port:         getEnvOrDefault("REDIS_PORT", "6379"),
-			Password:     getEnvOrDefault("REDIS_PASSWORD", ""),
-			Database:     getIntOrDefault("REDIS_DATABASE", 0),
-			PoolSize:     getIntOrDefault("REDIS_POOL_SIZE", 10),
-			MinIdleConns: getIntOrDefault("REDIS_MIN_IDLE_CONNS", 5),
-			MaxRetries:   getIntOrDefault("REDIS_MAX_RETRIES", 3),
-		},
+		Redis: redisConfig,
 		Services: ServicesConfig{
 			DataService: ServiceConfig{
-				RequestChannel:  "data-requests",
-				ResponseChannel: "data-responses",
-				Timeout:         getDurationOrDefault("DATA_SERVICE_TIMEOUT", 60*time.Second),
+				RequestChannel:    Namespaced(redisConfig.Namespace, "data-requests"),
+				ResponseChannel:   Namespaced(redisConfig.Namespace, "data-responses"),
+				DeadLetterChannel: deadLetterChannel("data"),
+				Timeout:           getDurationOrDefault("DATA_SERVICE_TIMEOUT", 60*time.Second),
 			},
 			AIService: ServiceConfig{
-				RequestChannel:  "ai-requests",
-				ResponseChannel: "ai-responses", 
-				Timeout:         getDurationOrDefault("AI_SERVICE_TIMEOUT", 120*time.Second),
+				RequestChannel:    Namespaced(redisConfig.Namespace, "ai-requests"),
+				ResponseChannel:   Namespaced(redisConfig.Namespace, "ai-responses"),
+				DeadLetterChannel: deadLetterChannel("ai"),
+				Timeout:           getDurationOrDefault("AI_SERVICE_TIMEOUT", 120*time.Second),
 			},
 			ExecService: ServiceConfig{
-				RequestChannel:  "exec-requests",
-				ResponseChannel: "exec-responses",
-				Timeout:         getDurationOrDefault("EXEC_SERVICE_TIMEOUT", 300*time.Second),
+				RequestChannel:    Namespaced(redisConfig.Namespace, "exec-requests"),
+				ResponseChannel:   Namespaced(redisConfig.Namespace, "exec-responses"),
+				DeadLetterChannel: deadLetterChannel("exec"),
+				Timeout:           getDurationOrDefault("EXEC_SERVICE_TIMEOUT", 300*time.Second),
 			},
-			MessageTimeout: getDurationOrDefault("MESSAGE_TIMEOUT", 300*time.Second),
-			DefaultTimeout: getDurationOrDefault("DEFAULT_SERVICE_TIMEOUT", 60*time.Second),
+			MessageTimeout:          getDurationOrDefault("MESSAGE_TIMEOUT", 300*time.Second),
+			DefaultTimeout:          getDurationOrDefault("DEFAULT_SERVICE_TIMEOUT", 60*time.Second),
+			QueueWaitPerRequest:     getDurationOrDefault("QUEUE_WAIT_PER_REQUEST", 10*time.Second),
+			MaxResponseWait:         getDurationOrDefault("MAX_RESPONSE_WAIT", 10*time.Minute),
+			LoadBalanceStrategy:     getEnvOrDefault("LOAD_BALANCE_STRATEGY", "round_robin"),
+			CircuitBreakerThreshold: getIntOrDefault("CIRCUIT_BREAKER_THRESHOLD", 5),
+			CircuitBreakerCooldown:  getDurationOrDefault("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second),
+			CriticalServices:        getListOrDefault("CRITICAL_SERVICES", []string{"data-abstractor", "ai-abstractor", "exec-agent"}),
+			AvailabilityGrace:       getDurationOrDefault("SERVICE_AVAILABILITY_GRACE", 0),
 		},
 		Orchestrator: OrchestratorConfig{
-			WorkspaceDir:     getEnvOrDefault("ORCHESTRATOR_WORKSPACE", "/tmp/orchestrator"),
-			TemplatesDir:     getEnvOrDefault("ORCHESTRATOR_TEMPLATES", "./templates"),
-			MaxConcurrent:    getIntOrDefault("MAX_CONCURRENT_WORKFLOWS", 10),
-			DefaultTimeout:   getDurationOrDefault("DEFAULT_WORKFLOW_TIMEOUT", 3600*time.Second), // 1 hour
-			ExecutionTTL:     getDurationOrDefault("EXECUTION_TTL", 24*time.Hour),
-			CleanupInterval:  getDurationOrDefault("CLEANUP_INTERVAL", 1*time.Hour),
-			RecoveryEnabled:  getBoolOrDefault("RECOVERY_ENABLED", true),
-			RecoveryInterval: getDurationOrDefault("RECOVERY_INTERVAL", 5*time.Minute),
+			WorkspaceDir:          getEnvOrDefault("ORCHESTRATOR_WORKSPACE", "/tmp/orchestrator"),
+			TemplatesDir:          getEnvOrDefault("ORCHESTRATOR_TEMPLATES", "./templates"),
+			ArtifactsDir:          getEnvOrDefault("ORCHESTRATOR_ARTIFACTS", "/tmp/orchestrator/artifacts"),
+			StrictTemplates:       getBoolOrDefault("STRICT_TEMPLATES", false),
+			MaxConcurrent:         getIntOrDefault("MAX_CONCURRENT_WORKFLOWS", 10),
+			MaxConcurrentTasks:    getIntOrDefault("MAX_CONCURRENT_TASKS", 100),
+			MaxSubWorkflowDepth:   getIntOrDefault("MAX_SUBWORKFLOW_DEPTH", 5),
+			TaskQueueTimeout:      getDurationOrDefault("TASK_QUEUE_TIMEOUT", 0),
+			BatchAbandonGrace:     getDurationOrDefault("BATCH_ABANDON_GRACE", 30*time.Second),
+			DefaultTimeout:        getDurationOrDefault("DEFAULT_WORKFLOW_TIMEOUT", 3600*time.Second), // 1 hour
+			ExecutionTTL:          getDurationOrDefault("EXECUTION_TTL", 24*time.Hour),
+			CleanupInterval:       getDurationOrDefault("CLEANUP_INTERVAL", 1*time.Hour),
+			RecoveryEnabled:       getBoolOrDefault("RECOVERY_ENABLED", true),
+			RecoveryInterval:      getDurationOrDefault("RECOVERY_INTERVAL", 5*time.Minute),
+			MaxRecoveryAttempts:   getIntOrDefault("MAX_RECOVERY_ATTEMPTS", 3),
+			InstanceID:            getEnvOrDefault("INSTANCE_ID", defaultInstanceID()),
+			HeartbeatTTL:          getDurationOrDefault("HEARTBEAT_TTL", 90*time.Second),
+			MaxInterpolationDepth: getIntOrDefault("MAX_INTERPOLATION_DEPTH", 32),
+			MaxInterpolationNodes: getIntOrDefault("MAX_INTERPOLATION_NODES", 10000),
+			ResultCacheTTL:        getDurationOrDefault("RESULT_CACHE_TTL", 24*time.Hour),
+			IdempotencyTTL:        getDurationOrDefault("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+			SecretsDir:            getEnvOrDefault("SECRETS_DIR", ""),
+			PromptsDir:            getEnvOrDefault("GENERATION_PROMPTS_DIR", ""),
+			GenerationProviders:   getListOrDefault("GENERATION_PROVIDERS", []string{"anthropic:claude-3-sonnet"}),
+			GenerationRetries:     getIntOrDefault("GENERATION_MAX_RETRIES", 2),
+			ServiceAccessPolicy:   getEnvOrDefault("SERVICE_ACCESS_POLICY", ""),
 		},
 		Capabilities: CapabilityConfig{
-			RefreshInterval: getDurationOrDefault("CAPABILITY_REFRESH_INTERVAL", 5*time.Minute),
-			Enabled:         getBoolOrDefault("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			RefreshInterval:  getDurationOrDefault("CAPABILITY_REFRESH_INTERVAL", 5*time.Minute),
+			Enabled:          getBoolOrDefault("CAPABILITY_ANNOUNCEMENTS_ENABLED", true),
+			SigningSecret:    getEnvOrDefault("CAPABILITY_SIGNING_SECRET", ""),
+			AnnounceDebounce: getDurationOrDefault("CAPABILITY_ANNOUNCE_DEBOUNCE", 0),
+		},
+		Export: ExportConfig{
+			HTTPURL:       getEnvOrDefault("EXPORT_HTTP_URL", ""),
+			Authorization: getEnvOrDefault("EXPORT_HTTP_AUTHORIZATION", ""),
+			KafkaRESTURL:  getEnvOrDefault("EXPORT_KAFKA_REST_URL", ""),
+			KafkaTopic:    getEnvOrDefault("EXPORT_KAFKA_TOPIC", "workflow-executions"),
+			TokenPrice:    getFloatOrDefault("EXPORT_TOKEN_PRICE", 0),
+		},
+		Callback: CallbackConfig{
+			SigningSecret:  getEnvOrDefault("CALLBACK_SIGNING_SECRET", ""),
+			MaxAttempts:    getIntOrDefault("CALLBACK_MAX_ATTEMPTS", 5),
+			InitialDelay:   getDurationOrDefault("CALLBACK_INITIAL_DELAY", time.Second),
+			AttemptTimeout: getDurationOrDefault("CALLBACK_TIMEOUT", 10*time.Second),
+			AllowedHosts:   getListOrDefault("CALLBACK_ALLOWED_HOSTS", nil),
+			AllowPrivate:   getBoolOrDefault("CALLBACK_ALLOW_PRIVATE", false),
+		},
+		Logging: LoggingConfig{
+			SampleRate:    getIntOrDefault("LOG_SAMPLE_RATE", 1),
+			SampleFilters: getListOrDefault("LOG_SAMPLE_ALWAYS", nil),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: getEnvOrDefault("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 		},
 	}
 }
 
+// Namespaced prefixes a Redis key or channel name with the environment namespace, if any
+func Namespaced(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + ":" + name
+}
+
+// defaultInstanceID is the host name, which is stable across restarts of a container
+// or a StatefulSet pod
+func defaultInstanceID() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "orchestrator"
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -141,6 +287,28 @@ func getBoolOrDefault(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getFloatOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getListOrDefault(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultValue
+}
+
 func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
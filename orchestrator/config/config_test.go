@@ -0,0 +1,12 @@
+package config
+
+import "testing"
+
+func TestNamespaced(t *testing.T) {
+	if got := Namespaced("", "workflow-requests"); got != "workflow-requests" {
+		t.Errorf("got %q, want workflow-requests without a namespace", got)
+	}
+	if got := Namespaced("staging", "workflow-requests"); got != "staging:workflow-requests" {
+		t.Errorf("got %q, want staging:workflow-requests", got)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+// redactedValue replaces secrets in exposed configuration
+const redactedValue = "REDACTED"
+
+// maskSecret hides a secret while still showing whether it is set
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// Redacted returns the effective configuration with secrets masked, for operators
+// verifying what the service actually parsed
+func (c *Config) Redacted() map[string]interface{} {
+	service := func(s ServiceConfig) map[string]interface{} {
+		return map[string]interface{}{
+			"request_channel":     s.RequestChannel,
+			"response_channel":    s.ResponseChannel,
+			"dead_letter_channel": s.DeadLetterChannel,
+			"timeout":             s.Timeout.String(),
+		}
+	}
+
+	return map[string]interface{}{
+		"server": map[string]interface{}{
+			"host":             c.Server.Host,
+			"port":             c.Server.Port,
+			"read_timeout":     c.Server.ReadTimeout.String(),
+			"write_timeout":    c.Server.WriteTimeout.String(),
+			"shutdown_timeout": c.Server.ShutdownTimeout.String(),
+			"drain_timeout":    c.Server.DrainTimeout.String(),
+		},
+		"redis": map[string]interface{}{
+			"namespace":      c.Redis.Namespace,
+			"host":           c.Redis.Host,
+			"port":           c.Redis.Port,
+			"password":       maskSecret(c.Redis.Password),
+			"database":       c.Redis.Database,
+			"pool_size":      c.Redis.PoolSize,
+			"min_idle_conns": c.Redis.MinIdleConns,
+			"max_retries":    c.Redis.MaxRetries,
+		},
+		"services": map[string]interface{}{
+			"data":                      service(c.Services.DataService),
+			"ai":                        service(c.Services.AIService),
+			"exec":                      service(c.Services.ExecService),
+			"message_timeout":           c.Services.MessageTimeout.String(),
+			"default_timeout":           c.Services.DefaultTimeout.String(),
+			"queue_wait_per_request":    c.Services.QueueWaitPerRequest.String(),
+			"max_response_wait":         c.Services.MaxResponseWait.String(),
+			"load_balance_strategy":     c.Services.LoadBalanceStrategy,
+			"circuit_breaker_threshold": c.Services.CircuitBreakerThreshold,
+			"circuit_breaker_cooldown":  c.Services.CircuitBreakerCooldown.String(),
+			"critical_services":         c.Services.CriticalServices,
+			"availability_grace":        c.Services.AvailabilityGrace.String(),
+		},
+		"orchestrator": map[string]interface{}{
+			"workspace_dir":           c.Orchestrator.WorkspaceDir,
+			"templates_dir":           c.Orchestrator.TemplatesDir,
+			"artifacts_dir":           c.Orchestrator.ArtifactsDir,
+			"strict_templates":        c.Orchestrator.StrictTemplates,
+			"max_concurrent":          c.Orchestrator.MaxConcurrent,
+			"max_concurrent_tasks":    c.Orchestrator.MaxConcurrentTasks,
+			"max_subworkflow_depth":   c.Orchestrator.MaxSubWorkflowDepth,
+			"task_queue_timeout":      c.Orchestrator.TaskQueueTimeout.String(),
+			"batch_abandon_grace":     c.Orchestrator.BatchAbandonGrace.String(),
+			"default_timeout":         c.Orchestrator.DefaultTimeout.String(),
+			"execution_ttl":           c.Orchestrator.ExecutionTTL.String(),
+			"cleanup_interval":        c.Orchestrator.CleanupInterval.String(),
+			"recovery_enabled":        c.Orchestrator.RecoveryEnabled,
+			"recovery_interval":       c.Orchestrator.RecoveryInterval.String(),
+			"max_recovery_attempts":   c.Orchestrator.MaxRecoveryAttempts,
+			"instance_id":             c.Orchestrator.InstanceID,
+			"heartbeat_ttl":           c.Orchestrator.HeartbeatTTL.String(),
+			"max_interpolation_depth": c.Orchestrator.MaxInterpolationDepth,
+			"max_interpolation_nodes": c.Orchestrator.MaxInterpolationNodes,
+			"result_cache_ttl":        c.Orchestrator.ResultCacheTTL.String(),
+			"secrets_dir":             c.Orchestrator.SecretsDir,
+			"prompts_dir":             c.Orchestrator.PromptsDir,
+			"generation_providers":    c.Orchestrator.GenerationProviders,
+			"generation_retries":      c.Orchestrator.GenerationRetries,
+			"service_access_policy":   c.Orchestrator.ServiceAccessPolicy,
+			"idempotency_ttl":         c.Orchestrator.IdempotencyTTL.String(),
+		},
+		"capabilities": map[string]interface{}{
+			"enabled":           c.Capabilities.Enabled,
+			"refresh_interval":  c.Capabilities.RefreshInterval.String(),
+			"signing_secret":    maskSecret(c.Capabilities.SigningSecret),
+			"announce_debounce": c.Capabilities.AnnounceDebounce.String(),
+		},
+		"export": map[string]interface{}{
+			"http_url":      c.Export.HTTPURL,
+			"authorization": maskSecret(c.Export.Authorization),
+		},
+		"callback": map[string]interface{}{
+			"signing_secret":  maskSecret(c.Callback.SigningSecret),
+			"max_attempts":    c.Callback.MaxAttempts,
+			"initial_delay":   c.Callback.InitialDelay.String(),
+			"attempt_timeout": c.Callback.AttemptTimeout.String(),
+			"allowed_hosts":   c.Callback.AllowedHosts,
+			"allow_private":   c.Callback.AllowPrivate,
+		},
+		"logging": map[string]interface{}{
+			"sample_rate":    c.Logging.SampleRate,
+			"sample_filters": c.Logging.SampleFilters,
+		},
+		"tracing": map[string]interface{}{
+			"otlp_endpoint": c.Tracing.OTLPEndpoint,
+		},
+	}
+}
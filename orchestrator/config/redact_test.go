@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactedMasksSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.Redis.Host = "redis.internal"
+	cfg.Redis.Password = "redis-secret"
+	cfg.Capabilities.SigningSecret = "signing-secret"
+	cfg.Export.HTTPURL = "https://warehouse.example/ingest"
+	cfg.Export.Authorization = "Bearer export-secret"
+	cfg.Callback.SigningSecret = "callback-secret"
+	cfg.Orchestrator.MaxConcurrent = 7
+
+	data, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exposed := string(data)
+
+	for _, secret := range []string{"redis-secret", "signing-secret", "export-secret", "callback-secret"} {
+		if strings.Contains(exposed, secret) {
+			t.Errorf("secret %q exposed in %s", secret, exposed)
+		}
+	}
+
+	redacted := cfg.Redacted()
+	if got := redacted["redis"].(map[string]interface{})["password"]; got != redactedValue {
+		t.Errorf("redis password %v, want %s", got, redactedValue)
+	}
+	if got := redacted["redis"].(map[string]interface{})["host"]; got != "redis.internal" {
+		t.Errorf("redis host %v, want redis.internal", got)
+	}
+	if got := redacted["export"].(map[string]interface{})["http_url"]; got != cfg.Export.HTTPURL {
+		t.Errorf("export url %v, want %s", got, cfg.Export.HTTPURL)
+	}
+	if got := redacted["orchestrator"].(map[string]interface{})["max_concurrent"]; got != 7 {
+		t.Errorf("max_concurrent %v, want 7", got)
+	}
+
+	// An unset secret stays empty so operators can tell it is missing
+	cfg.Redis.Password = ""
+	if got := cfg.Redacted()["redis"].(map[string]interface{})["password"]; got != "" {
+		t.Errorf("unset password shown as %q", got)
+	}
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBatchAbandonGrace is how long a cancelled batch waits for its tasks to stop
+const DefaultBatchAbandonGrace = 30 * time.Second
+
+// BatchAbandonedError names the tasks a batch stopped waiting for because they kept
+// running after the workflow was cancelled or timed out
+type BatchAbandonedError struct {
+	Tasks []string
+	Grace time.Duration
+	Cause error
+}
+
+func (e *BatchAbandonedError) Error() string {
+	return fmt.Sprintf("tasks %s did not stop within %s of %v; abandoned", strings.Join(e.Tasks, ", "), e.Grace, e.Cause)
+}
+
+func (e *BatchAbandonedError) Unwrap() error {
+	return e.Cause
+}
+
+// SetBatchAbandonGrace sets how long a batch keeps waiting for its tasks once the workflow
+// is cancelled or times out. Tasks still running after that are abandoned so a task that
+// ignores cancellation can't wedge the execution; 0 abandons them straight away.
+func (we *WorkflowExecutor) SetBatchAbandonGrace(grace time.Duration) {
+	we.batchAbandonGrace = grace
+}
+
+// batchTasks records which of a batch's tasks are still running. A task runs against a
+// detached copy of its state, so abandoning it never races with its goroutine.
+type batchTasks struct {
+	mutex     sync.Mutex
+	running   map[string]bool
+	abandoned map[string]bool
+}
+
+func newBatchTasks() *batchTasks {
+	return &batchTasks{running: make(map[string]bool), abandoned: make(map[string]bool)}
+}
+
+func (b *batchTasks) start(id string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.running[id] = true
+}
+
+func (b *batchTasks) finish(id string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.running, id)
+}
+
+// detach returns a view of the execution in which the task's state is a private copy.
+// The task's goroutine writes only to the view until it commits.
+func (b *batchTasks) detach(execution *models.WorkflowExecution, id string) *models.WorkflowExecution {
+	view := *execution
+	view.TaskStates = make(map[string]*models.TaskState, len(execution.TaskStates))
+	for taskID, state := range execution.TaskStates {
+		view.TaskStates[taskID] = state
+	}
+
+	state := *execution.TaskStates[id]
+	state.Output = copyVariables(state.Output)
+	state.Metadata = copyVariables(state.Metadata)
+	view.TaskStates[id] = &state
+	return &view
+}
+
+// commit copies a task's detached state back into the execution and reports whether it
+// did; a task the batch already abandoned keeps the state abandonTask gave it
+func (b *batchTasks) commit(execution, view *models.WorkflowExecution, id string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.running, id)
+	if b.abandoned[id] {
+		return false
+	}
+	*execution.TaskStates[id] = *view.TaskStates[id]
+	return true
+}
+
+// abandon marks a task abandoned unless it has already returned, after which the
+// execution's copy of its state belongs to the batch
+func (b *batchTasks) abandon(id string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.running[id] {
+		return false
+	}
+	b.abandoned[id] = true
+	return true
+}
+
+// stillRunning lists the tasks that haven't returned, sorted
+func (b *batchTasks) stillRunning() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ids := make([]string, 0, len(b.running))
+	for id := range b.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// awaitBatch waits for a batch's tasks to return. Once ctx is done they get the abandon
+// grace period to notice; any still running after it are failed and left behind, and a
+// BatchAbandonedError is returned.
+func (we *WorkflowExecutor) awaitBatch(ctx context.Context, wg *sync.WaitGroup, tasks *batchTasks, execution *models.WorkflowExecution) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	grace := time.NewTimer(we.batchAbandonGrace)
+	defer grace.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-grace.C:
+	}
+
+	var stuck []string
+	for _, id := range tasks.stillRunning() {
+		if tasks.abandon(id) {
+			we.abandonTask(execution, id)
+			stuck = append(stuck, id)
+		}
+	}
+	if len(stuck) == 0 {
+		// The last task returned just as the grace period ran out
+		<-done
+		return nil
+	}
+	return &BatchAbandonedError{Tasks: stuck, Grace: we.batchAbandonGrace, Cause: ctx.Err()}
+}
+
+// abandonTask fails a task that kept running after its workflow was cancelled. Its
+// goroutine, and any slot it holds, is left until the task returns; it writes only to
+// its detached state, which is then dropped.
+func (we *WorkflowExecutor) abandonTask(execution *models.WorkflowExecution, taskID string) {
+	taskState := execution.TaskStates[taskID]
+	now := time.Now()
+	taskState.Status = models.StatusFailed
+	taskState.ErrorCode = models.TaskErrorTimeout
+	taskState.Error = "task did not stop after the workflow was cancelled; abandoned"
+	taskState.EndTime = &now
+	recordDecision(taskState, DecisionFailed, "abandoned after ignoring cancellation")
+	we.publishTaskProgress(execution, taskState)
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"task_id":      taskID,
+		"grace":        we.batchAbandonGrace,
+	}).Error("Abandoning task that ignored cancellation")
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteWorkflowAbandonsTaskIgnoringCancellation(t *testing.T) {
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		defer close(returned)
+		<-release // ignores ctx
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"late": true}
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+	executor.SetBatchAbandonGrace(10 * time.Millisecond)
+
+	workflow := &models.WorkflowDefinition{
+		ID:    "stuck-workflow",
+		Tasks: []models.Task{{ID: "stuck", Type: "data"}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	response, err := executor.ExecuteWorkflow(ctx, workflow, &models.WorkflowRequest{ExecutionID: "exec-1"})
+
+	var abandoned *BatchAbandonedError
+	if !errors.As(err, &abandoned) {
+		t.Fatalf("expected a BatchAbandonedError, got %v", err)
+	}
+	if len(abandoned.Tasks) != 1 || abandoned.Tasks[0] != "stuck" {
+		t.Errorf("expected the stuck task to be abandoned, got %v", abandoned.Tasks)
+	}
+	if response.Success {
+		t.Error("expected the workflow to fail")
+	}
+
+	execution, err := executor.stateManager.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := execution.TaskStates["stuck"]
+	if state.Status != models.StatusFailed || !strings.Contains(state.Error, "abandoned") {
+		t.Fatalf("expected the task to be failed as abandoned, got %s: %s", state.Status, state.Error)
+	}
+
+	// The task returning late must not overwrite the abandoned state
+	close(release)
+	<-returned
+	time.Sleep(20 * time.Millisecond)
+	if state.Status != models.StatusFailed || state.Output["late"] != nil {
+		t.Errorf("late task result leaked into the execution: %s %v", state.Status, state.Output)
+	}
+}
+
+func TestBatchTasksCommitAfterAbandonIsDropped(t *testing.T) {
+	execution := &models.WorkflowExecution{
+		TaskStates: map[string]*models.TaskState{"a": {ID: "a", Status: models.StatusPending}},
+	}
+	tasks := newBatchTasks()
+	tasks.start("a")
+
+	view := tasks.detach(execution, "a")
+	view.TaskStates["a"].Status = models.StatusRunning
+	if execution.TaskStates["a"].Status != models.StatusPending {
+		t.Fatal("detached state must not write through to the execution")
+	}
+
+	if !tasks.abandon("a") {
+		t.Fatal("expected a running task to be abandoned")
+	}
+	if tasks.commit(execution, view, "a") {
+		t.Error("expected commit of an abandoned task to be dropped")
+	}
+	if execution.TaskStates["a"].Status != models.StatusPending {
+		t.Errorf("abandoned task's state was committed: %s", execution.TaskStates["a"].Status)
+	}
+}
+
+func TestBatchTasksAbandonAfterCommitIsRefused(t *testing.T) {
+	execution := &models.WorkflowExecution{
+		TaskStates: map[string]*models.TaskState{"a": {ID: "a", Status: models.StatusPending}},
+	}
+	tasks := newBatchTasks()
+	tasks.start("a")
+
+	view := tasks.detach(execution, "a")
+	view.TaskStates["a"].Status = models.StatusCompleted
+	if !tasks.commit(execution, view, "a") {
+		t.Fatal("expected commit to succeed")
+	}
+	if tasks.abandon("a") {
+		t.Error("a task that already returned must not be abandoned")
+	}
+	if execution.TaskStates["a"].Status != models.StatusCompleted {
+		t.Errorf("expected committed state, got %s", execution.TaskStates["a"].Status)
+	}
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// callbackTimeout bounds the delivery of one callback, retries included
+const callbackTimeout = 10 * time.Minute
+
+// CallbackNotifier delivers the response of a finished execution to the callback URL
+// its request named
+type CallbackNotifier interface {
+	CheckCallbackURL(callbackURL *url.URL) error
+	NotifyCallback(ctx context.Context, callbackURL string, response *models.WorkflowResponse) error
+}
+
+// SetCallbackNotifier enables callback_url on workflow requests
+func (we *WorkflowExecutor) SetCallbackNotifier(notifier CallbackNotifier) {
+	we.callbacks = notifier
+	we.callbackCtx, we.cancelCallbacks = context.WithCancel(context.Background())
+}
+
+// validateCallbackURL rejects callback URLs that could never be delivered to, or that
+// the notifier refuses to call
+func (we *WorkflowExecutor) validateCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if we.callbacks == nil {
+		return fmt.Errorf("callback_url is not supported by this orchestrator")
+	}
+
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid callback_url %q: must be an absolute http or https URL", callbackURL)
+	}
+	if err := we.callbacks.CheckCallbackURL(parsed); err != nil {
+		return fmt.Errorf("invalid callback_url %q: %w", callbackURL, err)
+	}
+	return nil
+}
+
+// notifyCallback delivers the response in the background. Callbacks are best effort:
+// failures are logged and never affect the execution outcome. It is called while the
+// execution is still tracked, so Drain sees the delivery once the execution finishes.
+func (we *WorkflowExecutor) notifyCallback(execution *models.WorkflowExecution, response *models.WorkflowResponse) {
+	if we.callbacks == nil || execution.Request == nil || execution.Request.CallbackURL == "" {
+		return
+	}
+
+	callbackURL := execution.Request.CallbackURL
+	we.callbacksInFlight.Add(1)
+	go func() {
+		defer we.callbacksInFlight.Done()
+		ctx, cancel := context.WithTimeout(we.callbackCtx, callbackTimeout)
+		defer cancel()
+
+		if err := we.callbacks.NotifyCallback(ctx, callbackURL, response); err != nil {
+			we.logger.WithError(err).WithFields(logrus.Fields{
+				"execution_id": execution.ID,
+				"status":       response.Status,
+			}).Warn("Failed to deliver workflow callback")
+		}
+	}()
+}
+
+// waitForCallbacks waits for callback deliveries in flight until ctx is done, then
+// abandons the rest
+func (we *WorkflowExecutor) waitForCallbacks(ctx context.Context) error {
+	if we.callbacks == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		we.callbacksInFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	we.abandonCallbacks()
+	<-done
+	return fmt.Errorf("workflow callbacks abandoned: %w", ctx.Err())
+}
+
+// abandonCallbacks cancels every callback delivery in flight
+func (we *WorkflowExecutor) abandonCallbacks() {
+	if we.cancelCallbacks != nil {
+		we.cancelCallbacks()
+	}
+}
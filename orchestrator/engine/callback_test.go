@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"net/url"
+	"orchestrator/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCallbacks records callbacks, holding each delivery until release is closed
+type fakeCallbacks struct {
+	mutex     sync.Mutex
+	delivered []*models.WorkflowResponse
+	release   chan struct{}
+	rejected  string
+}
+
+func (f *fakeCallbacks) CheckCallbackURL(callbackURL *url.URL) error {
+	if callbackURL.Hostname() == f.rejected {
+		return &url.Error{Op: "check", URL: callbackURL.String(), Err: context.Canceled}
+	}
+	return nil
+}
+
+func (f *fakeCallbacks) NotifyCallback(ctx context.Context, callbackURL string, response *models.WorkflowResponse) error {
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.delivered = append(f.delivered, response)
+	return nil
+}
+
+func (f *fakeCallbacks) count() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.delivered)
+}
+
+func newCallbackExecutor(callbacks *fakeCallbacks) *WorkflowExecutor {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetCallbackNotifier(callbacks)
+	return executor
+}
+
+var callbackWorkflow = &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "a", Type: "data"}}}
+
+func TestExecuteWorkflowRejectsCallbackURL(t *testing.T) {
+	executor := newCallbackExecutor(&fakeCallbacks{rejected: "internal"})
+
+	for _, callbackURL := range []string{"ftp://example.com/", "/relative", "http://internal/hook"} {
+		_, err := executor.ExecuteWorkflow(context.Background(), callbackWorkflow, &models.WorkflowRequest{CallbackURL: callbackURL})
+		if err == nil || !strings.Contains(err.Error(), "invalid callback_url") {
+			t.Errorf("%s: expected an invalid callback_url error, got %v", callbackURL, err)
+		}
+	}
+}
+
+func TestDrainWaitsForCallbacks(t *testing.T) {
+	callbacks := &fakeCallbacks{release: make(chan struct{})}
+	executor := newCallbackExecutor(callbacks)
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), callbackWorkflow, &models.WorkflowRequest{CallbackURL: "https://example.com/hook"}); err != nil {
+		t.Fatal(err)
+	}
+
+	drained := make(chan error)
+	go func() { drained <- executor.Drain(context.Background()) }()
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned while a callback was still being delivered")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(callbacks.release)
+	if err := <-drained; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callbacks.count() != 1 {
+		t.Errorf("expected the callback to be delivered, got %d", callbacks.count())
+	}
+}
+
+func TestDrainAbandonsCallbacksAtTimeout(t *testing.T) {
+	callbacks := &fakeCallbacks{release: make(chan struct{})}
+	executor := newCallbackExecutor(callbacks)
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), callbackWorkflow, &models.WorkflowRequest{CallbackURL: "https://example.com/hook"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := executor.Drain(ctx)
+	if err == nil || !strings.Contains(err.Error(), "callbacks abandoned") {
+		t.Fatalf("expected callbacks to be abandoned, got %v", err)
+	}
+	if callbacks.count() != 0 {
+		t.Error("abandoned callback was delivered")
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CancelReasonMetadataKey records why an execution was cancelled
+const CancelReasonMetadataKey = "cancel_reason"
+
+// cancelWaitTimeout bounds how long CancelWorkflow waits for running tasks to unwind
+const cancelWaitTimeout = 30 * time.Second
+
+// ErrExecutionNotRunning is returned when cancelling an execution that is unknown to this
+// orchestrator or has already finished
+var ErrExecutionNotRunning = errors.New("execution is not running")
+
+// ErrShuttingDown is returned when an execution is started after Drain was called
+var ErrShuttingDown = errors.New("orchestrator is shutting down")
+
+// ShutdownCancelReason is recorded on executions cancelled because draining timed out
+const ShutdownCancelReason = "server shutdown"
+
+// runningExecution is an execution currently running on this executor
+type runningExecution struct {
+	execution *models.WorkflowExecution
+	cancel    context.CancelFunc
+	done      chan struct{}
+	reason    string
+	cancelled bool
+}
+
+// trackExecution registers a running execution and returns the context it should run
+// under, along with a function that unregisters it once its outcome is saved. It fails
+// with ErrShuttingDown once the executor is draining.
+func (we *WorkflowExecutor) trackExecution(ctx context.Context, execution *models.WorkflowExecution) (context.Context, func(), error) {
+	we.runningMutex.Lock()
+	if we.draining {
+		we.runningMutex.Unlock()
+		return nil, nil, ErrShuttingDown
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	running := &runningExecution{
+		execution: execution,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	we.running[execution.ID] = running
+	we.runningMutex.Unlock()
+
+	return runCtx, func() {
+		we.runningMutex.Lock()
+		if we.running[execution.ID] == running {
+			delete(we.running, execution.ID)
+		}
+		we.runningMutex.Unlock()
+		cancel()
+		close(running.done)
+	}, nil
+}
+
+// IsRunning reports whether an execution is currently running on this executor
+func (we *WorkflowExecutor) IsRunning(executionID string) bool {
+	we.runningMutex.Lock()
+	defer we.runningMutex.Unlock()
+	_, running := we.running[executionID]
+	return running
+}
+
+// cancelReason returns the reason an execution was cancelled through CancelWorkflow
+func (we *WorkflowExecutor) cancelReason(executionID string) (string, bool) {
+	we.runningMutex.Lock()
+	defer we.runningMutex.Unlock()
+
+	running, exists := we.running[executionID]
+	if !exists || !running.cancelled {
+		return "", false
+	}
+	return running.reason, true
+}
+
+// CancelWorkflow cancels a running execution. Running tasks are marked cancelled, no
+// further tasks are dispatched, and the execution is saved with status cancelled. It waits
+// for the execution to record its outcome and returns ErrExecutionNotRunning when the
+// execution is unknown or finished before the cancellation took effect.
+func (we *WorkflowExecutor) CancelWorkflow(executionID, reason string) error {
+	we.runningMutex.Lock()
+	running, exists := we.running[executionID]
+	if exists && !running.cancelled {
+		running.cancelled = true
+		running.reason = reason
+		running.cancel()
+	}
+	we.runningMutex.Unlock()
+
+	if !exists {
+		return ErrExecutionNotRunning
+	}
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": executionID,
+		"reason":       reason,
+	}).Info("Cancelling workflow execution")
+
+	select {
+	case <-running.done:
+	case <-time.After(cancelWaitTimeout):
+		// Cancellation was signalled; the execution saves its state once its tasks return
+		we.logger.WithField("execution_id", executionID).Warn("Timed out waiting for cancelled execution to stop")
+		return nil
+	}
+
+	// The run completed before it observed the cancellation
+	if running.execution.Status != models.StatusCancelled {
+		return ErrExecutionNotRunning
+	}
+	return nil
+}
+
+// Drain stops the executor accepting new executions and waits for the running ones to
+// finish. Executions still running when ctx is done are cancelled with
+// ShutdownCancelReason, which saves them as cancelled. It returns an error if any of
+// them has not recorded its outcome within cancelWaitTimeout after that. Callbacks still
+// being delivered are waited for in the same way, and abandoned when time runs out.
+func (we *WorkflowExecutor) Drain(ctx context.Context) error {
+	if err := we.drainExecutions(ctx); err != nil {
+		we.abandonCallbacks()
+		return err
+	}
+
+	// Callbacks of executions stopped by the timeout get a short window of their own
+	if ctx.Err() != nil {
+		waitCtx, cancel := context.WithTimeout(context.Background(), cancelWaitTimeout)
+		defer cancel()
+		return we.waitForCallbacks(waitCtx)
+	}
+	return we.waitForCallbacks(ctx)
+}
+
+// drainExecutions waits for, and then cancels, the running executions for Drain
+func (we *WorkflowExecutor) drainExecutions(ctx context.Context) error {
+	we.runningMutex.Lock()
+	we.draining = true
+	runs := make([]*runningExecution, 0, len(we.running))
+	for _, running := range we.running {
+		runs = append(runs, running)
+	}
+	we.runningMutex.Unlock()
+
+	if len(runs) == 0 {
+		return nil
+	}
+	we.logger.WithField("executions", len(runs)).Info("Draining running workflow executions")
+
+	remaining := waitForExecutions(ctx.Done(), runs)
+	if len(remaining) == 0 {
+		we.logger.Info("All workflow executions finished")
+		return nil
+	}
+
+	we.runningMutex.Lock()
+	for _, running := range remaining {
+		if !running.cancelled {
+			running.cancelled = true
+			running.reason = ShutdownCancelReason
+			running.cancel()
+		}
+	}
+	we.runningMutex.Unlock()
+
+	we.logger.WithField("executions", len(remaining)).Warn("Drain timeout reached, cancelling remaining workflow executions")
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), cancelWaitTimeout)
+	defer cancel()
+	if stuck := waitForExecutions(waitCtx.Done(), remaining); len(stuck) > 0 {
+		return fmt.Errorf("%d cancelled executions did not stop within %v", len(stuck), cancelWaitTimeout)
+	}
+	return nil
+}
+
+// waitForExecutions waits for runs to finish until stop fires, returning those still running
+func waitForExecutions(stop <-chan struct{}, runs []*runningExecution) []*runningExecution {
+	for i, running := range runs {
+		select {
+		case <-running.done:
+		case <-stop:
+			var remaining []*runningExecution
+			for _, rest := range runs[i:] {
+				select {
+				case <-rest.done:
+				default:
+					remaining = append(remaining, rest)
+				}
+			}
+			return remaining
+		}
+	}
+	return nil
+}
@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"orchestrator/models"
 	"sort"
+	"strings"
 )
 
+// conditionTaskType is the task type whose on_success and on_failure lists are branches
+const conditionTaskType = "condition"
+
 // DAG represents a Directed Acyclic Graph for task dependencies
 type DAG struct {
 	tasks        map[string]*models.Task
 	dependencies map[string][]string
 	dependents   map[string][]string
+	conditional  map[string]map[string]bool // branch target -> condition tasks that choose it
 	sorted       []string
 }
 
@@ -20,31 +25,55 @@ func NewDAG(tasks []models.Task) (*DAG, error) {
 		tasks:        make(map[string]*models.Task),
 		dependencies: make(map[string][]string),
 		dependents:   make(map[string][]string),
+		conditional:  make(map[string]map[string]bool),
 	}
 
+	var problems validationProblems
+
 	// Build task map and dependency graph
 	for i := range tasks {
 		task := &tasks[i]
+		if _, exists := dag.tasks[task.ID]; exists {
+			problems.add(ProblemDuplicateTask, task.ID, "", nil, "duplicate task ID %s", task.ID)
+			continue
+		}
 		dag.tasks[task.ID] = task
-		dag.dependencies[task.ID] = task.DependsOn
+		dag.dependencies[task.ID] = append([]string(nil), task.DependsOn...)
+	}
 
-		// Build reverse dependency map (dependents)
-		for _, dep := range task.DependsOn {
-			if _, exists := dag.dependents[dep]; !exists {
-				dag.dependents[dep] = make([]string, 0)
+	// Branch targets of condition tasks run after the condition, even without depends_on
+	for _, taskID := range dag.sortedTaskIDs() {
+		task := dag.tasks[taskID]
+		if task.Type != conditionTaskType {
+			continue
+		}
+		for _, target := range append(append([]string(nil), task.OnSuccess...), task.OnFailure...) {
+			if _, exists := dag.tasks[target]; !exists {
+				problems.add(ProblemMissingBranch, task.ID, target, nil, "condition task %s branches to non-existent task %s", task.ID, target)
+				continue
+			}
+			if dag.conditional[target] == nil {
+				dag.conditional[target] = make(map[string]bool)
+			}
+			dag.conditional[target][task.ID] = true
+			if !containsString(dag.dependencies[target], task.ID) {
+				dag.dependencies[target] = append(dag.dependencies[target], task.ID)
 			}
-			dag.dependents[dep] = append(dag.dependents[dep], task.ID)
 		}
 	}
 
-	// Validate all dependencies exist
-	if err := dag.validateDependencies(); err != nil {
-		return nil, err
+	// Build reverse dependency map (dependents)
+	for _, task := range dag.sortedTaskIDs() {
+		for _, dep := range dag.dependencies[task] {
+			dag.dependents[dep] = append(dag.dependents[dep], task)
+		}
 	}
 
-	// Check for cycles
-	if err := dag.detectCycles(); err != nil {
-		return nil, err
+	// Report every missing dependency and cycle together
+	dag.missingDependencies(&problems)
+	dag.findCycles(&problems)
+	if len(problems) > 0 {
+		return nil, &DAGValidationError{Problems: problems}
 	}
 
 	// Perform topological sort
@@ -55,62 +84,14 @@ func NewDAG(tasks []models.Task) (*DAG, error) {
 	return dag, nil
 }
 
-// validateDependencies ensures all referenced dependencies exist as tasks
-func (dag *DAG) validateDependencies() error {
-	for taskID, deps := range dag.dependencies {
-		for _, dep := range deps {
-			if _, exists := dag.tasks[dep]; !exists {
-				return fmt.Errorf("task %s depends on non-existent task %s", taskID, dep)
-			}
-		}
-	}
-	return nil
-}
-
-// detectCycles uses DFS to detect circular dependencies
-func (dag *DAG) detectCycles() error {
-	white := make(map[string]bool)
-	gray := make(map[string]bool)
-	black := make(map[string]bool)
-
-	// Initialize all nodes as white (unvisited)
-	for taskID := range dag.tasks {
-		white[taskID] = true
-	}
-
-	// DFS from each white node
-	for taskID := range white {
-		if err := dag.dfsVisit(taskID, white, gray, black); err != nil {
-			return err
-		}
+// sortedTaskIDs lists task IDs in a stable order
+func (dag *DAG) sortedTaskIDs() []string {
+	ids := make([]string, 0, len(dag.tasks))
+	for id := range dag.tasks {
+		ids = append(ids, id)
 	}
-
-	return nil
-}
-
-// dfsVisit performs depth-first search to detect cycles
-func (dag *DAG) dfsVisit(taskID string, white, gray, black map[string]bool) error {
-	// Move from white to gray
-	delete(white, taskID)
-	gray[taskID] = true
-
-	// Visit all dependents
-	for _, dependent := range dag.dependents[taskID] {
-		if gray[dependent] {
-			return fmt.Errorf("circular dependency detected: task %s depends on %s", dependent, taskID)
-		}
-		if white[dependent] {
-			if err := dag.dfsVisit(dependent, white, gray, black); err != nil {
-				return err
-			}
-		}
-	}
-
-	// Move from gray to black
-	delete(gray, taskID)
-	black[taskID] = true
-
-	return nil
+	sort.Strings(ids)
+	return ids
 }
 
 // topologicalSort performs topological sorting using Kahn's algorithm
@@ -256,6 +237,69 @@ func (dag *DAG) GetParallelBatches() [][]string {
 	return batches
 }
 
+// IsConditional reports whether the edge from dependency to taskID is a condition branch
+func (dag *DAG) IsConditional(dependency, taskID string) bool {
+	return dag.conditional[taskID][dependency]
+}
+
+// SkipReason decides from the states of a task's dependencies whether the task must be
+// skipped, returning why. A branch target is skipped when any condition choosing it took
+// the other branch. Otherwise a task is skipped only when every dependency was skipped,
+// so a join after a conditional diamond still runs through the taken path.
+func (dag *DAG) SkipReason(taskID string, states map[string]*models.TaskState) (string, bool) {
+	deps := dag.dependencies[taskID]
+	if len(deps) == 0 {
+		return "", false
+	}
+
+	skippedDeps := 0
+	for _, dep := range deps {
+		state := states[dep]
+		if state == nil {
+			continue
+		}
+
+		if state.Status == models.StatusSkipped {
+			skippedDeps++
+			if dag.IsConditional(dep, taskID) {
+				return fmt.Sprintf("condition task %s was skipped", dep), true
+			}
+			continue
+		}
+
+		if dag.IsConditional(dep, taskID) && state.Status == models.StatusCompleted && !dag.branchTaken(dep, taskID, state) {
+			result, _ := state.Output["condition_result"].(bool)
+			return fmt.Sprintf("condition task %s evaluated to %t and did not take this branch", dep, result), true
+		}
+	}
+
+	if skippedDeps == len(deps) {
+		return fmt.Sprintf("all dependencies were skipped (%s)", strings.Join(deps, ", ")), true
+	}
+	return "", false
+}
+
+// branchTaken reports whether a completed condition task chose the branch containing target
+func (dag *DAG) branchTaken(conditionID, target string, state *models.TaskState) bool {
+	result, _ := state.Output["condition_result"].(bool)
+
+	condition := dag.tasks[conditionID]
+	branch := condition.OnFailure
+	if result {
+		branch = condition.OnSuccess
+	}
+	return containsString(branch, target)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Clone creates a deep copy of the DAG
 func (dag *DAG) Clone() *DAG {
 	clone := &DAG{
@@ -283,6 +327,15 @@ func (dag *DAG) Clone() *DAG {
 		copy(clone.dependents[id], deps)
 	}
 
+	// Copy conditional edges
+	clone.conditional = make(map[string]map[string]bool, len(dag.conditional))
+	for target, conditions := range dag.conditional {
+		clone.conditional[target] = make(map[string]bool, len(conditions))
+		for condition := range conditions {
+			clone.conditional[target][condition] = true
+		}
+	}
+
 	// Copy sorted order
 	copy(clone.sorted, dag.sorted)
 
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// conditionalDiamond is a condition choosing between two branches that meet at a join
+func conditionalDiamond() []models.Task {
+	return []models.Task{
+		{ID: "check", Type: "condition", OnSuccess: []string{"left"}, OnFailure: []string{"right"}},
+		{ID: "left", Type: "data"},
+		{ID: "right", Type: "data"},
+		{ID: "join", Type: "data", DependsOn: []string{"left", "right"}},
+		{ID: "after-left", Type: "data", DependsOn: []string{"left"}},
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	completed := func(output map[string]interface{}) *models.TaskState {
+		return &models.TaskState{Status: models.StatusCompleted, Output: output}
+	}
+	skipped := &models.TaskState{Status: models.StatusSkipped}
+	tookSuccess := completed(map[string]interface{}{"condition_result": true})
+	tookFailure := completed(map[string]interface{}{"condition_result": false})
+
+	cases := []struct {
+		name   string
+		task   string
+		states map[string]*models.TaskState
+		skip   bool
+		reason string
+	}{
+		{"branch taken", "left", map[string]*models.TaskState{"check": tookSuccess}, false, ""},
+		{"branch not taken", "right", map[string]*models.TaskState{"check": tookSuccess}, true, "evaluated to true and did not take this branch"},
+		{"failure branch taken", "right", map[string]*models.TaskState{"check": tookFailure}, false, ""},
+		{"success branch not taken", "left", map[string]*models.TaskState{"check": tookFailure}, true, "evaluated to false"},
+		{"condition without a result takes the failure branch", "right", map[string]*models.TaskState{"check": completed(nil)}, false, ""},
+		{"conditional dependency skipped", "left", map[string]*models.TaskState{"check": skipped}, true, "condition task check was skipped"},
+		{"condition not run yet", "left", map[string]*models.TaskState{}, false, ""},
+		{"all dependencies skipped", "join", map[string]*models.TaskState{"left": skipped, "right": skipped}, true, "all dependencies were skipped (left, right)"},
+		{"diamond join after one branch", "join", map[string]*models.TaskState{"left": completed(nil), "right": skipped}, false, ""},
+		{"diamond join after the other branch", "join", map[string]*models.TaskState{"left": skipped, "right": completed(nil)}, false, ""},
+		{"only dependency skipped", "after-left", map[string]*models.TaskState{"left": skipped}, true, "all dependencies were skipped (left)"},
+		{"failed dependency is not a skip", "after-left", map[string]*models.TaskState{"left": {Status: models.StatusFailed}}, false, ""},
+		{"no dependencies", "check", map[string]*models.TaskState{}, false, ""},
+	}
+
+	dag, err := NewDAG(conditionalDiamond())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reason, skip := dag.SkipReason(c.task, c.states)
+			if skip != c.skip {
+				t.Fatalf("got skip=%v (%q), want %v", skip, reason, c.skip)
+			}
+			if !strings.Contains(reason, c.reason) {
+				t.Errorf("got reason %q, want it to mention %q", reason, c.reason)
+			}
+		})
+	}
+}
+
+func TestConditionalDiamondRunsJoinThroughTakenBranch(t *testing.T) {
+	for _, result := range []bool{true, false} {
+		taken, notTaken := "left", "right"
+		if !result {
+			taken, notTaken = notTaken, taken
+		}
+
+		var mutex sync.Mutex
+		var ran []string
+		executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+			mutex.Lock()
+			ran = append(ran, task.ID)
+			mutex.Unlock()
+			if task.Type == "condition" {
+				execution.TaskStates[task.ID].Output = map[string]interface{}{"condition_result": result}
+			}
+			return nil
+		}), newMemoryStateManager(), nil, 1)
+
+		workflow := &models.WorkflowDefinition{ID: "wf", Tasks: conditionalDiamond()}
+		response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != models.StatusCompleted {
+			t.Fatalf("condition %v: got status %s (%s), want completed", result, response.Status, response.Error)
+		}
+
+		ranSet := make(map[string]bool)
+		for _, id := range ran {
+			ranSet[id] = true
+		}
+		if !ranSet[taken] || !ranSet["join"] {
+			t.Errorf("condition %v: ran %v, want %s and the join", result, ran, taken)
+		}
+		if ranSet[notTaken] {
+			t.Errorf("condition %v: ran %s on the branch not taken", result, notTaken)
+		}
+		// after-left follows left only, so it is skipped with it
+		if ranSet["after-left"] != result {
+			t.Errorf("condition %v: after-left ran=%v", result, ranSet["after-left"])
+		}
+	}
+}
+
+// dagProblems builds a DAG from tasks and returns the problems it was rejected for
+func dagProblems(t *testing.T, tasks []models.Task) []DAGProblem {
+	t.Helper()
+	_, err := NewDAG(tasks)
+	var validationErr *DAGValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a DAGValidationError, got %v", err)
+	}
+	return validationErr.Problems
+}
+
+func TestDAGValidationReportsMissingDependency(t *testing.T) {
+	problems := dagProblems(t, []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "report", Type: "ai", DependsOn: []string{"fetch", "summarize"}},
+	})
+
+	want := []DAGProblem{{
+		Kind:    ProblemMissingDependency,
+		TaskID:  "report",
+		Target:  "summarize",
+		Message: "task report depends on non-existent task summarize",
+	}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("got problems %+v, want %+v", problems, want)
+	}
+}
+
+func TestDAGValidationReportsEachCycle(t *testing.T) {
+	problems := dagProblems(t, []models.Task{
+		{ID: "a", Type: "data", DependsOn: []string{"c"}},
+		{ID: "b", Type: "data", DependsOn: []string{"a"}},
+		{ID: "c", Type: "data", DependsOn: []string{"b"}},
+		{ID: "self", Type: "data", DependsOn: []string{"self"}},
+		{ID: "ok", Type: "data", DependsOn: []string{"a"}},
+	})
+
+	want := []DAGProblem{
+		{Kind: ProblemCycle, Tasks: []string{"a", "b", "c"}, Message: "circular dependency between tasks a, b, c"},
+		{Kind: ProblemCycle, Tasks: []string{"self"}, Message: "circular dependency between tasks self"},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("got problems %+v, want %+v", problems, want)
+	}
+}
+
+func TestDAGValidationReportsEveryProblemAtOnce(t *testing.T) {
+	_, err := NewDAG([]models.Task{
+		{ID: "check", Type: "condition", OnSuccess: []string{"missing"}},
+		{ID: "check", Type: "condition"},
+		{ID: "loop", Type: "data", DependsOn: []string{"loop", "gone"}},
+	})
+
+	var validationErr *DAGValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a DAGValidationError, got %v", err)
+	}
+	var kinds []string
+	for _, problem := range validationErr.Problems {
+		kinds = append(kinds, problem.Kind)
+	}
+	want := []string{ProblemDuplicateTask, ProblemMissingBranch, ProblemMissingDependency, ProblemCycle}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("got problem kinds %v, want %v", kinds, want)
+	}
+	if !strings.HasPrefix(err.Error(), "4 problems in workflow graph: ") {
+		t.Errorf("got message %q", err.Error())
+	}
+}
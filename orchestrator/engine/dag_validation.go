@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of problem reported by DAG validation
+const (
+	ProblemDuplicateTask     = "duplicate_task"
+	ProblemMissingDependency = "missing_dependency"
+	ProblemMissingBranch     = "missing_branch"
+	ProblemCycle             = "cycle"
+)
+
+// DAGProblem is one problem found while building a DAG
+type DAGProblem struct {
+	Kind    string   `json:"kind"`
+	TaskID  string   `json:"task_id,omitempty"` // task the problem was found on; empty for cycles
+	Target  string   `json:"target,omitempty"`  // missing dependency or branch target
+	Tasks   []string `json:"tasks,omitempty"`   // tasks forming a cycle
+	Message string   `json:"message"`
+}
+
+// DAGValidationError reports every problem found in a workflow's task graph at once, so
+// authors can fix them together instead of one per run
+type DAGValidationError struct {
+	Problems []DAGProblem `json:"problems"`
+}
+
+func (e *DAGValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Message
+	}
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Message
+	}
+	return fmt.Sprintf("%d problems in workflow graph: %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
+// validationProblems collects problems in the order they are found
+type validationProblems []DAGProblem
+
+func (p *validationProblems) add(kind, taskID, target string, tasks []string, format string, args ...interface{}) {
+	*p = append(*p, DAGProblem{
+		Kind:    kind,
+		TaskID:  taskID,
+		Target:  target,
+		Tasks:   tasks,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// missingDependencies reports every dependency naming a task that does not exist
+func (dag *DAG) missingDependencies(problems *validationProblems) {
+	for _, taskID := range dag.sortedTaskIDs() {
+		for _, dep := range dag.dependencies[taskID] {
+			if _, exists := dag.tasks[dep]; !exists {
+				problems.add(ProblemMissingDependency, taskID, dep, nil, "task %s depends on non-existent task %s", taskID, dep)
+			}
+		}
+	}
+}
+
+// findCycles reports each group of tasks that depend on each other in a cycle. Groups are
+// the strongly connected components of the dependency graph (Tarjan's algorithm), so
+// independent cycles are reported separately.
+func (dag *DAG) findCycles(problems *validationProblems) {
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+	next := 0
+
+	var visit func(taskID string)
+	visit = func(taskID string) {
+		index[taskID] = next
+		lowLink[taskID] = next
+		next++
+		stack = append(stack, taskID)
+		onStack[taskID] = true
+
+		for _, dependent := range dag.dependents[taskID] {
+			if _, exists := dag.tasks[dependent]; !exists {
+				continue
+			}
+			if _, visited := index[dependent]; !visited {
+				visit(dependent)
+				if lowLink[dependent] < lowLink[taskID] {
+					lowLink[taskID] = lowLink[dependent]
+				}
+			} else if onStack[dependent] && index[dependent] < lowLink[taskID] {
+				lowLink[taskID] = index[dependent]
+			}
+		}
+
+		if lowLink[taskID] != index[taskID] {
+			return
+		}
+
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == taskID {
+				break
+			}
+		}
+		if len(component) > 1 || containsString(dag.dependencies[taskID], taskID) {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, taskID := range dag.sortedTaskIDs() {
+		if _, visited := index[taskID]; !visited {
+			visit(taskID)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	for _, cycle := range cycles {
+		problems.add(ProblemCycle, "", "", cycle, "circular dependency between tasks %s", strings.Join(cycle, ", "))
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DecisionsMetadataKey holds a task's decision trace: why it was scheduled, skipped or
+// kept, and how it ended
+const DecisionsMetadataKey = "decisions"
+
+// Decision trace events
+const (
+	DecisionScheduled = "scheduled" // dispatched because its dependencies allowed it
+	DecisionSkipped   = "skipped"   // not dispatched because of a skipped dependency or untaken branch
+	DecisionKept      = "kept"      // completed before a resume and not re-run
+	DecisionCompleted = "completed"
+	DecisionFailed    = "failed"
+	DecisionCancelled = "cancelled"
+)
+
+// TaskDecision is one entry of a task's decision trace
+type TaskDecision struct {
+	Event     string    `json:"event"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// recordDecision appends an entry to a task's decision trace. Traces loaded from state
+// decode as []interface{}, so entries are kept in that form.
+func recordDecision(state *models.TaskState, event, reason string) {
+	if state.Metadata == nil {
+		state.Metadata = make(map[string]interface{})
+	}
+	trace, _ := state.Metadata[DecisionsMetadataKey].([]interface{})
+	state.Metadata[DecisionsMetadataKey] = append(trace, TaskDecision{
+		Event:     event,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	})
+}
+
+// scheduleReason explains why a task is being dispatched
+func (dag *DAG) scheduleReason(taskID string, states map[string]*models.TaskState) string {
+	deps := append([]string(nil), dag.dependencies[taskID]...)
+	if len(deps) == 0 {
+		return "ran because it has no dependencies"
+	}
+
+	sort.Strings(deps)
+	var completed, skipped []string
+	for _, dep := range deps {
+		if state := states[dep]; state != nil && state.Status == models.StatusSkipped {
+			skipped = append(skipped, dep)
+		} else {
+			completed = append(completed, dep)
+		}
+	}
+
+	reason := "ran because dependencies completed: " + strings.Join(completed, ", ")
+	if len(skipped) > 0 {
+		reason += "; skipped dependencies ignored: " + strings.Join(skipped, ", ")
+	}
+	return reason
+}
+
+// recordOutcome appends how a dispatched task ended to its decision trace
+func recordOutcome(state *models.TaskState, err error) {
+	attempts := state.RetryCount + 1
+
+	switch {
+	case err == nil && state.Metadata[ResultCacheMetadataKey] == true:
+		recordDecision(state, DecisionCompleted, "reused cached result of an identical earlier run")
+	case err == nil:
+		recordDecision(state, DecisionCompleted, fmt.Sprintf("completed after %d attempt(s)", attempts))
+	case state.Status == models.StatusCancelled:
+		recordDecision(state, DecisionCancelled, fmt.Sprintf("cancelled during attempt %d", attempts))
+	default:
+		reason := fmt.Sprintf("failed after %d attempt(s)", attempts)
+		if state.ErrorCode != "" {
+			reason += " with code " + state.ErrorCode
+		}
+		recordDecision(state, DecisionFailed, reason+": "+err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// decisionTrace returns the events and reasons of a task's decision trace
+func decisionTrace(t *testing.T, state *models.TaskState) ([]string, []string) {
+	t.Helper()
+	trace, ok := state.Metadata[DecisionsMetadataKey].([]interface{})
+	if !ok {
+		t.Fatalf("task %s has no decision trace", state.ID)
+	}
+	var events, reasons []string
+	for _, entry := range trace {
+		decision := entry.(TaskDecision)
+		events = append(events, decision.Event)
+		reasons = append(reasons, decision.Reason)
+	}
+	return events, reasons
+}
+
+func TestSkippedTasksRecordWhyInDecisionTrace(t *testing.T) {
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "check" {
+			execution.TaskStates[task.ID].Output = map[string]interface{}{"condition_result": true}
+		}
+		return nil
+	}), states, nil, 2)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "check", Type: "condition", OnSuccess: []string{"left"}, OnFailure: []string{"right"}},
+		{ID: "left", Type: "data"},
+		{ID: "right", Type: "data"},
+		{ID: "after-right", Type: "data", DependsOn: []string{"right"}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := map[string]string{
+		"right":       "skipped because condition task check evaluated to true and did not take this branch",
+		"after-right": "skipped because all dependencies were skipped (right)",
+	}
+	for taskID, want := range skipped {
+		state := execution.TaskStates[taskID]
+		if state.Status != models.StatusSkipped {
+			t.Errorf("%s: got status %s, want skipped", taskID, state.Status)
+			continue
+		}
+		events, reasons := decisionTrace(t, state)
+		if len(events) != 1 || events[0] != DecisionSkipped || reasons[0] != want {
+			t.Errorf("%s: got trace %v %q, want a single skip because %q", taskID, events, reasons, want)
+		}
+		if !strings.HasSuffix(want, state.Metadata["skip_reason"].(string)) {
+			t.Errorf("%s: got skip reason %q", taskID, state.Metadata["skip_reason"])
+		}
+	}
+
+	events, reasons := decisionTrace(t, execution.TaskStates["left"])
+	if len(events) != 2 || events[0] != DecisionScheduled || events[1] != DecisionCompleted {
+		t.Fatalf("got trace %v for the taken branch, want scheduled then completed", events)
+	}
+	if reasons[0] != "ran because dependencies completed: check" {
+		t.Errorf("got schedule reason %q", reasons[0])
+	}
+}
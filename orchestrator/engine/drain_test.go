@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// drainWorkflow runs step, then finish once it completes
+var drainWorkflow = &models.WorkflowDefinition{ID: "drain", Tasks: []models.Task{
+	{ID: "step", Type: "data"},
+	{ID: "finish", Type: "data", DependsOn: []string{"step"}},
+}}
+
+// drainRun is the outcome of an execution started for a drain test
+type drainRun struct {
+	response *models.WorkflowResponse
+	err      error
+}
+
+// startDrainRun starts an execution in the background and waits for its first task
+func startDrainRun(t *testing.T, executor *WorkflowExecutor, executionID string, started <-chan string) <-chan drainRun {
+	t.Helper()
+	done := make(chan drainRun, 1)
+	go func() {
+		response, err := executor.ExecuteWorkflow(context.Background(), drainWorkflow, &models.WorkflowRequest{ExecutionID: executionID})
+		done <- drainRun{response, err}
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("execution %s never started a task", executionID)
+	}
+	return done
+}
+
+func TestDrainLetsExecutionsFinishWithinTimeout(t *testing.T) {
+	started := make(chan string, 10)
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	var ran []string
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "step" {
+			started <- execution.ID
+			<-release
+		}
+		mutex.Lock()
+		ran = append(ran, task.ID)
+		mutex.Unlock()
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+
+	done := startDrainRun(t, executor, "exec-long", started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() { drained <- executor.Drain(ctx) }()
+
+	// Once draining, new executions are turned away while the running one carries on.
+	// The probe finishes straight away if it gets in before Drain starts.
+	probe := &models.WorkflowDefinition{ID: "probe", Tasks: []models.Task{{ID: "probe", Type: "data"}}}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := executor.ExecuteWorkflow(context.Background(), probe, &models.WorkflowRequest{})
+		if errors.Is(err, ErrShuttingDown) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected ErrShuttingDown while draining, got %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v while an execution was still running", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	run := <-done
+	if run.err != nil || run.response.Status != models.StatusCompleted {
+		t.Fatalf("got %+v, %v, want the execution to complete", run.response, run.err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if ran[len(ran)-1] != "finish" {
+		t.Errorf("ran %v, want the long execution to run to its last task", ran)
+	}
+}
+
+func TestDrainCancelsStragglersAtTimeout(t *testing.T) {
+	started := make(chan string, 10)
+	var mutex sync.Mutex
+	var finished []string
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "step" {
+			started <- execution.ID
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		mutex.Lock()
+		finished = append(finished, execution.ID)
+		mutex.Unlock()
+		return nil
+	}), states, nil, 2)
+
+	runs := map[string]<-chan drainRun{
+		"exec-a": startDrainRun(t, executor, "exec-a", started),
+		"exec-b": startDrainRun(t, executor, "exec-b", started),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := executor.Drain(ctx); err != nil {
+		t.Fatalf("expected the stragglers to stop once cancelled, got %v", err)
+	}
+
+	for id, done := range runs {
+		run := <-done
+		if run.err == nil || run.response.Status != models.StatusCancelled {
+			t.Errorf("%s: got %+v, %v, want it cancelled", id, run.response, run.err)
+		}
+		execution, err := states.LoadExecution(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if execution.Status != models.StatusCancelled || execution.Metadata[CancelReasonMetadataKey] != ShutdownCancelReason {
+			t.Errorf("%s: saved %s with reason %v, want cancelled for %q", id, execution.Status, execution.Metadata[CancelReasonMetadataKey], ShutdownCancelReason)
+		}
+		if executor.IsRunning(id) {
+			t.Errorf("%s is still tracked as running", id)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(finished) != 0 {
+		t.Errorf("tasks after the cancelled step ran for %v", finished)
+	}
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+)
+
+// ProblemInvalidTask is reported by a dry run for a task whose definition is invalid
+const ProblemInvalidTask = "invalid_task"
+
+// TaskValidator checks a task's definition without running it. Task executors that
+// implement it have their checks included in dry runs.
+type TaskValidator interface {
+	ValidateTask(task *models.Task) error
+}
+
+// DryRunResult describes how a workflow would run, found without running any task
+type DryRunResult struct {
+	WorkflowID string       `json:"workflow_id"`
+	Valid      bool         `json:"valid"`
+	Batches    [][]string   `json:"batches,omitempty"` // tasks that would run in parallel, in order
+	Problems   []DAGProblem `json:"problems,omitempty"`
+}
+
+// DryRun checks a workflow as ExecuteWorkflow would see it: the task graph is built,
+// every task is validated and its variable references resolved against the request's
+// variables. Nothing is executed, persisted or sent to a service.
+func (we *WorkflowExecutor) DryRun(ctx context.Context, workflow *models.WorkflowDefinition, request *models.WorkflowRequest) *DryRunResult {
+	result := &DryRunResult{WorkflowID: workflow.ID}
+	var problems validationProblems
+
+	dag, err := NewDAG(workflow.Tasks)
+	var dagErr *DAGValidationError
+	switch {
+	case errors.As(err, &dagErr):
+		problems = append(problems, dagErr.Problems...)
+		dag = nil
+	case err != nil:
+		problems.add(ProblemInvalidTask, "", "", nil, "%v", err)
+		dag = nil
+	default:
+		result.Batches = dag.GetParallelBatches()
+	}
+
+	// Resolve against the variables an execution would start with
+	scratch := &models.WorkflowExecution{
+		ID:        "dry-run",
+		Variables: mergeVariables(workflow.Variables, request.Variables),
+	}
+	we.injectFlags(ctx, scratch)
+
+	validator, _ := we.taskExecutor.(TaskValidator)
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+
+		if validator != nil {
+			if err := validator.ValidateTask(task); err != nil {
+				problems.add(ProblemInvalidTask, task.ID, "", nil, "task %s: %v", task.ID, err)
+			}
+		}
+		if we.secretProvider == nil && containsSecretRef(task.Parameters) {
+			problems.add(ProblemInvalidTask, task.ID, "", nil, "task %s references secrets but no secret provider is configured", task.ID)
+		}
+		if _, _, err := we.interpolateVariables(task, scratch.Variables, false); err != nil {
+			problems.add(ProblemInvalidTask, task.ID, "", nil, "task %s: %v", task.ID, err)
+		}
+	}
+
+	problems = append(problems, we.checkReferences(workflow, dag, scratch.Variables)...)
+
+	result.Problems = problems
+	result.Valid = len(problems) == 0
+	return result
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// eventAppendTimeout bounds how long recording an event may hold up a task
+const eventAppendTimeout = 2 * time.Second
+
+// EventLog durably records the lifecycle events of executions
+type EventLog interface {
+	AppendEvent(ctx context.Context, event *models.ExecutionEvent) error
+}
+
+// SetEventLog makes the executor record workflow and task transitions and retries
+func (we *WorkflowExecutor) SetEventLog(log EventLog) {
+	we.eventLog = log
+}
+
+// taskEventTypes maps the task states that end up in the event log to their event.
+// Retries are recorded where they happen, with the error that caused them.
+var taskEventTypes = map[models.ExecutionStatus]string{
+	models.StatusRunning:   models.EventTaskStarted,
+	models.StatusCompleted: models.EventTaskCompleted,
+	models.StatusFailed:    models.EventTaskFailed,
+	models.StatusCancelled: models.EventTaskCancelled,
+	models.StatusSkipped:   models.EventTaskSkipped,
+}
+
+var workflowEventTypes = map[models.ExecutionStatus]string{
+	models.StatusRunning:   models.EventWorkflowStarted,
+	models.StatusCompleted: models.EventWorkflowCompleted,
+	models.StatusFailed:    models.EventWorkflowFailed,
+	models.StatusCancelled: models.EventWorkflowCancelled,
+}
+
+// recordTaskEvent logs a task's transition into its current state
+func (we *WorkflowExecutor) recordTaskEvent(execution *models.WorkflowExecution, taskState *models.TaskState) {
+	eventType, ok := taskEventTypes[taskState.Status]
+	if !ok {
+		return
+	}
+
+	details := map[string]interface{}{}
+	if taskState.RetryCount > 0 {
+		details["retry_count"] = taskState.RetryCount
+	}
+	if taskState.Error != "" && taskState.Status != models.StatusRunning {
+		details["error"] = taskState.Error
+	}
+	if taskState.ErrorCode != "" && taskState.Status != models.StatusRunning {
+		details["error_code"] = taskState.ErrorCode
+	}
+	if reason, ok := taskState.Metadata["skip_reason"].(string); ok && taskState.Status == models.StatusSkipped {
+		details["reason"] = reason
+	}
+	we.recordEvent(execution, eventType, taskState.ID, details)
+}
+
+// recordWorkflowEvent logs the execution's transition into its current state
+func (we *WorkflowExecutor) recordWorkflowEvent(execution *models.WorkflowExecution) {
+	eventType, ok := workflowEventTypes[execution.Status]
+	if !ok {
+		return
+	}
+
+	details := map[string]interface{}{}
+	if execution.Error != "" {
+		details["error"] = execution.Error
+	}
+	we.recordEvent(execution, eventType, "", details)
+}
+
+// recordEvent appends an event synchronously, so each task's events keep their order.
+// The log is best effort: a failed append is logged and the run carries on.
+func (we *WorkflowExecutor) recordEvent(execution *models.WorkflowExecution, eventType, taskID string, details map[string]interface{}) {
+	if we.eventLog == nil {
+		return
+	}
+	if len(details) == 0 {
+		details = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventAppendTimeout)
+	defer cancel()
+	event := &models.ExecutionEvent{
+		Type:        eventType,
+		ExecutionID: execution.ID,
+		TaskID:      taskID,
+		Timestamp:   time.Now(),
+		Details:     details,
+	}
+	if err := we.eventLog.AppendEvent(ctx, event); err != nil {
+		we.logger.WithError(err).WithFields(logrus.Fields{
+			"execution_id": execution.ID,
+			"event":        eventType,
+		}).Warn("Failed to record execution event")
+	}
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryEventLog keeps appended events in order
+type memoryEventLog struct {
+	mutex  sync.Mutex
+	events []models.ExecutionEvent
+}
+
+func (l *memoryEventLog) AppendEvent(ctx context.Context, event *models.ExecutionEvent) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, *event)
+	return nil
+}
+
+// sequence returns the types of the events recorded for taskID, "" for workflow events
+func (l *memoryEventLog) sequence(taskID string) []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var types []string
+	for _, event := range l.events {
+		if event.TaskID == taskID {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+// find returns the first event of a type recorded for taskID
+func (l *memoryEventLog) find(eventType, taskID string) *models.ExecutionEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i := range l.events {
+		if l.events[i].Type == eventType && l.events[i].TaskID == taskID {
+			return &l.events[i]
+		}
+	}
+	return nil
+}
+
+func TestExecutionRecordsLifecycleEvents(t *testing.T) {
+	attempts := 0
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		switch task.ID {
+		case "check":
+			execution.TaskStates[task.ID].Output = map[string]interface{}{"condition_result": true}
+		case "flaky":
+			attempts++
+			if attempts == 1 {
+				return errors.New("transient")
+			}
+		}
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+	log := &memoryEventLog{}
+	executor.SetEventLog(log)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "check", Type: "condition", OnSuccess: []string{"flaky"}, OnFailure: []string{"other"}},
+		{ID: "flaky", Type: "exec", RetryPolicy: &models.RetryPolicy{MaxRetries: 1, BackoffType: "fixed", InitialDelay: time.Millisecond}},
+		{ID: "other", Type: "exec"},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"":      {models.EventWorkflowStarted, models.EventWorkflowCompleted},
+		"check": {models.EventTaskStarted, models.EventTaskCompleted},
+		"flaky": {models.EventTaskStarted, models.EventTaskRetried, models.EventTaskCompleted},
+		"other": {models.EventTaskSkipped},
+	}
+	for taskID, sequence := range want {
+		if got := log.sequence(taskID); !reflect.DeepEqual(got, sequence) {
+			t.Errorf("task %q: got events %v, want %v", taskID, got, sequence)
+		}
+	}
+
+	// Workflow events bracket every task event
+	if first, last := log.events[0], log.events[len(log.events)-1]; first.Type != models.EventWorkflowStarted || last.Type != models.EventWorkflowCompleted {
+		t.Errorf("got %s first and %s last, want the workflow's start and completion", first.Type, last.Type)
+	}
+	for _, event := range log.events {
+		if event.ExecutionID != response.ExecutionID {
+			t.Errorf("got event %s for execution %q, want %q", event.Type, event.ExecutionID, response.ExecutionID)
+		}
+	}
+
+	if retried := log.find(models.EventTaskRetried, "flaky"); retried.Details["error"] != "transient" || retried.Details["attempt"] != 1 {
+		t.Errorf("unexpected retry details %v", retried.Details)
+	}
+	if skipped := log.find(models.EventTaskSkipped, "other"); skipped.Details["reason"] == nil {
+		t.Errorf("expected the skip event to carry the reason, got %v", skipped.Details)
+	}
+}
+
+func TestFailedExecutionRecordsFailureEvents(t *testing.T) {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return errors.New("no such collection")
+	}), newMemoryStateManager(), nil, 1)
+	log := &memoryEventLog{}
+	executor.SetEventLog(log)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err == nil {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	if got, want := log.sequence("fetch"), []string{models.EventTaskStarted, models.EventTaskFailed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got task events %v, want %v", got, want)
+	}
+	if got, want := log.sequence(""), []string{models.EventWorkflowStarted, models.EventWorkflowFailed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got workflow events %v, want %v", got, want)
+	}
+	if failed := log.find(models.EventTaskFailed, "fetch"); failed.Details["error"] != "no such collection" {
+		t.Errorf("expected the failure event to carry the error, got %v", failed.Details)
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import "orchestrator/models"
+
+// unresolvedCommandReferences lists the placeholders in an exec task's command that the
+// scope cannot resolve. The command runs verbatim in the container, so unlike other
+// parameters these fail the task instead of being left in place.
+func unresolvedCommandReferences(task *models.Task, variables map[string]interface{}) []string {
+	if task.Type != "exec" {
+		return nil
+	}
+
+	command, exists := task.Parameters["command"]
+	if !exists {
+		container, ok := task.Parameters["container"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		command = container["command"]
+	}
+
+	var args []string
+	switch command := command.(type) {
+	case []interface{}:
+		for _, arg := range command {
+			if s, ok := arg.(string); ok {
+				args = append(args, s)
+			}
+		}
+	case []string:
+		args = command
+	}
+
+	unresolved := &unresolvedReferences{seen: make(map[string]bool)}
+	for _, arg := range args {
+		interpolatePlaceholders(arg, variables, unresolved)
+	}
+	return unresolved.names
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateStringEscapedPlaceholder(t *testing.T) {
+	got, unresolved := InterpolateString("echo $${HOME} ${name}", map[string]interface{}{"name": "run", "HOME": "/root"})
+	if got != "echo ${HOME} run" {
+		t.Errorf("got %q", got)
+	}
+	if len(unresolved) != 0 {
+		t.Errorf("escaped placeholder reported as unresolved: %v", unresolved)
+	}
+}
+
+func TestExecuteWorkflowInterpolatesExecCommand(t *testing.T) {
+	var dispatched interface{}
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "fetch" {
+			execution.TaskStates[task.ID].Output = map[string]interface{}{"file": "/data/in.csv"}
+			return nil
+		}
+		dispatched = task.Parameters["command"]
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+
+	workflow := &models.WorkflowDefinition{
+		ID:        "exec-command",
+		Variables: map[string]interface{}{"max_rows": 100},
+		Tasks: []models.Task{
+			{ID: "fetch", Type: "data"},
+			{ID: "process", Type: "exec", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{
+				"command": []interface{}{"sh", "-c", "process --limit ${max_rows} --input ${tasks.fetch.output.file} --home $${HOME}"},
+			}},
+		},
+	}
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []interface{}{"sh", "-c", "process --limit 100 --input /data/in.csv --home ${HOME}"}
+	if !reflect.DeepEqual(dispatched, want) {
+		t.Errorf("got command %v, want %v", dispatched, want)
+	}
+}
+
+func TestExecuteWorkflowFailsOnUnresolvedExecCommand(t *testing.T) {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		t.Error("task with an unresolved command must not be dispatched")
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	workflow := &models.WorkflowDefinition{
+		ID: "exec-command",
+		Tasks: []models.Task{
+			{ID: "process", Type: "exec", Parameters: map[string]interface{}{
+				"container": map[string]interface{}{"command": []interface{}{"process", "--limit", "${max_rows}"}},
+				"note":      "${also_missing}",
+			}},
+		},
+	}
+
+	_, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err == nil || !strings.Contains(err.Error(), "unresolved variables in command: max_rows") {
+		t.Fatalf("expected an unresolved command error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "also_missing") {
+		t.Errorf("only command placeholders should fail the task: %v", err)
+	}
+}
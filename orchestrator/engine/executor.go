@@ -2,16 +2,23 @@ package engine
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"orchestrator/logging"
+	"orchestrator/metrics"
 	"orchestrator/models"
+	"orchestrator/tracing"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TaskExecutor handles the execution of individual tasks
@@ -21,13 +28,48 @@ type TaskExecutor interface {
 
 // WorkflowExecutor orchestrates the execution of entire workflows
 type WorkflowExecutor struct {
-	taskExecutor    TaskExecutor
-	stateManager    StateManager
-	messageCoord    MessageCoordinator
-	maxConcurrent   int
-	logger          *logrus.Logger
+	taskExecutor          TaskExecutor
+	stateManager          StateManager
+	messageCoord          MessageCoordinator
+	maxConcurrent         int
+	defaultQueueTimeout   time.Duration // for tasks without queue_timeout; 0 is unbounded
+	batchAbandonGrace     time.Duration // how long a cancelled batch waits for its tasks
+	taskSlots             chan struct{} // shared by all executions; nil when unlimited
+	artifactStore         ArtifactStore
+	serviceRegistry       ServiceRegistry
+	serviceGrace          time.Duration // how long gated executions wait for missing services
+	flagStore             FlagStore
+	heartbeatStore        HeartbeatStore
+	heartbeatTTL          time.Duration
+	exporter              ExecutionExporter
+	exportTokenPrice      float64 // cost of 1000 tokens in exported records; 0 leaves cost out
+	maxInterpolationDepth int
+	maxInterpolationNodes int
+	resultCache           ResultCache
+	resultCacheTTL        time.Duration
+	sampler               *logging.Sampler
+	secretProvider        SecretProvider
+	running               map[string]*runningExecution
+	runningMutex          sync.Mutex
+	draining              bool // set by Drain; no new executions are started
+	progress              ProgressPublisher
+	eventLog              EventLog
+	callbacks             CallbackNotifier
+	callbackCtx           context.Context // cancelled when Drain gives up on deliveries
+	cancelCallbacks       context.CancelFunc
+	callbacksInFlight     sync.WaitGroup
+	logger                *logrus.Logger
 }
 
+// Default guards against pathologically nested or oversized task parameters
+const (
+	DefaultInterpolationMaxDepth = 32
+	DefaultInterpolationMaxNodes = 10000
+)
+
+// RecoveryAttemptsMetadataKey counts how many times recovery has started an execution again
+const RecoveryAttemptsMetadataKey = "recovery_attempts"
+
 // StateManager interface for workflow state persistence
 type StateManager interface {
 	SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error
@@ -43,24 +85,51 @@ type MessageCoordinator interface {
 	SendExecRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error)
 }
 
+// ArtifactStore persists task outputs as they complete
+type ArtifactStore interface {
+	SaveTaskOutput(ctx context.Context, executionID, taskID string, output map[string]interface{}) error
+}
+
 // NewWorkflowExecutor creates a new workflow executor
 func NewWorkflowExecutor(taskExecutor TaskExecutor, stateManager StateManager, messageCoord MessageCoordinator, maxConcurrent int) *WorkflowExecutor {
 	return &WorkflowExecutor{
-		taskExecutor:  taskExecutor,
-		stateManager:  stateManager,
-		messageCoord:  messageCoord,
-		maxConcurrent: maxConcurrent,
-		logger:        logrus.New(),
+		taskExecutor:      taskExecutor,
+		stateManager:      stateManager,
+		messageCoord:      messageCoord,
+		maxConcurrent:     maxConcurrent,
+		batchAbandonGrace: DefaultBatchAbandonGrace,
+		running:           make(map[string]*runningExecution),
+		logger:            logrus.New(),
 	}
 }
 
+// SetLogSampler thins out per-task logs under load. Failures are logged separately and
+// are never sampled.
+func (we *WorkflowExecutor) SetLogSampler(sampler *logging.Sampler) {
+	we.sampler = sampler
+}
+
+// SetArtifactStore enables incremental persistence of task outputs
+func (we *WorkflowExecutor) SetArtifactStore(store ArtifactStore) {
+	we.artifactStore = store
+}
+
 // ExecuteWorkflow runs a workflow to completion
 func (we *WorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, request *models.WorkflowRequest) (*models.WorkflowResponse, error) {
 	startTime := time.Now()
-	
+
+	if err := we.validateCallbackURL(request.CallbackURL); err != nil {
+		return nil, err
+	}
+
+	executionID := request.ExecutionID
+	if executionID == "" {
+		executionID = generateExecutionID()
+	}
+
 	// Create execution instance
 	execution := &models.WorkflowExecution{
-		ID:            generateExecutionID(),
+		ID:            executionID,
 		WorkflowID:    workflow.ID,
 		CorrelationID: request.CorrelationID,
 		Status:        models.StatusRunning,
@@ -68,8 +137,16 @@ func (we *WorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *model
 		TaskStates:    make(map[string]*models.TaskState),
 		StartTime:     startTime,
 		Metadata:      make(map[string]interface{}),
+		MatrixID:      request.MatrixID,
+		Labels:        workflow.Labels,
+		Workflow:      workflow,
 	}
 
+	// Keep the request with the definition so recovery can start the execution again
+	submitted := *request
+	submitted.ExecutionID = executionID
+	execution.Request = &submitted
+
 	// Initialize task states
 	for _, task := range workflow.Tasks {
 		execution.TaskStates[task.ID] = &models.TaskState{
@@ -81,19 +158,123 @@ func (we *WorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *model
 		}
 	}
 
+	// Expose feature flags as read-only variables
+	we.injectFlags(ctx, execution)
+
+	// Snapshot the effective variables so the run can be reproduced
+	initialVariables := copyVariables(execution.Variables)
+	execution.InitialVariables = RedactVariables(initialVariables)
+
+	// Snapshot service versions so the run is reproducible
+	if request.PinVersions {
+		pinned, err := we.snapshotServiceVersions(workflow)
+		if err != nil {
+			return nil, err
+		}
+		execution.PinnedVersions = pinned
+	}
+
+	if request.Record {
+		execution.Metadata[RecordMetadataKey] = true
+	}
+	if request.RequireServices {
+		execution.Metadata[RequireServicesMetadataKey] = true
+	}
+	if request.ReplayFrom != "" {
+		execution.Metadata[ReplayMetadataKey] = request.ReplayFrom
+	}
+	if request.WorkflowTemplate != "" {
+		execution.Metadata[TemplateMetadataKey] = request.WorkflowTemplate
+	}
+	if request.TemplateVersion != "" {
+		execution.Metadata[TemplateVersionMetadataKey] = request.TemplateVersion
+	}
+	if request.RecoveryAttempts > 0 {
+		execution.Metadata[RecoveryAttemptsMetadataKey] = request.RecoveryAttempts
+	}
+	if request.ParentExecutionID != "" {
+		execution.Metadata[ParentExecutionMetadataKey] = request.ParentExecutionID
+		execution.Metadata[WorkflowStackMetadataKey] = request.WorkflowStack
+	}
+
+	if we.heartbeatStore != nil && we.heartbeatTTL > 0 {
+		execution.Metadata[HeartbeatTTLMetadataKey] = we.heartbeatTTL.Seconds()
+	}
+
+	return we.runExecution(ctx, workflow, execution, initialVariables, startTime)
+}
+
+// ResumeWorkflow continues a stopped execution. Completed tasks, including ones completed
+// manually, keep their output and are not re-run; every other task runs again.
+func (we *WorkflowExecutor) ResumeWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) (*models.WorkflowResponse, error) {
+	for _, task := range workflow.Tasks {
+		state, exists := execution.TaskStates[task.ID]
+		if exists && state.Status == models.StatusCompleted {
+			continue
+		}
+		execution.TaskStates[task.ID] = &models.TaskState{
+			ID:         task.ID,
+			Status:     models.StatusPending,
+			RetryCount: 0,
+			Output:     make(map[string]interface{}),
+			Metadata:   make(map[string]interface{}),
+		}
+	}
+
+	execution.Status = models.StatusRunning
+	execution.EndTime = nil
+	execution.Error = ""
+	execution.Workflow = workflow
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"workflow_id":  workflow.ID,
+	}).Info("Resuming workflow execution")
+	we.recordEvent(execution, models.EventWorkflowResumed, "", nil)
+
+	return we.runExecution(ctx, workflow, execution, copyVariables(execution.Variables), time.Now())
+}
+
+// runExecution executes a prepared execution and records its outcome. Variable changes are
+// diffed against initialVariables.
+func (we *WorkflowExecutor) runExecution(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution, initialVariables map[string]interface{}, startTime time.Time) (*models.WorkflowResponse, error) {
+	// Register the run so CancelWorkflow can stop it
+	ctx, untrack, err := we.trackExecution(ctx, execution)
+	if err != nil {
+		return nil, err
+	}
+	defer untrack()
+
+	// Root span of the execution; task and service request spans are its children
+	ctx, span := tracing.Tracer().Start(ctx, "workflow.execute", trace.WithAttributes(
+		attribute.String("workflow.id", workflow.ID),
+		attribute.String("workflow.execution_id", execution.ID),
+		attribute.String("workflow.correlation_id", execution.CorrelationID),
+	))
+	var spanErr error
+	defer func() { tracing.End(span, spanErr) }()
+
 	// Save initial state
 	if err := we.stateManager.SaveExecution(ctx, execution); err != nil {
 		return nil, fmt.Errorf("failed to save initial execution state: %w", err)
 	}
+	notifyExecutionStarted(ctx, execution.ID)
+
+	stopHeartbeat := we.startHeartbeat(execution)
 
 	we.logger.WithFields(logrus.Fields{
 		"execution_id":   execution.ID,
 		"workflow_id":    workflow.ID,
-		"correlation_id": request.CorrelationID,
+		"correlation_id": execution.CorrelationID,
 	}).Info("Starting workflow execution")
+	metrics.WorkflowStarted(workflowMetricLabel(execution))
+	we.publishWorkflowProgress(execution)
 
-	// Execute workflow
-	err := we.executeDAG(ctx, workflow, execution)
+	// Execute workflow once the services it needs are there, if the request asked for that
+	err = we.awaitServices(ctx, workflow, execution)
+	if err == nil {
+		err = we.executeDAG(ctx, workflow, execution)
+	}
 
 	// Update final state
 	endTime := time.Now()
@@ -101,24 +282,53 @@ func (we *WorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *model
 
 	if err != nil {
 		execution.Status = models.StatusFailed
+		if ctx.Err() == context.Canceled {
+			execution.Status = models.StatusCancelled
+		}
 		execution.Error = err.Error()
+		if reason, cancelled := we.cancelReason(execution.ID); cancelled && execution.Status == models.StatusCancelled {
+			execution.Error = "workflow cancelled: " + reason
+			if execution.Metadata == nil {
+				execution.Metadata = make(map[string]interface{})
+			}
+			execution.Metadata[CancelReasonMetadataKey] = reason
+		}
 	} else {
 		execution.Status = models.StatusCompleted
 	}
 
-	// Save final state
-	if saveErr := we.stateManager.SaveExecution(ctx, execution); saveErr != nil {
+	metrics.WorkflowFinished(workflowMetricLabel(execution), string(execution.Status))
+	span.SetAttributes(attribute.String("workflow.status", string(execution.Status)))
+	spanErr = err
+
+	if changes := diffVariables(initialVariables, execution.Variables); len(changes) > 0 {
+		execution.VariableChanges = RedactVariables(changes)
+	}
+
+	// Save final state; a cancelled context must not prevent recording the outcome
+	saveCtx := ctx
+	if ctx.Err() != nil {
+		saveCtx = context.Background()
+	}
+	if saveErr := we.stateManager.SaveExecution(saveCtx, execution); saveErr != nil {
 		we.logger.WithError(saveErr).Error("Failed to save final execution state")
 	}
+	// Only now, so the watchdog never sees a running execution without a heartbeat
+	stopHeartbeat()
+
+	we.exportExecution(workflow, execution)
+	we.publishWorkflowProgress(execution)
 
 	// Build response
 	response := &models.WorkflowResponse{
-		CorrelationID: request.CorrelationID,
-		ExecutionID:   execution.ID,
-		Status:        execution.Status,
-		Success:       execution.Status == models.StatusCompleted,
-		Duration:      endTime.Sub(startTime),
-		Timestamp:     endTime,
+		CorrelationID:    execution.CorrelationID,
+		ExecutionID:      execution.ID,
+		Status:           execution.Status,
+		Success:          execution.Status == models.StatusCompleted,
+		Duration:         endTime.Sub(startTime),
+		Timestamp:        endTime,
+		InitialVariables: execution.InitialVariables,
+		VariableChanges:  execution.VariableChanges,
 	}
 
 	if err != nil {
@@ -138,6 +348,8 @@ func (we *WorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *model
 		response.TaskResults = taskResults
 	}
 
+	we.notifyCallback(execution, response)
+
 	return response, err
 }
 
@@ -149,6 +361,13 @@ func (we *WorkflowExecutor) executeDAG(ctx context.Context, workflow *models.Wor
 		return fmt.Errorf("failed to build DAG: %w", err)
 	}
 
+	// Strict workflows fail before any task runs, listing every unresolved reference at once
+	if workflow.Interpolation == models.InterpolationStrict {
+		if problems := we.checkReferences(workflow, dag, execution.Variables); len(problems) > 0 {
+			return &ReferenceValidationError{Problems: problems}
+		}
+	}
+
 	// Create execution context with timeout
 	timeout := time.Duration(workflow.Timeout) * time.Second
 	if timeout == 0 {
@@ -159,19 +378,22 @@ func (we *WorkflowExecutor) executeDAG(ctx context.Context, workflow *models.Wor
 
 	// Execute tasks in parallel batches
 	batches := dag.GetParallelBatches()
-	
+
 	for batchIndex, batch := range batches {
-		we.logger.WithFields(logrus.Fields{
+		we.sampler.Info(we.logger, logrus.Fields{
 			"execution_id": execution.ID,
 			"batch_index":  batchIndex,
 			"batch_size":   len(batch),
 			"tasks":        batch,
-		}).Info("Executing task batch")
+		}, "Executing task batch")
 
 		if err := we.executeBatch(execCtx, batch, workflow, execution, dag); err != nil {
 			return fmt.Errorf("batch %d execution failed: %w", batchIndex, err)
 		}
 
+		// Shrink outputs nothing downstream can read anymore
+		we.applyOutputRetention(execCtx, workflow, execution, dag)
+
 		// Save state after each batch
 		if err := we.stateManager.SaveExecution(execCtx, execution); err != nil {
 			we.logger.WithError(err).Warn("Failed to save execution state after batch")
@@ -192,16 +414,19 @@ func (we *WorkflowExecutor) executeBatch(ctx context.Context, taskIDs []string,
 	semaphore := make(chan struct{}, we.maxConcurrent)
 	errChan := make(chan error, len(taskIDs))
 	var wg sync.WaitGroup
+	tasks := newBatchTasks()
+
+	// Outputs of earlier batches, referenced as ${tasks.<id>.output...}
+	taskOutputs := completedTaskOutputs(execution)
 
 	// Execute tasks in parallel
 	for _, taskID := range taskIDs {
 		wg.Add(1)
+		tasks.start(taskID)
 		go func(id string) {
 			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			defer tasks.finish(id)
+			queuedAt := time.Now()
 
 			task, exists := dag.GetTask(id)
 			if !exists {
@@ -209,15 +434,54 @@ func (we *WorkflowExecutor) executeBatch(ctx context.Context, taskIDs []string,
 				return
 			}
 
-			// Execute task
-			if err := we.executeTask(ctx, task, execution); err != nil {
+			// Tasks completed before a resume keep their output and are not re-run
+			if state := execution.TaskStates[id]; state != nil && state.Status == models.StatusCompleted {
+				recordDecision(state, DecisionKept, "completed before the execution resumed")
+				return
+			}
+
+			// Tasks on a branch a condition did not take are skipped, not dispatched
+			if reason, skip := dag.SkipReason(id, execution.TaskStates); skip {
+				we.skipTask(execution, id, reason)
+				return
+			}
+
+			// Wait for room under this batch's limit and the one shared with other executions
+			release, err := we.waitForDispatch(ctx, task, semaphore)
+			if err != nil {
+				var queueErr *QueueTimeoutError
+				if errors.As(err, &queueErr) {
+					we.failQueuedTask(execution, task, queueErr, time.Since(queuedAt))
+				}
+				errChan <- fmt.Errorf("task %s not started: %w", id, err)
+				return
+			}
+			defer release()
+
+			// Execute task, tracing why it ran and how it ended
+			view := tasks.detach(execution, id)
+			taskState := view.TaskStates[id]
+			recordTaskTiming(taskState, PhaseQueueWait, time.Since(queuedAt))
+			recordDecision(taskState, DecisionScheduled, dag.scheduleReason(id, view.TaskStates))
+			err = we.executeTask(ctx, workflow, task, view, taskOutputs)
+			recordOutcome(taskState, err)
+			if !tasks.commit(execution, view, id) {
+				return // abandoned while running
+			}
+			if err != nil {
 				errChan <- fmt.Errorf("task %s failed: %w", id, err)
+				return
 			}
+
+			we.persistTaskOutput(ctx, workflow, task, execution)
 		}(taskID)
 	}
 
-	// Wait for all tasks to complete
-	wg.Wait()
+	// Wait for all tasks to complete, or give up on ones that ignore cancellation.
+	// errChan is left open then, since abandoned tasks may still send to it.
+	if err := we.awaitBatch(ctx, &wg, tasks, execution); err != nil {
+		return err
+	}
 	close(errChan)
 
 	// Check for errors
@@ -233,30 +497,108 @@ func (we *WorkflowExecutor) executeBatch(ctx context.Context, taskIDs []string,
 	return nil
 }
 
+// skipTask marks a task skipped without dispatching it
+func (we *WorkflowExecutor) skipTask(execution *models.WorkflowExecution, taskID, reason string) {
+	taskState := execution.TaskStates[taskID]
+	now := time.Now()
+	taskState.Status = models.StatusSkipped
+	taskState.EndTime = &now
+	if taskState.Metadata == nil {
+		taskState.Metadata = make(map[string]interface{})
+	}
+	taskState.Metadata["skip_reason"] = reason
+	recordDecision(taskState, DecisionSkipped, "skipped because "+reason)
+	we.publishTaskProgress(execution, taskState)
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"task_id":      taskID,
+		"reason":       reason,
+	}).Info("Skipping task")
+}
+
 // executeTask executes a single task with retry logic
-func (we *WorkflowExecutor) executeTask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+func (we *WorkflowExecutor) executeTask(ctx context.Context, workflow *models.WorkflowDefinition, task *models.Task, execution *models.WorkflowExecution, taskOutputs map[string]interface{}) (err error) {
 	taskState := execution.TaskStates[task.ID]
-	
-	we.logger.WithFields(logrus.Fields{
+
+	ctx, span := tracing.Tracer().Start(ctx, "task.execute", trace.WithAttributes(
+		attribute.String("task.id", task.ID),
+		attribute.String("task.type", task.Type),
+	))
+	defer func() {
+		span.SetAttributes(
+			attribute.String("task.status", string(taskState.Status)),
+			attribute.Int("task.retry_count", taskState.RetryCount),
+		)
+		tracing.End(span, err)
+		we.publishTaskProgress(execution, taskState)
+	}()
+
+	we.sampler.Info(we.logger, logrus.Fields{
 		"execution_id": execution.ID,
 		"task_id":      task.ID,
 		"task_type":    task.Type,
-	}).Info("Starting task execution")
+	}, "Starting task execution")
 
 	taskState.Status = models.StatusRunning
 	startTime := time.Now()
 	taskState.StartTime = &startTime
+	we.publishTaskProgress(execution, taskState)
+
+	workflowLabel := workflowMetricLabel(execution)
+	defer func() {
+		metrics.TaskFinished(workflowLabel, task.Type, string(taskState.Status), time.Since(startTime))
+	}()
 
 	// Apply variable interpolation
-	interpolatedTask, err := we.interpolateVariables(task, execution.Variables)
+	scope := interpolationScope(execution.Variables, taskOutputs)
+	interpolatedTask, unresolved, err := we.interpolateVariables(task, scope, workflow.Interpolation == models.InterpolationStrict)
 	if err != nil {
 		taskState.Status = models.StatusFailed
 		taskState.Error = fmt.Sprintf("Variable interpolation failed: %v", err)
 		return err
 	}
+	we.warnUnresolvedTaskReferences(execution, task.ID, unresolved)
+	if missing := unresolvedCommandReferences(task, scope); len(missing) > 0 {
+		err = fmt.Errorf("unresolved variables in command: %s", strings.Join(missing, ", "))
+		taskState.Status = models.StatusFailed
+		taskState.Error = fmt.Sprintf("Variable interpolation failed: %v", err)
+		return err
+	}
+
+	// Deterministic tasks whose resolved inputs ran before reuse the earlier output
+	cacheKey := we.cachedTaskKey(interpolatedTask)
+	if cacheKey != "" && we.loadCachedResult(ctx, cacheKey, taskState) {
+		endTime := time.Now()
+		taskState.EndTime = &endTime
+		taskState.Status = models.StatusCompleted
+
+		we.sampler.Info(we.logger, logrus.Fields{
+			"execution_id": execution.ID,
+			"task_id":      task.ID,
+			"cache_key":    cacheKey,
+		}, "Reused cached task result")
+
+		return nil
+	}
+
+	// Secrets are resolved into a dispatch-only copy so they never reach task state
+	dispatchTask, secrets, err := we.resolveSecrets(ctx, interpolatedTask)
+	if err != nil {
+		taskState.Status = models.StatusFailed
+		taskState.Error = err.Error()
+		return err
+	}
+
+	// Services read the execution window from timeout; both copies are the task's own
+	if task.ExecTimeout > 0 {
+		dispatchTask.Timeout = task.ExecTimeout
+	}
 
 	// Execute with retry logic
 	var lastErr error
+	var retryAfter time.Duration
+	var timedOut bool
 	maxRetries := 0
 	if task.RetryPolicy != nil {
 		maxRetries = task.RetryPolicy.MaxRetries
@@ -266,38 +608,95 @@ func (we *WorkflowExecutor) executeTask(ctx context.Context, task *models.Task,
 		if attempt > 0 {
 			taskState.Status = models.StatusRetrying
 			taskState.RetryCount = attempt
+			metrics.TaskRetried(workflowLabel, task.Type)
+			we.publishTaskProgress(execution, taskState)
 
 			// Apply backoff delay
 			delay := we.calculateBackoffDelay(task.RetryPolicy, attempt)
+			if retryAfter > delay {
+				delay = retryAfter // honor the delay requested by a rate limited service
+			}
 			we.logger.WithFields(logrus.Fields{
 				"task_id": task.ID,
 				"attempt": attempt,
 				"delay":   delay,
 			}).Info("Retrying task execution")
-			
-			time.Sleep(delay)
+			we.recordEvent(execution, models.EventTaskRetried, task.ID, map[string]interface{}{
+				"attempt": attempt,
+				"delay":   delay.String(),
+				"error":   lastErr.Error(),
+			})
+
+			backoffStart := time.Now()
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				lastErr = ctx.Err()
+			case <-timer.C:
+			}
+			recordTaskTiming(taskState, PhaseBackoff, time.Since(backoffStart))
+			if ctx.Err() != nil {
+				break
+			}
 		}
 
-		// Create task-specific context with timeout
-		taskTimeout := time.Duration(task.Timeout) * time.Second
-		if taskTimeout == 0 {
-			taskTimeout = 5 * time.Minute // default task timeout
+		// Refuse to dispatch when no replica runs the version pinned at start
+		if err := we.checkPinnedVersion(task, execution); err != nil {
+			lastErr = err
+			break
 		}
-		taskCtx, cancel := context.WithTimeout(ctx, taskTimeout)
+
+		// Each attempt gets the full exec timeout; time spent queued doesn't count
+		call := taskCallFor(execution, task.ID, attempt)
+		call.Secrets = secrets
+		call.PinnedVersion = pinnedVersion(task, execution)
+		taskCtx, cancel := context.WithTimeout(WithTaskCall(ctx, call), execTimeout(task))
 
 		// Execute task
-		err = we.taskExecutor.ExecuteTask(taskCtx, interpolatedTask, execution)
+		execStart := time.Now()
+		err = we.taskExecutor.ExecuteTask(taskCtx, dispatchTask, execution)
+		recordTaskTiming(taskState, PhaseExec, time.Since(execStart))
+		timedOut = err != nil && taskCtx.Err() == context.DeadlineExceeded
 		cancel()
 
+		// A nominal success can still be retried when its output matches retry_if
+		if err == nil && task.RetryIf != "" {
+			retry, evalErr := we.shouldRetryOutput(task, execution)
+			if evalErr != nil {
+				lastErr = evalErr
+				break
+			}
+			if retry {
+				err = fmt.Errorf("task output matched retry_if condition: %s", task.RetryIf)
+			}
+		}
+
 		if err == nil {
+			lastErr = nil
 			break // Success
 		}
 
 		lastErr = err
+		retryAfter = 0
+		var rateErr *RetryAfterError
+		if errors.As(err, &rateErr) {
+			retryAfter = rateErr.RetryAfter
+		}
 		we.logger.WithError(err).WithFields(logrus.Fields{
 			"task_id": task.ID,
 			"attempt": attempt + 1,
 		}).Warn("Task execution attempt failed")
+
+		// Retrying is pointless once the workflow itself is cancelled or out of time
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Operations that could repeat side effects aren't retried unless forced
+		if attempt < maxRetries && !we.retryAllowed(execution, task) {
+			break
+		}
 	}
 
 	// Update final task state
@@ -305,50 +704,173 @@ func (we *WorkflowExecutor) executeTask(ctx context.Context, task *models.Task,
 	taskState.EndTime = &endTime
 
 	if lastErr != nil {
-		taskState.Status = models.StatusFailed
+		taskState.Status, taskState.ErrorCode = classifyTaskError(ctx, timedOut)
 		taskState.Error = lastErr.Error()
 		return lastErr
 	}
 
 	taskState.Status = models.StatusCompleted
-	
-	we.logger.WithFields(logrus.Fields{
+
+	if cacheKey != "" {
+		we.saveCachedResult(ctx, cacheKey, taskState)
+	}
+
+	we.sampler.Info(we.logger, logrus.Fields{
 		"execution_id": execution.ID,
 		"task_id":      task.ID,
 		"duration":     endTime.Sub(*taskState.StartTime),
 		"retry_count":  taskState.RetryCount,
-	}).Info("Task execution completed")
+	}, "Task execution completed")
 
 	return nil
 }
 
+// classifyTaskError decides the final state of a task that did not succeed. Cancellation
+// of the workflow context marks the task cancelled; an elapsed task or workflow timeout
+// is a failure with its own error code; anything else is a plain failure.
+func classifyTaskError(ctx context.Context, taskTimedOut bool) (models.ExecutionStatus, string) {
+	switch {
+	case ctx.Err() == context.Canceled:
+		return models.StatusCancelled, models.TaskErrorCancelled
+	case ctx.Err() == context.DeadlineExceeded, taskTimedOut:
+		return models.StatusFailed, models.TaskErrorTimeout
+	default:
+		return models.StatusFailed, ""
+	}
+}
+
+// shouldRetryOutput evaluates a task's retry_if expression against its latest output.
+// The output is visible as "output" alongside the workflow variables.
+func (we *WorkflowExecutor) shouldRetryOutput(task *models.Task, execution *models.WorkflowExecution) (bool, error) {
+	scope := make(map[string]interface{}, len(execution.Variables)+1)
+	for k, v := range execution.Variables {
+		scope[k] = v
+	}
+	scope["output"] = execution.TaskStates[task.ID].Output
+
+	retry, err := EvaluateCondition(task.RetryIf, scope)
+	if err != nil {
+		return false, fmt.Errorf("invalid retry_if expression %q: %w", task.RetryIf, err)
+	}
+
+	return retry, nil
+}
+
+// persistTaskOutput writes a completed task's output to the artifact store when enabled
+// for the task or the whole workflow. Failures are logged and do not fail the task.
+func (we *WorkflowExecutor) persistTaskOutput(ctx context.Context, workflow *models.WorkflowDefinition, task *models.Task, execution *models.WorkflowExecution) {
+	if we.artifactStore == nil || !(task.PersistOutput || workflow.PersistOutputs) {
+		return
+	}
+
+	taskState := execution.TaskStates[task.ID]
+	if err := we.artifactStore.SaveTaskOutput(ctx, execution.ID, task.ID, taskState.Output); err != nil {
+		we.logger.WithError(err).WithFields(logrus.Fields{
+			"execution_id": execution.ID,
+			"task_id":      task.ID,
+		}).Warn("Failed to persist task output artifact")
+	}
+}
+
 // interpolateVariables replaces variable placeholders in task parameters
-func (we *WorkflowExecutor) interpolateVariables(task *models.Task, variables map[string]interface{}) (*models.Task, error) {
+// In strict mode every unresolved reference across the parameters is collected and
+// reported in a single error; otherwise they are returned and left in place.
+func (we *WorkflowExecutor) interpolateVariables(task *models.Task, variables map[string]interface{}, strict bool) (*models.Task, []string, error) {
 	// Clone task to avoid modifying original
 	interpolated := *task
 	interpolated.Parameters = make(map[string]interface{})
 
+	state := we.newInterpolationState()
+	unresolved := state.unresolved
+
 	// Interpolate parameters
 	for key, value := range task.Parameters {
-		interpolatedValue, err := we.interpolateValue(value, variables)
+		interpolatedValue, err := we.interpolateValue(value, variables, state, 1)
 		if err != nil {
-			return nil, fmt.Errorf("failed to interpolate parameter %s: %w", key, err)
+			return nil, nil, fmt.Errorf("failed to interpolate parameter %s: %w", key, err)
 		}
 		interpolated.Parameters[key] = interpolatedValue
 	}
 
-	return &interpolated, nil
+	if strict && len(unresolved.names) > 0 {
+		sort.Strings(unresolved.names)
+		return nil, nil, fmt.Errorf("unresolved variables in task %s: %s", task.ID, strings.Join(unresolved.names, ", "))
+	}
+
+	return &interpolated, unresolved.names, nil
+}
+
+// unresolvedReferences collects the distinct variable names interpolation could not resolve
+type unresolvedReferences struct {
+	names []string
+	seen  map[string]bool
+}
+
+func (u *unresolvedReferences) add(name string) {
+	if u == nil || u.seen[name] {
+		return
+	}
+	u.seen[name] = true
+	u.names = append(u.names, name)
+}
+
+// SetInterpolationLimits bounds how deeply task parameters may nest and how many values
+// they may contain in total; zero keeps the default for that limit
+func (we *WorkflowExecutor) SetInterpolationLimits(maxDepth, maxNodes int) {
+	we.maxInterpolationDepth = maxDepth
+	we.maxInterpolationNodes = maxNodes
+}
+
+// interpolationState tracks one task's interpolation: unresolved references and the
+// number of values visited against the limits
+type interpolationState struct {
+	unresolved *unresolvedReferences
+	maxDepth   int
+	maxNodes   int
+	nodes      int
+}
+
+func (we *WorkflowExecutor) newInterpolationState() *interpolationState {
+	state := &interpolationState{
+		unresolved: &unresolvedReferences{seen: make(map[string]bool)},
+		maxDepth:   we.maxInterpolationDepth,
+		maxNodes:   we.maxInterpolationNodes,
+	}
+	if state.maxDepth <= 0 {
+		state.maxDepth = DefaultInterpolationMaxDepth
+	}
+	if state.maxNodes <= 0 {
+		state.maxNodes = DefaultInterpolationMaxNodes
+	}
+	return state
+}
+
+// visit counts a value at the given nesting depth, failing once either limit is exceeded.
+// The depth limit also stops self-referential structures from recursing forever.
+func (s *interpolationState) visit(depth int) error {
+	if depth > s.maxDepth {
+		return fmt.Errorf("parameters nest deeper than the maximum of %d levels", s.maxDepth)
+	}
+	s.nodes++
+	if s.nodes > s.maxNodes {
+		return fmt.Errorf("parameters contain more than the maximum of %d values", s.maxNodes)
+	}
+	return nil
 }
 
 // interpolateValue recursively interpolates variables in values
-func (we *WorkflowExecutor) interpolateValue(value interface{}, variables map[string]interface{}) (interface{}, error) {
+func (we *WorkflowExecutor) interpolateValue(value interface{}, variables map[string]interface{}, state *interpolationState, depth int) (interface{}, error) {
+	if err := state.visit(depth); err != nil {
+		return nil, err
+	}
+
 	switch v := value.(type) {
 	case string:
-		return we.interpolateString(v, variables)
+		return we.interpolateString(v, variables, state.unresolved)
 	case map[string]interface{}:
 		result := make(map[string]interface{})
 		for k, val := range v {
-			interpolatedVal, err := we.interpolateValue(val, variables)
+			interpolatedVal, err := we.interpolateValue(val, variables, state, depth+1)
 			if err != nil {
 				return nil, err
 			}
@@ -358,7 +880,7 @@ func (we *WorkflowExecutor) interpolateValue(value interface{}, variables map[st
 	case []interface{}:
 		result := make([]interface{}, len(v))
 		for i, val := range v {
-			interpolatedVal, err := we.interpolateValue(val, variables)
+			interpolatedVal, err := we.interpolateValue(val, variables, state, depth+1)
 			if err != nil {
 				return nil, err
 			}
@@ -370,21 +892,46 @@ func (we *WorkflowExecutor) interpolateValue(value interface{}, variables map[st
 	}
 }
 
-// interpolateString replaces ${variable} placeholders in strings
-func (we *WorkflowExecutor) interpolateString(s string, variables map[string]interface{}) (string, error) {
-	re := regexp.MustCompile(`\$\{([^}]+)\}`)
-	
-	return re.ReplaceAllStringFunc(s, func(match string) string {
+// interpolateString replaces ${variable} placeholders in strings, recording any it
+// cannot resolve
+func (we *WorkflowExecutor) interpolateString(s string, variables map[string]interface{}, unresolved *unresolvedReferences) (string, error) {
+	return interpolatePlaceholders(s, variables, unresolved), nil
+}
+
+// InterpolateString replaces ${name} placeholders, including dotted paths, with values from
+// variables, and turns $${name} into a literal ${name}. Placeholders that cannot be
+// resolved are kept and their names returned.
+func InterpolateString(s string, variables map[string]interface{}) (string, []string) {
+	unresolved := &unresolvedReferences{seen: make(map[string]bool)}
+	return interpolatePlaceholders(s, variables, unresolved), unresolved.names
+}
+
+// placeholderPattern matches ${name}, and $${name}, which escapes a literal ${name}
+var placeholderPattern = regexp.MustCompile(`\$?\$\{([^}]+)\}`)
+
+func interpolatePlaceholders(s string, variables map[string]interface{}, unresolved *unresolvedReferences) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		// $${name} passes ${name} through, e.g. for a shell in an exec command
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
 		// Extract variable name
 		varName := match[2 : len(match)-1] // Remove ${ and }
 		
 		if value, exists := variables[varName]; exists {
 			return fmt.Sprintf("%v", value)
 		}
-		
+
+		// Fall back to dotted lookups such as ${flags.use_cheap_model}
+		if value := lookupVariable(variables, varName); value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+
 		// Keep placeholder if variable not found
+		unresolved.add(varName)
 		return match
-	}), nil
+	})
 }
 
 // calculateBackoffDelay calculates delay for retry attempts
@@ -398,18 +945,60 @@ func (we *WorkflowExecutor) calculateBackoffDelay(policy *models.RetryPolicy, at
 	switch policy.BackoffType {
 	case "exponential":
 		for i := 1; i < attempt; i++ {
+			// Stop doubling at the cap, which also keeps long retry chains from overflowing
+			if policy.MaxDelay > 0 && delay >= policy.MaxDelay {
+				break
+			}
+			if delay > math.MaxInt64/2 {
+				delay = math.MaxInt64
+				break
+			}
 			delay *= 2
 		}
 	case "linear":
 		delay = time.Duration(int64(delay) * int64(attempt))
-	// "fixed" uses initial delay as-is
+		// "fixed" uses initial delay as-is
 	}
 
 	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
 		delay = policy.MaxDelay
 	}
 
-	return delay
+	return applyJitter(delay, policy.Jitter)
+}
+
+// applyJitter randomizes a backoff delay so tasks retrying against the same service spread
+// out instead of retrying in lockstep. Full jitter picks from [0, delay], equal jitter from
+// [delay/2, delay]; no jitter returns the delay unchanged.
+func applyJitter(delay time.Duration, jitter string) time.Duration {
+	if delay <= 0 {
+		return delay
+	}
+
+	switch jitter {
+	case models.JitterFull:
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	case models.JitterEqual:
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+	default:
+		return delay
+	}
+}
+
+// RetryAfterError is returned by task executors when a downstream service asks callers
+// to wait before retrying; the retry loop waits at least RetryAfter before the next attempt
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.Err, e.RetryAfter)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
 }
 
 // mergeVariables combines workflow and request variables, with request taking precedence
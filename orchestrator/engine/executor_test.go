@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"sync"
+	"testing"
+)
+
+// fakeArtifactStore records the task outputs saved for each execution
+type fakeArtifactStore struct {
+	mutex   sync.Mutex
+	outputs map[string]map[string]interface{}
+}
+
+func (f *fakeArtifactStore) SaveTaskOutput(ctx context.Context, executionID, taskID string, output map[string]interface{}) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.outputs == nil {
+		f.outputs = make(map[string]map[string]interface{})
+	}
+	f.outputs[taskID] = output
+	return nil
+}
+
+func (f *fakeArtifactStore) saved(taskID string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	_, exists := f.outputs[taskID]
+	return exists
+}
+
+func TestTaskOutputsArePersistedAsTasksComplete(t *testing.T) {
+	store := &fakeArtifactStore{}
+	var fetchSavedBeforeReport bool
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "report" {
+			fetchSavedBeforeReport = store.saved("fetch")
+		}
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"task": task.ID}
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetArtifactStore(store)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", PersistOutputs: true, Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "report", Type: "data", DependsOn: []string{"fetch"}},
+	}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !fetchSavedBeforeReport {
+		t.Error("expected fetch's output in the store before the dependent task ran")
+	}
+	if !store.saved("report") {
+		t.Error("expected the last task's output in the store")
+	}
+}
+
+func TestTaskOutputPersistenceIsGatedPerTask(t *testing.T) {
+	store := &fakeArtifactStore{}
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetArtifactStore(store)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "kept", Type: "data", PersistOutput: true},
+		{ID: "skipped", Type: "data"},
+	}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !store.saved("kept") || store.saved("skipped") {
+		t.Errorf("expected only the opted-in task to be persisted, got %v", store.outputs)
+	}
+}
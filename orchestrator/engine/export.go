@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// exportTimeout bounds how long a single export may take
+const exportTimeout = 30 * time.Second
+
+// ExecutionExporter ships finished executions to an external analytics sink
+type ExecutionExporter interface {
+	ExportExecution(ctx context.Context, record *models.ExecutionRecord) error
+}
+
+// SetExecutionExporter enables exporting every execution that reaches a terminal state
+func (we *WorkflowExecutor) SetExecutionExporter(exporter ExecutionExporter) {
+	we.exporter = exporter
+}
+
+// SetExportTokenPrice sets the cost of 1000 tokens, used to price the token usage of
+// exported executions
+func (we *WorkflowExecutor) SetExportTokenPrice(pricePer1K float64) {
+	we.exportTokenPrice = pricePer1K
+}
+
+// exportExecution sends the execution summary in the background. Exports are best
+// effort: failures are logged and never affect the execution outcome.
+func (we *WorkflowExecutor) exportExecution(workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) {
+	if we.exporter == nil {
+		return
+	}
+
+	record := buildExecutionRecord(workflow, execution)
+	record.Cost = float64(record.TokensUsed) * we.exportTokenPrice / 1000
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		if err := we.exporter.ExportExecution(ctx, record); err != nil {
+			we.logger.WithError(err).WithFields(logrus.Fields{
+				"execution_id": record.ExecutionID,
+				"status":       record.Status,
+			}).Warn("Failed to export execution record")
+		}
+	}()
+}
+
+// buildExecutionRecord snapshots the execution so the export doesn't race later changes
+func buildExecutionRecord(workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) *models.ExecutionRecord {
+	record := &models.ExecutionRecord{
+		ExecutionID:   execution.ID,
+		WorkflowID:    execution.WorkflowID,
+		WorkflowName:  workflow.Name,
+		CorrelationID: execution.CorrelationID,
+		MatrixID:      execution.MatrixID,
+		Status:        execution.Status,
+		Error:         execution.Error,
+		StartTime:     execution.StartTime,
+		Tasks:         make([]models.TaskRecord, 0, len(workflow.Tasks)),
+	}
+
+	if len(execution.Labels) > 0 {
+		record.Labels = make(map[string]string, len(execution.Labels))
+		for key, value := range execution.Labels {
+			record.Labels[key] = value
+		}
+	}
+
+	if execution.EndTime != nil {
+		record.EndTime = *execution.EndTime
+		record.DurationMs = execution.EndTime.Sub(execution.StartTime).Milliseconds()
+	}
+
+	for _, task := range workflow.Tasks {
+		state, exists := execution.TaskStates[task.ID]
+		if !exists {
+			continue
+		}
+
+		taskRecord := models.TaskRecord{
+			ID:         task.ID,
+			Type:       task.Type,
+			Status:     state.Status,
+			RetryCount: state.RetryCount,
+			Error:      state.Error,
+			ErrorCode:  state.ErrorCode,
+			TokensUsed: tokensUsed(state.Output),
+		}
+		if state.StartTime != nil && state.EndTime != nil {
+			taskRecord.DurationMs = state.EndTime.Sub(*state.StartTime).Milliseconds()
+		}
+		record.TokensUsed += taskRecord.TokensUsed
+		record.Tasks = append(record.Tasks, taskRecord)
+	}
+
+	return record
+}
+
+// tokensUsed returns the token usage a task reported in its output, which reads back
+// from JSON as a float
+func tokensUsed(output map[string]interface{}) int {
+	switch tokens := output["tokens_used"].(type) {
+	case int:
+		return tokens
+	case float64:
+		return int(tokens)
+	}
+	return 0
+}
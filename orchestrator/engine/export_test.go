@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"testing"
+	"time"
+)
+
+// exporterFunc adapts a function to the ExecutionExporter interface
+type exporterFunc func(ctx context.Context, record *models.ExecutionRecord) error
+
+func (f exporterFunc) ExportExecution(ctx context.Context, record *models.ExecutionRecord) error {
+	return f(ctx, record)
+}
+
+func TestExecuteWorkflowExportsFinishedExecution(t *testing.T) {
+	exported := make(chan *models.ExecutionRecord, 1)
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "process" {
+			return errors.New("boom")
+		}
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"tokens_used": float64(1500)}
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetExecutionExporter(exporterFunc(func(ctx context.Context, record *models.ExecutionRecord) error {
+		exported <- record
+		return nil
+	}))
+	executor.SetExportTokenPrice(0.02)
+
+	workflow := &models.WorkflowDefinition{
+		ID:     "export",
+		Name:   "Export",
+		Labels: map[string]string{"team": "analytics"},
+		Tasks: []models.Task{
+			{ID: "fetch", Type: "data"},
+			{ID: "process", Type: "exec", DependsOn: []string{"fetch"}},
+		},
+	}
+	executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"})
+
+	select {
+	case record := <-exported:
+		if record.ExecutionID != "exec-1" || record.WorkflowName != "Export" || record.Status != models.StatusFailed {
+			t.Errorf("unexpected record %+v", record)
+		}
+		if len(record.Tasks) != 2 || record.Tasks[0].Status != models.StatusCompleted || record.Tasks[1].Status != models.StatusFailed {
+			t.Errorf("unexpected task breakdown %+v", record.Tasks)
+		}
+		if record.EndTime.IsZero() {
+			t.Error("expected the record to carry the end time")
+		}
+		if record.Labels["team"] != "analytics" {
+			t.Errorf("got labels %v, want the workflow's", record.Labels)
+		}
+		if record.Tasks[0].TokensUsed != 1500 || record.TokensUsed != 1500 || record.Cost != 0.03 {
+			t.Errorf("got %d task tokens, %d total at cost %v, want 1500 priced at 0.03", record.Tasks[0].TokensUsed, record.TokensUsed, record.Cost)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execution was not exported")
+	}
+}
+
+func TestExportFailureDoesNotAffectExecution(t *testing.T) {
+	attempted := make(chan struct{})
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetExecutionExporter(exporterFunc(func(ctx context.Context, record *models.ExecutionRecord) error {
+		close(attempted)
+		return errors.New("sink down")
+	}))
+
+	workflow := &models.WorkflowDefinition{ID: "export", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err != nil || !response.Success {
+		t.Fatalf("expected the execution to succeed regardless of the sink, got %v", err)
+	}
+
+	select {
+	case <-attempted:
+	case <-time.After(time.Second):
+		t.Fatal("export was not attempted")
+	}
+}
@@ -0,0 +1,610 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EvaluateCondition evaluates a boolean expression against a set of variables.
+//
+// Supported syntax:
+//   - literals: numbers, 'single' or "double" quoted strings, true, false, null
+//   - variable references: name, nested.name, or ${name}
+//   - comparison: ==, !=, <, <=, >, >=
+//   - logical: &&, ||, ! and parentheses; && and || short-circuit
+//   - functions: exists(x), len(x), contains(haystack, needle)
+//
+// Unknown variables resolve to null rather than producing an error. Names
+// containing characters other than letters, digits, '_' and '.' (such as a
+// task ID with a hyphen) must use the ${name} form.
+func EvaluateCondition(expression string, variables map[string]interface{}) (bool, error) {
+	value, err := EvaluateExpression(expression, variables)
+	if err != nil {
+		return false, err
+	}
+	return isTruthy(value), nil
+}
+
+// EvaluateExpression evaluates an expression and returns its raw value
+func EvaluateExpression(expression string, variables map[string]interface{}) (interface{}, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+
+	p := &expressionParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression %q: unexpected token %q", expression, p.tokens[p.pos].value)
+	}
+
+	value, err := node.eval(variables)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	return value, nil
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind  tokenKind
+	value string
+}
+
+// tokenizeExpression splits an expression into tokens
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			end := i + 2
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated variable reference")
+			}
+			name := strings.TrimSpace(string(runes[i+2 : end]))
+			tokens = append(tokens, expressionToken{kind: tokenIdent, value: name})
+			i = end + 1
+
+		case r == '\'' || r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, value: sb.String()})
+			i = j + 1
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && expectsOperand(tokens)):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, value: string(runes[i:j])})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdent, value: string(runes[i:j])})
+			i = j
+
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, expressionToken{kind: tokenOperator, value: two})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '!', '(', ')', ',':
+				tokens = append(tokens, expressionToken{kind: tokenOperator, value: string(r)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// expectsOperand reports whether the next token should be a value, used to
+// tell a negative number apart from a binary operator
+func expectsOperand(tokens []expressionToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenOperator && last.value != ")"
+}
+
+// exprNode is a parsed expression; evaluation is separate from parsing so
+// that && and || can skip their right-hand side
+type exprNode interface {
+	eval(variables map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	path string
+}
+
+func (n variableNode) eval(variables map[string]interface{}) (interface{}, error) {
+	return lookupVariable(variables, n.path), nil
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n notNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	return !isTruthy(value), nil
+}
+
+// logicalNode is && or ||, evaluating the right-hand side only when the left does not decide the result
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n logicalNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "||" && isTruthy(left) {
+		return true, nil
+	}
+	if n.op == "&&" && !isTruthy(left) {
+		return false, nil
+	}
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	return isTruthy(right), nil
+}
+
+type comparisonNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n comparisonNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	return compareValues(n.op, left, right)
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+// eval applies a built-in function to its evaluated arguments
+func (n callNode) eval(variables map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	switch n.name {
+	case "exists":
+		return args[0] != nil, nil
+	case "len":
+		return float64(valueLength(args[0])), nil
+	default:
+		return valueContains(args[0], args[1]), nil
+	}
+}
+
+// builtinArity is the number of arguments each built-in function takes
+var builtinArity = map[string]int{
+	"exists":   1,
+	"len":      1,
+	"contains": 2,
+}
+
+// expressionParser is a recursive descent parser producing an exprNode tree
+type expressionParser struct {
+	tokens []expressionToken
+	pos    int
+}
+
+func (p *expressionParser) peek() *expressionToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *expressionParser) acceptOperator(op string) bool {
+	if tok := p.peek(); tok != nil && tok.kind == tokenOperator && tok.value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseComparison() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok == nil || tok.kind != tokenOperator {
+		return left, nil
+	}
+
+	op := tok.value
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		return left, nil
+	}
+
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparisonNode{op: op, left: left, right: right}, nil
+}
+
+func (p *expressionParser) parseUnary() (exprNode, error) {
+	if p.acceptOperator("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch tok.kind {
+	case tokenNumber:
+		p.pos++
+		num, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.value)
+		}
+		return literalNode{value: num}, nil
+
+	case tokenString:
+		p.pos++
+		return literalNode{value: tok.value}, nil
+
+	case tokenIdent:
+		p.pos++
+		switch tok.value {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null", "nil":
+			return literalNode{value: nil}, nil
+		}
+		if p.acceptOperator("(") {
+			return p.parseCall(tok.value)
+		}
+		return variableNode{path: tok.value}, nil
+
+	case tokenOperator:
+		if p.acceptOperator("(") {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptOperator(")") {
+				return nil, fmt.Errorf("missing closing parenthesis")
+			}
+			return node, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected token %q", tok.value)
+}
+
+// parseCall parses a built-in function call; the opening parenthesis has been consumed
+func (p *expressionParser) parseCall(name string) (exprNode, error) {
+	var args []exprNode
+	if !p.acceptOperator(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.acceptOperator(")") {
+				break
+			}
+			if !p.acceptOperator(",") {
+				return nil, fmt.Errorf("expected ',' or ')' in call to %s", name)
+			}
+		}
+	}
+
+	arity, known := builtinArity[name]
+	if !known {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", name, arity, len(args))
+	}
+
+	return callNode{name: name, args: args}, nil
+}
+
+// lookupVariable resolves a dotted path against the variable map
+func lookupVariable(variables map[string]interface{}, path string) interface{} {
+	if value, exists := variables[path]; exists {
+		return value
+	}
+
+	var current interface{} = variables
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, exists := node[part]
+			if !exists {
+				return nil
+			}
+			current = next
+		case map[string]string:
+			next, exists := node[part]
+			if !exists {
+				return nil
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil
+			}
+			current = node[idx]
+		default:
+			return nil
+		}
+	}
+
+	return current
+}
+
+// compareValues applies a comparison operator, comparing numerically when both sides are numbers
+func compareValues(op string, left, right interface{}) (bool, error) {
+	leftNum, leftIsNum := toNumber(left)
+	rightNum, rightIsNum := toNumber(right)
+
+	if leftIsNum && rightIsNum {
+		switch op {
+		case "==":
+			return leftNum == rightNum, nil
+		case "!=":
+			return leftNum != rightNum, nil
+		case "<":
+			return leftNum < rightNum, nil
+		case "<=":
+			return leftNum <= rightNum, nil
+		case ">":
+			return leftNum > rightNum, nil
+		case ">=":
+			return leftNum >= rightNum, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	}
+
+	leftStr, leftIsStr := left.(string)
+	rightStr, rightIsStr := right.(string)
+	if !leftIsStr || !rightIsStr {
+		return false, fmt.Errorf("cannot compare %v %s %v", left, op, right)
+	}
+
+	switch op {
+	case "<":
+		return leftStr < rightStr, nil
+	case "<=":
+		return leftStr <= rightStr, nil
+	case ">":
+		return leftStr > rightStr, nil
+	default:
+		return leftStr >= rightStr, nil
+	}
+}
+
+// valuesEqual compares two values, treating numeric strings and numbers as comparable
+func valuesEqual(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if leftNum, ok := toNumber(left); ok {
+		if rightNum, ok := toNumber(right); ok {
+			return leftNum == rightNum
+		}
+	}
+	if leftBool, ok := left.(bool); ok {
+		if rightStr, ok := right.(string); ok {
+			return strconv.FormatBool(leftBool) == rightStr
+		}
+	}
+	if rightBool, ok := right.(bool); ok {
+		if leftStr, ok := left.(string); ok {
+			return strconv.FormatBool(rightBool) == leftStr
+		}
+	}
+	return reflect.DeepEqual(left, right) || fmt.Sprintf("%v", left) == fmt.Sprintf("%v", right)
+}
+
+// toNumber converts numeric values (and numeric strings) to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		num, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		return num, true
+	default:
+		return 0, false
+	}
+}
+
+// isTruthy reports whether a value should be treated as true in a boolean context
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false" && v != "0"
+	}
+	if num, ok := toNumber(value); ok {
+		return num != 0
+	}
+	return valueLength(value) > 0
+}
+
+// valueLength returns the length of strings, slices and maps
+func valueLength(value interface{}) int {
+	if value == nil {
+		return 0
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len()
+	}
+	return 1
+}
+
+// valueContains checks substring, slice element or map key membership
+func valueContains(haystack, needle interface{}) bool {
+	switch h := haystack.(type) {
+	case string:
+		return strings.Contains(h, fmt.Sprintf("%v", needle))
+	case map[string]interface{}:
+		_, exists := h[fmt.Sprintf("%v", needle)]
+		return exists
+	}
+
+	rv := reflect.ValueOf(haystack)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			if valuesEqual(rv.Index(i).Interface(), needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluateConditionShortCircuit(t *testing.T) {
+	variables := map[string]interface{}{
+		"count": float64(3),
+	}
+
+	cases := []struct {
+		expression string
+		want       bool
+	}{
+		{"exists(missing) && missing > 1", false},
+		{"exists(count) && count > 1", true},
+		{"true || missing > 1", true},
+		{"false && missing > 1", false},
+		{"!exists(missing) || missing > 1", true},
+	}
+
+	for _, tc := range cases {
+		got, err := EvaluateCondition(tc.expression, variables)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.expression, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.expression, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluateConditionEvaluatesRightHandSideWhenNeeded(t *testing.T) {
+	if _, err := EvaluateCondition("true && missing > 1", nil); err == nil {
+		t.Fatal("expected comparing null with a number to fail once the right-hand side is evaluated")
+	}
+}
+
+func TestEvaluateConditionIdentifiersExcludeHyphen(t *testing.T) {
+	variables := map[string]interface{}{
+		"a":       map[string]interface{}{"n": float64(5)},
+		"fetch-1": map[string]interface{}{"ok": true},
+	}
+
+	_, err := EvaluateCondition("a.n-1 > 3", variables)
+	if err == nil || !strings.Contains(err.Error(), "unexpected character '-'") {
+		t.Fatalf("expected an error on '-', got %v", err)
+	}
+
+	got, err := EvaluateCondition("${fetch-1.ok} == true", variables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got {
+		t.Error("expected ${fetch-1.ok} to resolve through the braced form")
+	}
+}
+
+func TestEvaluateConditionMultibyteVariableReference(t *testing.T) {
+	variables := map[string]interface{}{
+		"größe": float64(10),
+	}
+
+	got, err := EvaluateCondition("'é' == 'é' && ${größe} > 5", variables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got {
+		t.Error("expected condition with multi-byte runes to be true")
+	}
+}
+
+func TestEvaluateExpressionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"count >",
+		"(count > 1",
+		"${unterminated",
+		"unknown(1)",
+		"len(1, 2)",
+		"count > 1 count",
+	} {
+		if _, err := EvaluateExpression(expression, nil); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"sync"
+)
+
+// taskExecutorFunc adapts a function to the TaskExecutor interface
+type taskExecutorFunc func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error
+
+func (f taskExecutorFunc) ExecuteTask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+	return f(ctx, task, execution)
+}
+
+// memoryStateManager keeps executions in memory
+type memoryStateManager struct {
+	mutex      sync.Mutex
+	executions map[string]*models.WorkflowExecution
+}
+
+func newMemoryStateManager() *memoryStateManager {
+	return &memoryStateManager{executions: make(map[string]*models.WorkflowExecution)}
+}
+
+func (m *memoryStateManager) SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.executions[execution.ID] = execution
+	return nil
+}
+
+func (m *memoryStateManager) LoadExecution(ctx context.Context, executionID string) (*models.WorkflowExecution, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	execution, exists := m.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("execution %s not found", executionID)
+	}
+	return execution, nil
+}
+
+func (m *memoryStateManager) DeleteExecution(ctx context.Context, executionID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.executions, executionID)
+	return nil
+}
+
+func (m *memoryStateManager) ListActiveExecutions(ctx context.Context) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var ids []string
+	for id, execution := range m.executions {
+		if execution.Status == models.StatusRunning {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// fakeRegistry reports service versions and retry safety from maps. versions holds the
+// version each service last announced, replicas further versions other replicas still run.
+type fakeRegistry struct {
+	mutex     sync.Mutex
+	versions  map[string]string
+	replicas  map[string][]string
+	retrySafe map[string]bool
+}
+
+func (f *fakeRegistry) setVersion(component, version string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.versions[component] = version
+}
+
+func (f *fakeRegistry) GetServiceRelease(component string) (string, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	version, exists := f.versions[component]
+	return version, exists
+}
+
+func (f *fakeRegistry) HasServiceRelease(component, version string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if current, exists := f.versions[component]; exists && current == version {
+		return true
+	}
+	for _, replica := range f.replicas[component] {
+		if replica == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeRegistry) IsServiceAvailable(component string) bool {
+	_, exists := f.GetServiceRelease(component)
+	return exists
+}
+
+func (f *fakeRegistry) IsOperationRetrySafe(component, operation string) (bool, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	safe, known := f.retrySafe[operation]
+	return safe, known
+}
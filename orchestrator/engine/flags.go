@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+)
+
+// FlagsVariable is the reserved variable that exposes feature flags to workflows,
+// e.g. ${flags.use_cheap_model}
+const FlagsVariable = "flags"
+
+// FlagStore provides operator-controlled feature flags
+type FlagStore interface {
+	GetFlags(ctx context.Context) (map[string]interface{}, error)
+}
+
+// SetFlagStore enables injecting feature flags into each execution's variables
+func (we *WorkflowExecutor) SetFlagStore(store FlagStore) {
+	we.flagStore = store
+}
+
+// injectFlags snapshots the current flags into the execution's variables at start. The
+// reserved variable always reflects the store, so requests cannot override flag values.
+func (we *WorkflowExecutor) injectFlags(ctx context.Context, execution *models.WorkflowExecution) {
+	if we.flagStore == nil {
+		return
+	}
+
+	if _, exists := execution.Variables[FlagsVariable]; exists {
+		we.logger.WithField("execution_id", execution.ID).Warn("Ignoring request variable shadowing reserved flags variable")
+	}
+
+	flags, err := we.flagStore.GetFlags(ctx)
+	if err != nil {
+		we.logger.WithError(err).WithField("execution_id", execution.ID).Warn("Failed to load feature flags, continuing without flags")
+		flags = make(map[string]interface{})
+	}
+
+	execution.Variables[FlagsVariable] = flags
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"strings"
+)
+
+// Supported graph rendering formats
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+)
+
+// statusColors fills rendered nodes by task status
+var statusColors = map[models.ExecutionStatus]string{
+	models.StatusPending:   "#e0e0e0",
+	models.StatusRunning:   "#9ecbff",
+	models.StatusRetrying:  "#ffe08a",
+	models.StatusCompleted: "#b7e4b0",
+	models.StatusFailed:    "#f4a6a6",
+	models.StatusCancelled: "#d7c4e8",
+	models.StatusSkipped:   "#f5f5f5",
+}
+
+// RenderGraph renders the DAG as Graphviz DOT or Mermaid. When states is non-nil each
+// task is coloured by its execution status.
+func (dag *DAG) RenderGraph(format, name string, states map[string]*models.TaskState) (string, error) {
+	switch format {
+	case GraphFormatDOT:
+		return dag.renderDOT(name, states), nil
+	case GraphFormatMermaid:
+		return dag.renderMermaid(states), nil
+	default:
+		return "", fmt.Errorf("unsupported graph format %q", format)
+	}
+}
+
+// orderedTasks lists task IDs batch by batch, sorted within each batch, so output is stable
+func (dag *DAG) orderedTasks() []string {
+	var ordered []string
+	for _, batch := range dag.GetParallelBatches() {
+		ordered = append(ordered, batch...)
+	}
+	return ordered
+}
+
+func (dag *DAG) renderDOT(name string, states map[string]*models.TaskState) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(name))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+
+	ordered := dag.orderedTasks()
+	for _, taskID := range ordered {
+		attrs := fmt.Sprintf("label=%s", dotQuote(nodeLabel(dag.tasks[taskID], states[taskID], "\n")))
+		if color, ok := nodeColor(states[taskID]); ok {
+			attrs += fmt.Sprintf(", fillcolor=%s", dotQuote(color))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(taskID), attrs)
+	}
+
+	for _, taskID := range ordered {
+		for _, dep := range dag.dependencies[taskID] {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(dep), dotQuote(taskID))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (dag *DAG) renderMermaid(states map[string]*models.TaskState) string {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	// Mermaid node IDs are restricted, so tasks get positional IDs and keep their name in the label
+	ordered := dag.orderedTasks()
+	nodeIDs := make(map[string]string, len(ordered))
+	for i, taskID := range ordered {
+		nodeIDs[taskID] = fmt.Sprintf("t%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeIDs[taskID], mermaidEscape(nodeLabel(dag.tasks[taskID], states[taskID], "<br/>")))
+	}
+
+	for _, taskID := range ordered {
+		for _, dep := range dag.dependencies[taskID] {
+			fmt.Fprintf(&b, "  %s --> %s\n", nodeIDs[dep], nodeIDs[taskID])
+		}
+	}
+
+	for _, taskID := range ordered {
+		if color, ok := nodeColor(states[taskID]); ok {
+			fmt.Fprintf(&b, "  style %s fill:%s\n", nodeIDs[taskID], color)
+		}
+	}
+
+	return b.String()
+}
+
+// nodeLabel shows the task ID, its type and, when known, its status
+func nodeLabel(task *models.Task, state *models.TaskState, separator string) string {
+	label := task.ID
+	if task.Type != "" {
+		label += separator + "(" + task.Type + ")"
+	}
+	if state != nil {
+		label += separator + string(state.Status)
+	}
+	return label
+}
+
+func nodeColor(state *models.TaskState) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+	color, ok := statusColors[state.Status]
+	return color, ok
+}
+
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
+
+// mermaidEscape replaces characters that would end a quoted Mermaid label
+func mermaidEscape(value string) string {
+	return strings.ReplaceAll(value, `"`, "#quot;")
+}
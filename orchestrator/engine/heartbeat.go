@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"time"
+)
+
+// HeartbeatTTLMetadataKey marks an execution as heartbeat-tracked and records its TTL
+const HeartbeatTTLMetadataKey = "heartbeat_ttl_seconds"
+
+// HeartbeatStore records that an execution is still being worked on by a live executor
+type HeartbeatStore interface {
+	TouchHeartbeat(ctx context.Context, executionID string, ttl time.Duration) error
+	ClearHeartbeat(ctx context.Context, executionID string) error
+}
+
+// SetHeartbeat makes running executions refresh a heartbeat that expires after ttl.
+// A watchdog can recover executions whose heartbeat lapsed, e.g. after the executing
+// instance died. A zero ttl disables heartbeats.
+func (we *WorkflowExecutor) SetHeartbeat(store HeartbeatStore, ttl time.Duration) {
+	we.heartbeatStore = store
+	we.heartbeatTTL = ttl
+}
+
+// startHeartbeat refreshes the execution's heartbeat at a third of its TTL until the
+// returned stop function is called, which also removes the heartbeat
+func (we *WorkflowExecutor) startHeartbeat(execution *models.WorkflowExecution) func() {
+	if we.heartbeatStore == nil || we.heartbeatTTL <= 0 {
+		return func() {}
+	}
+
+	touch := func() {
+		if err := we.heartbeatStore.TouchHeartbeat(context.Background(), execution.ID, we.heartbeatTTL); err != nil {
+			we.logger.WithError(err).WithField("execution_id", execution.ID).Warn("Failed to refresh execution heartbeat")
+		}
+	}
+	touch()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(we.heartbeatTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				touch()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if err := we.heartbeatStore.ClearHeartbeat(context.Background(), execution.ID); err != nil {
+			we.logger.WithError(err).WithField("execution_id", execution.ID).Warn("Failed to clear execution heartbeat")
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHeartbeats counts heartbeat touches and clears per execution
+type recordingHeartbeats struct {
+	mu      sync.Mutex
+	touches map[string]int
+	cleared map[string]bool
+	onClear func(executionID string)
+}
+
+func newRecordingHeartbeats() *recordingHeartbeats {
+	return &recordingHeartbeats{touches: make(map[string]int), cleared: make(map[string]bool)}
+}
+
+func (r *recordingHeartbeats) TouchHeartbeat(ctx context.Context, executionID string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touches[executionID]++
+	return nil
+}
+
+func (r *recordingHeartbeats) ClearHeartbeat(ctx context.Context, executionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleared[executionID] = true
+	if r.onClear != nil {
+		r.onClear(executionID)
+	}
+	return nil
+}
+
+func TestExecuteWorkflowRefreshesAndClearsHeartbeat(t *testing.T) {
+	heartbeats := newRecordingHeartbeats()
+	state := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		time.Sleep(100 * time.Millisecond) // long enough for several refreshes
+		return nil
+	}), state, nil, 1)
+	executor.SetHeartbeat(heartbeats, 30*time.Millisecond)
+
+	workflow := &models.WorkflowDefinition{ID: "heartbeat", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	heartbeats.mu.Lock()
+	defer heartbeats.mu.Unlock()
+	if heartbeats.touches["exec-1"] < 2 {
+		t.Errorf("expected the heartbeat to be refreshed while running, got %d touches", heartbeats.touches["exec-1"])
+	}
+	if !heartbeats.cleared["exec-1"] {
+		t.Error("expected the heartbeat to be cleared once the execution finished")
+	}
+
+	execution, err := state.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, tracked := execution.Metadata[HeartbeatTTLMetadataKey]; !tracked {
+		t.Error("expected the execution to be marked as heartbeat-tracked")
+	}
+}
+
+func TestHeartbeatIsClearedAfterFinalState(t *testing.T) {
+	heartbeats := newRecordingHeartbeats()
+	state := newMemoryStateManager()
+	var statusAtClear models.ExecutionStatus
+	heartbeats.onClear = func(executionID string) {
+		if execution, err := state.LoadExecution(context.Background(), executionID); err == nil {
+			statusAtClear = execution.Status
+		}
+	}
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), state, nil, 1)
+	executor.SetHeartbeat(heartbeats, time.Minute)
+
+	workflow := &models.WorkflowDefinition{ID: "heartbeat", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A watchdog looking in between must not find a running execution without a heartbeat
+	if statusAtClear != models.StatusCompleted {
+		t.Errorf("got status %q when the heartbeat was cleared, want the final state saved first", statusAtClear)
+	}
+}
+
+func TestExecuteWorkflowWithoutHeartbeatIsUntracked(t *testing.T) {
+	state := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), state, nil, 1)
+
+	workflow := &models.WorkflowDefinition{ID: "heartbeat", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	execution, err := state.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, tracked := execution.Metadata[HeartbeatTTLMetadataKey]; tracked {
+		t.Error("an execution without heartbeats must not be picked up by the watchdog")
+	}
+}
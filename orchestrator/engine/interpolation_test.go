@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// nestedParameters returns a map nested depth levels deep
+func nestedParameters(depth int) map[string]interface{} {
+	value := map[string]interface{}{"leaf": "${name}"}
+	for i := 1; i < depth; i++ {
+		value = map[string]interface{}{"child": value}
+	}
+	return value
+}
+
+func TestInterpolationLimitsTrip(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	executor.SetInterpolationLimits(4, 10)
+	variables := map[string]interface{}{"name": "sales"}
+
+	wide := make([]interface{}, 10)
+	for i := range wide {
+		wide[i] = "${name}"
+	}
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+
+	cases := []struct {
+		name       string
+		parameters map[string]interface{}
+		want       string
+	}{
+		{"within limits", map[string]interface{}{"query": nestedParameters(3)}, ""},
+		{"too deep", map[string]interface{}{"query": nestedParameters(4)}, "nest deeper than the maximum of 4 levels"},
+		{"too many values", map[string]interface{}{"rows": wide}, "more than the maximum of 10 values"},
+		{"refers to itself", map[string]interface{}{"query": cyclic}, "nest deeper than the maximum of 4 levels"},
+	}
+	for _, c := range cases {
+		task := &models.Task{ID: "fetch", Type: "data", Parameters: c.parameters}
+		_, _, err := executor.interpolateVariables(task, variables, false)
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestInterpolationLimitFailsTaskBeforeDispatch(t *testing.T) {
+	dispatched := false
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		dispatched = true
+		return nil
+	}), states, nil, 1)
+	executor.SetInterpolationLimits(3, 0)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"query": nestedParameters(5)}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err == nil {
+		t.Fatal("expected the oversized parameters to fail the workflow")
+	}
+	if dispatched {
+		t.Error("expected the task not to be dispatched")
+	}
+
+	execution, _ := states.LoadExecution(context.Background(), response.ExecutionID)
+	state := execution.TaskStates["fetch"]
+	if state.Status != models.StatusFailed || !strings.Contains(state.Error, "failed to interpolate parameter query: parameters nest deeper") {
+		t.Errorf("got status %s and error %q", state.Status, state.Error)
+	}
+}
+
+func TestStrictInterpolationReportsEveryUnresolvedVariable(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	variables := map[string]interface{}{"dataset": "sales"}
+	task := &models.Task{ID: "query_data", Type: "data", Parameters: map[string]interface{}{
+		"collection": "${dataset}",
+		"filter":     map[string]interface{}{"label": "${node_label}", "limit": "${limit}"},
+		"fields":     []interface{}{"${node_label}", "name"},
+	}}
+
+	_, _, err := executor.interpolateVariables(task, variables, true)
+	if err == nil || err.Error() != "unresolved variables in task query_data: limit, node_label" {
+		t.Errorf("got %v, want every missing variable listed once, sorted", err)
+	}
+
+	// Without strict mode the same references are returned and left in place
+	interpolated, unresolved, err := executor.interpolateVariables(task, variables, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unresolved) != 2 {
+		t.Errorf("got unresolved %v, want limit and node_label", unresolved)
+	}
+	filter := interpolated.Parameters["filter"].(map[string]interface{})
+	if interpolated.Parameters["collection"] != "sales" || filter["limit"] != "${limit}" {
+		t.Errorf("got parameters %v, want resolved references replaced and the rest kept", interpolated.Parameters)
+	}
+}
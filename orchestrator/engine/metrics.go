@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"orchestrator/metrics"
+	"orchestrator/models"
+)
+
+// TemplateMetadataKey records the template an execution was started from
+const TemplateMetadataKey = "template_id"
+
+// TemplateVersionMetadataKey records the version of that template the execution ran
+const TemplateVersionMetadataKey = "template_version"
+
+// workflowMetricLabel labels an execution's metrics by its template, so the number of
+// series stays bounded by the loaded templates
+func workflowMetricLabel(execution *models.WorkflowExecution) string {
+	if template, ok := execution.Metadata[TemplateMetadataKey].(string); ok && template != "" {
+		return template
+	}
+	return metrics.UnlabelledWorkflow
+}
+
+// ActiveExecutions returns how many executions this instance is running
+func (we *WorkflowExecutor) ActiveExecutions() int {
+	we.runningMutex.Lock()
+	defer we.runningMutex.Unlock()
+	return len(we.running)
+}
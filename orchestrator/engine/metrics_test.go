@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"orchestrator/metrics"
+	"orchestrator/models"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// scrapeMetrics fetches /metrics and parses the families it serves
+func scrapeMetrics(t *testing.T) map[string]*dto.MetricFamily {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != 200 {
+		t.Fatalf("got status %d from /metrics", recorder.Code)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(recorder.Body)
+	if err != nil {
+		t.Fatalf("/metrics is not in the text exposition format: %v", err)
+	}
+	return families
+}
+
+// findMetric returns the series of a family whose labels include all of want
+func findMetric(families map[string]*dto.MetricFamily, name string, want map[string]string) *dto.Metric {
+	family, exists := families[name]
+	if !exists {
+		return nil
+	}
+	for _, metric := range family.Metric {
+		matched := 0
+		for _, label := range metric.Label {
+			if value, exists := want[label.GetName()]; exists && value == label.GetValue() {
+				matched++
+			}
+		}
+		if matched == len(want) {
+			return metric
+		}
+	}
+	return nil
+}
+
+// counterValue returns a counter series' value, zero when it has not been created yet
+func counterValue(families map[string]*dto.MetricFamily, name string, labels map[string]string) float64 {
+	return findMetric(families, name, labels).GetCounter().GetValue()
+}
+
+// sampleCount returns a histogram series' observation count, zero when it has not been
+// created yet
+func sampleCount(families map[string]*dto.MetricFamily, name string, labels map[string]string) uint64 {
+	return findMetric(families, name, labels).GetHistogram().GetSampleCount()
+}
+
+func TestExecutionIsReportedOnMetricsEndpoint(t *testing.T) {
+	attempts := 0
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "flaky" {
+			attempts++
+			if attempts == 1 {
+				return errors.New("transient")
+			}
+		}
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	// A template label no other test uses keeps other tests from moving these series
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "flaky", Type: "exec", DependsOn: []string{"fetch"},
+			RetryPolicy: &models.RetryPolicy{MaxRetries: 1, BackoffType: "fixed", InitialDelay: time.Millisecond}},
+	}}
+	request := &models.WorkflowRequest{WorkflowTemplate: "metrics-scrape"}
+	workflowLabel := map[string]string{"workflow": "metrics-scrape"}
+	completedLabels := map[string]string{"workflow": "metrics-scrape", "status": string(models.StatusCompleted)}
+	taskLabels := func(taskType string) map[string]string {
+		return map[string]string{"workflow": "metrics-scrape", "task_type": taskType, "status": string(models.StatusCompleted)}
+	}
+	retryLabels := func(taskType string) map[string]string {
+		return map[string]string{"workflow": "metrics-scrape", "task_type": taskType}
+	}
+
+	// The registry is shared by the whole test binary, so only the change is checked
+	before := scrapeMetrics(t)
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, request); err != nil {
+		t.Fatal(err)
+	}
+	after := scrapeMetrics(t)
+
+	counterDelta := func(name string, labels map[string]string) float64 {
+		return counterValue(after, name, labels) - counterValue(before, name, labels)
+	}
+	if delta := counterDelta("orchestrator_workflows_started_total", workflowLabel); delta != 1 {
+		t.Errorf("got %v more started workflows, want 1", delta)
+	}
+	if delta := counterDelta("orchestrator_workflows_finished_total", completedLabels); delta != 1 {
+		t.Errorf("got %v more completed workflows, want 1", delta)
+	}
+
+	for _, taskType := range []string{"data", "exec"} {
+		name := "orchestrator_task_duration_seconds"
+		if delta := sampleCount(after, name, taskLabels(taskType)) - sampleCount(before, name, taskLabels(taskType)); delta != 1 {
+			t.Errorf("got %d more %s task duration observations, want 1", delta, taskType)
+			continue
+		}
+		if len(findMetric(after, name, taskLabels(taskType)).GetHistogram().GetBucket()) == 0 {
+			t.Errorf("got no buckets for the %s task duration", taskType)
+		}
+	}
+
+	if delta := counterDelta("orchestrator_task_retries_total", retryLabels("exec")); delta != 1 {
+		t.Errorf("got %v more exec retries, want 1", delta)
+	}
+	if findMetric(after, "orchestrator_task_retries_total", retryLabels("data")) != nil {
+		t.Error("expected no retries series for the task that succeeded first time")
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"orchestrator/models"
+)
+
+// ServiceRegistry exposes which services are running and their announced capabilities
+type ServiceRegistry interface {
+	GetServiceRelease(component string) (string, bool)
+	HasServiceRelease(component, version string) bool
+	IsServiceAvailable(component string) bool
+	IsOperationRetrySafe(component, operation string) (safe bool, known bool)
+}
+
+// serviceComponents maps task types to the component names services announce under
+var serviceComponents = map[string]string{
+	"data": "data-abstractor",
+	"ai":   "ai-abstractor",
+	"exec": "exec-agent",
+}
+
+// SetServiceRegistry enables version pinning against announced service versions
+func (we *WorkflowExecutor) SetServiceRegistry(registry ServiceRegistry) {
+	we.serviceRegistry = registry
+}
+
+// snapshotServiceVersions records the service version of every service the workflow
+// uses. The capability version is not used: it changes whenever a service's capabilities
+// do, e.g. when the exec agent refreshes its scanned images, without a new release.
+func (we *WorkflowExecutor) snapshotServiceVersions(workflow *models.WorkflowDefinition) (map[string]string, error) {
+	if we.serviceRegistry == nil {
+		return nil, fmt.Errorf("version pinning requested but no service registry is configured")
+	}
+
+	pinned := make(map[string]string)
+	for _, task := range workflow.Tasks {
+		component, exists := serviceComponents[task.Type]
+		if !exists {
+			continue
+		}
+		if _, seen := pinned[component]; seen {
+			continue
+		}
+
+		version, available := we.serviceRegistry.GetServiceRelease(component)
+		if !available {
+			return nil, fmt.Errorf("cannot pin version for %s: service is not available", component)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("cannot pin version for %s: service announces no service version", component)
+		}
+		pinned[component] = version
+	}
+
+	return pinned, nil
+}
+
+// pinnedVersion returns the service version a task's requests must be served by, or
+// empty when the execution pinned none for its service
+func pinnedVersion(task *models.Task, execution *models.WorkflowExecution) string {
+	component, exists := serviceComponents[task.Type]
+	if !exists {
+		return ""
+	}
+	return execution.PinnedVersions[component]
+}
+
+// checkPinnedVersion verifies a replica of the service a task targets still runs the
+// pinned version; the task's requests are then routed to such a replica
+func (we *WorkflowExecutor) checkPinnedVersion(task *models.Task, execution *models.WorkflowExecution) error {
+	if we.serviceRegistry == nil {
+		return nil
+	}
+
+	pinned := pinnedVersion(task, execution)
+	if pinned == "" {
+		return nil
+	}
+
+	component := serviceComponents[task.Type]
+	if !we.serviceRegistry.HasServiceRelease(component, pinned) {
+		return fmt.Errorf("no replica of pinned service %s runs version %s anymore", component, pinned)
+	}
+
+	return nil
+}
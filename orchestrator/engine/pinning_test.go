@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+func TestPinnedExecutionFailsWhenServiceUpgradesMidRun(t *testing.T) {
+	registry := &fakeRegistry{versions: map[string]string{"data-abstractor": "v1"}}
+	states := newMemoryStateManager()
+	var dispatched []string
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		dispatched = append(dispatched, task.ID)
+		// The service is upgraded while the first task runs
+		registry.setVersion("data-abstractor", "v2")
+		return nil
+	}), states, nil, 1)
+	executor.SetServiceRegistry(registry)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "first", Type: "data"},
+		{ID: "second", Type: "data", DependsOn: []string{"first"}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{PinVersions: true})
+	if err == nil || response.Status != models.StatusFailed {
+		t.Fatalf("expected the pinned execution to fail, got %v", err)
+	}
+
+	if len(dispatched) != 1 {
+		t.Fatalf("expected only the first task to be dispatched, got %v", dispatched)
+	}
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := execution.TaskStates["second"]
+	if state == nil || !strings.Contains(state.Error, "runs version v1") {
+		t.Errorf("expected second to fail on the missing pinned version, got %+v", state)
+	}
+}
+
+func TestPinnedExecutionStaysOnReplicasOfItsVersion(t *testing.T) {
+	registry := &fakeRegistry{
+		versions: map[string]string{"data-abstractor": "v1"},
+		replicas: map[string][]string{"data-abstractor": {"v1"}},
+	}
+	var routed []string
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		call, _ := TaskCallFromContext(ctx)
+		routed = append(routed, call.PinnedVersion)
+		// A new replica is rolled out while an old one keeps running
+		registry.setVersion("data-abstractor", "v2")
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetServiceRegistry(registry)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "first", Type: "data"},
+		{ID: "second", Type: "data", DependsOn: []string{"first"}},
+		{ID: "third", Type: "transform", DependsOn: []string{"second"}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{PinVersions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != models.StatusCompleted {
+		t.Fatalf("got status %s, want completed", response.Status)
+	}
+
+	// Requests carry the pinned version so the coordinator picks a v1 replica
+	want := []string{"v1", "v1", ""}
+	if strings.Join(routed, ",") != strings.Join(want, ",") {
+		t.Errorf("got pinned versions %q per task, want %q", routed, want)
+	}
+}
+
+func TestUnpinnedExecutionIgnoresUpgrades(t *testing.T) {
+	registry := &fakeRegistry{versions: map[string]string{"data-abstractor": "v1"}}
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		registry.setVersion("data-abstractor", "v2")
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetServiceRegistry(registry)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "first", Type: "data"},
+		{ID: "second", Type: "data", DependsOn: []string{"first"}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != models.StatusCompleted {
+		t.Errorf("got status %s, want completed", response.Status)
+	}
+}
+
+func TestPinningRequiresAvailableServices(t *testing.T) {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	executor.SetServiceRegistry(&fakeRegistry{versions: map[string]string{}})
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "a", Type: "ai"}}}
+	_, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{PinVersions: true})
+	if err == nil || !strings.Contains(err.Error(), "ai-abstractor") {
+		t.Errorf("expected pinning to fail for the unavailable service, got %v", err)
+	}
+}
+
+func TestPinningRequiresAnnouncedServiceVersion(t *testing.T) {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+	// Available, but running without SERVICE_VERSION
+	executor.SetServiceRegistry(&fakeRegistry{versions: map[string]string{"exec-agent": ""}})
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "run", Type: "exec"}}}
+	_, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{PinVersions: true})
+	if err == nil || !strings.Contains(err.Error(), "announces no service version") {
+		t.Errorf("expected pinning to fail without a service version, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequireServicesMetadataKey marks an execution that must not start until every service
+// its tasks use has announced itself
+const RequireServicesMetadataKey = "require_services"
+
+// servicePollInterval is how often the registry is checked during the grace period
+const servicePollInterval = time.Second
+
+// ServiceUnavailableError names the services a gated execution needed but did not find
+type ServiceUnavailableError struct {
+	Services []string
+}
+
+func (e *ServiceUnavailableError) Error() string {
+	return fmt.Sprintf("required services unavailable: %s", strings.Join(e.Services, ", "))
+}
+
+// SetServiceGracePeriod sets how long a gated execution waits for missing services to
+// announce themselves before failing; 0 fails immediately
+func (we *WorkflowExecutor) SetServiceGracePeriod(grace time.Duration) {
+	we.serviceGrace = grace
+}
+
+// awaitServices checks, for executions that asked for it, that the services the workflow
+// uses are available, waiting up to the grace period for missing ones to appear
+func (we *WorkflowExecutor) awaitServices(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) error {
+	if required, _ := execution.Metadata[RequireServicesMetadataKey].(bool); !required {
+		return nil
+	}
+	if we.serviceRegistry == nil {
+		return fmt.Errorf("service gating requested but no service registry is configured")
+	}
+
+	components := workflowComponents(workflow)
+	deadline := time.Now().Add(we.serviceGrace)
+	poll := time.NewTicker(servicePollInterval)
+	defer poll.Stop()
+
+	for {
+		missing := we.missingServices(components)
+		if len(missing) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return &ServiceUnavailableError{Services: missing}
+		}
+
+		we.logger.WithField("execution_id", execution.ID).WithField("missing", missing).Info("Waiting for required services")
+		select {
+		case <-poll.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// missingServices returns the components the registry does not report as available
+func (we *WorkflowExecutor) missingServices(components []string) []string {
+	var missing []string
+	for _, component := range components {
+		if !we.serviceRegistry.IsServiceAvailable(component) {
+			missing = append(missing, component)
+		}
+	}
+	return missing
+}
+
+// workflowComponents lists the service components a workflow's tasks, including the
+// sub-tasks of parallel tasks, are dispatched to
+func workflowComponents(workflow *models.WorkflowDefinition) []string {
+	seen := make(map[string]bool)
+	add := func(taskType string) {
+		if component, exists := serviceComponents[taskType]; exists {
+			seen[component] = true
+		}
+	}
+
+	for _, task := range workflow.Tasks {
+		add(task.Type)
+		if subTasks, ok := task.Parameters["tasks"].([]interface{}); ok && task.Type == "parallel" {
+			for _, subTask := range subTasks {
+				if subTaskMap, ok := subTask.(map[string]interface{}); ok {
+					subType, _ := subTaskMap["type"].(string)
+					add(subType)
+				}
+			}
+		}
+	}
+
+	components := make([]string, 0, len(seen))
+	for component := range seen {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components
+}
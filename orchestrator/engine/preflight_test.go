@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// preflightWorkflow uses the data abstractor and, through a parallel task, the exec agent
+func preflightWorkflow() *models.WorkflowDefinition {
+	return &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "fan_out", Type: "parallel", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{
+			"tasks": []interface{}{map[string]interface{}{"type": "exec"}},
+		}},
+	}}
+}
+
+// newPreflightExecutor returns an executor checking services against registry and a
+// count of the tasks it ran
+func newPreflightExecutor(registry *fakeRegistry) (*WorkflowExecutor, *int32) {
+	var ran int32
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		atomic.AddInt32(&ran, 1)
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+	executor.SetServiceRegistry(registry)
+	return executor, &ran
+}
+
+func TestRequireServicesRejectsUnavailableService(t *testing.T) {
+	executor, ran := newPreflightExecutor(&fakeRegistry{versions: map[string]string{"data-abstractor": "v1"}})
+
+	response, err := executor.ExecuteWorkflow(context.Background(), preflightWorkflow(), &models.WorkflowRequest{RequireServices: true})
+
+	var unavailable *ServiceUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected a ServiceUnavailableError, got %v", err)
+	}
+	if !reflect.DeepEqual(unavailable.Services, []string{"exec-agent"}) {
+		t.Errorf("got missing services %v, want the exec agent used by the parallel task", unavailable.Services)
+	}
+	if response.Status != models.StatusFailed {
+		t.Errorf("got status %s, want failed", response.Status)
+	}
+	if count := atomic.LoadInt32(ran); count != 0 {
+		t.Errorf("got %d tasks run, want none before the services are there", count)
+	}
+}
+
+func TestRequireServicesWaitsOutGracePeriod(t *testing.T) {
+	registry := &fakeRegistry{versions: map[string]string{"data-abstractor": "v1"}}
+	executor, ran := newPreflightExecutor(registry)
+	executor.SetServiceGracePeriod(5 * time.Second)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		registry.setVersion("exec-agent", "v1")
+	}()
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), preflightWorkflow(), &models.WorkflowRequest{RequireServices: true}); err != nil {
+		t.Fatalf("expected the workflow to run once the service announced itself, got %v", err)
+	}
+	if count := atomic.LoadInt32(ran); count != 2 {
+		t.Errorf("got %d tasks run, want 2", count)
+	}
+}
+
+func TestUngatedWorkflowIgnoresServiceAvailability(t *testing.T) {
+	executor, ran := newPreflightExecutor(&fakeRegistry{versions: map[string]string{}})
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), preflightWorkflow(), &models.WorkflowRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := atomic.LoadInt32(ran); count != 2 {
+		t.Errorf("got %d tasks run, want 2", count)
+	}
+}
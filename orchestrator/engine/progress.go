@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"time"
+)
+
+// progressPublishTimeout bounds how long a progress event may hold up a task
+const progressPublishTimeout = 2 * time.Second
+
+// ProgressPublisher broadcasts execution progress to live watchers
+type ProgressPublisher interface {
+	PublishProgress(ctx context.Context, event *models.ProgressEvent) error
+}
+
+// SetProgressPublisher makes the executor publish every task and workflow state change
+func (we *WorkflowExecutor) SetProgressPublisher(publisher ProgressPublisher) {
+	we.progress = publisher
+}
+
+// publishTaskProgress reports a task's current state, and records it in the event log
+func (we *WorkflowExecutor) publishTaskProgress(execution *models.WorkflowExecution, taskState *models.TaskState) {
+	we.recordTaskEvent(execution, taskState)
+	we.publishProgress(&models.ProgressEvent{
+		Type:        models.ProgressTask,
+		ExecutionID: execution.ID,
+		TaskID:      taskState.ID,
+		Status:      taskState.Status,
+		RetryCount:  taskState.RetryCount,
+		Error:       taskState.Error,
+		Timestamp:   time.Now(),
+	})
+}
+
+// publishWorkflowProgress reports the execution's current state, and records it in the
+// event log
+func (we *WorkflowExecutor) publishWorkflowProgress(execution *models.WorkflowExecution) {
+	we.recordWorkflowEvent(execution)
+	we.publishProgress(&models.ProgressEvent{
+		Type:        models.ProgressWorkflow,
+		ExecutionID: execution.ID,
+		Status:      execution.Status,
+		Error:       execution.Error,
+		Timestamp:   time.Now(),
+	})
+}
+
+// publishProgress sends an event synchronously, so each task's events keep their order.
+// Watchers are best effort: a failed publish is logged and the run carries on.
+func (we *WorkflowExecutor) publishProgress(event *models.ProgressEvent) {
+	if we.progress == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), progressPublishTimeout)
+	defer cancel()
+	if err := we.progress.PublishProgress(ctx, event); err != nil {
+		we.logger.WithError(err).WithField("execution_id", event.ExecutionID).Debug("Failed to publish progress event")
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"sort"
+	"strings"
+)
+
+// Kinds of problem reported when checking ${...} references
+const (
+	ProblemUndefinedVariable = "undefined_variable"
+	ProblemTaskReference     = "invalid_task_reference"
+)
+
+// ReferenceValidationError lists every ${...} reference in a workflow that neither a
+// variable nor an earlier task's output can satisfy
+type ReferenceValidationError struct {
+	Problems []DAGProblem `json:"problems"`
+}
+
+func (e *ReferenceValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Message
+	}
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Message
+	}
+	return fmt.Sprintf("%d unresolved references: %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
+// ValidateReferences checks every ${...} reference in the workflow's task parameters
+// against variables and the outputs of the tasks each task depends on. All unresolved
+// references are returned together in a ReferenceValidationError. Graph problems are left
+// to NewDAG; while the graph is invalid, task output references are only checked to name
+// existing tasks.
+func (we *WorkflowExecutor) ValidateReferences(workflow *models.WorkflowDefinition, variables map[string]interface{}) error {
+	dag, err := NewDAG(workflow.Tasks)
+	if err != nil {
+		dag = nil
+	}
+
+	if problems := we.checkReferences(workflow, dag, variables); len(problems) > 0 {
+		return &ReferenceValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkReferences reports the unresolved references of every task. Tasks whose
+// parameters can't be interpolated at all are skipped; callers report those separately.
+func (we *WorkflowExecutor) checkReferences(workflow *models.WorkflowDefinition, dag *DAG, variables map[string]interface{}) []DAGProblem {
+	var problems validationProblems
+
+	taskIDs := make(map[string]bool, len(workflow.Tasks))
+	for _, task := range workflow.Tasks {
+		taskIDs[task.ID] = true
+	}
+
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+
+		_, unresolved, err := we.interpolateVariables(task, variables, false)
+		if err != nil {
+			continue
+		}
+		sort.Strings(unresolved)
+
+		for _, name := range unresolved {
+			if !strings.HasPrefix(name, taskReferencesKey+".") {
+				message := fmt.Sprintf("task %s references undefined variable %s", task.ID, name)
+				if suggestion := closestVariable(name, variables); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+				}
+				problems.add(ProblemUndefinedVariable, task.ID, name, nil, "%s", message)
+				continue
+			}
+
+			// Task outputs only exist at run time, so it's enough that the task will have run first
+			referenced, _, _ := strings.Cut(strings.TrimPrefix(name, taskReferencesKey+"."), ".")
+			if !taskIDs[referenced] {
+				problems.add(ProblemTaskReference, task.ID, referenced, nil, "task %s references output of non-existent task %s", task.ID, referenced)
+			} else if dag != nil && !dependsOn(dag, task.ID, referenced) {
+				problems.add(ProblemTaskReference, task.ID, referenced, nil, "task %s references output of task %s, which it does not depend on", task.ID, referenced)
+			}
+		}
+	}
+
+	return problems
+}
+
+// dependsOn reports whether taskID depends on upstream, directly or transitively
+func dependsOn(dag *DAG, taskID, upstream string) bool {
+	visited := make(map[string]bool)
+	pending := append([]string(nil), dag.GetDependencies(taskID)...)
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if current == upstream {
+			return true
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+		pending = append(pending, dag.GetDependencies(current)...)
+	}
+	return false
+}
+
+// closestVariable suggests the variable a misspelled reference probably meant: the
+// defined name nearest to the reference's first segment, if it is close enough
+func closestVariable(name string, variables map[string]interface{}) string {
+	root, rest, dotted := strings.Cut(name, ".")
+	if _, exists := variables[root]; exists {
+		// The variable exists; it's the path inside it that's wrong
+		return ""
+	}
+
+	best, bestDistance := "", len(root)/3+1
+	for candidate := range variables {
+		distance := editDistance(root, candidate)
+		if distance < bestDistance || (distance == bestDistance && best != "" && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	if dotted {
+		return best + "." + rest
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
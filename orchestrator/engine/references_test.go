@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+// referencingWorkflow has one reference of each kind validation must catch, and valid
+// ones to variables and to transitively upstream task outputs
+func referencingWorkflow() *models.WorkflowDefinition {
+	return &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"collection": "${dataset}", "limit": "${limt}"}},
+		{ID: "clean", Type: "exec", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{"rows": "${tasks.fetch.output.rows}"}},
+		{ID: "report", Type: "ai", DependsOn: []string{"clean"}, Parameters: map[string]interface{}{
+			"prompt":  "${tasks.fetch.output.summary} ${tasks.summarize.output.text}",
+			"context": "${tasks.audit.output.notes}",
+		}},
+		{ID: "audit", Type: "data"},
+	}}
+}
+
+func TestValidateReferencesReportsEveryUnresolvedReference(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	variables := map[string]interface{}{"dataset": "sales", "limit": 10}
+
+	err := executor.ValidateReferences(referencingWorkflow(), variables)
+	var validationErr *ReferenceValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ReferenceValidationError, got %v", err)
+	}
+
+	want := []DAGProblem{
+		{Kind: ProblemUndefinedVariable, TaskID: "fetch", Target: "limt", Message: "task fetch references undefined variable limt (did you mean limit?)"},
+		{Kind: ProblemTaskReference, TaskID: "report", Target: "audit", Message: "task report references output of task audit, which it does not depend on"},
+		{Kind: ProblemTaskReference, TaskID: "report", Target: "summarize", Message: "task report references output of non-existent task summarize"},
+	}
+	if !reflect.DeepEqual(validationErr.Problems, want) {
+		t.Errorf("got problems %+v, want %+v", validationErr.Problems, want)
+	}
+}
+
+func TestValidateReferencesAcceptsResolvableWorkflow(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"collection": "${dataset}", "filter": "${flags.region}"}},
+		{ID: "report", Type: "ai", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{"prompt": "${tasks.fetch.output.summary}"}},
+	}}
+	variables := map[string]interface{}{"dataset": "sales", "flags": map[string]interface{}{"region": "eu"}}
+
+	if err := executor.ValidateReferences(workflow, variables); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStrictWorkflowFailsBeforeAnyTaskRuns(t *testing.T) {
+	ran := false
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		ran = true
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	workflow := referencingWorkflow()
+	workflow.Interpolation = models.InterpolationStrict
+	workflow.Variables = map[string]interface{}{"dataset": "sales", "limit": 10}
+	_, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+
+	var validationErr *ReferenceValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 3 {
+		t.Fatalf("expected all three problems reported together, got %v", err)
+	}
+	if ran {
+		t.Error("expected no task to run")
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+)
+
+// Execution metadata keys describing record/replay mode
+const (
+	RecordMetadataKey = "record_service_calls"
+	ReplayMetadataKey = "replay_of"
+)
+
+type taskCallKey struct{}
+
+// TaskCall identifies the task attempt a service request is made for, so message
+// coordinators can record or replay exchanges per task and route them to pinned versions
+type TaskCall struct {
+	ExecutionID   string
+	TaskID        string
+	Attempt       int
+	Record        bool     // capture the exchange under ExecutionID
+	ReplayFrom    string   // answer from the exchanges recorded for this execution
+	Secrets       []string // secret values resolved into the request, never to be recorded
+	PinnedVersion string   // service version the request must be served by, when the execution pinned one
+}
+
+// WithTaskCall attaches the task attempt to a context passed to the task executor
+func WithTaskCall(ctx context.Context, call TaskCall) context.Context {
+	return context.WithValue(ctx, taskCallKey{}, call)
+}
+
+// TaskCallFromContext returns the task attempt a request is made for, if any
+func TaskCallFromContext(ctx context.Context) (TaskCall, bool) {
+	call, ok := ctx.Value(taskCallKey{}).(TaskCall)
+	return call, ok
+}
+
+// RedactRequest returns a copy of request safe to persist, with the secret values
+// resolved for this attempt masked. The request is returned as is when it carries none.
+func (c TaskCall) RedactRequest(request *models.ServiceRequest) *models.ServiceRequest {
+	if len(c.Secrets) == 0 || request == nil {
+		return request
+	}
+	redacted := *request
+	redacted.Parameters, _ = redactSecretValues(request.Parameters, c.Secrets).(map[string]interface{})
+	return &redacted
+}
+
+// taskCallFor builds the task call for an attempt from the execution's record/replay mode
+func taskCallFor(execution *models.WorkflowExecution, taskID string, attempt int) TaskCall {
+	call := TaskCall{
+		ExecutionID: execution.ID,
+		TaskID:      taskID,
+		Attempt:     attempt,
+	}
+	call.Record, _ = execution.Metadata[RecordMetadataKey].(bool)
+	call.ReplayFrom, _ = execution.Metadata[ReplayMetadataKey].(string)
+	return call
+}
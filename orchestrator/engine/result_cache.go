@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ResultCacheMetadataKey marks a task state whose output was reused from the result cache
+const ResultCacheMetadataKey = "cache_hit"
+
+// ResultCache stores outputs of deterministic tasks by the hash of their resolved inputs
+type ResultCache interface {
+	LoadTaskResult(ctx context.Context, key string) (map[string]interface{}, bool, error)
+	SaveTaskResult(ctx context.Context, key string, output map[string]interface{}, ttl time.Duration) error
+}
+
+// SetResultCache enables reusing outputs of tasks marked deterministic whose resolved
+// parameters match an earlier successful run. Only operations their service announces as
+// retry safe are cached. Entries expire after ttl.
+func (we *WorkflowExecutor) SetResultCache(cache ResultCache, ttl time.Duration) {
+	we.resultCache = cache
+	we.resultCacheTTL = ttl
+}
+
+// taskCacheKey hashes the task type and its interpolated parameters. JSON encoding sorts
+// map keys, so equal parameters always produce the same key.
+func taskCacheKey(task *models.Task) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"type":       task.Type,
+		"parameters": task.Parameters,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cachedTaskKey returns the cache key for a task, or "" when the task is not cacheable.
+// A deterministic task is only cached when its operation is announced as retry safe:
+// reusing an output skips the side effects running it again would have.
+func (we *WorkflowExecutor) cachedTaskKey(task *models.Task) string {
+	if we.resultCache == nil || we.resultCacheTTL <= 0 || !task.Deterministic {
+		return ""
+	}
+	if _, _, safe, known := we.operationRetrySafety(task); !known || !safe {
+		return ""
+	}
+
+	key, err := taskCacheKey(task)
+	if err != nil {
+		we.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to hash task for result cache")
+		return ""
+	}
+	return key
+}
+
+// loadCachedResult applies a cached output to the task state, reporting whether it hit
+func (we *WorkflowExecutor) loadCachedResult(ctx context.Context, key string, taskState *models.TaskState) bool {
+	output, found, err := we.resultCache.LoadTaskResult(ctx, key)
+	if err != nil {
+		we.logger.WithError(err).WithField("task_id", taskState.ID).Warn("Failed to read result cache, executing task")
+		return false
+	}
+	if !found {
+		return false
+	}
+
+	taskState.Output = output
+	if taskState.Metadata == nil {
+		taskState.Metadata = make(map[string]interface{})
+	}
+	taskState.Metadata[ResultCacheMetadataKey] = true
+	taskState.Metadata["cache_key"] = key
+	return true
+}
+
+// saveCachedResult stores a successful task's output. Failures only cost a future hit.
+func (we *WorkflowExecutor) saveCachedResult(ctx context.Context, key string, taskState *models.TaskState) {
+	if err := we.resultCache.SaveTaskResult(ctx, key, taskState.Output, we.resultCacheTTL); err != nil {
+		we.logger.WithError(err).WithFields(logrus.Fields{
+			"task_id":   taskState.ID,
+			"cache_key": key,
+		}).Warn("Failed to store task result in cache")
+	}
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryResultCache is a ResultCache whose entries expire against a settable clock
+type memoryResultCache struct {
+	mutex   sync.Mutex
+	now     time.Time
+	outputs map[string]map[string]interface{}
+	expires map[string]time.Time
+}
+
+func newMemoryResultCache() *memoryResultCache {
+	return &memoryResultCache{
+		now:     time.Now(),
+		outputs: make(map[string]map[string]interface{}),
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (c *memoryResultCache) LoadTaskResult(ctx context.Context, key string) (map[string]interface{}, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	output, exists := c.outputs[key]
+	if !exists || !c.now.Before(c.expires[key]) {
+		return nil, false, nil
+	}
+	return output, true, nil
+}
+
+func (c *memoryResultCache) SaveTaskResult(ctx context.Context, key string, output map[string]interface{}, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.outputs[key] = output
+	c.expires[key] = c.now.Add(ttl)
+	return nil
+}
+
+func (c *memoryResultCache) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// cachingExecutor returns an executor caching results in cache for an hour, whose
+// service announces query as retry safe and delete as not. It counts dispatches.
+func cachingExecutor(cache ResultCache, dispatched *int) (*WorkflowExecutor, *memoryStateManager) {
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		*dispatched++
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"rows": *dispatched}
+		return nil
+	}), states, nil, 1)
+	executor.SetServiceRegistry(&fakeRegistry{
+		versions:  map[string]string{"data-abstractor": ""},
+		retrySafe: map[string]bool{"query": true, "delete": false},
+	})
+	executor.SetResultCache(cache, time.Hour)
+	return executor, states
+}
+
+// runCachedTask runs a one-task workflow and returns the task's final state
+func runCachedTask(t *testing.T, executor *WorkflowExecutor, states *memoryStateManager, task models.Task) *models.TaskState {
+	t.Helper()
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{task}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return execution.TaskStates[task.ID]
+}
+
+func deterministicQuery(table string) models.Task {
+	return models.Task{ID: "fetch", Type: "data", Deterministic: true, Parameters: map[string]interface{}{
+		"operation": "query",
+		"table":     table,
+	}}
+}
+
+func TestResultCacheReusesOutputForUnchangedTask(t *testing.T) {
+	dispatched := 0
+	executor, states := cachingExecutor(newMemoryResultCache(), &dispatched)
+
+	first := runCachedTask(t, executor, states, deterministicQuery("orders"))
+	second := runCachedTask(t, executor, states, deterministicQuery("orders"))
+
+	if dispatched != 1 {
+		t.Errorf("dispatched %d times, want the rerun served from cache", dispatched)
+	}
+	if first.Metadata[ResultCacheMetadataKey] == true {
+		t.Error("the first run must not be marked as a cache hit")
+	}
+	if second.Metadata[ResultCacheMetadataKey] != true || second.Status != models.StatusCompleted {
+		t.Errorf("got %s with metadata %v, want a completed cache hit", second.Status, second.Metadata)
+	}
+	if second.Output["rows"] != first.Output["rows"] {
+		t.Errorf("got output %v, want the cached %v", second.Output, first.Output)
+	}
+}
+
+func TestResultCacheMissesWhenParametersChange(t *testing.T) {
+	dispatched := 0
+	executor, states := cachingExecutor(newMemoryResultCache(), &dispatched)
+
+	runCachedTask(t, executor, states, deterministicQuery("orders"))
+	state := runCachedTask(t, executor, states, deterministicQuery("customers"))
+
+	if dispatched != 2 || state.Metadata[ResultCacheMetadataKey] == true {
+		t.Errorf("dispatched %d times, want the changed task executed again", dispatched)
+	}
+}
+
+func TestResultCacheEntriesExpire(t *testing.T) {
+	dispatched := 0
+	cache := newMemoryResultCache()
+	executor, states := cachingExecutor(cache, &dispatched)
+
+	runCachedTask(t, executor, states, deterministicQuery("orders"))
+	cache.advance(59 * time.Minute)
+	runCachedTask(t, executor, states, deterministicQuery("orders"))
+	if dispatched != 1 {
+		t.Fatalf("dispatched %d times within the TTL, want 1", dispatched)
+	}
+
+	cache.advance(2 * time.Minute)
+	state := runCachedTask(t, executor, states, deterministicQuery("orders"))
+	if dispatched != 2 || state.Metadata[ResultCacheMetadataKey] == true {
+		t.Errorf("dispatched %d times after the TTL, want the task executed again", dispatched)
+	}
+}
+
+func TestResultCacheSkipsTasksThatAreNotRetrySafe(t *testing.T) {
+	cases := map[string]models.Task{
+		"announced unsafe":  {ID: "purge", Type: "data", Deterministic: true, Parameters: map[string]interface{}{"operation": "delete"}},
+		"not announced":     {ID: "lookup", Type: "data", Deterministic: true, Parameters: map[string]interface{}{"operation": "lookup"}},
+		"not deterministic": {ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}},
+	}
+	for name, task := range cases {
+		dispatched := 0
+		cache := newMemoryResultCache()
+		executor, states := cachingExecutor(cache, &dispatched)
+
+		runCachedTask(t, executor, states, task)
+		runCachedTask(t, executor, states, task)
+		if dispatched != 2 || len(cache.outputs) != 0 {
+			t.Errorf("%s: dispatched %d times with %d cached outputs, want no caching", name, dispatched, len(cache.outputs))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OutputArtifactKey holds the artifact reference left behind by the artifact policy
+const OutputArtifactKey = "_artifact"
+
+// ValidateOutputRetention checks a retention policy name; empty means the default
+func ValidateOutputRetention(policy string) error {
+	switch policy {
+	case "", models.RetentionFull, models.RetentionDeclared, models.RetentionArtifact, models.RetentionDrop:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output retention policy: %s", policy)
+	}
+}
+
+// outputRetention resolves the policy for a task, falling back to the workflow default
+func outputRetention(workflow *models.WorkflowDefinition, task *models.Task) string {
+	if task.OutputRetention != "" {
+		return task.OutputRetention
+	}
+	if workflow.OutputRetention != "" {
+		return workflow.OutputRetention
+	}
+	return models.RetentionFull
+}
+
+// applyOutputRetention shrinks the outputs of completed tasks once every dependent has
+// finished and nothing can read them anymore. Tasks without dependents keep their output,
+// since it forms the workflow result.
+func (we *WorkflowExecutor) applyOutputRetention(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution, dag *DAG) {
+	for i := range workflow.Tasks {
+		task := &workflow.Tasks[i]
+		policy := outputRetention(workflow, task)
+		state := execution.TaskStates[task.ID]
+		if policy == models.RetentionFull || state == nil || state.Status != models.StatusCompleted {
+			continue
+		}
+		if _, applied := state.Metadata["output_retention"]; applied {
+			continue
+		}
+
+		dependents := dag.GetDependents(task.ID)
+		if len(dependents) == 0 || !allTasksFinished(dependents, execution) {
+			continue
+		}
+
+		if err := we.retainOutput(ctx, workflow, task, execution.ID, state, policy); err != nil {
+			we.logger.WithError(err).WithFields(logrus.Fields{
+				"execution_id": execution.ID,
+				"task_id":      task.ID,
+				"policy":       policy,
+			}).Warn("Failed to apply output retention, keeping full output")
+			continue
+		}
+
+		if state.Metadata == nil {
+			state.Metadata = make(map[string]interface{})
+		}
+		state.Metadata["output_retention"] = policy
+	}
+}
+
+// retainOutput replaces a task's output according to the retention policy
+func (we *WorkflowExecutor) retainOutput(ctx context.Context, workflow *models.WorkflowDefinition, task *models.Task, executionID string, state *models.TaskState, policy string) error {
+	switch policy {
+	case models.RetentionDrop:
+		state.Output = nil
+	case models.RetentionDeclared:
+		retained := make(map[string]interface{}, len(task.RetainedOutputs))
+		for _, key := range task.RetainedOutputs {
+			if value, exists := state.Output[key]; exists {
+				retained[key] = value
+			}
+		}
+		state.Output = retained
+	case models.RetentionArtifact:
+		if we.artifactStore == nil {
+			return fmt.Errorf("artifact retention requires an artifact store")
+		}
+		// Outputs persisted on completion are already in the store
+		if !(task.PersistOutput || workflow.PersistOutputs) {
+			if err := we.artifactStore.SaveTaskOutput(ctx, executionID, task.ID, state.Output); err != nil {
+				return err
+			}
+		}
+		state.Output = map[string]interface{}{
+			OutputArtifactKey: map[string]interface{}{
+				"execution_id": executionID,
+				"task_id":      task.ID,
+			},
+		}
+	}
+	return nil
+}
+
+func allTasksFinished(taskIDs []string, execution *models.WorkflowExecution) bool {
+	for _, id := range taskIDs {
+		state, exists := execution.TaskStates[id]
+		if !exists {
+			return false
+		}
+		switch state.Status {
+		case models.StatusCompleted, models.StatusFailed, models.StatusSkipped, models.StatusCancelled:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+// runRetentionWorkflow runs a fetch task with the given retention settings followed by a
+// report task reading its output, and returns the saved execution and what report saw
+func runRetentionWorkflow(t *testing.T, store ArtifactStore, fetch models.Task) (*models.WorkflowExecution, map[string]interface{}) {
+	t.Helper()
+	var seen map[string]interface{}
+	state := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if task.ID == "report" {
+			seen = execution.TaskStates["fetch"].Output
+		}
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"rows": []interface{}{1, 2, 3}, "count": 3}
+		return nil
+	}), state, nil, 1)
+	if store != nil {
+		executor.SetArtifactStore(store)
+	}
+
+	fetch.ID, fetch.Type = "fetch", "data"
+	workflow := &models.WorkflowDefinition{ID: "retention", Tasks: []models.Task{
+		fetch,
+		{ID: "report", Type: "data", DependsOn: []string{"fetch"}},
+	}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	execution, err := state.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return execution, seen
+}
+
+func TestOutputRetentionPolicies(t *testing.T) {
+	full := map[string]interface{}{"rows": []interface{}{1, 2, 3}, "count": 3}
+	cases := []struct {
+		policy string
+		task   models.Task
+		want   map[string]interface{}
+	}{
+		{models.RetentionFull, models.Task{}, full},
+		{models.RetentionDrop, models.Task{OutputRetention: models.RetentionDrop}, nil},
+		{models.RetentionDeclared, models.Task{OutputRetention: models.RetentionDeclared, RetainedOutputs: []string{"count", "missing"}}, map[string]interface{}{"count": 3}},
+	}
+	for _, c := range cases {
+		execution, seen := runRetentionWorkflow(t, nil, c.task)
+		if !reflect.DeepEqual(seen, full) {
+			t.Errorf("%s: the dependent must read the full output, got %v", c.policy, seen)
+		}
+		if got := execution.TaskStates["fetch"].Output; !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got retained output %v, want %v", c.policy, got, c.want)
+		}
+		if got := execution.TaskStates["report"].Output; !reflect.DeepEqual(got, full) {
+			t.Errorf("%s: the final task's output must be kept, got %v", c.policy, got)
+		}
+	}
+}
+
+func TestOutputRetentionOffloadsToArtifactStore(t *testing.T) {
+	store := &fakeArtifactStore{}
+	execution, _ := runRetentionWorkflow(t, store, models.Task{OutputRetention: models.RetentionArtifact})
+
+	if !store.saved("fetch") {
+		t.Fatal("expected the output to be offloaded to the artifact store")
+	}
+	reference := map[string]interface{}{OutputArtifactKey: map[string]interface{}{"execution_id": "exec-1", "task_id": "fetch"}}
+	if got := execution.TaskStates["fetch"].Output; !reflect.DeepEqual(got, reference) {
+		t.Errorf("got output %v, want the artifact reference", got)
+	}
+	if got := execution.TaskStates["fetch"].Metadata["output_retention"]; got != models.RetentionArtifact {
+		t.Errorf("got retention metadata %v", got)
+	}
+}
+
+func TestOutputRetentionWithoutArtifactStoreKeepsOutput(t *testing.T) {
+	execution, _ := runRetentionWorkflow(t, nil, models.Task{OutputRetention: models.RetentionArtifact})
+
+	if got := execution.TaskStates["fetch"].Output; got["count"] != 3 {
+		t.Errorf("expected the full output to be kept without a store, got %v", got)
+	}
+	if _, applied := execution.TaskStates["fetch"].Metadata["output_retention"]; applied {
+		t.Error("a failed retention must not be recorded as applied")
+	}
+}
+
+func TestValidateOutputRetention(t *testing.T) {
+	for _, policy := range []string{"", models.RetentionFull, models.RetentionDeclared, models.RetentionArtifact, models.RetentionDrop} {
+		if err := ValidateOutputRetention(policy); err != nil {
+			t.Errorf("%q: unexpected error %v", policy, err)
+		}
+	}
+	if err := ValidateOutputRetention("compress"); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+}
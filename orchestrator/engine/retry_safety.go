@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetrySuppressedMetadataKey records why a failed task was not retried despite its retry policy
+const RetrySuppressedMetadataKey = "retry_suppressed"
+
+// serviceOperations maps task types to the operation their services announce, for task
+// types whose operation isn't chosen by a parameter
+var serviceOperations = map[string]string{
+	"exec": "execute_container",
+}
+
+// taskOperation returns the announced operation a task dispatches, or "" if unknown
+func taskOperation(task *models.Task) string {
+	switch task.Type {
+	case "data":
+		operation, _ := task.Parameters["operation"].(string)
+		return operation
+	case "ai":
+		return aiOperation(task)
+	}
+	return serviceOperations[task.Type]
+}
+
+// aiOperation returns the AI abstractor operation a task's parameters amount to: asking
+// for JSON or YAML generates structured data, anything else generates content
+func aiOperation(task *models.Task) string {
+	format, _ := task.Parameters["response_format"].(string)
+	switch strings.ToLower(format) {
+	case "json", "yaml":
+		return "generate_structured_data"
+	}
+	return "generate_content"
+}
+
+// operationRetrySafety looks up whether the operation a task dispatches is announced as
+// retry safe. known is false without a registry or when the operation isn't announced.
+func (we *WorkflowExecutor) operationRetrySafety(task *models.Task) (component, operation string, safe, known bool) {
+	component, exists := serviceComponents[task.Type]
+	operation = taskOperation(task)
+	if we.serviceRegistry == nil || !exists || operation == "" {
+		return component, operation, false, false
+	}
+
+	safe, known = we.serviceRegistry.IsOperationRetrySafe(component, operation)
+	return component, operation, safe, known
+}
+
+// retryAllowed reports whether a failed task may be dispatched again. Operations their
+// service announces as not retry safe, which could repeat side effects such as running a
+// container twice, are only retried when the retry policy sets force_retry. Operations
+// the registry knows nothing about are retried as before.
+func (we *WorkflowExecutor) retryAllowed(execution *models.WorkflowExecution, task *models.Task) bool {
+	if we.serviceRegistry == nil || task.RetryPolicy == nil || task.RetryPolicy.ForceRetry {
+		return true
+	}
+
+	component, operation, safe, known := we.operationRetrySafety(task)
+	if !known || safe {
+		return true
+	}
+
+	reason := fmt.Sprintf("%s operation %s is not retry safe; set force_retry to retry it", component, operation)
+	taskState := execution.TaskStates[task.ID]
+	if taskState.Metadata == nil {
+		taskState.Metadata = make(map[string]interface{})
+	}
+	taskState.Metadata[RetrySuppressedMetadataKey] = reason
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+		"component":    component,
+		"operation":    operation,
+	}).Warn("Suppressing retry of operation that is not retry safe")
+
+	return false
+}
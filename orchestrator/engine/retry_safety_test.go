@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runRetrySafetyWorkflow runs task with two retries, failing every attempt, against a
+// registry announcing retrySafe, and returns the attempts made and the task's final state
+func runRetrySafetyWorkflow(t *testing.T, task models.Task, retrySafe map[string]bool) (int, *models.TaskState) {
+	t.Helper()
+	attempts := 0
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		attempts++
+		return errors.New("service failed")
+	}), states, nil, 1)
+	executor.SetServiceRegistry(&fakeRegistry{
+		versions:  map[string]string{"data-abstractor": "", "ai-abstractor": "", "exec-agent": ""},
+		retrySafe: retrySafe,
+	})
+
+	if task.RetryPolicy == nil {
+		task.RetryPolicy = &models.RetryPolicy{}
+	}
+	task.RetryPolicy.MaxRetries = 2
+	task.RetryPolicy.BackoffType = "fixed"
+	task.RetryPolicy.InitialDelay = time.Millisecond
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{task}}
+	response, _ := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if response == nil {
+		t.Fatal("expected a response")
+	}
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempts, execution.TaskStates[task.ID]
+}
+
+func TestRetryOfUnsafeOperationIsSuppressed(t *testing.T) {
+	attempts, state := runRetrySafetyWorkflow(t, models.Task{ID: "run", Type: "exec"}, map[string]bool{"execute_container": false})
+	if attempts != 1 {
+		t.Errorf("made %d attempts, want 1", attempts)
+	}
+	reason, _ := state.Metadata[RetrySuppressedMetadataKey].(string)
+	if !strings.Contains(reason, "execute_container is not retry safe") {
+		t.Errorf("got suppression reason %q", reason)
+	}
+}
+
+func TestForceRetryRetriesUnsafeOperation(t *testing.T) {
+	task := models.Task{ID: "run", Type: "exec", RetryPolicy: &models.RetryPolicy{ForceRetry: true}}
+	attempts, state := runRetrySafetyWorkflow(t, task, map[string]bool{"execute_container": false})
+	if attempts != 3 {
+		t.Errorf("made %d attempts, want 3", attempts)
+	}
+	if _, suppressed := state.Metadata[RetrySuppressedMetadataKey]; suppressed {
+		t.Error("expected no suppression with force_retry")
+	}
+}
+
+func TestRetrySafetyUsesAnnouncedAIOperations(t *testing.T) {
+	// Marking an AI operation unsafe shows which one the task was matched to
+	cases := []struct {
+		parameters map[string]interface{}
+		operation  string
+	}{
+		{map[string]interface{}{"prompt": "summarize"}, "generate_content"},
+		{map[string]interface{}{"prompt": "summarize", "response_format": "markdown"}, "generate_content"},
+		{map[string]interface{}{"prompt": "schema", "response_format": "json"}, "generate_structured_data"},
+		{map[string]interface{}{"prompt": "config", "response_format": "YAML"}, "generate_structured_data"},
+	}
+	for _, c := range cases {
+		task := models.Task{ID: "ask", Type: "ai", Parameters: c.parameters}
+		attempts, state := runRetrySafetyWorkflow(t, task, map[string]bool{c.operation: false})
+		reason, _ := state.Metadata[RetrySuppressedMetadataKey].(string)
+		if attempts != 1 || !strings.Contains(reason, c.operation) {
+			t.Errorf("%v: made %d attempts with reason %q, want %s suppressed", c.parameters, attempts, reason, c.operation)
+		}
+	}
+}
+
+func TestRetryOfSafeOrUnknownOperationContinues(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"announced safe": {"generate_content": true},
+		"not announced":  {},
+	}
+	for name, retrySafe := range cases {
+		attempts, _ := runRetrySafetyWorkflow(t, models.Task{ID: "ask", Type: "ai"}, retrySafe)
+		if attempts != 3 {
+			t.Errorf("%s: made %d attempts, want 3", name, attempts)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	var attempts []time.Time
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			// Wrapped, as executors do when adding context
+			return fmt.Errorf("ask failed: %w", &RetryAfterError{Err: errors.New("rate limited"), RetryAfter: 50 * time.Millisecond})
+		}
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{
+		ID:          "ask",
+		Type:        "ai",
+		RetryPolicy: &models.RetryPolicy{MaxRetries: 1, BackoffType: "fixed", InitialDelay: time.Millisecond},
+	}}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("made %d attempts, want 2", len(attempts))
+	}
+	if gap := attempts[1].Sub(attempts[0]); gap < 50*time.Millisecond {
+		t.Errorf("retried after %s, want at least the requested 50ms", gap)
+	}
+}
+
+// runRetryIfWorkflow runs one task whose attempts return the given confidences in turn
+func runRetryIfWorkflow(t *testing.T, retryIf string, confidences ...float64) (int, *models.TaskState) {
+	t.Helper()
+	attempts := 0
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		confidence := confidences[len(confidences)-1]
+		if attempts < len(confidences) {
+			confidence = confidences[attempts]
+		}
+		attempts++
+		execution.TaskStates[task.ID].Output = map[string]interface{}{"confidence": confidence}
+		return nil
+	}), states, nil, 1)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{
+		ID:          "classify",
+		Type:        "ai",
+		RetryIf:     retryIf,
+		RetryPolicy: &models.RetryPolicy{MaxRetries: 2, BackoffType: "fixed", InitialDelay: time.Millisecond},
+	}}}
+	response, _ := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempts, execution.TaskStates["classify"]
+}
+
+func TestRetryIfRetriesLowConfidenceUpToLimit(t *testing.T) {
+	attempts, state := runRetryIfWorkflow(t, "output.confidence < 0.5", 0.2)
+	if attempts != 3 {
+		t.Errorf("made %d attempts, want the initial one plus 2 retries", attempts)
+	}
+	if state.Status != models.StatusFailed || !strings.Contains(state.Error, "retry_if") {
+		t.Errorf("got %s: %s, want a failure naming retry_if", state.Status, state.Error)
+	}
+}
+
+func TestRetryIfStopsOnceOutputIsAcceptable(t *testing.T) {
+	attempts, state := runRetryIfWorkflow(t, "output.confidence < 0.5", 0.2, 0.9)
+	if attempts != 2 {
+		t.Errorf("made %d attempts, want 2", attempts)
+	}
+	if state.Status != models.StatusCompleted {
+		t.Errorf("got %s: %s, want completed", state.Status, state.Error)
+	}
+}
+
+func TestRetryIfInvalidExpressionFailsWithoutRetry(t *testing.T) {
+	attempts, state := runRetryIfWorkflow(t, "output.confidence <", 0.2)
+	if attempts != 1 {
+		t.Errorf("made %d attempts, want 1", attempts)
+	}
+	if state.Status != models.StatusFailed || !strings.Contains(state.Error, "invalid retry_if") {
+		t.Errorf("got %s: %s, want an invalid retry_if failure", state.Status, state.Error)
+	}
+}
+
+func TestExponentialBackoffIsCappedAtMaxDelay(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	policy := &models.RetryPolicy{BackoffType: "exponential", InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	want := map[int]time.Duration{
+		1:   100 * time.Millisecond,
+		2:   200 * time.Millisecond,
+		4:   800 * time.Millisecond,
+		5:   time.Second,
+		200: time.Second, // long retry chains must not overflow past the cap
+	}
+	for attempt, delay := range want {
+		if got := executor.calculateBackoffDelay(policy, attempt); got != delay {
+			t.Errorf("attempt %d: got delay %s, want %s", attempt, got, delay)
+		}
+	}
+}
+
+func TestBackoffJitterStaysWithinBounds(t *testing.T) {
+	executor := NewWorkflowExecutor(nil, newMemoryStateManager(), nil, 1)
+	cases := []struct {
+		jitter   string
+		min, max time.Duration
+	}{
+		{models.JitterFull, 0, time.Second},
+		{models.JitterEqual, 500 * time.Millisecond, time.Second},
+		{"", time.Second, time.Second},
+	}
+
+	for _, c := range cases {
+		// Exponential growth from attempt 6 would be 3.2s without the cap
+		policy := &models.RetryPolicy{BackoffType: "exponential", InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: c.jitter}
+		distinct := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			delay := executor.calculateBackoffDelay(policy, 6)
+			if delay < c.min || delay > c.max {
+				t.Fatalf("jitter %q: got delay %s, want it within [%s, %s]", c.jitter, delay, c.min, c.max)
+			}
+			distinct[delay] = true
+		}
+		if c.jitter != "" && len(distinct) < 2 {
+			t.Errorf("jitter %q: got the same delay every time, want it randomized", c.jitter)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"strings"
+)
+
+// SecretRefKey marks a parameter value that references a secret, e.g.
+// api_key: {secretRef: openai-key}
+const SecretRefKey = "secretRef"
+
+// SecretProvider resolves secret references to their values
+type SecretProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// SetSecretProvider enables secretRef parameters. References are resolved only into the
+// copy of the task sent to the service, so execution state keeps the reference.
+func (we *WorkflowExecutor) SetSecretProvider(provider SecretProvider) {
+	we.secretProvider = provider
+}
+
+// resolveSecrets returns the task to dispatch, with every secretRef replaced by its value,
+// and the values it resolved so anything persisting the dispatched request can redact them.
+// The task is returned unchanged when it references no secrets.
+func (we *WorkflowExecutor) resolveSecrets(ctx context.Context, task *models.Task) (*models.Task, []string, error) {
+	if !containsSecretRef(task.Parameters) {
+		return task, nil, nil
+	}
+	if we.secretProvider == nil {
+		return nil, nil, fmt.Errorf("task %s references secrets but no secret provider is configured", task.ID)
+	}
+
+	var secrets []string
+	resolved, err := we.resolveSecretValue(ctx, task.Parameters, &secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve secrets for task %s: %w", task.ID, err)
+	}
+
+	dispatch := *task
+	dispatch.Parameters = resolved.(map[string]interface{})
+	return &dispatch, secrets, nil
+}
+
+func (we *WorkflowExecutor) resolveSecretValue(ctx context.Context, value interface{}, secrets *[]string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if name, ok := secretRefName(v); ok {
+			secret, err := we.secretProvider.GetSecret(ctx, name)
+			if err == nil && secret != "" {
+				*secrets = append(*secrets, secret)
+			}
+			return secret, err
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := we.resolveSecretValue(ctx, item, secrets)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := we.resolveSecretValue(ctx, item, secrets)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = resolved
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// secretRefName recognises a map holding only a secretRef key with a string name
+func secretRefName(value map[string]interface{}) (string, bool) {
+	if len(value) != 1 {
+		return "", false
+	}
+	name, ok := value[SecretRefKey].(string)
+	return name, ok && name != ""
+}
+
+func containsSecretRef(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := secretRefName(v); ok {
+			return true
+		}
+		for _, item := range v {
+			if containsSecretRef(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if containsSecretRef(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactSecretValues returns a copy of value with every occurrence of a resolved secret
+// masked, leaving value itself untouched
+func redactSecretValues(value interface{}, secrets []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = redactSecretValues(item, secrets)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactSecretValues(item, secrets)
+		}
+		return result
+	case []string:
+		result := make([]string, len(v))
+		for i, item := range v {
+			result[i] = redactSecretValues(item, secrets).(string)
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]string, len(v))
+		for key, item := range v {
+			result[key] = redactSecretValues(item, secrets).(string)
+		}
+		return result
+	case string:
+		for _, secret := range secrets {
+			v = strings.ReplaceAll(v, secret, redactedVariableValue)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,18 @@
+package engine
+
+import "context"
+
+type executionStartedKey struct{}
+
+// WithExecutionStarted returns a context under which ExecuteWorkflow calls started once
+// the execution's initial state is saved, from when recovery can see it
+func WithExecutionStarted(ctx context.Context, started func(executionID string)) context.Context {
+	return context.WithValue(ctx, executionStartedKey{}, started)
+}
+
+// notifyExecutionStarted calls the hook set by WithExecutionStarted, if any
+func notifyExecutionStarted(ctx context.Context, executionID string) {
+	if started, ok := ctx.Value(executionStartedKey{}).(func(string)); ok {
+		started(executionID)
+	}
+}
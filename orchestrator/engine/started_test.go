@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"testing"
+)
+
+func TestExecuteWorkflowNotifiesStartedAfterInitialSave(t *testing.T) {
+	states := newMemoryStateManager()
+	var saved bool
+	var startedID string
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		if startedID == "" {
+			t.Error("expected the started hook to run before any task")
+		}
+		return nil
+	}), states, nil, 1)
+
+	ctx := WithExecutionStarted(context.Background(), func(executionID string) {
+		_, err := states.LoadExecution(context.Background(), executionID)
+		saved = err == nil
+		startedID = executionID
+	})
+
+	response, err := executor.ExecuteWorkflow(ctx, &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "a", Type: "data"}}}, &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if startedID != response.ExecutionID {
+		t.Fatalf("started hook got %q, want %q", startedID, response.ExecutionID)
+	}
+	if !saved {
+		t.Error("expected the execution to be saved before the started hook ran")
+	}
+}
+
+func TestExecuteWorkflowWithoutStartedHook(t *testing.T) {
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "a", Type: "data"}}}, &models.WorkflowRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
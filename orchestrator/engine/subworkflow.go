@@ -0,0 +1,37 @@
+package engine
+
+import "orchestrator/models"
+
+// SubWorkflowTaskType is the task type that runs a template as a nested execution
+const SubWorkflowTaskType = "workflow"
+
+// Metadata linking a nested execution to the execution whose task started it
+const (
+	ParentExecutionMetadataKey = "parent_execution_id"
+	WorkflowStackMetadataKey   = "workflow_stack" // templates from the root execution down to this one
+)
+
+// WorkflowStack returns the templates enclosing an execution, outermost first and ending
+// with its own. A root execution's stack is just its template, if it has one.
+func WorkflowStack(execution *models.WorkflowExecution) []string {
+	var stack []string
+	switch recorded := execution.Metadata[WorkflowStackMetadataKey].(type) {
+	case []string:
+		stack = append(stack, recorded...)
+	case []interface{}:
+		// Metadata read back from the state store
+		for _, entry := range recorded {
+			if templateID, ok := entry.(string); ok {
+				stack = append(stack, templateID)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return stack
+	}
+
+	if templateID, ok := execution.Metadata[TemplateMetadataKey].(string); ok && templateID != "" {
+		return []string{templateID}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"context"
+)
+
+// SetGlobalTaskLimit caps the tasks running at once across every execution on this
+// instance. The per-batch limit still bounds each workflow on its own; 0 disables the cap.
+func (we *WorkflowExecutor) SetGlobalTaskLimit(limit int) {
+	if limit <= 0 {
+		we.taskSlots = nil
+		return
+	}
+	we.taskSlots = make(chan struct{}, limit)
+}
+
+// acquireTaskSlot waits for room under the global task limit. The returned function
+// releases the slot.
+func (we *WorkflowExecutor) acquireTaskSlot(ctx context.Context) (func(), error) {
+	if we.taskSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case we.taskSlots <- struct{}{}:
+		return func() { <-we.taskSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlightTasks returns how many tasks hold a slot under the global task limit
+func (we *WorkflowExecutor) InFlightTasks() int {
+	return len(we.taskSlots)
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGlobalTaskLimitHoldsAcrossWorkflows(t *testing.T) {
+	var mutex sync.Mutex
+	running, peak := 0, 0
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		mutex.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return nil
+	}), newMemoryStateManager(), nil, 4)
+	executor.SetGlobalTaskLimit(2)
+
+	// Each workflow alone could run all its tasks at once under the per-batch limit
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		workflow := &models.WorkflowDefinition{ID: fmt.Sprintf("wf-%d", i), Tasks: []models.Task{
+			{ID: "a", Type: "exec"}, {ID: "b", Type: "exec"}, {ID: "c", Type: "exec"},
+		}}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if peak != 2 {
+		t.Errorf("got at most %d tasks running at once, want the global limit of 2", peak)
+	}
+	if inFlight := executor.InFlightTasks(); inFlight != 0 {
+		t.Errorf("got %d slots still held after every workflow finished", inFlight)
+	}
+}
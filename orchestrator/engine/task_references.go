@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"orchestrator/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// taskReferencesKey is the variable namespace exposing earlier task results, e.g.
+// ${tasks.fetch_data.output.summary}
+const taskReferencesKey = "tasks"
+
+// completedTaskOutputs snapshots the output and status of every completed task. It is
+// taken before a batch starts so tasks running in parallel never read each other's state.
+func completedTaskOutputs(execution *models.WorkflowExecution) map[string]interface{} {
+	outputs := make(map[string]interface{})
+	for taskID, state := range execution.TaskStates {
+		if state == nil || state.Status != models.StatusCompleted {
+			continue
+		}
+		outputs[taskID] = map[string]interface{}{
+			"output": state.Output,
+			"status": string(state.Status),
+		}
+	}
+	return outputs
+}
+
+// interpolationScope returns the variables a task is interpolated against: the workflow
+// variables plus the tasks namespace. A workflow variable named "tasks" takes precedence.
+func interpolationScope(variables map[string]interface{}, taskOutputs map[string]interface{}) map[string]interface{} {
+	if _, exists := variables[taskReferencesKey]; exists || len(taskOutputs) == 0 {
+		return variables
+	}
+
+	scope := make(map[string]interface{}, len(variables)+1)
+	for key, value := range variables {
+		scope[key] = value
+	}
+	scope[taskReferencesKey] = taskOutputs
+	return scope
+}
+
+// warnUnresolvedTaskReferences logs task references left in place, usually a missing
+// output field or a task that has not completed
+func (we *WorkflowExecutor) warnUnresolvedTaskReferences(execution *models.WorkflowExecution, taskID string, names []string) {
+	var references []string
+	for _, name := range names {
+		if strings.HasPrefix(name, taskReferencesKey+".") {
+			references = append(references, name)
+		}
+	}
+	if len(references) == 0 {
+		return
+	}
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"task_id":      taskID,
+		"references":   references,
+	}).Warn("Unresolved task output references left in place")
+}
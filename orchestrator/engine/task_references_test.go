@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"sync"
+	"testing"
+)
+
+// runReferencingWorkflow runs workflow with fetch producing a summary, and returns the
+// parameters each task was dispatched with
+func runReferencingWorkflow(t *testing.T, workflow *models.WorkflowDefinition) map[string]map[string]interface{} {
+	t.Helper()
+	var mutex sync.Mutex
+	dispatched := make(map[string]map[string]interface{})
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		mutex.Lock()
+		dispatched[task.ID] = task.Parameters
+		mutex.Unlock()
+		if task.ID == "fetch" {
+			execution.TaskStates[task.ID].Output = map[string]interface{}{
+				"summary": map[string]interface{}{"rows": 42, "table": "sales"},
+			}
+		}
+		return nil
+	}), newMemoryStateManager(), nil, 2)
+
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return dispatched
+}
+
+func TestTaskOutputReachesDownstreamParameters(t *testing.T) {
+	dispatched := runReferencingWorkflow(t, &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "sibling", Type: "data", Parameters: map[string]interface{}{"rows": "${tasks.fetch.output.summary.rows}"}},
+		{ID: "report", Type: "ai", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{
+			"prompt": "summarize ${tasks.fetch.output.summary.rows} rows of ${tasks.fetch.output.summary.table}",
+			"status": "${tasks.fetch.status}",
+		}},
+	}})
+
+	report := dispatched["report"]
+	if report["prompt"] != "summarize 42 rows of sales" {
+		t.Errorf("got prompt %q, want the fetch output interpolated", report["prompt"])
+	}
+	if report["status"] != string(models.StatusCompleted) {
+		t.Errorf("got status %q, want completed", report["status"])
+	}
+
+	// A task running alongside fetch can't see its output, so the reference is left in place
+	if got := dispatched["sibling"]["rows"]; got != "${tasks.fetch.output.summary.rows}" {
+		t.Errorf("got %q for a reference to a task in the same batch", got)
+	}
+}
+
+func TestWorkflowVariableNamedTasksTakesPrecedence(t *testing.T) {
+	dispatched := runReferencingWorkflow(t, &models.WorkflowDefinition{ID: "wf",
+		Variables: map[string]interface{}{"tasks": map[string]interface{}{"fetch": "from variables"}},
+		Tasks: []models.Task{
+			{ID: "fetch", Type: "data"},
+			{ID: "report", Type: "ai", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{"prompt": "${tasks.fetch}"}},
+		},
+	})
+
+	if got := dispatched["report"]["prompt"]; got != "from variables" {
+		t.Errorf("got prompt %q, want the workflow variable", got)
+	}
+}
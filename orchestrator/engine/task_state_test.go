@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"testing"
+	"time"
+)
+
+func TestClassifyTaskError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, expire := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer expire()
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		timedOut bool
+		status   models.ExecutionStatus
+		code     string
+	}{
+		{"cancelled workflow", cancelled, false, models.StatusCancelled, models.TaskErrorCancelled},
+		{"cancelled workflow wins over task timeout", cancelled, true, models.StatusCancelled, models.TaskErrorCancelled},
+		{"workflow deadline", expired, false, models.StatusFailed, models.TaskErrorTimeout},
+		{"task timeout", context.Background(), true, models.StatusFailed, models.TaskErrorTimeout},
+		{"plain failure", context.Background(), false, models.StatusFailed, ""},
+	}
+	for _, c := range cases {
+		status, code := classifyTaskError(c.ctx, c.timedOut)
+		if status != c.status || code != c.code {
+			t.Errorf("%s: got %s/%q, want %s/%q", c.name, status, code, c.status, c.code)
+		}
+	}
+}
+
+func TestCancelledWorkflowMarksTaskCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	state := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(taskCtx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		cancel()
+		<-taskCtx.Done()
+		return taskCtx.Err()
+	}), state, nil, 1)
+
+	workflow := &models.WorkflowDefinition{
+		ID:    "cancelled-workflow",
+		Tasks: []models.Task{{ID: "fetch", Type: "data", RetryPolicy: &models.RetryPolicy{MaxRetries: 3}}},
+	}
+	if _, err := executor.ExecuteWorkflow(ctx, workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err == nil {
+		t.Fatal("expected the cancellation to fail the workflow")
+	}
+
+	execution, err := state.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskState := execution.TaskStates["fetch"]
+	if taskState.Status != models.StatusCancelled || taskState.ErrorCode != models.TaskErrorCancelled {
+		t.Errorf("got task state %s/%q, want cancelled", taskState.Status, taskState.ErrorCode)
+	}
+	if taskState.RetryCount != 0 {
+		t.Errorf("a cancelled task must not be retried, got %d retries", taskState.RetryCount)
+	}
+	if execution.Status != models.StatusCancelled {
+		t.Errorf("got workflow status %s, want cancelled", execution.Status)
+	}
+}
+
+func TestTimedOutTaskMarksTaskFailedWithTimeout(t *testing.T) {
+	state := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(taskCtx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		<-taskCtx.Done()
+		return taskCtx.Err()
+	}), state, nil, 1)
+
+	workflow := &models.WorkflowDefinition{
+		ID:    "slow-workflow",
+		Tasks: []models.Task{{ID: "process", Type: "exec", ExecTimeout: 1}},
+	}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-1"}); err == nil {
+		t.Fatal("expected the timed out task to fail the workflow")
+	}
+
+	execution, err := state.LoadExecution(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskState := execution.TaskStates["process"]
+	if taskState.Status != models.StatusFailed || taskState.ErrorCode != models.TaskErrorTimeout {
+		t.Errorf("got task state %s/%q, want failed with a timeout", taskState.Status, taskState.ErrorCode)
+	}
+	if execution.Status != models.StatusFailed {
+		t.Errorf("got workflow status %s, want failed", execution.Status)
+	}
+}
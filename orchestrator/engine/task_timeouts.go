@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/metrics"
+	"orchestrator/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTaskExecTimeout bounds each attempt of a task that sets no timeout
+const DefaultTaskExecTimeout = 5 * time.Minute
+
+// TaskTimingMetadataKey holds how long a task spent in each phase, in milliseconds:
+// waiting for a slot, executing (all attempts) and backing off between attempts
+const TaskTimingMetadataKey = "timing"
+
+// Phases recorded under TaskTimingMetadataKey
+const (
+	PhaseQueueWait = "queue_wait_ms"
+	PhaseExec      = "exec_ms"
+	PhaseBackoff   = "backoff_ms"
+)
+
+// QueueTimeoutError reports a task that waited out its queue timeout without being dispatched
+type QueueTimeoutError struct {
+	TaskID  string
+	Timeout time.Duration
+}
+
+func (e *QueueTimeoutError) Error() string {
+	return fmt.Sprintf("task %s was not dispatched within its queue timeout of %s", e.TaskID, e.Timeout)
+}
+
+// SetDefaultQueueTimeout bounds how long tasks without a queue_timeout wait for a slot
+// before failing; 0 lets them wait as long as the workflow allows
+func (we *WorkflowExecutor) SetDefaultQueueTimeout(timeout time.Duration) {
+	we.defaultQueueTimeout = timeout
+}
+
+// queueTimeout returns how long a task may wait for a slot, 0 meaning no limit
+func (we *WorkflowExecutor) queueTimeout(task *models.Task) time.Duration {
+	if task.QueueTimeout > 0 {
+		return time.Duration(task.QueueTimeout) * time.Second
+	}
+	return we.defaultQueueTimeout
+}
+
+// execTimeout returns how long each attempt of a task may run once dispatched. exec_timeout
+// takes precedence over the older timeout field.
+func execTimeout(task *models.Task) time.Duration {
+	switch {
+	case task.ExecTimeout > 0:
+		return time.Duration(task.ExecTimeout) * time.Second
+	case task.Timeout > 0:
+		return time.Duration(task.Timeout) * time.Second
+	}
+	return DefaultTaskExecTimeout
+}
+
+// waitForDispatch takes a slot under the batch's concurrency limit and, unless the task
+// is a sub-workflow, under the global task limit. The wait is bounded by the task's queue
+// timeout; running out of it returns a QueueTimeoutError. The returned function releases
+// the slots.
+func (we *WorkflowExecutor) waitForDispatch(ctx context.Context, task *models.Task, semaphore chan struct{}) (func(), error) {
+	queueCtx := ctx
+	timeout := we.queueTimeout(task)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		queueCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Report the queue timeout only when it, not the workflow, ran out
+	waitErr := func() error {
+		if ctx.Err() == nil && queueCtx.Err() == context.DeadlineExceeded {
+			return &QueueTimeoutError{TaskID: task.ID, Timeout: timeout}
+		}
+		return queueCtx.Err()
+	}
+
+	select {
+	case semaphore <- struct{}{}:
+	case <-queueCtx.Done():
+		return nil, waitErr()
+	}
+
+	// A slot freed by a task that was cancelled can win the select above; don't dispatch
+	// once the wait is already over
+	if queueCtx.Err() != nil {
+		<-semaphore
+		return nil, waitErr()
+	}
+
+	// Sub-workflow tasks only wait for their nested execution, whose tasks take slots of
+	// their own; holding one here as well could use up the limit and deadlock
+	if task.Type == SubWorkflowTaskType {
+		return func() { <-semaphore }, nil
+	}
+
+	releaseSlot, err := we.acquireTaskSlot(queueCtx)
+	if err != nil {
+		<-semaphore
+		return nil, waitErr()
+	}
+	return func() {
+		releaseSlot()
+		<-semaphore
+	}, nil
+}
+
+// failQueuedTask marks a task that timed out waiting for a slot as failed, with an error
+// code distinguishing it from a task that timed out while running
+func (we *WorkflowExecutor) failQueuedTask(execution *models.WorkflowExecution, task *models.Task, err *QueueTimeoutError, waited time.Duration) {
+	taskState := execution.TaskStates[task.ID]
+	now := time.Now()
+	taskState.Status = models.StatusFailed
+	taskState.ErrorCode = models.TaskErrorQueueTimeout
+	taskState.Error = err.Error()
+	taskState.EndTime = &now
+	recordTaskTiming(taskState, PhaseQueueWait, waited)
+	recordDecision(taskState, DecisionFailed, "not dispatched within its queue timeout")
+	we.publishTaskProgress(execution, taskState)
+	metrics.TaskFinished(workflowMetricLabel(execution), task.Type, string(taskState.Status), waited)
+
+	we.logger.WithFields(logrus.Fields{
+		"execution_id":  execution.ID,
+		"task_id":       task.ID,
+		"queue_timeout": err.Timeout,
+	}).Warn("Task timed out waiting for a slot")
+}
+
+// recordTaskTiming adds time spent in a phase to a task's timing metadata. Timings
+// loaded from state decode as float64, so they are accumulated in that form.
+func recordTaskTiming(state *models.TaskState, phase string, elapsed time.Duration) {
+	if state.Metadata == nil {
+		state.Metadata = make(map[string]interface{})
+	}
+	timing, ok := state.Metadata[TaskTimingMetadataKey].(map[string]interface{})
+	if !ok {
+		timing = make(map[string]interface{})
+		state.Metadata[TaskTimingMetadataKey] = timing
+	}
+	previous, _ := timing[phase].(float64)
+	timing[phase] = previous + float64(elapsed.Milliseconds())
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runTimeoutWorkflow runs workflow on a single-slot executor and returns the saved execution
+func runTimeoutWorkflow(t *testing.T, executor *WorkflowExecutor, states *memoryStateManager, workflow *models.WorkflowDefinition) *models.WorkflowExecution {
+	t.Helper()
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	if err == nil {
+		t.Fatal("expected the timed out task to fail the workflow")
+	}
+	execution, loadErr := states.LoadExecution(context.Background(), response.ExecutionID)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	return execution
+}
+
+func TestQueueTimeoutIsReportedSeparately(t *testing.T) {
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		// Hold the only slot well past the queue timeout
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}), states, nil, 1)
+	executor.SetDefaultQueueTimeout(50 * time.Millisecond)
+
+	execution := runTimeoutWorkflow(t, executor, states, &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "first", Type: "exec"},
+		{ID: "second", Type: "exec"},
+	}})
+
+	// Either task may take the slot; the other must wait out the queue timeout
+	var queued, ran *models.TaskState
+	for _, state := range execution.TaskStates {
+		if state.ErrorCode == models.TaskErrorQueueTimeout {
+			queued = state
+		} else {
+			ran = state
+		}
+	}
+	if queued == nil || ran == nil {
+		t.Fatalf("expected exactly one task to time out in the queue, got %v", execution.TaskStates)
+	}
+
+	if queued.Status != models.StatusFailed || !strings.Contains(queued.Error, "not dispatched within its queue timeout of 50ms") {
+		t.Errorf("got status %s and error %q for the queued task", queued.Status, queued.Error)
+	}
+	timing := queued.Metadata[TaskTimingMetadataKey].(map[string]interface{})
+	if _, executed := timing[PhaseExec]; executed {
+		t.Error("expected the queued task never to execute")
+	}
+	if waited, _ := timing[PhaseQueueWait].(float64); waited < 50 {
+		t.Errorf("got queue wait %vms, want at least the queue timeout", waited)
+	}
+	if ran.Status != models.StatusCompleted {
+		t.Errorf("got status %s for the task holding the slot, want completed", ran.Status)
+	}
+}
+
+func TestExecTimeoutIsReportedSeparately(t *testing.T) {
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), states, nil, 1)
+	executor.SetDefaultQueueTimeout(50 * time.Millisecond)
+
+	execution := runTimeoutWorkflow(t, executor, states, &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "stuck", Type: "exec", ExecTimeout: 1},
+	}})
+
+	state := execution.TaskStates["stuck"]
+	if state.Status != models.StatusFailed || state.ErrorCode != models.TaskErrorTimeout {
+		t.Errorf("got status %s with code %q, want failed with %s", state.Status, state.ErrorCode, models.TaskErrorTimeout)
+	}
+	if !strings.Contains(state.Error, context.DeadlineExceeded.Error()) {
+		t.Errorf("got error %q, want the exec deadline", state.Error)
+	}
+	timing := state.Metadata[TaskTimingMetadataKey].(map[string]interface{})
+	if executed, _ := timing[PhaseExec].(float64); executed < 1000 {
+		t.Errorf("got exec time %vms, want the full exec timeout of 1s", executed)
+	}
+}
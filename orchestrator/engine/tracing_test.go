@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording every span for the rest of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTaskSpansAreChildrenOfWorkflowSpan(t *testing.T) {
+	recorder := recordSpans(t)
+
+	// Service request spans start from the context a task is dispatched with
+	var mutex sync.Mutex
+	dispatched := make(map[string]trace.SpanContext)
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		mutex.Lock()
+		dispatched[task.ID] = trace.SpanContextFromContext(ctx)
+		mutex.Unlock()
+		if task.ID == "report" {
+			return errors.New("model unavailable")
+		}
+		return nil
+	}), newMemoryStateManager(), nil, 1)
+
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "report", Type: "ai", DependsOn: []string{"fetch"}},
+	}}
+	if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{}); err == nil {
+		t.Fatal("expected the failing task to fail the workflow")
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	var root sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "workflow.execute" {
+			root = span
+			continue
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == "task.id" {
+				spans[attr.Value.AsString()] = span
+			}
+		}
+	}
+	if root == nil || len(spans) != 2 {
+		t.Fatalf("got %d spans, want a workflow span and one per task", len(recorder.Ended()))
+	}
+	if root.Parent().IsValid() {
+		t.Error("expected the workflow span to be the root")
+	}
+	if root.Status().Code != codes.Error {
+		t.Errorf("got workflow span status %+v, want an error", root.Status())
+	}
+
+	for id, span := range spans {
+		if span.Name() != "task.execute" || span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("expected task %s's span to be a child of the workflow span", id)
+		}
+		if dispatched[id].SpanID() != span.SpanContext().SpanID() {
+			t.Errorf("expected task %s to be dispatched within its own span", id)
+		}
+	}
+	if spans["fetch"].Status().Code == codes.Error {
+		t.Error("expected the fetch span to succeed")
+	}
+	if status := spans["report"].Status(); status.Code != codes.Error || status.Description != "model unavailable" {
+		t.Errorf("got report span status %+v, want the task's error", status)
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// redactedVariableValue replaces sensitive values in variable snapshots
+const redactedVariableValue = "REDACTED"
+
+// sensitiveVariablePattern matches variable names whose values must not be exposed
+var sensitiveVariablePattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|credential|private[_-]?key)`)
+
+// RedactVariables returns a deep copy of variables with sensitive values masked
+func RedactVariables(variables map[string]interface{}) map[string]interface{} {
+	if variables == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(variables))
+	for key, value := range variables {
+		if sensitiveVariablePattern.MatchString(key) {
+			result[key] = redactedVariableValue
+			continue
+		}
+		result[key] = redactValue(value)
+	}
+
+	return result
+}
+
+// redactValue masks sensitive keys inside nested maps and slices
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return RedactVariables(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// copyVariables deep copies nested maps and slices so later mutations don't leak into
+// a snapshot
+func copyVariables(variables map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(variables))
+	for key, value := range variables {
+		result[key] = copyValue(value)
+	}
+	return result
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyVariables(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = copyValue(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// diffVariables returns the variables that were added or changed since the initial
+// snapshot; removed variables are reported with a nil value
+func diffVariables(initial, final map[string]interface{}) map[string]interface{} {
+	changes := make(map[string]interface{})
+
+	for key, value := range final {
+		if previous, exists := initial[key]; !exists || !reflect.DeepEqual(previous, value) {
+			changes[key] = value
+		}
+	}
+
+	for key := range initial {
+		if _, exists := final[key]; !exists {
+			changes[key] = nil
+		}
+	}
+
+	return changes
+}
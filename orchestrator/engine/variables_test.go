@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+func TestRedactVariables(t *testing.T) {
+	variables := map[string]interface{}{
+		"region":   "eu",
+		"API_KEY":  "abc",
+		"database": map[string]interface{}{"host": "db", "password": "hunter2"},
+		"steps":    []interface{}{map[string]interface{}{"token": "t"}, "plain"},
+	}
+
+	want := map[string]interface{}{
+		"region":   "eu",
+		"API_KEY":  redactedVariableValue,
+		"database": map[string]interface{}{"host": "db", "password": redactedVariableValue},
+		"steps":    []interface{}{map[string]interface{}{"token": redactedVariableValue}, "plain"},
+	}
+	if got := RedactVariables(variables); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if variables["API_KEY"] != "abc" {
+		t.Error("the original variables must not be modified")
+	}
+}
+
+func TestDiffVariables(t *testing.T) {
+	initial := map[string]interface{}{"kept": 1, "changed": "a", "removed": true}
+	final := map[string]interface{}{"kept": 1, "changed": "b", "added": 2}
+
+	want := map[string]interface{}{"changed": "b", "added": 2, "removed": nil}
+	if got := diffVariables(initial, final); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExecutionSnapshotsVariables(t *testing.T) {
+	states := newMemoryStateManager()
+	executor := NewWorkflowExecutor(taskExecutorFunc(func(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+		// Tasks that export results into the variable scope
+		execution.Variables["row_count"] = 42
+		execution.Variables["session_token"] = "issued"
+		return nil
+	}), states, nil, 1)
+
+	workflow := &models.WorkflowDefinition{
+		ID:        "wf",
+		Variables: map[string]interface{}{"region": "eu", "limit": 10},
+		Tasks:     []models.Task{{ID: "a", Type: "data"}},
+	}
+	request := &models.WorkflowRequest{Variables: map[string]interface{}{"limit": 20, "api_key": "secret"}}
+
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantInitial := map[string]interface{}{"region": "eu", "limit": 20, "api_key": redactedVariableValue}
+	if !reflect.DeepEqual(response.InitialVariables, wantInitial) {
+		t.Errorf("initial variables %v, want %v", response.InitialVariables, wantInitial)
+	}
+	wantChanges := map[string]interface{}{"row_count": 42, "session_token": redactedVariableValue}
+	if !reflect.DeepEqual(response.VariableChanges, wantChanges) {
+		t.Errorf("variable changes %v, want %v", response.VariableChanges, wantChanges)
+	}
+
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(execution.InitialVariables, wantInitial) || !reflect.DeepEqual(execution.VariableChanges, wantChanges) {
+		t.Errorf("stored snapshot %v / %v does not match the response", execution.InitialVariables, execution.VariableChanges)
+	}
+}
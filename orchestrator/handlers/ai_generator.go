@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"orchestrator/engine"
 	"orchestrator/models"
 	"strings"
 
@@ -11,72 +11,68 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Provider and model used for workflow generation unless others are configured
+const (
+	defaultGenerationProvider = "anthropic"
+	defaultGenerationModel    = "claude-3-sonnet"
+)
+
+// defaultRegenerationRetries is how often an invalid generation is retried with feedback
+const defaultRegenerationRetries = 2
+
 // AIWorkflowGenerator generates workflows using AI services
 type AIWorkflowGenerator struct {
-	messageCoordinator MessageCoordinator
-	templateManager    TemplateManager
-	serviceRegistry    ServiceRegistry
-	logger            *logrus.Logger
+	messageCoordinator  MessageCoordinator
+	templateManager     TemplateLookup
+	serviceRegistry     ServiceRegistry
+	prompts             *PromptTemplates
+	providers           []GenerationProvider
+	regenerationRetries int
+	logger              *logrus.Logger
 }
 
-// MessageCoordinator interface for AI communication
+// MessageCoordinator interface for service communication
 type MessageCoordinator interface {
+	SendDataRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error)
 	SendAIRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error)
+	SendExecRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error)
 }
 
-// TemplateManager interface for template management
-type TemplateManager interface {
+// TemplateLookup interface for template management; satisfied by *TemplateManager
+type TemplateLookup interface {
 	GetTemplatesByCategory(category string) ([]*models.Template, error)
 	GetTemplate(id string) (*models.Template, error)
 }
 
-// ServiceRegistry interface for capability management
+// ServiceRegistry reports which services have announced themselves and what they offer.
+// It is satisfied by clients.ServiceRegistry.
 type ServiceRegistry interface {
-	GetAllActiveServices() map[string]*ServiceCapability
-	GetAvailableOperations() map[string][]Operation
 	IsServiceAvailable(component string) bool
 	GenerateCapabilitySummary() string
 }
 
-// ServiceCapability represents a service's announced capabilities
-type ServiceCapability struct {
-	Component    string                 `json:"component"`
-	Timestamp    string                 `json:"timestamp"`
-	Trigger      string                 `json:"trigger"`
-	Capabilities *ServiceCapabilities   `json:"capabilities"`
-}
-
-// ServiceCapabilities represents the complete capability information for a service
-type ServiceCapabilities struct {
-	Operations      []Operation      `json:"operations"`
-	MessagePatterns MessagePatterns  `json:"message_patterns"`
-}
-
-// Operation represents a single operation that a service can perform
-type Operation struct {
-	Name              string      `json:"name"`
-	Description       string      `json:"description"`
-	InputExample      interface{} `json:"input_example"`
-	OutputExample     interface{} `json:"output_example"`
-	RetrySafe         bool        `json:"retry_safe"`
-	EstimatedDuration string      `json:"estimated_duration"`
+// NewAIWorkflowGenerator creates a new AI workflow generator
+func NewAIWorkflowGenerator(messageCoordinator MessageCoordinator, templateManager TemplateLookup, serviceRegistry ServiceRegistry) *AIWorkflowGenerator {
+	return &AIWorkflowGenerator{
+		messageCoordinator:  messageCoordinator,
+		templateManager:     templateManager,
+		serviceRegistry:     serviceRegistry,
+		regenerationRetries: defaultRegenerationRetries,
+		logger:              logrus.New(),
+	}
 }
 
-// MessagePatterns defines how a service communicates via Redis
-type MessagePatterns struct {
-	RequestChannel   string `json:"request_channel"`
-	ResponseChannel  string `json:"response_channel"`
-	CorrelationField string `json:"correlation_field"`
+// SetRegenerationRetries sets how many times an invalid generated workflow is sent back
+// to the AI for correction; 0 disables regeneration
+func (ai *AIWorkflowGenerator) SetRegenerationRetries(retries int) {
+	if retries >= 0 {
+		ai.regenerationRetries = retries
+	}
 }
 
-// NewAIWorkflowGenerator creates a new AI workflow generator
-func NewAIWorkflowGenerator(messageCoordinator MessageCoordinator, templateManager TemplateManager, serviceRegistry ServiceRegistry) *AIWorkflowGenerator {
-	return &AIWorkflowGenerator{
-		messageCoordinator: messageCoordinator,
-		templateManager:   templateManager,
-		serviceRegistry:    serviceRegistry,
-		logger:           logrus.New(),
-	}
+// SetPromptTemplates enables loading system messages and examples from template files
+func (ai *AIWorkflowGenerator) SetPromptTemplates(prompts *PromptTemplates) {
+	ai.prompts = prompts
 }
 
 // GenerateWorkflow creates a workflow using AI based on user prompt
@@ -103,43 +99,94 @@ func (ai *AIWorkflowGenerator) GenerateWorkflow(ctx context.Context, request *mo
 		}
 	}
 
-	// Build AI prompt with context
-	prompt, err := ai.buildGenerationPrompt(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build generation prompt: %w", err)
-	}
-
-	// Send request to AI service
-	aiRequest := &models.ServiceRequest{
-		Service:    "ai",
-		Operation:  "generate",
-		Parameters: map[string]interface{}{
-			"provider":         "anthropic",
-			"prompt":           prompt,
-			"system_message":   ai.getSystemMessage(),
-			"response_format":  "yaml",
-			"model":            "claude-3-sonnet",
-			"max_tokens":       4000,
-			"temperature":      0.3,
-		},
-		Timeout: 120,
-	}
-
-	response, err := ai.messageCoordinator.SendAIRequest(ctx, aiRequest)
-	if err != nil {
-		return nil, fmt.Errorf("AI request failed: %w", err)
-	}
+	chain := ai.providerChain(request)
+
+	// An invalid generation is sent back with what broke, as a follow-up in the same
+	// conversation, up to regenerationRetries times
+	var history []map[string]interface{}
+	correction := ""
+	for attempt := 1; ; attempt++ {
+		// Send request to AI service, falling back through the configured providers
+		var sent string
+		response, provider, err := ai.sendWithFallback(ctx, chain, func(provider GenerationProvider) (*models.ServiceRequest, error) {
+			sent = correction
+			if sent == "" {
+				// Prompts and examples can be tuned per provider
+				prompt, err := ai.buildGenerationPrompt(request, provider.Name)
+				if err != nil {
+					return nil, fmt.Errorf("failed to build generation prompt: %w", err)
+				}
+				sent = prompt
+			}
 
-	if !response.Success {
-		return nil, fmt.Errorf("AI generation failed: %s", response.Error)
-	}
+			aiRequest := &models.ServiceRequest{
+				Service:   "ai",
+				Operation: "generate",
+				Parameters: map[string]interface{}{
+					"provider":        provider.Name,
+					"prompt":          sent,
+					"system_message":  ai.prompts.SystemMessage(provider.Name, ai.getSystemMessage()),
+					"response_format": "yaml",
+					"max_tokens":      4000,
+					"temperature":     0.3,
+				},
+				Timeout: 120,
+			}
+			if len(history) > 0 {
+				aiRequest.Parameters["messages"] = history
+			}
+			applyModel(aiRequest.Parameters, provider)
+			return aiRequest, nil
+		})
+		if err != nil {
+			// No provider could answer; asking again will not help
+			return nil, err
+		}
 
-	// Extract generated content
-	content, ok := response.Data["content"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid AI response format: missing content")
+		// Extract generated content
+		content, ok := response.Data["content"].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid AI response format: missing content")
+		}
+
+		workflow, err := ai.parseGeneratedWorkflow(content)
+		if err == nil {
+			ai.logger.WithFields(logrus.Fields{
+				"workflow_id":   workflow.ID,
+				"workflow_name": workflow.Name,
+				"task_count":    len(workflow.Tasks),
+				"provider":      provider.Name,
+				"model":         provider.Model,
+				"attempts":      attempt,
+			}).Info("Successfully generated workflow")
+
+			return workflow, nil
+		}
+
+		if attempt > ai.regenerationRetries {
+			if attempt > 1 {
+				return nil, fmt.Errorf("generated workflow still invalid after %d attempts: %w", attempt, err)
+			}
+			return nil, err
+		}
+
+		ai.logger.WithFields(logrus.Fields{
+			"provider": provider.Name,
+			"attempt":  attempt,
+			"error":    err.Error(),
+		}).Warn("Generated workflow is invalid, asking the provider to correct it")
+
+		history = append(history,
+			map[string]interface{}{"role": "user", "content": sent},
+			map[string]interface{}{"role": "assistant", "content": content},
+		)
+		correction = correctionPrompt(err)
+		chain = preferProvider(chain, provider)
 	}
+}
 
+// parseGeneratedWorkflow parses and validates a generated workflow
+func (ai *AIWorkflowGenerator) parseGeneratedWorkflow(content string) (*models.WorkflowDefinition, error) {
 	// Parse YAML workflow
 	var workflow models.WorkflowDefinition
 	if err := yaml.Unmarshal([]byte(content), &workflow); err != nil {
@@ -151,17 +198,11 @@ func (ai *AIWorkflowGenerator) GenerateWorkflow(ctx context.Context, request *mo
 		return nil, fmt.Errorf("workflow validation failed: %w", err)
 	}
 
-	ai.logger.WithFields(logrus.Fields{
-		"workflow_id":   workflow.ID,
-		"workflow_name": workflow.Name,
-		"task_count":    len(workflow.Tasks),
-	}).Info("Successfully generated workflow")
-
 	return &workflow, nil
 }
 
 // buildGenerationPrompt creates a comprehensive prompt for AI workflow generation
-func (ai *AIWorkflowGenerator) buildGenerationPrompt(request *models.AIGenerationRequest) (string, error) {
+func (ai *AIWorkflowGenerator) buildGenerationPrompt(request *models.AIGenerationRequest, provider string) (string, error) {
 	var promptBuilder strings.Builder
 
 	// Add main request
@@ -206,14 +247,16 @@ func (ai *AIWorkflowGenerator) buildGenerationPrompt(request *models.AIGeneratio
 	}
 
 	// Add examples based on complexity
+	var examples string
 	switch complexity {
 	case "simple":
-		promptBuilder.WriteString(ai.getSimpleWorkflowExamples())
+		examples = ai.getSimpleWorkflowExamples()
 	case "medium":
-		promptBuilder.WriteString(ai.getMediumWorkflowExamples())
+		examples = ai.getMediumWorkflowExamples()
 	case "complex":
-		promptBuilder.WriteString(ai.getComplexWorkflowExamples())
+		examples = ai.getComplexWorkflowExamples()
 	}
+	promptBuilder.WriteString(ai.prompts.Examples(provider, complexity, examples))
 
 	// Add template context if available
 	if request.Domain != "" {
@@ -299,10 +342,18 @@ Required fields:
 Task definition requirements:
 - id: unique task identifier
 - name: descriptive task name
-- type: one of [data, ai, exec, parallel, condition]
+- type: one of [data, ai, exec, parallel, condition, workflow]
 - parameters: service-specific parameters
 - depends_on: array of task IDs (if any dependencies)
 
+Required parameters by task type (workflows missing them are rejected):
+- data: operation
+- ai: prompt or messages
+- exec: image
+- parallel: tasks
+- condition: condition (a task field, not a parameter)
+- workflow: template (ID of an existing template to run as a sub-workflow)
+
 Optional but recommended:
 - variables: workflow-level variables
 - retry_policy: for critical tasks
@@ -312,6 +363,15 @@ Optional but recommended:
 Output only valid YAML that can be parsed directly.`
 }
 
+// correctionPrompt asks the AI to fix the workflow it just generated
+func correctionPrompt(problem error) string {
+	return fmt.Sprintf(`The workflow you generated is invalid: %v
+
+Fix these problems and return the complete corrected workflow. Keep everything that was valid.
+
+Output only valid YAML that can be parsed directly.`, problem)
+}
+
 // validateAndEnhanceWorkflow validates and improves the generated workflow
 func (ai *AIWorkflowGenerator) validateAndEnhanceWorkflow(workflow *models.WorkflowDefinition) error {
 	// Ensure required fields are present
@@ -327,11 +387,18 @@ func (ai *AIWorkflowGenerator) validateAndEnhanceWorkflow(workflow *models.Workf
 		return fmt.Errorf("workflow must contain at least one task")
 	}
 
-	// Validate task definitions
+	// Validate task definitions. Duplicate IDs are rejected rather than renamed, since a
+	// dependency on a duplicated ID cannot be attributed to either task.
 	taskIDs := make(map[string]bool)
+	var duplicateIDs []string
 	for i, task := range workflow.Tasks {
 		if task.ID == "" {
 			workflow.Tasks[i].ID = fmt.Sprintf("task_%d", i+1)
+			task.ID = workflow.Tasks[i].ID
+		}
+
+		if taskIDs[task.ID] {
+			duplicateIDs = append(duplicateIDs, task.ID)
 		}
 
 		if task.Name == "" {
@@ -343,7 +410,7 @@ func (ai *AIWorkflowGenerator) validateAndEnhanceWorkflow(workflow *models.Workf
 		}
 
 		// Validate task type
-		validTypes := []string{"data", "ai", "exec", "parallel", "condition"}
+		validTypes := []string{"data", "ai", "exec", "parallel", "condition", "workflow"}
 		isValidType := false
 		for _, validType := range validTypes {
 			if task.Type == validType {
@@ -358,14 +425,26 @@ func (ai *AIWorkflowGenerator) validateAndEnhanceWorkflow(workflow *models.Workf
 		taskIDs[task.ID] = true
 	}
 
-	// Validate dependencies
+	if len(duplicateIDs) > 0 {
+		return fmt.Errorf("workflow contains duplicate task IDs: %s", strings.Join(duplicateIDs, ", "))
+	}
+
+	// Check each task's parameters as execution would, so generations that omit them are
+	// rejected now rather than failing mid-run
+	var parameterProblems []TaskParameterProblem
 	for _, task := range workflow.Tasks {
-		for _, dep := range task.DependsOn {
-			if !taskIDs[dep] {
-				return fmt.Errorf("task %s depends on non-existent task %s", task.ID, dep)
-			}
+		if err := validateTaskParameters(&task); err != nil {
+			parameterProblems = append(parameterProblems, TaskParameterProblem{TaskID: task.ID, Message: err.Error()})
 		}
 	}
+	if len(parameterProblems) > 0 {
+		return &TaskParameterError{Problems: parameterProblems}
+	}
+
+	// Validate dependencies, reporting every missing dependency and cycle at once
+	if _, err := engine.NewDAG(workflow.Tasks); err != nil {
+		return err
+	}
 
 	// Add default retry policies for AI and external service tasks
 	for i, task := range workflow.Tasks {
@@ -388,6 +467,25 @@ func (ai *AIWorkflowGenerator) validateAndEnhanceWorkflow(workflow *models.Workf
 	return nil
 }
 
+// TaskParameterProblem is a task missing or misusing a parameter its type requires
+type TaskParameterProblem struct {
+	TaskID  string `json:"task_id"`
+	Message string `json:"message"`
+}
+
+// TaskParameterError reports every task with invalid parameters at once
+type TaskParameterError struct {
+	Problems []TaskParameterProblem `json:"problems"`
+}
+
+func (e *TaskParameterError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = fmt.Sprintf("task %s: %s", problem.TaskID, problem.Message)
+	}
+	return fmt.Sprintf("%d task(s) have invalid parameters: %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
 // SuggestWorkflowImprovements analyzes a workflow and suggests improvements
 func (ai *AIWorkflowGenerator) SuggestWorkflowImprovements(ctx context.Context, workflow *models.WorkflowDefinition) ([]string, error) {
 	suggestions := make([]string, 0)
@@ -448,6 +546,16 @@ func (ai *AIWorkflowGenerator) GenerateWorkflowFromTemplate(ctx context.Context,
 		return nil, fmt.Errorf("failed to load template: %w", err)
 	}
 
+	// Check and coerce variables against the template's declarations, filling defaults
+	variables, err = ValidateTemplateVariables(template, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckPreconditions(template, variables); err != nil {
+		return nil, err
+	}
+
 	// Clone workflow from template
 	workflow := template.Workflow
 	workflow.ID = fmt.Sprintf("generated-from-%s-%d", templateID, len(customizations))
@@ -487,29 +595,27 @@ Current workflow:
 Generate the modified workflow in YAML format, maintaining the same structure and ensuring all task dependencies remain valid.`, customizations, string(currentYAML))
 
 	// Send to AI service
-	aiRequest := &models.ServiceRequest{
-		Service: "ai",
-		Operation: "generate",
-		Parameters: map[string]interface{}{
-			"provider":        "anthropic",
-			"prompt":          prompt,
-			"system_message":  "You are a workflow optimization expert. Modify the given workflow according to requirements while maintaining validity.",
-			"response_format": "yaml",
-			"model":           "claude-3-sonnet",
-			"max_tokens":      3000,
-			"temperature":     0.2,
-		},
-		Timeout: 60,
-	}
-
-	response, err := ai.messageCoordinator.SendAIRequest(ctx, aiRequest)
+	response, provider, err := ai.sendWithFallback(ctx, ai.providerChain(nil), func(provider GenerationProvider) (*models.ServiceRequest, error) {
+		aiRequest := &models.ServiceRequest{
+			Service:   "ai",
+			Operation: "generate",
+			Parameters: map[string]interface{}{
+				"provider":        provider.Name,
+				"prompt":          prompt,
+				"system_message":  "You are a workflow optimization expert. Modify the given workflow according to requirements while maintaining validity.",
+				"response_format": "yaml",
+				"max_tokens":      3000,
+				"temperature":     0.2,
+			},
+			Timeout: 60,
+		}
+		applyModel(aiRequest.Parameters, provider)
+		return aiRequest, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("AI customization request failed: %w", err)
-	}
-
-	if !response.Success {
-		return nil, fmt.Errorf("AI customization failed: %s", response.Error)
+		return nil, fmt.Errorf("AI customization failed: %w", err)
 	}
+	ai.logger.WithField("provider", provider.Name).Debug("Workflow customized with AI")
 
 	// Parse modified workflow
 	content, ok := response.Data["content"].(string)
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+const duplicateTaskWorkflow = `
+id: summary
+name: Summarize sales
+tasks:
+  - id: fetch
+    type: data
+    parameters:
+      operation: query
+  - id: fetch
+    type: ai
+    parameters:
+      prompt: summarize
+  - id: report
+    type: ai
+    depends_on: [fetch]
+    parameters:
+      prompt: report
+`
+
+const correctedTaskWorkflow = `
+id: summary
+name: Summarize sales
+tasks:
+  - id: fetch
+    type: data
+    parameters:
+      operation: query
+  - id: summarize
+    type: ai
+    depends_on: [fetch]
+    parameters:
+      prompt: summarize
+`
+
+// generatedContent answers AI generation requests with each content in turn
+func generatedContent(contents ...string) *fakeCoordinator {
+	calls := 0
+	return &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		content := contents[len(contents)-1]
+		if calls < len(contents) {
+			content = contents[calls]
+		}
+		calls++
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"content": content}}, nil
+	}}
+}
+
+func TestGenerateWorkflowRejectsDuplicateTaskIDs(t *testing.T) {
+	generator := NewAIWorkflowGenerator(generatedContent(duplicateTaskWorkflow), nil, nil)
+	generator.SetRegenerationRetries(0)
+
+	_, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if err == nil || !strings.Contains(err.Error(), "duplicate task IDs: fetch") {
+		t.Fatalf("expected the duplicate ID to be reported, got %v", err)
+	}
+}
+
+func TestGenerateWorkflowCorrectsDuplicateTaskIDs(t *testing.T) {
+	coordinator := generatedContent(duplicateTaskWorkflow, correctedTaskWorkflow)
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+
+	workflow, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workflow.Tasks) != 2 || workflow.Tasks[1].ID != "summarize" {
+		t.Errorf("expected the corrected workflow, got %+v", workflow.Tasks)
+	}
+
+	if len(coordinator.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(coordinator.requests))
+	}
+	if prompt, _ := coordinator.requests[1].Parameters["prompt"].(string); !strings.Contains(prompt, "duplicate task IDs: fetch") {
+		t.Errorf("expected the correction to name the duplicate, got %q", prompt)
+	}
+}
+
+func TestValidateAndEnhanceWorkflowNumbersMissingIDs(t *testing.T) {
+	generator := NewAIWorkflowGenerator(&fakeCoordinator{}, nil, nil)
+	workflow := &models.WorkflowDefinition{Name: "numbered", Tasks: []models.Task{
+		{Type: "ai", Parameters: map[string]interface{}{"prompt": "a"}},
+		{ID: "task_1", Type: "ai", Parameters: map[string]interface{}{"prompt": "b"}},
+	}}
+
+	// A generated ID that collides with an explicit one is still caught
+	if err := generator.validateAndEnhanceWorkflow(workflow); err == nil || !strings.Contains(err.Error(), "duplicate task IDs: task_1") {
+		t.Fatalf("expected a collision with the generated ID, got %v", err)
+	}
+}
+
+const unparseableWorkflow = "id: summary\ntasks:\n  - id: fetch\n    type: [data\n"
+
+func TestGenerateWorkflowRegeneratesInvalidYAML(t *testing.T) {
+	calls := 0
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		if request.Parameters["provider"] == "anthropic" {
+			return &models.ServiceResponse{Error: "overloaded"}, nil
+		}
+		calls++
+		content := unparseableWorkflow
+		if calls > 1 {
+			content = correctedTaskWorkflow
+		}
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"content": content}}, nil
+	}}
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders([]GenerationProvider{{Name: "anthropic"}, {Name: "openai"}})
+
+	workflow, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workflow.ID != "summary" || len(workflow.Tasks) != 2 {
+		t.Errorf("expected the corrected workflow, got %+v", workflow)
+	}
+
+	// The correction goes straight to the provider that produced the invalid YAML
+	if len(coordinator.requests) != 3 {
+		t.Fatalf("sent %d requests, want the failed provider, the invalid answer and the correction", len(coordinator.requests))
+	}
+	correction := coordinator.requests[2].Parameters
+	if correction["provider"] != "openai" {
+		t.Errorf("sent the correction to %v, want openai", correction["provider"])
+	}
+	if prompt, _ := correction["prompt"].(string); !strings.Contains(prompt, "failed to parse generated workflow YAML") {
+		t.Errorf("expected the correction to include the parse error, got %q", prompt)
+	}
+	history, _ := correction["messages"].([]map[string]interface{})
+	if len(history) != 2 || history[0]["content"] != coordinator.requests[1].Parameters["prompt"] || history[1]["content"] != unparseableWorkflow {
+		t.Errorf("got history %v, want the original prompt and the invalid answer", history)
+	}
+}
+
+func TestGenerateWorkflowGivesUpAfterRegenerationRetries(t *testing.T) {
+	coordinator := generatedContent(unparseableWorkflow)
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetRegenerationRetries(2)
+
+	_, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if err == nil || !strings.HasPrefix(err.Error(), "generated workflow still invalid after 3 attempts: failed to parse generated workflow YAML") {
+		t.Errorf("got %v, want the last parse error after 3 attempts", err)
+	}
+	if len(coordinator.requests) != 3 {
+		t.Errorf("sent %d requests, want 3", len(coordinator.requests))
+	}
+
+	// Each correction carries the whole conversation so far
+	history, _ := coordinator.requests[2].Parameters["messages"].([]map[string]interface{})
+	if len(history) != 4 {
+		t.Errorf("got %d history messages on the last attempt, want 4", len(history))
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"orchestrator/engine"
+)
+
+// WriteTaskParameterProblems responds with 422 and the tasks missing required parameters
+// when err comes from validating a generated workflow, and reports whether it did
+func WriteTaskParameterProblems(w http.ResponseWriter, message string, err error) bool {
+	var parameterErr *TaskParameterError
+	if !errors.As(err, &parameterErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":    fmt.Sprintf("%s: %v", message, err),
+		"problems": parameterErr.Problems,
+	})
+	return true
+}
+
+// WriteGraphProblems responds with 422 and the structured list of problems when err comes
+// from DAG validation, and reports whether it did
+func WriteGraphProblems(w http.ResponseWriter, message string, err error) bool {
+	var validationErr *engine.DAGValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":    fmt.Sprintf("%s: %v", message, err),
+		"problems": validationErr.Problems,
+	})
+	return true
+}
+
+// WriteVariableViolations responds with 422 and the structured list of violations when
+// err comes from template variable validation, and reports whether it did
+func WriteVariableViolations(w http.ResponseWriter, message string, err error) bool {
+	var variableErr *VariableValidationError
+	if !errors.As(err, &variableErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":      fmt.Sprintf("%s: %v", message, err),
+		"violations": variableErr.Violations,
+	})
+	return true
+}
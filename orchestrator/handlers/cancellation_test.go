@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"testing"
+	"time"
+)
+
+func TestCancelWorkflowMidBatchStopsDispatch(t *testing.T) {
+	dispatched := make(chan *models.ServiceRequest, 10)
+	coordinator := &fakeCoordinator{hold: func(request *models.ServiceRequest) bool {
+		dispatched <- request
+		return true
+	}}
+	states := newMemoryStateManager()
+	// One task at a time, so the rest of the first batch is still waiting when it's cancelled
+	executor := engine.NewWorkflowExecutor(NewTaskExecutor(coordinator), states, nil, 1)
+
+	workflow := &models.WorkflowDefinition{ID: "cancelled", Tasks: []models.Task{
+		{ID: "first", Type: "data", Parameters: map[string]interface{}{"operation": "first"}},
+		{ID: "second", Type: "data", Parameters: map[string]interface{}{"operation": "second"}},
+		{ID: "third", Type: "data", Parameters: map[string]interface{}{"operation": "third"}},
+		{ID: "report", Type: "data", Parameters: map[string]interface{}{"operation": "report"}, DependsOn: []string{"first", "second", "third"}},
+	}}
+
+	type result struct {
+		response *models.WorkflowResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{ExecutionID: "exec-cancel"})
+		done <- result{response, err}
+	}()
+
+	var running *models.ServiceRequest
+	select {
+	case running = <-dispatched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no task was dispatched")
+	}
+
+	if err := executor.CancelWorkflow("exec-cancel", "operator request"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	var finished result
+	select {
+	case finished = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the execution did not stop after being cancelled")
+	}
+	if finished.err == nil || finished.response.Status != models.StatusCancelled {
+		t.Fatalf("got %+v, %v, want a cancelled execution", finished.response, finished.err)
+	}
+
+	coordinator.mutex.Lock()
+	sent := len(coordinator.requests)
+	coordinator.mutex.Unlock()
+	if sent != 1 {
+		t.Errorf("%d requests reached the coordinator, want only %s's", sent, running.Operation)
+	}
+
+	execution, err := states.LoadExecution(context.Background(), "exec-cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.Status != models.StatusCancelled {
+		t.Errorf("saved status %s, want cancelled", execution.Status)
+	}
+	if reason := execution.Metadata[engine.CancelReasonMetadataKey]; reason != "operator request" {
+		t.Errorf("recorded cancel reason %v", reason)
+	}
+	if state := execution.TaskStates[running.Operation]; state.Status != models.StatusCancelled {
+		t.Errorf("running task %s ended %s, want cancelled", running.Operation, state.Status)
+	}
+	for id, state := range execution.TaskStates {
+		if id != running.Operation && (state.StartTime != nil || state.Status == models.StatusCompleted) {
+			t.Errorf("task %s was dispatched after the cancellation: %+v", id, state)
+		}
+	}
+	if executor.IsRunning("exec-cancel") {
+		t.Error("the cancelled execution is still tracked as running")
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ExecutionCanceller stops running executions. It is satisfied by engine.WorkflowExecutor.
+type ExecutionCanceller interface {
+	CancelWorkflow(executionID, reason string) error
+}
+
+// ExecutionControlAPI lets operators cancel executions and complete their tasks by hand
+type ExecutionControlAPI struct {
+	executions ExecutionLoader
+	canceller  ExecutionCanceller
+	recovery   *RecoveryManager
+	templates  *TemplateManager
+	logger     *logrus.Logger
+}
+
+// NewExecutionControlAPI creates the cancel and complete-task endpoints. Completed tasks
+// are resumed past through recovery, with definitions looked up in templates.
+func NewExecutionControlAPI(executions ExecutionLoader, canceller ExecutionCanceller, recovery *RecoveryManager, templates *TemplateManager, logger *logrus.Logger) *ExecutionControlAPI {
+	return &ExecutionControlAPI{
+		executions: executions,
+		canceller:  canceller,
+		recovery:   recovery,
+		templates:  templates,
+		logger:     logger,
+	}
+}
+
+// RegisterRoutes adds DELETE /workflows/{id} and POST /workflows/{id}/tasks/{taskId}/complete
+// to the API router
+func (a *ExecutionControlAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/{id}", a.handleCancelWorkflow).Methods("DELETE")
+	api.HandleFunc("/workflows/{id}/tasks/{taskId}/complete", a.handleCompleteWorkflowTask).Methods("POST")
+}
+
+// handleCompleteWorkflowTask lets an operator supply a task's output by hand, optionally
+// resuming the execution past it
+func (a *ExecutionControlAPI) handleCompleteWorkflowTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	executionID := vars["id"]
+	taskID := vars["taskId"]
+
+	var body struct {
+		Output map[string]interface{} `json:"output"`
+		Resume bool                   `json:"resume"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	execution, err := a.executions.LoadExecution(r.Context(), executionID)
+	if err != nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	if _, exists := execution.TaskStates[taskID]; !exists {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	// Resuming needs the definition, which older executions only have through their template
+	var workflow *models.WorkflowDefinition
+	if body.Resume && execution.Workflow != nil {
+		workflow = execution.Workflow
+	} else if body.Resume {
+		template, err := a.templates.ExecutionTemplate(execution)
+		if err != nil {
+			http.Error(w, "Workflow definition not available", http.StatusNotFound)
+			return
+		}
+		workflow = &template.Workflow
+	}
+
+	// Reject a task that isn't waiting before claiming, so the claim isn't left held
+	if err := a.recovery.CheckTaskCompletable(execution, taskID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Claim the execution first so concurrent completions, or the recovery loop, cannot
+	// resume it a second time
+	if workflow != nil {
+		claimed, err := a.recovery.ClaimExecution(r.Context(), execution)
+		if err != nil {
+			http.Error(w, "Failed to claim execution", http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			http.Error(w, "Execution is running or already being resumed", http.StatusConflict)
+			return
+		}
+	}
+
+	state, err := a.recovery.CompleteTask(r.Context(), execution, taskID, body.Output)
+	if errors.Is(err, ErrTaskNotWaiting) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		a.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to complete task")
+		http.Error(w, "Failed to complete task", http.StatusInternalServerError)
+		return
+	}
+
+	if workflow != nil {
+		a.recovery.ResumeExecution(workflow, execution)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task":    state,
+		"resumed": workflow != nil,
+	})
+}
+
+// handleCancelWorkflow cancels a running execution. The request body may carry a reason.
+func (a *ExecutionControlAPI) handleCancelWorkflow(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	executionID := vars["id"]
+
+	body := struct {
+		Reason string `json:"reason"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if body.Reason == "" {
+		body.Reason = "User requested cancellation"
+	}
+
+	if err := a.canceller.CancelWorkflow(executionID, body.Reason); err != nil {
+		if errors.Is(err, engine.ErrExecutionNotRunning) {
+			http.Error(w, "Running workflow not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := models.StatusCancelled
+	if execution, err := a.executions.LoadExecution(r.Context(), executionID); err == nil {
+		status = execution.Status
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"execution_id": executionID,
+		"cancelled":    true,
+		"status":       status,
+		"reason":       body.Reason,
+		"cancelled_at": time.Now(),
+	})
+}
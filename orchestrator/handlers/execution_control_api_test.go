@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeCanceller cancels the executions in running and refuses the rest
+type fakeCanceller struct {
+	running   map[string]bool
+	cancelled map[string]string
+}
+
+func (f *fakeCanceller) CancelWorkflow(executionID, reason string) error {
+	if !f.running[executionID] {
+		return fmt.Errorf("%w: %s", engine.ErrExecutionNotRunning, executionID)
+	}
+	f.cancelled[executionID] = reason
+	return nil
+}
+
+// failingCheckpoints fails every checkpoint write, as when Redis is unreachable
+type failingCheckpoints struct {
+	*memoryStateManager
+}
+
+func (failingCheckpoints) SaveTaskCheckpoint(ctx context.Context, executionID, taskID string, state *models.TaskState) error {
+	return errors.New("dial tcp: connection refused")
+}
+
+func newExecutionControlAPI(states StateManager, canceller ExecutionCanceller) *ExecutionControlAPI {
+	recovery := NewRecoveryManager(states, newFakeWorkflowExecutor(), nil, time.Minute)
+	return NewExecutionControlAPI(states, canceller, recovery, nil, logrus.New())
+}
+
+func completeTask(api *ExecutionControlAPI, executionID, taskID, body string) *httptest.ResponseRecorder {
+	path := "/api/v1/workflows/" + executionID + "/tasks/" + taskID + "/complete"
+	return serveAPI(api, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+}
+
+func TestCompleteWorkflowTaskEndpoint(t *testing.T) {
+	execution := newTestExecution("fetch", "summarize")
+	execution.Status = models.StatusFailed
+	execution.TaskStates["fetch"].Status = models.StatusFailed
+	states := newMemoryStateManager(execution)
+	api := newExecutionControlAPI(states, nil)
+
+	recorder := completeTask(api, "exec-test", "fetch", `{"output": {"rows": 3}}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		Task    models.TaskState `json:"task"`
+		Resumed bool             `json:"resumed"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Task.Status != models.StatusCompleted || response.Task.Output["rows"] != float64(3) || response.Resumed {
+		t.Errorf("got %+v, want the task completed without resuming", response)
+	}
+	if saved, _ := states.LoadExecution(context.Background(), "exec-test"); saved.TaskStates["fetch"].Status != models.StatusCompleted {
+		t.Errorf("got saved status %s, want completed", saved.TaskStates["fetch"].Status)
+	}
+}
+
+func TestCompleteWorkflowTaskEndpointErrors(t *testing.T) {
+	execution := newTestExecution("fetch", "summarize")
+	execution.Status = models.StatusFailed
+	execution.TaskStates["fetch"].Status = models.StatusCompleted
+	api := newExecutionControlAPI(newMemoryStateManager(execution), nil)
+
+	cases := []struct {
+		executionID, taskID, body string
+		wantStatus                int
+	}{
+		{"exec-test", "summarize", `not json`, http.StatusBadRequest},
+		{"unknown", "summarize", `{}`, http.StatusNotFound},
+		{"exec-test", "missing", `{}`, http.StatusNotFound},
+		{"exec-test", "fetch", `{}`, http.StatusConflict},
+	}
+	for _, c := range cases {
+		if recorder := completeTask(api, c.executionID, c.taskID, c.body); recorder.Code != c.wantStatus {
+			t.Errorf("%s/%s: got %d %q, want %d", c.executionID, c.taskID, recorder.Code, recorder.Body, c.wantStatus)
+		}
+	}
+
+	failing := newExecutionControlAPI(failingCheckpoints{newMemoryStateManager(execution)}, nil)
+	recorder := completeTask(failing, "exec-test", "summarize", `{}`)
+	if recorder.Code != http.StatusInternalServerError || strings.Contains(recorder.Body.String(), "connection refused") {
+		t.Errorf("got %d %q, want a 500 that does not leak the store error", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCancelWorkflowEndpoint(t *testing.T) {
+	canceller := &fakeCanceller{running: map[string]bool{"exec-test": true}, cancelled: make(map[string]string)}
+	api := newExecutionControlAPI(newMemoryStateManager(), canceller)
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodDelete, "/api/v1/workflows/exec-test", strings.NewReader(`{"reason": "wrong input"}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	if canceller.cancelled["exec-test"] != "wrong input" {
+		t.Errorf("got cancellations %v, want exec-test with the given reason", canceller.cancelled)
+	}
+
+	recorder = serveAPI(api, httptest.NewRequest(http.MethodDelete, "/api/v1/workflows/exec-other", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 for an execution that is not running", recorder.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"orchestrator/clients"
+	"orchestrator/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ExecutionEventSource reads the event logs of executions. It is satisfied by
+// clients.RedisEventLog.
+type ExecutionEventSource interface {
+	Events(ctx context.Context, executionID string) ([]models.ExecutionEvent, error)
+}
+
+// ArtifactManifests reads the artifact manifests of executions. It is satisfied by
+// clients.FileArtifactStore.
+type ArtifactManifests interface {
+	GetManifest(executionID string) (*clients.ArtifactManifest, error)
+}
+
+// ExecutionHistoryAPI serves what an execution left behind: its event log and the
+// artifacts its tasks persisted
+type ExecutionHistoryAPI struct {
+	executions ExecutionLoader
+	events     ExecutionEventSource
+	artifacts  ArtifactManifests
+}
+
+// NewExecutionHistoryAPI creates the event log and artifact endpoints
+func NewExecutionHistoryAPI(executions ExecutionLoader, events ExecutionEventSource, artifacts ArtifactManifests) *ExecutionHistoryAPI {
+	return &ExecutionHistoryAPI{
+		executions: executions,
+		events:     events,
+		artifacts:  artifacts,
+	}
+}
+
+// RegisterRoutes adds GET /workflows/{id}/events and GET /workflows/{id}/artifacts to the
+// API router
+func (a *ExecutionHistoryAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/{id}/events", a.handleGetWorkflowEvents).Methods("GET")
+	api.HandleFunc("/workflows/{id}/artifacts", a.handleGetWorkflowArtifacts).Methods("GET")
+}
+
+func (a *ExecutionHistoryAPI) handleGetWorkflowArtifacts(w http.ResponseWriter, r *http.Request) {
+	executionID := mux.Vars(r)["id"]
+
+	manifest, err := a.artifacts.GetManifest(executionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// handleGetWorkflowEvents returns the event log of an execution, oldest first
+func (a *ExecutionHistoryAPI) handleGetWorkflowEvents(w http.ResponseWriter, r *http.Request) {
+	executionID := mux.Vars(r)["id"]
+
+	events, err := a.events.Events(r.Context(), executionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(events) == 0 {
+		if _, err := a.executions.LoadExecution(r.Context(), executionID); err != nil {
+			http.Error(w, "Workflow not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"execution_id": executionID,
+		"events":       events,
+	})
+}
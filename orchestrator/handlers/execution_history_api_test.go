@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/clients"
+	"orchestrator/models"
+	"testing"
+)
+
+// memoryEventSource serves fixed event logs
+type memoryEventSource map[string][]models.ExecutionEvent
+
+func (m memoryEventSource) Events(ctx context.Context, executionID string) ([]models.ExecutionEvent, error) {
+	return m[executionID], nil
+}
+
+// memoryManifests serves fixed artifact manifests
+type memoryManifests map[string]*clients.ArtifactManifest
+
+func (m memoryManifests) GetManifest(executionID string) (*clients.ArtifactManifest, error) {
+	manifest, exists := m[executionID]
+	if !exists {
+		return nil, errors.New("manifest not found")
+	}
+	return manifest, nil
+}
+
+func TestGetWorkflowEvents(t *testing.T) {
+	quiet := newTestExecution("fetch")
+	quiet.ID = "exec-quiet"
+	events := memoryEventSource{"exec-test": {{ExecutionID: "exec-test", Type: "task_started", TaskID: "fetch"}}}
+	api := NewExecutionHistoryAPI(newMemoryStateManager(newTestExecution("fetch"), quiet), events, memoryManifests{})
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-test/events", nil))
+	var response struct {
+		Events []models.ExecutionEvent `json:"events"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil || len(response.Events) != 1 || response.Events[0].TaskID != "fetch" {
+		t.Errorf("got %d %+v, %v, want the logged event", recorder.Code, response, err)
+	}
+
+	// An execution without events is still known; an unknown one is not found
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-quiet/events", nil)); recorder.Code != http.StatusOK {
+		t.Errorf("got %d, want an empty log for a known execution", recorder.Code)
+	}
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/unknown/events", nil)); recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 for an unknown execution", recorder.Code)
+	}
+}
+
+func TestGetWorkflowArtifacts(t *testing.T) {
+	manifests := memoryManifests{"exec-test": {ExecutionID: "exec-test", Tasks: []clients.ArtifactEntry{{TaskID: "fetch"}}}}
+	api := NewExecutionHistoryAPI(newMemoryStateManager(), memoryEventSource{}, manifests)
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-test/artifacts", nil))
+	var manifest clients.ArtifactManifest
+	if err := json.NewDecoder(recorder.Body).Decode(&manifest); err != nil || len(manifest.Tasks) != 1 || manifest.Tasks[0].TaskID != "fetch" {
+		t.Errorf("got %d %+v, %v, want the manifest", recorder.Code, manifest, err)
+	}
+
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/unknown/artifacts", nil)); recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 without a manifest", recorder.Code)
+	}
+}
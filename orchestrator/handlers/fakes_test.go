@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/clients"
+	"orchestrator/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// fakeCoordinator records service requests and answers them with respond. Requests hold
+// returns true for are never answered; like the real coordinator, the call then ends
+// with the caller's context.
+type fakeCoordinator struct {
+	mutex    sync.Mutex
+	requests []*models.ServiceRequest
+	respond  func(request *models.ServiceRequest) (*models.ServiceResponse, error)
+	hold     func(request *models.ServiceRequest) bool
+}
+
+func (f *fakeCoordinator) send(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	f.mutex.Lock()
+	f.requests = append(f.requests, request)
+	f.mutex.Unlock()
+	if f.hold != nil && f.hold(request) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.respond == nil {
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{}}, nil
+	}
+	return f.respond(request)
+}
+
+func (f *fakeCoordinator) SendDataRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f.send(ctx, request)
+}
+
+func (f *fakeCoordinator) SendAIRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f.send(ctx, request)
+}
+
+func (f *fakeCoordinator) SendExecRequest(ctx context.Context, request *models.ServiceRequest) (*models.ServiceResponse, error) {
+	return f.send(ctx, request)
+}
+
+// newTestExecution returns an execution with a pending state for each task ID
+func newTestExecution(taskIDs ...string) *models.WorkflowExecution {
+	execution := &models.WorkflowExecution{
+		ID:         "exec-test",
+		Variables:  make(map[string]interface{}),
+		TaskStates: make(map[string]*models.TaskState),
+		Metadata:   make(map[string]interface{}),
+	}
+	for _, id := range taskIDs {
+		execution.TaskStates[id] = &models.TaskState{
+			ID:       id,
+			Status:   models.StatusPending,
+			Output:   make(map[string]interface{}),
+			Metadata: make(map[string]interface{}),
+		}
+	}
+	return execution
+}
+
+// memoryStateManager is an in-memory StateManager for recovery tests
+type memoryStateManager struct {
+	mutex       sync.Mutex
+	executions  map[string]*models.WorkflowExecution
+	checkpoints map[string]*models.TaskState
+	claims      map[string]bool
+}
+
+func newMemoryStateManager(executions ...*models.WorkflowExecution) *memoryStateManager {
+	m := &memoryStateManager{
+		executions:  make(map[string]*models.WorkflowExecution),
+		checkpoints: make(map[string]*models.TaskState),
+		claims:      make(map[string]bool),
+	}
+	for _, execution := range executions {
+		m.executions[execution.ID] = execution
+	}
+	return m
+}
+
+func (m *memoryStateManager) SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.executions[execution.ID] = execution
+	return nil
+}
+
+func (m *memoryStateManager) LoadExecution(ctx context.Context, executionID string) (*models.WorkflowExecution, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	execution, exists := m.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", clients.ErrExecutionNotFound, executionID)
+	}
+	return execution, nil
+}
+
+func (m *memoryStateManager) DeleteExecution(ctx context.Context, executionID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.executions, executionID)
+	return nil
+}
+
+func (m *memoryStateManager) ListActiveExecutions(ctx context.Context) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var ids []string
+	for id, execution := range m.executions {
+		if execution.Status == models.StatusRunning {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryStateManager) SaveTaskCheckpoint(ctx context.Context, executionID, taskID string, state *models.TaskState) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.checkpoints[executionID+":"+taskID] = state
+	return nil
+}
+
+func (m *memoryStateManager) LoadTaskCheckpoint(ctx context.Context, executionID, taskID string) (*models.TaskState, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.checkpoints[executionID+":"+taskID], nil
+}
+
+func (m *memoryStateManager) LoadTaskState(ctx context.Context, executionID, taskID string) (*models.TaskState, error) {
+	execution, err := m.LoadExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return execution.TaskStates[taskID], nil
+}
+
+func (m *memoryStateManager) ClaimRecovery(ctx context.Context, executionID string, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.claims[executionID] {
+		return false, nil
+	}
+	m.claims[executionID] = true
+	return true, nil
+}
+
+func (m *memoryStateManager) CleanupExpiredExecutions(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+// fakeWorkflowExecutor records the executions recovery starts again
+type fakeWorkflowExecutor struct {
+	running          map[string]bool
+	started          chan *models.WorkflowRequest
+	resumed          chan *models.WorkflowExecution
+	resumedWorkflows chan *models.WorkflowDefinition
+}
+
+func newFakeWorkflowExecutor() *fakeWorkflowExecutor {
+	return &fakeWorkflowExecutor{
+		running:          make(map[string]bool),
+		started:          make(chan *models.WorkflowRequest, 10),
+		resumed:          make(chan *models.WorkflowExecution, 10),
+		resumedWorkflows: make(chan *models.WorkflowDefinition, 10),
+	}
+}
+
+func (f *fakeWorkflowExecutor) ExecuteWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, request *models.WorkflowRequest) (*models.WorkflowResponse, error) {
+	f.started <- request
+	return &models.WorkflowResponse{ExecutionID: request.ExecutionID, Success: true}, nil
+}
+
+func (f *fakeWorkflowExecutor) ResumeWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) (*models.WorkflowResponse, error) {
+	f.resumedWorkflows <- workflow
+	f.resumed <- execution
+	return &models.WorkflowResponse{ExecutionID: execution.ID, Success: true}, nil
+}
+
+func (f *fakeWorkflowExecutor) IsRunning(executionID string) bool {
+	return f.running[executionID]
+}
+
+// fakeHeartbeats reports the executions in alive as having a live heartbeat
+type fakeHeartbeats map[string]bool
+
+func (f fakeHeartbeats) HeartbeatAlive(ctx context.Context, executionID string) (bool, error) {
+	return f[executionID], nil
+}
+
+// routeRegistrar is an API that mounts its own routes
+type routeRegistrar interface {
+	RegisterRoutes(router *mux.Router)
+}
+
+// serveAPI mounts api under /api/v1, as the server does, and serves one request through it
+func serveAPI(api routeRegistrar, request *http.Request) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	api.RegisterRoutes(router.PathPrefix("/api/v1").Subrouter())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// newMiniRedisClient returns a client for an in-process Redis that is shut down with the test
+func newMiniRedisClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// FlagAdmin reads and changes feature flags. It is satisfied by clients.RedisFlagStore.
+type FlagAdmin interface {
+	GetFlags(ctx context.Context) (map[string]interface{}, error)
+	SetFlag(ctx context.Context, name string, value interface{}) error
+	DeleteFlag(ctx context.Context, name string) (bool, error)
+}
+
+// FlagsAPI lists, sets and deletes the feature flags workflows can branch on
+type FlagsAPI struct {
+	flags FlagAdmin
+}
+
+// NewFlagsAPI creates the feature flag endpoints
+func NewFlagsAPI(flags FlagAdmin) *FlagsAPI {
+	return &FlagsAPI{flags: flags}
+}
+
+// RegisterRoutes adds the /flags routes to the API router
+func (a *FlagsAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/flags", a.handleListFlags).Methods("GET")
+	api.HandleFunc("/flags/{name}", a.handleSetFlag).Methods("PUT")
+	api.HandleFunc("/flags/{name}", a.handleDeleteFlag).Methods("DELETE")
+}
+
+func (a *FlagsAPI) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := a.flags.GetFlags(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flags)
+}
+
+func (a *FlagsAPI) handleSetFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var request struct {
+		Value interface{} `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.flags.SetFlag(r.Context(), name, request.Value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":  name,
+		"value": request.Value,
+	})
+}
+
+func (a *FlagsAPI) handleDeleteFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	removed, err := a.flags.DeleteFlag(r.Context(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "Flag not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
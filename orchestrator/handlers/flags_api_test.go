@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// memoryFlagAdmin keeps feature flags in memory
+type memoryFlagAdmin map[string]interface{}
+
+func (m memoryFlagAdmin) GetFlags(ctx context.Context) (map[string]interface{}, error) {
+	return m, nil
+}
+
+func (m memoryFlagAdmin) SetFlag(ctx context.Context, name string, value interface{}) error {
+	m[name] = value
+	return nil
+}
+
+func (m memoryFlagAdmin) DeleteFlag(ctx context.Context, name string) (bool, error) {
+	_, exists := m[name]
+	delete(m, name)
+	return exists, nil
+}
+
+func TestFlagsAPI(t *testing.T) {
+	flags := memoryFlagAdmin{}
+	api := NewFlagsAPI(flags)
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodPut, "/api/v1/flags/use_cheap_model", strings.NewReader(`{"value": true}`)))
+	if recorder.Code != http.StatusOK || flags["use_cheap_model"] != true {
+		t.Fatalf("got %d %q and flags %v, want the flag set", recorder.Code, recorder.Body, flags)
+	}
+
+	recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/flags", nil))
+	var listed map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&listed); err != nil || listed["use_cheap_model"] != true {
+		t.Errorf("got %v, %v, want the flag listed", listed, err)
+	}
+
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodPut, "/api/v1/flags/x", strings.NewReader(`not json`))); recorder.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want 400 for an invalid body", recorder.Code)
+	}
+
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodDelete, "/api/v1/flags/use_cheap_model", nil)); recorder.Code != http.StatusNoContent {
+		t.Errorf("got %d, want 204 deleting a flag", recorder.Code)
+	}
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodDelete, "/api/v1/flags/use_cheap_model", nil)); recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 deleting a missing flag", recorder.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+// fakeFlagStore serves a fixed set of feature flags
+type fakeFlagStore struct {
+	flags map[string]interface{}
+	err   error
+}
+
+func (f *fakeFlagStore) GetFlags(ctx context.Context) (map[string]interface{}, error) {
+	return f.flags, f.err
+}
+
+// runFlaggedWorkflow runs a condition task branching on a flag and returns its follow-up tasks
+func runFlaggedWorkflow(t *testing.T, store engine.FlagStore, requestVariables map[string]interface{}) []string {
+	t.Helper()
+	states := newMemoryStateManager()
+	executor := engine.NewWorkflowExecutor(NewTaskExecutor(&fakeCoordinator{}), states, nil, 1)
+	executor.SetFlagStore(store)
+
+	workflow := &models.WorkflowDefinition{ID: "flagged", Tasks: []models.Task{
+		{
+			ID:        "choose_model",
+			Type:      "condition",
+			Condition: "${flags.use_cheap_model}",
+			OnSuccess: []string{"cheap"},
+			OnFailure: []string{"expensive"},
+		},
+		{ID: "cheap", Type: "data", Parameters: map[string]interface{}{"operation": "query"}, DependsOn: []string{"choose_model"}},
+		{ID: "expensive", Type: "data", Parameters: map[string]interface{}{"operation": "query"}, DependsOn: []string{"choose_model"}},
+	}}
+	response, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{Variables: requestVariables})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execution, err := states.LoadExecution(context.Background(), response.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	followUp, _ := execution.TaskStates["choose_model"].Output["follow_up_tasks"].([]string)
+	return followUp
+}
+
+func TestFeatureFlagSelectsConditionPath(t *testing.T) {
+	if path := runFlaggedWorkflow(t, &fakeFlagStore{flags: map[string]interface{}{"use_cheap_model": true}}, nil); !reflect.DeepEqual(path, []string{"cheap"}) {
+		t.Errorf("flag on: got path %v, want [cheap]", path)
+	}
+	if path := runFlaggedWorkflow(t, &fakeFlagStore{flags: map[string]interface{}{"use_cheap_model": false}}, nil); !reflect.DeepEqual(path, []string{"expensive"}) {
+		t.Errorf("flag off: got path %v, want [expensive]", path)
+	}
+}
+
+func TestFeatureFlagsCannotBeOverriddenByRequest(t *testing.T) {
+	store := &fakeFlagStore{flags: map[string]interface{}{"use_cheap_model": false}}
+	shadow := map[string]interface{}{engine.FlagsVariable: map[string]interface{}{"use_cheap_model": true}}
+	if path := runFlaggedWorkflow(t, store, shadow); !reflect.DeepEqual(path, []string{"expensive"}) {
+		t.Errorf("got path %v, want the store's value to win", path)
+	}
+}
+
+func TestFeatureFlagStoreFailureLeavesFlagsUnset(t *testing.T) {
+	store := &fakeFlagStore{err: errors.New("redis down")}
+	if path := runFlaggedWorkflow(t, store, nil); !reflect.DeepEqual(path, []string{"expensive"}) {
+		t.Errorf("got path %v, want an unset flag to be false", path)
+	}
+}
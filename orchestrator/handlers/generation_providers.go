@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GenerationProvider is an AI provider workflows can be generated with. An empty model
+// uses the one configured in the AI service.
+type GenerationProvider struct {
+	Name  string
+	Model string
+}
+
+// defaultGenerationProviders is used when no provider list is configured
+var defaultGenerationProviders = []GenerationProvider{
+	{Name: defaultGenerationProvider, Model: defaultGenerationModel},
+}
+
+// ParseGenerationProviders reads an ordered provider list whose entries are "provider"
+// or "provider:model", e.g. "anthropic:claude-3-sonnet", "openai"
+func ParseGenerationProviders(entries []string) []GenerationProvider {
+	var providers []GenerationProvider
+	for _, entry := range entries {
+		name, model, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		providers = append(providers, GenerationProvider{Name: name, Model: strings.TrimSpace(model)})
+	}
+	return providers
+}
+
+// SetGenerationProviders sets the providers tried, in order, until one generates a workflow
+func (ai *AIWorkflowGenerator) SetGenerationProviders(providers []GenerationProvider) {
+	if len(providers) > 0 {
+		ai.providers = providers
+	}
+}
+
+// providerChain returns the providers to try for a request. A provider or model named in
+// the request is tried first; the configured providers follow as fallbacks.
+func (ai *AIWorkflowGenerator) providerChain(request *models.AIGenerationRequest) []GenerationProvider {
+	configured := ai.providers
+	if len(configured) == 0 {
+		configured = defaultGenerationProviders
+	}
+
+	preferred := configured[0]
+	if request != nil && request.Provider != "" {
+		preferred = GenerationProvider{Name: request.Provider}
+		for _, provider := range configured {
+			if provider.Name == request.Provider {
+				preferred.Model = provider.Model
+				break
+			}
+		}
+	}
+	if request != nil && request.Model != "" {
+		preferred.Model = request.Model
+	}
+
+	return preferProvider(configured, preferred)
+}
+
+// sendWithFallback sends the request built for each provider in turn until one succeeds.
+// The AI service answers for a provider it has no client for with a failed response, so
+// unavailable providers fall through to the next one like any other failure.
+func (ai *AIWorkflowGenerator) sendWithFallback(ctx context.Context, chain []GenerationProvider, build func(GenerationProvider) (*models.ServiceRequest, error)) (*models.ServiceResponse, GenerationProvider, error) {
+	// Every provider is served by the AI service, so none can answer while it is gone
+	if ai.serviceRegistry != nil && !ai.serviceRegistry.IsServiceAvailable("ai-abstractor") {
+		ai.logger.Warn("AI service has not announced itself; generation may fail")
+	}
+
+	var failures []string
+	for i, provider := range chain {
+		if err := ctx.Err(); err != nil {
+			return nil, provider, err
+		}
+
+		request, err := build(provider)
+		if err != nil {
+			return nil, provider, err
+		}
+
+		response, err := ai.messageCoordinator.SendAIRequest(ctx, request)
+		if err == nil && response.Success {
+			return response, provider, nil
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = response.Error
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", provider.Name, reason))
+
+		fields := logrus.Fields{"provider": provider.Name, "model": provider.Model, "error": reason}
+		if i+1 < len(chain) {
+			fields["fallback"] = chain[i+1].Name
+		}
+		ai.logger.WithFields(fields).Warn("AI provider failed to generate")
+	}
+
+	return nil, GenerationProvider{}, fmt.Errorf("AI generation failed with every provider: %s", strings.Join(failures, "; "))
+}
+
+// preferProvider moves a provider to the front of the chain, keeping the others in order
+func preferProvider(chain []GenerationProvider, preferred GenerationProvider) []GenerationProvider {
+	reordered := []GenerationProvider{preferred}
+	for _, provider := range chain {
+		if provider.Name != preferred.Name {
+			reordered = append(reordered, provider)
+		}
+	}
+	return reordered
+}
+
+// applyModel sets the model parameter when the provider names one
+func applyModel(parameters map[string]interface{}, provider GenerationProvider) {
+	if provider.Model != "" {
+		parameters["model"] = provider.Model
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testProviders is the configured fallback order used by these tests
+var testProviders = []GenerationProvider{
+	{Name: "anthropic", Model: "claude-3-sonnet"},
+	{Name: "openai", Model: "gpt-4"},
+	{Name: "ollama"},
+}
+
+// providerResponses answers AI requests per provider; providers without an entry succeed
+// with correctedTaskWorkflow
+func providerResponses(failures map[string]error) *fakeCoordinator {
+	return &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		provider, _ := request.Parameters["provider"].(string)
+		if err, failed := failures[provider]; failed {
+			if err != nil {
+				return nil, err
+			}
+			return &models.ServiceResponse{Error: "no client configured for " + provider}, nil
+		}
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"content": correctedTaskWorkflow}}, nil
+	}}
+}
+
+// sentProviders returns the provider and model of each request sent
+func sentProviders(coordinator *fakeCoordinator) []GenerationProvider {
+	var sent []GenerationProvider
+	for _, request := range coordinator.requests {
+		provider, _ := request.Parameters["provider"].(string)
+		model, _ := request.Parameters["model"].(string)
+		sent = append(sent, GenerationProvider{Name: provider, Model: model})
+	}
+	return sent
+}
+
+func TestParseGenerationProviders(t *testing.T) {
+	got := ParseGenerationProviders([]string{" anthropic:claude-3-sonnet ", "openai", "", ":gpt-4", "ollama : llama3"})
+	want := []GenerationProvider{
+		{Name: "anthropic", Model: "claude-3-sonnet"},
+		{Name: "openai"},
+		{Name: "ollama", Model: "llama3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerateWorkflowFallsBackThroughProviders(t *testing.T) {
+	coordinator := providerResponses(map[string]error{"anthropic": nil, "openai": errors.New("request timeout after 2m0s")})
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders(testProviders)
+
+	workflow, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if workflow.ID != "summary" {
+		t.Errorf("got workflow %q, want the one ollama generated", workflow.ID)
+	}
+	if got := sentProviders(coordinator); !reflect.DeepEqual(got, testProviders) {
+		t.Errorf("tried %+v, want every provider in order", got)
+	}
+}
+
+func TestGenerateWorkflowReportsEveryProviderFailure(t *testing.T) {
+	coordinator := providerResponses(map[string]error{"anthropic": nil, "openai": errors.New("request timeout after 2m0s"), "ollama": nil})
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders(testProviders)
+
+	_, err := generator.GenerateWorkflow(context.Background(), &models.AIGenerationRequest{Prompt: "summarize sales"})
+	want := "AI generation failed with every provider: anthropic: no client configured for anthropic; " +
+		"openai: request timeout after 2m0s; ollama: no client configured for ollama"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	if len(coordinator.requests) != 3 {
+		t.Errorf("sent %d requests, want one per provider", len(coordinator.requests))
+	}
+}
+
+func TestGenerateWorkflowTriesRequestedProviderFirst(t *testing.T) {
+	coordinator := providerResponses(map[string]error{"openai": nil})
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders(testProviders)
+
+	request := &models.AIGenerationRequest{Prompt: "summarize sales", Provider: "openai", Model: "gpt-4o"}
+	if _, err := generator.GenerateWorkflow(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The requested model replaces the configured one; the rest keep the configured order
+	want := []GenerationProvider{{Name: "openai", Model: "gpt-4o"}, {Name: "anthropic", Model: "claude-3-sonnet"}}
+	if got := sentProviders(coordinator); !reflect.DeepEqual(got, want) {
+		t.Errorf("tried %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerateWorkflowStopsFallingBackWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		cancel()
+		return nil, context.Canceled
+	}}
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders(testProviders)
+
+	_, err := generator.GenerateWorkflow(ctx, &models.AIGenerationRequest{Prompt: "summarize sales"})
+	if !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "every provider") {
+		t.Errorf("got %v, want the cancellation", err)
+	}
+	if len(coordinator.requests) != 1 {
+		t.Errorf("sent %d requests after cancellation, want 1", len(coordinator.requests))
+	}
+}
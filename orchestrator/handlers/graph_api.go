@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"orchestrator/engine"
+	"orchestrator/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ExecutionLoader loads saved executions; it is satisfied by clients.RedisStateManager
+type ExecutionLoader interface {
+	LoadExecution(ctx context.Context, executionID string) (*models.WorkflowExecution, error)
+}
+
+// GraphAPI renders the DAGs of templates and executions as Graphviz DOT or Mermaid
+type GraphAPI struct {
+	executions ExecutionLoader
+	templates  *TemplateManager
+}
+
+// NewGraphAPI creates the graph endpoints
+func NewGraphAPI(executions ExecutionLoader, templates *TemplateManager) *GraphAPI {
+	return &GraphAPI{
+		executions: executions,
+		templates:  templates,
+	}
+}
+
+// RegisterRoutes adds the execution and template graph routes to the API router
+func (a *GraphAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/{id}/graph", a.handleGetWorkflowGraph).Methods("GET")
+	api.HandleFunc("/templates/{id}/graph", a.handleGetTemplateGraph).Methods("GET")
+}
+
+func (a *GraphAPI) handleGetWorkflowGraph(w http.ResponseWriter, r *http.Request) {
+	executionID := mux.Vars(r)["id"]
+
+	execution, err := a.executions.LoadExecution(r.Context(), executionID)
+	if err != nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	// Executions do not store their definition, so the graph is only available for templates
+	template, err := a.templates.ExecutionTemplate(execution)
+	if err != nil {
+		http.Error(w, "Workflow definition not available", http.StatusNotFound)
+		return
+	}
+
+	writeGraph(w, r, &template.Workflow, execution.TaskStates)
+}
+
+func (a *GraphAPI) handleGetTemplateGraph(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+
+	template, err := a.templates.GetTemplate(templateID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeGraph(w, r, &template.Workflow, nil)
+}
+
+// writeGraph renders a workflow DAG in the format given by the format query parameter
+// (dot by default), coloured by task status when states are given
+func writeGraph(w http.ResponseWriter, r *http.Request, workflow *models.WorkflowDefinition, states map[string]*models.TaskState) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = engine.GraphFormatDOT
+	}
+
+	dag, err := engine.NewDAG(workflow.Tasks)
+	if err != nil {
+		if WriteGraphProblems(w, "Invalid workflow graph", err) {
+			return
+		}
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	graph, err := dag.RenderGraph(format, workflow.ID, states)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if format == engine.GraphFormatDOT {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write([]byte(graph))
+}
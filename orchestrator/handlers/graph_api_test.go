@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// newGraphAPI serves a branching template and an execution started from it
+func newGraphAPI(t *testing.T) (*GraphAPI, *models.WorkflowExecution) {
+	t.Helper()
+	manager := NewTemplateManager(t.TempDir())
+	template := newTestTemplate("report")
+	template.Workflow.Tasks = []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "summarize", Type: "ai", DependsOn: []string{"fetch"}},
+		{ID: "chart", Type: "exec", DependsOn: []string{"fetch"}},
+		{ID: "publish", Type: "data", DependsOn: []string{"summarize", "chart"}},
+	}
+	if err := manager.CreateTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+
+	execution := newTestExecution("fetch", "summarize", "chart", "publish")
+	execution.Metadata[engine.TemplateMetadataKey] = "report"
+	execution.Metadata[engine.TemplateVersionMetadataKey] = template.Version
+	execution.TaskStates["fetch"].Status = models.StatusCompleted
+	execution.TaskStates["summarize"].Status = models.StatusFailed
+
+	orphan := newTestExecution("fetch")
+	orphan.ID = "exec-orphan"
+	orphan.WorkflowID = "no-such-workflow"
+
+	return NewGraphAPI(newMemoryStateManager(execution, orphan), manager), execution
+}
+
+func TestGetTemplateGraphFormats(t *testing.T) {
+	api, _ := newGraphAPI(t)
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/templates/report/graph", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "text/vnd.graphviz" {
+		t.Fatalf("dot: got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	dot := recorder.Body.String()
+	for _, want := range []string{`digraph "report_workflow" {`, `"fetch" -> "summarize";`, `"chart" -> "publish";`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot output lacks %s:\n%s", want, dot)
+		}
+	}
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("dot output is not closed:\n%s", dot)
+	}
+
+	recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/templates/report/graph?format=mermaid", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("mermaid: got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if mermaid := recorder.Body.String(); !strings.HasPrefix(mermaid, "flowchart LR\n") || strings.Count(mermaid, "-->") != 4 {
+		t.Errorf("unexpected mermaid output:\n%s", mermaid)
+	}
+}
+
+func TestGetWorkflowGraphColoursTaskStatus(t *testing.T) {
+	api, _ := newGraphAPI(t)
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-test/graph?format=mermaid", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	graph := recorder.Body.String()
+	if !strings.Contains(graph, "completed") || !strings.Contains(graph, "failed") || !strings.Contains(graph, "style ") {
+		t.Errorf("expected task statuses in the graph:\n%s", graph)
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	api, _ := newGraphAPI(t)
+
+	cases := []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/api/v1/templates/report/graph?format=svg", http.StatusBadRequest, "unsupported graph format"},
+		{"/api/v1/templates/unknown/graph", http.StatusNotFound, "not found"},
+		{"/api/v1/workflows/unknown/graph", http.StatusNotFound, "Workflow not found"},
+		{"/api/v1/workflows/exec-orphan/graph", http.StatusNotFound, "Workflow definition not available"},
+	}
+	for _, c := range cases {
+		recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if recorder.Code != c.code || !strings.Contains(recorder.Body.String(), c.want) {
+			t.Errorf("%s: got %d %q, want %d %q", c.path, recorder.Code, recorder.Body, c.code, c.want)
+		}
+	}
+}
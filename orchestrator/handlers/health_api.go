@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// RedisPinger checks the Redis connection; it is satisfied by *redis.Client
+type RedisPinger interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// ServiceAvailability reports whether a service has a fresh capability announcement.
+// It is satisfied by clients.ServiceRegistry.
+type ServiceAvailability interface {
+	IsServiceAvailable(component string) bool
+}
+
+// HealthAPI serves the liveness and readiness probes
+type HealthAPI struct {
+	redis            RedisPinger
+	services         ServiceAvailability
+	criticalServices []string
+}
+
+// readinessCheck is the state of one dependency
+type readinessCheck struct {
+	Status string `json:"status"` // ok, unavailable
+	Error  string `json:"error,omitempty"`
+}
+
+// NewHealthAPI creates the probes. The orchestrator is ready only while Redis answers and
+// every service in criticalServices is available; services may be nil when none are.
+func NewHealthAPI(redis RedisPinger, services ServiceAvailability, criticalServices []string) *HealthAPI {
+	return &HealthAPI{
+		redis:            redis,
+		services:         services,
+		criticalServices: criticalServices,
+	}
+}
+
+// RegisterRoutes adds GET /health and GET /ready to the root router
+func (a *HealthAPI) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/health", a.handleHealthCheck).Methods("GET")
+	router.HandleFunc("/ready", a.handleReadiness).Methods("GET")
+}
+
+// handleHealthCheck is the liveness probe: it only reports that the process is serving.
+// Dependencies are checked by handleReadiness.
+func (a *HealthAPI) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	health := map[string]interface{}{
+		"status":    "healthy",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"version":   "1.0.0",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleReadiness is the readiness probe. It reports 503 unless Redis answers and every
+// critical service has a fresh announcement in the service registry.
+func (a *HealthAPI) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ready := true
+	checks := make(map[string]readinessCheck)
+
+	if err := a.redis.Ping(r.Context()).Err(); err != nil {
+		ready = false
+		checks["redis"] = readinessCheck{Status: "unavailable", Error: err.Error()}
+	} else {
+		checks["redis"] = readinessCheck{Status: "ok"}
+	}
+
+	for _, service := range a.criticalServices {
+		if a.services != nil && a.services.IsServiceAvailable(service) {
+			checks[service] = readinessCheck{Status: "ok"}
+			continue
+		}
+		ready = false
+		checks[service] = readinessCheck{Status: "unavailable", Error: "no recent capability announcement"}
+	}
+
+	status := "ready"
+	if !ready {
+		status = "not_ready"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"timestamp": time.Now().Format(time.RFC3339),
+		"checks":    checks,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// fakeAvailability reports the services in it as available
+type fakeAvailability map[string]bool
+
+func (f fakeAvailability) IsServiceAvailable(component string) bool {
+	return f[component]
+}
+
+// readiness is the decoded body of /ready
+type readiness struct {
+	Status string                    `json:"status"`
+	Checks map[string]readinessCheck `json:"checks"`
+}
+
+// probe serves one request to the health endpoints, returning the status code and
+// the decoded body
+func probe(t *testing.T, api *HealthAPI, path string) (int, readiness) {
+	t.Helper()
+	router := mux.NewRouter()
+	api.RegisterRoutes(router)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body readiness
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return recorder.Code, body
+}
+
+func TestReadinessWithEverythingUp(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	api := NewHealthAPI(client, fakeAvailability{"data": true, "ai": true}, []string{"data", "ai"})
+
+	code, body := probe(t, api, "/ready")
+	if code != http.StatusOK || body.Status != "ready" {
+		t.Fatalf("got %d %+v, want ready", code, body)
+	}
+	for _, name := range []string{"redis", "data", "ai"} {
+		if body.Checks[name].Status != "ok" {
+			t.Errorf("check %s: %+v", name, body.Checks[name])
+		}
+	}
+}
+
+func TestReadinessRedisDown(t *testing.T) {
+	client, server := newMiniRedisClient(t)
+	api := NewHealthAPI(client, fakeAvailability{"data": true}, []string{"data"})
+	server.Close()
+
+	code, body := probe(t, api, "/ready")
+	if code != http.StatusServiceUnavailable || body.Status != "not_ready" {
+		t.Fatalf("got %d %+v, want not ready", code, body)
+	}
+	if check := body.Checks["redis"]; check.Status != "unavailable" || check.Error == "" {
+		t.Errorf("redis check: %+v", check)
+	}
+	if body.Checks["data"].Status != "ok" {
+		t.Errorf("data check: %+v", body.Checks["data"])
+	}
+
+	// Liveness does not depend on Redis
+	if code, body := probe(t, api, "/health"); code != http.StatusOK || body.Status != "healthy" {
+		t.Errorf("health: got %d %+v", code, body)
+	}
+}
+
+func TestReadinessCriticalServiceMissing(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+
+	cases := map[string]ServiceAvailability{
+		"not announced": fakeAvailability{"data": true},
+		"no registry":   nil,
+	}
+	for name, services := range cases {
+		code, body := probe(t, NewHealthAPI(client, services, []string{"exec"}), "/ready")
+		if code != http.StatusServiceUnavailable || body.Checks["exec"].Status != "unavailable" {
+			t.Errorf("%s: got %d %+v, want exec unavailable", name, code, body)
+		}
+		if body.Checks["redis"].Status != "ok" {
+			t.Errorf("%s: redis check %+v", name, body.Checks["redis"])
+		}
+	}
+
+	// Services that aren't critical don't affect readiness
+	if code, _ := probe(t, NewHealthAPI(client, fakeAvailability{}, nil), "/ready"); code != http.StatusOK {
+		t.Errorf("got %d with no critical services, want 200", code)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"sort"
+)
+
+// MaxMatrixCombinations bounds how many executions a single matrix request may launch
+const MaxMatrixCombinations = 100
+
+// ExpandMatrix builds one variable set per combination of matrix values, followed by the
+// explicit include sets. Every set starts from the shared base variables. Keys are expanded
+// in sorted order so the result is deterministic.
+func ExpandMatrix(base map[string]interface{}, matrix map[string][]interface{}, include []map[string]interface{}) ([]map[string]interface{}, error) {
+	keys := make([]string, 0, len(matrix))
+	for key, values := range matrix {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix variable %s has no values", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var combinations []map[string]interface{}
+	if len(keys) > 0 {
+		combinations = []map[string]interface{}{{}}
+		for _, key := range keys {
+			expanded := make([]map[string]interface{}, 0, len(combinations)*len(matrix[key]))
+			for _, combination := range combinations {
+				for _, value := range matrix[key] {
+					next := make(map[string]interface{}, len(combination)+1)
+					for k, v := range combination {
+						next[k] = v
+					}
+					next[key] = value
+					expanded = append(expanded, next)
+				}
+			}
+			if len(expanded)+len(include) > MaxMatrixCombinations {
+				return nil, fmt.Errorf("matrix expands to more than %d combinations", MaxMatrixCombinations)
+			}
+			combinations = expanded
+		}
+	}
+
+	combinations = append(combinations, include...)
+	if len(combinations) == 0 {
+		return nil, fmt.Errorf("matrix must define at least one variable set")
+	}
+	if len(combinations) > MaxMatrixCombinations {
+		return nil, fmt.Errorf("matrix expands to more than %d combinations", MaxMatrixCombinations)
+	}
+
+	result := make([]map[string]interface{}, len(combinations))
+	for i, combination := range combinations {
+		result[i] = mergeVariables(base, combination)
+	}
+
+	return result, nil
+}
+
+// SummarizeMatrix rolls up the statuses of a matrix group's executions: running while
+// anything is unfinished, then failed if anything failed, then cancelled, else completed
+func SummarizeMatrix(matrixID string, statuses map[string]models.ExecutionStatus) models.MatrixStatus {
+	status := models.MatrixStatus{
+		MatrixID:     matrixID,
+		Total:        len(statuses),
+		StatusCounts: make(map[models.ExecutionStatus]int),
+		Executions:   make(map[string]models.ExecutionStatus, len(statuses)),
+	}
+
+	for executionID, executionStatus := range statuses {
+		status.Executions[executionID] = executionStatus
+		status.StatusCounts[executionStatus]++
+	}
+
+	switch {
+	case status.StatusCounts[models.StatusPending]+status.StatusCounts[models.StatusRunning]+status.StatusCounts[models.StatusRetrying] > 0:
+		status.Status = models.StatusRunning
+	case status.StatusCounts[models.StatusFailed] > 0:
+		status.Status = models.StatusFailed
+	case status.StatusCounts[models.StatusCancelled] > 0:
+		status.Status = models.StatusCancelled
+	default:
+		status.Status = models.StatusCompleted
+	}
+
+	return status
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"orchestrator/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// MatrixStore keeps the executions of matrix groups. It is satisfied by
+// clients.RedisStateManager.
+type MatrixStore interface {
+	ExecutionLoader
+	SaveMatrixGroup(ctx context.Context, matrixID string, executionIDs []string) error
+	LoadMatrixGroup(ctx context.Context, matrixID string) ([]string, error)
+}
+
+// MatrixAPI launches one execution of a template per combination of variable sets and
+// reports on the group
+type MatrixAPI struct {
+	store           MatrixStore
+	resolveTemplate func(id, version string) (*models.Template, error)
+	enqueue         func(ctx context.Context, request *models.WorkflowRequest) error
+	logger          *logrus.Logger
+}
+
+// NewMatrixAPI creates the matrix endpoints. Templates are resolved with resolveTemplate
+// and each combination's request is queued with enqueue.
+func NewMatrixAPI(store MatrixStore, resolveTemplate func(id, version string) (*models.Template, error), enqueue func(ctx context.Context, request *models.WorkflowRequest) error, logger *logrus.Logger) *MatrixAPI {
+	return &MatrixAPI{
+		store:           store,
+		resolveTemplate: resolveTemplate,
+		enqueue:         enqueue,
+		logger:          logger,
+	}
+}
+
+// RegisterRoutes adds the /workflows/matrix routes to the API router
+func (a *MatrixAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/matrix", a.handleExecuteMatrix).Methods("POST")
+	api.HandleFunc("/workflows/matrix/{id}", a.handleGetMatrixStatus).Methods("GET")
+}
+
+func (a *MatrixAPI) handleExecuteMatrix(w http.ResponseWriter, r *http.Request) {
+	var request models.MatrixRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := a.resolveTemplate(request.TemplateID, request.TemplateVersion)
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	// Every combination runs the same version even if the template changes meanwhile
+	templateVersion := template.Version
+
+	variableSets, err := ExpandMatrix(request.Variables, request.Matrix, request.Include)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid matrix: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	matrixID := uuid.New().String()
+	executionIDs := make([]string, len(variableSets))
+	for i := range variableSets {
+		executionIDs[i] = fmt.Sprintf("exec_%s_%d", matrixID, i)
+	}
+
+	if err := a.store.SaveMatrixGroup(r.Context(), matrixID, executionIDs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for i, variables := range variableSets {
+		workflowRequest := &models.WorkflowRequest{
+			CorrelationID:    fmt.Sprintf("%s-%d", matrixID, i),
+			WorkflowTemplate: request.TemplateID,
+			TemplateVersion:  templateVersion,
+			Variables:        variables,
+			PinVersions:      request.PinVersions,
+			ExecutionID:      executionIDs[i],
+			MatrixID:         matrixID,
+			Priority:         request.Priority,
+		}
+		if err := a.enqueue(r.Context(), workflowRequest); err != nil {
+			a.logger.WithError(err).WithField("matrix_id", matrixID).Error("Failed to queue matrix execution")
+			http.Error(w, "Failed to queue matrix executions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	a.logger.WithFields(logrus.Fields{
+		"matrix_id":   matrixID,
+		"template_id": request.TemplateID,
+		"version":     templateVersion,
+		"executions":  len(executionIDs),
+	}).Info("Matrix execution queued")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"matrix_id":     matrixID,
+		"execution_ids": executionIDs,
+	})
+}
+
+func (a *MatrixAPI) handleGetMatrixStatus(w http.ResponseWriter, r *http.Request) {
+	matrixID := mux.Vars(r)["id"]
+
+	executionIDs, err := a.store.LoadMatrixGroup(r.Context(), matrixID)
+	if err != nil {
+		http.Error(w, "Matrix not found", http.StatusNotFound)
+		return
+	}
+
+	statuses := make(map[string]models.ExecutionStatus, len(executionIDs))
+	for _, executionID := range executionIDs {
+		// Executions that have not saved their initial state yet are still pending
+		statuses[executionID] = models.StatusPending
+		if execution, err := a.store.LoadExecution(r.Context(), executionID); err == nil {
+			statuses[executionID] = execution.Status
+		}
+	}
+	status := SummarizeMatrix(matrixID, statuses)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/models"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryMatrixStore keeps matrix groups in memory next to the executions
+type memoryMatrixStore struct {
+	*memoryStateManager
+	groups map[string][]string
+}
+
+func (m *memoryMatrixStore) SaveMatrixGroup(ctx context.Context, matrixID string, executionIDs []string) error {
+	m.groups[matrixID] = executionIDs
+	return nil
+}
+
+func (m *memoryMatrixStore) LoadMatrixGroup(ctx context.Context, matrixID string) ([]string, error) {
+	executionIDs, exists := m.groups[matrixID]
+	if !exists {
+		return nil, errors.New("matrix not found")
+	}
+	return executionIDs, nil
+}
+
+func TestExecuteMatrixQueuesEveryCombination(t *testing.T) {
+	store := &memoryMatrixStore{memoryStateManager: newMemoryStateManager(), groups: make(map[string][]string)}
+	resolve := func(id, version string) (*models.Template, error) {
+		if id != "ingest" {
+			return nil, errors.New("template not found")
+		}
+		return &models.Template{ID: id, Version: "2.0.0"}, nil
+	}
+	var queued []*models.WorkflowRequest
+	enqueue := func(ctx context.Context, request *models.WorkflowRequest) error {
+		queued = append(queued, request)
+		return nil
+	}
+	api := NewMatrixAPI(store, resolve, enqueue, logrus.New())
+
+	body := `{"template_id": "ingest", "matrix": {"region": ["eu", "us"]}}`
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodPost, "/api/v1/workflows/matrix", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	var response struct {
+		MatrixID     string   `json:"matrix_id"`
+		ExecutionIDs []string `json:"execution_ids"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.ExecutionIDs) != 2 || len(queued) != 2 {
+		t.Fatalf("got %d executions and %d queued requests, want 2", len(response.ExecutionIDs), len(queued))
+	}
+	for i, request := range queued {
+		if request.ExecutionID != response.ExecutionIDs[i] || request.MatrixID != response.MatrixID || request.TemplateVersion != "2.0.0" {
+			t.Errorf("got request %+v, want it in the group at the resolved version", request)
+		}
+	}
+
+	// Executions that have not started yet are reported as pending
+	recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/matrix/"+response.MatrixID, nil))
+	var status models.MatrixStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil || status.Total != 2 || status.StatusCounts[models.StatusPending] != 2 {
+		t.Errorf("got %d %+v, %v, want two pending executions", recorder.Code, status, err)
+	}
+
+	unknown := `{"template_id": "missing", "matrix": {"region": ["eu"]}}`
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodPost, "/api/v1/workflows/matrix", strings.NewReader(unknown))); recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 for an unknown template", recorder.Code)
+	}
+	if recorder = serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/matrix/unknown", nil)); recorder.Code != http.StatusNotFound {
+		t.Errorf("got %d, want 404 for an unknown matrix", recorder.Code)
+	}
+}
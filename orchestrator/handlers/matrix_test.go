@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	sets, err := ExpandMatrix(
+		map[string]interface{}{"bucket": "raw", "region": "eu"},
+		map[string][]interface{}{"region": {"eu", "us"}, "size": {1, 2}},
+		[]map[string]interface{}{{"region": "ap", "size": 9}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []map[string]interface{}{
+		{"bucket": "raw", "region": "eu", "size": 1},
+		{"bucket": "raw", "region": "eu", "size": 2},
+		{"bucket": "raw", "region": "us", "size": 1},
+		{"bucket": "raw", "region": "us", "size": 2},
+		{"bucket": "raw", "region": "ap", "size": 9},
+	}
+	if !reflect.DeepEqual(sets, want) {
+		t.Errorf("got %v, want %v", sets, want)
+	}
+}
+
+func TestExpandMatrixRejectsInvalidMatrices(t *testing.T) {
+	tooMany := make([]interface{}, MaxMatrixCombinations+1)
+	for name, matrix := range map[string]map[string][]interface{}{
+		"empty values": {"file": {}},
+		"no sets":      {},
+		"too many":     {"file": tooMany},
+	} {
+		if _, err := ExpandMatrix(nil, matrix, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMatrixLaunchesTaggedExecutions(t *testing.T) {
+	sets, err := ExpandMatrix(nil, map[string][]interface{}{"file": {"a.csv", "b.csv", "c.csv"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 3 {
+		t.Fatalf("got %d variable sets, want 3", len(sets))
+	}
+
+	states := newMemoryStateManager()
+	executor := engine.NewWorkflowExecutor(NewTaskExecutor(&fakeCoordinator{}), states, nil, 1)
+	workflow := &models.WorkflowDefinition{ID: "ingest", Tasks: []models.Task{
+		{ID: "load", Type: "data", Parameters: map[string]interface{}{"operation": "load", "file": "${file}"}},
+	}}
+
+	statuses := make(map[string]models.ExecutionStatus)
+	files := make(map[string]bool)
+	for i, variables := range sets {
+		executionID := fmt.Sprintf("exec_matrix-1_%d", i)
+		if _, err := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{
+			Variables:   variables,
+			ExecutionID: executionID,
+			MatrixID:    "matrix-1",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		execution, err := states.LoadExecution(context.Background(), executionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if execution.MatrixID != "matrix-1" {
+			t.Errorf("%s: matrix ID %q, want matrix-1", executionID, execution.MatrixID)
+		}
+		files[fmt.Sprint(execution.Variables["file"])] = true
+		statuses[executionID] = execution.Status
+	}
+	if len(files) != 3 {
+		t.Errorf("expected each execution to get its own input, got %v", files)
+	}
+
+	summary := SummarizeMatrix("matrix-1", statuses)
+	if summary.Total != 3 || summary.Status != models.StatusCompleted || summary.StatusCounts[models.StatusCompleted] != 3 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestSummarizeMatrix(t *testing.T) {
+	cases := []struct {
+		statuses []models.ExecutionStatus
+		want     models.ExecutionStatus
+	}{
+		{[]models.ExecutionStatus{models.StatusCompleted, models.StatusPending, models.StatusFailed}, models.StatusRunning},
+		{[]models.ExecutionStatus{models.StatusCompleted, models.StatusFailed, models.StatusCancelled}, models.StatusFailed},
+		{[]models.ExecutionStatus{models.StatusCompleted, models.StatusCancelled}, models.StatusCancelled},
+		{[]models.ExecutionStatus{models.StatusCompleted, models.StatusCompleted}, models.StatusCompleted},
+	}
+	for _, tc := range cases {
+		statuses := make(map[string]models.ExecutionStatus)
+		for i, status := range tc.statuses {
+			statuses[fmt.Sprintf("exec-%d", i)] = status
+		}
+		if got := SummarizeMatrix("m", statuses); got.Status != tc.want || got.Total != len(tc.statuses) {
+			t.Errorf("%v: got %s of %d, want %s", tc.statuses, got.Status, got.Total, tc.want)
+		}
+	}
+}
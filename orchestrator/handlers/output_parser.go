@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Supported exec output parser types
+const (
+	OutputParserJSON  = "json"
+	OutputParserCSV   = "csv"
+	OutputParserRegex = "regex"
+)
+
+// defaultParsedOutputKey is where parsed results land in the task output
+const defaultParsedOutputKey = "parsed"
+
+// OutputParserConfig describes how to turn an exec task's raw output into structured data
+type OutputParserConfig struct {
+	Type    string // json, csv or regex
+	File    string // output file to parse; empty means the container logs/output
+	Pattern string // regular expression for the regex parser
+	Target  string // key in the task output the parsed result is stored under
+	Header  bool   // csv: treat the first row as column names
+}
+
+// parseOutputParserConfig reads and validates the output_parser parameter of an exec task.
+// It returns nil if the task does not specify a parser.
+func parseOutputParserConfig(parameters map[string]interface{}) (*OutputParserConfig, error) {
+	raw, exists := parameters["output_parser"]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("output_parser must be an object")
+	}
+
+	config := &OutputParserConfig{
+		Target: defaultParsedOutputKey,
+		Header: true,
+	}
+
+	config.Type, _ = spec["type"].(string)
+	config.File, _ = spec["file"].(string)
+	config.Pattern, _ = spec["pattern"].(string)
+	if target, ok := spec["target"].(string); ok && target != "" {
+		config.Target = target
+	}
+	if header, ok := spec["header"].(bool); ok {
+		config.Header = header
+	}
+
+	switch config.Type {
+	case OutputParserJSON, OutputParserCSV:
+	case OutputParserRegex:
+		if config.Pattern == "" {
+			return nil, fmt.Errorf("regex output parser requires a pattern")
+		}
+		if _, err := regexp.Compile(config.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid output parser pattern: %w", err)
+		}
+	case "":
+		return nil, fmt.Errorf("output parser type is required")
+	default:
+		return nil, fmt.Errorf("unsupported output parser type: %s", config.Type)
+	}
+
+	return config, nil
+}
+
+// applyOutputParser parses the configured source from an exec response into structured data
+func applyOutputParser(config *OutputParserConfig, data map[string]interface{}) (interface{}, error) {
+	source, err := outputParserSource(config, data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case OutputParserJSON:
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(source), &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON output: %w", err)
+		}
+		return parsed, nil
+	case OutputParserCSV:
+		return parseCSVOutput(source, config.Header)
+	case OutputParserRegex:
+		return parseRegexOutput(source, config.Pattern)
+	default:
+		return nil, fmt.Errorf("unsupported output parser type: %s", config.Type)
+	}
+}
+
+// outputParserSource finds the text to parse: a named output file, or the logs/output
+// of the container run. The exec result may be returned at the top level or under "result".
+func outputParserSource(config *OutputParserConfig, data map[string]interface{}) (string, error) {
+	result := data
+	if nested, ok := data["result"].(map[string]interface{}); ok {
+		result = nested
+	}
+
+	if config.File != "" {
+		files, _ := result["output_files"].([]interface{})
+		for _, entry := range files {
+			file, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := file["name"].(string)
+			path, _ := file["path"].(string)
+			if name == config.File || path == config.File {
+				content, _ := file["content"].(string)
+				return content, nil
+			}
+		}
+		return "", fmt.Errorf("output file %s not found in exec result", config.File)
+	}
+
+	if logs, ok := result["logs"].(string); ok && logs != "" {
+		return logs, nil
+	}
+	if output, ok := result["output"].(string); ok {
+		return output, nil
+	}
+
+	return "", fmt.Errorf("exec result contains no logs or output to parse")
+}
+
+// parseCSVOutput converts CSV text into rows, keyed by column name when header is set
+func parseCSVOutput(source string, header bool) (interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(source)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV output: %w", err)
+	}
+
+	if !header {
+		rows := make([]interface{}, len(records))
+		for i, record := range records {
+			row := make([]interface{}, len(record))
+			for j, value := range record {
+				row[j] = value
+			}
+			rows[i] = row
+		}
+		return rows, nil
+	}
+
+	if len(records) == 0 {
+		return []interface{}{}, nil
+	}
+
+	columns := records[0]
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(columns))
+		for j, column := range columns {
+			if j < len(record) {
+				row[column] = record[j]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseRegexOutput extracts every match of pattern. Named groups produce one object per
+// match; otherwise the first capture group (or the whole match) is returned.
+func parseRegexOutput(source, pattern string) (interface{}, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output parser pattern: %w", err)
+	}
+
+	names := re.SubexpNames()
+	hasNamed := false
+	for _, name := range names {
+		if name != "" {
+			hasNamed = true
+			break
+		}
+	}
+
+	matches := re.FindAllStringSubmatch(source, -1)
+	results := make([]interface{}, 0, len(matches))
+	for _, match := range matches {
+		switch {
+		case hasNamed:
+			fields := make(map[string]interface{})
+			for i, name := range names {
+				if name != "" {
+					fields[name] = match[i]
+				}
+			}
+			results = append(results, fields)
+		case len(match) > 1:
+			results = append(results, match[1])
+		default:
+			results = append(results, match[0])
+		}
+	}
+
+	return results, nil
+}
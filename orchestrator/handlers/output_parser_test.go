@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyOutputParser(t *testing.T) {
+	result := map[string]interface{}{
+		"result": map[string]interface{}{
+			"logs": "processed 12 rows in 40ms\nprocessed 3 rows in 5ms\n",
+			"output_files": []interface{}{
+				map[string]interface{}{"name": "summary.json", "content": `{"rows": 15, "ok": true}`},
+				map[string]interface{}{"name": "rows.csv", "content": "id,name\n1,alpha\n2,beta\n"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		config *OutputParserConfig
+		want   interface{}
+	}{
+		{
+			name:   "json file",
+			config: &OutputParserConfig{Type: OutputParserJSON, File: "summary.json"},
+			want:   map[string]interface{}{"rows": float64(15), "ok": true},
+		},
+		{
+			name:   "csv with header",
+			config: &OutputParserConfig{Type: OutputParserCSV, File: "rows.csv", Header: true},
+			want: []interface{}{
+				map[string]interface{}{"id": "1", "name": "alpha"},
+				map[string]interface{}{"id": "2", "name": "beta"},
+			},
+		},
+		{
+			name:   "csv without header",
+			config: &OutputParserConfig{Type: OutputParserCSV, File: "rows.csv"},
+			want: []interface{}{
+				[]interface{}{"id", "name"},
+				[]interface{}{"1", "alpha"},
+				[]interface{}{"2", "beta"},
+			},
+		},
+		{
+			name:   "regex named groups over logs",
+			config: &OutputParserConfig{Type: OutputParserRegex, Pattern: `processed (?P<rows>\d+) rows in (?P<took>\w+)`},
+			want: []interface{}{
+				map[string]interface{}{"rows": "12", "took": "40ms"},
+				map[string]interface{}{"rows": "3", "took": "5ms"},
+			},
+		},
+		{
+			name:   "regex capture group",
+			config: &OutputParserConfig{Type: OutputParserRegex, Pattern: `processed (\d+) rows`},
+			want:   []interface{}{"12", "3"},
+		},
+	}
+
+	for _, tc := range cases {
+		got, err := applyOutputParser(tc.config, result)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := applyOutputParser(&OutputParserConfig{Type: OutputParserJSON, File: "missing.json"}, result); err == nil {
+		t.Error("expected a missing output file to fail")
+	}
+	if _, err := applyOutputParser(&OutputParserConfig{Type: OutputParserJSON}, map[string]interface{}{"logs": "not json"}); err == nil {
+		t.Error("expected invalid JSON to fail")
+	}
+}
+
+func TestParseOutputParserConfig(t *testing.T) {
+	config, err := parseOutputParserConfig(map[string]interface{}{})
+	if config != nil || err != nil {
+		t.Fatalf("expected no parser without output_parser, got %+v, %v", config, err)
+	}
+
+	config, err = parseOutputParserConfig(map[string]interface{}{
+		"output_parser": map[string]interface{}{"type": "csv", "file": "rows.csv", "header": false, "target": "rows"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &OutputParserConfig{Type: OutputParserCSV, File: "rows.csv", Target: "rows"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+
+	invalid := map[string]interface{}{
+		"must be an object":             "json",
+		"type is required":              map[string]interface{}{},
+		"unsupported":                   map[string]interface{}{"type": "xml"},
+		"requires a pattern":            map[string]interface{}{"type": "regex"},
+		"invalid output parser pattern": map[string]interface{}{"type": "regex", "pattern": "("},
+	}
+	for wantErr, spec := range invalid {
+		_, err := parseOutputParserConfig(map[string]interface{}{"output_parser": spec})
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%v: expected error containing %q, got %v", spec, wantErr, err)
+		}
+	}
+}
+
+func TestExecuteExecTaskAppliesOutputParser(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"logs": `{"score": 0.9}`}}, nil
+	}}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("score")
+
+	task := &models.Task{ID: "score", Type: "exec", Parameters: map[string]interface{}{
+		"container":     map[string]interface{}{"image": "tools:1"},
+		"output_parser": map[string]interface{}{"type": "json", "target": "result"},
+	}}
+	if err := executor.ExecuteTask(context.Background(), task, execution); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := execution.TaskStates["score"].Output
+	if !reflect.DeepEqual(output["result"], map[string]interface{}{"score": 0.9}) {
+		t.Errorf("expected parsed output under result, got %#v", output)
+	}
+	if output["logs"] != `{"score": 0.9}` {
+		t.Errorf("expected the raw output to be kept, got %#v", output)
+	}
+}
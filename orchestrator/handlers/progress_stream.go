@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"orchestrator/models"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// WebSocket timings for progress streams
+const (
+	streamWriteWait    = 10 * time.Second
+	streamPongWait     = 60 * time.Second
+	streamPingInterval = streamPongWait * 9 / 10
+)
+
+// streamUpgrader accepts any origin, matching the API's CORS policy
+var streamUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ProgressSubscriber listens for the progress events of one execution. It is satisfied by
+// clients.RedisProgressPublisher.
+type ProgressSubscriber interface {
+	Subscribe(ctx context.Context, executionID string) (*redis.PubSub, error)
+}
+
+// ProgressStreamAPI streams an execution's progress events over a WebSocket
+type ProgressStreamAPI struct {
+	executions ExecutionLoader
+	progress   ProgressSubscriber
+	logger     *logrus.Logger
+}
+
+// NewProgressStreamAPI creates the progress streaming endpoint
+func NewProgressStreamAPI(executions ExecutionLoader, progress ProgressSubscriber, logger *logrus.Logger) *ProgressStreamAPI {
+	return &ProgressStreamAPI{
+		executions: executions,
+		progress:   progress,
+		logger:     logger,
+	}
+}
+
+// RegisterRoutes adds GET /workflows/{id}/stream to the API router
+func (a *ProgressStreamAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/{id}/stream", a.handleStreamWorkflow).Methods("GET")
+}
+
+// handleStreamWorkflow upgrades to a WebSocket that pushes an execution's task and workflow
+// state changes as JSON progress events. The current state is sent first; the socket is
+// closed once the workflow finishes or the client goes away.
+func (a *ProgressStreamAPI) handleStreamWorkflow(w http.ResponseWriter, r *http.Request) {
+	executionID := mux.Vars(r)["id"]
+
+	if _, err := a.executions.LoadExecution(r.Context(), executionID); err != nil {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+
+	// The stream outlives the server's read and write timeouts
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client
+		a.logger.WithError(err).WithField("execution_id", executionID).Debug("WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Subscribe before reading the snapshot so no transition falls between the two
+	pubsub, err := a.progress.Subscribe(ctx, executionID)
+	if err != nil {
+		a.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to subscribe to workflow progress")
+		closeStream(conn, websocket.CloseInternalServerErr, "progress unavailable")
+		return
+	}
+	defer pubsub.Close()
+
+	execution, err := a.executions.LoadExecution(ctx, executionID)
+	if err != nil {
+		closeStream(conn, websocket.CloseInternalServerErr, "workflow unavailable")
+		return
+	}
+	for _, event := range progressSnapshot(execution) {
+		if err := writeStreamJSON(conn, event); err != nil {
+			return
+		}
+	}
+	if workflowFinished(execution.Status) {
+		closeStream(conn, websocket.CloseNormalClosure, string(execution.Status))
+		return
+	}
+
+	// Read until the client disconnects; reading also handles pongs and close frames
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case msg, open := <-messages:
+			if !open {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+				return
+			}
+
+			var event models.ProgressEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err == nil &&
+				event.Type == models.ProgressWorkflow && workflowFinished(event.Status) {
+				closeStream(conn, websocket.CloseNormalClosure, string(event.Status))
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// progressSnapshot describes an execution's current state as progress events
+func progressSnapshot(execution *models.WorkflowExecution) []*models.ProgressEvent {
+	now := time.Now()
+	events := []*models.ProgressEvent{{
+		Type:        models.ProgressWorkflow,
+		ExecutionID: execution.ID,
+		Status:      execution.Status,
+		Error:       execution.Error,
+		Timestamp:   now,
+	}}
+	for _, taskState := range execution.TaskStates {
+		events = append(events, &models.ProgressEvent{
+			Type:        models.ProgressTask,
+			ExecutionID: execution.ID,
+			TaskID:      taskState.ID,
+			Status:      taskState.Status,
+			RetryCount:  taskState.RetryCount,
+			Error:       taskState.Error,
+			Timestamp:   now,
+		})
+	}
+	return events
+}
+
+func workflowFinished(status models.ExecutionStatus) bool {
+	switch status {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return true
+	}
+	return false
+}
+
+func writeStreamJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+	return conn.WriteJSON(v)
+}
+
+func closeStream(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(streamWriteWait))
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/clients"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// failingSubscriber cannot subscribe to progress
+type failingSubscriber struct{}
+
+func (failingSubscriber) Subscribe(ctx context.Context, executionID string) (*redis.PubSub, error) {
+	return nil, errors.New("redis down")
+}
+
+// newStreamServer serves the progress stream of the given executions over HTTP
+func newStreamServer(t *testing.T, progress ProgressSubscriber, executions ...*models.WorkflowExecution) *httptest.Server {
+	t.Helper()
+	router := mux.NewRouter()
+	NewProgressStreamAPI(newMemoryStateManager(executions...), progress, logrus.New()).RegisterRoutes(router.PathPrefix("/api/v1").Subrouter())
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialStream opens the progress stream of an execution
+func dialStream(t *testing.T, server *httptest.Server, executionID string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/workflows/" + executionID + "/stream"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// readEvents reads count progress events from the stream
+func readEvents(t *testing.T, conn *websocket.Conn, count int) []models.ProgressEvent {
+	t.Helper()
+	events := make([]models.ProgressEvent, count)
+	for i := range events {
+		if err := conn.ReadJSON(&events[i]); err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+	}
+	return events
+}
+
+// expectClose reads until the server closes the stream and checks the close frame
+func expectClose(t *testing.T, conn *websocket.Conn, code int, reason string) {
+	t.Helper()
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != code || closeErr.Text != reason {
+		t.Fatalf("got %v, want close %d %q", err, code, reason)
+	}
+}
+
+func TestStreamWorkflowUnknownExecution(t *testing.T) {
+	server := newStreamServer(t, failingSubscriber{})
+
+	response, err := http.Get(server.URL + "/api/v1/workflows/unknown/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d, want 404", response.StatusCode)
+	}
+}
+
+func TestStreamWorkflowSubscriptionFailureClosesStream(t *testing.T) {
+	execution := newTestExecution("fetch")
+	execution.Status = models.StatusRunning
+	conn := dialStream(t, newStreamServer(t, failingSubscriber{}, execution), execution.ID)
+
+	expectClose(t, conn, websocket.CloseInternalServerErr, "progress unavailable")
+}
+
+func TestStreamFinishedWorkflowSendsSnapshotAndCloses(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	execution := newTestExecution("fetch")
+	execution.Status = models.StatusCompleted
+	execution.TaskStates["fetch"].Status = models.StatusCompleted
+	conn := dialStream(t, newStreamServer(t, clients.NewRedisProgressPublisher(client, "progress"), execution), execution.ID)
+
+	events := readEvents(t, conn, 2)
+	if events[0].Type != models.ProgressWorkflow || events[0].Status != models.StatusCompleted {
+		t.Errorf("got %+v, want the workflow's state first", events[0])
+	}
+	if events[1].Type != models.ProgressTask || events[1].TaskID != "fetch" || events[1].Status != models.StatusCompleted {
+		t.Errorf("got %+v, want the task's state", events[1])
+	}
+	expectClose(t, conn, websocket.CloseNormalClosure, string(models.StatusCompleted))
+}
+
+func TestStreamRunningWorkflowForwardsProgressUntilItFinishes(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	publisher := clients.NewRedisProgressPublisher(client, "progress")
+	execution := newTestExecution("fetch")
+	execution.Status = models.StatusRunning
+	conn := dialStream(t, newStreamServer(t, publisher, execution), execution.ID)
+
+	// The snapshot is only sent once the subscription is in place
+	readEvents(t, conn, 2)
+
+	published := []*models.ProgressEvent{
+		{Type: models.ProgressTask, ExecutionID: execution.ID, TaskID: "fetch", Status: models.StatusRunning},
+		{Type: models.ProgressTask, ExecutionID: execution.ID, TaskID: "fetch", Status: models.StatusCompleted},
+		{Type: models.ProgressWorkflow, ExecutionID: execution.ID, Status: models.StatusCompleted},
+	}
+	for _, event := range published {
+		if err := publisher.PublishProgress(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, event := range readEvents(t, conn, len(published)) {
+		if event.Type != published[i].Type || event.TaskID != published[i].TaskID || event.Status != published[i].Status {
+			t.Errorf("event %d: got %+v, want %+v", i, event, published[i])
+		}
+	}
+	expectClose(t, conn, websocket.CloseNormalClosure, string(models.StatusCompleted))
+}
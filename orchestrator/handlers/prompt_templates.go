@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// promptKeyPattern keeps provider and complexity names usable as file names
+var promptKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// PromptTemplates loads generation prompts from a directory so they can be tuned without
+// recompiling. Files are read on every use, so edits apply to the next generation. Lookups
+// try the provider-specific file first, then the generic one:
+//
+//	system.<provider>.txt, system.txt
+//	examples.<complexity>.<provider>.txt, examples.<complexity>.txt
+//
+// Missing files fall back to the built-in prompts.
+type PromptTemplates struct {
+	dir    string
+	logger *logrus.Logger
+}
+
+// NewPromptTemplates creates prompt templates read from dir
+func NewPromptTemplates(dir string) *PromptTemplates {
+	return &PromptTemplates{
+		dir:    dir,
+		logger: logrus.New(),
+	}
+}
+
+// SystemMessage returns the system message for a provider, or fallback
+func (pt *PromptTemplates) SystemMessage(provider, fallback string) string {
+	return pt.resolve(fallback, qualifiedName("system", provider), "system")
+}
+
+// Examples returns the example section for a complexity level and provider, or fallback
+func (pt *PromptTemplates) Examples(provider, complexity, fallback string) string {
+	if !promptKeyPattern.MatchString(complexity) {
+		return fallback
+	}
+	base := "examples." + complexity
+	return pt.resolve(fallback, qualifiedName(base, provider), base)
+}
+
+// resolve returns the first template file that exists among names, or fallback. A nil
+// receiver always uses the fallback.
+func (pt *PromptTemplates) resolve(fallback string, names ...string) string {
+	if pt == nil || pt.dir == "" {
+		return fallback
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(pt.dir, name+".txt")
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data)
+		}
+		if !os.IsNotExist(err) {
+			pt.logger.WithError(err).WithField("path", path).Warn("Failed to read prompt template, using built-in prompt")
+			return fallback
+		}
+	}
+
+	return fallback
+}
+
+// qualifiedName appends a provider to a template name, or returns "" when the provider
+// cannot be used in a file name
+func qualifiedName(base, provider string) string {
+	if !promptKeyPattern.MatchString(provider) {
+		return ""
+	}
+	return base + "." + provider
+}
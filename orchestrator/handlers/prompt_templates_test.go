@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePrompts writes each named prompt template into a new directory
+func writePrompts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPromptTemplatesPreferProviderFiles(t *testing.T) {
+	dir := writePrompts(t, map[string]string{
+		"system.txt":                  "generic system",
+		"system.openai.txt":           "openai system",
+		"examples.simple.txt":         "generic simple examples",
+		"examples.complex.ollama.txt": "ollama complex examples",
+	})
+	prompts := NewPromptTemplates(dir)
+
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"provider system message", prompts.SystemMessage("openai", "built-in"), "openai system"},
+		{"generic system message", prompts.SystemMessage("anthropic", "built-in"), "generic system"},
+		{"provider examples", prompts.Examples("ollama", "complex", "built-in"), "ollama complex examples"},
+		{"generic examples", prompts.Examples("ollama", "simple", "built-in"), "generic simple examples"},
+		{"missing examples", prompts.Examples("openai", "medium", "built-in"), "built-in"},
+		{"unsafe provider", prompts.SystemMessage("../system", "built-in"), "generic system"},
+		{"unsafe complexity", prompts.Examples("openai", "../system", "built-in"), "built-in"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestPromptTemplatesReloadEdits(t *testing.T) {
+	dir := writePrompts(t, map[string]string{"system.txt": "first"})
+	prompts := NewPromptTemplates(dir)
+	if got := prompts.SystemMessage("openai", "built-in"); got != "first" {
+		t.Fatalf("got %q, want first", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "system.txt"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := prompts.SystemMessage("openai", "built-in"); got != "second" {
+		t.Errorf("got %q, want the edited template", got)
+	}
+
+	// Without templates the built-in prompts are used
+	var none *PromptTemplates
+	if got := none.SystemMessage("openai", "built-in"); got != "built-in" {
+		t.Errorf("got %q from nil templates, want built-in", got)
+	}
+}
+
+func TestGenerateWorkflowUsesEachProvidersPrompts(t *testing.T) {
+	dir := writePrompts(t, map[string]string{
+		"system.openai.txt":          "openai system",
+		"examples.simple.openai.txt": "openai examples",
+	})
+	coordinator := providerResponses(map[string]error{"anthropic": nil})
+	generator := NewAIWorkflowGenerator(coordinator, nil, nil)
+	generator.SetGenerationProviders([]GenerationProvider{{Name: "anthropic"}, {Name: "openai"}})
+	generator.SetPromptTemplates(NewPromptTemplates(dir))
+
+	request := &models.AIGenerationRequest{Prompt: "summarize sales", Complexity: "simple"}
+	if _, err := generator.GenerateWorkflow(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(coordinator.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(coordinator.requests))
+	}
+	anthropic, openai := coordinator.requests[0].Parameters, coordinator.requests[1].Parameters
+	if anthropic["system_message"] != generator.getSystemMessage() {
+		t.Error("expected anthropic to get the built-in system message")
+	}
+	if prompt, _ := anthropic["prompt"].(string); !strings.Contains(prompt, generator.getSimpleWorkflowExamples()) {
+		t.Error("expected anthropic to get the built-in examples")
+	}
+
+	// The fallback provider's request is built with its own templates
+	if openai["system_message"] != "openai system" {
+		t.Errorf("got system message %q, want openai's template", openai["system_message"])
+	}
+	if prompt, _ := openai["prompt"].(string); !strings.Contains(prompt, "openai examples") || strings.Contains(prompt, generator.getSimpleWorkflowExamples()) {
+		t.Errorf("expected openai's examples in place of the built-in ones, got %q", prompt)
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"orchestrator/engine"
 	"orchestrator/models"
 	"strings"
 	"time"
@@ -12,14 +14,30 @@ import (
 
 // RecoveryManager handles workflow recovery and resilience
 type RecoveryManager struct {
-	stateManager       StateManager
-	workflowExecutor   WorkflowExecutor
-	templateManager    TemplateManager
-	recoveryInterval   time.Duration
+	stateManager      StateManager
+	workflowExecutor  WorkflowExecutor
+	templateManager   TemplateLookup
+	recoveryInterval  time.Duration
+	heartbeats        HeartbeatChecker
+	heartbeatInterval time.Duration
+	maxAttempts       int
+	onResponse        func(correlationID string, response *models.WorkflowResponse, err error)
+	eventLog          EventLog
 	logger            *logrus.Logger
 	stopChan          chan bool
 }
 
+// DefaultMaxRecoveryAttempts is how many times recovery starts an execution again before
+// failing it
+const DefaultMaxRecoveryAttempts = 3
+
+// ErrTaskNotWaiting is returned when completing a task of a running execution, or one
+// that already completed
+var ErrTaskNotWaiting = errors.New("task is not waiting for completion")
+
+// ManualCompletionMetadataKey marks a task state whose output was supplied by an operator
+const ManualCompletionMetadataKey = "manually_completed"
+
 // StateManager interface for recovery operations
 type StateManager interface {
 	SaveExecution(ctx context.Context, execution *models.WorkflowExecution) error
@@ -28,26 +46,65 @@ type StateManager interface {
 	ListActiveExecutions(ctx context.Context) ([]string, error)
 	SaveTaskCheckpoint(ctx context.Context, executionID, taskID string, state *models.TaskState) error
 	LoadTaskCheckpoint(ctx context.Context, executionID, taskID string) (*models.TaskState, error)
+	ClaimRecovery(ctx context.Context, executionID string, ttl time.Duration) (bool, error)
 	CleanupExpiredExecutions(ctx context.Context, before time.Time) (int, error)
 }
 
+// HeartbeatChecker reports whether a running execution's heartbeat is still fresh
+type HeartbeatChecker interface {
+	HeartbeatAlive(ctx context.Context, executionID string) (bool, error)
+}
+
+// EventLog records recovery actions in the event log of the executions they affect
+type EventLog interface {
+	AppendEvent(ctx context.Context, event *models.ExecutionEvent) error
+}
+
 // WorkflowExecutor interface for recovery operations
 type WorkflowExecutor interface {
 	ExecuteWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, request *models.WorkflowRequest) (*models.WorkflowResponse, error)
+	ResumeWorkflow(ctx context.Context, workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) (*models.WorkflowResponse, error)
+	IsRunning(executionID string) bool
 }
 
 // NewRecoveryManager creates a new recovery manager
-func NewRecoveryManager(stateManager StateManager, workflowExecutor WorkflowExecutor, templateManager TemplateManager, recoveryInterval time.Duration) *RecoveryManager {
+func NewRecoveryManager(stateManager StateManager, workflowExecutor WorkflowExecutor, templateManager TemplateLookup, recoveryInterval time.Duration) *RecoveryManager {
 	return &RecoveryManager{
 		stateManager:     stateManager,
 		workflowExecutor: workflowExecutor,
 		templateManager:  templateManager,
 		recoveryInterval: recoveryInterval,
-		logger:          logrus.New(),
-		stopChan:        make(chan bool),
+		maxAttempts:      DefaultMaxRecoveryAttempts,
+		logger:           logrus.New(),
+		stopChan:         make(chan bool),
 	}
 }
 
+// SetHeartbeatWatchdog enables recovering running executions whose heartbeat expired,
+// checked every interval. This catches stalled executions far sooner than the
+// activity heuristics of the recovery loop.
+func (rm *RecoveryManager) SetHeartbeatWatchdog(heartbeats HeartbeatChecker, interval time.Duration) {
+	rm.heartbeats = heartbeats
+	rm.heartbeatInterval = interval
+}
+
+// SetMaxRecoveryAttempts sets how many times an execution is restarted or resumed before
+// recovery fails it instead; 0 fails executions without starting them again
+func (rm *RecoveryManager) SetMaxRecoveryAttempts(attempts int) {
+	rm.maxAttempts = attempts
+}
+
+// SetResponseHandler receives the outcome of every execution recovery runs again, so
+// it can be delivered like the outcome of the original run
+func (rm *RecoveryManager) SetResponseHandler(handler func(correlationID string, response *models.WorkflowResponse, err error)) {
+	rm.onResponse = handler
+}
+
+// SetEventLog makes recovery record the actions it takes on executions
+func (rm *RecoveryManager) SetEventLog(log EventLog) {
+	rm.eventLog = log
+}
+
 // Start begins the recovery manager background processes
 func (rm *RecoveryManager) Start(ctx context.Context) {
 	rm.logger.WithField("recovery_interval", rm.recoveryInterval).Info("Starting recovery manager")
@@ -57,6 +114,11 @@ func (rm *RecoveryManager) Start(ctx context.Context) {
 	
 	// Start cleanup loop
 	go rm.cleanupLoop(ctx)
+
+	// Start heartbeat watchdog
+	if rm.heartbeats != nil && rm.heartbeatInterval > 0 {
+		go rm.heartbeatLoop(ctx)
+	}
 }
 
 // Stop stops the recovery manager
@@ -107,6 +169,67 @@ func (rm *RecoveryManager) cleanupLoop(ctx context.Context) {
 	}
 }
 
+// heartbeatLoop periodically recovers executions whose heartbeat expired
+func (rm *RecoveryManager) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(rm.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := rm.checkHeartbeats(ctx); err != nil {
+				rm.logger.WithError(err).Error("Heartbeat check failed")
+			}
+		case <-rm.stopChan:
+			rm.logger.Info("Heartbeat watchdog stopped")
+			return
+		case <-ctx.Done():
+			rm.logger.Info("Heartbeat watchdog cancelled")
+			return
+		}
+	}
+}
+
+// checkHeartbeats recovers running, heartbeat-tracked executions whose heartbeat lapsed.
+// Executions started within their TTL are skipped so a slow first touch isn't fatal.
+func (rm *RecoveryManager) checkHeartbeats(ctx context.Context) error {
+	activeExecutions, err := rm.stateManager.ListActiveExecutions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, executionID := range activeExecutions {
+		execution, err := rm.stateManager.LoadExecution(ctx, executionID)
+		if err != nil {
+			rm.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to load execution for heartbeat check")
+			continue
+		}
+
+		ttlSeconds, tracked := execution.Metadata[engine.HeartbeatTTLMetadataKey].(float64)
+		if !tracked || execution.Status != models.StatusRunning {
+			continue
+		}
+		if time.Since(execution.StartTime) < time.Duration(ttlSeconds*float64(time.Second)) {
+			continue
+		}
+
+		alive, err := rm.heartbeats.HeartbeatAlive(ctx, executionID)
+		if err != nil {
+			rm.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to check execution heartbeat")
+			continue
+		}
+		if alive {
+			continue
+		}
+
+		if _, err := rm.startRecovery(ctx, execution, "heartbeat_expired"); err != nil {
+			rm.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to recover execution with expired heartbeat")
+		}
+	}
+
+	return nil
+}
+
 // performRecovery identifies and recovers failed or stuck executions
 func (rm *RecoveryManager) performRecovery(ctx context.Context) error {
 	rm.logger.Debug("Starting recovery process")
@@ -150,14 +273,37 @@ func (rm *RecoveryManager) recoverExecution(ctx context.Context, executionID str
 		return false, nil
 	}
 
+	return rm.startRecovery(ctx, execution, reason)
+}
+
+// startRecovery restarts, resumes or fails an execution that needs recovery for reason. Every
+// restart or resume counts towards the execution's attempts; once they are used up, the
+// execution fails instead.
+func (rm *RecoveryManager) startRecovery(ctx context.Context, execution *models.WorkflowExecution, reason string) (bool, error) {
 	rm.logger.WithFields(logrus.Fields{
-		"execution_id": executionID,
-		"reason":      reason,
-		"status":      execution.Status,
+		"execution_id": execution.ID,
+		"reason":       reason,
+		"status":       execution.Status,
 	}).Info("Recovering execution")
 
 	// Determine recovery strategy
 	strategy := rm.determineRecoveryStrategy(execution, reason)
+
+	// Never run an execution twice
+	if strategy != "fail" {
+		if owned, err := rm.claimExecution(ctx, execution); err != nil || !owned {
+			return false, err
+		}
+		if attempts := recoveryAttempts(execution); attempts >= rm.maxAttempts {
+			rm.recordRecovery(ctx, execution, reason, "fail")
+			return rm.failExecution(ctx, execution, fmt.Sprintf("recovery_attempts_exhausted:%d", attempts))
+		}
+		if execution.Metadata == nil {
+			execution.Metadata = make(map[string]interface{})
+		}
+		execution.Metadata[engine.RecoveryAttemptsMetadataKey] = recoveryAttempts(execution) + 1
+	}
+	rm.recordRecovery(ctx, execution, reason, strategy)
 	
 	switch strategy {
 	case "restart":
@@ -172,6 +318,52 @@ func (rm *RecoveryManager) recoverExecution(ctx context.Context, executionID str
 	}
 }
 
+// claimExecution reports whether this instance may start an execution again: it must not
+// be running here or have a live heartbeat elsewhere, and no other instance's recovery
+// may have claimed it this interval
+func (rm *RecoveryManager) claimExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
+	fields := logrus.Fields{"execution_id": execution.ID}
+
+	if rm.workflowExecutor.IsRunning(execution.ID) {
+		rm.logger.WithFields(fields).Debug("Execution still running here, not recovering")
+		return false, nil
+	}
+
+	if rm.heartbeats != nil {
+		if _, tracked := execution.Metadata[engine.HeartbeatTTLMetadataKey]; tracked {
+			alive, err := rm.heartbeats.HeartbeatAlive(ctx, execution.ID)
+			if err != nil {
+				return false, fmt.Errorf("failed to check execution heartbeat: %w", err)
+			}
+			if alive {
+				rm.logger.WithFields(fields).Debug("Execution heartbeat is alive, not recovering")
+				return false, nil
+			}
+		}
+	}
+
+	claimed, err := rm.stateManager.ClaimRecovery(ctx, execution.ID, rm.recoveryInterval)
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		rm.logger.WithFields(fields).Debug("Execution already claimed by another instance's recovery")
+	}
+	return claimed, nil
+}
+
+// recoveryAttempts returns how many times recovery has started an execution again; the
+// count reads back from JSON as a float
+func recoveryAttempts(execution *models.WorkflowExecution) int {
+	switch attempts := execution.Metadata[engine.RecoveryAttemptsMetadataKey].(type) {
+	case int:
+		return attempts
+	case float64:
+		return int(attempts)
+	}
+	return 0
+}
+
 // needsRecovery determines if an execution needs recovery
 func (rm *RecoveryManager) needsRecovery(execution *models.WorkflowExecution) (bool, string) {
 	now := time.Now()
@@ -228,7 +420,7 @@ func (rm *RecoveryManager) determineRecoveryStrategy(execution *models.WorkflowE
 	case reason == "execution_timeout":
 		// For execution timeouts, mark as failed
 		return "fail"
-	case reason == "no_recent_activity":
+	case reason == "no_recent_activity", reason == "heartbeat_expired":
 		// For orphaned executions, try to resume
 		return "resume"
 	default:
@@ -236,68 +428,131 @@ func (rm *RecoveryManager) determineRecoveryStrategy(execution *models.WorkflowE
 	}
 }
 
-// restartExecution restarts an execution from the beginning
-func (rm *RecoveryManager) restartExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
-	rm.logger.WithField("execution_id", execution.ID).Info("Restarting execution")
+// recordRecovery logs that recovery acted on an execution, and why. The log is best
+// effort and never holds up recovery.
+func (rm *RecoveryManager) recordRecovery(ctx context.Context, execution *models.WorkflowExecution, reason, strategy string) {
+	if rm.eventLog == nil {
+		return
+	}
 
-	// Reset execution state
-	execution.Status = models.StatusPending
-	execution.EndTime = nil
-	
-	// Reset all task states
-	for taskID := range execution.TaskStates {
-		execution.TaskStates[taskID] = &models.TaskState{
-			ID:         taskID,
-			Status:     models.StatusPending,
-			RetryCount: 0,
-			Output:     make(map[string]interface{}),
-			Metadata:   make(map[string]interface{}),
-		}
+	event := &models.ExecutionEvent{
+		Type:        models.EventRecoveryTriggered,
+		ExecutionID: execution.ID,
+		Timestamp:   time.Now(),
+		Details: map[string]interface{}{
+			"reason":   reason,
+			"strategy": strategy,
+		},
+	}
+	if err := rm.eventLog.AppendEvent(ctx, event); err != nil {
+		rm.logger.WithError(err).WithField("execution_id", execution.ID).Warn("Failed to record recovery event")
 	}
+}
 
-	// Save updated state
-	if err := rm.stateManager.SaveExecution(ctx, execution); err != nil {
-		return false, err
+// restartExecution runs an execution again from the beginning, from the request and
+// definition it was started with
+func (rm *RecoveryManager) restartExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
+	workflow := rm.executionDefinition(execution)
+	if workflow == nil || execution.Request == nil {
+		return rm.failExecution(ctx, execution, "definition_unavailable")
 	}
 
-	// Note: In a real implementation, you would trigger workflow execution here
-	// For now, we just mark it as ready for restart
-	rm.logger.WithField("execution_id", execution.ID).Info("Execution prepared for restart")
+	rm.logger.WithField("execution_id", execution.ID).Info("Restarting execution")
+
+	request := *execution.Request
+	request.ExecutionID = execution.ID
+	request.RecoveryAttempts = recoveryAttempts(execution)
+	request.GenerateFromAI = nil
+	request.DryRun = false
+
+	go func() {
+		response, err := rm.workflowExecutor.ExecuteWorkflow(context.Background(), workflow, &request)
+		rm.recoveredRunFinished(execution, response, err)
+	}()
+
 	return true, nil
 }
 
-// resumeExecution resumes an execution from its last known good state
+// resumeExecution runs an execution again from its last known good state. Completed
+// tasks, including ones restored from checkpoints, keep their output and are not re-run.
 func (rm *RecoveryManager) resumeExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
+	workflow := rm.executionDefinition(execution)
+	if workflow == nil {
+		return rm.failExecution(ctx, execution, "definition_unavailable")
+	}
+
 	rm.logger.WithField("execution_id", execution.ID).Info("Resuming execution")
 
-	// Reset any running tasks to pending
+	// Restore tasks that completed, or were completed by an operator, since the last save
 	for taskID, taskState := range execution.TaskStates {
-		if taskState.Status == models.StatusRunning || taskState.Status == models.StatusRetrying {
-			// Try to load checkpoint
-			checkpoint, err := rm.stateManager.LoadTaskCheckpoint(ctx, execution.ID, taskID)
-			if err == nil && checkpoint != nil {
-				// Restore from checkpoint
-				execution.TaskStates[taskID] = checkpoint
-				rm.logger.WithField("task_id", taskID).Debug("Restored task from checkpoint")
-			} else {
-				// Reset to pending
-				taskState.Status = models.StatusPending
-				taskState.StartTime = nil
-				taskState.Error = ""
-			}
+		if taskState.Status == models.StatusCompleted {
+			continue
+		}
+		checkpoint, err := rm.stateManager.LoadTaskCheckpoint(ctx, execution.ID, taskID)
+		if err == nil && checkpoint != nil && checkpoint.Status == models.StatusCompleted {
+			execution.TaskStates[taskID] = checkpoint
+			rm.logger.WithField("task_id", taskID).Debug("Restored completed task from checkpoint")
 		}
 	}
 
-	// Update execution status
-	execution.Status = models.StatusRunning
+	go func() {
+		response, err := rm.workflowExecutor.ResumeWorkflow(context.Background(), workflow, execution)
+		rm.recoveredRunFinished(execution, response, err)
+	}()
 
-	// Save updated state
-	if err := rm.stateManager.SaveExecution(ctx, execution); err != nil {
-		return false, err
+	return true, nil
+}
+
+// ClaimExecution takes the right to start an execution again outside the recovery loop,
+// for instance after an operator completed one of its tasks. It reports false when the
+// execution is still running or another resume or recovery has claimed it.
+func (rm *RecoveryManager) ClaimExecution(ctx context.Context, execution *models.WorkflowExecution) (bool, error) {
+	return rm.claimExecution(ctx, execution)
+}
+
+// ResumeExecution resumes a claimed execution in the background; its outcome goes to the
+// response handler like that of a recovered run
+func (rm *RecoveryManager) ResumeExecution(workflow *models.WorkflowDefinition, execution *models.WorkflowExecution) {
+	rm.logger.WithField("execution_id", execution.ID).Info("Resuming execution")
+
+	go func() {
+		response, err := rm.workflowExecutor.ResumeWorkflow(context.Background(), workflow, execution)
+		rm.recoveredRunFinished(execution, response, err)
+	}()
+}
+
+// executionDefinition returns the definition an execution runs. Executions saved before
+// definitions were kept with them fall back to their template, unless they pinned a
+// version the latest template may no longer match.
+func (rm *RecoveryManager) executionDefinition(execution *models.WorkflowExecution) *models.WorkflowDefinition {
+	if execution.Workflow != nil {
+		return execution.Workflow
 	}
 
-	rm.logger.WithField("execution_id", execution.ID).Info("Execution prepared for resume")
-	return true, nil
+	templateID, _ := execution.Metadata[engine.TemplateMetadataKey].(string)
+	if _, pinned := execution.Metadata[engine.TemplateVersionMetadataKey]; templateID == "" || pinned || rm.templateManager == nil {
+		return nil
+	}
+	template, err := rm.templateManager.GetTemplate(templateID)
+	if err != nil {
+		rm.logger.WithError(err).WithField("execution_id", execution.ID).Warn("Failed to load template of execution")
+		return nil
+	}
+	return &template.Workflow
+}
+
+// recoveredRunFinished logs and delivers the outcome of an execution recovery ran again
+func (rm *RecoveryManager) recoveredRunFinished(execution *models.WorkflowExecution, response *models.WorkflowResponse, err error) {
+	fields := logrus.Fields{"execution_id": execution.ID}
+	if err != nil {
+		rm.logger.WithError(err).WithFields(fields).Warn("Recovered execution failed")
+	} else {
+		rm.logger.WithFields(fields).Info("Recovered execution completed")
+	}
+
+	if rm.onResponse != nil {
+		rm.onResponse(execution.CorrelationID, response, err)
+	}
 }
 
 // failExecution marks an execution as failed due to recovery conditions
@@ -314,7 +569,7 @@ func (rm *RecoveryManager) failExecution(ctx context.Context, execution *models.
 	execution.Error = fmt.Sprintf("Recovery failure: %s", reason)
 
 	// Mark any running tasks as failed
-	for taskID, taskState := range execution.TaskStates {
+	for _, taskState := range execution.TaskStates {
 		if taskState.Status == models.StatusRunning || taskState.Status == models.StatusRetrying {
 			taskState.Status = models.StatusFailed
 			taskState.EndTime = &now
@@ -363,6 +618,73 @@ func (rm *RecoveryManager) CreateCheckpoint(ctx context.Context, executionID, ta
 	return rm.stateManager.SaveTaskCheckpoint(ctx, executionID, taskID, state)
 }
 
+// CheckTaskCompletable reports whether CompleteTask would accept completing a task,
+// without changing anything. Running executions and completed tasks are rejected with
+// ErrTaskNotWaiting.
+func (rm *RecoveryManager) CheckTaskCompletable(execution *models.WorkflowExecution, taskID string) error {
+	current, exists := execution.TaskStates[taskID]
+	if !exists {
+		return fmt.Errorf("task %s not found in execution %s", taskID, execution.ID)
+	}
+	if execution.Status == models.StatusRunning {
+		return fmt.Errorf("%w: execution %s is still running", ErrTaskNotWaiting, execution.ID)
+	}
+	if current.Status == models.StatusCompleted {
+		return fmt.Errorf("%w: task %s is already completed", ErrTaskNotWaiting, taskID)
+	}
+	return nil
+}
+
+// CompleteTask marks a task completed with an operator-supplied output, so a resumed
+// execution continues past it. The output is checkpointed and written to the execution
+// state. Tasks CheckTaskCompletable rejects are not changed.
+func (rm *RecoveryManager) CompleteTask(ctx context.Context, execution *models.WorkflowExecution, taskID string, output map[string]interface{}) (*models.TaskState, error) {
+	if err := rm.CheckTaskCompletable(execution, taskID); err != nil {
+		return nil, err
+	}
+	current := execution.TaskStates[taskID]
+
+	if output == nil {
+		output = make(map[string]interface{})
+	}
+
+	now := time.Now()
+	startTime := current.StartTime
+	if startTime == nil {
+		startTime = &now
+	}
+
+	state := &models.TaskState{
+		ID:         taskID,
+		Status:     models.StatusCompleted,
+		StartTime:  startTime,
+		EndTime:    &now,
+		Output:     output,
+		RetryCount: current.RetryCount,
+		Metadata: map[string]interface{}{
+			ManualCompletionMetadataKey: true,
+			"previous_status":           current.Status,
+		},
+	}
+
+	if err := rm.CreateCheckpoint(ctx, execution.ID, taskID, state); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint task: %w", err)
+	}
+
+	execution.TaskStates[taskID] = state
+	if err := rm.stateManager.SaveExecution(ctx, execution); err != nil {
+		return nil, fmt.Errorf("failed to save execution: %w", err)
+	}
+
+	rm.logger.WithFields(logrus.Fields{
+		"execution_id":    execution.ID,
+		"task_id":         taskID,
+		"previous_status": current.Status,
+	}).Info("Task completed manually")
+
+	return state, nil
+}
+
 // GetRecoveryStats returns statistics about recovery operations
 func (rm *RecoveryManager) GetRecoveryStats(ctx context.Context) (map[string]interface{}, error) {
 	activeExecutions, err := rm.stateManager.ListActiveExecutions(ctx)
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/clients"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// orphanedExecution returns a running execution with no activity for an hour, which
+// recovery resumes
+func orphanedExecution(id string) *models.WorkflowExecution {
+	execution := newTestExecution("fetch")
+	execution.ID = id
+	execution.Status = models.StatusRunning
+	execution.StartTime = time.Now().Add(-time.Hour)
+	execution.Workflow = &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{{ID: "fetch", Type: "data"}}}
+	execution.Request = &models.WorkflowRequest{CorrelationID: "corr-" + id}
+	return execution
+}
+
+func TestRecoverExecutionResumesAndCountsAttempt(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+
+	recovered, err := rm.recoverExecution(context.Background(), "exec-1")
+	if err != nil || !recovered {
+		t.Fatalf("expected the execution to be recovered, got %v, %v", recovered, err)
+	}
+
+	select {
+	case resumed := <-executor.resumed:
+		if got := recoveryAttempts(resumed); got != 1 {
+			t.Errorf("expected 1 recovery attempt recorded, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execution was not resumed")
+	}
+}
+
+func TestRecoverExecutionFailsAfterMaxAttempts(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	execution.Metadata[engine.RecoveryAttemptsMetadataKey] = float64(2)
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+	rm.SetMaxRecoveryAttempts(2)
+
+	if _, err := rm.recoverExecution(context.Background(), "exec-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if execution.Status != models.StatusFailed || !strings.Contains(execution.Error, "recovery_attempts_exhausted") {
+		t.Errorf("expected the execution to fail after its attempts, got %s: %s", execution.Status, execution.Error)
+	}
+	select {
+	case <-executor.resumed:
+		t.Error("execution was resumed past the attempt cap")
+	default:
+	}
+}
+
+func TestRecoverExecutionSkipsLiveHeartbeat(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	execution.Metadata[engine.HeartbeatTTLMetadataKey] = float64(90)
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+	rm.SetHeartbeatWatchdog(fakeHeartbeats{"exec-1": true}, time.Minute)
+
+	recovered, err := rm.recoverExecution(context.Background(), "exec-1")
+	if err != nil || recovered {
+		t.Fatalf("an execution with a live heartbeat must be left alone, got %v, %v", recovered, err)
+	}
+	if execution.Status != models.StatusRunning {
+		t.Errorf("expected the execution to stay running, got %s", execution.Status)
+	}
+}
+
+func TestRecoverExecutionRunsOnlyOncePerClaim(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	executor := newFakeWorkflowExecutor()
+	state := newMemoryStateManager(execution)
+
+	first := NewRecoveryManager(state, executor, nil, time.Minute)
+	second := NewRecoveryManager(state, executor, nil, time.Minute)
+
+	if recovered, _ := first.recoverExecution(context.Background(), "exec-1"); !recovered {
+		t.Fatal("expected the first instance to recover the execution")
+	}
+	if recovered, _ := second.recoverExecution(context.Background(), "exec-1"); recovered {
+		t.Error("a second instance must not recover an execution already claimed")
+	}
+}
+
+func TestRecoverExecutionSkipsLocallyRunning(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	executor := newFakeWorkflowExecutor()
+	executor.running["exec-1"] = true
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+
+	if recovered, _ := rm.recoverExecution(context.Background(), "exec-1"); recovered {
+		t.Error("an execution running on this instance must not be recovered")
+	}
+}
+
+func TestRestartExecutionCarriesAttempts(t *testing.T) {
+	execution := orphanedExecution("exec-1")
+	execution.Metadata[engine.RecoveryAttemptsMetadataKey] = 1
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+
+	if _, err := rm.restartExecution(context.Background(), execution); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case request := <-executor.started:
+		if request.ExecutionID != "exec-1" || request.RecoveryAttempts != 1 {
+			t.Errorf("expected a restart of exec-1 with 1 attempt, got %s with %d", request.ExecutionID, request.RecoveryAttempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execution was not restarted")
+	}
+}
+
+func TestCheckHeartbeatsResumesStalledExecution(t *testing.T) {
+	stalled := orphanedExecution("exec-stalled")
+	stalled.Metadata[engine.HeartbeatTTLMetadataKey] = float64(30)
+	stalled.StartTime = time.Now().Add(-2 * time.Minute) // long before the recovery loop would act
+	alive := orphanedExecution("exec-alive")
+	alive.Metadata[engine.HeartbeatTTLMetadataKey] = float64(30)
+	fresh := orphanedExecution("exec-fresh")
+	fresh.Metadata[engine.HeartbeatTTLMetadataKey] = float64(30)
+	fresh.StartTime = time.Now() // within its TTL, the first heartbeat may not be written yet
+	untracked := orphanedExecution("exec-untracked")
+
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(stalled, alive, fresh, untracked), executor, nil, time.Minute)
+	rm.SetHeartbeatWatchdog(fakeHeartbeats{"exec-alive": true}, time.Second)
+
+	if err := rm.checkHeartbeats(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case resumed := <-executor.resumed:
+		if resumed.ID != "exec-stalled" || recoveryAttempts(resumed) != 1 {
+			t.Errorf("got %s resumed after %d attempts, want exec-stalled after 1", resumed.ID, recoveryAttempts(resumed))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stalled execution was not resumed")
+	}
+	select {
+	case resumed := <-executor.resumed:
+		t.Errorf("got %s resumed, want only the stalled execution", resumed.ID)
+	default:
+	}
+	for _, execution := range []*models.WorkflowExecution{stalled, alive, fresh, untracked} {
+		if execution.Status != models.StatusRunning {
+			t.Errorf("%s: expected to stay running, got %s", execution.ID, execution.Status)
+		}
+	}
+}
+
+func TestCheckHeartbeatsFailsAfterMaxAttempts(t *testing.T) {
+	stalled := orphanedExecution("exec-stalled")
+	stalled.Metadata[engine.HeartbeatTTLMetadataKey] = float64(30)
+	stalled.Metadata[engine.RecoveryAttemptsMetadataKey] = float64(3)
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(stalled), executor, nil, time.Minute)
+	rm.SetHeartbeatWatchdog(fakeHeartbeats{}, time.Second)
+
+	if err := rm.checkHeartbeats(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if stalled.Status != models.StatusFailed || !strings.Contains(stalled.Error, "recovery_attempts_exhausted:3") {
+		t.Errorf("expected the stalled execution to fail after its attempts, got %s: %s", stalled.Status, stalled.Error)
+	}
+	select {
+	case <-executor.resumed:
+		t.Error("execution was resumed past the attempt cap")
+	default:
+	}
+}
+
+func TestCompletedTaskUnblocksResumedWorkflow(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		if request.Service == "data" {
+			return &models.ServiceResponse{Success: false, Error: "backend down"}, nil
+		}
+		return &models.ServiceResponse{Success: true, Data: map[string]interface{}{"text": "summary"}}, nil
+	}}
+	states := newMemoryStateManager()
+	executor := engine.NewWorkflowExecutor(NewTaskExecutor(coordinator), states, nil, 1)
+	workflow := &models.WorkflowDefinition{ID: "wf", Tasks: []models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}},
+		{ID: "summarize", Type: "ai", DependsOn: []string{"fetch"}, Parameters: map[string]interface{}{"prompt": "${tasks.fetch.output.text}"}},
+	}}
+
+	failed, _ := executor.ExecuteWorkflow(context.Background(), workflow, &models.WorkflowRequest{})
+	execution, err := states.LoadExecution(context.Background(), failed.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.Status != models.StatusFailed {
+		t.Fatalf("got status %s, want the first run to fail at fetch", execution.Status)
+	}
+
+	rm := NewRecoveryManager(states, executor, nil, time.Minute)
+	done := make(chan *models.WorkflowResponse, 1)
+	rm.SetResponseHandler(func(correlationID string, response *models.WorkflowResponse, err error) {
+		done <- response
+	})
+
+	claimed, err := rm.ClaimExecution(context.Background(), execution)
+	if err != nil || !claimed {
+		t.Fatalf("got %v, %v, want the stopped execution claimed", claimed, err)
+	}
+	if _, err := rm.CompleteTask(context.Background(), execution, "fetch", map[string]interface{}{"text": "fixed by hand"}); err != nil {
+		t.Fatal(err)
+	}
+	rm.ResumeExecution(workflow, execution)
+
+	select {
+	case response := <-done:
+		if response == nil || !response.Success {
+			t.Fatalf("got %+v, want the resumed workflow to complete", response)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("resumed workflow did not finish")
+	}
+
+	last := coordinator.requests[len(coordinator.requests)-1]
+	if last.Service != "ai" || last.Parameters["prompt"] != "fixed by hand" {
+		t.Errorf("got last request %+v, want summarize to run on the supplied output", last)
+	}
+	if execution.TaskStates["summarize"].Status != models.StatusCompleted {
+		t.Errorf("got summarize %s, want completed", execution.TaskStates["summarize"].Status)
+	}
+}
+
+func TestCompleteTaskRejectsTasksNotWaiting(t *testing.T) {
+	execution := newTestExecution("fetch", "summarize")
+	execution.Status = models.StatusFailed
+	execution.TaskStates["fetch"].Status = models.StatusCompleted
+	running := newTestExecution("fetch")
+	running.ID = "exec-running"
+	running.Status = models.StatusRunning
+	rm := NewRecoveryManager(newMemoryStateManager(execution, running), newFakeWorkflowExecutor(), nil, time.Minute)
+
+	for _, c := range []struct {
+		execution *models.WorkflowExecution
+		taskID    string
+	}{
+		{execution, "fetch"},
+		{running, "fetch"},
+	} {
+		if err := rm.CheckTaskCompletable(c.execution, c.taskID); !errors.Is(err, ErrTaskNotWaiting) {
+			t.Errorf("%s/%s: got %v, want ErrTaskNotWaiting", c.execution.ID, c.taskID, err)
+		}
+		if _, err := rm.CompleteTask(context.Background(), c.execution, c.taskID, nil); !errors.Is(err, ErrTaskNotWaiting) {
+			t.Errorf("%s/%s: got %v from CompleteTask, want ErrTaskNotWaiting", c.execution.ID, c.taskID, err)
+		}
+	}
+
+	if err := rm.CheckTaskCompletable(execution, "summarize"); err != nil {
+		t.Errorf("got %v, want a pending task of a stopped execution completable", err)
+	}
+	if err := rm.CheckTaskCompletable(execution, "missing"); err == nil || errors.Is(err, ErrTaskNotWaiting) {
+		t.Errorf("got %v, want an unknown task reported as not found", err)
+	}
+}
+
+func TestClaimExecutionAllowsOneResume(t *testing.T) {
+	execution := newTestExecution("fetch")
+	execution.Status = models.StatusFailed
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(newMemoryStateManager(execution), executor, nil, time.Minute)
+
+	if claimed, err := rm.ClaimExecution(context.Background(), execution); err != nil || !claimed {
+		t.Fatalf("first claim: got %v, %v, want claimed", claimed, err)
+	}
+	if claimed, _ := rm.ClaimExecution(context.Background(), execution); claimed {
+		t.Error("second claim: want the execution already claimed")
+	}
+
+	running := newTestExecution("fetch")
+	running.ID = "exec-running"
+	executor.running[running.ID] = true
+	if claimed, _ := rm.ClaimExecution(context.Background(), running); claimed {
+		t.Error("want an execution running here not to be claimed")
+	}
+}
+
+func TestRecoveryResumesStoredDefinitionAfterRestart(t *testing.T) {
+	client, _ := newMiniRedisClient(t)
+	ctx := context.Background()
+
+	execution := orphanedExecution("exec-1")
+	execution.Workflow = &models.WorkflowDefinition{ID: "report", Tasks: []models.Task{
+		{ID: "fetch", Type: "data"},
+		{ID: "summarize", Type: "ai", DependsOn: []string{"fetch"}},
+	}}
+	execution.Metadata[engine.TemplateMetadataKey] = "report"
+	if err := clients.NewRedisStateManager(client, "test", time.Hour).SaveExecution(ctx, execution); err != nil {
+		t.Fatal(err)
+	}
+
+	// The template changed since the execution started
+	templates := NewTemplateManager(t.TempDir())
+	changed := newTestTemplate("report")
+	changed.Workflow.Tasks = []models.Task{{ID: "replacement", Type: "exec"}}
+	if err := templates.CreateTemplate(changed); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted orchestrator only has what Redis kept
+	executor := newFakeWorkflowExecutor()
+	rm := NewRecoveryManager(clients.NewRedisStateManager(client, "test", time.Hour), executor, templates, time.Minute)
+	if recovered, err := rm.recoverExecution(ctx, "exec-1"); err != nil || !recovered {
+		t.Fatalf("expected the execution to be recovered, got %v, %v", recovered, err)
+	}
+
+	select {
+	case workflow := <-executor.resumedWorkflows:
+		var ids []string
+		for _, task := range workflow.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if strings.Join(ids, ",") != "fetch,summarize" {
+			t.Errorf("resumed with tasks %v, want the stored definition's", ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("execution was not resumed")
+	}
+}
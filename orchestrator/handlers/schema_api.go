@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"orchestrator/models"
+
+	"github.com/gorilla/mux"
+)
+
+// definitionSchemas are the JSON Schemas served under /schema/{name}
+var definitionSchemas = map[string]func() map[string]interface{}{
+	"workflow":  models.WorkflowSchema,
+	"template":  models.TemplateSchema,
+	"execution": models.ExecutionSchema,
+}
+
+// SchemaAPI serves JSON Schemas of the definition models, for clients to validate against
+type SchemaAPI struct{}
+
+// NewSchemaAPI creates the schema endpoint
+func NewSchemaAPI() *SchemaAPI {
+	return &SchemaAPI{}
+}
+
+// RegisterRoutes adds GET /schema/{name} to the API router
+func (a *SchemaAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/schema/{name}", a.handleGetSchema).Methods("GET")
+}
+
+func (a *SchemaAPI) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	schema, exists := definitionSchemas[name]
+	if !exists {
+		http.Error(w, "Schema not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(schema())
+}
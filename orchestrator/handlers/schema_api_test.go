@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetSchema(t *testing.T) {
+	api := NewSchemaAPI()
+
+	for name := range definitionSchemas {
+		recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/schema/"+name, nil))
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/schema+json" {
+			t.Errorf("%s: got %d %q", name, recorder.Code, recorder.Header().Get("Content-Type"))
+			continue
+		}
+		var schema map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&schema); err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if schema["$schema"] == nil || schema["$ref"] == nil {
+			t.Errorf("%s: not a JSON Schema: %v", name, schema)
+		}
+	}
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/schema/unknown", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown schema: got %d, want 404", recorder.Code)
+	}
+}
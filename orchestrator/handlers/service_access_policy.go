@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"orchestrator/models"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Service access policy modes for exec tasks requesting services they are not granted
+const (
+	ServiceAccessStrip  = "strip"  // drop the disallowed services and run the task (default)
+	ServiceAccessReject = "reject" // fail the task before it is dispatched
+)
+
+// ServiceAccessRule grants services to workflows matching every selector it sets
+type ServiceAccessRule struct {
+	Workflows []string          `yaml:"workflows,omitempty"` // workflow IDs; empty matches any workflow
+	Labels    map[string]string `yaml:"labels,omitempty"`    // labels the workflow must carry
+	Allow     []string          `yaml:"allow"`               // services granted, e.g. data, ai
+}
+
+// ServiceAccessPolicy decides which services an exec task's container may reach through
+// service_access. A workflow is granted the default services plus those of every rule it
+// matches.
+type ServiceAccessPolicy struct {
+	Mode    string              `yaml:"mode,omitempty"`
+	Default []string            `yaml:"default,omitempty"`
+	Rules   []ServiceAccessRule `yaml:"rules"`
+}
+
+// LoadServiceAccessPolicy reads a policy from a YAML file
+func LoadServiceAccessPolicy(path string) (*ServiceAccessPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service access policy: %w", err)
+	}
+
+	var policy ServiceAccessPolicy
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse service access policy: %w", err)
+	}
+
+	switch policy.Mode {
+	case "":
+		policy.Mode = ServiceAccessStrip
+	case ServiceAccessStrip, ServiceAccessReject:
+	default:
+		return nil, fmt.Errorf("unknown service access policy mode %q", policy.Mode)
+	}
+
+	return &policy, nil
+}
+
+// Allowed returns the services granted to a workflow
+func (p *ServiceAccessPolicy) Allowed(workflowID string, labels map[string]string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, service := range p.Default {
+		allowed[service] = true
+	}
+
+	for _, rule := range p.Rules {
+		if !rule.matches(workflowID, labels) {
+			continue
+		}
+		for _, service := range rule.Allow {
+			allowed[service] = true
+		}
+	}
+
+	return allowed
+}
+
+func (r *ServiceAccessRule) matches(workflowID string, labels map[string]string) bool {
+	if len(r.Workflows) > 0 {
+		listed := false
+		for _, id := range r.Workflows {
+			if id == workflowID {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return false
+		}
+	}
+	for key, value := range r.Labels {
+		if actual, exists := labels[key]; !exists || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SetServiceAccessPolicy restricts the service_access exec tasks may request. Without a
+// policy every request is passed through.
+func (te *TaskExecutorImpl) SetServiceAccessPolicy(policy *ServiceAccessPolicy) {
+	te.serviceAccessPolicy = policy
+}
+
+// enforceServiceAccess checks the service_access of an exec request against the policy,
+// removing disallowed services or rejecting the task depending on the policy mode
+func (te *TaskExecutorImpl) enforceServiceAccess(params map[string]interface{}, task *models.Task, execution *models.WorkflowExecution) error {
+	policy := te.serviceAccessPolicy
+	if policy == nil {
+		return nil
+	}
+
+	raw, exists := params["service_access"]
+	if !exists {
+		return nil
+	}
+	requested, err := stringList(raw)
+	if err != nil {
+		return fmt.Errorf("invalid service_access: %w", err)
+	}
+
+	allowed := policy.Allowed(execution.WorkflowID, execution.Labels)
+	var granted, denied []string
+	for _, service := range requested {
+		if allowed[service] {
+			granted = append(granted, service)
+		} else {
+			denied = append(denied, service)
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+
+	sort.Strings(denied)
+	if policy.Mode == ServiceAccessReject {
+		return fmt.Errorf("service access denied for workflow %s: %s", execution.WorkflowID, strings.Join(denied, ", "))
+	}
+
+	te.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"workflow_id":  execution.WorkflowID,
+		"task_id":      task.ID,
+		"denied":       denied,
+	}).Warn("Removed service access not granted to workflow")
+
+	params["service_access"] = granted
+	if taskState := execution.TaskStates[task.ID]; taskState != nil {
+		if taskState.Metadata == nil {
+			taskState.Metadata = make(map[string]interface{})
+		}
+		taskState.Metadata["service_access_denied"] = denied
+	}
+	return nil
+}
+
+// stringList converts a decoded JSON or YAML list of strings
+func stringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %T", item)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %T", value)
+	}
+}
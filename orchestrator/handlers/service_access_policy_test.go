@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writePolicy writes a service access policy file and loads it
+func writePolicy(t *testing.T, content string) (*ServiceAccessPolicy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadServiceAccessPolicy(path)
+}
+
+const labelPolicy = `
+default: [data]
+rules:
+  - labels: {team: analytics, tier: trusted}
+    allow: [ai]
+`
+
+// runServiceAccessTask runs an exec task requesting data and AI access in an execution
+// with the given labels, returning the services sent to the exec agent
+func runServiceAccessTask(t *testing.T, policy *ServiceAccessPolicy, labels map[string]string) ([]string, *models.TaskState, error) {
+	t.Helper()
+	coordinator := &fakeCoordinator{}
+	executor := NewTaskExecutor(coordinator)
+	executor.SetServiceAccessPolicy(policy)
+
+	execution := newTestExecution("process")
+	execution.WorkflowID = "report"
+	execution.Labels = labels
+	task := &models.Task{ID: "process", Type: "exec", Parameters: map[string]interface{}{
+		"container":      map[string]interface{}{"image": "tools:1"},
+		"service_access": []interface{}{"data", "ai"},
+	}}
+
+	err := executor.ExecuteTask(context.Background(), task, execution)
+	if len(coordinator.requests) == 0 {
+		return nil, execution.TaskStates["process"], err
+	}
+	services, listErr := stringList(coordinator.requests[0].Parameters["service_access"])
+	if listErr != nil {
+		t.Fatal(listErr)
+	}
+	return services, execution.TaskStates["process"], err
+}
+
+func TestServiceAccessGrantedByMatchingLabels(t *testing.T) {
+	policy, err := writePolicy(t, labelPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services, state, err := runServiceAccessTask(t, policy, map[string]string{"team": "analytics", "tier": "trusted", "env": "prod"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(services, []string{"data", "ai"}) {
+		t.Errorf("got services %v, want both granted", services)
+	}
+	if _, denied := state.Metadata["service_access_denied"]; denied {
+		t.Error("expected nothing recorded as denied")
+	}
+}
+
+func TestServiceAccessStrippedWithoutMatchingLabels(t *testing.T) {
+	policy, err := writePolicy(t, labelPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every label of a rule must match, not just some
+	services, state, err := runServiceAccessTask(t, policy, map[string]string{"team": "analytics"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(services, []string{"data"}) {
+		t.Errorf("got services %v, want only the default", services)
+	}
+	if denied := state.Metadata["service_access_denied"]; !reflect.DeepEqual(denied, []string{"ai"}) {
+		t.Errorf("got denied %v, want ai", denied)
+	}
+}
+
+func TestServiceAccessRejectedInRejectMode(t *testing.T) {
+	policy, err := writePolicy(t, "mode: reject\n"+labelPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services, _, err := runServiceAccessTask(t, policy, map[string]string{"team": "finance", "tier": "trusted"})
+	if err == nil || !strings.Contains(err.Error(), "service access denied for workflow report: ai") {
+		t.Errorf("expected the task to be rejected, got %v", err)
+	}
+	if services != nil {
+		t.Errorf("expected nothing sent to the exec agent, got %v", services)
+	}
+}
+
+func TestLoadServiceAccessPolicyRejectsUnknownMode(t *testing.T) {
+	if _, err := writePolicy(t, "mode: warn\nrules: []\n"); err == nil || !strings.Contains(err.Error(), `unknown service access policy mode "warn"`) {
+		t.Errorf("expected an unknown mode to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"orchestrator/clients"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ServiceEventSource delivers service lifecycle events to subscribers. It is satisfied by
+// clients.ServiceRegistry.
+type ServiceEventSource interface {
+	SubscribeEvents() (<-chan clients.ServiceLifecycleEvent, func())
+}
+
+// ServiceEventsAPI streams service lifecycle events as server-sent events
+type ServiceEventsAPI struct {
+	source ServiceEventSource
+}
+
+// NewServiceEventsAPI creates the service event stream endpoint
+func NewServiceEventsAPI(source ServiceEventSource) *ServiceEventsAPI {
+	return &ServiceEventsAPI{source: source}
+}
+
+// RegisterRoutes adds GET /services/events to the API router
+func (a *ServiceEventsAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/services/events", a.handleServiceEvents).Methods("GET")
+}
+
+func (a *ServiceEventsAPI) handleServiceEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := a.source.SubscribeEvents()
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
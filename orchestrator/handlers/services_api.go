@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"orchestrator/clients"
+
+	"github.com/gorilla/mux"
+)
+
+// ServiceDirectory summarizes the services known from capability announcements.
+// It is satisfied by clients.ServiceRegistry.
+type ServiceDirectory interface {
+	GetStats() clients.ServiceRegistryStats
+}
+
+// ServicesAPI lists the services the orchestrator can dispatch to
+type ServicesAPI struct {
+	registry ServiceDirectory
+}
+
+// NewServicesAPI creates the service listing endpoint
+func NewServicesAPI(registry ServiceDirectory) *ServicesAPI {
+	return &ServicesAPI{registry: registry}
+}
+
+// RegisterRoutes adds GET /services to the API router
+func (a *ServicesAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/services", a.handleListServices).Methods("GET")
+}
+
+func (a *ServicesAPI) handleListServices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.registry.GetStats())
+}
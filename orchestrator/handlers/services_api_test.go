@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/clients"
+	"testing"
+)
+
+// fakeDirectory returns fixed registry stats
+type fakeDirectory clients.ServiceRegistryStats
+
+func (f fakeDirectory) GetStats() clients.ServiceRegistryStats {
+	return clients.ServiceRegistryStats(f)
+}
+
+func TestListServicesReturnsRegistryStats(t *testing.T) {
+	api := NewServicesAPI(fakeDirectory{
+		TotalServices:  2,
+		ActiveServices: 1,
+		StaleServices:  1,
+		ServiceSummary: map[string]clients.ServiceSummary{
+			"data": {Component: "data", OperationCount: 4, IsActive: true},
+		},
+	})
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/services", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+
+	var stats clients.ServiceRegistryStats
+	if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalServices != 2 || stats.ActiveServices != 1 || stats.ServiceSummary["data"].OperationCount != 4 {
+		t.Errorf("got %+v", stats)
+	}
+}
+
+func TestListServicesOnlyAllowsGet(t *testing.T) {
+	api := NewServicesAPI(fakeDirectory{})
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodPost, "/api/v1/services", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got %d, want 405", recorder.Code)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxSubWorkflowDepth bounds how deeply workflow tasks may nest executions
+const DefaultMaxSubWorkflowDepth = 5
+
+// SubWorkflowTemplates looks up the templates workflow tasks run
+type SubWorkflowTemplates interface {
+	GetTemplate(id string) (*models.Template, error)
+	GetTemplateVersion(id, version string) (*models.Template, error)
+}
+
+// SetSubWorkflows enables workflow tasks, which run a template as a nested execution
+// through the given executor. maxDepth limits nesting below the root execution; 0 uses
+// DefaultMaxSubWorkflowDepth.
+func (te *TaskExecutorImpl) SetSubWorkflows(templates SubWorkflowTemplates, executor WorkflowExecutor, maxDepth int) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxSubWorkflowDepth
+	}
+	te.subWorkflowTemplates = templates
+	te.subWorkflowExecutor = executor
+	te.maxSubWorkflowDepth = maxDepth
+}
+
+// executeSubWorkflowTask runs the template named by a workflow task as a nested execution
+// and makes its task results the task's output. The task's variables parameter, already
+// interpolated, is the nested execution's request variables.
+func (te *TaskExecutorImpl) executeSubWorkflowTask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
+	if te.subWorkflowTemplates == nil || te.subWorkflowExecutor == nil {
+		return fmt.Errorf("workflow tasks are not enabled")
+	}
+
+	templateID, _ := task.Parameters["template"].(string)
+	version, _ := task.Parameters["version"].(string)
+	variables, _ := task.Parameters["variables"].(map[string]interface{})
+
+	// The static checks can't see templates changed since, so guard the running chain too
+	stack := engine.WorkflowStack(execution)
+	for _, enclosing := range stack {
+		if enclosing == templateID {
+			return fmt.Errorf("sub-workflow cycle: %s", strings.Join(append(stack, templateID), " -> "))
+		}
+	}
+	if len(stack) > te.maxSubWorkflowDepth {
+		return fmt.Errorf("sub-workflow depth limit of %d exceeded: %s", te.maxSubWorkflowDepth, strings.Join(append(stack, templateID), " -> "))
+	}
+
+	var template *models.Template
+	var err error
+	if version == "" {
+		template, err = te.subWorkflowTemplates.GetTemplate(templateID)
+	} else {
+		template, err = te.subWorkflowTemplates.GetTemplateVersion(templateID, version)
+	}
+	if err != nil {
+		return fmt.Errorf("sub-workflow template: %w", err)
+	}
+
+	if err := CheckPreconditions(template, variables); err != nil {
+		return err
+	}
+
+	request := &models.WorkflowRequest{
+		CorrelationID:     execution.CorrelationID,
+		WorkflowTemplate:  template.ID,
+		TemplateVersion:   template.Version,
+		Variables:         ApplyVariableDefaults(template, variables),
+		ParentExecutionID: execution.ID,
+		WorkflowStack:     append(append([]string(nil), stack...), template.ID),
+	}
+
+	te.logger.WithFields(logrus.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+		"template_id":  template.ID,
+		"version":      template.Version,
+		"stack":        request.WorkflowStack,
+	}).Info("Starting sub-workflow")
+
+	response, err := te.subWorkflowExecutor.ExecuteWorkflow(ctx, &template.Workflow, request)
+	if response != nil {
+		execution.TaskStates[task.ID].Output = map[string]interface{}{
+			"execution_id": response.ExecutionID,
+			"template_id":  template.ID,
+			"version":      template.Version,
+			"status":       string(response.Status),
+			"task_results": response.TaskResults,
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("sub-workflow %s failed: %w", template.ID, err)
+	}
+
+	return nil
+}
+
+// validateSubWorkflowTask checks that a workflow task names a template that exists and
+// that following workflow tasks from it never leads back to a template already on the way
+func (te *TaskExecutorImpl) validateSubWorkflowTask(task *models.Task) error {
+	if te.subWorkflowTemplates == nil {
+		return nil
+	}
+
+	templateID, _ := task.Parameters["template"].(string)
+	version, _ := task.Parameters["version"].(string)
+	if version != "" {
+		if _, err := te.subWorkflowTemplates.GetTemplateVersion(templateID, version); err != nil {
+			return fmt.Errorf("workflow task template: %w", err)
+		}
+	}
+
+	lookup := func(id string) *models.Template {
+		template, err := te.subWorkflowTemplates.GetTemplate(id)
+		if err != nil {
+			return nil
+		}
+		return template
+	}
+	if lookup(templateID) == nil {
+		return fmt.Errorf("workflow task template %s not found", templateID)
+	}
+	if cycle := SubWorkflowCycle(templateID, lookup); cycle != nil {
+		return fmt.Errorf("sub-workflow cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// SubWorkflowCycle follows the workflow tasks of templates from start and returns the
+// first chain of template IDs that loops back on itself, or nil. Templates lookup can't
+// find are treated as leaves.
+func SubWorkflowCycle(start string, lookup func(id string) *models.Template) []string {
+	var path []string
+	onPath := make(map[string]bool)
+	done := make(map[string]bool)
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		if onPath[id] {
+			return append(append([]string(nil), path...), id)
+		}
+		if done[id] {
+			return nil
+		}
+
+		template := lookup(id)
+		if template == nil {
+			return nil
+		}
+
+		path = append(path, id)
+		onPath[id] = true
+		for _, referenced := range subWorkflowReferences(template) {
+			if cycle := visit(referenced); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[id] = false
+		done[id] = true
+		return nil
+	}
+
+	return visit(start)
+}
+
+// subWorkflowReferences returns the templates a template's workflow tasks run
+func subWorkflowReferences(template *models.Template) []string {
+	var referenced []string
+	for _, task := range template.Workflow.Tasks {
+		if task.Type != engine.SubWorkflowTaskType {
+			continue
+		}
+		if templateID, ok := task.Parameters["template"].(string); ok && templateID != "" {
+			referenced = append(referenced, templateID)
+		}
+	}
+	return referenced
+}
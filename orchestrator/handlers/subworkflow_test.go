@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// newSubWorkflowRunner returns a workflow executor whose workflow tasks run templates
+// from a fresh template manager, with service tasks answered by coordinator
+func newSubWorkflowRunner(t *testing.T, coordinator *fakeCoordinator, templates ...*models.Template) *engine.WorkflowExecutor {
+	t.Helper()
+	manager := NewTemplateManager(t.TempDir())
+	for _, template := range templates {
+		if err := manager.CreateTemplate(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	taskExecutor := NewTaskExecutor(coordinator)
+	executor := engine.NewWorkflowExecutor(taskExecutor, newMemoryStateManager(), coordinator, 2)
+	taskExecutor.SetSubWorkflows(manager, executor, 0)
+	return executor
+}
+
+// enrichTemplate returns a template whose only task is a data query
+func enrichTemplate() *models.Template {
+	return &models.Template{ID: "enrich", Name: "enrich", Workflow: models.WorkflowDefinition{
+		Tasks: []models.Task{{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}}},
+	}}
+}
+
+// parentWorkflow runs enrich as a workflow task and summarizes what the child fetched
+func parentWorkflow() *models.WorkflowDefinition {
+	return &models.WorkflowDefinition{ID: "parent", Tasks: []models.Task{
+		{ID: "child", Type: engine.SubWorkflowTaskType, Parameters: map[string]interface{}{"template": "enrich"}},
+		{ID: "report", Type: "ai", DependsOn: []string{"child"}, Parameters: map[string]interface{}{
+			"prompt": "summarize ${tasks.child.output.task_results.fetch.rows} rows",
+		}},
+	}}
+}
+
+func TestSubWorkflowOutputReachesParentTasks(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		response := &models.ServiceResponse{Success: true, Service: request.Service, Data: map[string]interface{}{}}
+		if request.Service == "data" {
+			response.Data["rows"] = 42
+		}
+		return response, nil
+	}}
+	executor := newSubWorkflowRunner(t, coordinator, enrichTemplate())
+
+	response, err := executor.ExecuteWorkflow(context.Background(), parentWorkflow(), &models.WorkflowRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	child, ok := response.TaskResults["child"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got no output for the workflow task: %v", response.TaskResults)
+	}
+	if child["template_id"] != "enrich" || child["status"] != string(models.StatusCompleted) || child["execution_id"] == "" {
+		t.Errorf("unexpected workflow task output %v", child)
+	}
+
+	var prompt interface{}
+	for _, request := range coordinator.requests {
+		if request.Service == "ai" {
+			prompt = request.Parameters["prompt"]
+		}
+	}
+	if prompt != "summarize 42 rows" {
+		t.Errorf("got prompt %v, want the child's rows interpolated", prompt)
+	}
+}
+
+func TestSubWorkflowFailureFailsParentTask(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		return &models.ServiceResponse{Success: false, Service: request.Service, Error: "no such collection"}, nil
+	}}
+	executor := newSubWorkflowRunner(t, coordinator, enrichTemplate())
+
+	response, err := executor.ExecuteWorkflow(context.Background(), parentWorkflow(), &models.WorkflowRequest{})
+	if err == nil {
+		t.Fatal("expected the child's failure to fail the parent")
+	}
+	if response.Status != models.StatusFailed {
+		t.Errorf("got parent status %s, want failed", response.Status)
+	}
+
+	for _, request := range coordinator.requests {
+		if request.Service == "ai" {
+			t.Error("expected the task depending on the failed workflow task not to run")
+		}
+	}
+	if !strings.Contains(err.Error(), "sub-workflow enrich failed") || !strings.Contains(err.Error(), "no such collection") {
+		t.Errorf("got error %v, want the child's failure reported against the workflow task", err)
+	}
+}
+
+func TestSubWorkflowGuardsRunningChain(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	if err := manager.CreateTemplate(enrichTemplate()); err != nil {
+		t.Fatal(err)
+	}
+	workflows := newFakeWorkflowExecutor()
+	executor := NewTaskExecutor(&fakeCoordinator{})
+	executor.SetSubWorkflows(manager, workflows, 2)
+
+	cases := map[string][]string{
+		"sub-workflow cycle: report -> enrich -> enrich":                   {"report", "enrich"},
+		"depth limit of 2 exceeded: report -> summary -> digest -> enrich": {"report", "summary", "digest"},
+	}
+	for want, stack := range cases {
+		execution := newTestExecution("child")
+		execution.Metadata[engine.WorkflowStackMetadataKey] = stack
+		task := &models.Task{ID: "child", Type: engine.SubWorkflowTaskType, Parameters: map[string]interface{}{"template": "enrich"}}
+
+		err := executor.ExecuteTask(context.Background(), task, execution)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("stack %v: expected error containing %q, got %v", stack, want, err)
+		}
+	}
+
+	select {
+	case request := <-workflows.started:
+		t.Errorf("expected no nested execution to start, got one for %s", request.WorkflowTemplate)
+	default:
+	}
+}
+
+func TestTemplateManagerRejectsSubWorkflowCycle(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	outer := newTestTemplate("outer")
+	outer.Workflow.Tasks = []models.Task{{ID: "run", Type: engine.SubWorkflowTaskType, Parameters: map[string]interface{}{"template": "inner"}}}
+	if err := manager.CreateTemplate(outer); err != nil {
+		t.Fatal(err)
+	}
+
+	inner := newTestTemplate("inner")
+	inner.Workflow.Tasks = []models.Task{{ID: "run", Type: engine.SubWorkflowTaskType, Parameters: map[string]interface{}{"template": "outer"}}}
+	err := manager.CreateTemplate(inner)
+	if !errors.Is(err, ErrInvalidTemplate) || !strings.Contains(err.Error(), "inner -> outer -> inner") {
+		t.Errorf("expected the cycle to be rejected, got %v", err)
+	}
+}
@@ -3,15 +3,23 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"orchestrator/engine"
 	"orchestrator/models"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 // TaskExecutorImpl implements the TaskExecutor interface
 type TaskExecutorImpl struct {
-	messageCoordinator MessageCoordinator
-	logger            *logrus.Logger
+	messageCoordinator   MessageCoordinator
+	serviceAccessPolicy  *ServiceAccessPolicy
+	subWorkflowTemplates SubWorkflowTemplates
+	subWorkflowExecutor  WorkflowExecutor
+	maxSubWorkflowDepth  int
+	logger               *logrus.Logger
 }
 
 // NewTaskExecutor creates a new task executor
@@ -33,7 +41,7 @@ func (te *TaskExecutorImpl) ExecuteTask(ctx context.Context, task *models.Task,
 	}).Info("Executing task")
 
 	var err error
-	
+
 	switch task.Type {
 	case "data":
 		err = te.executeDataTask(ctx, task, execution)
@@ -45,6 +53,8 @@ func (te *TaskExecutorImpl) ExecuteTask(ctx context.Context, task *models.Task,
 		err = te.executeParallelTask(ctx, task, execution)
 	case "condition":
 		err = te.executeConditionTask(ctx, task, execution)
+	case engine.SubWorkflowTaskType:
+		err = te.executeSubWorkflowTask(ctx, task, execution)
 	default:
 		err = fmt.Errorf("unsupported task type: %s", task.Type)
 	}
@@ -71,20 +81,31 @@ func (te *TaskExecutorImpl) ExecuteTask(ctx context.Context, task *models.Task,
 	return err
 }
 
+// serviceCorrelationID returns a fresh correlation ID for a request made on behalf of an
+// execution. Services that group usage by correlation prefix, such as the AI abstractor's
+// token budgets, see all of an execution's requests under its ID.
+func serviceCorrelationID(execution *models.WorkflowExecution) string {
+	return execution.ID + ":" + uuid.New().String()
+}
+
 // executeDataTask executes a data service task
 func (te *TaskExecutorImpl) executeDataTask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
 	request := &models.ServiceRequest{
-		Service:    "data",
-		Operation:  task.Parameters["operation"].(string),
-		Parameters: task.Parameters,
-		Timeout:    task.Timeout,
+		CorrelationID: serviceCorrelationID(execution),
+		Service:       "data",
+		Operation:     task.Parameters["operation"].(string),
+		Parameters:    task.Parameters,
+		Timeout:       task.Timeout,
 	}
 
+	startTime := time.Now()
 	response, err := te.messageCoordinator.SendDataRequest(ctx, request)
 	if err != nil {
 		return fmt.Errorf("data service request failed: %w", err)
 	}
 
+	te.recordDownstreamMetadata(execution.TaskStates[task.ID], request, response, time.Since(startTime))
+
 	if !response.Success {
 		return fmt.Errorf("data service error: %s", response.Error)
 	}
@@ -92,9 +113,6 @@ func (te *TaskExecutorImpl) executeDataTask(ctx context.Context, task *models.Ta
 	// Store response data in task state
 	taskState := execution.TaskStates[task.ID]
 	taskState.Output = response.Data
-	if taskState.Metadata == nil {
-		taskState.Metadata = make(map[string]interface{})
-	}
 	taskState.Metadata["service_response"] = response
 
 	return nil
@@ -103,27 +121,34 @@ func (te *TaskExecutorImpl) executeDataTask(ctx context.Context, task *models.Ta
 // executeAITask executes an AI service task
 func (te *TaskExecutorImpl) executeAITask(ctx context.Context, task *models.Task, execution *models.WorkflowExecution) error {
 	request := &models.ServiceRequest{
-		Service:    "ai",
-		Operation:  "generate", // Default AI operation
-		Parameters: task.Parameters,
-		Timeout:    task.Timeout,
+		CorrelationID: serviceCorrelationID(execution),
+		Service:       "ai",
+		Operation:     "generate", // Default AI operation
+		Parameters:    task.Parameters,
+		Timeout:       task.Timeout,
 	}
 
+	startTime := time.Now()
 	response, err := te.messageCoordinator.SendAIRequest(ctx, request)
 	if err != nil {
 		return fmt.Errorf("AI service request failed: %w", err)
 	}
 
+	te.recordDownstreamMetadata(execution.TaskStates[task.ID], request, response, time.Since(startTime))
+
 	if !response.Success {
+		if response.ErrorCode == models.ErrorCodeRateLimited {
+			return &engine.RetryAfterError{
+				Err:        fmt.Errorf("AI service rate limited: %s", response.Error),
+				RetryAfter: time.Duration(response.RetryAfter * float64(time.Second)),
+			}
+		}
 		return fmt.Errorf("AI service error: %s", response.Error)
 	}
 
 	// Store response data in task state
 	taskState := execution.TaskStates[task.ID]
 	taskState.Output = response.Data
-	if taskState.Metadata == nil {
-		taskState.Metadata = make(map[string]interface{})
-	}
 	taskState.Metadata["service_response"] = response
 
 	return nil
@@ -138,10 +163,26 @@ func (te *TaskExecutorImpl) executeExecTask(ctx context.Context, task *models.Ta
 	for k, v := range task.Parameters {
 		execParams[k] = v
 	}
-	
+
+	// The engine has resolved the command's placeholders; the exec agent takes plain strings
+	if err := normalizeExecCommand(execParams); err != nil {
+		return fmt.Errorf("invalid exec command: %w", err)
+	}
+
+	// Containers only reach the services the policy grants this workflow
+	if err := te.enforceServiceAccess(execParams, task, execution); err != nil {
+		return err
+	}
+
+	// A workspace kept for retries is removed after the last attempt the policy allows
+	markFinalWorkspaceAttempt(ctx, execParams, task)
+
 	// Add execution context
 	execParams["execution_id"] = execution.ID
 	execParams["task_id"] = task.ID
+	if len(execution.Labels) > 0 {
+		execParams["labels"] = execution.Labels
+	}
 	
 	// Add input data from previous tasks if specified
 	if inputMappings, exists := task.Parameters["input_mappings"]; exists {
@@ -159,17 +200,21 @@ func (te *TaskExecutorImpl) executeExecTask(ctx context.Context, task *models.Ta
 	}
 
 	request := &models.ServiceRequest{
-		Service:    "exec",
-		Operation:  "execute",
-		Parameters: execParams,
-		Timeout:    task.Timeout,
+		CorrelationID: serviceCorrelationID(execution),
+		Service:       "exec",
+		Operation:     "execute",
+		Parameters:    execParams,
+		Timeout:       task.Timeout,
 	}
 
+	startTime := time.Now()
 	response, err := te.messageCoordinator.SendExecRequest(ctx, request)
 	if err != nil {
 		return fmt.Errorf("exec service request failed: %w", err)
 	}
 
+	te.recordDownstreamMetadata(execution.TaskStates[task.ID], request, response, time.Since(startTime))
+
 	if !response.Success {
 		return fmt.Errorf("exec service error: %s", response.Error)
 	}
@@ -177,12 +222,63 @@ func (te *TaskExecutorImpl) executeExecTask(ctx context.Context, task *models.Ta
 	// Store response data in task state
 	taskState := execution.TaskStates[task.ID]
 	taskState.Output = response.Data
+	taskState.Metadata["service_response"] = response
+
+	// Parse raw files/logs into structured output if requested
+	parserConfig, err := parseOutputParserConfig(task.Parameters)
+	if err != nil {
+		return fmt.Errorf("invalid output parser: %w", err)
+	}
+	if parserConfig != nil {
+		parsed, err := applyOutputParser(parserConfig, response.Data)
+		if err != nil {
+			return fmt.Errorf("output parser failed: %w", err)
+		}
+		if taskState.Output == nil {
+			taskState.Output = make(map[string]interface{})
+		}
+		taskState.Output[parserConfig.Target] = parsed
+	}
+
+	return nil
+}
+
+// recordDownstreamMetadata stores a consistent summary of the downstream call in the
+// task metadata so a task can be traced to the exact service execution that served it
+func (te *TaskExecutorImpl) recordDownstreamMetadata(taskState *models.TaskState, request *models.ServiceRequest, response *models.ServiceResponse, duration time.Duration) {
 	if taskState.Metadata == nil {
 		taskState.Metadata = make(map[string]interface{})
 	}
-	taskState.Metadata["service_response"] = response
 
-	return nil
+	service := response.Service
+	if service == "" {
+		service = request.Service
+	}
+
+	downstream := map[string]interface{}{
+		"service":        service,
+		"correlation_id": request.CorrelationID,
+		"operation":      request.Operation,
+		"success":        response.Success,
+		"duration_ms":    duration.Milliseconds(),
+	}
+
+	if !response.Timestamp.IsZero() {
+		downstream["response_timestamp"] = response.Timestamp
+	}
+
+	// Exec agent reports its own execution ID; other services may include one in their data
+	executionID := response.ExecutionID
+	if executionID == "" {
+		if id, ok := response.Data["execution_id"].(string); ok {
+			executionID = id
+		}
+	}
+	if executionID != "" {
+		downstream["execution_id"] = executionID
+	}
+
+	taskState.Metadata["downstream"] = downstream
 }
 
 // executeParallelTask executes multiple sub-tasks in parallel
@@ -260,7 +356,10 @@ func (te *TaskExecutorImpl) executeConditionTask(ctx context.Context, task *mode
 	}
 
 	if len(followUpTasks) > 0 {
-		taskState.Output.(map[string]interface{})["follow_up_tasks"] = followUpTasks
+		if taskState.Output == nil {
+			taskState.Output = make(map[string]interface{})
+		}
+		taskState.Output["follow_up_tasks"] = followUpTasks
 	}
 
 	te.logger.WithFields(logrus.Fields{
@@ -273,33 +372,111 @@ func (te *TaskExecutorImpl) executeConditionTask(ctx context.Context, task *mode
 	return nil
 }
 
-// evaluateCondition evaluates a condition expression (simplified implementation)
+// markFinalWorkspaceAttempt tells the exec agent when an attempt reusing its workspace is
+// the last one, so the workspace is not kept for a retry that will never come
+func markFinalWorkspaceAttempt(ctx context.Context, params map[string]interface{}, task *models.Task) {
+	workspace, ok := params["workspace"].(map[string]interface{})
+	if !ok || workspace["reuse"] != true {
+		return
+	}
+
+	maxRetries := 0
+	if task.RetryPolicy != nil {
+		maxRetries = task.RetryPolicy.MaxRetries
+	}
+	call, known := engine.TaskCallFromContext(ctx)
+
+	marked := make(map[string]interface{}, len(workspace)+1)
+	for k, v := range workspace {
+		marked[k] = v
+	}
+	marked["final_attempt"] = !known || call.Attempt >= maxRetries
+	params["workspace"] = marked
+}
+
+// normalizeExecCommand turns the command array of an exec task, given either at the top
+// level of the parameters or under container, into a list of strings. Every argument must
+// be a scalar.
+func normalizeExecCommand(params map[string]interface{}) error {
+	target := params
+	if _, exists := target["command"]; !exists {
+		container, ok := params["container"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if _, exists := container["command"]; !exists {
+			return nil
+		}
+		// Copy so the task's own parameters are left as they were
+		target = make(map[string]interface{}, len(container))
+		for k, v := range container {
+			target[k] = v
+		}
+		params["container"] = target
+	}
+
+	var args []interface{}
+	switch command := target["command"].(type) {
+	case []interface{}:
+		args = command
+	case []string:
+		for _, arg := range command {
+			args = append(args, arg)
+		}
+	default:
+		return fmt.Errorf("command must be a list of arguments")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("command is empty")
+	}
+
+	resolved := make([]string, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return fmt.Errorf("command argument %d must be a string, got %T", i, arg)
+		}
+		resolved[i] = fmt.Sprintf("%v", arg)
+	}
+
+	target["command"] = resolved
+	return nil
+}
+
+// evaluateCondition evaluates a condition expression such as
+// ${fetch_data.output.nodes_found} > 100 && ${mode} == "prod" against the workflow
+// variables and task outputs. An empty condition is true.
 func (te *TaskExecutorImpl) evaluateCondition(condition string, execution *models.WorkflowExecution) (bool, error) {
-	// This is a simplified condition evaluator
-	// In a real implementation, you would use a proper expression parser/evaluator
-	
 	te.logger.WithFields(logrus.Fields{
 		"condition":    condition,
 		"execution_id": execution.ID,
 	}).Debug("Evaluating condition")
 
-	// Handle simple variable existence checks
-	if condition == "" {
+	if strings.TrimSpace(condition) == "" {
 		return true, nil
 	}
 
-	// Simple pattern matching for common conditions
-	switch {
-	case condition == "true":
-		return true, nil
-	case condition == "false":
-		return false, nil
-	default:
-		// For complex conditions, implement a proper expression evaluator
-		// For now, return true as default
-		te.logger.WithField("condition", condition).Warn("Complex condition evaluation not implemented, defaulting to true")
-		return true, nil
+	return engine.EvaluateCondition(condition, conditionScope(execution))
+}
+
+// conditionScope exposes each task's output as both task.field and task.output.field,
+// with its status as task.status. Workflow variables take precedence over a task with
+// the same ID.
+func conditionScope(execution *models.WorkflowExecution) map[string]interface{} {
+	scope := make(map[string]interface{}, len(execution.TaskStates)+len(execution.Variables))
+	for taskID, state := range execution.TaskStates {
+		entry := make(map[string]interface{}, len(state.Output)+2)
+		for key, value := range state.Output {
+			entry[key] = value
+		}
+		entry["output"] = state.Output
+		entry["status"] = string(state.Status)
+		scope[taskID] = entry
+	}
+	for key, value := range execution.Variables {
+		scope[key] = value
 	}
+	return scope
 }
 
 // GetTaskResult retrieves the output of a completed task
@@ -326,28 +503,66 @@ func (te *TaskExecutorImpl) ValidateTask(task *models.Task) error {
 		return fmt.Errorf("task type is required")
 	}
 
-	// Type-specific validation
+	if err := validateTaskParameters(task); err != nil {
+		return err
+	}
+
+	if task.Type == engine.SubWorkflowTaskType {
+		return te.validateSubWorkflowTask(task)
+	}
+	return nil
+}
+
+// validateTaskParameters checks that a task has the parameters its type requires. It is
+// shared by runtime validation and the checks on AI generated workflows.
+func validateTaskParameters(task *models.Task) error {
 	switch task.Type {
 	case "data":
 		if _, exists := task.Parameters["operation"]; !exists {
 			return fmt.Errorf("data task must specify operation")
 		}
+		if query, ok := task.Parameters["query"].(map[string]interface{}); ok {
+			if params, exists := query["parameters"]; exists {
+				if _, ok := params.(map[string]interface{}); !ok {
+					return fmt.Errorf("data task query parameters must be an object")
+				}
+			}
+		}
 	case "ai":
-		if _, exists := task.Parameters["prompt"]; !exists {
-			return fmt.Errorf("AI task must specify prompt")
+		_, hasPrompt := task.Parameters["prompt"]
+		_, hasMessages := task.Parameters["messages"]
+		if !hasPrompt && !hasMessages {
+			return fmt.Errorf("AI task must specify prompt or messages")
 		}
 	case "exec":
 		if _, exists := task.Parameters["image"]; !exists {
-			return fmt.Errorf("exec task must specify container image")
+			return fmt.Errorf("exec task must specify container image (parameter \"image\")")
+		}
+		if _, err := parseOutputParserConfig(task.Parameters); err != nil {
+			return fmt.Errorf("exec task has invalid output parser: %w", err)
 		}
 	case "parallel":
 		if _, exists := task.Parameters["tasks"]; !exists {
-			return fmt.Errorf("parallel task must specify sub-tasks")
+			return fmt.Errorf("parallel task must specify sub-tasks (parameter \"tasks\")")
 		}
 	case "condition":
 		if task.Condition == "" {
 			return fmt.Errorf("condition task must specify condition")
 		}
+	case engine.SubWorkflowTaskType:
+		if templateID, ok := task.Parameters["template"].(string); !ok || templateID == "" {
+			return fmt.Errorf("workflow task must specify template ID (parameter \"template\")")
+		}
+		if version, exists := task.Parameters["version"]; exists {
+			if _, ok := version.(string); !ok {
+				return fmt.Errorf("workflow task version must be a string (parameter \"version\")")
+			}
+		}
+		if variables, exists := task.Parameters["variables"]; exists {
+			if _, ok := variables.(map[string]interface{}); !ok {
+				return fmt.Errorf("workflow task variables must be an object (parameter \"variables\")")
+			}
+		}
 	default:
 		return fmt.Errorf("unsupported task type: %s", task.Type)
 	}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteExecTaskSendsCommandAsStrings(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("process")
+
+	task := &models.Task{ID: "process", Type: "exec", Parameters: map[string]interface{}{
+		"container": map[string]interface{}{"image": "tools:1", "command": []interface{}{"process", "--limit", 100, "${HOME}"}},
+	}}
+	if err := executor.ExecuteTask(context.Background(), task, execution); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	container := coordinator.requests[0].Parameters["container"].(map[string]interface{})
+	want := []string{"process", "--limit", "100", "${HOME}"}
+	if !reflect.DeepEqual(container["command"], want) {
+		t.Errorf("got command %#v, want %#v", container["command"], want)
+	}
+	if _, changed := task.Parameters["container"].(map[string]interface{})["command"].([]string); changed {
+		t.Error("the task's own parameters must not be rewritten")
+	}
+}
+
+func TestExecuteExecTaskSendsExecutionLabels(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("process")
+	execution.Labels = map[string]string{"tenant": "acme"}
+
+	task := &models.Task{ID: "process", Type: "exec", Parameters: map[string]interface{}{
+		"container": map[string]interface{}{"image": "tools:1"},
+	}}
+	if err := executor.ExecuteTask(context.Background(), task, execution); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := coordinator.requests[0].Parameters["labels"]; !reflect.DeepEqual(got, execution.Labels) {
+		t.Errorf("got labels %v, want the execution's", got)
+	}
+}
+
+func TestExecTaskMarksFinalWorkspaceAttempt(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("process")
+
+	task := &models.Task{ID: "process", Type: "exec", RetryPolicy: &models.RetryPolicy{MaxRetries: 2}, Parameters: map[string]interface{}{
+		"container": map[string]interface{}{"image": "tools:1"},
+		"workspace": map[string]interface{}{"reuse": true},
+	}}
+	for attempt, final := range map[int]bool{0: false, 1: false, 2: true} {
+		ctx := engine.WithTaskCall(context.Background(), engine.TaskCall{ExecutionID: execution.ID, TaskID: task.ID, Attempt: attempt})
+		if err := executor.ExecuteTask(ctx, task, execution); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		workspace := coordinator.requests[len(coordinator.requests)-1].Parameters["workspace"].(map[string]interface{})
+		if workspace["final_attempt"] != final || workspace["reuse"] != true {
+			t.Errorf("attempt %d: got workspace %v, want final_attempt %v", attempt, workspace, final)
+		}
+	}
+	if _, marked := task.Parameters["workspace"].(map[string]interface{})["final_attempt"]; marked {
+		t.Error("the task's own parameters must not be rewritten")
+	}
+}
+
+func TestNormalizeExecCommandRejectsInvalidCommands(t *testing.T) {
+	cases := map[string]interface{}{
+		"must be a list":   "process --limit 1",
+		"command is empty": []interface{}{},
+		"must be a string": []interface{}{"process", map[string]interface{}{"a": 1}},
+	}
+	for want, command := range cases {
+		err := normalizeExecCommand(map[string]interface{}{"command": command})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("command %v: expected error containing %q, got %v", command, want, err)
+		}
+	}
+}
+
+func TestServiceTasksRecordConsistentDownstreamMetadata(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		response := &models.ServiceResponse{Success: true, Service: request.Service, Data: map[string]interface{}{}}
+		switch request.Service {
+		case "exec":
+			response.ExecutionID = "exec-42"
+		case "data":
+			response.Data["execution_id"] = "query-7"
+		}
+		return response, nil
+	}}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("fetch", "summarize", "process")
+
+	tasks := []*models.Task{
+		{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}},
+		{ID: "summarize", Type: "ai", Parameters: map[string]interface{}{"prompt": "summarize"}},
+		{ID: "process", Type: "exec", Parameters: map[string]interface{}{"container": map[string]interface{}{"image": "tools:1"}}},
+	}
+	for _, task := range tasks {
+		if err := executor.ExecuteTask(context.Background(), task, execution); err != nil {
+			t.Fatalf("%s: unexpected error: %v", task.ID, err)
+		}
+	}
+
+	fields := []string{"service", "correlation_id", "operation", "success", "duration_ms"}
+	for _, task := range tasks {
+		downstream, ok := execution.TaskStates[task.ID].Metadata["downstream"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: no downstream metadata", task.ID)
+		}
+		for _, field := range fields {
+			if _, exists := downstream[field]; !exists {
+				t.Errorf("%s: downstream metadata lacks %s", task.ID, field)
+			}
+		}
+		if downstream["service"] != task.Type {
+			t.Errorf("%s: got service %v, want %s", task.ID, downstream["service"], task.Type)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, request := range coordinator.requests {
+		if !strings.HasPrefix(request.CorrelationID, execution.ID+":") || seen[request.CorrelationID] {
+			t.Errorf("%s: got correlation ID %q, want a unique ID prefixed with the execution ID", request.Service, request.CorrelationID)
+		}
+		seen[request.CorrelationID] = true
+	}
+
+	if got := execution.TaskStates["process"].Metadata["downstream"].(map[string]interface{})["execution_id"]; got != "exec-42" {
+		t.Errorf("got exec execution_id %v, want exec-42", got)
+	}
+	if got := execution.TaskStates["fetch"].Metadata["downstream"].(map[string]interface{})["execution_id"]; got != "query-7" {
+		t.Errorf("got data execution_id %v, want query-7 from the response data", got)
+	}
+}
+
+func TestFailedServiceTaskStillRecordsDownstreamMetadata(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		return &models.ServiceResponse{Success: false, Error: "no such collection"}, nil
+	}}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("fetch")
+
+	task := &models.Task{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query"}}
+	if err := executor.ExecuteTask(context.Background(), task, execution); err == nil {
+		t.Fatal("expected the service error to fail the task")
+	}
+
+	downstream := execution.TaskStates["fetch"].Metadata["downstream"].(map[string]interface{})
+	if downstream["success"] != false || downstream["service"] != "data" {
+		t.Errorf("unexpected downstream metadata %v", downstream)
+	}
+}
+
+func TestRateLimitedAIResponseCarriesRetryAfter(t *testing.T) {
+	coordinator := &fakeCoordinator{respond: func(request *models.ServiceRequest) (*models.ServiceResponse, error) {
+		return &models.ServiceResponse{Error: "slow down", ErrorCode: models.ErrorCodeRateLimited, RetryAfter: 2.5}, nil
+	}}
+	executor := NewTaskExecutor(coordinator)
+	execution := newTestExecution("ask")
+
+	task := &models.Task{ID: "ask", Type: "ai", Parameters: map[string]interface{}{"prompt": "hello"}}
+	err := executor.ExecuteTask(context.Background(), task, execution)
+
+	var rateErr *engine.RetryAfterError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("expected a RetryAfterError, got %v", err)
+	}
+	if rateErr.RetryAfter != 2500*time.Millisecond {
+		t.Errorf("retry after %s, want 2.5s", rateErr.RetryAfter)
+	}
+}
+
+func TestValidateDataTaskQueryParameters(t *testing.T) {
+	dataTask := func(parameters interface{}) *models.Task {
+		return &models.Task{ID: "fetch", Type: "data", Parameters: map[string]interface{}{
+			"operation": "traverse",
+			"query":     map[string]interface{}{"cypher": "MATCH (n) WHERE n.name = $name RETURN n", "parameters": parameters},
+		}}
+	}
+
+	if err := validateTaskParameters(dataTask(map[string]interface{}{"name": "${dataset}"})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, parameters := range []interface{}{"name=sales", []interface{}{"sales"}} {
+		if err := validateTaskParameters(dataTask(parameters)); err == nil || err.Error() != "data task query parameters must be an object" {
+			t.Errorf("parameters %v: got %v, want them rejected", parameters, err)
+		}
+	}
+}
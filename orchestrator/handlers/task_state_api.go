@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"orchestrator/clients"
+	"orchestrator/models"
+
+	"github.com/gorilla/mux"
+)
+
+// TaskStateLoader reads one task's state without loading the rest of its execution.
+// It is satisfied by clients.RedisStateManager, and reports a missing execution with
+// clients.ErrExecutionNotFound.
+type TaskStateLoader interface {
+	LoadTaskState(ctx context.Context, executionID, taskID string) (*models.TaskState, error)
+}
+
+// TaskStateAPI serves the state and output of single tasks of an execution
+type TaskStateAPI struct {
+	states TaskStateLoader
+}
+
+// NewTaskStateAPI creates the task state endpoint
+func NewTaskStateAPI(states TaskStateLoader) *TaskStateAPI {
+	return &TaskStateAPI{states: states}
+}
+
+// RegisterRoutes adds GET /workflows/{id}/tasks/{taskId} to the API router
+func (a *TaskStateAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/{id}/tasks/{taskId}", a.handleGetWorkflowTask).Methods("GET")
+}
+
+func (a *TaskStateAPI) handleGetWorkflowTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	executionID := vars["id"]
+	taskID := vars["taskId"]
+
+	state, err := a.states.LoadTaskState(r.Context(), executionID, taskID)
+	if errors.Is(err, clients.ErrExecutionNotFound) {
+		http.Error(w, "Workflow not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load task state", http.StatusInternalServerError)
+		return
+	}
+	if state == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+func TestGetWorkflowTaskReturnsOnlyThatTask(t *testing.T) {
+	execution := newTestExecution("fetch", "summarize")
+	execution.TaskStates["fetch"].Status = models.StatusCompleted
+	execution.TaskStates["fetch"].Output = map[string]interface{}{"rows": float64(3)}
+	execution.TaskStates["summarize"].Output = map[string]interface{}{"text": "large output"}
+	api := NewTaskStateAPI(newMemoryStateManager(execution))
+
+	recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-test/tasks/fetch", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	if strings.Contains(recorder.Body.String(), "large output") {
+		t.Error("the response carries another task's output")
+	}
+
+	var state models.TaskState
+	if err := json.NewDecoder(recorder.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state.ID != "fetch" || state.Status != models.StatusCompleted || state.Output["rows"] != float64(3) {
+		t.Errorf("got %+v", state)
+	}
+}
+
+func TestGetWorkflowTaskNotFound(t *testing.T) {
+	api := NewTaskStateAPI(newMemoryStateManager(newTestExecution("fetch")))
+
+	cases := map[string]string{
+		"/api/v1/workflows/exec-test/tasks/missing": "Task not found",
+		"/api/v1/workflows/unknown/tasks/fetch":     "Workflow not found",
+	}
+	for path, want := range cases {
+		recorder := serveAPI(api, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("%s: got %d %q, want 404 %q", path, recorder.Code, recorder.Body, want)
+		}
+	}
+}
+
+// failingTaskStates fails every load, as when Redis is unreachable
+type failingTaskStates struct{}
+
+func (failingTaskStates) LoadTaskState(ctx context.Context, executionID, taskID string) (*models.TaskState, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func TestGetWorkflowTaskStoreError(t *testing.T) {
+	recorder := serveAPI(NewTaskStateAPI(failingTaskStates{}), httptest.NewRequest(http.MethodGet, "/api/v1/workflows/exec-test/tasks/fetch", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("got %d %q, want a store failure reported as 500", recorder.Code, recorder.Body)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"orchestrator/models"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// maxTemplateBodySize bounds the template documents accepted over HTTP
+const maxTemplateBodySize = 1 << 20
+
+// TemplateAPI creates, updates and deletes templates at runtime. Writes go through the
+// template manager, so they are validated and persisted like templates loaded from disk.
+type TemplateAPI struct {
+	templates *TemplateManager
+}
+
+// NewTemplateAPI creates the template write endpoints
+func NewTemplateAPI(templates *TemplateManager) *TemplateAPI {
+	return &TemplateAPI{templates: templates}
+}
+
+// RegisterRoutes adds POST /templates and PUT and DELETE /templates/{id} to the API router
+func (a *TemplateAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/templates", a.handleCreateTemplate).Methods("POST")
+	api.HandleFunc("/templates/{id}", a.handleUpdateTemplate).Methods("PUT")
+	api.HandleFunc("/templates/{id}", a.handleDeleteTemplate).Methods("DELETE")
+}
+
+func (a *TemplateAPI) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := decodeTemplate(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid template: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.templates.CreateTemplate(template); err != nil {
+		writeTemplateWriteError(w, "Failed to create template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (a *TemplateAPI) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+
+	template, err := decodeTemplate(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid template: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// The body may leave the ID out, but must not rename the template
+	if template.ID == "" {
+		template.ID = templateID
+	} else if template.ID != templateID {
+		http.Error(w, fmt.Sprintf("Template ID %s does not match path ID %s", template.ID, templateID), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.templates.UpdateTemplate(template); err != nil {
+		writeTemplateWriteError(w, "Failed to update template", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+func (a *TemplateAPI) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+
+	if err := a.templates.DeleteTemplate(templateID); err != nil {
+		writeTemplateWriteError(w, "Failed to delete template", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeTemplate reads a template from the request body as YAML when the Content-Type
+// says so, and as JSON otherwise
+func decodeTemplate(r *http.Request) (*models.Template, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxTemplateBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(body) > maxTemplateBodySize {
+		return nil, fmt.Errorf("body exceeds %d bytes", maxTemplateBodySize)
+	}
+
+	var template models.Template
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		err = yaml.Unmarshal(body, &template)
+	} else {
+		err = json.Unmarshal(body, &template)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// writeTemplateWriteError maps template manager errors onto HTTP statuses: 409 for an ID
+// or version conflict, 404 for an unknown template, 422 with problems for an invalid graph and 400
+// for any other validation failure
+func writeTemplateWriteError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, ErrTemplateExists), errors.Is(err, ErrTemplateVersionExists):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusConflict)
+	case errors.Is(err, ErrTemplateNotFound):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusNotFound)
+	case WriteGraphProblems(w, message, err):
+	case errors.Is(err, ErrInvalidTemplate):
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", message, err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// templateRequest builds a template write request with a JSON body, or the raw body given
+func templateRequest(t *testing.T, method, path string, body interface{}) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	switch body := body.(type) {
+	case string:
+		reader = strings.NewReader(body)
+	case nil:
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func TestCreateTemplate(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	api := NewTemplateAPI(manager)
+
+	recorder := serveAPI(api, templateRequest(t, http.MethodPost, "/api/v1/templates", newTestTemplate("report")))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	var created models.Template
+	if err := json.NewDecoder(recorder.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != "report" || created.Version == "" {
+		t.Errorf("got %+v", created)
+	}
+
+	// Written to disk, so a fresh manager finds it
+	reloaded := NewTemplateManager(manager.templatesDir)
+	if err := reloaded.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.GetTemplate("report"); err != nil {
+		t.Errorf("created template was not persisted: %v", err)
+	}
+
+	recorder = serveAPI(api, templateRequest(t, http.MethodPost, "/api/v1/templates", newTestTemplate("report")))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("duplicate: got %d, want 409", recorder.Code)
+	}
+}
+
+func TestCreateTemplateFromYAML(t *testing.T) {
+	api := NewTemplateAPI(NewTemplateManager(t.TempDir()))
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/templates", strings.NewReader(
+		"id: nightly\nname: Nightly\nworkflow:\n  tasks:\n    - id: fetch\n      type: data\n"))
+	request.Header.Set("Content-Type", "application/yaml")
+	if recorder := serveAPI(api, request); recorder.Code != http.StatusCreated {
+		t.Errorf("got %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCreateTemplateRejectsInvalidTemplates(t *testing.T) {
+	api := NewTemplateAPI(NewTemplateManager(t.TempDir()))
+
+	unnamed := newTestTemplate("unnamed")
+	unnamed.Name = ""
+	cyclic := newTestTemplate("cyclic")
+	cyclic.Workflow.Tasks = []models.Task{
+		{ID: "a", Type: "data", DependsOn: []string{"b"}},
+		{ID: "b", Type: "data", DependsOn: []string{"a"}},
+	}
+
+	cases := []struct {
+		name string
+		body interface{}
+		code int
+	}{
+		{"malformed", "{not json", http.StatusBadRequest},
+		{"oversized", `{"id": "` + strings.Repeat("x", maxTemplateBodySize) + `"}`, http.StatusBadRequest},
+		{"fails validation", unnamed, http.StatusBadRequest},
+		{"dependency cycle", cyclic, http.StatusUnprocessableEntity},
+	}
+	for _, c := range cases {
+		recorder := serveAPI(api, templateRequest(t, http.MethodPost, "/api/v1/templates", c.body))
+		if recorder.Code != c.code {
+			t.Errorf("%s: got %d %s, want %d", c.name, recorder.Code, recorder.Body, c.code)
+		}
+	}
+
+	// Graph problems are listed individually
+	recorder := serveAPI(api, templateRequest(t, http.MethodPost, "/api/v1/templates", cyclic))
+	var body struct {
+		Problems []json.RawMessage `json:"problems"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil || len(body.Problems) == 0 {
+		t.Errorf("expected the cycle in problems, got %v", err)
+	}
+}
+
+func TestUpdateTemplate(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	if err := manager.CreateTemplate(newTestTemplate("report")); err != nil {
+		t.Fatal(err)
+	}
+	api := NewTemplateAPI(manager)
+
+	// The ID may be left out of the body
+	update := newTestTemplate("")
+	update.Name = "report"
+	update.Description = "second version"
+	recorder := serveAPI(api, templateRequest(t, http.MethodPut, "/api/v1/templates/report", update))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	latest, err := manager.GetTemplate("report")
+	if err != nil || latest.Description != "second version" {
+		t.Fatalf("got %+v, %v", latest, err)
+	}
+	if versions, _ := manager.ListTemplateVersions("report"); len(versions) != 2 {
+		t.Errorf("got versions %v, want both kept", versions)
+	}
+
+	cases := []struct {
+		name string
+		path string
+		body interface{}
+		code int
+	}{
+		{"renamed", "/api/v1/templates/report", newTestTemplate("other"), http.StatusBadRequest},
+		{"malformed", "/api/v1/templates/report", "[", http.StatusBadRequest},
+		{"unknown", "/api/v1/templates/missing", newTestTemplate("missing"), http.StatusNotFound},
+		{"existing version", "/api/v1/templates/report", latest, http.StatusConflict},
+	}
+	for _, c := range cases {
+		recorder := serveAPI(api, templateRequest(t, http.MethodPut, c.path, c.body))
+		if recorder.Code != c.code {
+			t.Errorf("%s: got %d %s, want %d", c.name, recorder.Code, recorder.Body, c.code)
+		}
+	}
+}
+
+func TestDeleteTemplate(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	if err := manager.CreateTemplate(newTestTemplate("report")); err != nil {
+		t.Fatal(err)
+	}
+	api := NewTemplateAPI(manager)
+
+	recorder := serveAPI(api, templateRequest(t, http.MethodDelete, "/api/v1/templates/report", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("got %d: %s", recorder.Code, recorder.Body)
+	}
+	if _, err := manager.GetTemplate("report"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("template still there: %v", err)
+	}
+
+	recorder = serveAPI(api, templateRequest(t, http.MethodDelete, "/api/v1/templates/report", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("second delete: got %d, want 404", recorder.Code)
+	}
+}
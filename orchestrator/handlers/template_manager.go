@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"orchestrator/engine"
 	"orchestrator/models"
 	"os"
 	"path/filepath"
@@ -13,26 +15,56 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// TemplateManager handles workflow templates storage and retrieval
+// ErrTemplateExists is returned when creating a template whose ID is already taken
+var ErrTemplateExists = errors.New("template already exists")
+
+// ErrTemplateNotFound is returned when a template ID is not known
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrInvalidTemplate is returned when a template being written fails validation
+var ErrInvalidTemplate = errors.New("template validation failed")
+
+// TemplateManager handles workflow templates storage and retrieval. Every version of a
+// template is kept; lookups by ID alone return the latest.
 type TemplateManager struct {
 	templatesDir string
-	templates    map[string]*models.Template
+	templates    map[string]*models.Template            // template ID -> latest version
+	versions     map[string]map[string]*models.Template // template ID -> version -> template
+	files        map[string][]string                    // template ID -> files its versions were loaded from or saved to
 	categories   map[string][]*models.Template
+	loadErrors   []TemplateLoadError
+	strict       bool
 	mutex        sync.RWMutex
 	logger       *logrus.Logger
 }
 
+// TemplateLoadError records a template file that failed to load
+type TemplateLoadError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
 // NewTemplateManager creates a new template manager
 func NewTemplateManager(templatesDir string) *TemplateManager {
 	return &TemplateManager{
 		templatesDir: templatesDir,
 		templates:    make(map[string]*models.Template),
+		versions:     make(map[string]map[string]*models.Template),
+		files:        make(map[string][]string),
 		categories:   make(map[string][]*models.Template),
 		logger:       logrus.New(),
 	}
 }
 
-// LoadTemplates scans the templates directory and loads all templates
+// SetStrictMode makes LoadTemplates fail if any template file cannot be loaded
+func (tm *TemplateManager) SetStrictMode(strict bool) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+	tm.strict = strict
+}
+
+// LoadTemplates scans the templates directory and loads all templates. Files that fail
+// to load are skipped and recorded; in strict mode any failure is returned as an error.
 func (tm *TemplateManager) LoadTemplates() error {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
@@ -41,12 +73,16 @@ func (tm *TemplateManager) LoadTemplates() error {
 
 	// Clear existing templates
 	tm.templates = make(map[string]*models.Template)
+	tm.versions = make(map[string]map[string]*models.Template)
+	tm.files = make(map[string][]string)
 	tm.categories = make(map[string][]*models.Template)
+	tm.loadErrors = make([]TemplateLoadError, 0)
 
 	// Walk through templates directory
 	err := filepath.WalkDir(tm.templatesDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			tm.logger.WithError(err).WithField("path", path).Warn("Error accessing template path")
+			tm.loadErrors = append(tm.loadErrors, TemplateLoadError{File: path, Error: err.Error()})
 			return nil // Continue walking
 		}
 
@@ -58,6 +94,7 @@ func (tm *TemplateManager) LoadTemplates() error {
 		// Load template file
 		if err := tm.loadTemplateFile(path); err != nil {
 			tm.logger.WithError(err).WithField("file", path).Error("Failed to load template file")
+			tm.loadErrors = append(tm.loadErrors, TemplateLoadError{File: path, Error: err.Error()})
 			// Continue loading other templates
 		}
 
@@ -71,11 +108,27 @@ func (tm *TemplateManager) LoadTemplates() error {
 	tm.logger.WithFields(logrus.Fields{
 		"total_templates": len(tm.templates),
 		"categories":      len(tm.categories),
+		"load_errors":     len(tm.loadErrors),
 	}).Info("Template loading completed")
 
+	if tm.strict && len(tm.loadErrors) > 0 {
+		return fmt.Errorf("%d template file(s) failed to load, first: %s: %s",
+			len(tm.loadErrors), tm.loadErrors[0].File, tm.loadErrors[0].Error)
+	}
+
 	return nil
 }
 
+// GetLoadErrors returns the template files that failed during the last load
+func (tm *TemplateManager) GetLoadErrors() []TemplateLoadError {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	result := make([]TemplateLoadError, len(tm.loadErrors))
+	copy(result, tm.loadErrors)
+	return result
+}
+
 // loadTemplateFile loads a single template file
 func (tm *TemplateManager) loadTemplateFile(filePath string) error {
 	// Read file content
@@ -95,24 +148,19 @@ func (tm *TemplateManager) loadTemplateFile(filePath string) error {
 		return fmt.Errorf("template validation failed: %w", err)
 	}
 
-	// Store template
-	tm.templates[template.ID] = &template
-
-	// Add to category index
-	category := template.Category
-	if category == "" {
-		category = "general"
-	}
-	
-	if tm.categories[category] == nil {
-		tm.categories[category] = make([]*models.Template, 0)
+	// Templates written before versioning have none; they are the first version
+	if template.Version == "" {
+		template.Version = initialTemplateVersion
 	}
-	tm.categories[category] = append(tm.categories[category], &template)
+
+	// Store the version, replacing any earlier file with the same ID and version
+	tm.storeVersion(&template, filePath)
 
 	tm.logger.WithFields(logrus.Fields{
 		"template_id":   template.ID,
 		"template_name": template.Name,
-		"category":      category,
+		"version":       template.Version,
+		"category":      templateCategory(&template),
 		"file":          filePath,
 	}).Debug("Loaded template")
 
@@ -156,17 +204,35 @@ func (tm *TemplateManager) validateTemplate(template *models.Template) error {
 		if task.Type == "" {
 			return fmt.Errorf("task type is required for task %s", task.ID)
 		}
-	}
 
-	// Validate task dependencies
-	for _, task := range template.Workflow.Tasks {
-		for _, dep := range task.DependsOn {
-			if !taskIDs[dep] {
-				return fmt.Errorf("task %s depends on non-existent task %s", task.ID, dep)
+		if err := engine.ValidateOutputRetention(task.OutputRetention); err != nil {
+			return fmt.Errorf("task %s: %w", task.ID, err)
+		}
+
+		if task.RetryPolicy != nil {
+			switch task.RetryPolicy.Jitter {
+			case "", models.JitterFull, models.JitterEqual:
+			default:
+				return fmt.Errorf("task %s: unsupported retry jitter: %s", task.ID, task.RetryPolicy.Jitter)
 			}
 		}
 	}
 
+	if err := engine.ValidateOutputRetention(template.Workflow.OutputRetention); err != nil {
+		return err
+	}
+
+	switch template.Workflow.Interpolation {
+	case "", models.InterpolationLenient, models.InterpolationStrict:
+	default:
+		return fmt.Errorf("unsupported interpolation mode: %s", template.Workflow.Interpolation)
+	}
+
+	// Validate task dependencies; every missing dependency and cycle is reported at once
+	if _, err := engine.NewDAG(template.Workflow.Tasks); err != nil {
+		return err
+	}
+
 	// Validate template variables
 	for _, variable := range template.Variables {
 		if variable.Name == "" {
@@ -180,6 +246,52 @@ func (tm *TemplateManager) validateTemplate(template *models.Template) error {
 	return nil
 }
 
+// CheckPreconditions evaluates a template's preconditions against the request variables.
+// Template defaults and workflow variables are visible to the expressions, with
+// request variables taking precedence.
+func CheckPreconditions(template *models.Template, variables map[string]interface{}) error {
+	if len(template.Preconditions) == 0 {
+		return nil
+	}
+
+	scope := make(map[string]interface{})
+	for _, variable := range template.Variables {
+		if variable.DefaultValue != nil {
+			scope[variable.Name] = variable.DefaultValue
+		}
+	}
+	scope = mergeVariables(scope, template.Workflow.Variables)
+	scope = mergeVariables(scope, variables)
+
+	var failed []string
+	for _, precondition := range template.Preconditions {
+		satisfied, err := engine.EvaluateCondition(precondition, scope)
+		if err != nil {
+			return fmt.Errorf("template %s precondition %q could not be evaluated: %w", template.ID, precondition, err)
+		}
+		if !satisfied {
+			failed = append(failed, precondition)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("template %s preconditions not satisfied: %s", template.ID, strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// ApplyVariableDefaults fills in template variable defaults the request did not provide
+func ApplyVariableDefaults(template *models.Template, variables map[string]interface{}) map[string]interface{} {
+	defaults := make(map[string]interface{})
+	for _, variable := range template.Variables {
+		if variable.DefaultValue != nil {
+			defaults[variable.Name] = variable.DefaultValue
+		}
+	}
+	return mergeVariables(defaults, variables)
+}
+
 // GetTemplate retrieves a template by ID
 func (tm *TemplateManager) GetTemplate(id string) (*models.Template, error) {
 	tm.mutex.RLock()
@@ -187,13 +299,27 @@ func (tm *TemplateManager) GetTemplate(id string) (*models.Template, error) {
 
 	template, exists := tm.templates[id]
 	if !exists {
-		return nil, fmt.Errorf("template %s not found", id)
+		return nil, fmt.Errorf("template %s: %w", id, ErrTemplateNotFound)
 	}
 
 	// Return a deep copy to prevent modification
 	return tm.cloneTemplate(template), nil
 }
 
+// FindByWorkflowID returns the template whose workflow definition has the given ID
+func (tm *TemplateManager) FindByWorkflowID(workflowID string) (*models.Template, error) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	for _, template := range tm.templates {
+		if template.Workflow.ID == workflowID {
+			return tm.cloneTemplate(template), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no template defines workflow %s", workflowID)
+}
+
 // GetTemplatesByCategory returns all templates in a category
 func (tm *TemplateManager) GetTemplatesByCategory(category string) ([]*models.Template, error) {
 	tm.mutex.RLock()
@@ -276,163 +402,257 @@ func (tm *TemplateManager) templateMatches(template *models.Template, query stri
 	return false
 }
 
-// CreateTemplate creates and stores a new template
+// CreateTemplate validates a new template and records it as its first version. It fails
+// with ErrTemplateExists if the ID is already taken.
 func (tm *TemplateManager) CreateTemplate(template *models.Template) error {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
 
 	// Validate template
 	if err := tm.validateTemplate(template); err != nil {
-		return fmt.Errorf("template validation failed: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
 	}
 
 	// Check for ID conflicts
 	if _, exists := tm.templates[template.ID]; exists {
-		return fmt.Errorf("template with ID %s already exists", template.ID)
+		return fmt.Errorf("template with ID %s: %w", template.ID, ErrTemplateExists)
 	}
 
-	// Store template
-	tm.templates[template.ID] = template
-
-	// Add to category index
-	category := template.Category
-	if category == "" {
-		category = "general"
-	}
-	
-	if tm.categories[category] == nil {
-		tm.categories[category] = make([]*models.Template, 0)
+	filePath, err := tm.persistTemplate(template)
+	if err != nil {
+		return err
 	}
-	tm.categories[category] = append(tm.categories[category], template)
 
 	tm.logger.WithFields(logrus.Fields{
 		"template_id":   template.ID,
 		"template_name": template.Name,
-		"category":      category,
+		"category":      templateCategory(template),
+		"version":       template.Version,
+		"file_path":     filePath,
 	}).Info("Created new template")
 
 	return nil
 }
 
-// SaveTemplate persists a template to disk
+// UpdateTemplate validates a template and records it as a new version of an existing
+// one; earlier versions stay available. It fails with ErrTemplateNotFound if no template
+// has the ID.
+func (tm *TemplateManager) UpdateTemplate(template *models.Template) error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if err := tm.validateTemplate(template); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	if _, exists := tm.templates[template.ID]; !exists {
+		return fmt.Errorf("template %s: %w", template.ID, ErrTemplateNotFound)
+	}
+
+	filePath, err := tm.persistTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	tm.logger.WithFields(logrus.Fields{
+		"template_id": template.ID,
+		"version":     template.Version,
+		"file_path":   filePath,
+	}).Info("Updated template")
+
+	return nil
+}
+
+// SaveTemplate persists a template to disk as a new version, creating the template if needed
 func (tm *TemplateManager) SaveTemplate(template *models.Template) error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
 	// Validate template
 	if err := tm.validateTemplate(template); err != nil {
-		return fmt.Errorf("template validation failed: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	filePath, err := tm.persistTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	tm.logger.WithFields(logrus.Fields{
+		"template_id": template.ID,
+		"version":     template.Version,
+		"file_path":   filePath,
+	}).Info("Saved template to disk")
+
+	return nil
+}
+
+// persistTemplate records a template as a new version of its ID, writes it to
+// <id>/<version>.yaml and makes it the latest. Without a version the next one after the
+// latest is assigned; a given version must be newer than the latest, since recorded
+// versions are never overwritten. Callers must hold the write lock.
+func (tm *TemplateManager) persistTemplate(template *models.Template) (string, error) {
+	// Workflow tasks must not lead back to a template that runs them, this one included
+	lookup := func(id string) *models.Template {
+		if id == template.ID {
+			return template
+		}
+		return tm.templates[id]
+	}
+	if cycle := SubWorkflowCycle(template.ID, lookup); cycle != nil {
+		return "", fmt.Errorf("%w: sub-workflow cycle: %s", ErrInvalidTemplate, strings.Join(cycle, " -> "))
 	}
 
-	// Generate file path
-	filename := fmt.Sprintf("%s.yaml", template.ID)
-	filePath := filepath.Join(tm.templatesDir, filename)
+	latest := tm.templates[template.ID]
+	if template.Version == "" {
+		template.Version = nextTemplateVersion(latest)
+	} else if _, exists := tm.versions[template.ID][template.Version]; exists {
+		return "", fmt.Errorf("template %s version %s: %w", template.ID, template.Version, ErrTemplateVersionExists)
+	} else if latest != nil && compareTemplateVersions(template.Version, latest.Version) <= 0 {
+		return "", fmt.Errorf("%w: version %s is not newer than the latest version %s", ErrInvalidTemplate, template.Version, latest.Version)
+	}
+
+	// The ID and version become file names, so they must not reach outside the directory
+	if !isSafeFileName(template.ID) {
+		return "", fmt.Errorf("%w: template ID %q cannot be used as a file name", ErrInvalidTemplate, template.ID)
+	}
+	if !isSafeFileName(template.Version) {
+		return "", fmt.Errorf("%w: template version %q cannot be used as a file name", ErrInvalidTemplate, template.Version)
+	}
 
 	// Marshal to YAML
 	data, err := yaml.Marshal(template)
 	if err != nil {
-		return fmt.Errorf("failed to marshal template to YAML: %w", err)
+		return "", fmt.Errorf("failed to marshal template to YAML: %w", err)
 	}
 
 	// Write to file
+	versionDir := filepath.Join(tm.templatesDir, template.ID)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create template version directory: %w", err)
+	}
+	filePath := filepath.Join(versionDir, fmt.Sprintf("%s.yaml", template.Version))
 	if err := writeFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write template file: %w", err)
+		return "", fmt.Errorf("failed to write template file: %w", err)
 	}
 
-	// Update in-memory storage
+	tm.storeVersion(template, filePath)
+	return filePath, nil
+}
+
+// deletingSuffix marks a version file set aside by DeleteTemplate. Such files are not
+// YAML, so LoadTemplates never picks them up again.
+const deletingSuffix = ".deleting"
+
+// DeleteTemplate removes a template with all its versions and their files. Every version
+// file is first renamed aside; if one can't be, those already moved are restored and the
+// template is left as it was. Files that then fail to be removed stay set aside, where
+// they are never loaded, so a partial failure can't bring back some of the versions.
+func (tm *TemplateManager) DeleteTemplate(id string) error {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
 
-	tm.templates[template.ID] = template
-
-	// Update category index
-	category := template.Category
-	if category == "" {
-		category = "general"
+	template, exists := tm.templates[id]
+	if !exists {
+		return fmt.Errorf("template %s: %w", id, ErrTemplateNotFound)
 	}
 
-	// Remove from old category if it exists
-	for cat, templates := range tm.categories {
-		for i, t := range templates {
-			if t.ID == template.ID && cat != category {
-				tm.categories[cat] = append(templates[:i], templates[i+1:]...)
-				break
+	files := tm.files[id]
+	var setAside []string
+	for _, filePath := range files {
+		err := os.Rename(filePath, filePath+deletingSuffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, moved := range setAside {
+				if restoreErr := os.Rename(moved+deletingSuffix, moved); restoreErr != nil {
+					tm.logger.WithError(restoreErr).WithField("file", moved).Error("Failed to restore template file")
+				}
 			}
+			return fmt.Errorf("failed to remove template file: %w", err)
 		}
+		setAside = append(setAside, filePath)
 	}
 
-	// Add to new category
-	if tm.categories[category] == nil {
-		tm.categories[category] = make([]*models.Template, 0)
-	}
-	
-	found := false
-	for i, t := range tm.categories[category] {
-		if t.ID == template.ID {
-			tm.categories[category][i] = template
-			found = true
-			break
+	for _, filePath := range setAside {
+		if err := os.Remove(filePath + deletingSuffix); err != nil {
+			tm.logger.WithError(err).WithField("file", filePath+deletingSuffix).Warn("Failed to remove deleted template file")
 		}
 	}
-	
-	if !found {
-		tm.categories[category] = append(tm.categories[category], template)
+	if isSafeFileName(id) {
+		// Only succeeds once the version directory is empty, which is all we want
+		os.Remove(filepath.Join(tm.templatesDir, id))
 	}
 
+	// Remove from memory and category index
+	delete(tm.templates, id)
+	delete(tm.versions, id)
+	delete(tm.files, id)
+	tm.reindex(template, templateCategory(template))
+
 	tm.logger.WithFields(logrus.Fields{
-		"template_id": template.ID,
-		"file_path":   filePath,
-	}).Info("Saved template to disk")
+		"template_id": id,
+		"versions":    len(files),
+	}).Info("Deleted template")
 
 	return nil
 }
 
-// DeleteTemplate removes a template
-func (tm *TemplateManager) DeleteTemplate(id string) error {
-	tm.mutex.Lock()
-	defer tm.mutex.Unlock()
-
-	template, exists := tm.templates[id]
-	if !exists {
-		return fmt.Errorf("template %s not found", id)
+// reindex updates the category index after a template is stored or removed. Every entry
+// for the template's ID is dropped, starting with oldCategory, and the stored template
+// (if it still exists) is added to its current category, so each template is listed in
+// exactly one category. Callers must hold the write lock.
+func (tm *TemplateManager) reindex(template *models.Template, oldCategory string) {
+	removeFrom := func(category string) {
+		templates := tm.categories[category]
+		kept := make([]*models.Template, 0, len(templates))
+		for _, t := range templates {
+			if t.ID != template.ID {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == 0 {
+			delete(tm.categories, category)
+		} else {
+			tm.categories[category] = kept
+		}
 	}
 
-	// Remove from memory
-	delete(tm.templates, id)
-
-	// Remove from category index
-	category := template.Category
-	if category == "" {
-		category = "general"
+	if oldCategory != "" {
+		removeFrom(oldCategory)
 	}
 
-	if templates, exists := tm.categories[category]; exists {
-		for i, t := range templates {
-			if t.ID == id {
-				tm.categories[category] = append(templates[:i], templates[i+1:]...)
+	// Sweep any stale entries left in other categories
+	for category, templates := range tm.categories {
+		for _, t := range templates {
+			if t.ID == template.ID {
+				removeFrom(category)
 				break
 			}
 		}
 	}
 
-	tm.logger.WithField("template_id", id).Info("Deleted template")
+	stored, exists := tm.templates[template.ID]
+	if !exists {
+		return
+	}
 
-	return nil
+	category := templateCategory(stored)
+	tm.categories[category] = append(tm.categories[category], stored)
 }
 
-// cloneTemplate creates a deep copy of a template
-func (tm *TemplateManager) cloneTemplate(template *models.Template) *models.Template {
-	// Use JSON marshaling for deep copy (simple approach)
-	data, err := yaml.Marshal(template)
-	if err != nil {
-		tm.logger.WithError(err).Error("Failed to marshal template for cloning")
-		return template // Return original on error
-	}
-
-	var clone models.Template
-	if err := yaml.Unmarshal(data, &clone); err != nil {
-		tm.logger.WithError(err).Error("Failed to unmarshal template clone")
-		return template // Return original on error
+// templateCategory returns the category a template is indexed under
+func templateCategory(template *models.Template) string {
+	if template.Category == "" {
+		return "general"
 	}
+	return template.Category
+}
 
-	return &clone
+// cloneTemplate creates a deep copy of a template so callers can't mutate shared state
+func (tm *TemplateManager) cloneTemplate(template *models.Template) *models.Template {
+	return template.Clone()
 }
 
 // GetStats returns statistics about loaded templates
@@ -446,10 +666,11 @@ func (tm *TemplateManager) GetStats() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"total_templates":   len(tm.templates),
-		"categories":        len(tm.categories),
+		"total_templates":    len(tm.templates),
+		"categories":         len(tm.categories),
 		"category_breakdown": categoryStats,
-		"templates_dir":     tm.templatesDir,
+		"templates_dir":      tm.templatesDir,
+		"load_errors":        len(tm.loadErrors),
 	}
 }
 
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"orchestrator/models"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newTestTemplate returns a valid single-task template
+func newTestTemplate(id string) *models.Template {
+	return &models.Template{
+		ID:   id,
+		Name: id,
+		Workflow: models.WorkflowDefinition{
+			Tasks: []models.Task{{ID: "fetch", Type: "data"}},
+		},
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	template := newTestTemplate("report")
+	template.Variables = []models.TemplateVariable{{Name: "limit", Type: "int", DefaultValue: float64(10)}}
+	template.Preconditions = []string{"limit <= 100", "exists(dataset)"}
+
+	if err := CheckPreconditions(template, map[string]interface{}{"dataset": "sales"}); err != nil {
+		t.Fatalf("expected preconditions to hold with the default limit, got %v", err)
+	}
+
+	err := CheckPreconditions(template, map[string]interface{}{"limit": float64(500)})
+	if err == nil {
+		t.Fatal("expected unsatisfied preconditions to fail")
+	}
+	for _, failed := range template.Preconditions {
+		if !strings.Contains(err.Error(), failed) {
+			t.Errorf("expected %q to be reported in %v", failed, err)
+		}
+	}
+
+	template.Preconditions = []string{"limit >"}
+	if err := CheckPreconditions(template, nil); err == nil || !strings.Contains(err.Error(), "could not be evaluated") {
+		t.Errorf("expected a malformed precondition to be reported, got %v", err)
+	}
+}
+
+func TestGenerateWorkflowFromTemplateChecksPreconditions(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	template := newTestTemplate("gated")
+	template.Preconditions = []string{"region == 'eu'"}
+	if err := manager.CreateTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	generator := NewAIWorkflowGenerator(&fakeCoordinator{}, manager, nil)
+
+	workflow, err := generator.GenerateWorkflowFromTemplate(context.Background(), "gated", map[string]interface{}{"region": "eu"}, "")
+	if err != nil {
+		t.Fatalf("expected a satisfied precondition to instantiate the template, got %v", err)
+	}
+	if workflow.Variables["region"] != "eu" {
+		t.Errorf("expected request variables on the workflow, got %v", workflow.Variables)
+	}
+
+	if _, err := generator.GenerateWorkflowFromTemplate(context.Background(), "gated", map[string]interface{}{"region": "us"}, ""); err == nil {
+		t.Fatal("expected an unsatisfied precondition to stop instantiation")
+	}
+}
+
+func TestLoadTemplatesReportsInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	valid, err := yaml.Marshal(newTestTemplate("valid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "valid.yaml"), valid, 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("id: broken\nworkflow: [not, a, map\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewTemplateManager(dir)
+	if err := manager.LoadTemplates(); err != nil {
+		t.Fatalf("expected lenient loading to skip the invalid template, got %v", err)
+	}
+	if _, err := manager.GetTemplate("valid"); err != nil {
+		t.Errorf("expected the valid template to load, got %v", err)
+	}
+
+	loadErrors := manager.GetLoadErrors()
+	if len(loadErrors) != 1 || loadErrors[0].File != invalidPath || loadErrors[0].Error == "" {
+		t.Fatalf("unexpected load errors %+v", loadErrors)
+	}
+	if count := manager.GetStats()["load_errors"]; count != 1 {
+		t.Errorf("stats report %v load errors, want 1", count)
+	}
+
+	manager.SetStrictMode(true)
+	if err := manager.LoadTemplates(); err == nil || !strings.Contains(err.Error(), invalidPath) {
+		t.Fatalf("expected strict loading to fail naming %s, got %v", invalidPath, err)
+	}
+}
+
+// categoryIndex returns the IDs listed under each category
+func categoryIndex(t *testing.T, manager *TemplateManager) map[string][]string {
+	t.Helper()
+	index := make(map[string][]string)
+	for _, category := range manager.ListCategories() {
+		templates, err := manager.GetTemplatesByCategory(category)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, template := range templates {
+			index[category] = append(index[category], template.ID)
+		}
+	}
+	return index
+}
+
+func TestTemplateCategoryIndexStaysConsistent(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+
+	template := newTestTemplate("report")
+	template.Category = "reports"
+	if err := manager.CreateTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	if index := categoryIndex(t, manager); len(index) != 1 || len(index["reports"]) != 1 {
+		t.Fatalf("after create: %v", index)
+	}
+
+	// Re-categorizing through a new version moves the template
+	moved := newTestTemplate("report")
+	moved.Category = "finance"
+	if err := manager.UpdateTemplate(moved); err != nil {
+		t.Fatal(err)
+	}
+	if index := categoryIndex(t, manager); len(index) != 1 || len(index["finance"]) != 1 {
+		t.Fatalf("after re-categorizing: %v", index)
+	}
+
+	// Saving again in the same category does not list it twice
+	resaved := newTestTemplate("report")
+	resaved.Category = "finance"
+	if err := manager.SaveTemplate(resaved); err != nil {
+		t.Fatal(err)
+	}
+	if index := categoryIndex(t, manager); len(index) != 1 || len(index["finance"]) != 1 {
+		t.Fatalf("after saving again: %v", index)
+	}
+
+	if err := manager.DeleteTemplate("report"); err != nil {
+		t.Fatal(err)
+	}
+	if index := categoryIndex(t, manager); len(index) != 0 {
+		t.Fatalf("after delete: %v", index)
+	}
+}
+
+func TestApplyVariableDefaults(t *testing.T) {
+	template := newTestTemplate("report")
+	template.Variables = []models.TemplateVariable{
+		{Name: "limit", Type: "int", DefaultValue: float64(10)},
+		{Name: "region", Type: "string", DefaultValue: "eu"},
+		{Name: "dataset", Type: "string"},
+	}
+
+	got := ApplyVariableDefaults(template, map[string]interface{}{"region": "us", "extra": true})
+	want := map[string]interface{}{"limit": float64(10), "region": "us", "extra": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetTemplateReturnsIsolatedCopy(t *testing.T) {
+	manager := NewTemplateManager(t.TempDir())
+	template := newTestTemplate("shared")
+	template.Workflow.Tasks[0].Parameters = map[string]interface{}{"operation": "query"}
+	if err := manager.CreateTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := manager.GetTemplate("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Workflow.Tasks[0].Parameters["operation"] = "drop"
+	first.Workflow.Tasks = append(first.Workflow.Tasks, models.Task{ID: "extra"})
+
+	second, err := manager.GetTemplate("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Workflow.Tasks) != 1 || second.Workflow.Tasks[0].Parameters["operation"] != "query" {
+		t.Errorf("a caller's changes leaked into the stored template: %+v", second.Workflow.Tasks)
+	}
+}
+
+func BenchmarkGetTemplate(b *testing.B) {
+	manager := NewTemplateManager(b.TempDir())
+	if err := manager.CreateTemplate(newTestTemplate("bench")); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := manager.GetTemplate("bench"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
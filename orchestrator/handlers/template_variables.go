@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"orchestrator/models"
+)
+
+// Kinds of template variable violation
+const (
+	ViolationMissingRequired = "missing_required"
+	ViolationInvalidType     = "invalid_type"
+	ViolationNotAnOption     = "not_an_option"
+)
+
+// VariableViolation is one request variable that does not satisfy its template declaration
+type VariableViolation struct {
+	Variable string `json:"variable"`
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+}
+
+// VariableValidationError reports every variable violation at once
+type VariableValidationError struct {
+	TemplateID string              `json:"template_id"`
+	Violations []VariableViolation `json:"violations"`
+}
+
+func (e *VariableValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return fmt.Sprintf("template %s variables are invalid: %s", e.TemplateID, strings.Join(messages, "; "))
+}
+
+// ValidateTemplateVariables checks request variables against the template's declared
+// variables. Required variables must be present, each value is coerced to its declared
+// type and, when options are declared, must be one of them. Omitted optional variables
+// take their default. Variables the template does not declare are passed through.
+// The returned map holds the coerced values; the input is not modified.
+func ValidateTemplateVariables(template *models.Template, variables map[string]interface{}) (map[string]interface{}, error) {
+	result := mergeVariables(nil, variables)
+
+	var violations []VariableViolation
+	for _, variable := range template.Variables {
+		value, provided := result[variable.Name]
+		if !provided || value == nil {
+			if variable.Required {
+				violations = append(violations, VariableViolation{
+					Variable: variable.Name,
+					Kind:     ViolationMissingRequired,
+					Message:  fmt.Sprintf("%s is required", variable.Name),
+				})
+				continue
+			}
+			if variable.DefaultValue == nil {
+				continue
+			}
+			value = variable.DefaultValue
+		}
+
+		coerced, err := coerceVariable(variable.Type, value)
+		if err != nil {
+			violations = append(violations, VariableViolation{
+				Variable: variable.Name,
+				Kind:     ViolationInvalidType,
+				Message:  fmt.Sprintf("%s: %v", variable.Name, err),
+			})
+			continue
+		}
+
+		if invalid := invalidOptions(coerced, variable.Options); len(invalid) > 0 {
+			violations = append(violations, VariableViolation{
+				Variable: variable.Name,
+				Kind:     ViolationNotAnOption,
+				Message: fmt.Sprintf("%s: %s not one of %s", variable.Name,
+					strings.Join(invalid, ", "), strings.Join(variable.Options, ", ")),
+			})
+			continue
+		}
+
+		result[variable.Name] = coerced
+	}
+
+	if len(violations) > 0 {
+		return nil, &VariableValidationError{TemplateID: template.ID, Violations: violations}
+	}
+	return result, nil
+}
+
+// coerceVariable converts a value decoded from JSON or YAML to the declared type.
+// Strings holding a number or boolean are accepted for int and bool variables.
+func coerceVariable(kind string, value interface{}) (interface{}, error) {
+	switch kind {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool, int, int64, float64:
+			return fmt.Sprint(v), nil
+		}
+		return nil, fmt.Errorf("expected string, got %T", value)
+
+	case "int":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v != math.Trunc(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("expected int, got %v", v)
+			}
+			return int(v), nil
+		case string:
+			i, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected int, got %q", v)
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("expected int, got %T", value)
+
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected bool, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected bool, got %T", value)
+
+	case "array":
+		if reflect.ValueOf(value).Kind() != reflect.Slice {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		return value, nil
+
+	case "object":
+		switch value.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return value, nil
+		}
+		return nil, fmt.Errorf("expected object, got %T", value)
+	}
+
+	// Undeclared or unknown types are not checked
+	return value, nil
+}
+
+// invalidOptions returns the values not among options; each element of an array is
+// checked on its own
+func invalidOptions(value interface{}, options []string) []string {
+	if len(options) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(options))
+	for _, option := range options {
+		allowed[option] = true
+	}
+
+	values := []interface{}{value}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+		values = make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).Interface()
+		}
+	}
+
+	var invalid []string
+	for _, v := range values {
+		if s := fmt.Sprint(v); !allowed[s] {
+			invalid = append(invalid, strconv.Quote(s))
+		}
+	}
+	return invalid
+}
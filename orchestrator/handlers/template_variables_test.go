@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"orchestrator/models"
+	"reflect"
+	"testing"
+)
+
+// reportTemplate declares one variable of each kind the checks apply to
+func reportTemplate() *models.Template {
+	return &models.Template{ID: "report", Variables: []models.TemplateVariable{
+		{Name: "dataset", Type: "string", Required: true},
+		{Name: "limit", Type: "int", DefaultValue: 100},
+		{Name: "dry_run", Type: "bool"},
+		{Name: "format", Type: "string", Options: []string{"csv", "json"}},
+		{Name: "regions", Type: "array", Options: []string{"eu", "us"}},
+		{Name: "filter", Type: "object"},
+	}}
+}
+
+func TestValidateTemplateVariablesCoercesValues(t *testing.T) {
+	variables := map[string]interface{}{
+		"dataset": 2024,
+		"dry_run": "true",
+		"format":  "csv",
+		"regions": []interface{}{"eu", "us"},
+		"filter":  map[string]interface{}{"status": "open"},
+		"owner":   "analytics",
+	}
+
+	got, err := ValidateTemplateVariables(reportTemplate(), variables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"dataset": "2024",
+		"limit":   100,
+		"dry_run": true,
+		"format":  "csv",
+		"regions": []interface{}{"eu", "us"},
+		"filter":  map[string]interface{}{"status": "open"},
+		"owner":   "analytics",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if variables["dataset"] != 2024 {
+		t.Error("expected the request's variables to be left unmodified")
+	}
+}
+
+func TestCoerceVariableToInt(t *testing.T) {
+	cases := []struct {
+		value   interface{}
+		want    interface{}
+		invalid bool
+	}{
+		{float64(25), 25, false},
+		{" 25 ", 25, false},
+		{int64(25), 25, false},
+		{2.5, nil, true},
+		{"many", nil, true},
+		{true, nil, true},
+	}
+	for _, c := range cases {
+		got, err := coerceVariable("int", c.value)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%#v: expected an error, got %v", c.value, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%#v: got %v, %v, want %v", c.value, got, err, c.want)
+		}
+	}
+}
+
+func TestValidateTemplateVariablesReportsEveryViolation(t *testing.T) {
+	_, err := ValidateTemplateVariables(reportTemplate(), map[string]interface{}{
+		"limit":   "lots",
+		"format":  "xml",
+		"regions": []interface{}{"eu", "apac", "latam"},
+		"filter":  "status = open",
+	})
+
+	var validationErr *VariableValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a VariableValidationError, got %v", err)
+	}
+
+	want := []VariableViolation{
+		{Variable: "dataset", Kind: ViolationMissingRequired, Message: "dataset is required"},
+		{Variable: "limit", Kind: ViolationInvalidType, Message: `limit: expected int, got "lots"`},
+		{Variable: "format", Kind: ViolationNotAnOption, Message: `format: "xml" not one of csv, json`},
+		{Variable: "regions", Kind: ViolationNotAnOption, Message: `regions: "apac", "latam" not one of eu, us`},
+		{Variable: "filter", Kind: ViolationInvalidType, Message: "filter: expected object, got string"},
+	}
+	if !reflect.DeepEqual(validationErr.Violations, want) {
+		t.Errorf("got violations %+v, want %+v", validationErr.Violations, want)
+	}
+}
+
+func TestGenerateFromTemplateRejectsInvalidVariablesBeforeGenerating(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	templates := NewTemplateManager(t.TempDir())
+	template := newTestTemplate("report")
+	template.Variables = reportTemplate().Variables
+	if err := templates.CreateTemplate(template); err != nil {
+		t.Fatal(err)
+	}
+	generator := NewAIWorkflowGenerator(coordinator, templates, nil)
+
+	_, err := generator.GenerateWorkflowFromTemplate(context.Background(), "report", map[string]interface{}{"format": "xml"}, "")
+	var validationErr *VariableValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) != 2 {
+		t.Fatalf("expected the missing dataset and invalid format reported, got %v", err)
+	}
+	if len(coordinator.requests) != 0 {
+		t.Errorf("expected nothing sent to the AI service, got %d requests", len(coordinator.requests))
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// initialTemplateVersion is the version of templates that don't declare one
+const initialTemplateVersion = "1"
+
+// ErrTemplateVersionExists is returned when saving a template version that is already recorded
+var ErrTemplateVersionExists = errors.New("template version already exists")
+
+// GetTemplateVersion retrieves a specific version of a template
+func (tm *TemplateManager) GetTemplateVersion(id, version string) (*models.Template, error) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	template, exists := tm.versions[id][version]
+	if !exists {
+		return nil, fmt.Errorf("template %s version %s: %w", id, version, ErrTemplateNotFound)
+	}
+
+	return tm.cloneTemplate(template), nil
+}
+
+// ExecutionTemplate returns the template version an execution was started from, falling
+// back to the latest template defining its workflow for executions that didn't record one
+func (tm *TemplateManager) ExecutionTemplate(execution *models.WorkflowExecution) (*models.Template, error) {
+	templateID, _ := execution.Metadata[engine.TemplateMetadataKey].(string)
+	version, _ := execution.Metadata[engine.TemplateVersionMetadataKey].(string)
+	if templateID != "" && version != "" {
+		return tm.GetTemplateVersion(templateID, version)
+	}
+	return tm.FindByWorkflowID(execution.WorkflowID)
+}
+
+// ListTemplateVersions returns the recorded versions of a template, oldest first
+func (tm *TemplateManager) ListTemplateVersions(id string) ([]string, error) {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	versions, exists := tm.versions[id]
+	if !exists {
+		return nil, fmt.Errorf("template %s: %w", id, ErrTemplateNotFound)
+	}
+
+	result := make([]string, 0, len(versions))
+	for version := range versions {
+		result = append(result, version)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareTemplateVersions(result[i], result[j]) < 0
+	})
+	return result, nil
+}
+
+// storeVersion records a template version and makes it the latest unless a newer one is
+// already stored. Callers must hold the write lock.
+func (tm *TemplateManager) storeVersion(template *models.Template, filePath string) {
+	if tm.versions[template.ID] == nil {
+		tm.versions[template.ID] = make(map[string]*models.Template)
+	}
+	tm.versions[template.ID][template.Version] = template
+	tm.files[template.ID] = append(tm.files[template.ID], filePath)
+
+	latest, exists := tm.templates[template.ID]
+	if exists && compareTemplateVersions(template.Version, latest.Version) < 0 {
+		return
+	}
+
+	oldCategory := ""
+	if exists {
+		oldCategory = templateCategory(latest)
+	}
+	tm.templates[template.ID] = template
+	tm.reindex(template, oldCategory)
+}
+
+// nextTemplateVersion returns the version after the latest one by incrementing its last
+// numeric part ("1.0" -> "1.1", "3" -> "4"), or the initial version for a new template
+func nextTemplateVersion(latest *models.Template) string {
+	if latest == nil || latest.Version == "" {
+		return initialTemplateVersion
+	}
+
+	parts := strings.Split(latest.Version, ".")
+	last := len(parts) - 1
+	if n, err := strconv.Atoi(parts[last]); err == nil {
+		parts[last] = strconv.Itoa(n + 1)
+		return strings.Join(parts, ".")
+	}
+	return latest.Version + ".1"
+}
+
+// compareTemplateVersions orders versions part by part, numerically where both parts are
+// numbers. A version that extends another ("1.0.1" and "1.0") is the newer one.
+func compareTemplateVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr == nil && bErr == nil {
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+// isSafeFileName reports whether a template ID or version can be used as a file name
+// inside the templates directory
+func isSafeFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
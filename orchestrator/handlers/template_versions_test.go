@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateVersionsStayPinnable(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewTemplateManager(dir)
+
+	if err := manager.CreateTemplate(newTestTemplate("report")); err != nil {
+		t.Fatal(err)
+	}
+	updated := newTestTemplate("report")
+	updated.Workflow.Tasks[0].ID = "fetch-v2"
+	if err := manager.UpdateTemplate(updated); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := manager.GetTemplate("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != "2" || latest.Workflow.Tasks[0].ID != "fetch-v2" {
+		t.Errorf("got latest version %s with task %s, want the update", latest.Version, latest.Workflow.Tasks[0].ID)
+	}
+
+	pinned, err := manager.GetTemplateVersion("report", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned.Workflow.Tasks[0].ID != "fetch" {
+		t.Errorf("got task %s in pinned version 1, want the original", pinned.Workflow.Tasks[0].ID)
+	}
+
+	// A recorded version is never overwritten
+	overwrite := newTestTemplate("report")
+	overwrite.Version = "1"
+	if err := manager.SaveTemplate(overwrite); !errors.Is(err, ErrTemplateVersionExists) {
+		t.Errorf("got %v saving version 1 again, want ErrTemplateVersionExists", err)
+	}
+	if _, err := manager.GetTemplateVersion("report", "3"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got %v for an unknown version, want ErrTemplateNotFound", err)
+	}
+
+	// Every version survives a restart
+	reloaded := NewTemplateManager(dir)
+	if err := reloaded.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := reloaded.ListTemplateVersions("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"1", "2"}) {
+		t.Errorf("got versions %v after reload, want [1 2]", versions)
+	}
+	if pinned, err := reloaded.GetTemplateVersion("report", "1"); err != nil || pinned.Workflow.Tasks[0].ID != "fetch" {
+		t.Errorf("got %+v, %v for version 1 after reload", pinned, err)
+	}
+}
+
+func TestCompareTemplateVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1", "2", -1},
+		{"2", "10", -1},
+		{"1.0", "1.0.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0", 0},
+		{"1.beta", "1.alpha", 1},
+	}
+	for _, c := range cases {
+		if got := compareTemplateVersions(c.a, c.b); got != c.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+// templateFiles returns the YAML files left under dir
+func templateFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".yaml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+func TestDeleteTemplateRemovesEveryVersion(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewTemplateManager(dir)
+	for i := 0; i < 3; i++ {
+		if err := manager.SaveTemplate(newTestTemplate("report")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := manager.DeleteTemplate("report"); err != nil {
+		t.Fatal(err)
+	}
+	if files := templateFiles(t, dir); len(files) != 0 {
+		t.Errorf("got files %v left after delete", files)
+	}
+	if _, err := manager.GetTemplateVersion("report", "1"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got %v for a deleted version, want ErrTemplateNotFound", err)
+	}
+	if err := manager.DeleteTemplate("report"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got %v deleting again, want ErrTemplateNotFound", err)
+	}
+
+	reloaded := NewTemplateManager(dir)
+	if err := reloaded.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.GetTemplate("report"); err == nil {
+		t.Error("expected no version of the deleted template after reload")
+	}
+}
+
+func TestDeleteTemplateFailureKeepsEveryVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	// Version 1 was written before versioning, in its own file
+	legacy, err := yaml.Marshal(newTestTemplate("report"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.yaml"), legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewTemplateManager(dir)
+	if err := manager.LoadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.UpdateTemplate(newTestTemplate("report")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 2's file can't be set aside, after version 1's already was
+	blocker := filepath.Join(dir, "report", "2.yaml"+deletingSuffix)
+	if err := os.MkdirAll(filepath.Join(blocker, "occupied"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.DeleteTemplate("report"); err == nil {
+		t.Fatal("expected the delete to fail")
+	}
+	if files := templateFiles(t, dir); len(files) != 2 {
+		t.Errorf("got files %v, want both versions restored", files)
+	}
+	for _, version := range []string{"1", "2"} {
+		if _, err := manager.GetTemplateVersion("report", version); err != nil {
+			t.Errorf("version %s: %v", version, err)
+		}
+	}
+
+	os.RemoveAll(blocker)
+	if err := manager.DeleteTemplate("report"); err != nil {
+		t.Fatalf("expected the retried delete to succeed, got %v", err)
+	}
+	if files := templateFiles(t, dir); len(files) != 0 {
+		t.Errorf("got files %v left after the retried delete", files)
+	}
+}
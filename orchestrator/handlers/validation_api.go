@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"orchestrator/engine"
+	"orchestrator/models"
+
+	"github.com/gorilla/mux"
+)
+
+// WorkflowResolver returns the workflow a request runs: its inline definition, a
+// template, or one generated by AI
+type WorkflowResolver func(ctx context.Context, request *models.WorkflowRequest) (*models.WorkflowDefinition, error)
+
+// DryRunner checks a workflow without running it; it is satisfied by engine.WorkflowExecutor
+type DryRunner interface {
+	DryRun(ctx context.Context, workflow *models.WorkflowDefinition, request *models.WorkflowRequest) *engine.DryRunResult
+}
+
+// ValidationAPI checks workflow requests without calling any service on their behalf
+type ValidationAPI struct {
+	resolve  WorkflowResolver
+	executor DryRunner
+}
+
+// NewValidationAPI creates the workflow validation endpoint
+func NewValidationAPI(resolve WorkflowResolver, executor DryRunner) *ValidationAPI {
+	return &ValidationAPI{
+		resolve:  resolve,
+		executor: executor,
+	}
+}
+
+// RegisterRoutes adds POST /workflows/validate to the API router
+func (a *ValidationAPI) RegisterRoutes(api *mux.Router) {
+	api.HandleFunc("/workflows/validate", a.handleValidateWorkflow).Methods("POST")
+}
+
+// DryRun resolves a request's workflow and checks it without running it. Only AI
+// generation contacts a service, since generating is the only way to get its workflow.
+func (a *ValidationAPI) DryRun(ctx context.Context, request *models.WorkflowRequest) (*engine.DryRunResult, error) {
+	workflow, err := a.resolve(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return a.executor.DryRun(ctx, workflow, request), nil
+}
+
+// handleValidateWorkflow checks a workflow request without running it, as dry_run does
+func (a *ValidationAPI) handleValidateWorkflow(w http.ResponseWriter, r *http.Request) {
+	var request models.WorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	request.DryRun = true
+	a.WriteDryRun(w, r, &request)
+}
+
+// WriteDryRun responds with the dry-run result: 200 when the workflow is valid and 422
+// with its problems otherwise
+func (a *ValidationAPI) WriteDryRun(w http.ResponseWriter, r *http.Request, request *models.WorkflowRequest) {
+	result, err := a.DryRun(r.Context(), request)
+	if err != nil {
+		if WriteGraphProblems(w, "Workflow is invalid", err) || WriteTaskParameterProblems(w, "Workflow is invalid", err) {
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to resolve workflow: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.Valid {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(result)
+}
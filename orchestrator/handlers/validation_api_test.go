@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orchestrator/engine"
+	"orchestrator/models"
+	"strings"
+	"testing"
+)
+
+// newValidationAPI checks the workflows in workflows, looked up by the request's template,
+// with a real executor whose coordinator records anything sent to a service
+func newValidationAPI(workflows map[string]*models.WorkflowDefinition) (*ValidationAPI, *fakeCoordinator) {
+	coordinator := &fakeCoordinator{}
+	executor := engine.NewWorkflowExecutor(NewTaskExecutor(coordinator), newMemoryStateManager(), nil, 1)
+	resolve := func(ctx context.Context, request *models.WorkflowRequest) (*models.WorkflowDefinition, error) {
+		switch request.WorkflowTemplate {
+		case "bad-graph":
+			return nil, &engine.DAGValidationError{Problems: []engine.DAGProblem{{Kind: engine.ProblemCycle, Message: "a -> b -> a"}}}
+		case "bad-parameters":
+			return nil, &TaskParameterError{Problems: []TaskParameterProblem{{TaskID: "fetch", Message: "operation is required"}}}
+		}
+		workflow, exists := workflows[request.WorkflowTemplate]
+		if !exists {
+			return nil, fmt.Errorf("template %s: %w", request.WorkflowTemplate, ErrTemplateNotFound)
+		}
+		return workflow, nil
+	}
+	return NewValidationAPI(resolve, executor), coordinator
+}
+
+// validate posts a request to the validation endpoint
+func validate(api *ValidationAPI, body string) *httptest.ResponseRecorder {
+	return serveAPI(api, httptest.NewRequest(http.MethodPost, "/api/v1/workflows/validate", strings.NewReader(body)))
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	api, coordinator := newValidationAPI(map[string]*models.WorkflowDefinition{
+		"valid": {ID: "valid", Tasks: []models.Task{
+			{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query", "limit": "${limit}"}},
+			{ID: "summarize", Type: "ai", Parameters: map[string]interface{}{"prompt": "Summarize ${tasks.fetch.output}"}, DependsOn: []string{"fetch"}},
+		}},
+		"cycle": {ID: "cycle", Tasks: []models.Task{
+			{ID: "a", Type: "data", DependsOn: []string{"b"}},
+			{ID: "b", Type: "data", DependsOn: []string{"a"}},
+		}},
+		"undefined": {ID: "undefined", Tasks: []models.Task{
+			{ID: "fetch", Type: "data", Parameters: map[string]interface{}{"operation": "query", "region": "${region}"}},
+		}},
+	})
+
+	cases := []struct {
+		name    string
+		code    int
+		problem string
+	}{
+		{"valid", http.StatusOK, ""},
+		{"cycle", http.StatusUnprocessableEntity, engine.ProblemCycle},
+		{"undefined", http.StatusUnprocessableEntity, engine.ProblemUndefinedVariable},
+	}
+	for _, c := range cases {
+		recorder := validate(api, `{"workflow_template": "`+c.name+`", "variables": {"limit": 10}}`)
+		if recorder.Code != c.code {
+			t.Errorf("%s: got %d %s, want %d", c.name, recorder.Code, recorder.Body, c.code)
+			continue
+		}
+		var result engine.DryRunResult
+		if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if c.problem == "" {
+			if !result.Valid || len(result.Batches) != 2 {
+				t.Errorf("%s: got %+v, want two batches", c.name, result)
+			}
+			continue
+		}
+		if result.Valid || len(result.Problems) == 0 || result.Problems[0].Kind != c.problem {
+			t.Errorf("%s: got %+v, want a %s problem", c.name, result, c.problem)
+		}
+	}
+
+	if len(coordinator.requests) != 0 {
+		t.Errorf("validation sent %d service requests", len(coordinator.requests))
+	}
+}
+
+func TestValidateWorkflowErrors(t *testing.T) {
+	api, _ := newValidationAPI(nil)
+
+	cases := []struct {
+		name string
+		body string
+		code int
+		want string
+	}{
+		{"malformed body", "{", http.StatusBadRequest, "Invalid request body"},
+		{"unresolvable", `{"workflow_template": "missing"}`, http.StatusBadRequest, "Failed to resolve workflow"},
+		{"invalid graph", `{"workflow_template": "bad-graph"}`, http.StatusUnprocessableEntity, `"problems"`},
+		{"invalid parameters", `{"workflow_template": "bad-parameters"}`, http.StatusUnprocessableEntity, "operation is required"},
+	}
+	for _, c := range cases {
+		recorder := validate(api, c.body)
+		if recorder.Code != c.code || !strings.Contains(recorder.Body.String(), c.want) {
+			t.Errorf("%s: got %d %q, want %d containing %q", c.name, recorder.Code, recorder.Body, c.code, c.want)
+		}
+	}
+}
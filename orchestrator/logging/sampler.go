@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sampleKeys are the fields that identify a request, in order of preference. Hashing them
+// keeps every log line of a sampled request or execution, rather than scattered lines.
+var sampleKeys = []string{"correlation_id", "execution_id"}
+
+// Sampler thins out high-volume per-request logs. One in every rate requests is logged,
+// along with anything matching a filter and anything carrying an error. A nil Sampler
+// logs everything.
+type Sampler struct {
+	rate    uint32
+	filters map[string]string
+	counter uint32
+}
+
+// NewSampler creates a sampler logging one in rate requests. Filters are "key=value"
+// pairs; entries with a matching field are always logged.
+func NewSampler(rate int, filters []string) (*Sampler, error) {
+	if rate < 1 {
+		rate = 1
+	}
+
+	parsed := make(map[string]string, len(filters))
+	for _, filter := range filters {
+		filter = strings.TrimSpace(filter)
+		if filter == "" {
+			continue
+		}
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid log sample filter %q, expected key=value", filter)
+		}
+		parsed[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return &Sampler{
+		rate:    uint32(rate),
+		filters: parsed,
+	}, nil
+}
+
+// Allow reports whether an entry with these fields should be logged
+func (s *Sampler) Allow(fields logrus.Fields) bool {
+	if s == nil || s.rate <= 1 {
+		return true
+	}
+
+	// Failures are never sampled out
+	if _, hasError := fields[logrus.ErrorKey]; hasError {
+		return true
+	}
+	if success, ok := fields["success"].(bool); ok && !success {
+		return true
+	}
+
+	for key, value := range s.filters {
+		if field, ok := fields[key]; ok && fmt.Sprint(field) == value {
+			return true
+		}
+	}
+
+	for _, key := range sampleKeys {
+		if id, ok := fields[key].(string); ok && id != "" {
+			hash := fnv.New32a()
+			hash.Write([]byte(id))
+			return hash.Sum32()%s.rate == 0
+		}
+	}
+
+	return atomic.AddUint32(&s.counter, 1)%s.rate == 0
+}
+
+// Info logs the entry at info level when the sampler allows it
+func (s *Sampler) Info(logger logrus.FieldLogger, fields logrus.Fields, msg string) {
+	if s.Allow(fields) {
+		logger.WithFields(fields).Info(msg)
+	}
+}
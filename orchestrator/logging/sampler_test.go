@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestNewSamplerRejectsInvalidFilters(t *testing.T) {
+	for _, filter := range []string{"service", "=data"} {
+		if _, err := NewSampler(10, []string{filter}); err == nil {
+			t.Errorf("expected filter %q to be rejected", filter)
+		}
+	}
+}
+
+func TestSamplerKeepsWholeRequests(t *testing.T) {
+	sampler, err := NewSampler(10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("exec-%d", i)
+		allowed := sampler.Allow(logrus.Fields{"execution_id": id, "task_id": "fetch"})
+		// Every line of a request gets the same decision
+		for _, task := range []string{"clean", "report"} {
+			if sampler.Allow(logrus.Fields{"execution_id": id, "task_id": task}) != allowed {
+				t.Fatalf("got different decisions for lines of execution %s", id)
+			}
+		}
+		if allowed {
+			logged++
+		}
+	}
+	if logged < 50 || logged > 150 {
+		t.Errorf("logged %d of 1000 requests, want about 100", logged)
+	}
+}
+
+func TestSamplerCountsEntriesWithoutRequestID(t *testing.T) {
+	sampler, err := NewSampler(4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logged := 0
+	for i := 0; i < 12; i++ {
+		if sampler.Allow(logrus.Fields{"service": "data"}) {
+			logged++
+		}
+	}
+	if logged != 3 {
+		t.Errorf("logged %d of 12 entries, want 3", logged)
+	}
+}
+
+func TestSamplerAlwaysLogsFailuresAndFilteredEntries(t *testing.T) {
+	sampler, err := NewSampler(1000000, []string{" workflow_id = nightly-report "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An ID whose hash is sampled out, so only the other fields can let it through
+	id := "exec-0"
+	for i := 1; sampler.Allow(logrus.Fields{"execution_id": id}); i++ {
+		id = fmt.Sprintf("exec-%d", i)
+	}
+
+	cases := []struct {
+		name   string
+		fields logrus.Fields
+		want   bool
+	}{
+		{"sampled out", logrus.Fields{"execution_id": id}, false},
+		{"error", logrus.Fields{"execution_id": id, logrus.ErrorKey: errors.New("boom")}, true},
+		{"unsuccessful", logrus.Fields{"execution_id": id, "success": false}, true},
+		{"successful", logrus.Fields{"execution_id": id, "success": true}, false},
+		{"matching filter", logrus.Fields{"execution_id": id, "workflow_id": "nightly-report"}, true},
+		{"other value", logrus.Fields{"execution_id": id, "workflow_id": "hourly-report"}, false},
+	}
+	for _, c := range cases {
+		if got := sampler.Allow(c.fields); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSamplerInfo(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	var unsampled *Sampler
+	unsampled.Info(logger, logrus.Fields{"execution_id": "exec-1"}, "Executing task")
+	if len(hook.Entries) != 1 || hook.LastEntry().Data["execution_id"] != "exec-1" {
+		t.Fatalf("got %d entries, want a nil sampler to log everything", len(hook.Entries))
+	}
+
+	sampler, err := NewSampler(2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		sampler.Info(logger, logrus.Fields{"service": "data"}, "Sending request")
+	}
+	if len(hook.Entries) != 3 {
+		t.Errorf("got %d entries, want 2 of the 4 sampled lines logged", len(hook.Entries)-1)
+	}
+}
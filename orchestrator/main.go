@@ -1,22 +1,31 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"orchestrator/capabilities"
 	"orchestrator/clients"
 	"orchestrator/config"
 	"orchestrator/engine"
 	"orchestrator/handlers"
+	"orchestrator/logging"
+	"orchestrator/metrics"
 	"orchestrator/models"
+	"orchestrator/tracing"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -29,7 +38,14 @@ type OrchestratorServer struct {
 	messageCoordinator *clients.RedisMessageCoordinator
 	templateManager    *handlers.TemplateManager
 	serviceRegistry    *clients.ServiceRegistry
+	artifactStore      *clients.FileArtifactStore
+	flagStore          *clients.RedisFlagStore
+	progressPublisher  *clients.RedisProgressPublisher
+	eventLog           *clients.RedisEventLog
+	workflowQueue      *clients.RedisWorkflowQueue
+	queueWake          chan struct{} // signalled when a request is enqueued
 	aiGenerator        *handlers.AIWorkflowGenerator
+	validation         *handlers.ValidationAPI
 	taskExecutor       *handlers.TaskExecutorImpl
 	workflowExecutor   *engine.WorkflowExecutor
 	recoveryManager    *handlers.RecoveryManager
@@ -51,6 +67,12 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.Info("Starting Orchestrator service")
 
+	// Setup tracing; spans are only exported when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), "orchestrator", cfg.Tracing.OTLPEndpoint)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to setup tracing")
+	}
+
 	// Create orchestrator server
 	server, err := NewOrchestratorServer(cfg, logger)
 	if err != nil {
@@ -61,6 +83,13 @@ func main() {
 	if err := server.Start(); err != nil {
 		logger.WithError(err).Fatal("Failed to start orchestrator server")
 	}
+
+	// Flush spans still buffered for export
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.WithError(err).Warn("Failed to flush traces")
+	}
 }
 
 func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*OrchestratorServer, error) {
@@ -82,7 +111,7 @@ func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*Orchestr
 	// Create state manager
 	stateManager := clients.NewRedisStateManager(
 		redisClient,
-		"orchestrator",
+		config.Namespaced(cfg.Redis.Namespace, "orchestrator"),
 		cfg.Orchestrator.ExecutionTTL,
 	)
 
@@ -90,37 +119,79 @@ func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*Orchestr
 	messageCoordinator := clients.NewRedisMessageCoordinator(
 		redisClient,
 		clients.ServiceChannelConfig{
-			RequestChannel:  cfg.Services.DataService.RequestChannel,
-			ResponseChannel: cfg.Services.DataService.ResponseChannel,
-			Timeout:         cfg.Services.DataService.Timeout,
+			RequestChannel:    cfg.Services.DataService.RequestChannel,
+			ResponseChannel:   cfg.Services.DataService.ResponseChannel,
+			DeadLetterChannel: cfg.Services.DataService.DeadLetterChannel,
+			Timeout:           cfg.Services.DataService.Timeout,
 		},
 		clients.ServiceChannelConfig{
-			RequestChannel:  cfg.Services.AIService.RequestChannel,
-			ResponseChannel: cfg.Services.AIService.ResponseChannel,
-			Timeout:         cfg.Services.AIService.Timeout,
+			RequestChannel:    cfg.Services.AIService.RequestChannel,
+			ResponseChannel:   cfg.Services.AIService.ResponseChannel,
+			DeadLetterChannel: cfg.Services.AIService.DeadLetterChannel,
+			Timeout:           cfg.Services.AIService.Timeout,
 		},
 		clients.ServiceChannelConfig{
-			RequestChannel:  cfg.Services.ExecService.RequestChannel,
-			ResponseChannel: cfg.Services.ExecService.ResponseChannel,
-			Timeout:         cfg.Services.ExecService.Timeout,
+			RequestChannel:    cfg.Services.ExecService.RequestChannel,
+			ResponseChannel:   cfg.Services.ExecService.ResponseChannel,
+			DeadLetterChannel: cfg.Services.ExecService.DeadLetterChannel,
+			Timeout:           cfg.Services.ExecService.Timeout,
 		},
 		cfg.Services.DefaultTimeout,
+		clients.CircuitBreakerConfig{
+			Threshold: cfg.Services.CircuitBreakerThreshold,
+			Cooldown:  cfg.Services.CircuitBreakerCooldown,
+		},
 	)
+	messageCoordinator.SetAdaptiveTimeout(cfg.Services.QueueWaitPerRequest, cfg.Services.MaxResponseWait)
 
 	// Create template manager
 	templateManager := handlers.NewTemplateManager(cfg.Orchestrator.TemplatesDir)
+	templateManager.SetStrictMode(cfg.Orchestrator.StrictTemplates)
 	if err := templateManager.LoadTemplates(); err != nil {
+		if cfg.Orchestrator.StrictTemplates {
+			logger.WithError(err).Fatal("Failed to load templates in strict mode")
+		}
 		logger.WithError(err).Warn("Failed to load templates, continuing without templates")
 	}
 
 	// Create service registry
-	serviceRegistry := clients.NewServiceRegistry(redisClient, 0) // Use default stale threshold
+	serviceRegistry := clients.NewServiceRegistry(redisClient, 0, cfg.Redis.Namespace) // Use default stale threshold
+	serviceRegistry.SetVerificationSecret(cfg.Capabilities.SigningSecret)
+
+	// Balance requests across service replicas announced to the registry
+	instanceBalancer, err := clients.NewInstanceBalancer(serviceRegistry, cfg.Services.LoadBalanceStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid load balancing strategy: %w", err)
+	}
+	messageCoordinator.SetInstanceBalancer(instanceBalancer)
+
+	// Sample per-request logs so high load does not flood the log backend
+	logSampler, err := logging.NewSampler(cfg.Logging.SampleRate, cfg.Logging.SampleFilters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log sampling configuration: %w", err)
+	}
+	messageCoordinator.SetLogSampler(logSampler)
 
 	// Create AI generator
 	aiGenerator := handlers.NewAIWorkflowGenerator(messageCoordinator, templateManager, serviceRegistry)
-
-	// Create task executor
-	taskExecutor := handlers.NewTaskExecutor(messageCoordinator)
+	if cfg.Orchestrator.PromptsDir != "" {
+		aiGenerator.SetPromptTemplates(handlers.NewPromptTemplates(cfg.Orchestrator.PromptsDir))
+	}
+	aiGenerator.SetGenerationProviders(handlers.ParseGenerationProviders(cfg.Orchestrator.GenerationProviders))
+	aiGenerator.SetRegenerationRetries(cfg.Orchestrator.GenerationRetries)
+
+	// Create artifact store for incremental task outputs and service recordings
+	artifactStore := clients.NewFileArtifactStore(cfg.Orchestrator.ArtifactsDir)
+
+	// Create task executor; service calls can be recorded and replayed per execution
+	taskExecutor := handlers.NewTaskExecutor(clients.NewReplayCoordinator(messageCoordinator, artifactStore))
+	if cfg.Orchestrator.ServiceAccessPolicy != "" {
+		policy, err := handlers.LoadServiceAccessPolicy(cfg.Orchestrator.ServiceAccessPolicy)
+		if err != nil {
+			return nil, err
+		}
+		taskExecutor.SetServiceAccessPolicy(policy)
+	}
 
 	// Create workflow executor
 	workflowExecutor := engine.NewWorkflowExecutor(
@@ -130,6 +201,68 @@ func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*Orchestr
 		cfg.Orchestrator.MaxConcurrent,
 	)
 
+	// Workflow tasks run templates as nested executions of the same executor
+	taskExecutor.SetSubWorkflows(templateManager, workflowExecutor, cfg.Orchestrator.MaxSubWorkflowDepth)
+
+	workflowExecutor.SetDefaultQueueTimeout(cfg.Orchestrator.TaskQueueTimeout)
+	workflowExecutor.SetBatchAbandonGrace(cfg.Orchestrator.BatchAbandonGrace)
+	workflowExecutor.SetArtifactStore(artifactStore)
+	workflowExecutor.SetServiceRegistry(serviceRegistry)
+	workflowExecutor.SetServiceGracePeriod(cfg.Services.AvailabilityGrace)
+
+	// Create feature flag store
+	flagStore := clients.NewRedisFlagStore(redisClient, config.Namespaced(cfg.Redis.Namespace, "orchestrator"))
+	workflowExecutor.SetFlagStore(flagStore)
+
+	// Publish task state changes for clients streaming an execution's progress
+	progressPublisher := clients.NewRedisProgressPublisher(redisClient, config.Namespaced(cfg.Redis.Namespace, "workflow-progress"))
+	workflowExecutor.SetProgressPublisher(progressPublisher)
+
+	// Keep a durable log of each execution's transitions, retries and recovery actions
+	eventLog := clients.NewRedisEventLog(redisClient, config.Namespaced(cfg.Redis.Namespace, "orchestrator"), cfg.Orchestrator.ExecutionTTL)
+	workflowExecutor.SetEventLog(eventLog)
+
+	// Export finished executions to an external sink if configured
+	exportHeaders := map[string]string{}
+	if cfg.Export.Authorization != "" {
+		exportHeaders["Authorization"] = cfg.Export.Authorization
+	}
+	if cfg.Export.KafkaRESTURL != "" {
+		producer := clients.NewKafkaRESTProducer(cfg.Export.KafkaRESTURL, exportHeaders)
+		workflowExecutor.SetExecutionExporter(clients.NewKafkaExecutionExporter(producer, cfg.Export.KafkaTopic))
+	} else if cfg.Export.HTTPURL != "" {
+		workflowExecutor.SetExecutionExporter(clients.NewHTTPExecutionExporter(cfg.Export.HTTPURL, exportHeaders))
+	}
+	workflowExecutor.SetExportTokenPrice(cfg.Export.TokenPrice)
+
+	// POST workflow responses to the callback_url of requests that set one
+	callbackOptions := clients.DefaultCallbackOptions
+	callbackOptions.MaxAttempts = cfg.Callback.MaxAttempts
+	callbackOptions.InitialDelay = cfg.Callback.InitialDelay
+	callbackOptions.AttemptTimeout = cfg.Callback.AttemptTimeout
+	callbackOptions.AllowedHosts = cfg.Callback.AllowedHosts
+	callbackOptions.AllowPrivate = cfg.Callback.AllowPrivate
+	workflowExecutor.SetCallbackNotifier(clients.NewHTTPCallbackNotifier(cfg.Callback.SigningSecret, callbackOptions))
+
+	// Refresh execution heartbeats while workflows run
+	workflowExecutor.SetHeartbeat(stateManager, cfg.Orchestrator.HeartbeatTTL)
+	workflowExecutor.SetInterpolationLimits(cfg.Orchestrator.MaxInterpolationDepth, cfg.Orchestrator.MaxInterpolationNodes)
+	workflowExecutor.SetLogSampler(logSampler)
+	workflowExecutor.SetSecretProvider(clients.NewFileSecretProvider(cfg.Orchestrator.SecretsDir))
+	workflowExecutor.SetResultCache(stateManager, cfg.Orchestrator.ResultCacheTTL)
+	workflowExecutor.SetGlobalTaskLimit(cfg.Orchestrator.MaxConcurrentTasks)
+
+	// Gauges read on every scrape of /metrics
+	metrics.RegisterGaugeFunc("orchestrator_active_executions",
+		"Workflow executions running on this instance.",
+		func() float64 { return float64(workflowExecutor.ActiveExecutions()) })
+	metrics.RegisterGaugeFunc("orchestrator_pending_service_requests",
+		"Service requests waiting for a response.",
+		func() float64 { return float64(messageCoordinator.PendingRequests()) })
+	metrics.RegisterGaugeFunc("orchestrator_inflight_tasks",
+		"Tasks holding a slot under MAX_CONCURRENT_TASKS.",
+		func() float64 { return float64(workflowExecutor.InFlightTasks()) })
+
 	// Create recovery manager
 	recoveryManager := handlers.NewRecoveryManager(
 		stateManager,
@@ -137,6 +270,11 @@ func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*Orchestr
 		templateManager,
 		cfg.Orchestrator.RecoveryInterval,
 	)
+	recoveryManager.SetEventLog(eventLog)
+	recoveryManager.SetMaxRecoveryAttempts(cfg.Orchestrator.MaxRecoveryAttempts)
+	if cfg.Orchestrator.HeartbeatTTL > 0 {
+		recoveryManager.SetHeartbeatWatchdog(stateManager, cfg.Orchestrator.HeartbeatTTL/2)
+	}
 
 	// Create capability manager if enabled
 	var capabilityManager *capabilities.CapabilityManager
@@ -146,28 +284,40 @@ func NewOrchestratorServer(cfg *config.Config, logger *logrus.Logger) (*Orchestr
 			capabilities.GetOrchestratorCapabilities(),
 			redisClient,
 			cfg.Capabilities.RefreshInterval,
+			cfg.Redis.Namespace,
 		)
+		capabilityManager.SetSigningSecret(cfg.Capabilities.SigningSecret)
+		capabilityManager.SetDebounceWindow(cfg.Capabilities.AnnounceDebounce)
 	}
 
-	return &OrchestratorServer{
+	server := &OrchestratorServer{
 		config:             cfg,
 		redisClient:        redisClient,
 		stateManager:       stateManager,
 		messageCoordinator: messageCoordinator,
 		templateManager:    templateManager,
 		serviceRegistry:    serviceRegistry,
+		artifactStore:      artifactStore,
+		flagStore:          flagStore,
+		progressPublisher:  progressPublisher,
+		eventLog:           eventLog,
+		workflowQueue:      clients.NewRedisWorkflowQueue(redisClient, config.Namespaced(cfg.Redis.Namespace, "orchestrator"), cfg.Orchestrator.InstanceID),
+		queueWake:          make(chan struct{}, 1),
 		aiGenerator:        aiGenerator,
 		taskExecutor:       taskExecutor,
 		workflowExecutor:   workflowExecutor,
 		recoveryManager:    recoveryManager,
 		capabilityManager:  capabilityManager,
 		logger:             logger,
-	}, nil
+	}
+	server.validation = handlers.NewValidationAPI(server.resolveWorkflow, workflowExecutor)
+	return server, nil
 }
 
 func (s *OrchestratorServer) Start() error {
 	// Start recovery manager if enabled
 	if s.config.Orchestrator.RecoveryEnabled {
+		s.recoveryManager.SetResponseHandler(s.sendWorkflowResponse)
 		s.recoveryManager.Start(context.Background())
 		defer s.recoveryManager.Stop()
 	}
@@ -191,7 +341,17 @@ func (s *OrchestratorServer) Start() error {
 	}
 
 	// Start message bus listener
-	go s.startMessageListener()
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	defer stopListener()
+	go s.startMessageListener(listenerCtx)
+
+	// Queue again requests a previous run of this instance took but never started
+	if requeued, err := s.workflowQueue.RequeueProcessing(context.Background()); err != nil {
+		s.logger.WithError(err).Error("Failed to requeue unstarted workflow requests")
+	} else if requeued > 0 {
+		s.logger.WithField("count", requeued).Info("Requeued unstarted workflow requests")
+	}
+	go s.dispatchQueuedWorkflows(listenerCtx)
 
 	// Start HTTP server
 	router := s.setupRoutes()
@@ -217,13 +377,24 @@ func (s *OrchestratorServer) Start() error {
 
 	s.logger.Info("Shutting down server...")
 
+	// Stop accepting new workflow requests from the message bus
+	stopListener()
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
 	defer cancel()
 
+	// Running workflows are drained even if open connections outlived the timeout
 	if err := server.Shutdown(ctx); err != nil {
 		s.logger.WithError(err).Error("Failed to shutdown server gracefully")
-		return err
+	}
+
+	// Let running workflows finish; stragglers are cancelled and saved as such
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), s.config.Server.DrainTimeout)
+	defer cancelDrain()
+
+	if err := s.workflowExecutor.Drain(drainCtx); err != nil {
+		s.logger.WithError(err).Warn("Workflow executions did not drain cleanly")
 	}
 
 	// Stop capability manager if running
@@ -258,21 +429,45 @@ func (s *OrchestratorServer) setupRoutes() *mux.Router {
 	// API routes
 	api := router.PathPrefix("/api/v1").Subrouter()
 	api.HandleFunc("/workflows", s.handleExecuteWorkflow).Methods("POST")
+	api.HandleFunc("/workflows", s.handleListWorkflows).Methods("GET")
+	s.validation.RegisterRoutes(api)
+	handlers.NewMatrixAPI(s.stateManager, s.resolveTemplate, s.enqueueWorkflowRequest, s.logger).RegisterRoutes(api)
 	api.HandleFunc("/workflows/{id}", s.handleGetWorkflow).Methods("GET")
 	api.HandleFunc("/workflows/{id}/status", s.handleGetWorkflowStatus).Methods("GET")
-	
+	handlers.NewExecutionControlAPI(s.stateManager, s.workflowExecutor, s.recoveryManager, s.templateManager, s.logger).RegisterRoutes(api)
+	handlers.NewExecutionHistoryAPI(s.stateManager, s.eventLog, s.artifactStore).RegisterRoutes(api)
+	handlers.NewTaskStateAPI(s.stateManager).RegisterRoutes(api)
+	handlers.NewProgressStreamAPI(s.stateManager, s.progressPublisher, s.logger).RegisterRoutes(api)
+	handlers.NewGraphAPI(s.stateManager, s.templateManager).RegisterRoutes(api)
+
 	// Template routes
 	api.HandleFunc("/templates", s.handleListTemplates).Methods("GET")
+	api.HandleFunc("/templates/errors", s.handleGetTemplateErrors).Methods("GET")
 	api.HandleFunc("/templates/{id}", s.handleGetTemplate).Methods("GET")
+	api.HandleFunc("/templates/{id}/versions", s.handleListTemplateVersions).Methods("GET")
+	api.HandleFunc("/templates/{id}/versions/{version}", s.handleGetTemplateVersion).Methods("GET")
 	api.HandleFunc("/templates/categories/{category}", s.handleGetTemplatesByCategory).Methods("GET")
+	handlers.NewTemplateAPI(s.templateManager).RegisterRoutes(api)
+
+	// Service routes; lifecycle events are a server-sent event stream
+	handlers.NewServicesAPI(s.serviceRegistry).RegisterRoutes(api)
+	handlers.NewServiceEventsAPI(s.serviceRegistry).RegisterRoutes(api)
+
+	// Feature flag admin routes
+	handlers.NewFlagsAPI(s.flagStore).RegisterRoutes(api)
+
+	// JSON Schemas of the definition models, for clients to validate against
+	handlers.NewSchemaAPI().RegisterRoutes(api)
 	
 	// AI generation routes
 	api.HandleFunc("/generate", s.handleGenerateWorkflow).Methods("POST")
 	api.HandleFunc("/generate/from-template/{id}", s.handleGenerateFromTemplate).Methods("POST")
-	
+
 	// Health and status routes
-	router.HandleFunc("/health", s.handleHealthCheck).Methods("GET")
+	handlers.NewHealthAPI(s.redisClient, s.serviceRegistry, s.config.Services.CriticalServices).RegisterRoutes(router)
 	router.HandleFunc("/status", s.handleStatus).Methods("GET")
+	router.HandleFunc("/config", s.handleConfig).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 	
 	// Add CORS middleware
 	router.Use(corsMiddleware)
@@ -281,12 +476,18 @@ func (s *OrchestratorServer) setupRoutes() *mux.Router {
 	return router
 }
 
-func (s *OrchestratorServer) startMessageListener() {
+func (s *OrchestratorServer) startMessageListener(ctx context.Context) {
 	s.logger.Info("Starting workflow request listener")
-	
-	pubsub := s.redisClient.Subscribe(context.Background(), "workflow-requests")
+
+	pubsub := s.redisClient.Subscribe(ctx, config.Namespaced(s.config.Redis.Namespace, "workflow-requests"))
 	defer pubsub.Close()
 
+	// Closing the subscription ends the loop below
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
 	ch := pubsub.Channel()
 	
 	for msg := range ch {
@@ -296,44 +497,257 @@ func (s *OrchestratorServer) startMessageListener() {
 			continue
 		}
 
-		// Process workflow request asynchronously
-		go s.processWorkflowRequest(context.Background(), &request)
+		if err := s.enqueueWorkflowRequest(ctx, &request); err != nil {
+			s.logger.WithError(err).WithField("correlation_id", request.CorrelationID).Error("Failed to queue workflow request")
+			s.sendWorkflowResponse(request.CorrelationID, nil, err)
+		}
 	}
 }
 
-func (s *OrchestratorServer) processWorkflowRequest(ctx context.Context, request *models.WorkflowRequest) {
-	s.logger.WithField("correlation_id", request.CorrelationID).Info("Processing workflow request")
+// enqueueWorkflowRequest queues a request and wakes the dispatcher. It runs when a worker
+// is free and nothing of higher priority waits, whichever way it was submitted.
+func (s *OrchestratorServer) enqueueWorkflowRequest(ctx context.Context, request *models.WorkflowRequest) error {
+	if err := s.workflowQueue.Enqueue(ctx, request); err != nil {
+		return err
+	}
+	select {
+	case s.queueWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
 
-	var workflow *models.WorkflowDefinition
-	var err error
+// queuePollInterval is how often an idle dispatcher checks for requests queued by
+// other instances
+const queuePollInterval = time.Second
 
-	// Determine workflow source
-	if request.GenerateFromAI != nil {
-		// Generate workflow using AI
-		workflow, err = s.aiGenerator.GenerateWorkflow(ctx, request.GenerateFromAI)
-	} else if request.WorkflowTemplate != "" {
-		// Load workflow from template
-		template, templateErr := s.templateManager.GetTemplate(request.WorkflowTemplate)
-		if templateErr != nil {
-			s.sendWorkflowResponse(request.CorrelationID, nil, templateErr)
+// dispatchQueuedWorkflows runs queued workflow requests in priority order, at most
+// MaxConcurrent at a time, until ctx is cancelled. Running workflows are left to Drain.
+func (s *OrchestratorServer) dispatchQueuedWorkflows(ctx context.Context) {
+	workers := s.config.Orchestrator.MaxConcurrent
+	if workers <= 0 {
+		workers = 1
+	}
+	slots := make(chan struct{}, workers)
+
+	poll := time.NewTicker(queuePollInterval)
+	defer poll.Stop()
+
+	for {
+		// Only take a request once a worker is free, so later high-priority requests
+		// are not stuck behind ones already taken
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
 			return
 		}
-		workflow = &template.Workflow
-	} else {
-		s.sendWorkflowResponse(request.CorrelationID, nil, fmt.Errorf("no workflow source specified"))
+
+		queued, err := s.workflowQueue.Dequeue(ctx)
+		if err != nil || queued == nil {
+			<-slots
+			if err != nil && ctx.Err() == nil {
+				s.logger.WithError(err).Error("Failed to read workflow queue")
+			}
+			select {
+			case <-s.queueWake:
+			case <-poll.C:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		go func() {
+			defer func() { <-slots }()
+			// The request leaves the processing set once its execution is saved, where
+			// recovery takes over, or once it finishes without one
+			var ack sync.Once
+			acknowledge := func() {
+				ack.Do(func() {
+					if err := s.workflowQueue.Ack(context.Background(), queued); err != nil {
+						s.logger.WithError(err).Error("Failed to acknowledge queued workflow request")
+					}
+				})
+			}
+			defer acknowledge()
+			started := engine.WithExecutionStarted(context.Background(), func(string) { acknowledge() })
+			s.submitWorkflowRequest(started, queued.Request)
+		}()
+	}
+}
+
+// submitWorkflowRequest processes a request from the message bus, answering a repeated
+// idempotency key with the existing execution instead of starting another
+func (s *OrchestratorServer) submitWorkflowRequest(ctx context.Context, request *models.WorkflowRequest) {
+	// Dry runs start nothing, so they neither claim nor answer idempotency keys
+	if request.DryRun {
+		s.sendDryRunResponse(ctx, request)
+		return
+	}
+
+	existing, err := s.claimIdempotencyKey(ctx, request)
+	if err != nil {
+		s.sendWorkflowResponse(request.CorrelationID, nil, err)
+		return
+	}
+	if existing != nil {
+		s.sendWorkflowResponse(request.CorrelationID, existing, nil)
+		return
+	}
+
+	s.processWorkflowRequest(ctx, request)
+}
+
+// claimIdempotencyKey assigns the request its execution ID up front and claims its
+// idempotency key, if any. When the key was already claimed it returns the response
+// of the execution holding it.
+func (s *OrchestratorServer) claimIdempotencyKey(ctx context.Context, request *models.WorkflowRequest) (*models.WorkflowResponse, error) {
+	if request.IdempotencyKey == "" {
+		return nil, nil
+	}
+	if request.ExecutionID == "" {
+		request.ExecutionID = fmt.Sprintf("exec_%s", uuid.New().String())
+	}
+
+	claimed, executionID, err := s.stateManager.ClaimIdempotencyKey(ctx, request.IdempotencyKey, request.ExecutionID, s.config.Orchestrator.IdempotencyTTL)
+	if err != nil {
+		return nil, err
+	}
+	// A request queued after claiming its key holds the claim already
+	if claimed || executionID == request.ExecutionID {
+		return nil, nil
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"correlation_id":  request.CorrelationID,
+		"idempotency_key": request.IdempotencyKey,
+		"execution_id":    executionID,
+	}).Info("Duplicate workflow request, returning existing execution")
+
+	return s.existingExecutionResponse(ctx, request, executionID), nil
+}
+
+// releaseIdempotencyKey frees the key of a request whose execution never started
+func (s *OrchestratorServer) releaseIdempotencyKey(request *models.WorkflowRequest) {
+	if request.IdempotencyKey == "" {
 		return
 	}
+	if err := s.stateManager.ReleaseIdempotencyKey(context.Background(), request.IdempotencyKey, request.ExecutionID); err != nil {
+		s.logger.WithError(err).WithField("idempotency_key", request.IdempotencyKey).Warn("Failed to release idempotency key")
+	}
+}
+
+// existingExecutionResponse describes the execution a duplicate request maps to. An
+// execution that has not been saved yet is reported as pending.
+func (s *OrchestratorServer) existingExecutionResponse(ctx context.Context, request *models.WorkflowRequest, executionID string) *models.WorkflowResponse {
+	response := &models.WorkflowResponse{
+		CorrelationID: request.CorrelationID,
+		ExecutionID:   executionID,
+		Status:        models.StatusPending,
+		Timestamp:     time.Now(),
+		Duplicate:     true,
+	}
+
+	execution, err := s.stateManager.LoadExecution(ctx, executionID)
+	if err != nil {
+		return response
+	}
+
+	response.CorrelationID = execution.CorrelationID
+	response.Status = execution.Status
+	response.Success = execution.Status == models.StatusCompleted
+	response.Error = execution.Error
+	response.InitialVariables = execution.InitialVariables
+	response.VariableChanges = execution.VariableChanges
+	if execution.EndTime != nil {
+		response.Duration = execution.EndTime.Sub(execution.StartTime)
+	}
+
+	if response.Success {
+		taskResults := make(map[string]interface{})
+		for taskID, state := range execution.TaskStates {
+			if len(state.Output) > 0 {
+				taskResults[taskID] = state.Output
+			}
+		}
+		response.TaskResults = taskResults
+	}
+
+	return response
+}
+
+func (s *OrchestratorServer) processWorkflowRequest(ctx context.Context, request *models.WorkflowRequest) {
+	s.logger.WithField("correlation_id", request.CorrelationID).Info("Processing workflow request")
 
+	workflow, err := s.resolveWorkflow(ctx, request)
 	if err != nil {
+		s.releaseIdempotencyKey(request)
 		s.sendWorkflowResponse(request.CorrelationID, nil, err)
 		return
 	}
 
 	// Execute workflow
 	response, err := s.workflowExecutor.ExecuteWorkflow(ctx, workflow, request)
+	if response == nil && err != nil {
+		// Failed before the execution was recorded
+		s.releaseIdempotencyKey(request)
+	}
 	s.sendWorkflowResponse(request.CorrelationID, response, err)
 }
 
+// resolveWorkflow returns the definition a request runs, generated by the AI or taken from
+// a template whose preconditions hold. Template variable defaults are applied to the request.
+func (s *OrchestratorServer) resolveWorkflow(ctx context.Context, request *models.WorkflowRequest) (*models.WorkflowDefinition, error) {
+	if request.GenerateFromAI != nil {
+		return s.aiGenerator.GenerateWorkflow(ctx, request.GenerateFromAI)
+	}
+	if request.WorkflowTemplate == "" {
+		return nil, fmt.Errorf("no workflow source specified")
+	}
+
+	template, err := s.resolveTemplate(request.WorkflowTemplate, request.TemplateVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := handlers.CheckPreconditions(template, request.Variables); err != nil {
+		return nil, err
+	}
+	request.Variables = handlers.ApplyVariableDefaults(template, request.Variables)
+	request.TemplateVersion = template.Version
+	return &template.Workflow, nil
+}
+
+// sendDryRunResponse answers a dry-run request from the message bus with the plan and
+// problems under results.dry_run
+func (s *OrchestratorServer) sendDryRunResponse(ctx context.Context, request *models.WorkflowRequest) {
+	result, err := s.validation.DryRun(ctx, request)
+	if err != nil {
+		s.sendWorkflowResponse(request.CorrelationID, nil, err)
+		return
+	}
+
+	response := &models.WorkflowResponse{
+		CorrelationID: request.CorrelationID,
+		Status:        models.StatusCompleted,
+		Success:       result.Valid,
+		Results:       map[string]interface{}{"dry_run": result},
+		Timestamp:     time.Now(),
+	}
+	if !result.Valid {
+		response.Status = models.StatusFailed
+		response.Error = (&engine.DAGValidationError{Problems: result.Problems}).Error()
+	}
+	s.sendWorkflowResponse(request.CorrelationID, response, nil)
+}
+
+// resolveTemplate returns a pinned version of a template, or its latest when version is empty
+func (s *OrchestratorServer) resolveTemplate(id, version string) (*models.Template, error) {
+	if version == "" {
+		return s.templateManager.GetTemplate(id)
+	}
+	return s.templateManager.GetTemplateVersion(id, version)
+}
+
 func (s *OrchestratorServer) sendWorkflowResponse(correlationID string, response *models.WorkflowResponse, err error) {
 	if err != nil {
 		response = &models.WorkflowResponse{
@@ -351,7 +765,7 @@ func (s *OrchestratorServer) sendWorkflowResponse(correlationID string, response
 		return
 	}
 
-	if publishErr := s.redisClient.Publish(context.Background(), "workflow-responses", responseData).Err(); publishErr != nil {
+	if publishErr := s.redisClient.Publish(context.Background(), config.Namespaced(s.config.Redis.Namespace, "workflow-responses"), responseData).Err(); publishErr != nil {
 		s.logger.WithError(publishErr).Error("Failed to publish workflow response")
 	}
 }
@@ -365,19 +779,83 @@ func (s *OrchestratorServer) handleExecuteWorkflow(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Process workflow in background
-	go s.processWorkflowRequest(r.Context(), &request)
+	if request.DryRun {
+		s.validation.WriteDryRun(w, r, &request)
+		return
+	}
+
+	// A repeated idempotency key returns the execution it started instead of a new one
+	existing, err := s.claimIdempotencyKey(r.Context(), &request)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to claim idempotency key")
+		http.Error(w, "Failed to claim idempotency key", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":        "Workflow already submitted with this idempotency key",
+			"correlation_id": existing.CorrelationID,
+			"execution_id":   existing.ExecutionID,
+			"status":         existing.Status,
+			"duplicate":      true,
+		})
+		return
+	}
+
+	// Queue the workflow; the dispatcher runs it by priority within the concurrency limit
+	if err := s.enqueueWorkflowRequest(r.Context(), &request); err != nil {
+		s.logger.WithError(err).WithField("correlation_id", request.CorrelationID).Error("Failed to queue workflow request")
+		s.releaseIdempotencyKey(&request)
+		http.Error(w, "Failed to queue workflow request", http.StatusInternalServerError)
+		return
+	}
 
 	// Return immediate response
 	response := map[string]interface{}{
-		"message":        "Workflow execution started",
+		"message":        "Workflow execution queued",
 		"correlation_id": request.CorrelationID,
 	}
+	if request.ExecutionID != "" {
+		response["execution_id"] = request.ExecutionID
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleListWorkflows pages through executions, newest first, optionally by status
+func (s *OrchestratorServer) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := clients.ExecutionListOptions{
+		Status: models.ExecutionStatus(query.Get("status")),
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		opts.Limit = parsed
+	}
+
+	page, err := s.stateManager.ListExecutions(r.Context(), opts)
+	if errors.Is(err, clients.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list executions")
+		http.Error(w, "Failed to list executions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
 func (s *OrchestratorServer) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	executionID := vars["id"]
@@ -388,6 +866,14 @@ func (s *OrchestratorServer) handleGetWorkflow(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Never expose secrets passed as variables
+	execution.Variables = engine.RedactVariables(execution.Variables)
+	if execution.Request != nil {
+		request := *execution.Request
+		request.Variables = engine.RedactVariables(request.Variables)
+		execution.Request = &request
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(execution)
 }
@@ -410,6 +896,15 @@ func (s *OrchestratorServer) handleGetWorkflowStatus(w http.ResponseWriter, r *h
 		"task_count":   len(execution.TaskStates),
 	}
 
+	// Each task's decision trace explains why it ran, was skipped or failed
+	decisions := make(map[string]interface{}, len(execution.TaskStates))
+	for taskID, state := range execution.TaskStates {
+		if trace, exists := state.Metadata[engine.DecisionsMetadataKey]; exists {
+			decisions[taskID] = trace
+		}
+	}
+	status["decisions"] = decisions
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
@@ -421,6 +916,16 @@ func (s *OrchestratorServer) handleListTemplates(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(templates)
 }
 
+func (s *OrchestratorServer) handleGetTemplateErrors(w http.ResponseWriter, r *http.Request) {
+	loadErrors := s.templateManager.GetLoadErrors()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": loadErrors,
+		"count":  len(loadErrors),
+	})
+}
+
 func (s *OrchestratorServer) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	templateID := vars["id"]
@@ -435,6 +940,35 @@ func (s *OrchestratorServer) handleGetTemplate(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(template)
 }
 
+func (s *OrchestratorServer) handleListTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+
+	versions, err := s.templateManager.ListTemplateVersions(templateID)
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"template_id": templateID,
+		"versions":    versions,
+	})
+}
+
+func (s *OrchestratorServer) handleGetTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	template, err := s.templateManager.GetTemplateVersion(vars["id"], vars["version"])
+	if err != nil {
+		http.Error(w, "Template version not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
 func (s *OrchestratorServer) handleGetTemplatesByCategory(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	category := vars["category"]
@@ -458,6 +992,12 @@ func (s *OrchestratorServer) handleGenerateWorkflow(w http.ResponseWriter, r *ht
 
 	workflow, err := s.aiGenerator.GenerateWorkflow(r.Context(), &request)
 	if err != nil {
+		if handlers.WriteGraphProblems(w, "Workflow generation failed", err) {
+			return
+		}
+		if handlers.WriteTaskParameterProblems(w, "Workflow generation failed", err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("Workflow generation failed: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -482,6 +1022,12 @@ func (s *OrchestratorServer) handleGenerateFromTemplate(w http.ResponseWriter, r
 
 	workflow, err := s.aiGenerator.GenerateWorkflowFromTemplate(r.Context(), templateID, request.Variables, request.Customizations)
 	if err != nil {
+		if handlers.WriteGraphProblems(w, "Template-based generation failed", err) {
+			return
+		}
+		if handlers.WriteVariableViolations(w, "Template-based generation failed", err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("Template-based generation failed: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -490,35 +1036,23 @@ func (s *OrchestratorServer) handleGenerateFromTemplate(w http.ResponseWriter, r
 	json.NewEncoder(w).Encode(workflow)
 }
 
-func (s *OrchestratorServer) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	health := map[string]interface{}{
-		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"version":   "1.0.0",
-	}
-
-	// Check Redis connection
-	if err := s.redisClient.Ping(r.Context()).Err(); err != nil {
-		health["status"] = "unhealthy"
-		health["redis_error"] = err.Error()
-		w.WriteHeader(http.StatusServiceUnavailable)
-	}
-
+func (s *OrchestratorServer) handleConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(health)
+	json.NewEncoder(w).Encode(s.config.Redacted())
 }
 
 func (s *OrchestratorServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	activeExecutions, _ := s.stateManager.ListActiveExecutions(r.Context())
-	
+
 	status := map[string]interface{}{
-		"service":            "orchestrator",
-		"status":             "running",
-		"active_workflows":   len(activeExecutions),
-		"templates_loaded":   len(s.templateManager.ListAllTemplates()),
-		"recovery_enabled":   s.config.Orchestrator.RecoveryEnabled,
-		"max_concurrent":     s.config.Orchestrator.MaxConcurrent,
-		"uptime":            time.Since(time.Now()).String(), // Placeholder
+		"service":             "orchestrator",
+		"status":              "running",
+		"active_workflows":    len(activeExecutions),
+		"templates_loaded":    len(s.templateManager.ListAllTemplates()),
+		"recovery_enabled":    s.config.Orchestrator.RecoveryEnabled,
+		"max_concurrent":      s.config.Orchestrator.MaxConcurrent,
+		"uptime":              time.Since(time.Now()).String(), // Placeholder
+		"message_coordinator": s.messageCoordinator.GetStats(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -571,4 +1105,25 @@ type responseWriter struct {
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
-}
\ No newline at end of file
+}
+
+// Flush lets streaming handlers flush through the logging wrapper
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades take over the connection through the logging wrapper
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the metrics served by the orchestrator's /metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+// durationBuckets cover quick service calls through long-running exec tasks, in seconds
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// UnlabelledWorkflow is the workflow label of executions not started from a template,
+// such as AI-generated workflows, so their IDs cannot grow the number of series
+const UnlabelledWorkflow = "adhoc"
+
+var (
+	factory = promauto.With(Registry)
+
+	workflowsStarted = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_workflows_started_total",
+		Help: "Workflow executions started.",
+	}, []string{"workflow"})
+	workflowsFinished = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_workflows_finished_total",
+		Help: "Workflow executions finished, by final status.",
+	}, []string{"workflow", "status"})
+	taskDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orchestrator_task_duration_seconds",
+		Help:    "Task execution time including retries, by final status.",
+		Buckets: durationBuckets,
+	}, []string{"workflow", "task_type", "status"})
+	taskRetries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "orchestrator_task_retries_total",
+		Help: "Task attempts after the first.",
+	}, []string{"workflow", "task_type"})
+	serviceRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "orchestrator_service_request_duration_seconds",
+		Help:    "Round trip of requests to downstream services, by outcome.",
+		Buckets: durationBuckets,
+	}, []string{"service", "outcome"})
+)
+
+// Handler serves Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+var (
+	gaugeMutex sync.Mutex
+	gaugeFuncs = make(map[string]prometheus.GaugeFunc)
+)
+
+// RegisterGaugeFunc registers a gauge whose value is read from fn on every scrape.
+// Registering a name again replaces the gauge, so fn is the one read from then on.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	gaugeMutex.Lock()
+	defer gaugeMutex.Unlock()
+
+	if previous, exists := gaugeFuncs[name]; exists {
+		Registry.Unregister(previous)
+	}
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+	Registry.MustRegister(gauge)
+	gaugeFuncs[name] = gauge
+}
+
+// WorkflowStarted counts an execution starting
+func WorkflowStarted(workflow string) {
+	workflowsStarted.WithLabelValues(workflow).Inc()
+}
+
+// WorkflowFinished counts an execution reaching a final status
+func WorkflowFinished(workflow, status string) {
+	workflowsFinished.WithLabelValues(workflow, status).Inc()
+}
+
+// TaskFinished records how long a task took to reach its final status
+func TaskFinished(workflow, taskType, status string, duration time.Duration) {
+	taskDuration.WithLabelValues(workflow, taskType, status).Observe(duration.Seconds())
+}
+
+// TaskRetried counts a retry attempt of a task
+func TaskRetried(workflow, taskType string) {
+	taskRetries.WithLabelValues(workflow, taskType).Inc()
+}
+
+// ServiceRequestFinished records a downstream request's outcome and round trip
+func ServiceRequestFinished(service, outcome string, duration time.Duration) {
+	serviceRequestDuration.WithLabelValues(service, outcome).Observe(duration.Seconds())
+}
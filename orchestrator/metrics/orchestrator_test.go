@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestServiceRequestFinishedFillsBuckets(t *testing.T) {
+	// Start from no series so the test can run more than once in a process
+	serviceRequestDuration.DeletePartialMatch(prometheus.Labels{"service": "test-histogram"})
+	ServiceRequestFinished("test-histogram", "success", 30*time.Millisecond)
+	ServiceRequestFinished("test-histogram", "success", 2*time.Second)
+	ServiceRequestFinished("test-histogram", "timeout", time.Hour)
+
+	expected := `
+# HELP orchestrator_service_request_duration_seconds Round trip of requests to downstream services, by outcome.
+# TYPE orchestrator_service_request_duration_seconds histogram
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="0.05"} 1
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="0.1"} 1
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="0.25"} 1
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="0.5"} 1
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="1"} 1
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="2.5"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="5"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="10"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="30"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="60"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="120"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="300"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="600"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="success",service="test-histogram",le="+Inf"} 2
+orchestrator_service_request_duration_seconds_sum{outcome="success",service="test-histogram"} 2.03
+orchestrator_service_request_duration_seconds_count{outcome="success",service="test-histogram"} 2
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="0.05"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="0.1"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="0.25"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="0.5"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="1"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="2.5"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="5"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="10"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="30"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="60"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="120"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="300"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="600"} 0
+orchestrator_service_request_duration_seconds_bucket{outcome="timeout",service="test-histogram",le="+Inf"} 1
+orchestrator_service_request_duration_seconds_sum{outcome="timeout",service="test-histogram"} 3600
+orchestrator_service_request_duration_seconds_count{outcome="timeout",service="test-histogram"} 1
+`
+	// Only this test's series are compared; other tests share the registry
+	if err := testutil.CollectAndCompare(serviceRequestDuration.MustCurryWith(map[string]string{"service": "test-histogram"}), strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCountersAccumulatePerLabelSet(t *testing.T) {
+	for _, vec := range []*prometheus.CounterVec{workflowsStarted, workflowsFinished, taskRetries} {
+		vec.DeletePartialMatch(prometheus.Labels{"workflow": "test-counter"})
+	}
+	WorkflowStarted("test-counter")
+	WorkflowStarted("test-counter")
+	WorkflowFinished("test-counter", "completed")
+	WorkflowFinished("test-counter", "failed")
+	WorkflowFinished("test-counter", "failed")
+	TaskRetried("test-counter", "exec")
+
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"started", testutil.ToFloat64(workflowsStarted.WithLabelValues("test-counter")), 2},
+		{"completed", testutil.ToFloat64(workflowsFinished.WithLabelValues("test-counter", "completed")), 1},
+		{"failed", testutil.ToFloat64(workflowsFinished.WithLabelValues("test-counter", "failed")), 2},
+		{"retries", testutil.ToFloat64(taskRetries.WithLabelValues("test-counter", "exec")), 1},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestHandlerServesTextFormatWithGauges(t *testing.T) {
+	value := 3.0
+	RegisterGaugeFunc("orchestrator_test_gauge", "A gauge read on scrape.", func() float64 { return value })
+	workflowsStarted.DeleteLabelValues(`quoted "label"`)
+	WorkflowStarted(`quoted "label"`)
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+			t.Errorf("got content type %q, want the text exposition format", recorder.Header().Get("Content-Type"))
+		}
+		return recorder.Body.String()
+	}
+
+	body := scrape()
+	for _, want := range []string{
+		"# TYPE orchestrator_test_gauge gauge\norchestrator_test_gauge 3\n",
+		"# TYPE orchestrator_workflows_started_total counter\n",
+		`orchestrator_workflows_started_total{workflow="quoted \"label\""} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q:\n%s", want, body)
+		}
+	}
+
+	value = 5
+	if body := scrape(); !strings.Contains(body, "orchestrator_test_gauge 5\n") {
+		t.Errorf("expected the gauge to be read again on scrape:\n%s", body)
+	}
+}
+
+func TestRegisterGaugeFuncReplacesExistingGauge(t *testing.T) {
+	RegisterGaugeFunc("orchestrator_test_replaced_gauge", "A gauge registered twice.", func() float64 { return 1 })
+	RegisterGaugeFunc("orchestrator_test_replaced_gauge", "A gauge registered twice.", func() float64 { return 2 })
+
+	expected := `
+# HELP orchestrator_test_replaced_gauge A gauge registered twice.
+# TYPE orchestrator_test_replaced_gauge gauge
+orchestrator_test_replaced_gauge 2
+`
+	if err := testutil.GatherAndCompare(Registry, strings.NewReader(expected), "orchestrator_test_replaced_gauge"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+// Clone returns a deep copy of the template. It copies the struct graph directly rather
+// than round-tripping through YAML, so it is cheap enough for every template read.
+func (t *Template) Clone() *Template {
+	if t == nil {
+		return nil
+	}
+
+	clone := *t
+	clone.Preconditions = cloneStrings(t.Preconditions)
+	clone.Workflow = *t.Workflow.Clone()
+
+	if t.Variables != nil {
+		clone.Variables = make([]TemplateVariable, len(t.Variables))
+		for i, variable := range t.Variables {
+			variable.DefaultValue = cloneValue(variable.DefaultValue)
+			variable.Options = cloneStrings(variable.Options)
+			clone.Variables[i] = variable
+		}
+	}
+
+	return &clone
+}
+
+// Clone returns a deep copy of the workflow definition
+func (w *WorkflowDefinition) Clone() *WorkflowDefinition {
+	if w == nil {
+		return nil
+	}
+
+	clone := *w
+	clone.Variables = cloneMap(w.Variables)
+	clone.Labels = cloneStringMap(w.Labels)
+
+	if w.OnError != nil {
+		onError := *w.OnError
+		clone.OnError = &onError
+	}
+
+	if w.Tasks != nil {
+		clone.Tasks = make([]Task, len(w.Tasks))
+		for i := range w.Tasks {
+			clone.Tasks[i] = w.Tasks[i].clone()
+		}
+	}
+
+	return &clone
+}
+
+func (t Task) clone() Task {
+	t.DependsOn = cloneStrings(t.DependsOn)
+	t.OnSuccess = cloneStrings(t.OnSuccess)
+	t.OnFailure = cloneStrings(t.OnFailure)
+	t.RetainedOutputs = cloneStrings(t.RetainedOutputs)
+	t.Parameters = cloneMap(t.Parameters)
+
+	if t.RetryPolicy != nil {
+		policy := *t.RetryPolicy
+		t.RetryPolicy = &policy
+	}
+
+	t.Variables = cloneStringMap(t.Variables)
+
+	return t
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(values)), values...)
+}
+
+func cloneStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(values))
+	for key, value := range values {
+		clone[key] = value
+	}
+	return clone
+}
+
+func cloneMap(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		result[key] = cloneValue(value)
+	}
+	return result
+}
+
+// cloneValue deep copies the generic containers produced by YAML and JSON decoding;
+// scalars are immutable and returned as-is
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return cloneMap(v)
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			result[key] = cloneValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = cloneValue(item)
+		}
+		return result
+	case []string:
+		return cloneStrings(v)
+	default:
+		return value
+	}
+}
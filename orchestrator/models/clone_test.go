@@ -0,0 +1,102 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newCloneTestTemplate returns a template with every nested container populated
+func newCloneTestTemplate() *Template {
+	return &Template{
+		ID:            "report",
+		Name:          "Report",
+		Version:       "2",
+		Preconditions: []string{"exists(dataset)"},
+		Variables: []TemplateVariable{
+			{Name: "mode", Type: "string", DefaultValue: "fast", Options: []string{"fast", "full"}},
+			{Name: "columns", Type: "array", DefaultValue: []interface{}{"a", map[string]interface{}{"b": 1}}},
+		},
+		Workflow: WorkflowDefinition{
+			ID:        "report",
+			Variables: map[string]interface{}{"limits": map[string]interface{}{"rows": 10}},
+			OnError:   &ErrorHandling{},
+			Labels:    map[string]string{"team": "analytics"},
+			Tasks: []Task{{
+				ID:              "fetch",
+				Type:            "data",
+				DependsOn:       []string{"setup"},
+				OnSuccess:       []string{"next"},
+				RetainedOutputs: []string{"rows"},
+				Parameters:      map[string]interface{}{"query": map[string]interface{}{"tags": []interface{}{"x"}}},
+				RetryPolicy:     &RetryPolicy{MaxRetries: 2},
+				Variables:       map[string]string{"alias": "value"},
+			}},
+		},
+	}
+}
+
+func TestTemplateCloneIsDeep(t *testing.T) {
+	original := newCloneTestTemplate()
+	clone := original.Clone()
+	if !reflect.DeepEqual(clone, original) {
+		t.Fatalf("clone differs from the original:\n%+v\n%+v", clone, original)
+	}
+
+	// Mutate everything reachable from the clone
+	clone.Preconditions[0] = "changed"
+	clone.Variables[0].Options[0] = "changed"
+	clone.Variables[1].DefaultValue.([]interface{})[1].(map[string]interface{})["b"] = 2
+	clone.Workflow.Variables["limits"].(map[string]interface{})["rows"] = 99
+	clone.Workflow.Labels["team"] = "changed"
+	task := &clone.Workflow.Tasks[0]
+	task.DependsOn[0] = "changed"
+	task.OnSuccess[0] = "changed"
+	task.RetainedOutputs[0] = "changed"
+	task.Parameters["query"].(map[string]interface{})["tags"].([]interface{})[0] = "changed"
+	task.RetryPolicy.MaxRetries = 9
+	task.Variables["alias"] = "changed"
+
+	if !reflect.DeepEqual(original, newCloneTestTemplate()) {
+		t.Errorf("mutating the clone changed the original: %+v", original)
+	}
+	if clone.Workflow.OnError == original.Workflow.OnError {
+		t.Error("expected on_error to be copied")
+	}
+}
+
+func TestCloneNil(t *testing.T) {
+	var template *Template
+	if template.Clone() != nil {
+		t.Error("expected a nil template to clone to nil")
+	}
+	var workflow *WorkflowDefinition
+	if workflow.Clone() != nil {
+		t.Error("expected a nil workflow to clone to nil")
+	}
+}
+
+func BenchmarkTemplateClone(b *testing.B) {
+	template := newCloneTestTemplate()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		template.Clone()
+	}
+}
+
+// BenchmarkTemplateYAMLRoundTrip is the cost of the YAML clone Clone replaced
+func BenchmarkTemplateYAMLRoundTrip(b *testing.B) {
+	template := newCloneTestTemplate()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, err := yaml.Marshal(template)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var clone Template
+		if err := yaml.Unmarshal(data, &clone); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaDialect is the JSON Schema draft the generated schemas follow
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Values accepted by string fields that have a fixed set, keyed by "Struct.json_name"
+var schemaFieldEnums = map[string][]string{
+	"Task.type":                           {"data", "ai", "exec", "parallel", "condition", "workflow"},
+	"Task.output_retention":               {RetentionFull, RetentionDeclared, RetentionArtifact, RetentionDrop},
+	"WorkflowDefinition.output_retention": {RetentionFull, RetentionDeclared, RetentionArtifact, RetentionDrop},
+	"WorkflowDefinition.interpolation":    {InterpolationLenient, InterpolationStrict},
+	"RetryPolicy.backoff_type":            {"fixed", "exponential", "linear"},
+	"RetryPolicy.jitter":                  {JitterFull, JitterEqual},
+	"ErrorHandling.strategy":              {"abort", "continue", "retry"},
+	"TemplateVariable.type":               {"string", "int", "bool", "array", "object"},
+}
+
+// Fields that must be present, keyed by struct name. Tags can't express this since
+// several required fields are filled in with defaults when omitted.
+var schemaRequiredFields = map[string][]string{
+	"Template":           {"id", "name", "workflow"},
+	"WorkflowDefinition": {"tasks"},
+	"Task":               {"id", "type"},
+	"TemplateVariable":   {"name", "type"},
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	statusType   = reflect.TypeOf(ExecutionStatus(""))
+)
+
+// executionStatuses lists every ExecutionStatus value
+var executionStatuses = []string{
+	string(StatusPending), string(StatusRunning), string(StatusCompleted), string(StatusFailed),
+	string(StatusCancelled), string(StatusRetrying), string(StatusSkipped),
+}
+
+// WorkflowSchema returns the JSON Schema of a workflow definition
+func WorkflowSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(WorkflowDefinition{}), "Workflow definition")
+}
+
+// TemplateSchema returns the JSON Schema of a workflow template
+func TemplateSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(Template{}), "Workflow template")
+}
+
+// ExecutionSchema returns the JSON Schema of a workflow execution's state
+func ExecutionSchema() map[string]interface{} {
+	return generateSchema(reflect.TypeOf(WorkflowExecution{}), "Workflow execution")
+}
+
+// generateSchema reflects a schema from the model structs, so it follows them as they
+// change. Each struct becomes an entry in $defs and the root refers to the given one.
+func generateSchema(root reflect.Type, title string) map[string]interface{} {
+	defs := make(map[string]interface{})
+	ref := schemaFor(root, defs)
+
+	schema := map[string]interface{}{
+		"$schema": schemaDialect,
+		"title":   title,
+		"$defs":   defs,
+	}
+	for key, value := range ref {
+		schema[key] = value
+	}
+	return schema
+}
+
+// schemaFor returns the schema of a type, adding struct definitions to defs
+func schemaFor(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		// JSON carries nanoseconds; YAML also accepts strings such as "5s"
+		return map[string]interface{}{
+			"type":        []string{"integer", "string"},
+			"description": "nanoseconds, or a duration string such as \"5s\" in YAML",
+		}
+	case statusType:
+		return map[string]interface{}{"type": "string", "enum": executionStatuses}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), defs)}
+	case reflect.Struct:
+		name := t.Name()
+		if _, exists := defs[name]; !exists {
+			// Reserve the name first so recursive types terminate
+			defs[name] = nil
+			defs[name] = structSchema(t, defs)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+
+	// interface{} and anything else accept any value
+	return map[string]interface{}{}
+}
+
+// structSchema describes a struct's exported fields under their JSON names
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaFor(field.Type, defs)
+		if values, exists := schemaFieldEnums[t.Name()+"."+name]; exists {
+			property["enum"] = values
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if required, exists := schemaRequiredFields[t.Name()]; exists {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -6,11 +6,61 @@ import (
 
 // WorkflowRequest represents an incoming request to orchestrate a workflow
 type WorkflowRequest struct {
-	CorrelationID    string                 `json:"correlation_id"`
-	WorkflowTemplate string                 `json:"workflow_template"`
-	Variables        map[string]interface{} `json:"variables,omitempty"`
-	GenerateFromAI   *AIGenerationRequest   `json:"generate_from_ai,omitempty"`
-	Priority         int                    `json:"priority,omitempty"`
+	CorrelationID     string                 `json:"correlation_id"`
+	WorkflowTemplate  string                 `json:"workflow_template"`
+	TemplateVersion   string                 `json:"template_version,omitempty"` // pins a template version; the latest is used when empty
+	Variables         map[string]interface{} `json:"variables,omitempty"`
+	GenerateFromAI    *AIGenerationRequest   `json:"generate_from_ai,omitempty"`
+	Priority          int                    `json:"priority,omitempty"`
+	PinVersions       bool                   `json:"pin_versions,omitempty"`        // snapshot service versions at start and enforce them
+	ExecutionID       string                 `json:"execution_id,omitempty"`        // pre-assigned execution ID, generated when empty
+	MatrixID          string                 `json:"matrix_id,omitempty"`           // groups executions launched from one matrix request
+	Record            bool                   `json:"record,omitempty"`              // capture every service request/response for replay
+	ReplayFrom        string                 `json:"replay_from,omitempty"`         // execution whose recorded responses stand in for the services
+	IdempotencyKey    string                 `json:"idempotency_key,omitempty"`     // repeated submissions with the same key return the first execution
+	RequireServices   bool                   `json:"require_services,omitempty"`    // fail before any task runs if a service the workflow uses is unavailable
+	DryRun            bool                   `json:"dry_run,omitempty"`             // validate and plan the workflow without running it
+	ParentExecutionID string                 `json:"parent_execution_id,omitempty"` // set on nested executions started by a workflow task
+	WorkflowStack     []string               `json:"workflow_stack,omitempty"`      // templates enclosing a nested execution, outermost first
+	CallbackURL       string                 `json:"callback_url,omitempty"`        // receives the workflow response as a signed POST when the execution finishes
+	RecoveryAttempts  int                    `json:"-"`                             // times recovery has started this execution again; set by recovery only
+}
+
+// MatrixRequest launches one execution of a template per combination of variable sets
+type MatrixRequest struct {
+	TemplateID      string                   `json:"template_id"`
+	TemplateVersion string                   `json:"template_version,omitempty"`
+	Variables       map[string]interface{}   `json:"variables,omitempty"` // shared by every combination
+	Matrix          map[string][]interface{} `json:"matrix,omitempty"`    // variable -> values, expanded as a cartesian product
+	Include         []map[string]interface{} `json:"include,omitempty"`   // explicit extra variable sets
+	PinVersions     bool                     `json:"pin_versions,omitempty"`
+	Priority        int                      `json:"priority,omitempty"` // queue priority of every execution in the group
+}
+
+// MatrixStatus aggregates the statuses of the executions in a matrix group
+type MatrixStatus struct {
+	MatrixID     string                     `json:"matrix_id"`
+	Status       ExecutionStatus            `json:"status"`
+	Total        int                        `json:"total"`
+	StatusCounts map[ExecutionStatus]int    `json:"status_counts"`
+	Executions   map[string]ExecutionStatus `json:"executions"`
+}
+
+// ExecutionSummary is the listing view of a workflow execution
+type ExecutionSummary struct {
+	ID         string          `json:"id"`
+	WorkflowID string          `json:"workflow_id"`
+	Status     ExecutionStatus `json:"status"`
+	StartTime  time.Time       `json:"start_time"`
+	EndTime    *time.Time      `json:"end_time,omitempty"`
+	MatrixID   string          `json:"matrix_id,omitempty"`
+}
+
+// ExecutionPage is one page of an execution listing, newest first. NextCursor is empty
+// on the last page.
+type ExecutionPage struct {
+	Executions []ExecutionSummary `json:"executions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
 }
 
 // AIGenerationRequest contains parameters for AI-generated workflow creation
@@ -19,44 +69,79 @@ type AIGenerationRequest struct {
 	Domain           string   `json:"domain,omitempty"`
 	RequiredServices []string `json:"required_services,omitempty"`
 	Complexity       string   `json:"complexity,omitempty"` // simple, medium, complex
+	Provider         string   `json:"provider,omitempty"`   // preferred AI provider; the configured providers are tried after it
+	Model            string   `json:"model,omitempty"`      // model for the preferred provider; defaults to its configured model
 	OutputFormat     string   `json:"output_format,omitempty"`
 }
 
 // WorkflowDefinition represents a complete workflow with tasks and dependencies
 type WorkflowDefinition struct {
-	ID          string                 `yaml:"id" json:"id"`
-	Name        string                 `yaml:"name" json:"name"`
-	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
-	Version     string                 `yaml:"version,omitempty" json:"version,omitempty"`
-	Variables   map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
-	Tasks       []Task                 `yaml:"tasks" json:"tasks"`
-	OnError     *ErrorHandling         `yaml:"on_error,omitempty" json:"on_error,omitempty"`
-	Timeout     int                    `yaml:"timeout,omitempty" json:"timeout,omitempty"` // seconds
+	ID              string                 `yaml:"id" json:"id"`
+	Name            string                 `yaml:"name" json:"name"`
+	Description     string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Version         string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Variables       map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Tasks           []Task                 `yaml:"tasks" json:"tasks"`
+	OnError         *ErrorHandling         `yaml:"on_error,omitempty" json:"on_error,omitempty"`
+	Timeout         int                    `yaml:"timeout,omitempty" json:"timeout,omitempty"` // seconds
+	PersistOutputs  bool                   `yaml:"persist_outputs,omitempty" json:"persist_outputs,omitempty"`
+	OutputRetention string                 `yaml:"output_retention,omitempty" json:"output_retention,omitempty"` // default retention policy for all tasks
+	Interpolation   string                 `yaml:"interpolation,omitempty" json:"interpolation,omitempty"`       // lenient (default) or strict
+	Labels          map[string]string      `yaml:"labels,omitempty" json:"labels,omitempty"`                     // matched by the service access policy
 }
 
+// Variable interpolation modes
+const (
+	InterpolationLenient = "lenient" // unknown ${var} references are left in place
+	InterpolationStrict  = "strict"  // unknown references fail the task before it runs
+)
+
 // Task represents a single step in the workflow
 type Task struct {
-	ID           string                 `yaml:"id" json:"id"`
-	Name         string                 `yaml:"name" json:"name"`
-	Type         string                 `yaml:"type" json:"type"` // data, ai, exec, parallel, condition
-	DependsOn    []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
-	Parameters   map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
-	RetryPolicy  *RetryPolicy           `yaml:"retry_policy,omitempty" json:"retry_policy,omitempty"`
-	Timeout      int                    `yaml:"timeout,omitempty" json:"timeout,omitempty"`
-	Condition    string                 `yaml:"condition,omitempty" json:"condition,omitempty"`
-	OnSuccess    []string               `yaml:"on_success,omitempty" json:"on_success,omitempty"`
-	OnFailure    []string               `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
-	Variables    map[string]string      `yaml:"variables,omitempty" json:"variables,omitempty"`
+	ID              string                 `yaml:"id" json:"id"`
+	Name            string                 `yaml:"name" json:"name"`
+	Type            string                 `yaml:"type" json:"type"` // data, ai, exec, parallel, condition, workflow
+	DependsOn       []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Parameters      map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RetryPolicy     *RetryPolicy           `yaml:"retry_policy,omitempty" json:"retry_policy,omitempty"`
+	RetryIf         string                 `yaml:"retry_if,omitempty" json:"retry_if,omitempty"` // expression over the output that retries a nominal success
+	Timeout         int                    `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	QueueTimeout    int                    `yaml:"queue_timeout,omitempty" json:"queue_timeout,omitempty"` // seconds the task may wait for a slot before failing
+	ExecTimeout     int                    `yaml:"exec_timeout,omitempty" json:"exec_timeout,omitempty"`   // seconds each attempt may run once dispatched; overrides timeout
+	Condition       string                 `yaml:"condition,omitempty" json:"condition,omitempty"`
+	OnSuccess       []string               `yaml:"on_success,omitempty" json:"on_success,omitempty"`
+	OnFailure       []string               `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	Variables       map[string]string      `yaml:"variables,omitempty" json:"variables,omitempty"`
+	PersistOutput   bool                   `yaml:"persist_output,omitempty" json:"persist_output,omitempty"`
+	OutputRetention string                 `yaml:"output_retention,omitempty" json:"output_retention,omitempty"` // full, declared, artifact or drop
+	RetainedOutputs []string               `yaml:"retained_outputs,omitempty" json:"retained_outputs,omitempty"` // output keys kept by the declared policy
+	Deterministic   bool                   `yaml:"deterministic,omitempty" json:"deterministic,omitempty"`       // same resolved parameters always give the same output, so results may be cached
 }
 
+// Output retention policies applied once a task's dependents have finished
+const (
+	RetentionFull     = "full"     // keep the whole output (default)
+	RetentionDeclared = "declared" // keep only the keys listed in retained_outputs
+	RetentionArtifact = "artifact" // offload to the artifact store and keep a reference
+	RetentionDrop     = "drop"     // discard the output
+)
+
 // RetryPolicy defines how tasks should be retried on failure
 type RetryPolicy struct {
 	MaxRetries   int           `yaml:"max_retries" json:"max_retries"`
 	BackoffType  string        `yaml:"backoff_type" json:"backoff_type"` // fixed, exponential, linear
 	InitialDelay time.Duration `yaml:"initial_delay" json:"initial_delay"`
 	MaxDelay     time.Duration `yaml:"max_delay,omitempty" json:"max_delay,omitempty"`
+	Jitter       string        `yaml:"jitter,omitempty" json:"jitter,omitempty"`           // full or equal; unset keeps delays deterministic
+	ForceRetry   bool          `yaml:"force_retry,omitempty" json:"force_retry,omitempty"` // retry even operations their service marks as not retry safe
 }
 
+// Retry jitter modes
+const (
+	JitterFull  = "full"  // random delay in [0, delay]
+	JitterEqual = "equal" // random delay in [delay/2, delay]
+)
+
 // ErrorHandling defines global workflow error handling
 type ErrorHandling struct {
 	Strategy   string `yaml:"strategy" json:"strategy"` // abort, continue, retry
@@ -66,16 +151,23 @@ type ErrorHandling struct {
 
 // WorkflowExecution represents the runtime state of a workflow
 type WorkflowExecution struct {
-	ID            string                 `json:"id"`
-	WorkflowID    string                 `json:"workflow_id"`
-	CorrelationID string                 `json:"correlation_id"`
-	Status        ExecutionStatus        `json:"status"`
-	Variables     map[string]interface{} `json:"variables"`
-	TaskStates    map[string]*TaskState  `json:"task_states"`
-	StartTime     time.Time              `json:"start_time"`
-	EndTime       *time.Time             `json:"end_time,omitempty"`
-	Error         string                 `json:"error,omitempty"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	ID               string                 `json:"id"`
+	WorkflowID       string                 `json:"workflow_id"`
+	CorrelationID    string                 `json:"correlation_id"`
+	Status           ExecutionStatus        `json:"status"`
+	Variables        map[string]interface{} `json:"variables"`
+	TaskStates       map[string]*TaskState  `json:"task_states"`
+	StartTime        time.Time              `json:"start_time"`
+	EndTime          *time.Time             `json:"end_time,omitempty"`
+	Error            string                 `json:"error,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	PinnedVersions   map[string]string      `json:"pinned_versions,omitempty"` // component -> announced service version
+	MatrixID         string                 `json:"matrix_id,omitempty"`
+	InitialVariables map[string]interface{} `json:"initial_variables,omitempty"` // effective variables at start, redacted
+	VariableChanges  map[string]interface{} `json:"variable_changes,omitempty"`  // variables added or changed during the run, redacted
+	Labels           map[string]string      `json:"labels,omitempty"`            // copied from the workflow definition
+	Workflow         *WorkflowDefinition    `json:"workflow,omitempty"`          // definition being run, so recovery can run it again
+	Request          *WorkflowRequest       `json:"request,omitempty"`           // request that started the execution, so recovery can restart it
 }
 
 // TaskState tracks the execution state of an individual task
@@ -86,6 +178,7 @@ type TaskState struct {
 	EndTime    *time.Time             `json:"end_time,omitempty"`
 	RetryCount int                    `json:"retry_count"`
 	Error      string                 `json:"error,omitempty"`
+	ErrorCode  string                 `json:"error_code,omitempty"` // distinguishes cancellation and timeouts from task failures
 	Output     map[string]interface{} `json:"output,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -103,26 +196,37 @@ const (
 	StatusSkipped   ExecutionStatus = "skipped"
 )
 
+// Task error codes recorded alongside a failed or cancelled task state
+const (
+	TaskErrorCancelled    = "CANCELLED"     // the workflow was cancelled or the orchestrator shut down
+	TaskErrorTimeout      = "TIMEOUT"       // the task or workflow timeout elapsed
+	TaskErrorQueueTimeout = "QUEUE_TIMEOUT" // the task waited out its queue timeout without being dispatched
+)
+
 // WorkflowResponse is the response sent back after workflow execution
 type WorkflowResponse struct {
-	CorrelationID string                 `json:"correlation_id"`
-	ExecutionID   string                 `json:"execution_id"`
-	Status        ExecutionStatus        `json:"status"`
-	Success       bool                   `json:"success"`
-	Results       map[string]interface{} `json:"results,omitempty"`
-	Error         string                 `json:"error,omitempty"`
-	Duration      time.Duration          `json:"duration"`
-	TaskResults   map[string]interface{} `json:"task_results,omitempty"`
-	Timestamp     time.Time              `json:"timestamp"`
+	CorrelationID    string                 `json:"correlation_id"`
+	ExecutionID      string                 `json:"execution_id"`
+	Status           ExecutionStatus        `json:"status"`
+	Success          bool                   `json:"success"`
+	Results          map[string]interface{} `json:"results,omitempty"`
+	Error            string                 `json:"error,omitempty"`
+	Duration         time.Duration          `json:"duration"`
+	TaskResults      map[string]interface{} `json:"task_results,omitempty"`
+	Timestamp        time.Time              `json:"timestamp"`
+	InitialVariables map[string]interface{} `json:"initial_variables,omitempty"`
+	VariableChanges  map[string]interface{} `json:"variable_changes,omitempty"`
+	Duplicate        bool                   `json:"duplicate,omitempty"` // the request repeated an idempotency key; this is the existing execution
 }
 
 // ServiceRequest represents a request to be sent to other services
 type ServiceRequest struct {
-	Service       string                 `json:"service"`       // data, ai, exec
-	Operation     string                 `json:"operation"`     // service-specific operation
+	Service       string                 `json:"service"`   // data, ai, exec
+	Operation     string                 `json:"operation"` // service-specific operation
 	CorrelationID string                 `json:"correlation_id"`
 	Parameters    map[string]interface{} `json:"parameters"`
 	Timeout       int                    `json:"timeout,omitempty"`
+	TraceContext  map[string]string      `json:"trace_context,omitempty"` // W3C traceparent/tracestate of the calling span
 }
 
 // ServiceResponse represents a response from other services
@@ -133,16 +237,25 @@ type ServiceResponse struct {
 	Error         string                 `json:"error,omitempty"`
 	Timestamp     time.Time              `json:"timestamp"`
 	Service       string                 `json:"service"`
+	ExecutionID   string                 `json:"execution_id,omitempty"` // downstream execution reference, if any
+	ErrorCode     string                 `json:"error_code,omitempty"`
+	RetryAfter    float64                `json:"retry_after_seconds,omitempty"` // delay requested by a rate limited service
 }
 
+// ErrorCodeRateLimited marks a service response rejected by an upstream rate limit
+const ErrorCodeRateLimited = "RATE_LIMITED"
+
 // Template represents a reusable workflow template
 type Template struct {
 	ID          string             `yaml:"id" json:"id"`
 	Name        string             `yaml:"name" json:"name"`
 	Description string             `yaml:"description,omitempty" json:"description,omitempty"`
 	Category    string             `yaml:"category,omitempty" json:"category,omitempty"`
+	Version     string             `yaml:"version,omitempty" json:"version,omitempty"` // assigned on save when empty; recorded versions are immutable
 	Variables   []TemplateVariable `yaml:"variables,omitempty" json:"variables,omitempty"`
-	Workflow    WorkflowDefinition `yaml:"workflow" json:"workflow"`
+	// Preconditions are expressions that must all hold before the template is instantiated
+	Preconditions []string           `yaml:"preconditions,omitempty" json:"preconditions,omitempty"`
+	Workflow      WorkflowDefinition `yaml:"workflow" json:"workflow"`
 }
 
 // TemplateVariable defines a configurable variable in a template
@@ -153,4 +266,88 @@ type TemplateVariable struct {
 	Required     bool        `yaml:"required" json:"required"`
 	DefaultValue interface{} `yaml:"default,omitempty" json:"default,omitempty"`
 	Options      []string    `yaml:"options,omitempty" json:"options,omitempty"`
-}
\ No newline at end of file
+}
+// Kinds of progress event
+const (
+	ProgressTask     = "task"
+	ProgressWorkflow = "workflow"
+)
+
+// ProgressEvent reports a state change of an execution or one of its tasks while it runs
+type ProgressEvent struct {
+	Type        string          `json:"type"` // task or workflow
+	ExecutionID string          `json:"execution_id"`
+	TaskID      string          `json:"task_id,omitempty"`
+	Status      ExecutionStatus `json:"status"`
+	RetryCount  int             `json:"retry_count,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// Execution lifecycle event types recorded in an execution's event log
+const (
+	EventWorkflowStarted   = "workflow_started"
+	EventWorkflowResumed   = "workflow_resumed"
+	EventWorkflowCompleted = "workflow_completed"
+	EventWorkflowFailed    = "workflow_failed"
+	EventWorkflowCancelled = "workflow_cancelled"
+	EventTaskStarted       = "task_started"
+	EventTaskRetried       = "task_retried"
+	EventTaskCompleted     = "task_completed"
+	EventTaskFailed        = "task_failed"
+	EventTaskCancelled     = "task_cancelled"
+	EventTaskSkipped       = "task_skipped"
+	EventRecoveryTriggered = "recovery_triggered"
+)
+
+// ExecutionEvent is one entry of an execution's append-only event log
+type ExecutionEvent struct {
+	Type        string                 `json:"type"`
+	ExecutionID string                 `json:"execution_id"`
+	TaskID      string                 `json:"task_id,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// ExecutionRecord is the summary of a finished execution sent to external export sinks
+type ExecutionRecord struct {
+	ExecutionID   string            `json:"execution_id"`
+	WorkflowID    string            `json:"workflow_id"`
+	WorkflowName  string            `json:"workflow_name,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	MatrixID      string            `json:"matrix_id,omitempty"`
+	Status        ExecutionStatus   `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	DurationMs    int64             `json:"duration_ms"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	TokensUsed    int               `json:"tokens_used"`    // summed over the tasks
+	Cost          float64           `json:"cost,omitempty"` // tokens used at the configured price
+	Tasks         []TaskRecord      `json:"tasks"`
+}
+
+// TaskRecord summarizes a single task of an exported execution
+type TaskRecord struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Status     ExecutionStatus `json:"status"`
+	RetryCount int             `json:"retry_count"`
+	DurationMs int64           `json:"duration_ms,omitempty"`
+	TokensUsed int             `json:"tokens_used,omitempty"` // reported by AI tasks as tokens_used in their output
+	Error      string          `json:"error,omitempty"`
+	ErrorCode  string          `json:"error_code,omitempty"`
+}
+
+// ServiceExchange is a recorded service request and its response, keyed to the task
+// attempt that made it
+type ServiceExchange struct {
+	TaskID     string           `json:"task_id"`
+	Attempt    int              `json:"attempt"`
+	Service    string           `json:"service"`
+	Operation  string           `json:"operation"`
+	Request    *ServiceRequest  `json:"request"`
+	Response   *ServiceResponse `json:"response,omitempty"`
+	Error      string           `json:"error,omitempty"` // transport error returned instead of a response
+	RecordedAt time.Time        `json:"recorded_at"`
+}